@@ -1,48 +1,355 @@
 package config
 
 import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"log/slog"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"workflower/lib/locale"
+	"workflower/lib/suno"
+	"workflower/lib/timefmt"
+	"workflower/users"
+
+	"github.com/joho/godotenv"
 )
 
+// DefaultNamingTemplate reproduces the historical truncated-description titles
+const DefaultNamingTemplate = `{{.Description}}`
+
+// DefaultAppEnv is the profile used when APP_ENV is not set
+const DefaultAppEnv = "prod"
+
+// profileFiles holds the defaults of every APP_ENV profile, one KEY=value file per profile
+//
+//go:embed profiles/*.env
+var profileFiles embed.FS
+
+// profile holds the defaults of the selected profile; environment variables override them
+var profile map[string]string
+
+// Version is the application version, set at build time with
+// -ldflags "-X workflower/config.Version=v1.2.3"
+var Version = "1.0.0"
+
 // Config holds all application configuration from environment variables
 type Config struct {
+	// Environment profile (see config/profiles) and what it mainly selects
+	AppEnv   string     // dev, staging or prod
+	LogLevel slog.Level // LOG_LEVEL: debug, info, warn or error
+	DryRun   bool       // Telegram messages and outbound webhooks are logged instead of sent
+
 	// Server
 	ServerPort string
 	BaseURL    string
+	StoreFile  string // JSON snapshot of the store, empty to keep workflows in memory only
+
+	// Archival of the lyrics and step logs of old finished workflows to compressed blobs
+	ArchiveDir           string        // empty disables archival
+	ArchiveAfter         time.Duration // finished workflows untouched for this long are archived
+	ArchiveCheckInterval time.Duration
+
+	// Mirroring of completed workflows into a cloud folder, one subfolder per project
+	SyncTarget   string // dropbox:/folder or dir:/path (a folder synced by a desktop client), empty disables sync
+	SyncMode     string // one-way (the folder mirrors the server) or two-way (remote edits are kept)
+	SyncInterval time.Duration
+	DropboxToken string
+
+	// Reverse proxy: client IPs are read from ProxyHeader on requests from TrustedProxies
+	ProxyHeader    string // e.g. CF-Connecting-IP or X-Forwarded-For, empty to use the peer address
+	TrustedProxies []string
+
+	// CORS of the JSON API, for single-page apps and browser extensions served from other origins
+	CORSAllowedOrigins []string      // e.g. https://app.example.com or https://*.example.com, empty disables CORS
+	CORSAllowedMethods []string      // methods granted to preflight requests
+	CORSMaxAge         time.Duration // how long browsers may cache a preflight answer
+
+	// Rate limits of the endpoints that start workflows (and spend OpenAI and Suno credits)
+	StartRateLimitRPS         float64 // per client IP and per signed-in user, 0 disables it
+	StartRateLimitBurst       int
+	StartRateLimitGlobalRPS   float64 // across all clients, 0 disables it
+	StartRateLimitGlobalBurst int
+
+	// Media proxy (/workflow/:id/audio and /video)
+	MediaCacheDir string // generated files are kept here after the first download, empty streams them every time
+	ResultsDir    string // every completed song is archived under <dir>/<workflow id>/, empty disables it
+
+	// Access log (separate from the journal, sensitive query values redacted)
+	AccessLogDir       string        // empty disables it
+	AccessLogRetention time.Duration // daily files older than this are deleted, 0 keeps them
+
+	// Render cache of list pages, cleared by workflow events
+	RenderCacheTTL  time.Duration // 0 disables it
+	RenderCacheSize int           // maximum number of cached pages
+
+	// Display
+	DisplayTimezone string
+	DisplayLocation *time.Location // resolved from DisplayTimezone
+	LocaleName      string         // locale of dates, numbers and durations of pages, messages and exports
+	Locale          locale.Locale  // resolved from LocaleName
 
 	// OpenAI
-	OpenAIAPIKey string
-	OpenAIModel  string
+	OpenAIAPIKey                 string   // first key of OpenAIAPIKeys
+	OpenAIAPIKeys                []string // key pool used round-robin (see OpenAIKeys)
+	OpenAIModel                  string
+	OpenAIBaseURL                string  // OpenAI-compatible API the LLM steps use
+	OpenAIPromptPricePerMTok     float64 // USD per 1M prompt tokens
+	OpenAICompletionPricePerMTok float64 // USD per 1M completion tokens
+	EnableModeration             bool    // check task descriptions and lyrics before generation
+	ModerationModel              string
+	LyricsEngine                 string // default lyrics drafting engine: "openai" or "suno"
+	HouseStyle                   string // style guide appended to every LLM system prompt, overridable on /admin
+	TranscriptMaxChars           int    // pasted transcripts longer than this are rejected
+	StructureTolerance           int    // syllables a line may differ from the source lyrics line it matches
+	StructureAttempts            int    // drafts generated before lyrics not matching the source lyrics fail the step
+
+	// Screening of titles and style tags before review (artist names Suno rejects, trademarks, profanity)
+	ScreeningDefaults bool              // include the built-in list
+	ScreeningTerms    map[string]string // more terms -> suggested wording, "" to suggest removing the term
+
+	// Similarity screening of the lyrics under review against known lyrics
+	SimilarityCorpusDir string  // directory of .txt lyrics to compare with, empty disables the corpus check
+	SimilarityThreshold float64 // share of a passage's word sequences (or LLM confidence) that flags it
+	SimilarityLLM       bool    // also ask the LLM for passages copied from published songs
+
+	// Usage-based invoices of client work per project (GET /reports/invoice)
+	InvoiceIssuer            string  // name printed at the top of PDF invoices
+	InvoiceCreditPriceUSD    float64 // USD billed per Suno credit
+	InvoiceStoragePricePerGB float64 // USD billed per GB of uploaded reference audio
+
+	// Engine guards; breaches fail the workflow and notify the admin channel
+	LLMStepTimeout            time.Duration // hard timeout of one LLM call, 0 disables it
+	MaxTokensPerWorkflow      int           // LLM tokens one workflow may use, 0 for no cap
+	MaxSunoSubmissionsPerHour int           // Suno generations across all workflows, 0 for no cap
 
 	// Suno (via suno-api server)
-	SunoBaseURL string
+	SunoBaseURL              string
+	SunoTransportName        string         // "proxy" (self-hosted suno-api) or "hosted" (bearer token)
+	SunoTransport            suno.Transport // resolved from SunoTransportName
+	SunoAPIToken             string         // bearer token of the hosted transport
+	SunoCreditsPerGeneration int
+	SunoModel                string         // model of new songs, e.g. chirp-v4 (overridable per workflow)
+	SunoModels               []string       // models offered on the start and review forms, SunoModel included
+	SunoHealthInterval       time.Duration  // how often the session is validated, 0 disables the monitor
+	SunoQuotaInterval        time.Duration  // how often the credits are recorded for the usage history, 0 disables it
+	SunoLowCredits           int            // credits left below which a warning is sent, 0 for none (overridable on /admin)
+	SunoPollInterval         time.Duration  // how often a submitted clip is polled until it completes
+	SunoNotFoundGrace        time.Duration  // a submitted clip suno-api does not list yet counts as pending for this long
+	SunoCallbackSecret       string         // shared secret of POST /suno/callback, empty disables callbacks
+	SunoCallbackPollInterval time.Duration  // fallback polling interval while callbacks are enabled
+	SunoRequestTimeout       time.Duration  // bounds a single HTTP attempt to suno-api
+	SunoMaxAttempts          int            // attempts per request on network errors, 429 and 5xx
+	SunoRetryBackoff         time.Duration  // wait before the first retry, doubled for each further one
+	SunoBreakerThreshold     int            // consecutive failed requests that open the circuit breaker, 0 disables it
+	SunoBreakerCooldown      time.Duration  // how long an open circuit fails fast before suno-api is probed again
+	SunoRateLimit            int            // suno-api requests per minute and deployment (account), 0 disables the limit
+	SunoRateBurst            int            // requests sent at once before SunoRateLimit spaces them out
+	SunoAccounts             []SunoAccount  // several suno-api deployments used as a pool instead of SunoBaseURL
+	SunoAccountSelection     suno.Selection // which pool account a new generation goes to
+	GenerateStems            bool           // default for the per-workflow "generate stems" option
+	LongSongSegmentChars     int            // lyrics longer than this are generated as a long song (generate, extend, concat)
+	LyricsMaxChars           int            // lyrics longer than this cannot be submitted
+
+	// Style tag taxonomy (autocomplete, unknown tag hints, keep-rate report)
+	TagSyncInterval time.Duration // how often the taxonomy is rebuilt, 0 disables the sync
+	TagSyncPages    int           // pages of the Suno library feed read per sync, 0 for none
 
 	// Telegram
 	TelegramBotToken      string
 	TelegramChatID        string
 	TelegramWebhookPath   string
 	TelegramWebhookSecret string
-	TelegramWebhookURL    string
+	TelegramWebhookURL    string        // defaults to BASE_URL + TelegramWebhookPath for an HTTPS BASE_URL
+	TelegramWebhookCheck  time.Duration // how often the registered webhook is compared with TelegramWebhookURL, 0 checks at startup only
+	MessagesDir           string        // *.tmpl files overriding the embedded message catalogs, empty for none
+	MessagesLanguage      string        // catalog of chats without a /language preference, e.g. en or es
+
+	// Identity and review assignment
+	SecretKey  string   // signs identity cookies and review links; random per process if unset
+	AdminUsers []string // identities allowed to reassign reviews (web names or "tg:<chat id>")
+	AdminToken string   // required to claim an admin web identity
+
+	// Shared instances: /workflows only lists the workflows started in the visitor's browser
+	// (and those assigned to them); admins see all
+	SessionScopedList bool
+
+	// Roles (see package users); identities not listed get DefaultRole
+	ReviewerUsers []string
+	ViewerUsers   []string
+	DefaultRole   users.Role // viewer or reviewer
+
+	// Web UI login: with either set, every page except /health and /metrics requires a session
+	LoginPassword       string            // shared password for any non-admin name (admins sign in with ADMIN_TOKEN)
+	LoginUsers          map[string]string // user accounts: name -> bcrypt hash
+	SessionTTL          time.Duration     // lifetime of a login session
+	LoginRateLimitRPS   float64           // sign-in attempts per client IP, 0 disables the limit
+	LoginRateLimitBurst int
+
+	// Review escalation: assignee -> backup reviewer -> admin channel
+	EscalationAfter  time.Duration // time a reviewer has before escalation, 0 disables it
+	BackupReviewer   string        // identity the review is reassigned to on the first escalation
+	EscalationChatID string        // admin channel notified on the final escalation
+
+	// Expiry of drafts never reviewed: awaiting_review for this long becomes expired, 0 disables it
+	WorkflowExpireAfter time.Duration
+	ExpiryPurge         bool // drop the transcript and lyrics of expired workflows
+
+	// Outbound webhooks
+	WebhookURLs        []string
+	WebhookSecret      string
+	WebhookMaxAttempts int
+
+	// Deploy webhook: CI pushes releases to /admin/deploy-webhook, disabled when the secret is empty
+	DeployWebhookSecret string
+	DeployDrainTimeout  time.Duration // how long a restart waits for workflows being prepared or submitted to Suno
+
+	// Review webhook: external review tools approve or reject via /hooks/review, disabled when the secret is empty
+	ReviewWebhookSecret string
+	ReviewWebhookFields map[string]string // review field -> JSON path in the payload, e.g. lyrics:data.lyrics
 
 	// Workflow
 	EnablePremiumFeatures bool
+	AutoApprove           bool // approve workflows without lyrics issues or screening hits as proposed
 	MaxAudioSizeMB        int
+	AudioURLTimeout       time.Duration // limit for fetching a reference track given as audio_url
+	AudioURLAllowPrivate  bool          // let audio_url reach private and loopback addresses (trusted networks only)
+	UploadURLTTL          time.Duration // lifetime of the signed links to uploaded reference audio
+	NamingTemplate        string
+	IDScheme              string // "uuid4" (random) or "uuid7" (time-ordered) IDs of new workflows
+	DefaultLanguage       string
+	LanguageDetection     bool // workflows started without a language get the one their text is written in
+
+	// Due dates, reminders and digests
+	ReminderCheckInterval time.Duration
+	ReminderLeadTime      time.Duration
+	DigestHour            int // hour of day (display time zone) for the daily digest, -1 disables it
 }
 
 // Load reads configuration from environment variables
+// Values are taken from the environment, then from the APP_ENV profile, then from the
+// built-in defaults.
 func Load() *Config {
-	return &Config{
+	appEnv := loadProfile(getEnv("APP_ENV", DefaultAppEnv))
+	cfg := &Config{
+		AppEnv:   appEnv,
+		LogLevel: getEnvLogLevel("LOG_LEVEL", slog.LevelInfo),
+		DryRun:   getEnvBool("DRY_RUN", false),
+
 		// Server
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		BaseURL:    getEnv("BASE_URL", "http://localhost:8080"),
+		StoreFile:  getEnv("STORE_FILE", ""),
+
+		// Archival
+		ArchiveDir:           getEnv("ARCHIVE_DIR", ""),
+		ArchiveAfter:         getEnvDuration("ARCHIVE_AFTER", 30*24*time.Hour),
+		ArchiveCheckInterval: getEnvDuration("ARCHIVE_CHECK_INTERVAL", time.Hour),
+
+		// Cloud sync
+		SyncTarget:   getEnv("SYNC_TARGET", ""),
+		SyncMode:     getEnv("SYNC_MODE", "one-way"),
+		SyncInterval: getEnvDuration("SYNC_INTERVAL", 15*time.Minute),
+		DropboxToken: getEnv("DROPBOX_TOKEN", ""),
+
+		// Reverse proxy
+		ProxyHeader:    getEnv("PROXY_HEADER", ""),
+		TrustedProxies: getEnvListDefault("TRUSTED_PROXIES", []string{"127.0.0.1", "::1"}),
+
+		// CORS
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS"),
+		CORSAllowedMethods: getEnvListDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "DELETE"}),
+		CORSMaxAge:         getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+
+		// Start rate limits
+		StartRateLimitRPS:         getEnvFloat("START_RATE_LIMIT_RPS", 0.1),
+		StartRateLimitBurst:       getEnvInt("START_RATE_LIMIT_BURST", 5),
+		StartRateLimitGlobalRPS:   getEnvFloat("START_RATE_LIMIT_GLOBAL_RPS", 1),
+		StartRateLimitGlobalBurst: getEnvInt("START_RATE_LIMIT_GLOBAL_BURST", 20),
+
+		// Media proxy
+		MediaCacheDir: getEnv("MEDIA_CACHE_DIR", ""),
+		ResultsDir:    getEnv("RESULTS_DIR", ""),
+
+		// Access log
+		AccessLogDir:       getEnv("ACCESS_LOG_DIR", ""),
+		AccessLogRetention: getEnvDuration("ACCESS_LOG_RETENTION", 14*24*time.Hour),
+
+		// Render cache
+		RenderCacheTTL:  getEnvDuration("RENDER_CACHE_TTL", 10*time.Second),
+		RenderCacheSize: getEnvInt("RENDER_CACHE_SIZE", 100),
+
+		// Display
+		DisplayTimezone: getEnv("DISPLAY_TIMEZONE", "Local"),
+		LocaleName:      getEnv("LOCALE", locale.Default),
 
 		// OpenAI
-		OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
-		OpenAIModel:  getEnv("OPENAI_MODEL", "gpt-4o"),
+		OpenAIAPIKeys:                OpenAIKeys(),
+		OpenAIModel:                  getEnv("OPENAI_MODEL", "gpt-4o"),
+		OpenAIBaseURL:                getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIPromptPricePerMTok:     getEnvFloat("OPENAI_PROMPT_PRICE_PER_MTOK", 2.50),
+		OpenAICompletionPricePerMTok: getEnvFloat("OPENAI_COMPLETION_PRICE_PER_MTOK", 10.00),
+		EnableModeration:             getEnvBool("ENABLE_MODERATION", true),
+		ModerationModel:              getEnv("OPENAI_MODERATION_MODEL", "omni-moderation-latest"),
+		LyricsEngine:                 getEnv("LYRICS_ENGINE", "openai"),
+		HouseStyle:                   strings.ReplaceAll(getEnv("HOUSE_STYLE", ""), `\n`, "\n"),
+		TranscriptMaxChars:           getEnvInt("TRANSCRIPT_MAX_CHARS", 30000),
+		StructureTolerance:           getEnvInt("LYRICS_STRUCTURE_TOLERANCE", 1),
+		StructureAttempts:            getEnvInt("LYRICS_STRUCTURE_ATTEMPTS", 3),
+
+		// Screening
+		ScreeningDefaults: getEnvBool("SCREENING_DEFAULTS", true),
+		ScreeningTerms:    getEnvTerms("SCREENING_TERMS"),
+
+		// Similarity screening
+		SimilarityCorpusDir: getEnv("SIMILARITY_CORPUS_DIR", ""),
+		SimilarityThreshold: getEnvFloat("SIMILARITY_THRESHOLD", 0.6),
+		SimilarityLLM:       getEnvBool("SIMILARITY_LLM", false),
+
+		// Invoices
+		InvoiceIssuer:            getEnv("INVOICE_ISSUER", ""),
+		InvoiceCreditPriceUSD:    getEnvFloat("INVOICE_CREDIT_PRICE_USD", 0),
+		InvoiceStoragePricePerGB: getEnvFloat("INVOICE_STORAGE_PRICE_PER_GB", 0),
+
+		// Engine guards
+		LLMStepTimeout:            getEnvDuration("LLM_STEP_TIMEOUT", 3*time.Minute),
+		MaxTokensPerWorkflow:      getEnvInt("MAX_TOKENS_PER_WORKFLOW", 0),
+		MaxSunoSubmissionsPerHour: getEnvInt("MAX_SUNO_SUBMISSIONS_PER_HOUR", 0),
 
 		// Suno (via suno-api server - see lib/suno/README.md for setup)
-		SunoBaseURL: getEnv("SUNO_BASE_URL", "http://localhost:3000"),
+		SunoBaseURL:              getEnv("SUNO_BASE_URL", "http://localhost:3000"),
+		SunoTransportName:        getEnv("SUNO_TRANSPORT", string(suno.TransportProxy)),
+		SunoAPIToken:             getEnv("SUNO_API_TOKEN", ""),
+		SunoCreditsPerGeneration: getEnvInt("SUNO_CREDITS_PER_GENERATION", 10),
+		SunoModel:                getEnv("SUNO_MODEL", suno.DefaultModel),
+		SunoModels:               getEnvListDefault("SUNO_MODELS", suno.Models),
+		SunoHealthInterval:       getEnvDuration("SUNO_HEALTH_INTERVAL", 5*time.Minute),
+		SunoQuotaInterval:        getEnvDuration("SUNO_QUOTA_INTERVAL", time.Hour),
+		SunoLowCredits:           getEnvInt("SUNO_LOW_CREDITS", 100),
+		SunoPollInterval:         getEnvDuration("SUNO_POLL_INTERVAL", 5*time.Second),
+		SunoNotFoundGrace:        getEnvDuration("SUNO_NOT_FOUND_GRACE", 30*time.Second),
+		SunoCallbackSecret:       getEnv("SUNO_CALLBACK_SECRET", ""),
+		SunoCallbackPollInterval: getEnvDuration("SUNO_CALLBACK_POLL_INTERVAL", time.Minute),
+		SunoRequestTimeout:       getEnvDuration("SUNO_REQUEST_TIMEOUT", 300*time.Second),
+		SunoMaxAttempts:          getEnvInt("SUNO_MAX_ATTEMPTS", 3),
+		SunoRetryBackoff:         getEnvDuration("SUNO_RETRY_BACKOFF", 2*time.Second),
+		SunoBreakerThreshold:     getEnvInt("SUNO_BREAKER_THRESHOLD", 5),
+		SunoBreakerCooldown:      getEnvDuration("SUNO_BREAKER_COOLDOWN", time.Minute),
+		SunoRateLimit:            getEnvInt("SUNO_RATE_LIMIT", 60),
+		SunoRateBurst:            getEnvInt("SUNO_RATE_BURST", 10),
+		SunoAccounts:             sunoAccounts(getEnvList("SUNO_ACCOUNTS"), getEnvMap("SUNO_ACCOUNT_TOKENS")),
+		GenerateStems:            getEnvBool("GENERATE_STEMS", false),
+		LongSongSegmentChars:     getEnvInt("LONG_SONG_SEGMENT_CHARS", 1200),
+		LyricsMaxChars:           getEnvInt("LYRICS_MAX_CHARS", 5000),
+
+		// Style tags
+		TagSyncInterval: getEnvDuration("TAG_SYNC_INTERVAL", 24*time.Hour),
+		TagSyncPages:    getEnvInt("TAG_SYNC_PAGES", 5),
 
 		// Telegram
 		TelegramBotToken:      getEnv("TELEGRAM_BOT_TOKEN", ""),
@@ -50,22 +357,294 @@ func Load() *Config {
 		TelegramWebhookPath:   getEnv("TELEGRAM_WEBHOOK_PATH", "/telegram/webhook"),
 		TelegramWebhookSecret: getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
 		TelegramWebhookURL:    getEnv("TELEGRAM_WEBHOOK_URL", ""),
+		TelegramWebhookCheck:  getEnvDuration("TELEGRAM_WEBHOOK_CHECK_INTERVAL", 10*time.Minute),
+		MessagesDir:           getEnv("MESSAGES_DIR", ""),
+		MessagesLanguage:      getEnv("MESSAGES_LANGUAGE", "en"),
+
+		// Identity and review assignment
+		SecretKey:  getEnv("SECRET_KEY", ""),
+		AdminUsers: getEnvList("ADMIN_USERS"),
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+
+		SessionScopedList: getEnvBool("SESSION_SCOPED_LIST", false),
+
+		// Roles
+		ReviewerUsers: getEnvList("REVIEWER_USERS"),
+		ViewerUsers:   getEnvList("VIEWER_USERS"),
+
+		// Web UI login
+		LoginPassword: getEnv("LOGIN_PASSWORD", ""),
+		LoginUsers:    getEnvMap("LOGIN_USERS"),
+		SessionTTL:    getEnvDuration("SESSION_TTL", 7*24*time.Hour),
+
+		LoginRateLimitRPS:   getEnvFloat("LOGIN_RATE_LIMIT_RPS", 0.1),
+		LoginRateLimitBurst: getEnvInt("LOGIN_RATE_LIMIT_BURST", 10),
+
+		// Review escalation
+		EscalationAfter:  getEnvDuration("ESCALATION_AFTER", 0),
+		BackupReviewer:   getEnv("BACKUP_REVIEWER", ""),
+		EscalationChatID: getEnv("ESCALATION_CHAT_ID", getEnv("TELEGRAM_CHAT_ID", "")),
+
+		// Draft expiry
+		WorkflowExpireAfter: getEnvDuration("WORKFLOW_EXPIRE_AFTER", 0),
+		ExpiryPurge:         getEnvBool("EXPIRY_PURGE", false),
+
+		// Outbound webhooks
+		WebhookURLs:        getEnvList("WEBHOOK_URLS"),
+		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+
+		// Deploy webhook
+		DeployWebhookSecret: getEnv("DEPLOY_WEBHOOK_SECRET", ""),
+		DeployDrainTimeout:  getEnvDuration("DEPLOY_DRAIN_TIMEOUT", 2*time.Minute),
+
+		// Review webhook
+		ReviewWebhookSecret: getEnv("REVIEW_WEBHOOK_SECRET", ""),
+		ReviewWebhookFields: getEnvMap("REVIEW_WEBHOOK_FIELDS"),
 
 		// Workflow
 		EnablePremiumFeatures: getEnvBool("ENABLE_PREMIUM_FEATURES", false),
+		AutoApprove:           getEnvBool("AUTO_APPROVE", false),
 		MaxAudioSizeMB:        getEnvInt("MAX_AUDIO_SIZE_MB", 50),
+		AudioURLTimeout:       getEnvDuration("AUDIO_URL_TIMEOUT", 2*time.Minute),
+		AudioURLAllowPrivate:  getEnvBool("AUDIO_URL_ALLOW_PRIVATE", false),
+		UploadURLTTL:          getEnvDuration("UPLOAD_URL_TTL", time.Hour),
+		NamingTemplate:        getEnv("NAMING_TEMPLATE", DefaultNamingTemplate),
+		IDScheme:              getEnv("ID_SCHEME", "uuid4"),
+		DefaultLanguage:       getEnv("DEFAULT_LANGUAGE", "English"),
+		LanguageDetection:     getEnvBool("LANGUAGE_DETECTION", true),
+
+		// Due dates, reminders and digests
+		ReminderCheckInterval: getEnvDuration("REMINDER_CHECK_INTERVAL", 15*time.Minute),
+		ReminderLeadTime:      getEnvDuration("REMINDER_LEAD_TIME", 24*time.Hour),
+		DigestHour:            getEnvInt("DIGEST_HOUR", 9),
+	}
+
+	if len(cfg.OpenAIAPIKeys) > 0 {
+		cfg.OpenAIAPIKey = cfg.OpenAIAPIKeys[0]
+	}
+
+	loc, err := timefmt.LoadLocation(cfg.DisplayTimezone)
+	if err != nil {
+		slog.Warn("Invalid DISPLAY_TIMEZONE, using server time zone", "error", err)
+		loc = time.Local
+	}
+	cfg.DisplayLocation = loc
+
+	cfg.SunoTransport, err = suno.ParseTransport(cfg.SunoTransportName)
+	if err != nil {
+		slog.Warn("Invalid SUNO_TRANSPORT, using proxy", "error", err)
+		cfg.SunoTransport = suno.TransportProxy
+	}
+	if cfg.SunoTransport == suno.TransportHosted && cfg.SunoAPIToken == "" {
+		slog.Warn("SUNO_TRANSPORT=hosted needs SUNO_API_TOKEN, using proxy")
+		cfg.SunoTransport = suno.TransportProxy
+	}
+
+	cfg.SunoAccountSelection, err = suno.ParseSelection(getEnv("SUNO_ACCOUNT_SELECTION", ""))
+	if err != nil {
+		slog.Warn("Invalid SUNO_ACCOUNT_SELECTION, using "+string(suno.SelectRoundRobin), "error", err)
+		cfg.SunoAccountSelection = suno.SelectRoundRobin
+	}
+
+	if !slices.Contains(cfg.SunoModels, cfg.SunoModel) {
+		cfg.SunoModels = append(slices.Clone(cfg.SunoModels), cfg.SunoModel)
+	}
+
+	cfg.Locale, err = locale.Lookup(cfg.LocaleName)
+	if err != nil {
+		slog.Warn("Invalid LOCALE, using "+locale.Default, "error", err)
+		cfg.Locale = locale.MustLookup(locale.Default)
+	}
+
+	// Listing reviewers or viewers means the others are not reviewers
+	defaultRole := users.RoleReviewer
+	if len(cfg.ReviewerUsers) > 0 || len(cfg.ViewerUsers) > 0 {
+		defaultRole = users.RoleViewer
+	}
+	role, ok := users.ParseRole(getEnv("DEFAULT_ROLE", string(defaultRole)))
+	if !ok || role == users.RoleAdmin {
+		slog.Warn("Invalid DEFAULT_ROLE (viewer or reviewer), using "+string(defaultRole), "value", getEnv("DEFAULT_ROLE", ""))
+		role = defaultRole
+	}
+	cfg.DefaultRole = role
+
+	// Without login, web names are chosen by the visitors themselves: only admin names are checked
+	// (ADMIN_TOKEN), so the roles of all other names are open to anyone
+	if !cfg.LoginRequired() {
+		switch {
+		case role == users.RoleReviewer:
+			slog.Warn("Login is off and DEFAULT_ROLE is reviewer: every visitor can start and review workflows; set LOGIN_PASSWORD or LOGIN_USERS, or DEFAULT_ROLE=viewer")
+		case len(cfg.ReviewerUsers) > 0:
+			slog.Warn("Login is off: web names are not verified, so anyone can pick a name from REVIEWER_USERS and review; set LOGIN_PASSWORD or LOGIN_USERS")
+		}
+	}
+
+	cfg.CORSAllowedOrigins = slices.DeleteFunc(cfg.CORSAllowedOrigins, func(origin string) bool {
+		if !validOrigin(origin) {
+			slog.Warn("Invalid CORS_ALLOWED_ORIGINS entry (scheme://host[:port], * only as a subdomain), ignored", "value", origin)
+			return true
+		}
+		return false
+	})
+
+	if cfg.SyncMode != "one-way" && cfg.SyncMode != "two-way" {
+		slog.Warn("Invalid SYNC_MODE (one-way or two-way), using one-way", "value", cfg.SyncMode)
+		cfg.SyncMode = "one-way"
 	}
+
+	if cfg.OpenAIAPIKey == "" {
+		slog.Warn("OPENAI_API_KEY not set, lyrics are drafted by Suno and moderation, properties, bracket and persona steps are skipped")
+		cfg.LyricsEngine = "suno"
+	}
+
+	// Telegram can only deliver to HTTPS, so a plain-HTTP BASE_URL gets no webhook
+	if cfg.TelegramWebhookURL == "" && strings.HasPrefix(cfg.BaseURL, "https://") {
+		cfg.TelegramWebhookURL = cfg.WebhookURLFor(cfg.BaseURL)
+	}
+
+	if cfg.SecretKey == "" {
+		slog.Warn("SECRET_KEY not set, identity cookies and review links will not survive a restart")
+		cfg.SecretKey = RandomSecret()
+	}
+
+	return cfg
 }
 
-func getEnv(key, defaultValue string) string {
+// validOrigin accepts a browser origin (scheme://host[:port]) or a pattern of subdomains
+// (https://*.example.com); a bare * is refused, as API calls carry the identity cookie
+func validOrigin(origin string) bool {
+	origin = strings.Replace(origin, "://*.", "://", 1)
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(u.Host, "*") {
+		return false
+	}
+	return (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// setupKeys are the main settings; with none of them in the environment the server is
+// unconfigured and starts the setup wizard
+var setupKeys = []string{
+	"OPENAI_API_KEY", "OPENAI_API_KEYS", "SUNO_BASE_URL", "TELEGRAM_BOT_TOKEN",
+	"ADMIN_USERS", "ADMIN_TOKEN", "LOGIN_PASSWORD", "LOGIN_USERS",
+}
+
+// NeedsSetup reports whether the server runs without configuration (call it after loading
+// .env); SETUP_WIZARD=false turns the first-run wizard off
+func NeedsSetup() bool {
+	if !getEnvBool("SETUP_WIZARD", true) {
+		return false
+	}
+	for _, key := range setupKeys {
+		if os.Getenv(key) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// LoginRequired reports whether the web UI requires signing in (LOGIN_PASSWORD or LOGIN_USERS)
+func (c *Config) LoginRequired() bool {
+	return c.LoginPassword != "" || len(c.LoginUsers) > 0
+}
+
+// OpenAIKeys returns the OpenAI key pool from the environment: OPENAI_API_KEY followed by
+// the comma-separated OPENAI_API_KEYS, without duplicates
+// It is read again on SIGHUP to rotate keys without a restart.
+func OpenAIKeys() []string {
+	var keys []string
+	for _, key := range append([]string{getEnv("OPENAI_API_KEY", "")}, getEnvList("OPENAI_API_KEYS")...) {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SunoAccount is one suno-api deployment of the account pool (SUNO_ACCOUNTS)
+type SunoAccount struct {
+	Name    string
+	BaseURL string
+	Token   string // bearer token of the hosted transport, SUNO_API_TOKEN when empty
+}
+
+// sunoAccounts parses SUNO_ACCOUNTS entries of the form name=URL; tokens maps names to their
+// hosted transport token (SUNO_ACCOUNT_TOKENS). Entries without a name or URL and repeated
+// names are ignored.
+func sunoAccounts(entries []string, tokens map[string]string) []SunoAccount {
+	var accounts []SunoAccount
+	for _, entry := range entries {
+		name, url, _ := strings.Cut(entry, "=")
+		name, url = strings.TrimSpace(name), strings.TrimSpace(url)
+		if name == "" || url == "" || slices.ContainsFunc(accounts, func(a SunoAccount) bool { return a.Name == name }) {
+			slog.Warn("Ignoring SUNO_ACCOUNTS entry", "entry", entry)
+			continue
+		}
+		accounts = append(accounts, SunoAccount{Name: name, BaseURL: url, Token: tokens[name]})
+	}
+	return accounts
+}
+
+// WebhookURLFor returns the Telegram webhook URL under baseURL (TELEGRAM_WEBHOOK_PATH)
+func (c *Config) WebhookURLFor(baseURL string) string {
+	path := strings.TrimSpace(c.TelegramWebhookPath)
+	if path == "" {
+		path = "/telegram/webhook"
+	} else if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.TrimRight(baseURL, "/") + path
+}
+
+// HasOpenAI reports whether an OpenAI API key is configured
+func (c *Config) HasOpenAI() bool {
+	return c.OpenAIAPIKey != ""
+}
+
+// Profiles returns the names of the embedded APP_ENV profiles
+func Profiles() []string {
+	files, _ := profileFiles.ReadDir("profiles")
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(file.Name(), ".env"))
+	}
+	return names
+}
+
+// loadProfile selects the defaults of a profile and returns its name
+// An unknown profile is reported and replaced by DefaultAppEnv, the one with the fewest surprises.
+func loadProfile(name string) string {
+	data, err := profileFiles.ReadFile("profiles/" + name + ".env")
+	if err != nil {
+		slog.Error("Unknown APP_ENV, using the default profile", "app_env", name, "profiles", Profiles(), "default", DefaultAppEnv)
+		name = DefaultAppEnv
+		data, _ = profileFiles.ReadFile("profiles/" + name + ".env")
+	}
+	values, err := godotenv.UnmarshalBytes(data)
+	if err != nil {
+		slog.Error("Invalid profile", "app_env", name, "error", err)
+	}
+	profile = values
+	return name
+}
+
+// lookupEnv returns the value of an environment variable, or the profile's default for it
+func lookupEnv(key string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+	return profile[key]
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := lookupEnv(key); value != "" {
+		return value
+	}
 	return defaultValue
 }
 
 func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		b, err := strconv.ParseBool(value)
 		if err == nil {
 			return b
@@ -75,7 +654,7 @@ func getEnvBool(key string, defaultValue bool) bool {
 }
 
 func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		i, err := strconv.Atoi(value)
 		if err == nil {
 			return i
@@ -84,3 +663,80 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvLogLevel reads a slog level name: debug, info, warn or error
+func getEnvLogLevel(key string, defaultValue slog.Level) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(lookupEnv(key))); err != nil {
+		return defaultValue
+	}
+	return level
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := lookupEnv(key); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := lookupEnv(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, dropping empty entries
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(lookupEnv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvListDefault reads a comma-separated list, defaultValue when the variable is not set
+func getEnvListDefault(key string, defaultValue []string) []string {
+	if lookupEnv(key) == "" {
+		return defaultValue
+	}
+	return getEnvList(key)
+}
+
+// getEnvMap parses a comma-separated list of key:value pairs; entries without a colon are ignored
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		if k, v, ok := strings.Cut(item, ":"); ok && strings.TrimSpace(k) != "" {
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return result
+}
+
+// getEnvTerms parses a comma-separated list of term:replacement pairs; the replacement may be omitted
+func getEnvTerms(key string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		term, replacement, _ := strings.Cut(item, ":")
+		if term = strings.TrimSpace(term); term != "" {
+			result[term] = strings.TrimSpace(replacement)
+		}
+	}
+	return result
+}
+
+// RandomSecret returns a random hex-encoded 32-byte secret
+func RandomSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
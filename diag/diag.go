@@ -0,0 +1,300 @@
+// Package diag collects a diagnostics bundle for bug reports: version, sanitized configuration,
+// health probes of the running server, recent errors, queue depth and store statistics
+package diag
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"workflower/config"
+	"workflower/lib/accesslog"
+	"workflower/storage"
+)
+
+const (
+	// probeTimeout bounds each request to the running server
+	probeTimeout = 30 * time.Second
+	// journalLines is how many error lines of the service journal are included
+	journalLines = 300
+	// maxAccessErrors is how many failed requests of the access log are included
+	maxAccessErrors = 200
+	// maxRecentFailures is how many failed workflows are listed in the store statistics
+	maxRecentFailures = 20
+	// redacted replaces secrets in the bundle
+	redacted = "[REDACTED]"
+)
+
+// secretFields matches configuration fields holding credentials
+var secretFields = regexp.MustCompile(`(?i)(key|token|secret|password|cookie)|^LoginUsers$`)
+
+// Options configure a bundle
+type Options struct {
+	ServiceName string // systemd unit whose journal is searched for errors
+	ServerURL   string // running server to probe, e.g. http://localhost:8080
+}
+
+// Collect writes the diagnostics bundle of the configured server to w as tar.gz
+// Failing sources are recorded in the bundle instead of failing it.
+func Collect(ctx context.Context, cfg *config.Config, opts Options, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	prefix := "workflower-diag-" + now.Format("20060102-150405") + "/"
+
+	files := []struct {
+		name    string
+		collect func() ([]byte, error)
+	}{
+		{"version.json", func() ([]byte, error) { return versionInfo(now) }},
+		{"config.json", func() ([]byte, error) { return json.MarshalIndent(sanitizedConfig(cfg), "", "  ") }},
+		{"health.txt", func() ([]byte, error) { return probe(ctx, opts.ServerURL+"/health?deep=1") }},
+		{"metrics.txt", func() ([]byte, error) { return probe(ctx, opts.ServerURL+"/metrics") }},
+		{"store.json", func() ([]byte, error) { return storeStats(cfg, now) }},
+		{"journal-errors.log", func() ([]byte, error) { return journalErrors(ctx, opts.ServiceName) }},
+		{"access-errors.jsonl", func() ([]byte, error) { return accessErrors(cfg.AccessLogDir) }},
+	}
+	for _, file := range files {
+		data, err := file.collect()
+		if err != nil {
+			data = fmt.Appendf(data, "\ncollection failed: %v\n", err)
+		}
+		header := &tar.Header{Name: prefix + file.name, Mode: 0o644, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func versionInfo(now time.Time) ([]byte, error) {
+	hostname, _ := os.Hostname()
+	executable, _ := os.Executable()
+	return json.MarshalIndent(map[string]any{
+		"version":    config.Version,
+		"go":         runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"cpus":       runtime.NumCPU(),
+		"hostname":   hostname,
+		"executable": executable,
+		"collected":  now.Format(time.RFC3339),
+	}, "", "  ")
+}
+
+// sanitizedConfig returns the configuration with credentials replaced and URLs reduced to their
+// host, as webhook URLs often carry tokens in their path
+func sanitizedConfig(cfg *config.Config) map[string]any {
+	result := make(map[string]any)
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		switch value.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map:
+			if secretFields.MatchString(field.Name) {
+				if value.Len() > 0 {
+					result[field.Name] = redacted
+				} else {
+					result[field.Name] = ""
+				}
+				continue
+			}
+		case reflect.Pointer:
+			continue // DisplayLocation, derived from DisplayTimezone
+		}
+		switch x := value.Interface().(type) {
+		case string:
+			result[field.Name] = sanitizeURL(x)
+		case []string:
+			list := make([]string, len(x))
+			for i, s := range x {
+				list[i] = sanitizeURL(s)
+			}
+			result[field.Name] = list
+		case time.Duration:
+			result[field.Name] = x.String()
+		default:
+			result[field.Name] = x
+		}
+	}
+	return result
+}
+
+// sanitizeURL keeps the scheme and host of URLs with a path, query or credentials
+func sanitizeURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return s
+	}
+	if u.User == nil && u.RawQuery == "" && (u.Path == "" || u.Path == "/") {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// probe fetches an endpoint of the running server; the answer is kept whatever its status
+func probe(ctx context.Context, target string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("server not reachable at %s: %w", target, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	return fmt.Appendf(nil, "# GET %s: %s\n%s", target, resp.Status, body), err
+}
+
+// storeStats summarizes the store file: workflows per status, queue depth, archival, webhook
+// deliveries and the errors of the most recent failures
+func storeStats(cfg *config.Config, now time.Time) ([]byte, error) {
+	if cfg.StoreFile == "" {
+		return []byte(`{"store": "in-memory only (STORE_FILE not set)"}`), nil
+	}
+	info, err := os.Stat(cfg.StoreFile)
+	if err != nil {
+		return nil, err
+	}
+	store, err := storage.OpenStore(cfg.StoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	type failure struct {
+		Seq       int       `json:"seq"`
+		Status    string    `json:"status"`
+		Error     string    `json:"error"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	byStatus := make(map[string]int)
+	var total, archived, queued int
+	var oldestReview *time.Time
+	var failures []failure
+	for wf := range store.All() {
+		total++
+		byStatus[wf.Status]++
+		if wf.ArchivedAt != nil {
+			archived++
+		}
+		switch wf.Status {
+		case storage.StatusProcessing, storage.StatusApproved, storage.StatusGenerating, storage.StatusBlockedAuth:
+			queued++
+		case storage.StatusAwaitingReview:
+			if wf.AssignedAt != nil && (oldestReview == nil || wf.AssignedAt.Before(*oldestReview)) {
+				oldestReview = wf.AssignedAt
+			}
+		case storage.StatusFailed, storage.StatusBlockedModeration:
+			if len(failures) < maxRecentFailures {
+				failures = append(failures, failure{Seq: wf.Seq, Status: wf.Status, Error: wf.ErrorMsg, UpdatedAt: wf.UpdatedAt})
+			}
+		}
+	}
+
+	deliveries := store.ListWebhookDeliveries()
+	failedDeliveries := 0
+	for _, d := range deliveries {
+		if !d.Success {
+			failedDeliveries++
+		}
+	}
+
+	stats := map[string]any{
+		"file":               cfg.StoreFile,
+		"file_bytes":         info.Size(),
+		"file_modified":      info.ModTime().Format(time.RFC3339),
+		"workflows":          total,
+		"by_status":          byStatus,
+		"queue_depth":        queued,
+		"awaiting_review":    byStatus[storage.StatusAwaitingReview],
+		"archived":           archived,
+		"audit_entries":      len(store.ListAuditEntries("")),
+		"webhook_deliveries": len(deliveries),
+		"webhook_failures":   failedDeliveries,
+		"recent_failures":    failures,
+	}
+	if oldestReview != nil {
+		stats["oldest_review_since"] = oldestReview.Format(time.RFC3339)
+		stats["oldest_review_age"] = now.Sub(*oldestReview).Round(time.Minute).String()
+	}
+	return json.MarshalIndent(stats, "", "  ")
+}
+
+// journalErrors returns the warnings and errors the service logged in the last day
+func journalErrors(ctx context.Context, service string) ([]byte, error) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return []byte("journalctl not available; attach the server's log output instead\n"), nil
+	}
+	out, err := exec.CommandContext(ctx, "journalctl", "-u", service, "-p", "warning",
+		"--since", "-24h", "-n", fmt.Sprint(journalLines), "--no-pager", "-o", "short-iso").CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("journalctl: %w", err)
+	}
+	return out, nil
+}
+
+// accessErrors returns the failed (5xx) requests of the two most recent access log files
+func accessErrors(dir string) ([]byte, error) {
+	if dir == "" {
+		return []byte("access log disabled (ACCESS_LOG_DIR not set)\n"), nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	if len(files) > 2 {
+		files = files[len(files)-2:]
+	}
+
+	var lines []string
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry accesslog.Entry
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Status >= 500 {
+				lines = append(lines, scanner.Text())
+			}
+		}
+		_ = f.Close()
+	}
+	if len(lines) > maxAccessErrors {
+		lines = lines[len(lines)-maxAccessErrors:]
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
@@ -0,0 +1,319 @@
+// Package evaluate replays stored workflows through a new version of the prompts and compares
+// what it produces with the content reviewers approved, so that prompts can be iterated on real
+// requests without running workflows: it is a dry run, nothing is saved and Suno is not called
+package evaluate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"workflower/config"
+	"workflower/storage"
+	"workflower/templates/prompts"
+	"workflower/workflow"
+)
+
+// Options configure a run
+type Options struct {
+	PromptsDir  string   // prompt files replacing the embedded ones, named as in templates/prompts
+	Workflows   []string // IDs of the workflows to replay, empty for the newest approved ones
+	Project     string   // only workflows of this project
+	Limit       int      // workflows replayed at most
+	Concurrency int      // workflows replayed at once
+	ReportFile  string   // the full report as JSON, with the replayed lyrics, empty for none
+}
+
+// Report is the outcome of a run
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Prompts     []string  `json:"prompts"` // prompt files replaced
+	Results     []Result  `json:"results"`
+	Summary     Summary   `json:"summary"`
+}
+
+// Result compares a replayed workflow with the content it was approved with
+// Similarities run from 0 (nothing in common) to 1 (the same words); the baseline is the content
+// originally generated, so a candidate above its baseline is closer to what reviewers approved.
+type Result struct {
+	WorkflowID         string                  `json:"workflow_id"`
+	Title              string                  `json:"title"`
+	Approved           string                  `json:"approved_lyrics"`
+	ApprovedProperties *storage.SunoProperties `json:"approved_properties"`
+	Candidate          *workflow.Simulation    `json:"candidate,omitempty"`
+	BaselineSimilarity float64                 `json:"baseline_similarity"` // generated lyrics vs approved
+	LyricsSimilarity   float64                 `json:"lyrics_similarity"`   // replayed lyrics vs approved
+	StyleOverlap       float64                 `json:"style_overlap"`       // shared style tags
+	VocalMatch         bool                    `json:"vocal_match"`
+	Error              string                  `json:"error,omitempty"`
+}
+
+// Summary averages the results replayed without error
+type Summary struct {
+	Replayed           int     `json:"replayed"`
+	Failed             int     `json:"failed"`
+	BaselineSimilarity float64 `json:"baseline_similarity"`
+	LyricsSimilarity   float64 `json:"lyrics_similarity"`
+	Closer             int     `json:"closer"` // replays closer to the approved lyrics than their baseline
+	StyleOverlap       float64 `json:"style_overlap"`
+	VocalMatches       int     `json:"vocal_matches"`
+	LLMCostUSD         float64 `json:"llm_cost_usd"`
+}
+
+// Run replays the approved workflows of store with the prompts of opts.PromptsDir and writes the
+// comparison to w
+// The engine gets its own in-memory store, Telegram and outbound webhooks are disabled: only the
+// OpenAI requests leave the host.
+func Run(ctx context.Context, cfg *config.Config, store *storage.Store, opts Options, w io.Writer) error {
+	if !cfg.HasOpenAI() {
+		return workflow.ErrSimulationNeedsOpenAI
+	}
+	promptsList, replaced, err := prompts.LoadDir(opts.PromptsDir)
+	if err != nil {
+		return err
+	}
+	states, err := selectWorkflows(store, opts)
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		return errors.New("no approved workflows to replay")
+	}
+
+	c := *cfg
+	c.StoreFile, c.ArchiveDir, c.SyncTarget = "", "", ""
+	c.TelegramBotToken, c.TelegramChatID, c.EscalationChatID = "", "", ""
+	c.WebhookURLs = nil
+	c.SunoAccounts = nil
+	engine := workflow.NewEngine(&c, storage.NewStore(), promptsList)
+
+	report := &Report{GeneratedAt: time.Now().UTC(), Prompts: replaced, Results: make([]Result, len(states))}
+	slots := make(chan struct{}, max(opts.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, state := range states {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			report.Results[i] = replay(ctx, engine, state)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report.Summary = summarize(report.Results)
+
+	if opts.ReportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(opts.ReportFile, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return writeReport(w, report)
+}
+
+// selectWorkflows returns the workflows to replay, newest first
+func selectWorkflows(store *storage.Store, opts Options) ([]*storage.WorkflowState, error) {
+	var states []*storage.WorkflowState
+	if len(opts.Workflows) > 0 {
+		for _, id := range opts.Workflows {
+			state, ok := store.Get(id)
+			if !ok {
+				return nil, fmt.Errorf("workflow %s not found", id)
+			}
+			if _, _, ok := workflow.ApprovedContent(state); !ok {
+				return nil, fmt.Errorf("workflow %s has no approved generated lyrics", id)
+			}
+			states = append(states, state)
+		}
+		return states, nil
+	}
+	for state := range store.All() {
+		if opts.Limit > 0 && len(states) == opts.Limit {
+			break
+		}
+		if opts.Project != "" && state.Project != opts.Project {
+			continue
+		}
+		if _, _, ok := workflow.ApprovedContent(state); ok {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// replay simulates a workflow and compares the result with its approved content
+func replay(ctx context.Context, engine *workflow.Engine, state *storage.WorkflowState) Result {
+	lyrics, props, _ := workflow.ApprovedContent(state)
+	result := Result{
+		WorkflowID:         state.ID,
+		Title:              state.Title,
+		Approved:           lyrics,
+		ApprovedProperties: props,
+		BaselineSimilarity: Similarity(state.LyricsWithBrackets, lyrics),
+	}
+	sim, err := engine.Simulate(ctx, state)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Candidate = sim
+	result.LyricsSimilarity = Similarity(sim.LyricsWithBrackets, lyrics)
+	if sim.Properties != nil {
+		result.StyleOverlap = tagOverlap(sim.Properties.Style, props.Style)
+		result.VocalMatch = strings.EqualFold(strings.TrimSpace(sim.Properties.VocalType), strings.TrimSpace(props.VocalType))
+	}
+	return result
+}
+
+func summarize(results []Result) Summary {
+	var s Summary
+	for _, r := range results {
+		if r.Error != "" {
+			s.Failed++
+			continue
+		}
+		s.Replayed++
+		s.BaselineSimilarity += r.BaselineSimilarity
+		s.LyricsSimilarity += r.LyricsSimilarity
+		s.StyleOverlap += r.StyleOverlap
+		s.LLMCostUSD += r.Candidate.Usage.LLMCostUSD
+		if r.LyricsSimilarity > r.BaselineSimilarity {
+			s.Closer++
+		}
+		if r.VocalMatch {
+			s.VocalMatches++
+		}
+	}
+	if s.Replayed > 0 {
+		n := float64(s.Replayed)
+		s.BaselineSimilarity /= n
+		s.LyricsSimilarity /= n
+		s.StyleOverlap /= n
+	}
+	return s
+}
+
+// writeReport writes one line per workflow and the averages
+func writeReport(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Prompts replaced\t%s\n\n", strings.Join(report.Prompts, ", "))
+	fmt.Fprintln(tw, "Workflow\tTitle\tLyrics (baseline → replay)\tStyle tags\tVocals\tCost")
+	for _, r := range report.Results {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\tfailed: %s\t\t\t\n", r.WorkflowID, truncate(r.Title, 30), truncate(r.Error, 60))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2f → %.2f\t%.2f\t%s\t$%.4f\n", r.WorkflowID, truncate(r.Title, 30),
+			r.BaselineSimilarity, r.LyricsSimilarity, r.StyleOverlap, vocalLabel(r.VocalMatch), r.Candidate.Usage.LLMCostUSD)
+	}
+	fmt.Fprintln(tw)
+
+	s := report.Summary
+	fmt.Fprintf(tw, "Replayed\t%d, %d failed\n", s.Replayed, s.Failed)
+	if s.Replayed > 0 {
+		fmt.Fprintf(tw, "Lyrics similarity\t%.2f on average, %.2f with the original prompts; %d of %d closer to the approved lyrics\n",
+			s.LyricsSimilarity, s.BaselineSimilarity, s.Closer, s.Replayed)
+		fmt.Fprintf(tw, "Style tags\t%.2f shared on average\n", s.StyleOverlap)
+		fmt.Fprintf(tw, "Vocal type\t%d of %d as approved\n", s.VocalMatches, s.Replayed)
+		fmt.Fprintf(tw, "OpenAI cost\t$%.4f\n", s.LLMCostUSD)
+	}
+	return tw.Flush()
+}
+
+// Similarity compares the words of two lyrics, bracket instructions left out: the Dice
+// coefficient of their word multisets, from 0 (no word in common) to 1 (the same words)
+func Similarity(a, b string) float64 {
+	wa, wb := lyricWords(a), lyricWords(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	counts := make(map[string]int, len(wa))
+	for _, word := range wa {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range wb {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(wa)+len(wb))
+}
+
+// lyricWords returns the lowercase words of lyrics outside [bracket instructions]
+func lyricWords(lyrics string) []string {
+	var words []string
+	for _, line := range strings.Split(lyrics, "\n") {
+		var b strings.Builder
+		depth := 0
+		for _, r := range line {
+			switch {
+			case r == '[':
+				depth++
+			case r == ']':
+				depth = max(depth-1, 0)
+			case depth == 0:
+				b.WriteRune(r)
+			}
+		}
+		words = append(words, strings.FieldsFunc(strings.ToLower(b.String()), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})...)
+	}
+	return words
+}
+
+// tagOverlap returns the share of the comma-separated style tags of a and b they have in common
+// (Jaccard index)
+func tagOverlap(a, b string) float64 {
+	ta, tb := tags(a), tags(b)
+	if len(ta) == 0 && len(tb) == 0 {
+		return 1
+	}
+	common := 0
+	for _, tag := range ta {
+		if slices.Contains(tb, tag) {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+func tags(style string) []string {
+	var result []string
+	for _, tag := range strings.Split(style, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func vocalLabel(match bool) string {
+	if match {
+		return "same"
+	}
+	return "differs"
+}
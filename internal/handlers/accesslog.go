@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"workflower/lib/accesslog"
+	"workflower/lib/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+)
+
+// accessLogMiddleware records every request in the access log with sensitive query values redacted
+// Request bodies and headers are never logged.
+func (h *Handler) accessLogMiddleware(c *fiber.Ctx) error {
+	started := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+	entry := accesslog.Entry{
+		Time:       started,
+		Method:     c.Method(),
+		Path:       c.Path(),
+		Query:      accesslog.RedactQuery(string(c.Request().URI().QueryString())),
+		Status:     status,
+		DurationMS: float64(time.Since(started).Microseconds()) / 1000,
+		Bytes:      len(c.Response().Body()),
+		IP:         c.IP(),
+		Identity:   h.viewerIdentity(c),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		RequestID:  logger.RequestID(c.UserContext()),
+	}
+	if werr := h.accessLog.Write(entry); werr != nil {
+		slog.Error("Failed to write access log", "error", werr)
+	}
+	return err
+}
+
+// AccessLog searches the recent access log (admins only)
+// ?q= keeps entries containing every space-separated term, ?limit= caps the result (default 100)
+func (h *Handler) AccessLog(c *fiber.Ctx) error {
+	if !h.engine.IsAdmin(h.viewerIdentity(c)) {
+		return h.fail(c, http.StatusForbidden, "Only admins can read the access log")
+	}
+	if h.accessLog == nil {
+		return h.fail(c, http.StatusNotFound, "Access log is disabled (set ACCESS_LOG_DIR)")
+	}
+
+	limit := c.QueryInt("limit", defaultAccessLogLimit)
+	if limit <= 0 || limit > maxAccessLogLimit {
+		limit = maxAccessLogLimit
+	}
+	entries, err := h.accessLog.Search(c.Query("q"), limit)
+	if err != nil {
+		return h.fail(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"entries": entries,
+	})
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiWorkflow is a workflow as returned by the JSON API
+type apiWorkflow struct {
+	*storage.WorkflowState
+	URL       string                     `json:"url"`
+	Progress  *workflow.ProgressEstimate `json:"progress,omitempty"`  // omitted once failed, rejected, cancelled, ...
+	Operation *apiOperation              `json:"operation,omitempty"` // the generation started by an approval
+}
+
+// apiStartRequest is the body of POST /api/v1/workflows
+type apiStartRequest struct {
+	Project         string     `json:"project"`
+	ExternalRef     string     `json:"external_ref"`     // your reference (CRM ticket, order number), filterable in the list
+	TaskDescription string     `json:"task_description"` // optional directions when a transcript is given
+	Transcript      string     `json:"transcript"`       // chat or diary text summarized into the task description
+	Lyrics          string     `json:"lyrics"`           // final lyrics written by the caller; generation is skipped
+	AddBrackets     *bool      `json:"add_brackets"`     // add bracket instructions to Lyrics, default true
+	SourceLyrics    string     `json:"source_lyrics"`    // lyrics of the extended or covered track; generated lyrics keep their structure
+	IsPremium       bool       `json:"is_premium"`
+	DueAt           *time.Time `json:"due_at"` // RFC 3339
+	LongSong        bool       `json:"long_song"`
+	Assignee        string     `json:"assignee"`
+	GenerateStems   bool       `json:"generate_stems"`
+	Language        string     `json:"language"` // code or name; omitted or "auto" to detect it (LANGUAGE_DETECTION)
+	LyricsEngine    string     `json:"lyrics_engine"`
+	SunoModel       string     `json:"suno_model"`      // one of SUNO_MODELS, omitted for SUNO_MODEL
+	SunoPersonaID   string     `json:"suno_persona_id"` // Suno persona of a premium song (GET /personas), omitted for none
+	AudioURL        string     `json:"audio_url"`       // reference track fetched by the server (public http(s) hosts, MAX_AUDIO_SIZE_MB)
+}
+
+// apiReviewRequest is the body of POST /api/v1/workflows/:id/review
+// Omitted fields keep the generated values.
+type apiReviewRequest struct {
+	Action       string                  `json:"action"` // "approve" or "reject"
+	Lyrics       string                  `json:"lyrics"`
+	Title        string                  `json:"title"` // sent to Suno instead of the title rendered from the naming template
+	Properties   *storage.SunoProperties `json:"properties"`
+	Preset       string                  `json:"preset"`    // name of a preset saved on /admin, used when properties are omitted
+	VariantB     *storage.SunoProperties `json:"variant_b"` // A/B submission
+	PersonaInspo *storage.PersonaInspo   `json:"persona_inspo"`
+	OverrideLint bool                    `json:"override_lint"` // approve despite bracket lint issues
+}
+
+// apiWorkflowPage is the body of GET /api/v1/workflows
+type apiWorkflowPage struct {
+	Workflows  []apiWorkflow `json:"workflows"`
+	NextCursor int           `json:"next_cursor"` // 0 on the last page
+}
+
+// registerAPIRoutes adds the versioned JSON API; it mirrors the HTML endpoints and
+// identifies callers by the same signed identity cookie
+// The routes come from apiRoutes, which also describes them in the OpenAPI document.
+func (h *Handler) registerAPIRoutes(r *fiber.App, reviewer fiber.Handler) {
+	api := r.Group(apiPrefix)
+	for _, route := range h.apiRoutes(reviewer) {
+		api.Add(route.Method, route.Path, route.Handlers...)
+	}
+	r.Get(openAPIPath, h.OpenAPISpec)
+	r.Get(apiDocsPath, h.APIDocs)
+}
+
+// APICreateWorkflow starts a workflow and answers 201 with it
+func (h *Handler) APICreateWorkflow(c *fiber.Ctx) error {
+	var req apiStartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid JSON body")
+	}
+	lyrics := strings.TrimSpace(req.Lyrics)
+	if strings.TrimSpace(req.TaskDescription) == "" && strings.TrimSpace(req.Transcript) == "" && lyrics == "" {
+		return apiError(c, http.StatusBadRequest, "task_description, transcript or lyrics is required")
+	}
+	if err := h.engine.CheckTranscript(req.Transcript); err != nil {
+		return h.failWith(c, err)
+	}
+	if err := h.engine.CheckSourceLyrics(req.SourceLyrics, lyrics != ""); err != nil {
+		return h.failWith(c, err)
+	}
+	language, err := h.validateStartOptions(req.Language, req.LyricsEngine)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+
+	var audioFilePath, audioFileName string
+	if strings.TrimSpace(req.AudioURL) != "" {
+		audioFilePath, audioFileName, err = h.fetchAudio(c.UserContext(), req.AudioURL)
+		var rejected *uploadError
+		if errors.As(err, &rejected) {
+			return apiError(c, rejected.status, rejected.msg)
+		}
+		if err != nil {
+			return apiError(c, http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	state, err := h.engine.StartWorkflow(c.UserContext(), workflow.StartParams{
+		Project:         req.Project,
+		ExternalRef:     req.ExternalRef,
+		TaskDescription: req.TaskDescription,
+		Transcript:      req.Transcript,
+		Lyrics:          lyrics,
+		LyricsImported:  lyrics != "",
+		AddBrackets:     req.AddBrackets == nil || *req.AddBrackets,
+		SourceLyrics:    req.SourceLyrics,
+		IsPremium:       req.IsPremium,
+		AudioFilePath:   audioFilePath,
+		AudioFileName:   audioFileName,
+		DueAt:           req.DueAt,
+		LongSong:        req.LongSong,
+		Assignee:        strings.TrimSpace(req.Assignee),
+		GenerateStems:   req.GenerateStems,
+		Language:        language,
+		LyricsEngine:    req.LyricsEngine,
+		SunoModel:       req.SunoModel,
+		SunoPersonaID:   req.SunoPersonaID,
+	})
+	if err != nil {
+		return h.failWith(c, err)
+	}
+
+	c.Location("/api/v1/workflows/" + state.ID)
+	return c.Status(http.StatusCreated).JSON(h.apiWorkflow(c, state))
+}
+
+// APIListWorkflows returns one page of the workflows the caller may list (see listScope), newest first
+// ?status=, ?project= and ?external_ref= filter, ?limit= sets the page size (default 50) and ?before= continues
+// from the next_cursor of the previous page.
+func (h *Handler) APIListWorkflows(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultListLimit)
+	if limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+	status, project, externalRef := c.Query("status"), c.Query("project"), c.Query("external_ref")
+
+	scope := h.listScope(c, h.viewerIdentity(c))
+	page, nextCursor := h.listPage(c.QueryInt("before", 0), limit, status, project, externalRef, scope)
+	workflows := make([]apiWorkflow, 0, len(page))
+	for _, wf := range page {
+		workflows = append(workflows, h.apiWorkflow(c, wf))
+	}
+
+	return c.JSON(apiWorkflowPage{Workflows: workflows, NextCursor: nextCursor})
+}
+
+// APIGetWorkflow returns a workflow by ID or sequence number
+func (h *Handler) APIGetWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	return c.JSON(h.apiWorkflow(c, wf))
+}
+
+// APIReviewWorkflow approves (submitting to Suno) or rejects a workflow awaiting review
+// Lyrics with blocking issues answer 422 with the issues, leaving the workflow in review.
+func (h *Handler) APIReviewWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	var req apiReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid JSON body")
+	}
+	if req.Action != "approve" && req.Action != "reject" {
+		return apiError(c, http.StatusBadRequest, `action must be "approve" or "reject"`)
+	}
+	viewer := h.viewerIdentity(c)
+	if !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	if req.Action == "reject" {
+		if err := h.engine.RejectWorkflow(wf, viewer); err != nil {
+			return h.failWith(c, err)
+		}
+		return c.JSON(h.apiWorkflow(c, wf))
+	}
+
+	if req.Properties == nil && req.Preset != "" {
+		preset, ok := h.engine.Preset(req.Preset)
+		if !ok {
+			return apiError(c, http.StatusBadRequest, fmt.Sprintf("unknown preset %q", req.Preset))
+		}
+		req.Properties = &preset.Properties
+	}
+	err := h.engine.EditReview(wf, func(wf *storage.WorkflowState) {
+		if req.Lyrics != "" {
+			wf.EditedLyrics = req.Lyrics
+		}
+		if req.Properties != nil {
+			wf.EditedProperties = req.Properties
+		}
+		if title := strings.TrimSpace(req.Title); title != "" {
+			wf.EditedTitle = title
+		}
+		wf.VariantB = req.VariantB
+		wf.LintOverridden = req.OverrideLint
+		if wf.IsPremium && req.PersonaInspo != nil {
+			wf.PersonaInspo = req.PersonaInspo
+		}
+	})
+	if err != nil {
+		return h.failWith(c, err)
+	}
+
+	if err := h.engine.ApproveWorkflow(c.UserContext(), wf, viewer); err != nil {
+		if errors.Is(err, workflow.ErrInvalidLyrics) {
+			return apiLyricsError(c, wf, err)
+		}
+		return h.failWith(c, err)
+	}
+	result := h.apiWorkflow(c, wf)
+	result.Operation = h.apiOperation(c, h.engine.StartOperation(workflow.OperationGenerate, wf))
+	return c.JSON(result)
+}
+
+// APICancelWorkflow stops an unfinished workflow (reviewers of the workflow only)
+func (h *Handler) APICancelWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	if viewer := h.viewerIdentity(c); !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	if err := h.engine.CancelWorkflow(wf); err != nil {
+		return h.failWith(c, err)
+	}
+	return c.JSON(h.apiWorkflow(c, wf))
+}
+
+// APIDeleteWorkflow removes a workflow (admins only), stopping it first if it is still running
+func (h *Handler) APIDeleteWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, "only admins can delete workflows")
+	}
+
+	h.engine.DeleteWorkflow(wf, viewer)
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// apiWorkflow wraps a workflow with the URL of its status page
+func (h *Handler) apiWorkflow(c *fiber.Ctx, wf *storage.WorkflowState) apiWorkflow {
+	result := apiWorkflow{WorkflowState: wf, URL: fmt.Sprintf("%s/workflow/%s", c.BaseURL(), wf.ID)}
+	if progress, ok := h.engine.Progress(wf); ok {
+		result.Progress = &progress
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBulkIDs caps the workflows of one bulk request
+const maxBulkIDs = 500
+
+// bulkActions are the actions of POST /api/v1/workflows/bulk and the list page's bulk form
+var bulkActions = []string{"approve", "reject", "cancel", "delete"}
+
+// apiBulkRequest is the body of POST /api/v1/workflows/bulk
+type apiBulkRequest struct {
+	Action string   `json:"action"` // approve, reject, cancel or delete
+	IDs    []string `json:"ids"`    // workflow IDs or sequence numbers
+}
+
+// apiBulkResult is the outcome of a bulk action on one workflow
+type apiBulkResult struct {
+	ID     string             `json:"id"`               // as requested
+	Seq    int                `json:"seq,omitempty"`    // 0 for unknown workflows
+	Status string             `json:"status,omitempty"` // status after the action, "deleted" once deleted
+	Error  string             `json:"error,omitempty"`  // why the action was not applied
+	Code   workflow.ErrorCode `json:"code,omitempty"`   // code of the error
+}
+
+// apiBulkResponse is the body of POST /api/v1/workflows/bulk
+type apiBulkResponse struct {
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Results   []apiBulkResult `json:"results"` // in request order
+}
+
+// APIBulkWorkflows applies one action to a list of workflows, each checked on its own: the
+// answer is 200 with a result per workflow even when some of them failed
+func (h *Handler) APIBulkWorkflows(c *fiber.Ctx) error {
+	var req apiBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid JSON body")
+	}
+	if err := checkBulkRequest(req.Action, req.IDs); err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(h.bulkAction(c, req.Action, req.IDs))
+}
+
+// BulkWorkflows applies the bulk form of the list page and returns to the list
+// Deleting needs the number of selected workflows repeated in the confirm field.
+func (h *Handler) BulkWorkflows(c *fiber.Ctx) error {
+	action := c.FormValue("action")
+	var ids []string
+	for _, value := range c.Request().PostArgs().PeekMulti("ids") {
+		ids = append(ids, string(value))
+	}
+	if err := checkBulkRequest(action, ids); err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+	if action == "delete" && c.FormValue("confirm") != strconv.Itoa(len(ids)) {
+		return h.fail(c, http.StatusBadRequest, fmt.Sprintf("Confirm by repeating the number of selected workflows (%d)", len(ids)))
+	}
+
+	result := h.bulkAction(c, action, ids)
+	if result.Failed > 0 {
+		msg := fmt.Sprintf("%s: %d succeeded, %d failed\n", action, result.Succeeded, result.Failed)
+		for _, r := range result.Results {
+			if r.Error != "" {
+				msg += fmt.Sprintf("\n%s: %s", r.ID, r.Error)
+			}
+		}
+		return h.fail(c, http.StatusConflict, msg)
+	}
+	return c.Redirect(listReturnURL(c), http.StatusFound)
+}
+
+// checkBulkRequest validates the action and size of a bulk request
+func checkBulkRequest(action string, ids []string) error {
+	if !slices.Contains(bulkActions, action) {
+		return errors.New(`action must be "approve", "reject", "cancel" or "delete"`)
+	}
+	if len(ids) == 0 {
+		return errors.New("no workflows selected")
+	}
+	if len(ids) > maxBulkIDs {
+		return fmt.Errorf("at most %d workflows per request", maxBulkIDs)
+	}
+	return nil
+}
+
+// bulkAction applies action to the workflows as the viewer of c, with the same checks as the
+// single actions: approve and reject need a workflow awaiting review the viewer may review,
+// cancel a reviewable one and delete an admin. Approval uses the proposed lyrics and properties,
+// like the list page's quick approve; lyrics with blocking issues stay in review.
+func (h *Handler) bulkAction(c *fiber.Ctx, action string, refs []string) apiBulkResponse {
+	ctx, viewer := c.UserContext(), h.viewerIdentity(c)
+	resp := apiBulkResponse{Results: make([]apiBulkResult, 0, len(refs))}
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		result := apiBulkResult{ID: ref}
+		wf, ok := h.viewerWorkflow(c, ref)
+		switch {
+		case !ok:
+			result.Error, result.Code = workflow.ErrWorkflowNotFound.Error(), workflow.CodeNotFound
+		case seen[wf.ID]:
+			continue
+		default:
+			seen[wf.ID] = true
+			result.Seq = wf.Seq
+			if err := h.applyBulkAction(ctx, wf, viewer, action); err != nil {
+				result.Error, result.Code = err.Error(), workflow.ErrorCodeOf(err)
+			}
+			result.Status = wf.Status
+			if action == "delete" && result.Error == "" {
+				result.Status = "deleted"
+			}
+		}
+
+		if result.Error != "" {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp
+}
+
+// applyBulkAction applies a bulk action to one workflow
+func (h *Handler) applyBulkAction(ctx context.Context, wf *storage.WorkflowState, viewer, action string) error {
+	if action == "delete" {
+		if !h.engine.IsAdmin(viewer) {
+			return workflow.NewError(workflow.CodeForbidden, wf.ID, "only admins can delete workflows")
+		}
+		h.engine.DeleteWorkflow(wf, viewer)
+		return nil
+	}
+
+	if !h.engine.CanReview(wf, viewer) {
+		return workflow.NewError(workflow.CodeForbidden, wf.ID, reviewDenied(wf))
+	}
+	if action == "cancel" {
+		return h.engine.CancelWorkflow(wf)
+	}
+	if action == "reject" {
+		return h.engine.RejectWorkflow(wf, viewer)
+	}
+
+	if err := h.engine.EditReview(wf, func(wf *storage.WorkflowState) { wf.LintOverridden = false }); err != nil {
+		return err
+	}
+	if err := h.engine.ApproveWorkflow(ctx, wf, viewer); err != nil {
+		if errors.Is(err, workflow.ErrInvalidLyrics) {
+			return workflow.NewError(workflow.CodeInvalid, wf.ID, "lyrics have blocking issues, review the workflow on its own")
+		}
+		return err
+	}
+	return nil
+}
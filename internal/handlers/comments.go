@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxCommentChars is documented in the API (the apiRoutes table shadows the workflow package)
+const maxCommentChars = workflow.MaxCommentChars
+
+// apiCommentRequest is the body of POST /api/v1/workflows/:id/comments
+type apiCommentRequest struct {
+	Text string `json:"text"`
+}
+
+// apiCommentList is the body of GET /api/v1/workflows/:id/comments
+type apiCommentList struct {
+	Comments []storage.Comment `json:"comments"` // oldest first
+}
+
+// AddComment records a note from the comment form of the status and review pages
+func (h *Handler) AddComment(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	if _, err := h.engine.AddComment(wf, h.viewerIdentity(c), c.FormValue("text")); err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Redirect(safeReferer(c)+"#comments", http.StatusFound)
+}
+
+// APIListComments returns the comments of a workflow, oldest first
+func (h *Handler) APIListComments(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	comments := wf.Comments
+	if comments == nil {
+		comments = []storage.Comment{}
+	}
+	return c.JSON(apiCommentList{Comments: comments})
+}
+
+// APIAddComment records a note on a workflow and answers 201 with it
+func (h *Handler) APIAddComment(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	var req apiCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid JSON body")
+	}
+
+	comment, err := h.engine.AddComment(wf, h.viewerIdentity(c), req.Text)
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Status(http.StatusCreated).JSON(comment)
+}
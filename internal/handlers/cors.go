@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"strings"
+
+	"workflower/lib/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// newCORS creates the CORS middleware of the JSON API (/api/...), which lets the origins of
+// CORS_ALLOWED_ORIGINS call it from the browser with the identity cookie; nil when none are set.
+// It answers preflight requests itself, ahead of the CSRF and sign-in checks, which browsers
+// send without cookies.
+func (h *Handler) newCORS() fiber.Handler {
+	if len(h.cfg.CORSAllowedOrigins) == 0 {
+		return nil
+	}
+	return cors.New(cors.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return !strings.HasPrefix(c.Path(), "/api/")
+		},
+		AllowOrigins:     strings.Join(h.cfg.CORSAllowedOrigins, ","),
+		AllowMethods:     strings.Join(h.cfg.CORSAllowedMethods, ","),
+		AllowHeaders:     strings.Join([]string{fiber.HeaderContentType, csrfHeader, logger.RequestIDHeader}, ","),
+		ExposeHeaders:    strings.Join([]string{csrfHeader, logger.RequestIDHeader, fiber.HeaderRetryAfter, operationLocationHeader}, ","),
+		AllowCredentials: true,
+		MaxAge:           int(h.cfg.CORSMaxAge.Seconds()),
+	})
+}
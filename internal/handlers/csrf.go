@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	csrfCookie    = "csrf"         // random per-browser value the tokens are derived from
+	csrfFormField = "_csrf"        // hidden field of every HTML form
+	csrfHeader    = "X-CSRF-Token" // alternative to the form field for scripts
+	csrfLocal     = "csrf"         // the browser's CSRF value for the current request
+)
+
+// csrfProtect gives every browser a CSRF cookie and rejects state-changing requests that do
+// not carry the matching token, so that another site cannot start, approve or delete
+// workflows in the name of a visitor.
+// JSON API calls pass with a JSON body, which cross-site forms cannot send and other origins
+// only after a CORS preflight granted to CORS_ALLOWED_ORIGINS; inbound webhooks
+// are authenticated by their own secrets.
+func (h *Handler) csrfProtect(c *fiber.Ctx) error {
+	id := c.Cookies(csrfCookie)
+	if len(id) != 32 {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+		c.Cookie(&fiber.Cookie{
+			Name:     csrfCookie,
+			Value:    id,
+			Path:     "/",
+			HTTPOnly: true,
+			Secure:   strings.HasPrefix(h.cfg.BaseURL, "https://"),
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+	}
+	c.Locals(csrfLocal, id)
+
+	// Cross-site pages can only send DELETE after a CORS preflight, which is only granted to
+	// CORS_ALLOWED_ORIGINS on the API (see newCORS). Other sites cannot read the header
+	// either, scripts use it instead of scraping a form.
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodDelete:
+		c.Set(csrfHeader, h.csrfToken(c))
+		return c.Next()
+	}
+	path := c.Path()
+	switch path {
+	case "/admin/deploy-webhook", reviewHookPath, workflow.SunoCallbackPath, "/graphql", normalizeWebhookPath(h.cfg.TelegramWebhookPath):
+		return c.Next()
+	}
+
+	token := c.Get(csrfHeader)
+	if strings.HasPrefix(path, "/api/") {
+		if token == "" && strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+			return c.Next()
+		}
+	} else if token == "" {
+		token = c.FormValue(csrfFormField)
+	}
+	if hmac.Equal([]byte(token), []byte(h.csrfToken(c))) {
+		return c.Next()
+	}
+
+	return h.fail(c, http.StatusForbidden, "Invalid or missing CSRF token, reload the page and try again")
+}
+
+// csrfToken returns the token of the forms rendered for this browser and session
+// It changes when the viewer signs in or out, so forms of another session are rejected.
+func (h *Handler) csrfToken(c *fiber.Ctx) string {
+	id, _ := c.Locals(csrfLocal).(string)
+	if id == "" {
+		return ""
+	}
+	return h.engine.Sign("csrf|" + id + "|" + h.viewerIdentity(c))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"workflower/lib/deploy"
+	"workflower/lib/webhook"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// deployMaxAge is how old a deploy webhook delivery may be, measured from its signed timestamp;
+// deliveries from the future are allowed as much clock skew
+const deployMaxAge = 5 * time.Minute
+
+// deployRequest is the body CI posts to the deploy webhook
+type deployRequest struct {
+	ArtifactURL string `json:"artifact_url"`
+	SHA256      string `json:"sha256"`
+	Version     string `json:"version,omitempty"`
+}
+
+// DeployWebhook installs a release pushed by CI and asks for a restart (see RestartRequested)
+// The timestamp (X-Workflower-Timestamp) and body must be signed with DEPLOY_WEBHOOK_SECRET
+// (X-Workflower-Signature, see webhook.SignAt); stale or repeated deliveries are refused.
+func (h *Handler) DeployWebhook(c *fiber.Ctx) error {
+	if h.cfg.DeployWebhookSecret == "" {
+		return h.fail(c, http.StatusNotFound, "Deploy webhook disabled")
+	}
+	signature := c.Get(webhook.SignatureHeader)
+	signedAt, ok := webhook.VerifyAt(h.cfg.DeployWebhookSecret, c.Get(webhook.TimestampHeader), c.Body(), signature)
+	if !ok {
+		return h.fail(c, http.StatusUnauthorized, "Invalid signature")
+	}
+	if !h.deployReplays.accept(signature, signedAt) {
+		slog.Warn("Deploy webhook: stale or repeated delivery refused", "signed_at", signedAt)
+		return h.fail(c, http.StatusConflict, "Stale or repeated delivery")
+	}
+
+	var req deployRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return h.fail(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	slog.Info("Deploy webhook: installing release", "version", req.Version, "artifact_url", req.ArtifactURL)
+	binary, err := deploy.InstallRelease(c.Context(), req.ArtifactURL, req.SHA256)
+	if err != nil {
+		slog.Error("Deploy webhook: install failed", "version", req.Version, "error", err)
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	slog.Info("Deploy webhook: release installed, restarting", "version", req.Version, "binary", binary)
+	select {
+	case h.restart <- struct{}{}:
+	default: // a restart is already pending
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "restarting",
+		"version": req.Version,
+	})
+}
+
+// RestartRequested is signalled when the deploy webhook has installed a release; the server
+// then shuts down, lets the engine drain and exits with deploy.RestartExitCode (see main)
+func (h *Handler) RestartRequested() <-chan struct{} {
+	return h.restart
+}
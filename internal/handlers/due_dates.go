@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// dueDateLayout matches the value of an <input type="date">
+const dueDateLayout = "2006-01-02"
+
+// SetWorkflowDueDate sets or clears (empty due_date) the due date of a single workflow
+func (h *Handler) SetWorkflowDueDate(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	due, err := parseDueDate(c.FormValue("due_date"), h.viewerLocation(c))
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	h.engine.SetDueDate(wf, due)
+	return c.Redirect(safeReferer(c), http.StatusFound)
+}
+
+// SetProjectDueDate sets or clears the due date of every unfinished workflow in a project
+func (h *Handler) SetProjectDueDate(c *fiber.Ctx) error {
+	project := strings.TrimSpace(c.Params("project"))
+	if project == "" {
+		return h.fail(c, http.StatusBadRequest, "Project is required")
+	}
+
+	due, err := parseDueDate(c.FormValue("due_date"), h.viewerLocation(c))
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	updated := h.engine.SetProjectDueDate(project, due)
+	return c.JSON(fiber.Map{
+		"project": project,
+		"due_at":  due,
+		"updated": updated,
+	})
+}
+
+// parseDueDate parses a YYYY-MM-DD date as the end of that day in loc; an empty value clears the due date
+func parseDueDate(value string, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	day, err := time.ParseInLocation(dueDateLayout, value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid due date %q, expected YYYY-MM-DD", value)
+	}
+	due := day.AddDate(0, 0, 1).Add(-time.Second)
+	return &due, nil
+}
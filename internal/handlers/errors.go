@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"workflower/internal/ui_templates"
+	"workflower/lib/logger"
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Codes of the errors raised by the handlers rather than the engine
+const (
+	codeUnauthorized workflow.ErrorCode = "unauthorized"
+	codeTooLarge     workflow.ErrorCode = "too_large"
+	codeBadGateway   workflow.ErrorCode = "bad_gateway"
+)
+
+// errorStatuses maps error codes to HTTP statuses
+var errorStatuses = map[workflow.ErrorCode]int{
+	workflow.CodeInvalid:     http.StatusBadRequest,
+	codeUnauthorized:         http.StatusUnauthorized,
+	workflow.CodeForbidden:   http.StatusForbidden,
+	workflow.CodeNotFound:    http.StatusNotFound,
+	workflow.CodeConflict:    http.StatusConflict,
+	codeTooLarge:             http.StatusRequestEntityTooLarge,
+	workflow.CodeRateLimited: http.StatusTooManyRequests,
+	workflow.CodeInternal:    http.StatusInternalServerError,
+	codeBadGateway:           http.StatusBadGateway,
+	workflow.CodeUnavailable: http.StatusServiceUnavailable,
+}
+
+// apiErrorBody is the body of every JSON error
+type apiErrorBody struct {
+	Code       workflow.ErrorCode `json:"code"`
+	Message    string             `json:"message"`
+	WorkflowID string             `json:"workflow_id,omitempty"` // workflow the error concerns
+}
+
+// apiLyricsErrorBody answers an approval blocked by lyrics issues
+type apiLyricsErrorBody struct {
+	apiErrorBody
+	Issues []storage.LyricsIssue `json:"issues"`
+}
+
+// errorView is the error shown by the error page
+type errorView struct {
+	Status     int
+	Code       workflow.ErrorCode
+	Heading    string // status text, e.g. "Not Found"
+	WorkflowID string // linked back to, unless the workflow is what was not found
+	RequestID  string
+}
+
+// apiError answers with a JSON error body; the code follows the status
+func apiError(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(apiErrorBody{Code: errorCode(status), Message: message})
+}
+
+// apiLyricsError answers an approval blocked by lyrics issues with the issues
+func apiLyricsError(c *fiber.Ctx, wf *storage.WorkflowState, err error) error {
+	body := apiErrorBody{Code: workflow.ErrorCodeOf(err), Message: err.Error(), WorkflowID: wf.ID}
+	return c.Status(http.StatusUnprocessableEntity).JSON(apiLyricsErrorBody{apiErrorBody: body, Issues: wf.LyricsIssues})
+}
+
+// fail answers a failed request with an error of the given status (see sendError)
+func (h *Handler) fail(c *fiber.Ctx, status int, message string) error {
+	return h.sendError(c, status, apiErrorBody{Code: errorCode(status), Message: message})
+}
+
+// failWorkflow answers a failed request about a workflow
+func (h *Handler) failWorkflow(c *fiber.Ctx, status int, workflowID, message string) error {
+	return h.sendError(c, status, apiErrorBody{Code: errorCode(status), Message: message, WorkflowID: workflowID})
+}
+
+// failWith answers a failed request with an engine error: the status follows its code, and
+// errors without one are internal
+func (h *Handler) failWith(c *fiber.Ctx, err error) error {
+	code := workflow.ErrorCodeOf(err)
+	body := apiErrorBody{Code: code, Message: err.Error(), WorkflowID: workflow.ErrorWorkflowID(err)}
+	return h.sendError(c, errorStatuses[code], body)
+}
+
+// workflowNotFound answers a request for an unknown workflow
+func (h *Handler) workflowNotFound(c *fiber.Ctx) error {
+	return h.failWith(c, workflow.ErrWorkflowNotFound)
+}
+
+// sendError answers with an error in the form the client reads: JSON on the API and to clients
+// that do not accept HTML, the bare message to htmx requests, a styled page to browsers
+func (h *Handler) sendError(c *fiber.Ctx, status int, body apiErrorBody) error {
+	switch {
+	case strings.HasPrefix(c.Path(), "/api/") || !strings.Contains(c.Get(fiber.HeaderAccept), fiber.MIMETextHTML):
+		return c.Status(status).JSON(body)
+	case c.Get("HX-Request") != "":
+		return c.Status(status).SendString(upperFirst(body.Message))
+	default:
+		return h.renderError(c, status, body)
+	}
+}
+
+// renderError renders the error page, or the bare message when the page itself fails
+func (h *Handler) renderError(c *fiber.Ctx, status int, body apiErrorBody) error {
+	viewer := h.viewerIdentity(c)
+	view := errorView{
+		Status:    status,
+		Code:      body.Code,
+		Heading:   http.StatusText(status),
+		RequestID: logger.RequestID(c.UserContext()),
+	}
+	if body.Code != workflow.CodeNotFound {
+		view.WorkflowID = body.WorkflowID
+	}
+	data := ui_templates.PageData{
+		Title:    view.Heading,
+		Location: h.viewerLocation(c),
+		Viewer:   viewer,
+		IsAdmin:  h.engine.IsAdmin(viewer),
+		Error:    upperFirst(body.Message),
+		Failure:  view,
+		CSRF:     h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Error.Execute(&buf, data); err != nil {
+		slog.ErrorContext(c.UserContext(), "Failed to render the error page", "error", err)
+		return c.Status(status).SendString(upperFirst(body.Message))
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// HandleError answers the errors returned by handlers instead of a response
+// (fiber.Config.ErrorHandler): unknown routes, oversized bodies, panics and engine errors
+// Unexpected errors are logged and answered without their details.
+func (h *Handler) HandleError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		if fiberErr.Code >= http.StatusInternalServerError {
+			slog.ErrorContext(c.UserContext(), "Request failed", "method", c.Method(), "path", c.Path(), "error", err)
+		}
+		return h.fail(c, fiberErr.Code, fiberErr.Message)
+	}
+	if workflow.ErrorCodeOf(err) != workflow.CodeInternal {
+		return h.failWith(c, err)
+	}
+	slog.ErrorContext(c.UserContext(), "Request failed", "method", c.Method(), "path", c.Path(), "error", err)
+	return h.fail(c, http.StatusInternalServerError, "Internal server error")
+}
+
+// ErrorHandler turns panics of the handlers into errors, answered by HandleError
+func ErrorHandler() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return c.Next()
+	}
+}
+
+// errorCode returns the code of an HTTP error status
+func errorCode(status int) workflow.ErrorCode {
+	if status == http.StatusUnprocessableEntity {
+		return workflow.CodeInvalid
+	}
+	for code, s := range errorStatuses {
+		if s == status {
+			return code
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		return workflow.CodeInternal
+	}
+	return workflow.CodeInvalid
+}
+
+// upperFirst capitalizes a message for display; engine errors start in lowercase
+func upperFirst(message string) string {
+	r, size := utf8.DecodeRuneInString(message)
+	if size == 0 {
+		return message
+	}
+	return string(unicode.ToUpper(r)) + message[size:]
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"workflower/lib/webhook"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	sseBufferSize        = 32
+	sseKeepAliveInterval = 15 * time.Second
+)
+
+// WorkflowEvents streams engine events for a single workflow as Server-Sent Events
+// Every event is a workflow.EventEnvelope; with WEBHOOK_SECRET set, a second data line carries
+// its signature, computed like the webhook signature over the first line.
+func (h *Handler) WorkflowEvents(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if _, ok := h.store.Get(id); !ok {
+		return h.workflowNotFound(c)
+	}
+
+	events := make(chan workflow.Event, sseBufferSize)
+	unsubscribe := h.engine.Events().Subscribe(func(event workflow.Event) {
+		if event.WorkflowID() != id {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			// Slow client: drop the event rather than block the engine
+		}
+	})
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Set(webhook.SchemaVersionHeader, strconv.Itoa(workflow.EventSchemaVersion))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event := <-events:
+				envelope := h.engine.ExternalEvent(event)
+				payload, err := json.Marshal(envelope)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n", envelope.ID, envelope.Event, payload); err != nil {
+					return
+				}
+				if h.cfg.WebhookSecret != "" {
+					if _, err := fmt.Fprintf(w, "data: %s\n", webhook.Sign(h.cfg.WebhookSecret, payload)); err != nil {
+						return
+					}
+				}
+				if _, err := fmt.Fprint(w, "\n"); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			if err := w.Flush(); err != nil {
+				// Client went away
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// Metrics exposes engine counters in the Prometheus text format
+func (h *Handler) Metrics(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	return h.engine.Metrics().WritePrometheus(c)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"workflower/lib/openapi"
+	"workflower/lib/webhook"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiEventSchema is the body of GET /api/v1/events/schema
+type apiEventSchema struct {
+	SchemaVersion int                        `json:"schema_version"`
+	Envelopes     map[string]*openapi.Schema `json:"envelopes"` // "sse" and "webhook"
+	Events        map[string]*openapi.Schema `json:"events"`    // data of the SSE events, by name
+	Webhooks      map[string]*openapi.Schema `json:"webhooks"`  // data of the webhook events, by name
+	Components    openapi.Components         `json:"components"`
+}
+
+// APIEventSchema describes the outbound events of the current schema version as JSON Schemas
+// (OpenAPI flavored), built from the same types that are sent
+func (h *Handler) APIEventSchema(c *fiber.Ctx) error {
+	doc := openapi.New("Workflower events", "", "")
+	schema := apiEventSchema{
+		SchemaVersion: workflow.EventSchemaVersion,
+		Envelopes: map[string]*openapi.Schema{
+			"sse":     doc.Schema(workflow.EventEnvelope{}),
+			"webhook": doc.Schema(webhook.Payload{}),
+		},
+		Events:   make(map[string]*openapi.Schema),
+		Webhooks: make(map[string]*openapi.Schema),
+	}
+	for name, data := range workflow.EventDataTypes() {
+		schema.Events[name] = doc.Schema(data)
+	}
+	for _, name := range workflow.WebhookEvents() {
+		schema.Webhooks[name] = doc.Schema(workflow.EventWorkflow{})
+	}
+	schema.Components = doc.Components
+	return c.JSON(schema)
+}
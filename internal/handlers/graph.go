@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"workflower/internal/ui_templates"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WorkflowGraph returns the step DAG of a workflow with per-step status and durations
+// JSON by default; browsers (or ?format=html) get the rendered view
+func (h *Handler) WorkflowGraph(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	graph := h.engine.Graph(wf)
+
+	format := c.Query("format")
+	if format == "" && c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML {
+		format = "html"
+	}
+	if format != "html" {
+		return c.JSON(graph)
+	}
+
+	data := ui_templates.PageData{
+		Title:    "Workflow Graph",
+		Workflow: wf,
+		Graph:    graph,
+		Location: h.viewerLocation(c),
+		Viewer:   h.viewerIdentity(c),
+		CSRF:     h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Graph.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"workflower/lib/graphql"
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	graphqlDefaultLimit = 50
+	graphqlMaxLimit     = 500
+	// graphqlMaxQuery bounds the length of a query document; dashboard queries are far shorter
+	graphqlMaxQuery = 64 << 10
+)
+
+// graphqlSchema documents the GraphQL API (served at /graphql/schema)
+// Field names follow the JSON encoding used by the REST endpoints
+const graphqlSchema = `type Query {
+  workflows(status: [String], project: String, external_ref: String, assignee: String,
+            language: String, overdue: Boolean, search: String, limit: Int = 50,
+            offset: Int = 0): [Workflow]         # search: title, task description, external_ref
+  workflow(id: String!): Workflow              # UUID or sequence number ("42" or "#42")
+  projects: [Project]
+  project(name: String!): Project
+  audit_log(workflow_id: String, limit: Int = 50): [AuditEntry]
+  spend(month: String): [MonthlySpend]         # month as YYYY-MM
+  statuses: [Status]
+}
+
+type Subscription {
+  # Live engine events as Server-Sent Events, one "next" event per engine event
+  events(workflow_id: String, project: String, names: [String]): Event
+}
+
+type Workflow {
+  __typename, id, seq, project, project_seq, external_ref, created_at, updated_at, status, status_label,
+  is_terminal, is_overdue, url, assignee, assigned_at, escalation_level, due_at, task_description,
+  is_premium, language, title, lyrics, lyrics_with_brackets, edited_lyrics, suno_properties,
+  persona_inspo, usage, long_song, segments, audio_url, video_url, stems_url, error_msg,
+  moderation_categories, progress, ...every other field of the workflow JSON
+  audit(limit: Int = 50): [AuditEntry]
+}
+
+type Project {
+  __typename, name, workflow_count, open_count, overdue_count, next_due_at
+  workflows(status: [String]): [Workflow]
+}
+
+type Event {
+  __typename, name, workflow_id, at, step, duration, error, from, to, by, level, done, total
+  workflow: Workflow    # snapshot for status/assignment events, current state otherwise
+}
+`
+
+// GraphQLSchema serves a description of the GraphQL API
+func (h *Handler) GraphQLSchema(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	return c.SendString(graphqlSchema)
+}
+
+// GraphQL executes a GraphQL query (POST JSON body or GET ?query=&variables=)
+// Subscriptions are streamed as Server-Sent Events
+func (h *Handler) GraphQL(c *fiber.Ctx) error {
+	var req graphql.Request
+	if c.Method() == http.MethodGet {
+		req.Query = c.Query("query")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(fmt.Errorf("invalid variables: %w", err)))
+			}
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(fmt.Errorf("invalid request body: %w", err)))
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(fmt.Errorf("query is required")))
+	}
+	if len(req.Query) > graphqlMaxQuery {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(fmt.Errorf("query is longer than %d bytes", graphqlMaxQuery)))
+	}
+
+	op, err := graphql.Parse(req.Query, req.Variables)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(err))
+	}
+
+	if op.Type == graphql.OperationSubscription {
+		return h.graphqlSubscription(c, op)
+	}
+
+	data, err := graphql.Select(h.graphqlQueryRoot(c.BaseURL(), h.listScope(c, h.viewerIdentity(c))), op.Selections)
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(graphql.ResponseError(err))
+	}
+	return c.JSON(graphql.Response{Data: data})
+}
+
+// graphqlQueryRoot returns the root Query object; visible, when not nil, narrows the workflows,
+// projects and audit entries it resolves to those of the viewer's list (see listScope)
+func (h *Handler) graphqlQueryRoot(baseURL string, visible func(*storage.WorkflowState) bool) map[string]any {
+	return map[string]any{
+		graphql.TypenameField: "Query",
+		"workflows": graphql.Resolver(func(args map[string]any) (any, error) {
+			return h.graphqlWorkflows(args, baseURL, visible)
+		}),
+		"workflow": graphql.Resolver(func(args map[string]any) (any, error) {
+			wf, ok := h.lookupWorkflow(graphql.StringArg(args, "id"), visible)
+			if !ok {
+				return nil, nil
+			}
+			return h.graphqlWorkflow(wf, baseURL)
+		}),
+		"projects": graphql.Resolver(func(args map[string]any) (any, error) {
+			var projects []any
+			for _, name := range h.projectNames(visible) {
+				projects = append(projects, h.graphqlProject(name, baseURL, visible))
+			}
+			return projects, nil
+		}),
+		"project": graphql.Resolver(func(args map[string]any) (any, error) {
+			name := graphql.StringArg(args, "name")
+			if !slices.Contains(h.projectNames(visible), name) {
+				return nil, nil
+			}
+			return h.graphqlProject(name, baseURL, visible), nil
+		}),
+		"audit_log": graphql.Resolver(func(args map[string]any) (any, error) {
+			var workflowID string
+			if ref := graphql.StringArg(args, "workflow_id"); ref != "" {
+				wf, ok := h.lookupWorkflow(ref, visible)
+				if !ok {
+					return nil, fmt.Errorf("workflow %q not found", ref)
+				}
+				workflowID = wf.ID
+			}
+			entries := h.store.ListAuditEntries(workflowID)
+			if visible != nil && workflowID == "" {
+				entries = slices.DeleteFunc(entries, func(entry storage.AuditEntry) bool {
+					wf, ok := h.store.Get(entry.WorkflowID)
+					return !ok || !visible(wf)
+				})
+			}
+			return graphqlAudit(entries, args)
+		}),
+		"spend": graphql.Resolver(func(args map[string]any) (any, error) {
+			month := graphql.StringArg(args, "month")
+			var months []any
+			for _, spend := range h.store.ListMonthlySpend() {
+				if month != "" && spend.Month != month {
+					continue
+				}
+				value, err := graphql.ToValue(spend)
+				if err != nil {
+					return nil, err
+				}
+				months = append(months, value)
+			}
+			return months, nil
+		}),
+		"statuses": graphql.Resolver(func(args map[string]any) (any, error) {
+			var statuses []any
+			for _, info := range storage.Statuses() {
+				statuses = append(statuses, map[string]any{
+					graphql.TypenameField: "Status",
+					"name":                info.Name,
+					"label":               info.Label,
+					"terminal":            info.Terminal,
+				})
+			}
+			return statuses, nil
+		}),
+	}
+}
+
+// graphqlWorkflows filters, paginates and converts workflows; visible, when not nil, narrows them further
+// The store is streamed and iteration stops once the requested page is complete.
+func (h *Handler) graphqlWorkflows(args map[string]any, baseURL string, visible func(*storage.WorkflowState) bool) (any, error) {
+	statuses := graphql.StringListArg(args, "status")
+	project := graphql.StringArg(args, "project")
+	externalRef := graphql.StringArg(args, "external_ref")
+	assignee := graphql.StringArg(args, "assignee")
+	language := graphql.StringArg(args, "language")
+	search := strings.ToLower(graphql.StringArg(args, "search"))
+	overdue, filterOverdue := graphql.BoolArg(args, "overdue")
+
+	offset := max(graphql.IntArg(args, "offset", 0), 0)
+	limit := max(min(graphql.IntArg(args, "limit", graphqlDefaultLimit), graphqlMaxLimit), 0)
+
+	result := []any{}
+	skipped := 0
+	for wf := range h.store.All() {
+		if len(result) >= limit {
+			break
+		}
+		switch {
+		case visible != nil && !visible(wf),
+			len(statuses) > 0 && !slices.Contains(statuses, wf.Status),
+			len(statuses) == 0 && storage.LookupStatus(wf.Status).Hidden,
+			project != "" && wf.Project != project,
+			externalRef != "" && wf.ExternalRef != externalRef,
+			assignee != "" && wf.Assignee != assignee,
+			language != "" && !strings.EqualFold(wf.Language, language),
+			filterOverdue && wf.IsOverdue() != overdue,
+			search != "" && !strings.Contains(strings.ToLower(wf.Title+"\n"+wf.TaskDescription+"\n"+wf.ExternalRef), search):
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		value, err := h.graphqlWorkflow(wf, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// graphqlWorkflow converts a workflow to a GraphQL object with computed fields
+func (h *Handler) graphqlWorkflow(wf *storage.WorkflowState, baseURL string) (map[string]any, error) {
+	value, err := graphql.ToValue(wf)
+	if err != nil {
+		return nil, err
+	}
+	value[graphql.TypenameField] = "Workflow"
+	value["status_label"] = storage.LookupStatus(wf.Status).Label
+	value["is_terminal"] = wf.IsTerminal()
+	value["is_overdue"] = wf.IsOverdue()
+	value["url"] = fmt.Sprintf("%s/workflow/%s", baseURL, wf.ID)
+	value["progress"] = nil
+	if progress, ok := h.engine.Progress(wf); ok {
+		if value["progress"], err = graphql.ToValue(progress); err != nil {
+			return nil, err
+		}
+	}
+	value["audit"] = graphql.Resolver(func(args map[string]any) (any, error) {
+		return graphqlAudit(h.store.ListAuditEntries(wf.ID), args)
+	})
+	return value, nil
+}
+
+// graphqlProject builds a project summary of the visible workflows (all when nil); workflows are
+// resolved on demand
+func (h *Handler) graphqlProject(name, baseURL string, visible func(*storage.WorkflowState) bool) map[string]any {
+	var count, open, overdue int
+	var nextDue *time.Time
+	for wf := range h.store.All() {
+		if wf.Project != name || (visible != nil && !visible(wf)) {
+			continue
+		}
+		count++
+		if wf.IsTerminal() {
+			continue
+		}
+		open++
+		if wf.IsOverdue() {
+			overdue++
+		}
+		if wf.DueAt != nil && (nextDue == nil || wf.DueAt.Before(*nextDue)) {
+			nextDue = wf.DueAt
+		}
+	}
+
+	return map[string]any{
+		graphql.TypenameField: "Project",
+		"name":                name,
+		"workflow_count":      count,
+		"open_count":          open,
+		"overdue_count":       overdue,
+		"next_due_at":         nextDue,
+		"workflows": graphql.Resolver(func(args map[string]any) (any, error) {
+			return h.graphqlWorkflows(map[string]any{
+				"status":  args["status"],
+				"project": name,
+				"limit":   graphqlMaxLimit,
+			}, baseURL, visible)
+		}),
+	}
+}
+
+// graphqlAudit converts audit entries, honouring the limit argument
+func graphqlAudit(entries []storage.AuditEntry, args map[string]any) (any, error) {
+	limit := min(graphql.IntArg(args, "limit", graphqlDefaultLimit), graphqlMaxLimit)
+	result := []any{}
+	for _, entry := range entries {
+		if len(result) >= limit {
+			break
+		}
+		value, err := graphql.ToValue(entry)
+		if err != nil {
+			return nil, err
+		}
+		value[graphql.TypenameField] = "AuditEntry"
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// projectNames returns the distinct non-empty project names of the visible workflows (all when
+// nil), sorted
+func (h *Handler) projectNames(visible func(*storage.WorkflowState) bool) []string {
+	seen := map[string]bool{}
+	var names []string
+	for wf := range h.store.All() {
+		if wf.Project != "" && !seen[wf.Project] && (visible == nil || visible(wf)) {
+			seen[wf.Project] = true
+			names = append(names, wf.Project)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// graphqlEventFields are present on every Event object; those not carried by an event are null
+var graphqlEventFields = []string{"workflow_id", "at", "step", "duration", "error", "from", "to", "by", "level", "done", "total"}
+
+// graphqlEvent converts an engine event to a GraphQL Event object
+func (h *Handler) graphqlEvent(event workflow.Event, baseURL string) (map[string]any, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return nil, err
+	}
+	for _, field := range graphqlEventFields {
+		if _, ok := value[field]; !ok {
+			value[field] = nil
+		}
+	}
+	value[graphql.TypenameField] = "Event"
+	value["name"] = event.Name()
+	value["workflow_id"] = event.WorkflowID()
+
+	// Prefer the snapshot carried by the event; fall back to the current state
+	var snapshot *storage.WorkflowState
+	switch e := event.(type) {
+	case workflow.StatusChanged:
+		snapshot = &e.Workflow
+	case workflow.Assigned:
+		snapshot = &e.Workflow
+	case workflow.Escalated:
+		snapshot = &e.Workflow
+	}
+	value["workflow"] = graphql.Resolver(func(args map[string]any) (any, error) {
+		wf := snapshot
+		if wf == nil {
+			var ok bool
+			if wf, ok = h.store.Get(event.WorkflowID()); !ok {
+				return nil, nil
+			}
+		}
+		return h.graphqlWorkflow(wf, baseURL)
+	})
+	return value, nil
+}
+
+// graphqlSubscription streams the "events" subscription as Server-Sent Events
+// Each engine event matching the arguments is sent as a "next" event holding a GraphQL response
+func (h *Handler) graphqlSubscription(c *fiber.Ctx, op *graphql.Operation) error {
+	if len(op.Selections) != 1 || op.Selections[0].Name != "events" {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ErrorResponse(fmt.Errorf("subscriptions support a single \"events\" field")))
+	}
+	field := op.Selections[0]
+	baseURL := c.BaseURL()
+	visible := h.listScope(c, h.viewerIdentity(c))
+
+	workflowID := graphql.StringArg(field.Args, "workflow_id")
+	if workflowID != "" {
+		wf, ok := h.lookupWorkflow(workflowID, visible)
+		if !ok {
+			return c.Status(http.StatusNotFound).JSON(graphql.ErrorResponse(fmt.Errorf("workflow %q not found", workflowID)))
+		}
+		workflowID = wf.ID
+		visible = nil // found by UUID or in the viewer's list
+	}
+	project := graphql.StringArg(field.Args, "project")
+	names := graphql.StringListArg(field.Args, "names")
+
+	// Validate the selection up front against an empty event
+	sample, err := h.graphqlEvent(workflow.StepStarted{}, baseURL)
+	if err == nil {
+		_, err = graphql.Select(map[string]any{field.Key(): sample}, op.Selections)
+	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(graphql.ResponseError(err))
+	}
+
+	events := make(chan workflow.Event, sseBufferSize)
+	unsubscribe := h.engine.Events().Subscribe(func(event workflow.Event) {
+		if workflowID != "" && event.WorkflowID() != workflowID {
+			return
+		}
+		if len(names) > 0 && !slices.Contains(names, event.Name()) {
+			return
+		}
+		if project != "" || visible != nil {
+			if wf, ok := h.store.Get(event.WorkflowID()); !ok || (project != "" && wf.Project != project) || (visible != nil && !visible(wf)) {
+				return
+			}
+		}
+		select {
+		case events <- event:
+		default:
+			// Slow client: drop the event rather than block the engine
+		}
+	})
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event := <-events:
+				var response graphql.Response
+				value, err := h.graphqlEvent(event, baseURL)
+				if err == nil {
+					response.Data, err = graphql.Select(map[string]any{field.Key(): value}, op.Selections)
+				}
+				if err != nil {
+					response = graphql.ResponseError(err)
+				}
+				payload, err := json.Marshal(response)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", payload); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			if err := w.Flush(); err != nil {
+				// Client went away
+				return
+			}
+		}
+	})
+
+	return nil
+}
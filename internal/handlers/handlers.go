@@ -0,0 +1,1136 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/config"
+	"workflower/internal/ui_templates"
+	"workflower/lib/accesslog"
+	"workflower/lib/lru"
+	"workflower/lib/ratelimit"
+	"workflower/lib/safehttp"
+	"workflower/lib/telegram"
+	"workflower/storage"
+	"workflower/users"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Page sizes of the workflows list
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// Handler holds dependencies for HTTP handlers
+type Handler struct {
+	cfg       *config.Config
+	store     *storage.Store
+	engine    *workflow.Engine
+	notifier  *telegram.Notifier
+	templates *ui_templates.TemplatesList
+	accessLog *accesslog.Logger          // nil when ACCESS_LOG_DIR is not set
+	pageCache *lru.Cache[string, []byte] // rendered list pages, nil when disabled
+
+	audioClient *http.Client // fetches audio_url references (see fetchAudio)
+
+	startLimits startLimiters
+	loginLimit  *ratelimit.Limiter // sign-in attempts per client IP, nil without a limit
+
+	deployReplays *replaySet    // deploy webhook deliveries already accepted (see DeployWebhook)
+	reviewReplays *replaySet    // review webhook deliveries already accepted (see ReviewWebhook)
+	restart       chan struct{} // see RestartRequested
+}
+
+// NewHandler creates a new handler instance
+func NewHandler(cfg *config.Config, store *storage.Store, engine *workflow.Engine, templates *ui_templates.TemplatesList) *Handler {
+	h := &Handler{
+		cfg:       cfg,
+		store:     store,
+		engine:    engine,
+		notifier:  telegram.NewNotifier(cfg.TelegramBotToken, cfg.TelegramChatID),
+		templates: templates,
+
+		deployReplays: newReplaySet(deployMaxAge),
+		reviewReplays: newReplaySet(reviewHookMaxAge),
+		restart:       make(chan struct{}, 1),
+	}
+	h.notifier.SetDryRun(cfg.DryRun)
+	if cfg.AccessLogDir != "" {
+		accessLog, err := accesslog.New(cfg.AccessLogDir, cfg.AccessLogRetention)
+		if err != nil {
+			slog.Error("Access log disabled", "error", err)
+		} else {
+			h.accessLog = accessLog
+		}
+	}
+	h.pageCache = h.newPageCache()
+	h.startLimits = h.newStartLimiters()
+	h.loginLimit = ratelimit.New(cfg.LoginRateLimitRPS, cfg.LoginRateLimitBurst)
+	h.audioClient = safehttp.NewClient(cfg.AudioURLTimeout, cfg.AudioURLAllowPrivate)
+	h.subscribeMediaCache()
+	return h
+}
+
+// RegisterRoutes sets up all HTTP routes
+func (h *Handler) RegisterRoutes(r *fiber.App) {
+	r.Use(h.requestID)
+	if h.accessLog != nil {
+		r.Use(h.accessLogMiddleware)
+	}
+	if corsMiddleware := h.newCORS(); corsMiddleware != nil {
+		r.Use(corsMiddleware)
+	}
+	r.Use(h.csrfProtect)
+	if h.pageCache != nil {
+		r.Use(h.invalidatePagesOnWrite)
+	}
+	if h.cfg.LoginRequired() {
+		r.Use(h.requireLogin)
+	}
+
+	// Static pages
+	r.Get("/", h.StartPage)
+	r.Get("/workflows", h.WorkflowsList)
+	r.Get("/workflow/:id", h.WorkflowStatus)
+	r.Get("/workflow/:id/events", h.WorkflowEvents)
+	r.Get("/workflow/:id/graph", h.WorkflowGraph)
+	r.Get("/workflow/:id/lineage", h.WorkflowLineage)
+	r.Get("/workflow/:id/audio", h.WorkflowAudio)
+	r.Get("/workflow/:id/video", h.WorkflowVideo)
+	r.Get(workflow.UploadsPath+"*", h.ServeUpload)
+	r.Get("/review/:id", h.ReviewPage)
+	r.Get("/w/:seq", h.ShortLink)
+
+	// API endpoints; viewers only see workflows, reviewing is checked per workflow (CanReview)
+	// Starting workflows spends OpenAI and Suno credits and is rate limited
+	reviewer := h.requireRole(users.RoleReviewer)
+	r.Post("/workflow/start", reviewer, h.limitStarts, h.StartWorkflow)
+	r.Post("/workflow/:id/submit", h.SubmitReview)
+	r.Post("/workflow/:id/draft", h.SaveDraft)
+	r.Post("/review/:id/check", h.CheckReview) // htmx fragments of the review page
+	r.Post("/review/:id/regenerate/:field", h.RegenerateReviewField)
+	r.Post("/workflow/:id/approve", h.QuickApprove) // quick actions of the list page
+	r.Post("/workflow/:id/reject", h.QuickReject)
+	r.Post("/workflow/:id/cancel", h.CancelWorkflow)
+	r.Post("/workflows/bulk", h.BulkWorkflows) // checkboxes of the list page
+	r.Post("/workflow/:id/due", reviewer, h.SetWorkflowDueDate)
+	r.Post("/workflow/:id/comments", reviewer, h.AddComment)
+	r.Post("/workflow/:id/assign", h.AssignWorkflow)
+	r.Post("/workflow/:id/steal", h.StealWorkflow)
+	r.Post("/workflow/:id/clone", reviewer, h.limitStarts, h.CloneWorkflow)
+	r.Post("/workflow/:id/retry", reviewer, h.limitStarts, h.RetryWorkflow)
+	r.Post("/workflow/:id/persona", reviewer, h.CreatePersona)
+	r.Post("/workflow/:id/delete", h.DeleteWorkflow) // HTML forms cannot send DELETE
+	r.Delete("/workflow/:id", h.DeleteWorkflow)
+	r.Post("/projects/:project/due", reviewer, h.SetProjectDueDate)
+	r.Post("/preferences/timezone", h.SetTimezone)
+	r.Post("/preferences/identity", h.SetIdentity)
+
+	// Web UI sessions
+	r.Get("/login", h.LoginPage)
+	r.Post("/login", h.limitLogins, h.Login)
+	r.Post("/logout", h.Logout)
+
+	// Telegram chat linking (/link CODE)
+	r.Get("/telegram", h.TelegramLinkPage)
+	r.Post("/telegram/link", h.CreateTelegramLinkCode)
+	r.Post("/telegram/unlink", h.UnlinkTelegram)
+
+	// Versioned JSON API
+	h.registerAPIRoutes(r, reviewer)
+
+	// Telegram webhook
+	r.Post(normalizeWebhookPath(h.cfg.TelegramWebhookPath), h.TelegramWebhook)
+
+	// Outbound webhook delivery log
+	r.Get("/webhooks/deliveries", h.WebhookDeliveries)
+
+	// Audit log (?workflow=ID or #N to filter)
+	r.Get("/audit", h.AuditLog)
+
+	// Review throughput per reviewer (from the audit log)
+	r.Get("/reports/reviewers", h.ReviewerReport)
+
+	// Keep rate of the style tags (from the tag taxonomy)
+	r.Get("/reports/tags", h.TagReport)
+
+	// Cumulative monthly spend
+	r.Get("/spend", h.Spend)
+
+	// Usage-based invoices per project (JSON, CSV or PDF)
+	r.Get("/reports/invoice", h.Invoice)
+
+	// GraphQL API (subscriptions are streamed as Server-Sent Events)
+	r.Get("/graphql", h.GraphQL)
+	r.Post("/graphql", h.GraphQL)
+	r.Get("/graphql/schema", h.GraphQLSchema)
+
+	// Runtime settings and presets (admins only)
+	r.Get("/admin", h.AdminPage)
+	r.Post("/admin/settings", h.SaveSettings)
+	r.Post("/admin/presets", h.SavePreset)
+	r.Post("/admin/presets/delete", h.DeletePreset)
+	r.Post("/admin/tags/sync", h.SyncTags)
+
+	// Redacted access log search (admins only)
+	r.Get("/admin/access-log", h.AccessLog)
+
+	// Re-announce reviews missed during a notification outage (admins only)
+	r.Post("/admin/renotify", h.ResendNotifications)
+
+	// Release deployment triggered by CI (HMAC-signed)
+	r.Post("/admin/deploy-webhook", h.DeployWebhook)
+
+	// Review decisions made in external tools (HMAC-signed)
+	r.Post(reviewHookPath, h.ReviewWebhook)
+
+	// Finished clips reported by suno-api (shared secret)
+	r.Post(workflow.SunoCallbackPath, h.SunoCallback)
+
+	// Health check and metrics
+	r.Get("/health", h.HealthCheck)
+	r.Get("/metrics", h.Metrics)
+}
+
+// StartPage renders the workflow starter form
+func (h *Handler) StartPage(c *fiber.Ctx) error {
+	return h.renderStart(c, "")
+}
+
+// renderStart renders the workflow starter form with a form error ("" for none)
+func (h *Handler) renderStart(c *fiber.Ctx, formError string) error {
+	viewer := h.viewerIdentity(c)
+	data := ui_templates.PageData{
+		Title:    "Create Song",
+		Location: h.viewerLocation(c),
+		Viewer:   viewer,
+		IsAdmin:  h.engine.IsAdmin(viewer),
+		CanEdit:  h.engine.Can(viewer, users.RoleReviewer),
+		Defaults: ui_templates.StartDefaults{
+			IsPremium:          h.engine.Settings().DefaultPremium,
+			GenerateStems:      h.cfg.GenerateStems,
+			Language:           h.startLanguage(),
+			DetectLanguage:     h.cfg.LanguageDetection,
+			Languages:          workflow.Languages,
+			LyricsEngine:       h.cfg.LyricsEngine,
+			HasOpenAI:          h.cfg.HasOpenAI(),
+			TranscriptMaxChars: h.cfg.TranscriptMaxChars,
+			SunoModel:          h.cfg.SunoModel,
+			SunoModels:         h.engine.SunoModels(),
+			SunoPersonas:       h.sunoPersonas(c),
+		},
+		Error: formError,
+		CSRF:  h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Start.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
+
+// WorkflowsList shows one page of workflows, newest first (served from the render cache when enabled)
+// ?status= filters by status (one tab per status), ?before= is the cursor returned as the "Older"
+// link, ?limit= the page size (default 50).
+func (h *Handler) WorkflowsList(c *fiber.Ctx) error {
+	return h.cachedPage(c, func() ([]byte, error) {
+		limit := c.QueryInt("limit", defaultListLimit)
+		if limit <= 0 || limit > maxListLimit {
+			limit = maxListLimit
+		}
+		status := c.Query("status")
+		if !slices.ContainsFunc(storage.Statuses(), func(info storage.StatusInfo) bool { return info.Name == status }) {
+			status = "" // unknown status, show everything
+		}
+		viewer := h.viewerIdentity(c)
+		scope := h.listScope(c, viewer)
+		workflows, next := h.listPage(c.QueryInt("before", 0), limit, status, "", "", scope)
+
+		rows := make([]listRow, len(workflows))
+		for i, wf := range workflows {
+			rows[i] = listRow{WorkflowState: wf, CanReview: !wf.IsTerminal() && h.engine.CanReview(wf, viewer)}
+		}
+
+		self := "/workflows"
+		if status != "" {
+			self += "?status=" + url.QueryEscape(status)
+		}
+		data := ui_templates.PageData{
+			Title:     "Workflows",
+			Workflows: rows,
+			Filter:    listFilter{Status: status, Tabs: statusTabs(status), Self: self, Scoped: scope != nil},
+			Location:  h.viewerLocation(c),
+			Viewer:    viewer,
+			IsAdmin:   h.engine.IsAdmin(viewer),
+			CSRF:      h.csrfToken(c),
+		}
+		if next > 0 {
+			data.NextPage = fmt.Sprintf("/workflows?before=%d&limit=%d", next, limit)
+			if status != "" {
+				data.NextPage += "&status=" + url.QueryEscape(status)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := h.templates.List.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("Template error: %v", err)
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// listRow is a workflow of the list page with the quick actions the viewer may use on it
+type listRow struct {
+	*storage.WorkflowState
+	CanReview bool // approve, reject and cancel from the list
+}
+
+// listFilter is the status filter of the list page
+type listFilter struct {
+	Status string      // selected status, "" for all
+	Tabs   []statusTab // "All" followed by every registered status
+	Self   string      // URL of the first page of the current filter
+	Scoped bool        // only the viewer's own workflows are listed (see listScope)
+}
+
+// statusTab is one status filter tab of the list page
+type statusTab struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+// statusTabs returns the filter tabs of the list page, status being the selected one
+func statusTabs(status string) []statusTab {
+	tabs := []statusTab{{Label: "all", URL: "/workflows", Active: status == ""}}
+	for _, info := range storage.Statuses() {
+		tabs = append(tabs, statusTab{
+			Label:  info.Label,
+			URL:    "/workflows?status=" + url.QueryEscape(info.Name),
+			Active: info.Name == status,
+		})
+	}
+	return tabs
+}
+
+// listPage returns up to limit workflows older than the before cursor, newest first, with the
+// given status, project and external reference ("" matches any), and the cursor of the next page
+// (0 on the last page); visible, when not nil, narrows the list further (see listScope)
+// Without a status, workflows of hidden statuses (expired) are left out.
+func (h *Handler) listPage(before, limit int, status, project, externalRef string, visible func(*storage.WorkflowState) bool) ([]*storage.WorkflowState, int) {
+	var workflows []*storage.WorkflowState
+	for wf := range h.store.Before(before) {
+		if (status != "" && wf.Status != status) || (project != "" && wf.Project != project) ||
+			(externalRef != "" && wf.ExternalRef != externalRef) || (visible != nil && !visible(wf)) {
+			continue
+		}
+		if status == "" && storage.LookupStatus(wf.Status).Hidden {
+			continue
+		}
+		if len(workflows) == limit {
+			return workflows, workflows[len(workflows)-1].Seq
+		}
+		workflows = append(workflows, wf)
+	}
+	return workflows, 0
+}
+
+// WorkflowStatus shows the status of a specific workflow
+func (h *Handler) WorkflowStatus(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	wf, ok := h.store.Get(id)
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	// If awaiting review, redirect to review page (when the viewer may review it)
+	viewer := h.viewerIdentity(c)
+	if wf.Status == storage.StatusAwaitingReview && h.engine.CanReview(wf, viewer) {
+		return c.Redirect("/review/"+id, http.StatusFound)
+	}
+
+	data := ui_templates.PageData{
+		Title:    "Workflow Status",
+		Workflow: wf,
+		Progress: h.progress(wf),
+		Lineage:  h.lineageGraph(wf),
+		Location: h.viewerLocation(c),
+		Viewer:   viewer,
+		IsAdmin:  h.engine.IsAdmin(viewer),
+		CanEdit:  h.engine.Can(viewer, users.RoleReviewer),
+		CSRF:     h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Status.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
+
+// ShortLink redirects a human-friendly sequence number to its workflow
+func (h *Handler) ShortLink(c *fiber.Ctx) error {
+	seq, err := strconv.Atoi(strings.TrimPrefix(c.Params("seq"), "#"))
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, "Invalid sequence number")
+	}
+
+	wf, ok := h.viewerWorkflow(c, strconv.Itoa(seq))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	return c.Redirect("/workflow/"+wf.ID, http.StatusFound)
+}
+
+// ReviewPage shows the human-in-the-loop review form
+func (h *Handler) ReviewPage(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	wf, ok := h.store.Get(id)
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	if wf.Status != storage.StatusAwaitingReview {
+		return c.Redirect("/workflow/"+id, http.StatusFound)
+	}
+
+	// Review links sent to Telegram assignees identify the chat on the web
+	viewer := h.viewerIdentity(c)
+	if token := c.Query("as"); token != "" && h.engine.VerifyReviewToken(wf, token) {
+		viewer = wf.Assignee
+		h.setIdentityCookie(c, viewer)
+	}
+	if viewer == "" && h.cfg.LoginRequired() {
+		return c.Redirect("/login?next="+url.QueryEscape("/review/"+id), http.StatusFound)
+	}
+	if !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	return h.renderReview(c, wf, viewer)
+}
+
+// renderReview renders the review form of a workflow for viewer
+func (h *Handler) renderReview(c *fiber.Ctx, wf *storage.WorkflowState, viewer string) error {
+	var buf bytes.Buffer
+	if err := h.templates.Review.Execute(&buf, h.reviewData(c, wf, viewer)); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
+
+// reviewData returns the data of the review page and its fragments
+func (h *Handler) reviewData(c *fiber.Ctx, wf *storage.WorkflowState, viewer string) ui_templates.PageData {
+	data := ui_templates.PageData{
+		Title:    "Review",
+		Workflow: wf,
+		Location: h.viewerLocation(c),
+		Spend:    h.engine.MonthlySpend(time.Now()),
+		Viewer:   viewer,
+		IsAdmin:  h.engine.IsAdmin(viewer),
+		CanEdit:  h.engine.Can(viewer, users.RoleReviewer),
+		Upload:   h.engine.UploadURL(wf.AudioFilePath, time.Now()),
+		Presets:  h.engine.Settings().Presets,
+		CSRF:     h.csrfToken(c),
+		Defaults: ui_templates.StartDefaults{HasOpenAI: h.cfg.HasOpenAI(), SunoModel: h.cfg.SunoModel, SunoModels: h.engine.SunoModels()},
+	}
+	if wf.IsPremium {
+		data.Defaults.SunoPersonas = h.sunoPersonas(c)
+	}
+	if props := wf.EditedProperties; props != nil {
+		data.Tags = h.engine.UnknownTags(props.Style + "," + props.VocalType)
+	}
+	return data
+}
+
+// StartWorkflow handles the workflow creation request
+func (h *Handler) StartWorkflow(c *fiber.Ctx) error {
+	taskDescription := c.FormValue("task_description")
+	transcript := c.FormValue("transcript")
+	lyrics := strings.TrimSpace(c.FormValue("lyrics"))
+	if strings.TrimSpace(taskDescription) == "" && strings.TrimSpace(transcript) == "" && lyrics == "" {
+		return h.fail(c, http.StatusBadRequest, "Task description, transcript or lyrics are required")
+	}
+	if err := h.engine.CheckTranscript(transcript); err != nil {
+		return h.failWith(c, err)
+	}
+	sourceLyrics := c.FormValue("source_lyrics")
+	if err := h.engine.CheckSourceLyrics(sourceLyrics, lyrics != ""); err != nil {
+		return h.failWith(c, err)
+	}
+
+	isPremium := c.FormValue("is_premium") == "true"
+
+	dueAt, err := parseDueDate(c.FormValue("due_date"), h.viewerLocation(c))
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	lyricsEngine := c.FormValue("lyrics_engine")
+	language, err := h.validateStartOptions(c.FormValue("language"), lyricsEngine)
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	// Handle audio file upload, or fetch the track given as audio_url
+	var audioFilePath, audioFileName string
+	fileHeader, _ := c.FormFile("audio_file")
+	audioURL := strings.TrimSpace(c.FormValue("audio_url"))
+	switch {
+	case fileHeader != nil && audioURL != "":
+		return h.renderStart(c.Status(http.StatusBadRequest), "Choose either an audio file or an audio URL.")
+	case fileHeader != nil:
+		audioFilePath, audioFileName, err = h.saveUpload(fileHeader)
+	case audioURL != "":
+		audioFilePath, audioFileName, err = h.fetchAudio(c.UserContext(), audioURL)
+	}
+	var rejected *uploadError
+	if errors.As(err, &rejected) {
+		return h.renderStart(c.Status(rejected.status), rejected.msg)
+	}
+	if err != nil {
+		return h.fail(c, http.StatusInternalServerError, err.Error())
+	}
+
+	// Start the workflow
+	state, err := h.engine.StartWorkflow(c.UserContext(), workflow.StartParams{
+		Project:         c.FormValue("project"),
+		ExternalRef:     c.FormValue("external_ref"),
+		TaskDescription: taskDescription,
+		Transcript:      transcript,
+		Lyrics:          lyrics,
+		LyricsImported:  lyrics != "",
+		AddBrackets:     c.FormValue("add_brackets") == "true",
+		SourceLyrics:    sourceLyrics,
+		IsPremium:       isPremium,
+		AudioFilePath:   audioFilePath,
+		AudioFileName:   audioFileName,
+		DueAt:           dueAt,
+		LongSong:        c.FormValue("long_song") == "true",
+		Assignee:        strings.TrimSpace(c.FormValue("assignee")),
+		GenerateStems:   c.FormValue("generate_stems") == "true",
+		Language:        language,
+		LyricsEngine:    lyricsEngine,
+		SunoModel:       c.FormValue("suno_model"),
+		SunoPersonaID:   c.FormValue("suno_persona_id"),
+		Session:         h.browserSession(c, true),
+	})
+	if err != nil {
+		return h.failWith(c, fmt.Errorf("failed to start workflow: %w", err))
+	}
+
+	// Redirect to workflow status page
+	return c.Redirect("/workflow/"+state.ID, http.StatusFound)
+}
+
+// validateStartOptions checks the language and lyrics engine of a new workflow and
+// returns the language name to use ("" to detect it when none is given)
+func (h *Handler) validateStartOptions(language, lyricsEngine string) (string, error) {
+	if lyricsEngine != "" && lyricsEngine != workflow.LyricsEngineOpenAI && lyricsEngine != workflow.LyricsEngineSuno {
+		return "", fmt.Errorf("Unsupported lyrics engine: %s", lyricsEngine)
+	}
+	if language == "" || language == languageAuto {
+		return "", nil // detected by the engine, or DEFAULT_LANGUAGE
+	}
+	code, ok := workflow.LookupLanguage(language)
+	if !ok {
+		return "", fmt.Errorf("Unsupported language: %s", language)
+	}
+	return code, nil
+}
+
+// CloneWorkflow starts a new workflow seeded from an existing one (keep_lyrics=true reuses its lyrics)
+func (h *Handler) CloneWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	state, err := h.engine.CloneWorkflow(c.UserContext(), wf, c.FormValue("keep_lyrics") == "true", h.browserSession(c, true))
+	if err != nil {
+		return h.failWith(c, workflow.ForWorkflow(fmt.Errorf("failed to clone workflow: %w", err), wf.ID))
+	}
+	return c.Redirect("/workflow/"+state.ID, http.StatusFound)
+}
+
+// RetryWorkflow re-runs a failed workflow from the step that failed
+func (h *Handler) RetryWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	step, err := h.engine.RetryWorkflow(c.UserContext(), wf)
+	if err != nil {
+		return h.failWith(c, workflow.ForWorkflow(fmt.Errorf("failed to retry workflow: %w", err), wf.ID))
+	}
+	slog.InfoContext(c.UserContext(), "Workflow retried", "workflow_id", wf.ID, "step", step, "by", h.viewerIdentity(c))
+	return c.Redirect("/workflow/"+wf.ID, http.StatusFound)
+}
+
+// DeleteWorkflow removes a workflow with its uploaded audio (admins only)
+// The workflow number must be repeated in "confirm" (form value or query) as a confirmation
+// step; DELETE requests answer 204, form posts redirect to the workflows list.
+func (h *Handler) DeleteWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, "Only admins can delete workflows")
+	}
+	confirm := strings.TrimPrefix(strings.TrimSpace(c.FormValue("confirm", c.Query("confirm"))), "#")
+	if confirm != strconv.Itoa(wf.Seq) && confirm != wf.ID {
+		return h.failWorkflow(c, http.StatusBadRequest, wf.ID, fmt.Sprintf("Confirm by repeating the workflow number (%d)", wf.Seq))
+	}
+
+	h.engine.DeleteWorkflow(wf, viewer)
+	if c.Method() == fiber.MethodDelete {
+		return c.SendStatus(http.StatusNoContent)
+	}
+	return c.Redirect("/workflows", http.StatusFound)
+}
+
+// SubmitReview handles the review form submission
+func (h *Handler) SubmitReview(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	wf, ok := h.store.Get(id)
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	viewer := h.viewerIdentity(c)
+	if !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	action := c.FormValue("action")
+
+	if action == "reject" {
+		if err := h.engine.RejectWorkflow(wf, viewer); err != nil {
+			return h.failWith(c, err)
+		}
+		return c.Redirect("/workflow/"+id, http.StatusFound)
+	}
+
+	if err := h.engine.EditReview(wf, func(wf *storage.WorkflowState) { applyReviewEdits(c, wf) }); err != nil {
+		return h.failWith(c, err)
+	}
+
+	// Approve and submit to Suno
+	if err := h.engine.ApproveWorkflow(c.UserContext(), wf, viewer); err != nil {
+		if errors.Is(err, workflow.ErrInvalidLyrics) {
+			// Show the blocking issues with the reviewer's edits kept
+			c.Status(http.StatusUnprocessableEntity)
+			return h.renderReview(c, wf, viewer)
+		}
+		return h.failWith(c, workflow.ForWorkflow(fmt.Errorf("failed to approve workflow: %w", err), wf.ID))
+	}
+
+	return c.Redirect("/workflow/"+id, http.StatusFound)
+}
+
+// applyReviewEdits copies the edits of the review form onto a workflow
+func applyReviewEdits(c *fiber.Ctx, wf *storage.WorkflowState) {
+	wf.EditedLyrics = c.FormValue("edited_lyrics")
+	wf.LintOverridden = c.FormValue("override_lint") == "true"
+
+	// A title matching the rendered one keeps following the naming template
+	if title := strings.TrimSpace(c.FormValue("title")); title != wf.Title {
+		wf.EditedTitle = title
+	}
+
+	// Parse properties
+	weirdness, _ := strconv.ParseFloat(c.FormValue("weirdness"), 64)
+	wf.EditedProperties = &storage.SunoProperties{
+		Style:          c.FormValue("style"),
+		VocalType:      c.FormValue("vocal_type"),
+		Weirdness:      weirdness,
+		StyleInfluence: c.FormValue("style_influence"),
+		Model:          strings.TrimSpace(c.FormValue("suno_model")),
+	}
+
+	// A/B submission: a second property set for the same lyrics
+	if c.FormValue("ab_test") == "true" {
+		weirdnessB, _ := strconv.ParseFloat(c.FormValue("b_weirdness"), 64)
+		wf.VariantB = &storage.SunoProperties{
+			Style:          c.FormValue("b_style"),
+			VocalType:      c.FormValue("b_vocal_type"),
+			Weirdness:      weirdnessB,
+			StyleInfluence: c.FormValue("b_style_influence"),
+			Model:          strings.TrimSpace(c.FormValue("b_suno_model")),
+		}
+	} else {
+		wf.VariantB = nil
+	}
+
+	// Update premium features if present
+	if wf.IsPremium {
+		persona := c.FormValue("persona")
+		inspo := c.FormValue("inspo")
+		personaID := strings.TrimSpace(c.FormValue("persona_id"))
+		if persona != "" || inspo != "" || personaID != "" {
+			wf.PersonaInspo = &storage.PersonaInspo{
+				Persona:   persona,
+				Inspo:     inspo,
+				PersonaID: personaID,
+			}
+		}
+	}
+}
+
+// TelegramWebhook handles incoming Telegram webhook updates.
+func (h *Handler) TelegramWebhook(c *fiber.Ctx) error {
+	if h.cfg.TelegramBotToken == "" {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "telegram_disabled"})
+	}
+
+	if !telegram.VerifyWebhookSecret(c.Get(telegram.WebhookSecretHeader), h.cfg.TelegramWebhookSecret) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"status": "unauthorized"})
+	}
+
+	var update telegram.Update
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"status": "invalid_payload"})
+	}
+
+	go h.handleTelegramUpdate(c.UserContext(), update)
+	return c.SendStatus(http.StatusOK)
+}
+
+func (h *Handler) handleTelegramUpdate(ctx context.Context, update telegram.Update) {
+	message := telegram.ExtractMessage(&update)
+	if message == nil {
+		return
+	}
+	if message.From != nil && message.From.IsBot {
+		return
+	}
+
+	text := strings.TrimSpace(message.Text)
+	if text == "" {
+		text = strings.TrimSpace(message.Caption)
+	}
+	if text == "" {
+		return
+	}
+
+	chatID := strconv.FormatInt(message.Chat.ID, 10)
+	command, args := parseTelegramCommand(text)
+	// Any chat may link itself to a web account; linked chats are accepted like the default chat
+	if command == "/link" {
+		h.linkTelegramChat(ctx, chatID, args)
+		return
+	}
+	if h.cfg.TelegramChatID != "" && chatID != h.cfg.TelegramChatID && h.store.GetChatPreferences(chatID).LinkedUser == "" {
+		slog.InfoContext(ctx, "Telegram webhook ignored chat", "chat_id", chatID, "expected", h.cfg.TelegramChatID)
+		return
+	}
+
+	baseURL := strings.TrimRight(h.cfg.BaseURL, "/")
+	switch command {
+	case "/start", "/help":
+		h.replyTelegramHelp(ctx, chatID)
+		return
+	case "/status":
+		if strings.TrimSpace(args) == "" {
+			h.replyTelegram(ctx, chatID, "usage_status", nil)
+			return
+		}
+		h.replyTelegramStatus(ctx, chatID, args, baseURL)
+		return
+	case "/tz":
+		h.setTelegramTimezone(ctx, chatID, args)
+		return
+	case "/language":
+		h.setTelegramLanguage(ctx, chatID, args)
+		return
+	case "/premium":
+		if strings.TrimSpace(args) == "" {
+			h.replyTelegram(ctx, chatID, "usage_premium", nil)
+			return
+		}
+		h.startWorkflowFromTelegram(ctx, chatID, args, true, "", baseURL)
+		return
+	case "/basic":
+		if strings.TrimSpace(args) == "" {
+			h.replyTelegram(ctx, chatID, "usage_basic", nil)
+			return
+		}
+		h.startWorkflowFromTelegram(ctx, chatID, args, false, "", baseURL)
+		return
+	case "/lang":
+		h.startLanguageWorkflowFromTelegram(ctx, chatID, args, baseURL)
+		return
+	case "/audio":
+		h.startAudioWorkflowFromTelegram(ctx, chatID, args, baseURL)
+		return
+	case "/lyrics":
+		if strings.TrimSpace(args) == "" {
+			h.replyTelegram(ctx, chatID, "usage_lyrics", nil)
+			return
+		}
+		h.launchTelegramWorkflow(ctx, chatID, workflow.StartParams{
+			Lyrics:         strings.TrimSpace(args),
+			LyricsImported: true,
+			AddBrackets:    true,
+			IsPremium:      h.engine.Settings().DefaultPremium,
+			GenerateStems:  h.cfg.GenerateStems,
+		}, baseURL)
+		return
+	case "/transcript":
+		if strings.TrimSpace(args) == "" {
+			h.replyTelegram(ctx, chatID, "usage_transcript", nil)
+			return
+		}
+		h.startTranscriptWorkflowFromTelegram(ctx, chatID, args, baseURL)
+		return
+	default:
+		if command != "" {
+			h.replyTelegram(ctx, chatID, "unknown_command", nil)
+			return
+		}
+		h.startWorkflowFromTelegram(ctx, chatID, args, h.engine.Settings().DefaultPremium, "", baseURL)
+	}
+}
+
+// startLanguageWorkflowFromTelegram handles "/lang CODE [/premium|/basic] task description"
+func (h *Handler) startLanguageWorkflowFromTelegram(ctx context.Context, chatID, args, baseURL string) {
+	code, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	language, ok := workflow.LookupLanguage(code)
+	if !ok {
+		h.replyTelegram(ctx, chatID, "usage_lang", map[string]any{"Supported": languageCodes()})
+		return
+	}
+
+	// The mode can follow the language: /lang es /premium ...
+	isPremium := h.engine.Settings().DefaultPremium
+	command, task := parseTelegramCommand(strings.TrimSpace(rest))
+	switch command {
+	case "":
+	case "/premium":
+		isPremium = true
+	case "/basic":
+		isPremium = false
+	default:
+		h.replyTelegram(ctx, chatID, "usage_lang", nil)
+		return
+	}
+	if strings.TrimSpace(task) == "" {
+		h.replyTelegram(ctx, chatID, "usage_lang", nil)
+		return
+	}
+
+	h.startWorkflowFromTelegram(ctx, chatID, task, isPremium, language, baseURL)
+}
+
+func (h *Handler) startWorkflowFromTelegram(ctx context.Context, chatID, task string, isPremium bool, language, baseURL string) {
+	task = strings.TrimSpace(task)
+	if task == "" {
+		h.replyTelegram(ctx, chatID, "task_required", nil)
+		return
+	}
+	h.launchTelegramWorkflow(ctx, chatID, workflow.StartParams{
+		TaskDescription: task,
+		IsPremium:       isPremium,
+		GenerateStems:   h.cfg.GenerateStems,
+		Language:        language,
+	}, baseURL)
+}
+
+// startAudioWorkflowFromTelegram handles "/audio URL task description": a workflow with a
+// reference track hosted elsewhere, fetched like the audio_url of the start form
+func (h *Handler) startAudioWorkflowFromTelegram(ctx context.Context, chatID, args, baseURL string) {
+	audioURL, task, _ := strings.Cut(strings.TrimSpace(args), " ")
+	task = strings.TrimSpace(task)
+	if audioURL == "" || task == "" {
+		h.replyTelegram(ctx, chatID, "usage_audio", nil)
+		return
+	}
+	// Checked before fetching as well, so that viewers cannot make the server download
+	if !h.engine.Can(h.engine.ChatIdentity(chatID), users.RoleReviewer) {
+		h.replyTelegram(ctx, chatID, "reviewer_required", nil)
+		return
+	}
+
+	audioFilePath, audioFileName, err := h.fetchAudio(ctx, audioURL)
+	if err != nil {
+		h.replyTelegram(ctx, chatID, "start_failed", map[string]any{"Error": err.Error()})
+		return
+	}
+	h.launchTelegramWorkflow(ctx, chatID, workflow.StartParams{
+		TaskDescription: task,
+		IsPremium:       h.engine.Settings().DefaultPremium,
+		AudioFilePath:   audioFilePath,
+		AudioFileName:   audioFileName,
+		GenerateStems:   h.cfg.GenerateStems,
+	}, baseURL)
+}
+
+// startTranscriptWorkflowFromTelegram handles "/transcript TEXT": a song from a pasted chat or diary
+func (h *Handler) startTranscriptWorkflowFromTelegram(ctx context.Context, chatID, transcript, baseURL string) {
+	if err := h.engine.CheckTranscript(transcript); err != nil {
+		h.replyTelegram(ctx, chatID, "start_failed", map[string]any{"Error": err.Error()})
+		return
+	}
+	h.launchTelegramWorkflow(ctx, chatID, workflow.StartParams{
+		Transcript:    transcript,
+		IsPremium:     h.engine.Settings().DefaultPremium,
+		GenerateStems: h.cfg.GenerateStems,
+	}, baseURL)
+}
+
+// launchTelegramWorkflow starts a workflow for a chat, within its role and rate limits, and replies with the link
+func (h *Handler) launchTelegramWorkflow(ctx context.Context, chatID string, params workflow.StartParams, baseURL string) {
+	identity := h.engine.ChatIdentity(chatID)
+	if !h.engine.Can(identity, users.RoleReviewer) {
+		h.replyTelegram(ctx, chatID, "reviewer_required", nil)
+		return
+	}
+	if ok, wait := h.startLimits.allowStart("", identity); !ok {
+		h.replyTelegram(ctx, chatID, "rate_limited", map[string]any{"Seconds": math.Ceil(wait.Seconds())})
+		return
+	}
+
+	state, err := h.engine.StartWorkflow(ctx, params)
+	if err != nil {
+		h.replyTelegram(ctx, chatID, "start_failed", map[string]any{"Error": err.Error()})
+		return
+	}
+
+	h.engine.NoteChatLanguage(chatID, state)
+	h.replyTelegram(ctx, chatID, "workflow_started", map[string]any{
+		"Workflow": state, "URL": fmt.Sprintf("%s/w/%d", baseURL, state.Seq),
+	})
+}
+
+func (h *Handler) replyTelegramStatus(ctx context.Context, chatID, workflowID, baseURL string) {
+	id := strings.TrimSpace(workflowID)
+	if id == "" {
+		h.replyTelegram(ctx, chatID, "usage_status", nil)
+		return
+	}
+
+	wf, ok := h.lookupWorkflow(id, nil)
+	if !ok {
+		h.replyTelegram(ctx, chatID, "workflow_not_found", nil)
+		return
+	}
+
+	var reviewURL string
+	if wf.Status == storage.StatusAwaitingReview {
+		reviewURL = fmt.Sprintf("%s/review/%s", baseURL, wf.ID)
+	}
+	h.replyTelegram(ctx, chatID, "workflow_status", map[string]any{
+		"Workflow":  wf,
+		"Updated":   h.cfg.Locale.Display(wf.UpdatedAt, h.engine.ChatLocation(chatID)),
+		"URL":       fmt.Sprintf("%s/workflow/%s", baseURL, wf.ID),
+		"Progress":  h.progress(wf),
+		"ReviewURL": reviewURL,
+	})
+}
+
+// progress returns the progress estimate shown on the status page, nil when there is none
+func (h *Handler) progress(wf *storage.WorkflowState) *workflow.ProgressEstimate {
+	progress, ok := h.engine.Progress(wf)
+	if !ok || wf.IsTerminal() {
+		return nil
+	}
+	return &progress
+}
+
+// lookupWorkflow resolves a workflow by UUID or by sequence number ("42" or "#42"); visible,
+// when not nil, narrows the workflows found by sequence number, which unlike UUIDs can be walked
+func (h *Handler) lookupWorkflow(ref string, visible func(*storage.WorkflowState) bool) (*storage.WorkflowState, bool) {
+	if wf, ok := h.store.Get(ref); ok {
+		return wf, true
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, false
+	}
+	wf, ok := h.store.GetBySeq(seq)
+	if !ok || (visible != nil && !visible(wf)) {
+		return nil, false
+	}
+	return wf, true
+}
+
+// viewerWorkflow resolves a workflow for the viewer of c: sequence numbers only reach the
+// workflows of their list (see listScope)
+func (h *Handler) viewerWorkflow(c *fiber.Ctx, ref string) (*storage.WorkflowState, bool) {
+	return h.lookupWorkflow(ref, h.listScope(c, h.viewerIdentity(c)))
+}
+
+func (h *Handler) replyTelegramHelp(ctx context.Context, chatID string) {
+	h.replyTelegram(ctx, chatID, "help", map[string]any{"Premium": h.engine.Settings().DefaultPremium})
+}
+
+// defaultLanguage returns DEFAULT_LANGUAGE, falling back to English when it is not supported
+func (h *Handler) defaultLanguage() string {
+	if language, ok := workflow.LookupLanguage(h.cfg.DefaultLanguage); ok {
+		return language
+	}
+	return workflow.Languages[0].Name
+}
+
+// languageAuto asks for the language of a new workflow to be detected from its text
+const languageAuto = "auto"
+
+// startLanguage returns the language preselected on the start form, "" for auto-detection
+func (h *Handler) startLanguage() string {
+	if h.cfg.LanguageDetection {
+		return ""
+	}
+	return h.defaultLanguage()
+}
+
+func languageCodes() string {
+	codes := make([]string, len(workflow.Languages))
+	for i, lang := range workflow.Languages {
+		codes[i] = lang.Code
+	}
+	return strings.Join(codes, ", ")
+}
+
+// replyTelegram sends a message of the catalog to a chat, in the chat's language (see workflow.Engine.Message)
+func (h *Handler) replyTelegram(ctx context.Context, chatID, name string, data any) {
+	if err := h.notifier.SendToChat(ctx, chatID, h.engine.Message(chatID, name, data)); err != nil {
+		slog.WarnContext(ctx, "Failed to send Telegram reply", "error", err, "chat_id", chatID)
+	}
+}
+
+// WebhookDeliveries returns the outbound webhook delivery log, newest first
+func (h *Handler) WebhookDeliveries(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"deliveries": h.store.ListWebhookDeliveries(),
+	})
+}
+
+// AuditLog returns the audit log, newest first, optionally filtered by workflow
+func (h *Handler) AuditLog(c *fiber.Ctx) error {
+	var workflowID string
+	if ref := c.Query("workflow"); ref != "" {
+		wf, ok := h.viewerWorkflow(c, ref)
+		if !ok {
+			// Entries of deleted workflows are still found by ID
+			if entries := h.store.ListAuditEntries(ref); len(entries) > 0 {
+				return c.JSON(fiber.Map{"entries": entries})
+			}
+			return h.workflowNotFound(c)
+		}
+		workflowID = wf.ID
+	}
+	return c.JSON(fiber.Map{
+		"entries": h.store.ListAuditEntries(workflowID),
+	})
+}
+
+// ReviewerReport returns review turnaround, approval and change rates per reviewer and the
+// busiest hours over the last ?days= days (default 30); ?format=csv returns the reviewers as a
+// spreadsheet formatted for LOCALE
+func (h *Handler) ReviewerReport(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	if days <= 0 {
+		return h.fail(c, http.StatusBadRequest, "days must be positive")
+	}
+	now := time.Now()
+	report := h.engine.ReviewReport(now.AddDate(0, 0, -days), now)
+	if c.Query("format") != "csv" {
+		return c.JSON(report)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf, h.cfg.Locale, h.cfg.DisplayLocation); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="reviewers-%s.csv"`, now.In(h.cfg.DisplayLocation).Format("2006-01-02")))
+	return c.Send(buf.Bytes())
+}
+
+// Spend returns the cumulative spend per month, newest first
+func (h *Handler) Spend(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"months": h.store.ListMonthlySpend(),
+	})
+}
+
+// HealthCheck returns server health status
+// With ?deep=1 every dependency is checked as well; the answer is 503 when one of them is down.
+func (h *Handler) HealthCheck(c *fiber.Ctx) error {
+	health := fiber.Map{
+		"status":    "ok",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"version":   config.Version,
+		"suno":      h.engine.SunoHealth(),
+	}
+	if accounts := h.engine.SunoAccounts(); accounts != nil {
+		health["suno_accounts"] = accounts
+	}
+	if !c.QueryBool("deep") {
+		return c.Status(http.StatusOK).JSON(health)
+	}
+
+	report := h.engine.DeepHealth(c.UserContext())
+	health["status"] = report.Status
+	health["checked_at"] = report.CheckedAt
+	health["dependencies"] = report.Dependencies
+	if report.Status == workflow.HealthDown {
+		return c.Status(http.StatusServiceUnavailable).JSON(health)
+	}
+	return c.Status(http.StatusOK).JSON(health)
+}
+
+func normalizeWebhookPath(path string) string {
+	normalized := strings.TrimSpace(path)
+	if normalized == "" {
+		return "/telegram/webhook"
+	}
+	if !strings.HasPrefix(normalized, "/") {
+		normalized = "/" + normalized
+	}
+	return normalized
+}
+
+func parseTelegramCommand(text string) (string, string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return "", ""
+	}
+	if !strings.HasPrefix(trimmed, "/") {
+		return "", trimmed
+	}
+
+	parts := strings.Fields(trimmed)
+	command := parts[0]
+	if at := strings.Index(command, "@"); at != -1 {
+		command = command[:at]
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(trimmed, parts[0]))
+	return strings.ToLower(command), args
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/storage"
+	"workflower/users"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	identityCookie    = "user"
+	maxIdentityLength = 64
+)
+
+// SetIdentity stores the viewer's name in a signed cookie; an empty name signs out
+// Admin names (ADMIN_USERS) additionally require ADMIN_TOKEN. When login is required
+// names are only set by signing in (see Login).
+func (h *Handler) SetIdentity(c *fiber.Ctx) error {
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		h.setIdentityCookie(c, "")
+		return c.Redirect(safeReferer(c), http.StatusFound)
+	}
+
+	if h.cfg.LoginRequired() {
+		return h.fail(c, http.StatusForbidden, "Sign in at /login")
+	}
+	if len(name) > maxIdentityLength || strings.ContainsAny(name, ".;, ") {
+		return h.fail(c, http.StatusBadRequest, "Name must be at most 64 characters without spaces, dots, commas or semicolons")
+	}
+	if strings.HasPrefix(name, workflow.TelegramIdentityPrefix) {
+		return h.fail(c, http.StatusBadRequest, "Telegram identities are set through review links")
+	}
+	if h.engine.IsAdmin(name) {
+		token := c.FormValue("token")
+		if h.cfg.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.AdminToken)) != 1 {
+			return h.fail(c, http.StatusForbidden, "Admin token required")
+		}
+	}
+
+	h.setIdentityCookie(c, name)
+	return c.Redirect(safeReferer(c), http.StatusFound)
+}
+
+// viewerIdentity returns the identity from the signed cookie, or "" for anonymous viewers
+// and expired sessions
+func (h *Handler) viewerIdentity(c *fiber.Ctx) string {
+	parts := strings.Split(c.Cookies(identityCookie), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	name, expires, signature := parts[0], parts[1], parts[2]
+	if !h.engine.VerifySignature(identityCookieValue(name, expires), signature) {
+		return ""
+	}
+	if unix, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > unix {
+		return ""
+	}
+	return name
+}
+
+// setIdentityCookie signs the viewer in as identity, or out when identity is empty
+// The signed value carries its expiry: SESSION_TTL when login is required, a year otherwise.
+func (h *Handler) setIdentityCookie(c *fiber.Ctx, identity string) {
+	cookie := &fiber.Cookie{
+		Name:     identityCookie,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   strings.HasPrefix(h.cfg.BaseURL, "https://"),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+	if identity == "" {
+		cookie.Expires = time.Unix(0, 0)
+	} else {
+		expires := time.Now().AddDate(0, 0, preferenceCookieDays)
+		if h.cfg.LoginRequired() {
+			expires = time.Now().Add(h.cfg.SessionTTL)
+		}
+		unix := strconv.FormatInt(expires.Unix(), 10)
+		cookie.Value = identity + "." + unix + "." + h.engine.Sign(identityCookieValue(identity, unix))
+		cookie.Expires = expires
+	}
+	c.Cookie(cookie)
+}
+
+func identityCookieValue(identity, expires string) string {
+	return "identity|" + identity + "|" + expires
+}
+
+// requireRole restricts a route to identities with at least the given role
+func (h *Handler) requireRole(role users.Role) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.engine.Can(h.viewerIdentity(c), role) {
+			return c.Next()
+		}
+		return h.fail(c, http.StatusForbidden, fmt.Sprintf("This requires the %s role", role))
+	}
+}
+
+// reviewDenied explains why the viewer may not review a workflow (see Engine.CanReview)
+func reviewDenied(wf *storage.WorkflowState) string {
+	if wf.Assignee != "" {
+		return fmt.Sprintf("This review is assigned to %s", wf.Assignee)
+	}
+	return fmt.Sprintf("Reviewing requires the %s role", users.RoleReviewer)
+}
+
+// AssignWorkflow changes the reviewer of a workflow (admins only); an empty assignee unassigns it
+func (h *Handler) AssignWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, "Only admins can reassign reviews")
+	}
+
+	h.engine.Assign(wf, c.FormValue("assignee"), viewer)
+	return c.Redirect(safeReferer(c), http.StatusFound)
+}
+
+// StealWorkflow assigns the review to the requesting admin
+func (h *Handler) StealWorkflow(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, "Only admins can take over reviews")
+	}
+
+	h.engine.Assign(wf, viewer, viewer)
+	return c.Redirect("/review/"+wf.ID, http.StatusFound)
+}
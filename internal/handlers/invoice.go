@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Invoice returns the usage-based invoice of the workflows created from ?from= to ?to= (YYYY-MM-DD,
+// both inclusive, in DISPLAY_TIMEZONE; the current month by default) per project, or of
+// ?project= only; ?format=csv or ?format=pdf returns it as a file formatted for LOCALE
+func (h *Handler) Invoice(c *fiber.Ctx) error {
+	loc := h.cfg.DisplayLocation
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, 0)
+	if value := c.Query("from"); value != "" {
+		day, err := time.ParseInLocation(dueDateLayout, value, loc)
+		if err != nil {
+			return h.fail(c, http.StatusBadRequest, "invalid from, expected YYYY-MM-DD")
+		}
+		from = day
+	}
+	if value := c.Query("to"); value != "" {
+		day, err := time.ParseInLocation(dueDateLayout, value, loc)
+		if err != nil {
+			return h.fail(c, http.StatusBadRequest, "invalid to, expected YYYY-MM-DD")
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return h.fail(c, http.StatusBadRequest, "from must not be after to")
+	}
+
+	project := strings.TrimSpace(c.Query("project"))
+	invoice := h.engine.Invoice(from, to, project)
+
+	var buf bytes.Buffer
+	var contentType, ext string
+	switch c.Query("format") {
+	case "csv":
+		if err := invoice.WriteCSV(&buf, h.cfg.Locale, loc); err != nil {
+			return err
+		}
+		contentType, ext = "text/csv; charset=utf-8", "csv"
+	case "pdf":
+		if err := invoice.WritePDF(&buf, h.cfg.Locale, loc); err != nil {
+			return err
+		}
+		contentType, ext = "application/pdf", "pdf"
+	default:
+		return c.JSON(invoice)
+	}
+
+	name := "invoice-" + from.Format(dueDateLayout)
+	if project != "" {
+		// Header-safe ASCII; other characters of the project name become '_'
+		safe := strings.Map(func(r rune) rune {
+			if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-') {
+				return r
+			}
+			return '_'
+		}, project)
+		name = "invoice-" + safe + "-" + from.Format(dueDateLayout)
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, ext))
+	return c.Send(buf.Bytes())
+}
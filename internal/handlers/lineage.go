@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Lineage graph layout: an indented tree, one node per row
+const (
+	lineageIndent     = 28
+	lineageRowHeight  = 44
+	lineageNodeWidth  = 380
+	lineageNodeHeight = 32
+	lineageMargin     = 4
+	lineageLabelChars = 26
+)
+
+// WorkflowLineage returns the lineage of a workflow as JSON: the workflows cloned from the same
+// original concept and the clips, extensions and stems each of them generated
+func (h *Handler) WorkflowLineage(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	return c.JSON(h.engine.Lineage(wf))
+}
+
+// lineageGraph is the lineage laid out for the SVG of the status page
+type lineageGraph struct {
+	Width, Height         int
+	NodeWidth, NodeHeight int
+	Nodes                 []lineageGraphNode
+}
+
+type lineageGraphNode struct {
+	workflow.LineageNode
+	X, Y    int
+	StatusX int    // right end of the status text
+	Link    string // elbow from the parent, "" for the root
+	URL     string // status page of a workflow, audio of a clip
+	Color   string // Tailwind color name of the status
+	Text    string // label shortened to fit the node
+	Current bool   // the workflow of the page
+}
+
+// lineageGraph lays out the lineage of a workflow, or returns nil when it has nothing beyond the
+// workflow and its own clip
+func (h *Handler) lineageGraph(wf *storage.WorkflowState) *lineageGraph {
+	lineage := h.engine.Lineage(wf)
+	if !lineage.HasDerivatives() {
+		return nil
+	}
+
+	graph := &lineageGraph{NodeWidth: lineageNodeWidth, NodeHeight: lineageNodeHeight}
+	rows := make(map[string]lineageGraphNode, len(lineage.Nodes))
+	for row, node := range lineage.Nodes {
+		n := lineageGraphNode{
+			LineageNode: node,
+			X:           lineageMargin + node.Depth*lineageIndent,
+			Y:           lineageMargin + row*lineageRowHeight,
+			StatusX:     lineageMargin + node.Depth*lineageIndent + lineageNodeWidth - 12,
+			Text:        shortLabel(node.Label),
+			Current:     node.ID == wf.ID,
+		}
+		if node.IsWorkflow() {
+			n.URL = "/workflow/" + node.ID
+			n.Color = storage.LookupStatus(node.Status).Color
+		} else {
+			n.URL = node.AudioURL
+			n.Color = clipColor(node.Status)
+		}
+		if parent, ok := rows[node.Parent]; ok {
+			n.Link = fmt.Sprintf("M%d %d V%d H%d", parent.X+lineageIndent/2, parent.Y+lineageNodeHeight, n.Y+lineageNodeHeight/2, n.X)
+		}
+		rows[node.ID] = n
+		graph.Nodes = append(graph.Nodes, n)
+		graph.Width = max(graph.Width, n.X+lineageNodeWidth+lineageMargin)
+	}
+	graph.Height = 2*lineageMargin + len(lineage.Nodes)*lineageRowHeight - (lineageRowHeight - lineageNodeHeight)
+	return graph
+}
+
+// clipColor returns the Tailwind color name of a Suno clip status
+func clipColor(status string) string {
+	switch status {
+	case "complete":
+		return "green"
+	case "error":
+		return "rose"
+	case "pending":
+		return "gray"
+	default:
+		return "violet"
+	}
+}
+
+// shortLabel cuts a label to lineageLabelChars characters
+func shortLabel(label string) string {
+	runes := []rune(label)
+	if len(runes) <= lineageLabelChars {
+		return label
+	}
+	return string(runes[:lineageLabelChars-1]) + "…"
+}
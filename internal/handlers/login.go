@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"workflower/internal/ui_templates"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginPage renders the sign-in form
+func (h *Handler) LoginPage(c *fiber.Ctx) error {
+	return h.renderLogin(c, http.StatusOK, safeNext(c.Query("next")), "")
+}
+
+// Login checks the name and password and starts a session
+// Accounts from LOGIN_USERS sign in with their own password, admins without an account
+// with ADMIN_TOKEN, and everyone else with the shared LOGIN_PASSWORD.
+func (h *Handler) Login(c *fiber.Ctx) error {
+	name := strings.TrimSpace(c.FormValue("name"))
+	next := safeNext(c.FormValue("next"))
+
+	if name == "" || len(name) > maxIdentityLength || strings.ContainsAny(name, ".;, ") ||
+		strings.HasPrefix(name, workflow.TelegramIdentityPrefix) {
+		return h.renderLogin(c, http.StatusBadRequest, next,
+			"Name must be at most 64 characters without spaces, dots, commas or semicolons")
+	}
+	if !h.authenticate(name, c.FormValue("password")) {
+		return h.renderLogin(c, http.StatusUnauthorized, next, "Wrong name or password")
+	}
+
+	h.setIdentityCookie(c, name)
+	return c.Redirect(next, http.StatusFound)
+}
+
+// Logout ends the session
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	h.setIdentityCookie(c, "")
+	if h.cfg.LoginRequired() {
+		return c.Redirect("/login", http.StatusFound)
+	}
+	return c.Redirect("/", http.StatusFound)
+}
+
+// authenticate reports whether password is valid for name
+func (h *Handler) authenticate(name, password string) bool {
+	if hash, ok := h.cfg.LoginUsers[name]; ok {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	secret := h.cfg.LoginPassword
+	if h.engine.IsAdmin(name) {
+		secret = h.cfg.AdminToken
+	}
+	return secret != "" && subtle.ConstantTimeCompare([]byte(password), []byte(secret)) == 1
+}
+
+// requireLogin redirects anonymous viewers to the sign-in page (401 for API and form posts)
+// Health checks, metrics, inbound webhooks and Telegram review links stay reachable. /metrics is
+// public on purpose, for scrapers and diag that hold no session: it serves aggregate counters
+// only, never workflow content; restrict it at the reverse proxy where that is too much.
+func (h *Handler) requireLogin(c *fiber.Ctx) error {
+	if h.viewerIdentity(c) != "" {
+		return c.Next()
+	}
+
+	path := c.Path()
+	switch path {
+	case "/health", "/metrics", "/login", "/admin/deploy-webhook", reviewHookPath, workflow.SunoCallbackPath, normalizeWebhookPath(h.cfg.TelegramWebhookPath):
+		return c.Next()
+	}
+	// Review links sent to Telegram assignees carry their own token (checked by ReviewPage)
+	if c.Method() == fiber.MethodGet && strings.HasPrefix(path, "/review/") && c.Query("as") != "" {
+		return c.Next()
+	}
+
+	if (strings.HasPrefix(path, "/api/") && path != apiDocsPath) || path == "/graphql" {
+		return apiError(c, http.StatusUnauthorized, "sign in required")
+	}
+	if c.Method() != fiber.MethodGet {
+		return h.fail(c, http.StatusUnauthorized, "Sign in required")
+	}
+	return c.Redirect("/login?next="+url.QueryEscape(string(c.Request().URI().RequestURI())), http.StatusFound)
+}
+
+// renderLogin renders the sign-in form with an optional error
+func (h *Handler) renderLogin(c *fiber.Ctx, status int, next, message string) error {
+	data := ui_templates.PageData{
+		Title:    "Sign In",
+		Location: h.viewerLocation(c),
+		Spend:    h.engine.MonthlySpend(time.Now()),
+		Next:     next,
+		Error:    message,
+		CSRF:     h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Login.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// safeNext returns next if it is a local path, "/" otherwise
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// mediaFetchTimeout bounds a whole download from the Suno CDN, body included
+	mediaFetchTimeout = 10 * time.Minute
+	// mediaRenewTimeout bounds asking Suno for a fresh link
+	mediaRenewTimeout = 30 * time.Second
+)
+
+// mediaClient fetches generated files; there is no per-request context, as streamed bodies are
+// read after the handler returned
+var mediaClient = &http.Client{Timeout: mediaFetchTimeout}
+
+// errNoMedia reports a workflow without the requested file (not generated yet)
+var errNoMedia = errors.New("not generated yet")
+
+// mediaKind is a generated file served through the proxy
+type mediaKind struct {
+	name       string // "audio" or "video"
+	defaultExt string // used when the CDN URL has no extension
+	url        func(*storage.WorkflowState) string
+}
+
+var (
+	mediaAudio = mediaKind{name: "audio", defaultExt: ".mp3", url: func(wf *storage.WorkflowState) string { return wf.AudioURL }}
+	mediaVideo = mediaKind{name: "video", defaultExt: ".mp4", url: func(wf *storage.WorkflowState) string { return wf.VideoURL }}
+)
+
+// WorkflowAudio serves the generated song through the server (see proxyMedia)
+func (h *Handler) WorkflowAudio(c *fiber.Ctx) error {
+	return h.proxyMedia(c, mediaAudio)
+}
+
+// WorkflowVideo serves the generated video through the server (see proxyMedia)
+func (h *Handler) WorkflowVideo(c *fiber.Ctx) error {
+	return h.proxyMedia(c, mediaVideo)
+}
+
+// proxyMedia streams a generated file from the song archive (RESULTS_DIR), from MEDIA_CACHE_DIR
+// once cached or from the Suno CDN, as an attachment named after the workflow title (?inline=1 to play it in the browser)
+// Range requests are passed on, so players can seek. Expired CDN links are renewed from Suno.
+func (h *Handler) proxyMedia(c *fiber.Ctx, kind mediaKind) error {
+	wf, ok := h.store.Get(c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	mediaURL := kind.url(wf)
+	if mediaURL == "" {
+		return h.failWorkflow(c, http.StatusNotFound, wf.ID, fmt.Sprintf("No %s yet", kind.name))
+	}
+
+	ext := path.Ext(strings.SplitN(mediaURL, "?", 2)[0])
+	if ext == "" || len(ext) > 5 {
+		ext = kind.defaultExt
+	}
+	disposition := "attachment"
+	if c.QueryBool("inline") {
+		disposition = "inline"
+	}
+	disposition = mime.FormatMediaType(disposition, map[string]string{"filename": mediaFilename(wf, ext)})
+
+	if file, ok := h.engine.ResultFile(wf.ID, kind.name); ok {
+		c.Set(fiber.HeaderContentDisposition, disposition)
+		return c.SendFile(file)
+	}
+	if h.cfg.MediaCacheDir != "" {
+		file, err := h.cachedMedia(wf, kind, ext)
+		if err != nil {
+			return h.mediaError(c, kind, err)
+		}
+		c.Set(fiber.HeaderContentDisposition, disposition)
+		return c.SendFile(file)
+	}
+
+	resp, err := h.fetchMedia(wf, kind, c.Get(fiber.HeaderRange))
+	if err != nil {
+		return h.mediaError(c, kind, err)
+	}
+	for _, header := range []string{fiber.HeaderContentType, fiber.HeaderContentRange, fiber.HeaderAcceptRanges, fiber.HeaderLastModified, fiber.HeaderETag} {
+		if value := resp.Header.Get(header); value != "" {
+			c.Set(header, value)
+		}
+	}
+	c.Set(fiber.HeaderContentDisposition, disposition)
+	c.Status(resp.StatusCode)
+	return c.SendStream(resp.Body, int(resp.ContentLength))
+}
+
+// fetchMedia requests the file from the CDN, asking Suno for a fresh link once when the
+// current one has expired; the caller closes the body
+func (h *Handler) fetchMedia(wf *storage.WorkflowState, kind mediaKind, byteRange string) (*http.Response, error) {
+	for renewed := false; ; renewed = true {
+		mediaURL := kind.url(wf)
+		if mediaURL == "" {
+			return nil, errNoMedia
+		}
+		req, err := http.NewRequest(http.MethodGet, mediaURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s URL: %w", kind.name, err)
+		}
+		if byteRange != "" {
+			req.Header.Set(fiber.HeaderRange, byteRange)
+		}
+
+		resp, err := mediaClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+
+		expired := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone
+		if !expired || renewed {
+			return nil, fmt.Errorf("CDN returned %d", resp.StatusCode)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), mediaRenewTimeout)
+		err = h.engine.RefreshMediaURLs(ctx, wf)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("link expired and could not be renewed: %w", err)
+		}
+	}
+}
+
+// cachedMedia returns the cached copy of the file, downloading it on the first request
+func (h *Handler) cachedMedia(wf *storage.WorkflowState, kind mediaKind, ext string) (string, error) {
+	file := filepath.Join(h.cfg.MediaCacheDir, wf.ID+"-"+kind.name+ext)
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+
+	if err := os.MkdirAll(h.cfg.MediaCacheDir, 0o755); err != nil {
+		return "", err
+	}
+	resp, err := h.fetchMedia(wf, kind, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	// Written aside and renamed, so concurrent requests never serve a partial file
+	tmp, err := os.CreateTemp(h.cfg.MediaCacheDir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", err
+	}
+	slog.Info("Cached generated file", "workflow_id", wf.ID, "kind", kind.name)
+	return file, nil
+}
+
+// removeCachedMedia deletes the cached files of a deleted workflow
+func (h *Handler) removeCachedMedia(workflowID string) {
+	for _, kind := range []mediaKind{mediaAudio, mediaVideo} {
+		files, _ := filepath.Glob(filepath.Join(h.cfg.MediaCacheDir, workflowID+"-"+kind.name+".*"))
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				slog.Warn("Failed to remove cached file", "file", file, "error", err)
+			}
+		}
+	}
+}
+
+// subscribeMediaCache removes cached files when their workflow is deleted
+func (h *Handler) subscribeMediaCache() {
+	if h.cfg.MediaCacheDir == "" {
+		return
+	}
+	h.engine.Events().Subscribe(func(event workflow.Event) {
+		if deleted, ok := event.(workflow.Deleted); ok {
+			h.removeCachedMedia(deleted.Workflow.ID)
+		}
+	})
+}
+
+// mediaError answers a failed proxy request: 404 without a file, 502 when the CDN failed
+func (h *Handler) mediaError(c *fiber.Ctx, kind mediaKind, err error) error {
+	if errors.Is(err, errNoMedia) {
+		return h.fail(c, http.StatusNotFound, fmt.Sprintf("No %s yet", kind.name))
+	}
+	slog.Warn("Failed to fetch generated file", "kind", kind.name, "error", err)
+	return h.fail(c, http.StatusBadGateway, fmt.Sprintf("Failed to fetch the %s: %v", kind.name, err))
+}
+
+// mediaFilename names a downloaded file after the workflow title ("workflow-<seq>" without one)
+func mediaFilename(wf *storage.WorkflowState, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_', r == '.':
+			return r
+		case unicode.IsSpace(r):
+			return ' '
+		default:
+			return -1
+		}
+	}, wf.Title)
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > 80 {
+		name = strings.TrimSpace(string(runes[:80]))
+	}
+	if strings.Trim(name, ".") == "" {
+		name = fmt.Sprintf("workflow-%d", wf.Seq)
+	}
+	return name + ext
+}
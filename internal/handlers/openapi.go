@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"workflower/config"
+	"workflower/lib/openapi"
+	"workflower/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	apiPrefix   = "/api/v1"
+	openAPIPath = "/api/openapi.json"
+	apiDocsPath = "/api/docs"
+)
+
+// apiRoute is an endpoint of the versioned JSON API: its handlers and its contract
+// The table drives both the routes (registerAPIRoutes) and the OpenAPI document (OpenAPISpec).
+type apiRoute struct {
+	ID          string // OpenAPI operationId
+	Method      string
+	Path        string // fiber syntax, relative to apiPrefix
+	Summary     string
+	Description string
+	Query       []openapi.Parameter
+	Request     any                 // zero value of the JSON body type, nil without a body
+	Responses   map[int]apiResponse // by status code
+	Handlers    []fiber.Handler
+}
+
+// apiResponse is a documented answer of an API route
+type apiResponse struct {
+	Description string
+	Body        any // zero value of the JSON body type, nil without a body
+}
+
+// apiRoutes returns the API endpoints; reviewer guards the ones that spend credits
+func (h *Handler) apiRoutes(reviewer fiber.Handler) []apiRoute {
+	failed := func(description string) apiResponse { return apiResponse{description, apiErrorBody{}} }
+	workflow := apiResponse{"The workflow", apiWorkflow{}}
+	notFound := failed("No workflow with this ID or number")
+	operation := apiResponse{"The operation", apiOperation{}}
+
+	return []apiRoute{
+		{
+			Method:  fiber.MethodPost,
+			ID:      "createWorkflow",
+			Path:    "/workflows",
+			Summary: "Start a workflow",
+			Description: "Needs `task_description`, `transcript` or `lyrics`. A transcript is summarized into the " +
+				"task description (which then holds directions); lyrics skip generation; source lyrics are matched " +
+				"line by line. `audio_url` is fetched as the reference track, within the upload limits. Spends " +
+				"OpenAI and Suno credits, rate limited per identity.",
+			Request: apiStartRequest{},
+			Responses: map[int]apiResponse{
+				http.StatusCreated:               {"The started workflow (its URL in Location)", apiWorkflow{}},
+				http.StatusBadRequest:            failed("Invalid options"),
+				http.StatusRequestEntityTooLarge: failed("The audio_url track is larger than MAX_AUDIO_SIZE_MB"),
+				http.StatusUnsupportedMediaType:  failed("The audio_url track is not MP3, WAV, FLAC or M4A"),
+				http.StatusTooManyRequests:       failed("Start rate limit reached"),
+				http.StatusBadGateway:            failed("The audio_url could not be fetched"),
+			},
+			Handlers: []fiber.Handler{reviewer, h.limitStarts, h.APICreateWorkflow},
+		},
+		{
+			Method:  fiber.MethodGet,
+			ID:      "listWorkflows",
+			Path:    "/workflows",
+			Summary: "List workflows, newest first",
+			Query: []openapi.Parameter{
+				queryParam("status", "Only workflows with this status", "string"),
+				queryParam("project", "Only workflows of this project", "string"),
+				queryParam("external_ref", "Only workflows started with this external_ref", "string"),
+				queryParam("limit", "Page size (default "+strconv.Itoa(defaultListLimit)+", at most "+strconv.Itoa(maxListLimit)+")", "integer"),
+				queryParam("before", "next_cursor of the previous page", "integer"),
+			},
+			Responses: map[int]apiResponse{http.StatusOK: {"One page of workflows", apiWorkflowPage{}}},
+			Handlers:  []fiber.Handler{h.APIListWorkflows},
+		},
+		{
+			Method:  fiber.MethodPost,
+			ID:      "bulkWorkflows",
+			Path:    "/workflows/bulk",
+			Summary: "Approve, reject, cancel or delete several workflows",
+			Description: "Each workflow is checked as by the single actions and reported in `results`; one failing " +
+				"does not stop the others. Approval uses the proposed lyrics and properties. At most " +
+				strconv.Itoa(maxBulkIDs) + " IDs.",
+			Request: apiBulkRequest{},
+			Responses: map[int]apiResponse{
+				http.StatusOK:         {"The outcome per workflow", apiBulkResponse{}},
+				http.StatusBadRequest: failed("Invalid action or ID list"),
+			},
+			Handlers: []fiber.Handler{h.APIBulkWorkflows},
+		},
+		{
+			Method:    fiber.MethodGet,
+			ID:        "getWorkflow",
+			Path:      "/workflows/:id",
+			Summary:   "Get a workflow by ID or sequence number",
+			Responses: map[int]apiResponse{http.StatusOK: workflow, http.StatusNotFound: notFound},
+			Handlers:  []fiber.Handler{h.APIGetWorkflow},
+		},
+		{
+			Method:  fiber.MethodPost,
+			ID:      "reviewWorkflow",
+			Path:    "/workflows/:id/review",
+			Summary: "Approve or reject a workflow awaiting review",
+			Description: "Approving submits the lyrics and properties to Suno; omitted fields keep the generated " +
+				"values. Bracket lint blocks approval unless `override_lint` is set. The generation is returned " +
+				"as `operation` (its URL also in Operation-Location) to poll until done.",
+			Request: apiReviewRequest{},
+			Responses: map[int]apiResponse{
+				http.StatusOK:                  workflow,
+				http.StatusBadRequest:          failed("Invalid action"),
+				http.StatusForbidden:           failed("The caller may not review this workflow"),
+				http.StatusNotFound:            notFound,
+				http.StatusConflict:            failed("The workflow is not awaiting review"),
+				http.StatusUnprocessableEntity: {"The lyrics have blocking issues; the workflow stays in review", apiLyricsErrorBody{}},
+			},
+			Handlers: []fiber.Handler{h.APIReviewWorkflow},
+		},
+		{
+			Method:  fiber.MethodPost,
+			ID:      "cancelWorkflow",
+			Path:    "/workflows/:id/cancel",
+			Summary: "Stop an unfinished workflow",
+			Responses: map[int]apiResponse{
+				http.StatusOK:        workflow,
+				http.StatusForbidden: failed("The caller may not review this workflow"),
+				http.StatusNotFound:  notFound,
+				http.StatusConflict:  failed("The workflow has already finished"),
+			},
+			Handlers: []fiber.Handler{h.APICancelWorkflow},
+		},
+		{
+			Method:  fiber.MethodPost,
+			ID:      "generateStems",
+			Path:    "/workflows/:id/stems",
+			Summary: "Separate a completed song into stems",
+			Description: "Runs in the background and spends Suno credits; poll the returned operation " +
+				"(its URL also in Operation-Location) until done.",
+			Responses: map[int]apiResponse{
+				http.StatusAccepted:  operation,
+				http.StatusForbidden: failed("The caller may not review this workflow"),
+				http.StatusNotFound:  notFound,
+				http.StatusConflict:  failed("The workflow is not completed, or has or is getting stems"),
+			},
+			Handlers: []fiber.Handler{reviewer, h.APIGenerateStems},
+		},
+		{
+			Method:  fiber.MethodPost,
+			ID:      "createPersona",
+			Path:    "/workflows/:id/persona",
+			Summary: "Create a Suno persona from a completed premium song",
+			Description: "The persona keeps the voice and style of the final clip; pass its `id` as `suno_persona_id` " +
+				"when starting premium workflows, or as `persona_inspo.persona_id` when approving one. The body is optional.",
+			Request: apiPersonaRequest{},
+			Responses: map[int]apiResponse{
+				http.StatusCreated:            {"The persona", apiPersona{}},
+				http.StatusBadRequest:         failed("Suno refused the persona"),
+				http.StatusForbidden:          failed("The caller may not review this workflow"),
+				http.StatusNotFound:           notFound,
+				http.StatusConflict:           failed("The workflow is not a completed premium song"),
+				http.StatusServiceUnavailable: failed("Suno is not reachable"),
+			},
+			Handlers: []fiber.Handler{reviewer, h.APICreatePersona},
+		},
+		{
+			Method:      fiber.MethodGet,
+			ID:          "listPersonas",
+			Path:        "/personas",
+			Summary:     "List the Suno personas premium workflows may be generated with",
+			Description: "Personas of the Suno account (of every account of SUNO_ACCOUNTS), newest first; cached for a few minutes.",
+			Responses: map[int]apiResponse{
+				http.StatusOK:                 {"The personas", apiPersonaList{}},
+				http.StatusServiceUnavailable: failed("Suno is not reachable"),
+			},
+			Handlers: []fiber.Handler{h.APIListPersonas},
+		},
+		{
+			Method:  fiber.MethodGet,
+			ID:      "getOperation",
+			Path:    "/operations/:id",
+			Summary: "Poll a slow action",
+			Description: "Generation after approval (long-song extensions and requested stems included) and stems " +
+				"are operations. While `done` is false, `progress` estimates the remaining time and Retry-After " +
+				"suggests when to poll again; once done, `result` holds the song or `error` why it failed.",
+			Responses: map[int]apiResponse{http.StatusOK: operation, http.StatusNotFound: failed("No operation with this ID")},
+			Handlers:  []fiber.Handler{h.APIGetOperation},
+		},
+		{
+			Method:    fiber.MethodGet,
+			ID:        "listComments",
+			Path:      "/workflows/:id/comments",
+			Summary:   "List the comments of a workflow, oldest first",
+			Responses: map[int]apiResponse{http.StatusOK: {"The comments", apiCommentList{}}, http.StatusNotFound: notFound},
+			Handlers:  []fiber.Handler{h.APIListComments},
+		},
+		{
+			Method:      fiber.MethodPost,
+			ID:          "addComment",
+			Path:        "/workflows/:id/comments",
+			Summary:     "Leave a note on a workflow",
+			Description: "Recorded with the caller's identity in any status. At most " + strconv.Itoa(maxCommentChars) + " characters.",
+			Request:     apiCommentRequest{},
+			Responses: map[int]apiResponse{
+				http.StatusCreated:    {"The comment", storage.Comment{}},
+				http.StatusBadRequest: failed("Empty or too long text"),
+				http.StatusForbidden:  failed("The caller is not a reviewer"),
+				http.StatusNotFound:   notFound,
+			},
+			Handlers: []fiber.Handler{reviewer, h.APIAddComment},
+		},
+		{
+			Method:  fiber.MethodDelete,
+			ID:      "deleteWorkflow",
+			Path:    "/workflows/:id",
+			Summary: "Delete a workflow (admins only)",
+			Responses: map[int]apiResponse{
+				http.StatusNoContent: {Description: "Deleted"},
+				http.StatusForbidden: failed("The caller is not an admin"),
+				http.StatusNotFound:  notFound,
+			},
+			Handlers: []fiber.Handler{h.APIDeleteWorkflow},
+		},
+		{
+			Method:  fiber.MethodGet,
+			ID:      "listTags",
+			Path:    "/tags",
+			Summary: "Suggest style tags",
+			Description: "Tags of the style tag taxonomy (bundled, seen in the Suno library or used by workflows) " +
+				"starting with `q`, then containing it; the tags most often kept and used come first.",
+			Query: []openapi.Parameter{
+				queryParam("q", "Typed prefix, e.g. the last tag of a style list", "string"),
+				queryParam("limit", "Number of tags (default "+strconv.Itoa(defaultTagLimit)+", at most "+strconv.Itoa(maxTagLimit)+")", "integer"),
+			},
+			Responses: map[int]apiResponse{http.StatusOK: {"The matching tags", apiTagList{}}},
+			Handlers:  []fiber.Handler{h.APIListTags},
+		},
+		{
+			Method:  fiber.MethodGet,
+			ID:      "getEventSchema",
+			Path:    "/events/schema",
+			Summary: "Describe the outbound events",
+			Description: "JSON Schemas of the webhook payloads and of the events streamed at " +
+				"`/workflow/{id}/events`, for the current `schema_version`. Fields may be added within a " +
+				"version; removals and changes raise it.",
+			Responses: map[int]apiResponse{http.StatusOK: {"The event schemas", apiEventSchema{}}},
+			Handlers:  []fiber.Handler{h.APIEventSchema},
+		},
+	}
+}
+
+func queryParam(name, description, typ string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ}}
+}
+
+// openAPIDocument describes the API routes as an OpenAPI 3 document
+func (h *Handler) openAPIDocument() *openapi.Document {
+	doc := openapi.New("Workflower API", config.Version,
+		"Start, follow and review song workflows. Callers are identified by the signed identity cookie "+
+			"of the web UI; with login enabled, sign in at /login first. JSON bodies need no CSRF token.")
+	doc.AddSecurity("identityCookie", &openapi.SecurityScheme{
+		Type: "apiKey", In: "cookie", Name: identityCookie,
+		Description: "Set by signing in at /login, or by picking a name in the page footer without login",
+	})
+
+	for _, route := range h.apiRoutes(nil) {
+		op := &openapi.Operation{
+			OperationID: route.ID,
+			Summary:     route.Summary,
+			Description: route.Description,
+			Tags:        []string{"workflows"},
+			Parameters:  route.Query,
+			Responses:   make(map[string]*openapi.Response),
+		}
+		if route.Request != nil {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: doc.JSONBody(route.Request)}
+		}
+		for status, response := range route.Responses {
+			op.Responses[strconv.Itoa(status)] = &openapi.Response{Description: response.Description}
+			if response.Body != nil {
+				op.Responses[strconv.Itoa(status)].Content = doc.JSONBody(response.Body)
+			}
+		}
+		if h.cfg.LoginRequired() {
+			op.Responses[strconv.Itoa(http.StatusUnauthorized)] = &openapi.Response{
+				Description: "Sign in required", Content: doc.JSONBody(apiErrorBody{}),
+			}
+		}
+		doc.Add(route.Method, apiPrefix+route.Path, op)
+	}
+
+	// Statuses are plain strings in Go; list them where they appear
+	var statuses []string
+	for _, info := range storage.Statuses() {
+		statuses = append(statuses, info.Name)
+	}
+	if wf, ok := doc.Components.Schemas["Workflow"]; ok { // apiWorkflow
+		wf.Properties["status"].Enum = statuses
+	}
+	for _, item := range doc.Paths {
+		for _, op := range *item {
+			for _, param := range op.Parameters {
+				if param.Name == "status" {
+					param.Schema.Enum = statuses
+				}
+			}
+		}
+	}
+	return doc
+}
+
+// OpenAPISpec serves the OpenAPI 3 document of the JSON API
+func (h *Handler) OpenAPISpec(c *fiber.Ctx) error {
+	return c.JSON(h.openAPIDocument())
+}
+
+// APIDocs serves Swagger UI for the OpenAPI document
+func (h *Handler) APIDocs(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.SendString(apiDocsPage)
+}
+
+// apiDocsPage loads Swagger UI from the CDN, like the Tailwind script of the other pages
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Workflower API</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        SwaggerUIBundle({url: "` + openAPIPath + `", dom_id: "#swagger-ui", withCredentials: true});
+    </script>
+</body>
+</html>
+`
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	operationLocationHeader = "Operation-Location"
+	operationRetryAfter     = 5 // seconds between polls suggested for a running operation
+)
+
+// apiOperation is an operation as returned by the API
+// Poll URL until done is true; then either error or result is set.
+type apiOperation struct {
+	storage.Operation
+	URL         string                     `json:"url"`
+	WorkflowURL string                     `json:"workflow_url"`
+	Progress    *workflow.ProgressEstimate `json:"progress,omitempty"` // while running
+}
+
+// apiOperation returns the API representation of an operation and points the
+// Operation-Location header at it
+func (h *Handler) apiOperation(c *fiber.Ctx, op storage.Operation) *apiOperation {
+	result := &apiOperation{
+		Operation:   op,
+		URL:         fmt.Sprintf("%s%s/operations/%s", c.BaseURL(), apiPrefix, op.ID),
+		WorkflowURL: fmt.Sprintf("%s%s/workflows/%s", c.BaseURL(), apiPrefix, op.WorkflowID),
+	}
+	if !op.Done {
+		if wf, ok := h.store.Get(op.WorkflowID); ok {
+			if progress, ok := h.engine.Progress(wf); ok {
+				result.Progress = &progress
+			}
+		}
+	}
+	c.Set(operationLocationHeader, result.URL)
+	return result
+}
+
+// APIGetOperation returns an operation; while it runs, Retry-After suggests when to poll again
+func (h *Handler) APIGetOperation(c *fiber.Ctx) error {
+	op, ok := h.engine.GetOperation(c.Params("id"))
+	if !ok {
+		return apiError(c, http.StatusNotFound, "operation not found")
+	}
+	if !op.Done {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(operationRetryAfter))
+	}
+	return c.JSON(h.apiOperation(c, op))
+}
+
+// APIGenerateStems separates a completed song into stems (reviewers of the workflow only)
+func (h *Handler) APIGenerateStems(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	if viewer := h.viewerIdentity(c); !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	op, err := h.engine.GenerateStems(c.UserContext(), wf)
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Status(http.StatusAccepted).JSON(h.apiOperation(c, op))
+}
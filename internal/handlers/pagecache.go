@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+
+	"workflower/lib/lru"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newPageCache creates the render cache of list pages, cleared whenever a workflow
+// changes status, reviewer or escalation level or is commented on; nil when RENDER_CACHE_TTL is 0
+func (h *Handler) newPageCache() *lru.Cache[string, []byte] {
+	if h.cfg.RenderCacheTTL <= 0 || h.cfg.RenderCacheSize <= 0 {
+		return nil
+	}
+	cache := lru.New[string, []byte](h.cfg.RenderCacheSize, h.cfg.RenderCacheTTL)
+	h.engine.Events().Subscribe(func(event workflow.Event) {
+		switch event.(type) {
+		case workflow.StatusChanged, workflow.Assigned, workflow.Escalated, workflow.Deleted, workflow.Commented:
+			cache.Clear()
+		}
+	})
+	return cache
+}
+
+// invalidatePagesOnWrite clears the render cache after every state-changing request
+// (due dates, preferences, ...), which do not all go through engine events
+func (h *Handler) invalidatePagesOnWrite(c *fiber.Ctx) error {
+	err := c.Next()
+	if c.Method() != http.MethodGet && c.Method() != http.MethodHead {
+		h.pageCache.Clear()
+	}
+	return err
+}
+
+// pageCacheKey identifies a rendered page: path, filter parameters and the viewer and browser
+// (whose CSRF token is in the forms and whose session may scope the list) it was rendered for
+func (h *Handler) pageCacheKey(c *fiber.Ctx) string {
+	return c.Path() + "?" + string(c.Request().URI().QueryString()) +
+		"|" + h.viewerIdentity(c) + "|" + h.viewerLocation(c).String() + "|" + h.csrfToken(c) +
+		"|" + h.browserSession(c, false)
+}
+
+// cachedPage serves a page from the render cache, rendering and storing it on a miss
+func (h *Handler) cachedPage(c *fiber.Ctx, render func() ([]byte, error)) error {
+	var key string
+	if h.pageCache != nil {
+		key = h.pageCacheKey(c)
+		if page, ok := h.pageCache.Get(key); ok {
+			c.Set("Content-Type", "text/html; charset=utf-8")
+			return c.Send(page)
+		}
+	}
+
+	page, err := render()
+	if err != nil {
+		return h.fail(c, http.StatusInternalServerError, err.Error())
+	}
+	if h.pageCache != nil {
+		h.pageCache.Put(key, page)
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(page)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"workflower/lib/suno"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// personaPageTimeout bounds listing the Suno personas for a form, which renders without them
+// when Suno is slow
+const personaPageTimeout = 5 * time.Second
+
+// apiPersona is a Suno persona as the API returns it
+type apiPersona struct {
+	ID          string `json:"id"` // suno_persona_id of new workflows, persona_inspo.persona_id of reviews
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	RootClipID  string `json:"root_clip_id"` // the clip it was created from
+	IsPublic    bool   `json:"is_public"`
+	ClipCount   int    `json:"clip_count"`
+}
+
+// apiPersonaList is the body of GET /api/v1/personas
+type apiPersonaList struct {
+	Personas []apiPersona `json:"personas"` // newest first
+}
+
+// apiPersonaRequest is the body of POST /api/v1/workflows/:id/persona
+type apiPersonaRequest struct {
+	Name        string `json:"name"` // the song title when empty
+	Description string `json:"description"`
+}
+
+func newAPIPersona(p *suno.Persona) apiPersona {
+	return apiPersona{ID: p.ID, Name: p.Name, Description: p.Description, RootClipID: p.RootClipID, IsPublic: p.IsPublic, ClipCount: p.ClipCount}
+}
+
+// sunoPersonas returns the personas offered on the start and review forms, none when Suno
+// cannot list them
+func (h *Handler) sunoPersonas(c *fiber.Ctx) []suno.Persona {
+	ctx, cancel := context.WithTimeout(c.UserContext(), personaPageTimeout)
+	defer cancel()
+	personas, err := h.engine.SunoPersonas(ctx)
+	if err != nil {
+		slog.WarnContext(c.UserContext(), "Could not list Suno personas", "error", err)
+		return nil
+	}
+	return personas
+}
+
+// CreatePersona creates a Suno persona from a completed premium song (persona form of the status
+// page) and returns to its status page
+func (h *Handler) CreatePersona(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	if viewer := h.viewerIdentity(c); !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+
+	if _, err := h.engine.CreateSunoPersona(c.UserContext(), wf, c.FormValue("name"), c.FormValue("description")); err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Redirect("/workflow/"+wf.ID, http.StatusFound)
+}
+
+// APIListPersonas returns the personas of the Suno account, newest first
+func (h *Handler) APIListPersonas(c *fiber.Ctx) error {
+	personas, err := h.engine.SunoPersonas(c.UserContext())
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	list := apiPersonaList{Personas: make([]apiPersona, 0, len(personas))}
+	for i := range personas {
+		list.Personas = append(list.Personas, newAPIPersona(&personas[i]))
+	}
+	return c.JSON(list)
+}
+
+// APICreatePersona creates a Suno persona from a completed premium song (reviewers of the
+// workflow only) and answers 201 with it
+func (h *Handler) APICreatePersona(c *fiber.Ctx) error {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return h.workflowNotFound(c)
+	}
+	if viewer := h.viewerIdentity(c); !h.engine.CanReview(wf, viewer) {
+		return h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+	var req apiPersonaRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return apiError(c, http.StatusBadRequest, "invalid JSON body")
+		}
+	}
+
+	persona, err := h.engine.CreateSunoPersona(c.UserContext(), wf, req.Name, req.Description)
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Status(http.StatusCreated).JSON(newAPIPersona(persona))
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"workflower/lib/timefmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	timezoneCookie       = "tz"
+	preferenceCookieDays = 365
+)
+
+// SetTimezone stores the viewer's display time zone in a cookie
+func (h *Handler) SetTimezone(c *fiber.Ctx) error {
+	name := strings.TrimSpace(c.FormValue("tz"))
+
+	cookie := &fiber.Cookie{
+		Name:     timezoneCookie,
+		Path:     "/",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+	if name == "" {
+		// Reset to the server default
+		cookie.Expires = time.Unix(0, 0)
+	} else {
+		if _, err := timefmt.LoadLocation(name); err != nil {
+			return h.fail(c, http.StatusBadRequest, err.Error())
+		}
+		cookie.Value = name
+		cookie.Expires = time.Now().AddDate(0, 0, preferenceCookieDays)
+	}
+	c.Cookie(cookie)
+
+	return c.Redirect(safeReferer(c), http.StatusFound)
+}
+
+// viewerLocation returns the viewer's preferred time zone, falling back to DISPLAY_TIMEZONE
+func (h *Handler) viewerLocation(c *fiber.Ctx) *time.Location {
+	if name := c.Cookies(timezoneCookie); name != "" {
+		if loc, err := timefmt.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return h.cfg.DisplayLocation
+}
+
+// setTelegramTimezone handles "/tz Area/City" and stores the chat's time zone preference
+func (h *Handler) setTelegramTimezone(ctx context.Context, chatID, args string) {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		current := h.engine.ChatLocation(chatID)
+		h.replyTelegram(ctx, chatID, "tz_current", map[string]any{"Current": current})
+		return
+	}
+
+	if _, err := timefmt.LoadLocation(name); err != nil {
+		h.replyTelegram(ctx, chatID, "tz_unknown", map[string]any{"Name": name})
+		return
+	}
+
+	prefs := h.store.GetChatPreferences(chatID)
+	prefs.Timezone = name
+	h.store.SaveChatPreferences(chatID, prefs)
+	h.replyTelegram(ctx, chatID, "tz_set", map[string]any{"Name": name})
+}
+
+// setTelegramLanguage handles "/language CODE" and stores the language of the chat's messages
+func (h *Handler) setTelegramLanguage(ctx context.Context, chatID, args string) {
+	available := strings.Join(h.engine.MessageLanguages(), ", ")
+	code := strings.ToLower(strings.TrimSpace(args))
+	if code == "" {
+		h.replyTelegram(ctx, chatID, "language_current", map[string]any{"Current": h.engine.ChatLanguage(chatID), "Available": available})
+		return
+	}
+	if !slices.Contains(h.engine.MessageLanguages(), code) {
+		h.replyTelegram(ctx, chatID, "language_unknown", map[string]any{"Name": code, "Available": available})
+		return
+	}
+
+	prefs := h.store.GetChatPreferences(chatID)
+	prefs.Language = code
+	h.store.SaveChatPreferences(chatID, prefs)
+	h.replyTelegram(ctx, chatID, "language_set", nil)
+}
+
+// safeReferer returns the local path of the Referer header, or "/" for foreign or missing referers
+func safeReferer(c *fiber.Ctx) string {
+	referer := c.Get(fiber.HeaderReferer)
+	if referer == "" {
+		return "/"
+	}
+	base := strings.TrimRight(c.BaseURL(), "/")
+	if !strings.HasPrefix(referer, base+"/") {
+		return "/"
+	}
+	return strings.TrimPrefix(referer, base)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// QuickApprove approves a workflow awaiting review with the proposed lyrics and properties
+// (the list page's quick action); blocking lyrics issues, bracket lint included, send the reviewer
+// to the review page, where lint can be overridden.
+func (h *Handler) QuickApprove(c *fiber.Ctx) error {
+	wf, viewer, err := h.quickActionWorkflow(c)
+	if err != nil {
+		return err
+	}
+	if err := h.engine.EditReview(wf, func(wf *storage.WorkflowState) { wf.LintOverridden = false }); err != nil {
+		return h.failWith(c, err)
+	}
+
+	if err := h.engine.ApproveWorkflow(c.UserContext(), wf, viewer); err != nil {
+		if errors.Is(err, workflow.ErrInvalidLyrics) {
+			return c.Redirect("/review/"+wf.ID, http.StatusFound)
+		}
+		return h.failWith(c, workflow.ForWorkflow(fmt.Errorf("failed to approve workflow: %w", err), wf.ID))
+	}
+	return c.Redirect(listReturnURL(c), http.StatusFound)
+}
+
+// QuickReject rejects a workflow awaiting review from the list page
+func (h *Handler) QuickReject(c *fiber.Ctx) error {
+	wf, viewer, err := h.quickActionWorkflow(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.engine.RejectWorkflow(wf, viewer); err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Redirect(listReturnURL(c), http.StatusFound)
+}
+
+// CancelWorkflow cancels a running workflow from the list page
+func (h *Handler) CancelWorkflow(c *fiber.Ctx) error {
+	wf, _, err := h.quickActionWorkflow(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.engine.CancelWorkflow(wf); err != nil {
+		return h.failWith(c, err)
+	}
+	return c.Redirect(listReturnURL(c), http.StatusFound)
+}
+
+// quickActionWorkflow looks up the workflow of a quick action and checks that the viewer may
+// review it (and that it awaits review when awaitingReview is set)
+// The returned error is the response already sent.
+func (h *Handler) quickActionWorkflow(c *fiber.Ctx) (*storage.WorkflowState, string, error) {
+	wf, ok := h.viewerWorkflow(c, c.Params("id"))
+	if !ok {
+		return nil, "", h.workflowNotFound(c)
+	}
+
+	viewer := h.viewerIdentity(c)
+	if !h.engine.CanReview(wf, viewer) {
+		return nil, "", h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+	return wf, viewer, nil
+}
+
+// listReturnURL returns the list page a quick action came from (the "return" form field),
+// falling back to the full list; only list URLs are followed so the field cannot redirect offsite
+func listReturnURL(c *fiber.Ctx) string {
+	ret := c.FormValue("return")
+	if ret == "/workflows" || strings.HasPrefix(ret, "/workflows?") {
+		return ret
+	}
+	return "/workflows"
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workflower/lib/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// startLimiters are the token buckets of the endpoints that start workflows
+type startLimiters struct {
+	perIP   *ratelimit.Limiter
+	perUser *ratelimit.Limiter // signed-in users and Telegram chats
+	global  *ratelimit.Limiter
+}
+
+// newStartLimiters creates the start rate limits; disabled limits are nil
+func (h *Handler) newStartLimiters() startLimiters {
+	return startLimiters{
+		perIP:   ratelimit.New(h.cfg.StartRateLimitRPS, h.cfg.StartRateLimitBurst),
+		perUser: ratelimit.New(h.cfg.StartRateLimitRPS, h.cfg.StartRateLimitBurst),
+		global:  ratelimit.New(h.cfg.StartRateLimitGlobalRPS, h.cfg.StartRateLimitGlobalBurst),
+	}
+}
+
+// allowStart takes a token from the buckets of a client (IP and/or identity) and then from the
+// global bucket, so that one client running out does not use up everyone's budget
+func (l startLimiters) allowStart(ip, identity string) (bool, time.Duration) {
+	if ip != "" {
+		if ok, wait := l.perIP.Allow(ip); !ok {
+			return false, wait
+		}
+	}
+	if identity != "" {
+		if ok, wait := l.perUser.Allow(identity); !ok {
+			return false, wait
+		}
+	}
+	return l.global.Allow("")
+}
+
+// limitLogins rejects sign-in attempts over the per-IP rate limit with 429 and Retry-After, so
+// that passwords cannot be guessed at speed
+func (h *Handler) limitLogins(c *fiber.Ctx) error {
+	ok, wait := h.loginLimit.Allow(c.IP())
+	if ok {
+		return c.Next()
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return h.renderLogin(c, http.StatusTooManyRequests, safeNext(c.FormValue("next")),
+		fmt.Sprintf("Too many sign-in attempts, retry in %ds", seconds))
+}
+
+// limitStarts rejects workflow starts over the rate limits with 429 and Retry-After
+func (h *Handler) limitStarts(c *fiber.Ctx) error {
+	ok, wait := h.startLimits.allowStart(c.IP(), h.viewerIdentity(c))
+	if ok {
+		return c.Next()
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return h.fail(c, http.StatusTooManyRequests, fmt.Sprintf("Too many workflows started, retry in %ds", seconds))
+}
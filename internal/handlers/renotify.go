@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ResendNotifications re-sends the review notifications that did not get through for
+// workflows awaiting review since ?since= (RFC 3339, or YYYY-MM-DD in the viewer's time zone)
+// Admins only; workflows already announced are skipped, so it is safe to run repeatedly.
+func (h *Handler) ResendNotifications(c *fiber.Ctx) error {
+	if !h.engine.IsAdmin(h.viewerIdentity(c)) {
+		return h.fail(c, http.StatusForbidden, "Only admins can re-send notifications")
+	}
+
+	since, err := parseSince(c.FormValue("since"), h.viewerLocation(c))
+	if err != nil {
+		return h.fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	report, err := h.engine.ResendReviewNotifications(c.UserContext(), since)
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	return c.JSON(report)
+}
+
+// parseSince parses an RFC 3339 timestamp or a YYYY-MM-DD day (its start in loc)
+func parseSince(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("since is required (RFC 3339 time or YYYY-MM-DD)")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(dueDateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, errors.New("invalid since, expected an RFC 3339 time or YYYY-MM-DD")
+	}
+	return day, nil
+}
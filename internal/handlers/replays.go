@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// replaySet remembers the signatures of the signed webhook deliveries (see webhook.VerifyAt)
+// accepted within maxAge, so that a captured delivery cannot be sent again
+type replaySet struct {
+	maxAge    time.Duration
+	mu        sync.Mutex
+	startedAt time.Time            // deliveries signed before the process started are stale
+	seen      map[string]time.Time // signature -> signed at
+}
+
+func newReplaySet(maxAge time.Duration) *replaySet {
+	return &replaySet{maxAge: maxAge, startedAt: time.Now(), seen: make(map[string]time.Time)}
+}
+
+// accept records a delivery signed at signedAt and reports whether it is fresh: within maxAge
+// (deliveries from the future are allowed as much clock skew), signed after the process started
+// (the deliveries seen before are forgotten on restart; the restart a deploy delivery causes
+// makes it stale at once) and not seen before
+func (r *replaySet) accept(signature string, signedAt time.Time) bool {
+	now := time.Now()
+	if now.Sub(signedAt) > r.maxAge || signedAt.Sub(now) > r.maxAge || signedAt.Before(r.startedAt.Truncate(time.Second)) {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sig, at := range r.seen {
+		if now.Sub(at) > r.maxAge {
+			delete(r.seen, sig)
+		}
+	}
+	if _, ok := r.seen[signature]; ok {
+		return false
+	}
+	r.seen[signature] = signedAt
+	return true
+}
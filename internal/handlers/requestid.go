@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"workflower/lib/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxRequestIDLength caps request IDs accepted from clients and proxies
+const maxRequestIDLength = 64
+
+// requestID tags every request with an ID: the client's X-Request-ID when it is usable, a new
+// one otherwise. It is returned in X-Request-ID and carried in c.UserContext(), so the engine
+// logs and outbound calls made for the request (and for a Telegram update) carry it too.
+func (h *Handler) requestID(c *fiber.Ctx) error {
+	id := c.Get(logger.RequestIDHeader)
+	if !validRequestID(id) {
+		id = logger.NewRequestID()
+	}
+	c.Set(logger.RequestIDHeader, id)
+	c.SetUserContext(logger.WithRequestID(c.UserContext(), id))
+	return c.Next()
+}
+
+// validRequestID accepts IDs of letters, digits, '-', '_' and '.' that are safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
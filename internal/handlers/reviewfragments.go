@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"workflower/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CheckReview answers the edits of the review page with its lyrics issues, screening hits and
+// unknown style tags, which htmx swaps into place by element ID (out of band)
+// Nothing is saved: the workflow is checked as it would be approved with the form as it stands.
+func (h *Handler) CheckReview(c *fiber.Ctx) error {
+	wf, viewer, err := h.reviewFragmentWorkflow(c)
+	if wf == nil {
+		return err
+	}
+	preview := *wf
+	applyReviewEdits(c, &preview)
+	h.engine.CheckReview(&preview)
+	return h.renderReviewFragments(c, &preview, viewer, "", "review_lyrics_checks", "review_style_checks")
+}
+
+// RegenerateReviewField replaces one field group of the review page: "title" renders the title
+// from the naming template for the edited properties, "properties" asks the LLM for new ones
+// The result only fills the form; the reviewer keeps it by approving.
+func (h *Handler) RegenerateReviewField(c *fiber.Ctx) error {
+	wf, viewer, err := h.reviewFragmentWorkflow(c)
+	if wf == nil {
+		return err
+	}
+	preview := *wf
+	applyReviewEdits(c, &preview)
+
+	switch c.Params("field") {
+	case "title":
+		preview.EditedTitle = ""
+		preview.Title = h.engine.TemplateTitle(&preview)
+		return h.renderReviewFragments(c, &preview, viewer, "", "review_title")
+	case "properties":
+		props, err := h.engine.RegenerateProperties(c.UserContext(), wf)
+		if err != nil {
+			slog.WarnContext(c.UserContext(), "Failed to regenerate properties", "workflow_id", wf.ID, "error", err)
+			return h.renderReviewFragments(c, &preview, viewer, "Regenerating failed: "+err.Error(), "review_properties")
+		}
+		props.Model = preview.EditedProperties.Model // the model stays the reviewer's choice
+		preview.EditedProperties = props
+		preview.Usage = wf.Usage
+		h.engine.CheckReview(&preview)
+		return h.renderReviewFragments(c, &preview, viewer, "", "review_properties", "review_style_checks")
+	default:
+		return h.failWorkflow(c, http.StatusNotFound, wf.ID, "Unknown field")
+	}
+}
+
+// SaveDraft keeps the edits of the review form on the workflow without approving it, so they
+// survive leaving the page; the review page sends them every few seconds while they change
+func (h *Handler) SaveDraft(c *fiber.Ctx) error {
+	wf, viewer, err := h.reviewFragmentWorkflow(c)
+	if wf == nil {
+		return err
+	}
+	now := time.Now()
+	err = h.engine.EditReview(wf, func(wf *storage.WorkflowState) {
+		applyReviewEdits(c, wf)
+		wf.DraftSavedAt = &now
+	})
+	if err != nil { // decided since the form was loaded
+		c.Set("HX-Redirect", "/workflow/"+wf.ID)
+		return c.SendStatus(http.StatusConflict)
+	}
+	return h.renderReviewFragments(c, wf, viewer, "", "review_draft")
+}
+
+// reviewFragmentWorkflow returns the workflow of a fragment request and the viewer; the workflow
+// is nil when the request was answered already
+// A workflow that left review sends htmx to its status page.
+func (h *Handler) reviewFragmentWorkflow(c *fiber.Ctx) (*storage.WorkflowState, string, error) {
+	wf, ok := h.store.Get(c.Params("id"))
+	if !ok {
+		return nil, "", h.workflowNotFound(c)
+	}
+	if wf.Status != storage.StatusAwaitingReview {
+		c.Set("HX-Redirect", "/workflow/"+wf.ID)
+		return nil, "", c.SendStatus(http.StatusConflict)
+	}
+	viewer := h.viewerIdentity(c)
+	if !h.engine.CanReview(wf, viewer) {
+		return nil, "", h.failWorkflow(c, http.StatusForbidden, wf.ID, reviewDenied(wf))
+	}
+	return wf, viewer, nil
+}
+
+// renderReviewFragments renders the named fragments of the review page, one after the other
+func (h *Handler) renderReviewFragments(c *fiber.Ctx, wf *storage.WorkflowState, viewer, formError string, names ...string) error {
+	data := h.reviewData(c, wf, viewer)
+	data.Error = formError
+	var buf bytes.Buffer
+	for _, name := range names {
+		if err := h.templates.Review.ExecuteTemplate(&buf, name, data); err != nil {
+			return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+		}
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/lib/webhook"
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// reviewHookPath receives review decisions made in external tools
+const reviewHookPath = "/hooks/review"
+
+// reviewHookMaxAge is how old a review webhook delivery may be, measured from its signed timestamp
+const reviewHookMaxAge = 5 * time.Minute
+
+// reviewHookFields are the fields a review webhook payload can set, each read from the JSON path
+// configured in REVIEW_WEBHOOK_FIELDS (the field name itself by default)
+var reviewHookFields = []string{
+	"workflow_id", "action", "reviewer", "lyrics", "title",
+	"style", "vocal_type", "lyrics_mode", "weirdness", "style_influence", "model", "override_lint",
+}
+
+// ReviewWebhook approves or rejects a workflow awaiting review on behalf of an external review
+// tool (a Notion automation, a form, ...), so the review is not tied to this app's pages
+// The timestamp (X-Workflower-Timestamp) and body must be signed with REVIEW_WEBHOOK_SECRET
+// (X-Workflower-Signature, see webhook.SignAt); stale or repeated deliveries are refused. Payload
+// fields are mapped by REVIEW_WEBHOOK_FIELDS; omitted fields keep the generated values.
+func (h *Handler) ReviewWebhook(c *fiber.Ctx) error {
+	if h.cfg.ReviewWebhookSecret == "" {
+		return h.fail(c, http.StatusNotFound, "Review webhook disabled")
+	}
+	signature := c.Get(webhook.SignatureHeader)
+	signedAt, ok := webhook.VerifyAt(h.cfg.ReviewWebhookSecret, c.Get(webhook.TimestampHeader), c.Body(), signature)
+	if !ok {
+		return apiError(c, http.StatusUnauthorized, "invalid signature")
+	}
+	if !h.reviewReplays.accept(signature, signedAt) {
+		slog.Warn("Review webhook: stale or repeated delivery refused", "signed_at", signedAt)
+		return apiError(c, http.StatusConflict, "stale or repeated delivery")
+	}
+
+	var payload any
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid JSON body")
+	}
+	fields := h.reviewHookValues(payload)
+
+	wf, ok := h.lookupWorkflow(fields["workflow_id"], nil)
+	if !ok {
+		return apiError(c, http.StatusNotFound, "workflow not found")
+	}
+	approve, ok := parseReviewAction(fields["action"])
+	if !ok {
+		return apiError(c, http.StatusBadRequest, `action must be "approve" or "reject"`)
+	}
+	by := "webhook"
+	if reviewer := fields["reviewer"]; reviewer != "" {
+		by += ":" + reviewer
+	}
+
+	if !approve {
+		if err := h.engine.RejectWorkflow(wf, by); err != nil {
+			return h.failWith(c, err)
+		}
+		return c.JSON(h.apiWorkflow(c, wf))
+	}
+
+	var invalid error
+	err := h.engine.EditReview(wf, func(wf *storage.WorkflowState) {
+		props, err := editedHookProperties(wf, fields)
+		if err != nil {
+			invalid = err
+			return
+		}
+		if lyrics := fields["lyrics"]; strings.TrimSpace(lyrics) != "" {
+			wf.EditedLyrics = lyrics
+		}
+		if title := strings.TrimSpace(fields["title"]); title != "" {
+			wf.EditedTitle = title
+		}
+		wf.EditedProperties = props
+		wf.LintOverridden, _ = strconv.ParseBool(fields["override_lint"])
+	})
+	if err != nil {
+		return h.failWith(c, err)
+	}
+	if invalid != nil {
+		return apiError(c, http.StatusBadRequest, invalid.Error())
+	}
+
+	if err := h.engine.ApproveWorkflow(c.UserContext(), wf, by); err != nil {
+		if errors.Is(err, workflow.ErrInvalidLyrics) {
+			return apiLyricsError(c, wf, err)
+		}
+		return h.failWith(c, err)
+	}
+	return c.JSON(h.apiWorkflow(c, wf))
+}
+
+// reviewHookValues reads the review fields out of a webhook payload ("" for missing ones)
+func (h *Handler) reviewHookValues(payload any) map[string]string {
+	values := make(map[string]string, len(reviewHookFields))
+	for _, field := range reviewHookFields {
+		path := h.cfg.ReviewWebhookFields[field]
+		if path == "" {
+			path = field
+		}
+		values[field] = jsonPathString(payload, path)
+	}
+	return values
+}
+
+// editedHookProperties returns the properties to submit: the generated (or already edited) ones
+// with the fields of the payload applied, nil when the payload changes none
+func editedHookProperties(wf *storage.WorkflowState, fields map[string]string) (*storage.SunoProperties, error) {
+	var props storage.SunoProperties
+	if wf.EditedProperties != nil {
+		props = *wf.EditedProperties
+	} else if wf.SunoProperties != nil {
+		props = *wf.SunoProperties
+	}
+	changed := false
+	for field, target := range map[string]*string{
+		"style": &props.Style, "vocal_type": &props.VocalType,
+		"lyrics_mode": &props.LyricsMode, "style_influence": &props.StyleInfluence, "model": &props.Model,
+	} {
+		if value := strings.TrimSpace(fields[field]); value != "" {
+			*target, changed = value, true
+		}
+	}
+	if value := strings.TrimSpace(fields["weirdness"]); value != "" {
+		weirdness, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("weirdness must be a number")
+		}
+		props.Weirdness, changed = weirdness, true
+	}
+	if !changed {
+		return wf.EditedProperties, nil
+	}
+	return &props, nil
+}
+
+// parseReviewAction maps the decision values of review tools onto approve (true) or reject
+func parseReviewAction(action string) (approve, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "approve", "approved", "accept", "accepted", "yes", "true":
+		return true, true
+	case "reject", "rejected", "decline", "declined", "no", "false":
+		return false, true
+	}
+	return false, false
+}
+
+// jsonPathString follows a dot-separated path (object keys and array indexes, e.g.
+// "data.properties.Lyrics.rich_text.0.plain_text") and returns the value there as text
+func jsonPathString(value any, path string) string {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return ""
+			}
+			value = v[i]
+		default:
+			return ""
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"workflower/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionCookie identifies the browser workflows are started from (see SESSION_SCOPED_LIST)
+const sessionCookie = "session"
+
+// browserSession returns the hash of the browser's session, stored with the workflows it starts
+// so that the cookie itself never shows in API responses; with create, a browser without a
+// session gets one. It is "" when SESSION_SCOPED_LIST is off.
+func (h *Handler) browserSession(c *fiber.Ctx, create bool) string {
+	if !h.cfg.SessionScopedList {
+		return ""
+	}
+	id := c.Cookies(sessionCookie)
+	if len(id) != 32 {
+		if !create {
+			return ""
+		}
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+		c.Cookie(&fiber.Cookie{
+			Name:     sessionCookie,
+			Value:    id,
+			Path:     "/",
+			Expires:  time.Now().AddDate(0, 0, preferenceCookieDays),
+			HTTPOnly: true,
+			Secure:   strings.HasPrefix(h.cfg.BaseURL, "https://"),
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+	}
+	sum := sha256.Sum256([]byte("session|" + id))
+	return hex.EncodeToString(sum[:16])
+}
+
+// listScope returns the filter of the workflows list for viewer, nil when they see every workflow:
+// with SESSION_SCOPED_LIST, everyone but admins only sees the workflows started in their browser
+// and those assigned to them. It applies to the page, the API and GraphQL alike and to lookups by
+// sequence number (see lookupWorkflow); workflow pages stay reachable by their UUID link.
+func (h *Handler) listScope(c *fiber.Ctx, viewer string) func(*storage.WorkflowState) bool {
+	if !h.cfg.SessionScopedList || h.engine.IsAdmin(viewer) {
+		return nil
+	}
+	session := h.browserSession(c, false)
+	return func(wf *storage.WorkflowState) bool {
+		return (session != "" && wf.Session == session) || (viewer != "" && wf.Assignee == viewer)
+	}
+}
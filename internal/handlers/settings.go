@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/internal/ui_templates"
+	"workflower/storage"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// settingsView is the admin settings page: the settings in effect and their environment defaults
+type settingsView struct {
+	Current         workflow.Settings
+	Defaults        workflow.Settings
+	UpdatedAt       *time.Time
+	UpdatedBy       string
+	ArchiveEnabled  bool          // ARCHIVE_DIR is set; retention days have no effect otherwise
+	CallbackPolling time.Duration // slower polling while Suno callbacks are enabled, 0 when they are not
+	Tags            storage.TagTaxonomy
+	Quota           workflow.QuotaReport
+}
+
+// AdminPage shows the runtime settings and presets (admins only)
+func (h *Handler) AdminPage(c *fiber.Ctx) error {
+	return h.renderAdmin(c, http.StatusOK, "")
+}
+
+// SaveSettings saves the runtime settings edited on the admin page; action=reset returns to the environment
+func (h *Handler) SaveSettings(c *fiber.Ctx) error {
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.fail(c, http.StatusForbidden, "Only admins can change settings")
+	}
+	if c.FormValue("action") == "reset" {
+		h.engine.ResetSettings(viewer)
+		return c.Redirect("/admin", http.StatusFound)
+	}
+
+	settings := h.engine.Settings()
+	settings.AutoApprove = c.FormValue("auto_approve") == "true"
+	settings.DefaultPremium = c.FormValue("default_premium") == "true"
+	interval, err := time.ParseDuration(strings.TrimSpace(c.FormValue("poll_interval")))
+	if err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, "Invalid poll interval, expected e.g. 5s or 1m")
+	}
+	settings.PollInterval = interval
+	days, err := strconv.Atoi(strings.TrimSpace(c.FormValue("retention_days")))
+	if err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, "Invalid retention, expected a number of days")
+	}
+	settings.RetentionDays = days
+	settings.HouseStyle = c.FormValue("house_style")
+	lowCredits, err := strconv.Atoi(strings.TrimSpace(c.FormValue("low_credits")))
+	if err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, "Invalid low credit threshold, expected a number of credits")
+	}
+	settings.LowCredits = lowCredits
+
+	if err := h.engine.UpdateSettings(settings, viewer); err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, err.Error())
+	}
+	return c.Redirect("/admin", http.StatusFound)
+}
+
+// SavePreset adds or replaces a Suno property preset
+func (h *Handler) SavePreset(c *fiber.Ctx) error {
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.fail(c, http.StatusForbidden, "Only admins can change presets")
+	}
+
+	weirdness, err := strconv.ParseFloat(c.FormValue("weirdness"), 64)
+	if err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, "Invalid weirdness, expected a number between 0 and 1")
+	}
+	preset := storage.Preset{
+		Name: c.FormValue("name"),
+		Properties: storage.SunoProperties{
+			Style:          c.FormValue("style"),
+			VocalType:      strings.TrimSpace(c.FormValue("vocal_type")),
+			Weirdness:      weirdness,
+			StyleInfluence: strings.TrimSpace(c.FormValue("style_influence")),
+		},
+	}
+	if err := h.engine.SavePreset(preset, viewer); err != nil {
+		return h.renderAdmin(c, http.StatusBadRequest, err.Error())
+	}
+	return c.Redirect("/admin#presets", http.StatusFound)
+}
+
+// DeletePreset removes a Suno property preset
+func (h *Handler) DeletePreset(c *fiber.Ctx) error {
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.fail(c, http.StatusForbidden, "Only admins can change presets")
+	}
+	if !h.engine.DeletePreset(c.FormValue("name"), viewer) {
+		return h.fail(c, http.StatusNotFound, "Preset not found")
+	}
+	return c.Redirect("/admin#presets", http.StatusFound)
+}
+
+// renderAdmin renders the admin page with a form error ("" for none)
+func (h *Handler) renderAdmin(c *fiber.Ctx, status int, formError string) error {
+	viewer := h.viewerIdentity(c)
+	if !h.engine.IsAdmin(viewer) {
+		return h.fail(c, http.StatusForbidden, "Only admins can change settings")
+	}
+
+	view := settingsView{
+		Current:        h.engine.Settings(),
+		Defaults:       h.engine.DefaultSettings(),
+		ArchiveEnabled: h.cfg.ArchiveDir != "",
+		Tags:           h.engine.StyleTags(),
+		Quota:          h.engine.QuotaReport(),
+	}
+	view.UpdatedAt, view.UpdatedBy = h.engine.SettingsUpdated()
+	if h.cfg.SunoCallbackSecret != "" {
+		view.CallbackPolling = h.cfg.SunoCallbackPollInterval
+	}
+	data := ui_templates.PageData{
+		Title:    "Admin",
+		Location: h.viewerLocation(c),
+		Viewer:   viewer,
+		IsAdmin:  true,
+		Settings: view,
+		Error:    formError,
+		CSRF:     h.csrfToken(c),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Admin.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"workflower/config"
+	"workflower/internal/ui_templates"
+	"workflower/lib/suno"
+	"workflower/lib/telegram"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// setupCheckTimeout bounds the connection checks of the wizard
+	setupCheckTimeout = 15 * time.Second
+	// minAdminPasswordLength is the shortest admin password the wizard accepts
+	minAdminPasswordLength = 8
+)
+
+// SetupWizard serves the one-time setup page of an unconfigured server: it checks the
+// suno-api URL and the Telegram bot, then writes the env file and reports Done so that the
+// server can start with the new configuration
+// Every request must carry the token printed in the log, so whoever reaches the port first
+// cannot configure the server.
+type SetupWizard struct {
+	templates *ui_templates.TemplatesList
+	envFile   string
+	token     string
+
+	doneOnce sync.Once
+	done     chan struct{}
+}
+
+// setupForm holds the wizard fields and the outcome of the checks run so far
+type setupForm struct {
+	Token          string
+	AdminName      string
+	AdminPassword  string
+	BaseURL        string
+	OpenAIKey      string
+	SunoURL        string
+	TelegramToken  string
+	TelegramChatID string
+
+	SunoCheck     *setupCheck
+	TelegramCheck *setupCheck
+	Saved         bool
+}
+
+// setupCheck is the result of a connection check shown next to the checked field
+type setupCheck struct {
+	OK      bool
+	Message string
+}
+
+// NewSetupWizard creates the wizard writing envFile
+func NewSetupWizard(templates *ui_templates.TemplatesList, envFile string) *SetupWizard {
+	return &SetupWizard{
+		templates: templates,
+		envFile:   envFile,
+		token:     config.RandomSecret()[:24],
+		done:      make(chan struct{}),
+	}
+}
+
+// Token returns the token that opens the wizard (/setup?token=...)
+func (w *SetupWizard) Token() string {
+	return w.token
+}
+
+// Done is closed once the configuration is written
+func (w *SetupWizard) Done() <-chan struct{} {
+	return w.done
+}
+
+// RegisterRoutes sets up the wizard routes; every other page points to the wizard
+func (w *SetupWizard) RegisterRoutes(r *fiber.App) {
+	// Answer the supervisor's health check so a fresh install is not reverted
+	r.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "setup", "version": config.Version})
+	})
+	r.Get("/setup", w.Page)
+	r.Post("/setup/check", w.Check)
+	r.Post("/setup", w.Save)
+	r.Use(func(c *fiber.Ctx) error {
+		return c.Redirect("/setup", http.StatusFound)
+	})
+}
+
+// Page renders the empty wizard form
+func (w *SetupWizard) Page(c *fiber.Ctx) error {
+	if !w.validToken(c.Query("token")) {
+		return w.render(c, http.StatusUnauthorized, nil, "Open the setup link printed in the server log")
+	}
+	return w.render(c, http.StatusOK, &setupForm{
+		Token:     w.token,
+		AdminName: "admin",
+		BaseURL:   c.BaseURL(),
+		SunoURL:   "http://localhost:3000",
+	}, "")
+}
+
+// Check runs the connection check picked by the "check" button and shows the form again
+// "suno" asks suno-api for the remaining credits, "telegram_chat" fills in the chat that last
+// wrote to the bot and "telegram_test" sends a test message to the chat.
+func (w *SetupWizard) Check(c *fiber.Ctx) error {
+	form := readSetupForm(c)
+	if !w.validToken(form.Token) {
+		return w.render(c, http.StatusUnauthorized, nil, "Open the setup link printed in the server log")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), setupCheckTimeout)
+	defer cancel()
+
+	switch c.FormValue("check") {
+	case "suno":
+		form.SunoCheck = checkSuno(ctx, form.SunoURL)
+	case "telegram_chat":
+		form.TelegramCheck = findTelegramChat(ctx, form)
+	case "telegram_test":
+		form.TelegramCheck = sendTelegramTest(ctx, form.TelegramToken, form.TelegramChatID)
+	default:
+		return w.render(c, http.StatusBadRequest, form, "Unknown check")
+	}
+	return w.render(c, http.StatusOK, form, "")
+}
+
+// Save validates the form and writes the env file
+func (w *SetupWizard) Save(c *fiber.Ctx) error {
+	form := readSetupForm(c)
+	if !w.validToken(form.Token) {
+		return w.render(c, http.StatusUnauthorized, nil, "Open the setup link printed in the server log")
+	}
+	if problem := form.problem(); problem != "" {
+		return w.render(c, http.StatusBadRequest, form, problem)
+	}
+
+	env, err := form.env()
+	if err != nil {
+		return w.render(c, http.StatusInternalServerError, form, err.Error())
+	}
+	// O_EXCL: never overwrite a configuration written in the meantime
+	f, err := os.OpenFile(w.envFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return w.render(c, http.StatusInternalServerError, form, fmt.Sprintf("Failed to write %s: %v", w.envFile, err))
+	}
+	if _, err := f.WriteString(env); err != nil {
+		_ = f.Close()
+		return w.render(c, http.StatusInternalServerError, form, fmt.Sprintf("Failed to write %s: %v", w.envFile, err))
+	}
+	if err := f.Close(); err != nil {
+		return w.render(c, http.StatusInternalServerError, form, fmt.Sprintf("Failed to write %s: %v", w.envFile, err))
+	}
+
+	form.Saved = true
+	w.doneOnce.Do(func() { close(w.done) })
+	return w.render(c, http.StatusOK, form, "")
+}
+
+// validToken compares the token of a request with the wizard's
+func (w *SetupWizard) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(w.token)) == 1
+}
+
+// render renders the wizard; a nil form shows only the message
+func (w *SetupWizard) render(c *fiber.Ctx, status int, form *setupForm, message string) error {
+	data := ui_templates.PageData{
+		Title: "Setup",
+		Bare:  true,
+		Setup: form,
+		Error: message,
+	}
+
+	var buf bytes.Buffer
+	if err := w.templates.Setup.Execute(&buf, data); err != nil {
+		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// readSetupForm reads the wizard fields from the posted form
+func readSetupForm(c *fiber.Ctx) *setupForm {
+	return &setupForm{
+		Token:          c.FormValue("token"),
+		AdminName:      strings.TrimSpace(c.FormValue("admin_name")),
+		AdminPassword:  c.FormValue("admin_password"),
+		BaseURL:        strings.TrimRight(strings.TrimSpace(c.FormValue("base_url")), "/"),
+		OpenAIKey:      strings.TrimSpace(c.FormValue("openai_key")),
+		SunoURL:        strings.TrimRight(strings.TrimSpace(c.FormValue("suno_url")), "/"),
+		TelegramToken:  strings.TrimSpace(c.FormValue("telegram_token")),
+		TelegramChatID: strings.TrimSpace(c.FormValue("telegram_chat_id")),
+	}
+}
+
+// problem returns what keeps the fields from being saved, "" when they are valid
+func (f *setupForm) problem() string {
+	if f.AdminName == "" || len(f.AdminName) > maxIdentityLength || strings.ContainsAny(f.AdminName, ".;,: ") ||
+		strings.HasPrefix(f.AdminName, workflow.TelegramIdentityPrefix) {
+		return "Admin name must be at most 64 characters without spaces, dots, colons, commas or semicolons"
+	}
+	if len(f.AdminPassword) < minAdminPasswordLength {
+		return fmt.Sprintf("Admin password must be at least %d characters", minAdminPasswordLength)
+	}
+	for name, value := range map[string]string{"Public URL": f.BaseURL, "suno-api URL": f.SunoURL} {
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return name + " must be an http(s) URL"
+		}
+	}
+	if f.TelegramChatID != "" && f.TelegramToken == "" {
+		return "A Telegram chat needs the bot token"
+	}
+	for _, value := range []string{f.OpenAIKey, f.TelegramToken, f.TelegramChatID} {
+		if strings.ContainsAny(value, "'\n\r") {
+			return "Keys and tokens cannot contain quotes or line breaks"
+		}
+	}
+	return ""
+}
+
+// env renders the configuration as an env file
+// The admin signs in with a LOGIN_USERS account; values are single-quoted so that the "$"
+// of the bcrypt hash is not expanded.
+func (f *setupForm) env() (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(f.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the admin password: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by the setup wizard on %s\n", time.Now().Format(time.RFC3339))
+	line := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s='%s'\n", key, value)
+		}
+	}
+	line("BASE_URL", f.BaseURL)
+	line("SECRET_KEY", config.RandomSecret())
+	line("ADMIN_USERS", f.AdminName)
+	line("LOGIN_USERS", f.AdminName+":"+string(hash))
+	line("OPENAI_API_KEY", f.OpenAIKey)
+	line("SUNO_BASE_URL", f.SunoURL)
+	line("TELEGRAM_BOT_TOKEN", f.TelegramToken)
+	line("TELEGRAM_CHAT_ID", f.TelegramChatID)
+	return b.String(), nil
+}
+
+// checkSuno asks suno-api for the remaining credits
+func checkSuno(ctx context.Context, baseURL string) *setupCheck {
+	if baseURL == "" {
+		return &setupCheck{Message: "Enter the suno-api URL first"}
+	}
+	quota, err := suno.NewClient(baseURL).GetQuota(ctx)
+	if err != nil {
+		return &setupCheck{Message: err.Error()}
+	}
+	return &setupCheck{OK: true, Message: fmt.Sprintf("Connected, %d credits left", quota.CreditsLeft)}
+}
+
+// findTelegramChat fills in the chat that last wrote to the bot
+func findTelegramChat(ctx context.Context, form *setupForm) *setupCheck {
+	if form.TelegramToken == "" {
+		return &setupCheck{Message: "Enter the bot token first"}
+	}
+	chat, err := telegram.NewNotifier(form.TelegramToken, "").LatestChat(ctx)
+	if err != nil {
+		return &setupCheck{Message: err.Error()}
+	}
+	form.TelegramChatID = fmt.Sprint(chat.ID)
+	name := chat.Title
+	if name == "" {
+		name = strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+	}
+	return &setupCheck{OK: true, Message: fmt.Sprintf("Found chat %q, send a test message to confirm", name)}
+}
+
+// sendTelegramTest sends a test message to the chat
+func sendTelegramTest(ctx context.Context, token, chatID string) *setupCheck {
+	if token == "" || chatID == "" {
+		return &setupCheck{Message: "Enter the bot token and chat ID first"}
+	}
+	if err := telegram.NewNotifier(token, chatID).Send(ctx, "✅ <b>Workflower is linked to this chat</b>"); err != nil {
+		return &setupCheck{Message: err.Error()}
+	}
+	return &setupCheck{OK: true, Message: "Test message sent"}
+}
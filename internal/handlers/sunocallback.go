@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"workflower/lib/suno"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sunoCallbackSecretHeader may carry the callback secret instead of the token query parameter
+const sunoCallbackSecretHeader = "X-Callback-Secret"
+
+// sunoCallbackBody is the envelope deployments wrap clips in: {"data": [...]} or
+// {"code": 200, "data": {"callbackType": "complete", "data": [...]}}
+type sunoCallbackBody struct {
+	CallbackType string          `json:"callbackType"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// SunoCallback advances the workflows waiting for clips that suno-api reports as finished, so
+// they do not wait for their next poll (see SUNO_CALLBACK_SECRET)
+// Unknown clips answer 404; the workflow then still completes through polling.
+func (h *Handler) SunoCallback(c *fiber.Ctx) error {
+	if h.cfg.SunoCallbackSecret == "" {
+		return h.fail(c, http.StatusNotFound, "Suno callbacks disabled")
+	}
+	token := c.Query("token")
+	if token == "" {
+		token = c.Get(sunoCallbackSecretHeader)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.SunoCallbackSecret)) != 1 {
+		return apiError(c, http.StatusUnauthorized, "invalid callback secret")
+	}
+
+	clips, err := parseSunoCallback(c.Body(), "")
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid callback body")
+	}
+
+	matched := 0
+	for i := range clips {
+		clip := &clips[i]
+		state, ok := h.engine.DeliverClip(clip)
+		if !ok {
+			slog.InfoContext(c.UserContext(), "Suno callback for unknown clip", "clip_id", clip.ID, "status", clip.Status)
+			continue
+		}
+		matched++
+		slog.InfoContext(c.UserContext(), "Suno callback matched", "workflow_id", state.ID, "clip_id", clip.ID, "status", clip.Status)
+	}
+	if matched == 0 {
+		return apiError(c, http.StatusNotFound, "no workflow is waiting for these clips")
+	}
+	return c.JSON(fiber.Map{"matched": matched})
+}
+
+// parseSunoCallback reads the clips of a callback: a clip, a list of clips or either of them
+// in a "data" envelope. A "complete" callback type marks clips without a status as complete.
+func parseSunoCallback(body []byte, callbackType string) ([]suno.AudioInfo, error) {
+	var clips []suno.AudioInfo
+	if err := json.Unmarshal(body, &clips); err != nil {
+		var envelope sunoCallbackBody
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, err
+		}
+		if envelope.CallbackType != "" {
+			callbackType = envelope.CallbackType
+		}
+		if len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+			return parseSunoCallback(envelope.Data, callbackType)
+		}
+		var clip suno.AudioInfo
+		if err := json.Unmarshal(body, &clip); err != nil {
+			return nil, err
+		}
+		clips = []suno.AudioInfo{clip}
+	}
+	for i := range clips {
+		if clips[i].Status == "" && callbackType == "complete" {
+			clips[i].Status = "complete"
+		}
+	}
+	return clips, nil
+}
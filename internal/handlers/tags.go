@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"workflower/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultTagLimit = 10
+	maxTagLimit     = 50
+)
+
+// apiTagList is the body of GET /api/v1/tags
+type apiTagList struct {
+	Tags     []storage.StyleTag `json:"tags"`                // most often kept and used first
+	SyncedAt *time.Time         `json:"synced_at,omitempty"` // omitted before the first sync (bundled tags only)
+}
+
+// APIListTags suggests style tags for autocomplete: ?q= is the typed prefix, ?limit= the number
+// of suggestions (default 10)
+func (h *Handler) APIListTags(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultTagLimit)
+	if limit <= 0 || limit > maxTagLimit {
+		limit = maxTagLimit
+	}
+	tags := h.engine.SuggestTags(c.Query("q"), limit)
+	if tags == nil {
+		tags = []storage.StyleTag{}
+	}
+	return c.JSON(apiTagList{Tags: tags, SyncedAt: h.engine.StyleTags().SyncedAt})
+}
+
+// TagReport returns the style tags used by at least ?min_uses= workflows (default 1) with how
+// often the workflows using them were kept, best keep rate first
+func (h *Handler) TagReport(c *fiber.Ctx) error {
+	minUses := c.QueryInt("min_uses", 1)
+	if minUses <= 0 {
+		return h.fail(c, http.StatusBadRequest, "min_uses must be positive")
+	}
+	taxonomy := h.engine.StyleTags()
+	return c.JSON(fiber.Map{
+		"synced_at":     taxonomy.SyncedAt,
+		"library_clips": taxonomy.LibraryClips,
+		"tags":          h.engine.TagReport(minUses),
+	})
+}
+
+// SyncTags rebuilds the style tag taxonomy now instead of waiting for TAG_SYNC_INTERVAL (admins only)
+func (h *Handler) SyncTags(c *fiber.Ctx) error {
+	if !h.engine.IsAdmin(h.viewerIdentity(c)) {
+		return h.fail(c, http.StatusForbidden, "Only admins can sync tags")
+	}
+	h.engine.SyncTags(c.UserContext())
+	return c.Redirect("/admin#tags", http.StatusFound)
+}
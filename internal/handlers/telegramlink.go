@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"workflower/internal/ui_templates"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// telegramLinkView is the Telegram link state of the viewer shown on the link page
+type telegramLinkView struct {
+	ChatID        string    // linked chat, "" when none
+	Code          string    // freshly issued /link code
+	Expires       time.Time // expiry of Code
+	BotConfigured bool
+}
+
+// TelegramLinkPage shows the chat linked to the viewer's account
+func (h *Handler) TelegramLinkPage(c *fiber.Ctx) error {
+	return h.renderTelegramLink(c, "", time.Time{})
+}
+
+// CreateTelegramLinkCode issues a /link code for the viewer's account
+func (h *Handler) CreateTelegramLinkCode(c *fiber.Ctx) error {
+	viewer := h.viewerIdentity(c)
+	if viewer == "" {
+		return h.fail(c, http.StatusUnauthorized, "Sign in to link a Telegram chat")
+	}
+	code, expires := h.engine.NewTelegramLinkCode(viewer)
+	return h.renderTelegramLink(c, code, expires)
+}
+
+// UnlinkTelegram removes the chat linked to the viewer's account
+func (h *Handler) UnlinkTelegram(c *fiber.Ctx) error {
+	viewer := h.viewerIdentity(c)
+	if viewer == "" {
+		return h.fail(c, http.StatusUnauthorized, "Sign in to unlink a Telegram chat")
+	}
+	h.engine.UnlinkTelegramChat(viewer)
+	return c.Redirect("/telegram", http.StatusFound)
+}
+
+// renderTelegramLink renders the link page, with a code when one was just issued
+func (h *Handler) renderTelegramLink(c *fiber.Ctx, code string, expires time.Time) error {
+	viewer := h.viewerIdentity(c)
+	data := ui_templates.PageData{
+		Title:    "Telegram",
+		Location: h.viewerLocation(c),
+		Viewer:   viewer,
+		IsAdmin:  h.engine.IsAdmin(viewer),
+		Link: telegramLinkView{
+			ChatID:        h.engine.LinkedTelegramChat(viewer),
+			Code:          code,
+			Expires:       expires,
+			BotConfigured: h.cfg.TelegramBotToken != "",
+		},
+		CSRF: h.csrfToken(c),
+	}
+	if viewer == "" {
+		data.Link = nil
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.TelegramLink.Execute(&buf, data); err != nil {
+		return h.fail(c, http.StatusInternalServerError, fmt.Sprintf("Template error: %v", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
+
+// linkTelegramChat handles "/link CODE": binds the chat to the web account that issued the code
+func (h *Handler) linkTelegramChat(ctx context.Context, chatID, code string) {
+	pageURL := strings.TrimRight(h.cfg.BaseURL, "/") + "/telegram"
+	if strings.TrimSpace(code) == "" {
+		h.replyTelegram(ctx, chatID, "link_usage", map[string]any{"PageURL": pageURL})
+		return
+	}
+
+	user, err := h.engine.LinkTelegramChat(chatID, code)
+	if errors.Is(err, workflow.ErrInvalidLinkCode) {
+		h.replyTelegram(ctx, chatID, "link_invalid", map[string]any{"PageURL": pageURL})
+		return
+	}
+	if err != nil {
+		h.replyTelegram(ctx, chatID, "link_failed", map[string]any{"Error": err.Error()})
+		return
+	}
+	h.replyTelegram(ctx, chatID, "linked", map[string]any{"User": user})
+}
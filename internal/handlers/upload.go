@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"workflower/lib/safehttp"
+	"workflower/workflow"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// uploadSniffLen is how much of an upload is read to recognize its format
+	uploadSniffLen = 512
+	// uploadNameMaxLen caps the kept part of uploaded file names, in characters
+	uploadNameMaxLen = 100
+)
+
+// audioFormat is an accepted upload format
+type audioFormat struct {
+	name  string
+	ext   string
+	match func(head []byte) bool
+}
+
+// audioFormats are the accepted upload formats, recognized by their first bytes; the extension
+// and Content-Type sent by the browser are not trusted
+var audioFormats = []audioFormat{
+	{"MP3", ".mp3", isMP3},
+	{"WAV", ".wav", func(b []byte) bool {
+		return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WAVE"
+	}},
+	{"FLAC", ".flac", func(b []byte) bool { return bytes.HasPrefix(b, []byte("fLaC")) }},
+	{"M4A", ".m4a", isM4A},
+}
+
+// uploadNameUnsafeChars are replaced in uploaded file names: path separators, control and shell
+// characters
+var uploadNameUnsafeChars = regexp.MustCompile(`[^\p{L}\p{N} ._()-]+`)
+
+// uploadError is a rejected upload, shown to the user with its status
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string { return e.msg }
+
+// saveUpload validates an uploaded audio reference and stores it under workflow.UploadsDir
+// It returns the stored path and the sanitized original name; rejected files are *uploadError.
+func (h *Handler) saveUpload(fileHeader *multipart.FileHeader) (string, string, error) {
+	if fileHeader.Size > int64(h.cfg.MaxAudioSizeMB)<<20 {
+		return "", "", h.audioTooLarge()
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+	return h.storeAudio(file, fileHeader.Filename)
+}
+
+// fetchAudio downloads a reference track given as audio_url and stores it like an upload
+// Only http(s) URLs of public hosts are fetched (see safehttp.NewClient); the size limit and
+// format checks of uploads apply. Rejected URLs are *uploadError.
+func (h *Handler) fetchAudio(ctx context.Context, rawURL string) (string, string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", &uploadError{http.StatusBadRequest, "The audio URL must be an http or https link."}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", "", &uploadError{http.StatusBadRequest, "The audio URL is not valid."}
+	}
+	resp, err := h.audioClient.Do(req)
+	if errors.Is(err, safehttp.ErrForbiddenAddress) {
+		return "", "", &uploadError{http.StatusBadRequest, "The audio URL points to a private or local address."}
+	}
+	if err != nil {
+		return "", "", &uploadError{http.StatusBadGateway, fmt.Sprintf("Failed to fetch the audio URL: %v", err)}
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", &uploadError{http.StatusBadGateway, fmt.Sprintf("The audio URL answered %s.", resp.Status)}
+	}
+	if resp.ContentLength > int64(h.cfg.MaxAudioSizeMB)<<20 {
+		return "", "", h.audioTooLarge()
+	}
+	// The content is sniffed like an upload; a declared type only rules out pages and documents early
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get(fiber.HeaderContentType)); mediaType != "" &&
+		!strings.HasPrefix(mediaType, "audio/") && mediaType != "application/octet-stream" {
+		return "", "", &uploadError{http.StatusUnsupportedMediaType,
+			fmt.Sprintf("The audio URL serves %s, not audio. Link an MP3, WAV, FLAC or M4A file.", mediaType)}
+	}
+	return h.storeAudio(resp.Body, path.Base(resp.Request.URL.Path))
+}
+
+// storeAudio checks the size and format of an audio reference read from src and stores it under
+// workflow.UploadsDir with the sanitized name
+func (h *Handler) storeAudio(r io.Reader, name string) (string, string, error) {
+	src := http.MaxBytesReader(nil, io.NopCloser(r), int64(h.cfg.MaxAudioSizeMB)<<20)
+
+	head := make([]byte, uploadSniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", fmt.Errorf("failed to read audio file: %w", err)
+	}
+	head = head[:n]
+	format, ok := sniffAudio(head)
+	if !ok {
+		return "", "", &uploadError{http.StatusUnsupportedMediaType,
+			fmt.Sprintf("%q is not a supported audio file. Upload an MP3, WAV, FLAC or M4A file.", sanitizeUploadName(name, ""))}
+	}
+
+	uploadsDir := filepath.Join(workflow.UploadsDir, time.Now().Format("2006-01-02"))
+	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+	name = sanitizeUploadName(name, format.ext)
+	path := filepath.Join(uploadsDir, uuid.New().String()+"_"+name)
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save file: %w", err)
+	}
+	_, err = io.Copy(dst, io.MultiReader(bytes.NewReader(head), src))
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return "", "", h.audioTooLarge()
+		}
+		return "", "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return path, name, nil
+}
+
+func (h *Handler) audioTooLarge() *uploadError {
+	return &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("The audio file is larger than %d MB.", h.cfg.MaxAudioSizeMB)}
+}
+
+// ServeUpload serves an uploaded file through a link signed by Engine.UploadURL, so the
+// uploads directory itself is never exposed; expired or tampered links answer 403
+func (h *Handler) ServeUpload(c *fiber.Ctx) error {
+	rel, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return h.fail(c, http.StatusNotFound, "File not found")
+	}
+	path, ok := h.engine.VerifyUploadURL(rel, c.Query("expires"), c.Query("sig"), time.Now())
+	if !ok {
+		return h.fail(c, http.StatusForbidden, "Invalid or expired link")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return h.fail(c, http.StatusNotFound, "File not found")
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendFile(path)
+}
+
+// sniffAudio returns the accepted format the first bytes of a file belong to
+func sniffAudio(head []byte) (audioFormat, bool) {
+	for _, format := range audioFormats {
+		if format.match(head) {
+			return format, true
+		}
+	}
+	return audioFormat{}, false
+}
+
+// isMP3 matches an ID3 tag or an MPEG audio frame header (sync word, layer I-III)
+// ADTS AAC shares the sync word but has layer bits 00, so it is not matched.
+func isMP3(b []byte) bool {
+	if bytes.HasPrefix(b, []byte("ID3")) {
+		return true
+	}
+	return len(b) >= 3 && b[0] == 0xFF && b[1]&0xE0 == 0xE0 && b[1]&0x06 != 0 && b[2]&0xF0 != 0xF0
+}
+
+// isM4A matches an MPEG-4 file whose ftyp box names the M4A (or M4B audiobook) brand
+// Plain MP4 brands are not matched, as they are usually video.
+func isM4A(b []byte) bool {
+	if len(b) < 16 || string(b[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	if size < 16 || size > len(b) {
+		size = len(b)
+	}
+	brands := b[8:size] // major brand, minor version, compatible brands
+	for i := 0; i+4 <= len(brands); i += 4 {
+		if i == 4 {
+			continue // minor version
+		}
+		if brand := string(brands[i : i+4]); brand == "M4A " || brand == "M4B " {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeUploadName keeps the base name of an uploaded file without unsafe characters, with
+// ext (the detected format) as its extension unless ext is empty
+func sanitizeUploadName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if ext != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	name = strings.Trim(uploadNameUnsafeChars.ReplaceAllString(name, "_"), " ._")
+	if utf8.RuneCountInString(name) > uploadNameMaxLen {
+		name = string([]rune(name)[:uploadNameMaxLen])
+	}
+	if name == "" {
+		name = "audio"
+	}
+	return name + ext
+}
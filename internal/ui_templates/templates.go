@@ -0,0 +1,201 @@
+package ui_templates
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"time"
+
+	"workflower/lib/locale"
+	"workflower/lib/suno"
+	"workflower/lib/templating"
+	"workflower/storage"
+)
+
+//go:embed base_layout.html
+var baseLayoutHTML string
+
+//go:embed start_page.html
+var startPageHTML string
+
+//go:embed review_page.html
+var reviewPageHTML string
+
+//go:embed status_page.html
+var statusPageHTML string
+
+//go:embed comments.html
+var commentsHTML string
+
+//go:embed workflows_list.html
+var workflowsListHTML string
+
+//go:embed graph_page.html
+var graphPageHTML string
+
+//go:embed login_page.html
+var loginPageHTML string
+
+//go:embed setup_page.html
+var setupPageHTML string
+
+//go:embed telegram_link_page.html
+var telegramLinkPageHTML string
+
+//go:embed admin_page.html
+var adminPageHTML string
+
+//go:embed error_page.html
+var errorPageHTML string
+
+// PageData represents the data passed to templates
+type PageData struct {
+	Title     string
+	Workflow  any
+	Workflows any
+	NextPage  string         // URL of the next (older) page of a list, "" on the last page
+	Filter    any            // status filter tabs of the workflows list
+	Location  *time.Location // display time zone for the current viewer
+	Spend     any            // cumulative spend of the current month
+	Graph     any            // step graph of the workflow (graph page)
+	Progress  any            // progress estimate of an unfinished workflow (status page)
+	Lineage   any            // clones, clips, extensions and stems around the workflow (status page)
+	Viewer    string         // identity of the current viewer ("" when anonymous)
+	IsAdmin   bool
+	CanEdit   bool          // viewer may start, retry, clone, schedule and comment on workflows (reviewer role)
+	Defaults  StartDefaults // initial values of the start form
+	Next      string        // where to continue after signing in (login page)
+	Error     string        // form error shown on the page
+	Setup     any           // setup wizard form and check results
+	Link      any           // Telegram chat linked to the viewer (Telegram page)
+	Upload    string        // signed link to the uploaded reference audio (review page)
+	Presets   any           // Suno property presets (review page)
+	Tags      any           // proposed style tags missing from the tag taxonomy (review page)
+	Settings  any           // runtime settings and their defaults (admin page)
+	Failure   any           // status, code, workflow and request ID of the error page
+	CSRF      string        // token of the state-changing forms (see handlers.csrfProtect)
+	Bare      bool          // no navigation or preference forms (setup wizard)
+}
+
+// StartDefaults holds the configured defaults of the start form options
+type StartDefaults struct {
+	IsPremium          bool
+	GenerateStems      bool
+	Language           string
+	Languages          any            // selectable lyrics languages ({Code, Name})
+	DetectLanguage     bool           // offer detecting the language from the description
+	LyricsEngine       string         // "openai" or "suno"
+	HasOpenAI          bool           // the OpenAI lyrics engine is available
+	TranscriptMaxChars int            // cap of pasted transcripts, 0 for none; transcripts need OpenAI
+	SunoModel          string         // SUNO_MODEL, used when none is chosen
+	SunoModels         []string       // selectable Suno models
+	SunoPersonas       []suno.Persona // personas of the Suno account premium songs may be generated with
+}
+
+// templateFuncs returns the helper functions available in every page template, formatting
+// times, numbers and durations the way l writes them
+func templateFuncs(l locale.Locale) htmltemplate.FuncMap {
+	return htmltemplate.FuncMap{
+		"formatTime":   l.Display,
+		"formatNumber": l.Number,
+		"status":       storage.LookupStatus,
+		"statusIcon":   statusIcon,
+		"formatMS":     func(ms int64) string { return l.Duration(time.Duration(ms) * time.Millisecond) },
+	}
+}
+
+// statusIconPaths holds the SVG markup of the icons named in the status registry
+var statusIconPaths = map[string]string{
+	"check":   `<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 13l4 4L19 7"/>`,
+	"alert":   `<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M12 9v2m0 4h.01m-6.938 4h13.856c1.54 0 2.502-1.667 1.732-3L13.732 4c-.77-1.333-2.694-1.333-3.464 0L3.34 16c-.77 1.333.192 3 1.732 3z"/>`,
+	"cross":   `<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M6 18L18 6M6 6l12 12"/>`,
+	"eye":     `<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M15 12a3 3 0 11-6 0 3 3 0 016 0z"/><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M2.458 12C3.732 7.943 7.523 5 12 5c4.478 0 8.268 2.943 9.542 7-1.274 4.057-5.064 7-9.542 7-4.477 0-8.268-2.943-9.542-7z"/>`,
+	"spinner": `<circle class="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" stroke-width="4"></circle><path class="opacity-75" fill="currentColor" stroke="none" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>`,
+}
+
+// statusIcon renders the icon of a status as inline SVG with the given size classes
+func statusIcon(info storage.StatusInfo, size string) htmltemplate.HTML {
+	paths, ok := statusIconPaths[info.Icon]
+	if !ok {
+		paths = statusIconPaths["spinner"]
+	}
+	class := size + " " + info.TextClass()
+	if info.Icon == "spinner" || !ok {
+		class += " animate-spin"
+	}
+	return htmltemplate.HTML(fmt.Sprintf(`<svg class="%s" fill="none" stroke="currentColor" viewBox="0 0 24 24">%s</svg>`,
+		htmltemplate.HTMLEscapeString(class), paths))
+}
+
+type TemplatesList struct {
+	Start  *htmltemplate.Template
+	Review *htmltemplate.Template
+	Status *htmltemplate.Template
+	List   *htmltemplate.Template
+	Graph  *htmltemplate.Template
+	Login  *htmltemplate.Template
+	Setup  *htmltemplate.Template
+
+	TelegramLink *htmltemplate.Template
+	Admin        *htmltemplate.Template
+	Error        *htmltemplate.Template
+}
+
+// Init initializes all templates with embedded content, formatting for the locale l
+func Init(l locale.Locale) (*TemplatesList, error) {
+	var err error
+	tplList := TemplatesList{}
+	funcs := templateFuncs(l)
+
+	tplList.Start, err = templating.ParseHTMLTemplatesWithFuncs("start", funcs, baseLayoutHTML, startPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Review, err = templating.ParseHTMLTemplatesWithFuncs("review", funcs, baseLayoutHTML, reviewPageHTML, commentsHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Status, err = templating.ParseHTMLTemplatesWithFuncs("status", funcs, baseLayoutHTML, statusPageHTML, commentsHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.List, err = templating.ParseHTMLTemplatesWithFuncs("list", funcs, baseLayoutHTML, workflowsListHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Graph, err = templating.ParseHTMLTemplatesWithFuncs("graph", funcs, baseLayoutHTML, graphPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Login, err = templating.ParseHTMLTemplatesWithFuncs("login", funcs, baseLayoutHTML, loginPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Setup, err = templating.ParseHTMLTemplatesWithFuncs("setup", funcs, baseLayoutHTML, setupPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.TelegramLink, err = templating.ParseHTMLTemplatesWithFuncs("telegram_link", funcs, baseLayoutHTML, telegramLinkPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Admin, err = templating.ParseHTMLTemplatesWithFuncs("admin", funcs, baseLayoutHTML, adminPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tplList.Error, err = templating.ParseHTMLTemplatesWithFuncs("error", funcs, baseLayoutHTML, errorPageHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tplList, nil
+}
@@ -0,0 +1,258 @@
+package accesslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix = "access-"
+	fileSuffix = ".log"
+	dayLayout  = "2006-01-02"
+
+	// Redacted replaces sensitive values in logged URLs
+	Redacted = "[REDACTED]"
+)
+
+// sensitiveParams are query parameters whose values never reach the log:
+// credentials and signed links, and free text that may carry task descriptions or lyrics
+var sensitiveParams = map[string]bool{
+	"as":               true, // signed review links
+	"token":            true,
+	"access_token":     true,
+	"secret":           true,
+	"password":         true,
+	"key":              true,
+	"api_key":          true,
+	"sig":              true,
+	"signature":        true,
+	"code":             true,
+	"task_description": true,
+	"description":      true,
+	"lyrics":           true,
+	"prompt":           true,
+	"search":           true,
+	"q":                true,
+	"query":            true, // GraphQL documents may inline search terms
+	"variables":        true, // GraphQL variables may carry any of the above
+}
+
+// Entry is one request in the access log
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"` // redacted
+	Status     int       `json:"status"`
+	DurationMS float64   `json:"duration_ms"`
+	Bytes      int       `json:"bytes"`
+	IP         string    `json:"ip"`
+	Identity   string    `json:"identity,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// Logger appends entries as JSON lines to one file per day in dir and
+// deletes files older than the retention period
+type Logger struct {
+	dir       string
+	retention time.Duration
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// New creates a logger writing to dir; a retention of zero keeps files forever
+func New(dir string, retention time.Duration) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create access log directory: %w", err)
+	}
+	l := &Logger{dir: dir, retention: retention}
+	l.prune(time.Now())
+	return l, nil
+}
+
+// Write appends an entry, rotating to a new file when the day changes
+func (l *Logger) Write(entry Entry) error {
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false) // keep "&" in queries greppable
+	if err := enc.Encode(entry); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := entry.Time.UTC().Format(dayLayout)
+	if l.file == nil || day != l.day {
+		if l.file != nil {
+			_ = l.file.Close()
+		}
+		f, err := os.OpenFile(l.path(day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+		if err != nil {
+			l.file = nil
+			return fmt.Errorf("failed to open access log: %w", err)
+		}
+		l.file, l.day = f, day
+		l.prune(entry.Time)
+	}
+
+	_, err := l.file.Write(line.Bytes())
+	return err
+}
+
+// Search returns up to limit entries whose JSON line contains every term of query
+// (case-insensitive), newest first
+func (l *Logger) Search(query string, limit int) ([]Entry, error) {
+	terms := strings.Fields(strings.ToLower(query))
+
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Entry
+	for i := len(files) - 1; i >= 0 && len(result) < limit; i-- {
+		matches, err := searchFile(filepath.Join(l.dir, files[i]), terms)
+		if err != nil {
+			return nil, err
+		}
+		for j := len(matches) - 1; j >= 0 && len(result) < limit; j-- {
+			result = append(result, matches[j])
+		}
+	}
+	return result, nil
+}
+
+// Close closes the current file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// searchFile returns the matching entries of one file in file order
+func searchFile(path string, terms []string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // pruned meanwhile
+		}
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	var matches []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		lower := strings.ToLower(string(line))
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(lower, term) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err == nil {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, scanner.Err()
+}
+
+// files lists the log files, oldest first
+func (l *Logger) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access logs: %w", err)
+	}
+	var names []string
+	for _, e := range dirEntries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// prune deletes the files of days that ended more than the retention period before now
+func (l *Logger) prune(now time.Time) {
+	if l.retention <= 0 {
+		return
+	}
+	files, err := l.files()
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-l.retention)
+	for _, name := range files {
+		day, err := time.Parse(dayLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		if day.Add(24 * time.Hour).Before(cutoff) {
+			_ = os.Remove(filepath.Join(l.dir, name))
+		}
+	}
+}
+
+func (l *Logger) path(day string) string {
+	return filepath.Join(l.dir, filePrefix+day+fileSuffix)
+}
+
+// RedactQuery replaces the values of sensitive parameters in a raw query string
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted // unparseable queries are dropped rather than leaked
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		for _, v := range values[key] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(key))
+			b.WriteByte('=')
+			if sensitiveParams[strings.ToLower(key)] {
+				b.WriteString(Redacted)
+			} else {
+				b.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,19 @@
+package blob_test
+
+import (
+	"testing"
+
+	"workflower/lib/blob"
+	"workflower/storage"
+	"workflower/storage/storetest"
+)
+
+func TestDir(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storage.Blobs {
+		dir, err := blob.NewDir(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	})
+}
@@ -0,0 +1,70 @@
+// Package cloudsync mirrors files into a cloud folder: the Dropbox API, or a local folder that a
+// desktop client (Google Drive for desktop, Dropbox, OneDrive) syncs
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned for files that do not exist on the remote side
+	ErrNotFound = errors.New("file not found")
+	// ErrConflict is returned by Put when the file changed since the given revision
+	ErrConflict = errors.New("file changed on the remote side")
+)
+
+// File is a file on the remote side
+type File struct {
+	Path string // slash-separated, relative to the sync root
+	Rev  string // changes with every write of the file
+}
+
+// Remote is a folder tree files are mirrored into
+type Remote interface {
+	// Name describes the remote for logs, e.g. "dropbox:/Workflower"
+	Name() string
+	// Stat returns the current revision of a file, ErrNotFound when there is none
+	Stat(ctx context.Context, path string) (File, error)
+	// Get returns the content of a file, ErrNotFound when there is none
+	Get(ctx context.Context, path string) ([]byte, File, error)
+	// Put writes a file, creating its folders; with ifRev set, only if the file is still at that
+	// revision (ErrConflict otherwise)
+	Put(ctx context.Context, path string, data []byte, ifRev string) (File, error)
+}
+
+// Open returns the remote of a SYNC_TARGET: "dropbox:/folder" (with an access token) or
+// "dir:/path" for a folder synced by a desktop client
+func Open(target, dropboxToken string) (Remote, error) {
+	kind, root, ok := strings.Cut(target, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid sync target %q: want dropbox:/folder or dir:/path", target)
+	}
+	switch kind {
+	case "dropbox":
+		if dropboxToken == "" {
+			return nil, errors.New("dropbox sync needs an access token")
+		}
+		return NewDropbox(dropboxToken, root), nil
+	case "dir":
+		return NewDir(root)
+	default:
+		return nil, fmt.Errorf("unknown sync target %q: want dropbox or dir", kind)
+	}
+}
+
+// cleanPath rejects paths leaving the sync root
+func cleanPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return path, nil
+}
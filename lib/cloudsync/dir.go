@@ -0,0 +1,99 @@
+package cloudsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir is a local folder kept in sync by a desktop client
+// Revisions are content hashes, so edits made through the client are seen as changes.
+type Dir struct {
+	root string
+}
+
+// NewDir returns the folder at root, creating it if needed
+func NewDir(root string) (*Dir, error) {
+	if root == "" {
+		return nil, errors.New("empty sync folder")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create sync folder: %w", err)
+	}
+	return &Dir{root: root}, nil
+}
+
+// Name implements Remote
+func (d *Dir) Name() string {
+	return "dir:" + d.root
+}
+
+// Stat implements Remote
+func (d *Dir) Stat(ctx context.Context, path string) (File, error) {
+	_, file, err := d.Get(ctx, path)
+	return file, err
+}
+
+// Get implements Remote
+func (d *Dir) Get(_ context.Context, path string) ([]byte, File, error) {
+	clean, err := cleanPath(path)
+	if err != nil {
+		return nil, File{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(d.root, filepath.FromSlash(clean)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, File{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, File{}, err
+	}
+	return data, File{Path: clean, Rev: contentRev(data)}, nil
+}
+
+// Put implements Remote
+func (d *Dir) Put(ctx context.Context, path string, data []byte, ifRev string) (File, error) {
+	clean, err := cleanPath(path)
+	if err != nil {
+		return File{}, err
+	}
+	if ifRev != "" {
+		current, err := d.Stat(ctx, clean)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return File{}, err
+		}
+		if current.Rev != ifRev {
+			return File{}, ErrConflict
+		}
+	}
+
+	full := filepath.Join(d.root, filepath.FromSlash(clean))
+	if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+		return File{}, err
+	}
+	// Written aside and renamed, so the desktop client never uploads a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".sync-*")
+	if err != nil {
+		return File{}, err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return File{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return File{}, err
+	}
+	if err := os.Rename(tmp.Name(), full); err != nil {
+		return File{}, err
+	}
+	return File{Path: clean, Rev: contentRev(data)}, nil
+}
+
+func contentRev(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,188 @@
+package cloudsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	dropboxAPIURL     = "https://api.dropboxapi.com/2"
+	dropboxContentURL = "https://content.dropboxapi.com/2"
+)
+
+// Dropbox is a folder of a Dropbox account, accessed with an access token
+type Dropbox struct {
+	token      string
+	root       string // folder under the account root, e.g. "/Workflower"
+	httpClient *http.Client
+}
+
+// NewDropbox returns the folder root of the account the token belongs to
+func NewDropbox(token, root string) *Dropbox {
+	return &Dropbox{
+		token:      token,
+		root:       "/" + strings.Trim(root, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// dropboxMetadata is the part of file metadata the sync needs
+type dropboxMetadata struct {
+	Tag string `json:".tag"`
+	Rev string `json:"rev"`
+}
+
+// dropboxError is the body of a failed API call
+type dropboxError struct {
+	ErrorSummary string `json:"error_summary"`
+}
+
+// Name implements Remote
+func (d *Dropbox) Name() string {
+	return "dropbox:" + d.root
+}
+
+// Stat implements Remote
+func (d *Dropbox) Stat(ctx context.Context, p string) (File, error) {
+	full, clean, err := d.fullPath(p)
+	if err != nil {
+		return File{}, err
+	}
+	body, _ := json.Marshal(map[string]string{"path": full})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dropboxAPIURL+"/files/get_metadata", bytes.NewReader(body))
+	if err != nil {
+		return File{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	data, _, err := d.do(req)
+	if err != nil {
+		return File{}, err
+	}
+	var meta dropboxMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return File{}, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	if meta.Tag != "file" {
+		return File{}, fmt.Errorf("%s is a %s, not a file", full, meta.Tag)
+	}
+	return File{Path: clean, Rev: meta.Rev}, nil
+}
+
+// Get implements Remote
+func (d *Dropbox) Get(ctx context.Context, p string) ([]byte, File, error) {
+	full, clean, err := d.fullPath(p)
+	if err != nil {
+		return nil, File{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dropboxContentURL+"/files/download", nil)
+	if err != nil {
+		return nil, File{}, err
+	}
+	req.Header.Set("Dropbox-API-Arg", dropboxArg(map[string]any{"path": full}))
+
+	data, header, err := d.do(req)
+	if err != nil {
+		return nil, File{}, err
+	}
+	var meta dropboxMetadata
+	if err := json.Unmarshal([]byte(header.Get("Dropbox-API-Result")), &meta); err != nil {
+		return nil, File{}, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return data, File{Path: clean, Rev: meta.Rev}, nil
+}
+
+// Put implements Remote; Dropbox creates missing folders itself
+func (d *Dropbox) Put(ctx context.Context, p string, data []byte, ifRev string) (File, error) {
+	full, clean, err := d.fullPath(p)
+	if err != nil {
+		return File{}, err
+	}
+	var mode any = "overwrite"
+	if ifRev != "" {
+		mode = map[string]string{".tag": "update", "update": ifRev}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dropboxContentURL+"/files/upload", bytes.NewReader(data))
+	if err != nil {
+		return File{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Dropbox-API-Arg", dropboxArg(map[string]any{"path": full, "mode": mode, "autorename": false, "mute": true}))
+
+	body, _, err := d.do(req)
+	if err != nil {
+		return File{}, err
+	}
+	var meta dropboxMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return File{}, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return File{Path: clean, Rev: meta.Rev}, nil
+}
+
+// do sends an authorized request and maps path errors to ErrNotFound and ErrConflict
+func (d *Dropbox) do(req *http.Request) ([]byte, http.Header, error) {
+	req.Header.Set("Authorization", "Bearer "+d.token)
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return body, resp.Header, nil
+	}
+
+	var apiErr dropboxError
+	_ = json.Unmarshal(body, &apiErr)
+	switch {
+	case resp.StatusCode == http.StatusConflict && strings.Contains(apiErr.ErrorSummary, "not_found"):
+		return nil, nil, ErrNotFound
+	case resp.StatusCode == http.StatusConflict && strings.Contains(apiErr.ErrorSummary, "conflict"):
+		return nil, nil, ErrConflict
+	case apiErr.ErrorSummary != "":
+		return nil, nil, fmt.Errorf("dropbox API returned %d: %s", resp.StatusCode, apiErr.ErrorSummary)
+	default:
+		return nil, nil, fmt.Errorf("dropbox API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+// fullPath returns the account path of a path relative to the root, and the cleaned relative path
+func (d *Dropbox) fullPath(p string) (string, string, error) {
+	clean, err := cleanPath(p)
+	if err != nil {
+		return "", "", err
+	}
+	return path.Join(d.root, clean), clean, nil
+}
+
+// dropboxArg encodes the Dropbox-API-Arg header, which must be ASCII: other characters are escaped
+func dropboxArg(arg map[string]any) string {
+	data, _ := json.Marshal(arg)
+	var b strings.Builder
+	for _, r := range string(data) {
+		if r < 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+		if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+			fmt.Fprintf(&b, `\u%04x\u%04x`, r1, r2)
+		} else {
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
@@ -40,9 +40,14 @@ func Deploy() error {
 		}
 	}
 
-	// Step 4: Copy binary
+	// Step 4: Copy binary, keeping the previous one for the supervisor to revert to
 	fmt.Println("📤 Copying binary...")
 	binaryPath := filepath.Join(remotePath, cfg.AppName)
+	backupCmd := fmt.Sprintf("if [ -f %[1]s ]; then cp -p %[1]s %[1]s%[2]s && date -u +%%FT%%TZ > %[1]s%[3]s; fi",
+		binaryPath, backupSuffix, pendingSuffix)
+	if output, err := client.RunCommand(backupCmd); err != nil {
+		return fmt.Errorf("failed to back up previous binary: %s: %w", output, err)
+	}
 	sourceBinary := filepath.Join(BUILD_DIR, cfg.AppName)
 	if err := client.CopyFile(sourceBinary, binaryPath); err != nil {
 		return fmt.Errorf("failed to copy binary: %w", err)
@@ -0,0 +1,121 @@
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Export formats
+const (
+	ExportAnsible   = "ansible"
+	ExportTerraform = "terraform"
+)
+
+// exportTarget is the deploy target as seen by external provisioning tools
+type exportTarget struct {
+	Name    string // inventory host name
+	Host    string
+	Port    int
+	User    string
+	KeyPath string
+}
+
+// Export writes the deploy target and service settings from .env/.deploy.env as
+// an Ansible inventory (YAML) or Terraform variables (tfvars), so that existing
+// infrastructure code can provision the host while -D keeps handling app rollout
+func Export(format string, w io.Writer) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	target, err := parseTarget(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ExportAnsible:
+		return exportAnsible(w, cfg, target)
+	case ExportTerraform:
+		return exportTerraform(w, cfg, target)
+	default:
+		return fmt.Errorf("unknown export format %q (use %s or %s)", format, ExportAnsible, ExportTerraform)
+	}
+}
+
+// parseTarget splits REMOTE_HOST ("[user@]host[:port]") into its parts
+// A port in REMOTE_HOST takes precedence over SSH_PORT, as when connecting
+func parseTarget(cfg *Config) (exportTarget, error) {
+	target := exportTarget{Name: cfg.AppName, Port: cfg.SSHPort, KeyPath: cfg.SSHKeyPath}
+
+	host := cfg.RemoteHost
+	if user, rest, ok := strings.Cut(host, "@"); ok {
+		target.User = user
+		host = rest
+	}
+	if h, portStr, ok := strings.Cut(host, ":"); ok {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return target, fmt.Errorf("invalid port in REMOTE_HOST: %w", err)
+		}
+		host = h
+		target.Port = port
+	}
+	target.Host = host
+	return target, nil
+}
+
+// exportVars returns the application variables shared by all formats, in output order
+func exportVars(cfg *Config) [][2]string {
+	return [][2]string{
+		{"workflower_app_name", cfg.AppName},
+		{"workflower_remote_path", cfg.RemotePath()},
+		{"workflower_binary_path", cfg.RemotePath() + "/" + cfg.AppName},
+		{"workflower_service_name", getServiceName(cfg.AppName)},
+		{"workflower_service_user", cfg.ServiceUser},
+		{"workflower_service_group", cfg.ServiceGroup},
+		{"workflower_service_description", cfg.ServiceDescription},
+	}
+}
+
+// exportAnsible writes a YAML inventory with the target host and the app variables
+func exportAnsible(w io.Writer, cfg *Config, target exportTarget) error {
+	var b strings.Builder
+	b.WriteString("# Generated by workflower deploy export --format ansible\n")
+	b.WriteString("all:\n  hosts:\n")
+	fmt.Fprintf(&b, "    %s:\n", target.Name)
+	fmt.Fprintf(&b, "      ansible_host: %s\n", strconv.Quote(target.Host))
+	fmt.Fprintf(&b, "      ansible_port: %d\n", target.Port)
+	if target.User != "" {
+		fmt.Fprintf(&b, "      ansible_user: %s\n", strconv.Quote(target.User))
+	}
+	if target.KeyPath != "" {
+		fmt.Fprintf(&b, "      ansible_ssh_private_key_file: %s\n", strconv.Quote(target.KeyPath))
+	}
+	b.WriteString("  vars:\n")
+	for _, v := range exportVars(cfg) {
+		fmt.Fprintf(&b, "    %s: %s\n", v[0], strconv.Quote(v[1]))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportTerraform writes tfvars assignments for the target host and the app variables
+func exportTerraform(w io.Writer, cfg *Config, target exportTarget) error {
+	var b strings.Builder
+	b.WriteString("# Generated by workflower deploy export --format terraform\n")
+	fmt.Fprintf(&b, "workflower_host = %s\n", strconv.Quote(target.Host))
+	fmt.Fprintf(&b, "workflower_ssh_port = %d\n", target.Port)
+	fmt.Fprintf(&b, "workflower_ssh_user = %s\n", strconv.Quote(target.User))
+	fmt.Fprintf(&b, "workflower_ssh_key_path = %s\n", strconv.Quote(target.KeyPath))
+	for _, v := range exportVars(cfg) {
+		fmt.Fprintf(&b, "%s = %s\n", v[0], strconv.Quote(v[1]))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
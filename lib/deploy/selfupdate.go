@@ -0,0 +1,177 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	releaseCheckTimeout = 30 * time.Second
+	maxChecksumsSize    = 1 << 20
+)
+
+// Release is a GitHub release as returned by the releases API
+type Release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a release
+type ReleaseAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// SelfUpdate checks RELEASE_URL (a GitHub "latest release" API URL) for a newer version,
+// installs the binary matching this platform after verifying its SHA-256 checksum, and
+// restarts the systemd service of APP_NAME when it is running.
+// With checkOnly it only reports whether an update is available.
+func SelfUpdate(ctx context.Context, currentVersion string, checkOnly bool) error {
+	_ = godotenv.Load(".env")
+
+	releaseURL := os.Getenv("RELEASE_URL")
+	if releaseURL == "" {
+		return fmt.Errorf("RELEASE_URL not set (e.g. https://api.github.com/repos/OWNER/REPO/releases/latest)")
+	}
+	token := os.Getenv("RELEASE_TOKEN")
+
+	release, err := fetchRelease(ctx, releaseURL, token)
+	if err != nil {
+		return err
+	}
+	if release.TagName == currentVersion {
+		fmt.Printf("✅ Already up to date (%s)\n", currentVersion)
+		return nil
+	}
+
+	binary, err := release.platformAsset(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📦 Update available: %s -> %s (%s)\n", currentVersion, release.TagName, binary.Name)
+	if checkOnly {
+		return nil
+	}
+
+	checksum, err := release.checksum(ctx, binary.Name, token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📥 Downloading and verifying...")
+	exe, err := InstallRelease(ctx, binary.DownloadURL, checksum)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔧 Installed %s to %s\n", release.TagName, exe)
+
+	if appName := os.Getenv("APP_NAME"); appName != "" {
+		serviceName := getServiceName(appName)
+		if isServiceActive(serviceName) {
+			if err := restartOrStartService(serviceName); err != nil {
+				return fmt.Errorf("binary updated but restart failed: %w", err)
+			}
+			fmt.Printf("🔄 Restarted %s\n", serviceName)
+			return nil
+		}
+	}
+	fmt.Println("ℹ️  Restart the server to run the new version")
+	return nil
+}
+
+// fetchRelease loads the release description from the GitHub API
+func fetchRelease(ctx context.Context, releaseURL, token string) (*Release, error) {
+	body, err := fetch(ctx, releaseURL, token, "application/vnd.github+json", maxChecksumsSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check release: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release has no tag name")
+	}
+	return &release, nil
+}
+
+// platformAsset returns the binary built for goos/goarch, e.g. "workflower_linux_amd64"
+func (r *Release) platformAsset(goos, goarch string) (*ReleaseAsset, error) {
+	suffix := goos + "_" + goarch
+	for i, asset := range r.Assets {
+		name := strings.TrimSuffix(asset.Name, ".exe")
+		if strings.HasSuffix(name, suffix) || strings.HasSuffix(name, goos+"-"+goarch) {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no binary for %s/%s", r.TagName, goos, goarch)
+}
+
+// checksum returns the SHA-256 checksum of an asset from "<asset>.sha256" or a
+// checksums file ("<sha256>  <asset>" per line, as written by sha256sum and goreleaser)
+func (r *Release) checksum(ctx context.Context, assetName, token string) (string, error) {
+	for _, asset := range r.Assets {
+		name := strings.ToLower(asset.Name)
+		if name != strings.ToLower(assetName)+".sha256" && !strings.Contains(name, "checksums") && name != "sha256sums" {
+			continue
+		}
+		body, err := fetch(ctx, asset.DownloadURL, token, "application/octet-stream", maxChecksumsSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", asset.Name, err)
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 1 && strings.HasSuffix(name, ".sha256") {
+				return fields[0], nil
+			}
+			if len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == assetName {
+				return fields[0], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("release %s has no checksum for %s", r.TagName, assetName)
+}
+
+// fetch GETs a small document, authenticating with token when set
+func fetch(ctx context.Context, url, token, accept string, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, releaseCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "workflower-self-update")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// isServiceActive reports whether a systemd service is running
+func isServiceActive(serviceName string) bool {
+	return exec.Command("systemctl", "is-active", "--quiet", serviceName).Run() == nil
+}
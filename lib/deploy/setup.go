@@ -28,6 +28,12 @@ func Setup() error {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	// Install the supervisor that starts the server and reverts unhealthy binary swaps
+	slog.Info("Installing supervisor")
+	if err := installSupervisor(fmt.Sprintf("%s/%s%s", remotePath, cfg.AppName, SupervisorSuffix)); err != nil {
+		return fmt.Errorf("failed to install supervisor: %w", err)
+	}
+
 	// Step 1: Generate service file
 	slog.Info("Generating systemd service file")
 	serviceContent, err := GenerateServiceFile(cfg)
@@ -133,11 +139,8 @@ func enableService(serviceName string) error {
 
 // restartOrStartService restarts or starts the service based on current state
 func restartOrStartService(serviceName string) error {
-	// Check if service is active
-	cmd := exec.Command("systemctl", "is-active", "--quiet", serviceName)
-	isActive := cmd.Run() == nil
-
-	if isActive {
+	var cmd *exec.Cmd
+	if isServiceActive(serviceName) {
 		slog.Info("Restarting service", "service", serviceName)
 		cmd = exec.Command("sudo", "systemctl", "restart", serviceName)
 	} else {
@@ -0,0 +1,259 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"workflower/lib/telegram"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// SupervisorSuffix names the copy of the binary that setup installs as the service entry point
+	SupervisorSuffix = "-supervisor"
+
+	backupSuffix  = ".old"
+	pendingSuffix = ".pending"
+
+	defaultHealthTimeout = 30 * time.Second
+	healthPollInterval   = time.Second
+	stopTimeout          = 10 * time.Second
+)
+
+// errUnhealthy reports that a freshly installed binary did not pass its health check
+var errUnhealthy = errors.New("health check did not pass")
+
+// errExitedEarly reports that the child exited during its health check window
+var errExitedEarly = fmt.Errorf("%w: server exited during startup", errUnhealthy)
+
+// markPending keeps the current binary as <exe>.old and flags the binary about to be
+// installed as pending, so that the supervisor reverts it when it fails its health check
+func markPending(exe string) error {
+	if err := copyFile(exe, exe+backupSuffix); err != nil {
+		return fmt.Errorf("failed to back up binary: %w", err)
+	}
+	stamp := time.Now().UTC().Format(time.RFC3339)
+	if err := os.WriteFile(exe+pendingSuffix, []byte(stamp+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to mark binary as pending: %w", err)
+	}
+	return nil
+}
+
+// Supervise runs the server binary as a child process and keeps it running
+// After a binary swap (a pending marker next to the binary) the child must answer
+// GET /health within DEPLOY_HEALTH_TIMEOUT; otherwise the previous binary is restored,
+// admins are notified on Telegram and the previous version is started again.
+// A child exiting with RestartExitCode is restarted in place; any other exit ends
+// the supervisor with an error so that systemd restarts the service.
+func Supervise(ctx context.Context, binary string, args []string) error {
+	_ = godotenv.Load(".env")
+
+	healthTimeout := defaultHealthTimeout
+	if v := os.Getenv("DEPLOY_HEALTH_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid DEPLOY_HEALTH_TIMEOUT: %w", err)
+		}
+		healthTimeout = d
+	}
+	healthURL := fmt.Sprintf("http://127.0.0.1:%s/health", getEnvOrDefault("SERVER_PORT", "8080"))
+
+	for {
+		pending := fileExists(binary + pendingSuffix)
+
+		cmd := exec.Command(binary, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			if pending {
+				if rbErr := rollback(ctx, binary, err); rbErr != nil {
+					return rbErr
+				}
+				continue
+			}
+			return fmt.Errorf("failed to start %s: %w", binary, err)
+		}
+		slog.Info("Supervisor started server", "binary", binary, "pid", cmd.Process.Pid, "pending", pending)
+
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+
+		if pending {
+			err := waitHealthy(ctx, healthURL, healthTimeout, exited)
+			if ctx.Err() != nil {
+				stopChild(cmd, exited)
+				return nil
+			}
+			if err != nil {
+				if !errors.Is(err, errExitedEarly) {
+					stopChild(cmd, exited)
+				}
+				if rbErr := rollback(ctx, binary, err); rbErr != nil {
+					return rbErr
+				}
+				continue
+			}
+			_ = os.Remove(binary + pendingSuffix)
+			slog.Info("New binary passed health check", "binary", binary)
+		}
+
+		select {
+		case <-ctx.Done():
+			stopChild(cmd, exited)
+			return nil
+		case err := <-exited:
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == RestartExitCode {
+				slog.Info("Server requested restart", "binary", binary)
+				continue
+			}
+			if err == nil {
+				return nil
+			}
+			return fmt.Errorf("server exited: %w", err)
+		}
+	}
+}
+
+// waitHealthy polls healthURL until it answers 200, the child exits or timeout passes
+// A child that exits during the window is reported as unhealthy; its exit is consumed.
+func waitHealthy(ctx context.Context, healthURL string, timeout time.Duration, exited <-chan error) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	client := &http.Client{Timeout: healthPollInterval}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited with status 0")
+			}
+			return fmt.Errorf("%w: %v", errExitedEarly, err)
+		case <-deadline.C:
+			return fmt.Errorf("%w: no healthy response from %s within %s", errUnhealthy, healthURL, timeout)
+		case <-ticker.C:
+			resp, err := client.Get(healthURL)
+			if err != nil {
+				continue
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+	}
+}
+
+// stopChild asks the child to terminate and kills it if it does not exit in time
+func stopChild(cmd *exec.Cmd, exited <-chan error) {
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(stopTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// rollback restores the previous binary after a failed health check and notifies admins
+func rollback(ctx context.Context, binary string, cause error) error {
+	_ = os.Remove(binary + pendingSuffix)
+
+	if !fileExists(binary + backupSuffix) {
+		notifyAdmins(ctx, fmt.Sprintf("🚨 Deployed binary failed its health check and no previous binary is available\n\n%s\n%v", binary, cause))
+		return fmt.Errorf("new binary is unhealthy and there is no backup to restore: %w", cause)
+	}
+	if err := os.Rename(binary+backupSuffix, binary); err != nil {
+		notifyAdmins(ctx, fmt.Sprintf("🚨 Deployed binary failed its health check and could not be reverted\n\n%s\n%v\n%v", binary, cause, err))
+		return fmt.Errorf("failed to restore previous binary: %w", err)
+	}
+
+	slog.Error("Reverted to previous binary", "binary", binary, "cause", cause)
+	notifyAdmins(ctx, fmt.Sprintf("⏪ Deploy reverted\n\nThe new binary failed its health check and the previous version was restored.\n\n%s\n%v", binary, cause))
+	return nil
+}
+
+// notifyAdmins sends a message to TELEGRAM_CHAT_ID and to the Telegram admins in ADMIN_USERS
+func notifyAdmins(ctx context.Context, message string) {
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		return
+	}
+
+	var chats []string
+	seen := make(map[string]bool)
+	add := func(chatID string) {
+		if chatID = strings.TrimSpace(chatID); chatID != "" && !seen[chatID] {
+			seen[chatID] = true
+			chats = append(chats, chatID)
+		}
+	}
+	add(os.Getenv("TELEGRAM_CHAT_ID"))
+	for _, user := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		if chatID, ok := strings.CutPrefix(strings.TrimSpace(user), "tg:"); ok {
+			add(chatID)
+		}
+	}
+
+	// Deliver even when the supervisor is shutting down
+	ctx = context.WithoutCancel(ctx)
+	notifier := telegram.NewNotifier(token, "")
+	for _, chatID := range chats {
+		if err := notifier.SendToChat(ctx, chatID, message); err != nil {
+			slog.Error("Failed to notify admin", "chat_id", chatID, "error", err)
+		}
+	}
+}
+
+// installSupervisor copies the running binary next to the service binary as its supervisor
+// The copy is only refreshed by setup, so a broken release cannot replace its own supervisor.
+func installSupervisor(path string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate running binary: %w", err)
+	}
+	tmpPath := path + ".new"
+	if err := copyFile(exe, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to install supervisor: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies an executable, replacing dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, artifactFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
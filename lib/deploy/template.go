@@ -29,9 +29,9 @@ func GenerateServiceFile(cfg *Config) (string, error) {
 		User:             cfg.ServiceUser,
 		Group:            cfg.ServiceGroup,
 		WorkingDirectory: remotePath,
-		ExecStart:        fmt.Sprintf("%s/%s", remotePath, cfg.AppName),
+		ExecStart:        fmt.Sprintf("%s/%s%s supervise %s/%s", remotePath, cfg.AppName, SupervisorSuffix, remotePath, cfg.AppName),
 		EnvFile:          fmt.Sprintf("%s/.env", remotePath),
-		ReadWritePaths:   fmt.Sprintf("%s/uploads", remotePath),
+		ReadWritePaths:   remotePath, // uploads, and binary swaps by the deploy webhook
 	}
 
 	content, err := templating.Execute(serviceTemplate, serviceConfig, templating.Text)
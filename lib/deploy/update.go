@@ -0,0 +1,109 @@
+package deploy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// RestartExitCode is the exit status used after a binary swap; any non-zero
+	// status makes systemd (Restart=on-failure) start the new binary
+	RestartExitCode = 75
+
+	maxArtifactSize  = 512 << 20
+	downloadTimeout  = 5 * time.Minute
+	artifactFileMode = 0755
+)
+
+// InstallRelease downloads a release binary, verifies its SHA-256 checksum and
+// swaps it in place of the running executable, keeping the previous binary as <exe>.old
+// for the supervisor to restore if the new one fails its health check. The caller restarts the process.
+// It returns the path of the replaced binary.
+func InstallRelease(ctx context.Context, artifactURL, checksum string) (string, error) {
+	u, err := url.Parse(artifactURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("invalid artifact URL %q", artifactURL)
+	}
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if len(checksum) != sha256.Size*2 {
+		return "", fmt.Errorf("sha256 checksum must be %d hex characters", sha256.Size*2)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate running binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", fmt.Errorf("failed to resolve running binary: %w", err)
+	}
+
+	// Download next to the binary so that the final rename is atomic
+	tmpPath := exe + ".new"
+	if err := download(ctx, artifactURL, tmpPath, checksum); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+
+	// Keep the running binary so the supervisor can revert an unhealthy release
+	if err := markPending(exe); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, exe); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to swap binary: %w", err)
+	}
+	return exe, nil
+}
+
+// download writes the artifact to path and checks its SHA-256 checksum
+func download(ctx context.Context, artifactURL, path, checksum string) error {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifactURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download artifact: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download artifact: HTTP %d", resp.StatusCode)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, artifactFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(resp.Body, maxArtifactSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if n > maxArtifactSize {
+		return fmt.Errorf("artifact is larger than %d MB", maxArtifactSize>>20)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != checksum {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, sum)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package eventbus
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Handler receives published events
+// Handlers run synchronously on the publisher's goroutine and must not block;
+// slow work (network calls, disk I/O) should be moved to a goroutine
+type Handler[T any] func(event T)
+
+// Bus is a minimal in-process publish/subscribe bus
+type Bus[T any] struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]Handler[T]
+}
+
+// New creates an empty event bus
+func New[T any]() *Bus[T] {
+	return &Bus[T]{
+		handlers: make(map[int]Handler[T]),
+	}
+}
+
+// Subscribe registers a handler and returns a function that removes it
+func (b *Bus[T]) Subscribe(handler Handler[T]) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers the event to every subscribed handler
+// A panicking handler is logged and does not affect other subscribers
+func (b *Bus[T]) Publish(event T) {
+	b.mu.RLock()
+	handlers := make([]Handler[T], 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		dispatch(handler, event)
+	}
+}
+
+func dispatch[T any](handler Handler[T], event T) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Event handler panicked", "panic", r)
+		}
+	}()
+	handler(event)
+}
@@ -0,0 +1,288 @@
+// Package graphql implements a small, dependency-free subset of GraphQL:
+// single query/subscription operations with aliases, arguments and variables,
+// executed by projecting JSON-like values onto the requested selection set.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Operation types
+const (
+	OperationQuery        = "query"
+	OperationSubscription = "subscription"
+)
+
+// TypenameField is answered from the "__typename" key of an object
+const TypenameField = "__typename"
+
+// Operation is a parsed GraphQL operation
+type Operation struct {
+	Type       string
+	Name       string
+	Selections []*Field
+}
+
+// Field is a selected field with its arguments (variables already substituted)
+type Field struct {
+	Alias      string
+	Name       string
+	Args       map[string]any
+	Selections []*Field
+}
+
+// Key returns the response key of the field: its alias, or its name
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Resolver lazily computes a field value from the field's arguments
+// Objects may hold resolvers for fields that are expensive or take arguments
+type Resolver func(args map[string]any) (any, error)
+
+// Request is a GraphQL request body
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response body
+type Response struct {
+	Data   any     `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error entry
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// ErrorResponse wraps err as a response without data
+func ErrorResponse(err error) Response {
+	return Response{Errors: []Error{{Message: err.Error()}}}
+}
+
+// Object is a JSON object that keeps the key order of the selection set
+type Object []Entry
+
+// Entry is a key/value pair of an Object
+type Entry struct {
+	Key   string
+	Value any
+}
+
+// MarshalJSON encodes the object with its keys in selection order
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ToValue converts a struct to its JSON-like form (map[string]any, []any and scalars)
+// Field names are those of the JSON encoding; fields dropped by omitempty are kept as null
+// so that they can still be selected
+func ToValue(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	fillMissing(value, reflect.TypeOf(v))
+	return value, nil
+}
+
+// fillMissing adds null entries for the JSON fields of t that are absent from value
+func fillMissing(value any, t reflect.Type) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return
+	}
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			fillMissing(item, t)
+		}
+	case map[string]any:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if item, ok := v[name]; ok {
+				fillMissing(item, field.Type)
+			} else {
+				v[name] = nil
+			}
+		}
+	}
+}
+
+// Select projects value onto a selection set
+// value is a JSON-like object (or list of objects) whose entries may be Resolvers
+func Select(value any, selections []*Field) (any, error) {
+	return selectValue(value, selections, nil)
+}
+
+func selectValue(value any, selections []*Field, path []any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			selected, err := selectValue(item, selections, append(append([]any(nil), path...), i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = selected
+		}
+		return list, nil
+	case map[string]any:
+		if len(selections) == 0 {
+			return nil, &PathError{Path: path, Err: fmt.Errorf("field must have a selection of subfields")}
+		}
+		object := make(Object, 0, len(selections))
+		for _, field := range selections {
+			fieldPath := append(append([]any(nil), path...), field.Key())
+			fieldValue, ok := v[field.Name]
+			if !ok {
+				return nil, &PathError{Path: fieldPath, Err: fmt.Errorf("unknown field %q", field.Name)}
+			}
+			if resolve, ok := fieldValue.(Resolver); ok {
+				resolved, err := resolve(field.Args)
+				if err != nil {
+					return nil, &PathError{Path: fieldPath, Err: err}
+				}
+				fieldValue = resolved
+			}
+			if isComposite(fieldValue) || len(field.Selections) > 0 {
+				selected, err := selectValue(fieldValue, field.Selections, fieldPath)
+				if err != nil {
+					return nil, err
+				}
+				fieldValue = selected
+			}
+			object = append(object, Entry{Key: field.Key(), Value: fieldValue})
+		}
+		return object, nil
+	default:
+		if len(selections) > 0 {
+			return nil, &PathError{Path: path, Err: fmt.Errorf("scalar field cannot have a selection")}
+		}
+		return value, nil
+	}
+}
+
+// isComposite reports whether v is an object or a list that needs a selection (lists of scalars do not)
+func isComposite(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return true
+	case []any:
+		for _, item := range v {
+			if isComposite(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PathError is an execution error at a response path
+type PathError struct {
+	Path []any
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseError converts an execution error into a response
+func ResponseError(err error) Response {
+	if pathErr, ok := err.(*PathError); ok {
+		return Response{Errors: []Error{{Message: pathErr.Err.Error(), Path: pathErr.Path}}}
+	}
+	return ErrorResponse(err)
+}
+
+// StringArg returns a string argument ("" when missing or not a string)
+func StringArg(args map[string]any, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// IntArg returns an integer argument, or def when missing
+func IntArg(args map[string]any, name string, def int) int {
+	switch v := args[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// BoolArg returns a boolean argument and whether it was provided
+func BoolArg(args map[string]any, name string) (value, ok bool) {
+	value, ok = args[name].(bool)
+	return value, ok
+}
+
+// StringListArg returns a list-of-strings argument; a single string is accepted as a one-item list
+func StringListArg(args map[string]any, name string) []string {
+	switch v := args[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lex splits a GraphQL document into tokens, skipping whitespace, commas and comments
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokenPunct, "...", i})
+			i += 3
+		case strings.ContainsRune("{}()[]:!$=@|&", rune(c)):
+			tokens = append(tokens, token{tokenPunct, string(c), i})
+			i++
+		case c == '"':
+			value, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("at %d: %w", i, err)
+			}
+			tokens = append(tokens, token{tokenString, value, i})
+			i += n
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			kind := tokenInt
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				if !isDigit(src[i]) {
+					kind = tokenFloat
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind, src[start:i], start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || isDigit(src[i]) || unicode.IsLetter(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenName, src[start:i], start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lexString reads a quoted string and returns its value and length in the source
+func lexString(src string) (string, int, error) {
+	if strings.HasPrefix(src, `"""`) {
+		end := strings.Index(src[3:], `"""`)
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated block string")
+		}
+		return strings.TrimSpace(src[3 : 3+end]), end + 6, nil
+	}
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '"':
+			value, err := strconv.Unquote(src[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string: %w", err)
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// maxDepth bounds the nesting of selection sets, list and object values and types, so that a
+// hostile query cannot exhaust the stack of the recursive descent
+const maxDepth = 32
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]any
+}
+
+// Parse parses the first operation of a GraphQL document and substitutes its variables
+// Fragments, directives and mutations are not supported
+func Parse(query string, variables map[string]any) (*Operation, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, variables: variables}
+	if p.variables == nil {
+		p.variables = map[string]any{}
+	}
+	return p.parseOperation()
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+func (p *parser) expectPunct(value string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.value != value {
+		return p.errorf(t, "expected %q", value)
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", p.errorf(t, "expected name")
+	}
+	return t.value, nil
+}
+
+// enter checks the nesting depth of the construct starting at the next token
+func (p *parser) enter(depth int) error {
+	if depth > maxDepth {
+		return p.errorf(p.peek(), "nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	found := t.value
+	if t.kind == tokenEOF {
+		found = "end of document"
+	}
+	return fmt.Errorf("syntax error at %d: %s, found %q", t.pos, fmt.Sprintf(format, args...), found)
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: OperationQuery}
+
+	if t := p.peek(); t.kind == tokenName {
+		switch t.value {
+		case OperationQuery, OperationSubscription:
+			op.Type = t.value
+		case "mutation":
+			return nil, fmt.Errorf("mutations are not supported")
+		case "fragment":
+			return nil, fmt.Errorf("fragments are not supported")
+		default:
+			return nil, p.errorf(t, "expected operation")
+		}
+		p.next()
+
+		if p.peek().kind == tokenName {
+			op.Name = p.next().value
+		}
+		if p.isPunct("(") {
+			if err := p.parseVariableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+		if p.isPunct("@") {
+			return nil, p.errorf(p.peek(), "directives are not supported")
+		}
+	}
+
+	selections, err := p.parseSelectionSet(1)
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+
+	if t := p.peek(); t.kind != tokenEOF {
+		if t.kind == tokenName && t.value == "fragment" {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		return nil, p.errorf(t, "expected end of document (only one operation is supported)")
+	}
+	return op, nil
+}
+
+// parseVariableDefinitions applies default values of variables that were not provided
+func (p *parser) parseVariableDefinitions() error {
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for !p.isPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(1); err != nil {
+			return err
+		}
+		if p.isPunct("=") {
+			p.next()
+			value, err := p.parseValue(1)
+			if err != nil {
+				return err
+			}
+			if _, ok := p.variables[name]; !ok {
+				p.variables[name] = value
+			}
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) skipType(depth int) error {
+	if err := p.enter(depth); err != nil {
+		return err
+	}
+	if p.isPunct("[") {
+		p.next()
+		if err := p.skipType(depth + 1); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet(depth int) ([]*Field, error) {
+	if err := p.enter(depth); err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for !p.isPunct("}") {
+		if p.isPunct("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		field, err := p.parseField(depth)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	p.next()
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+// parseField parses a field of a selection set at depth
+func (p *parser) parseField(depth int) (*Field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{Name: name}
+	if p.isPunct(":") {
+		p.next()
+		field.Alias = name
+		if field.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isPunct("(") {
+		p.next()
+		field.Args = map[string]any{}
+		for !p.isPunct(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			field.Args[argName] = value
+		}
+		p.next()
+	}
+
+	if p.isPunct("@") {
+		return nil, p.errorf(p.peek(), "directives are not supported")
+	}
+
+	if p.isPunct("{") {
+		if field.Selections, err = p.parseSelectionSet(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) parseValue(depth int) (any, error) {
+	if err := p.enter(depth); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, p.errorf(t, "invalid integer")
+		}
+		return n, nil
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid float")
+		}
+		return f, nil
+	case tokenString:
+		return t.value, nil
+	case tokenName:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// Enum values are passed as strings
+		return t.value, nil
+	case tokenPunct:
+		switch t.value {
+		case "$":
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return p.variables[name], nil
+		case "[":
+			var list []any
+			for !p.isPunct("]") {
+				value, err := p.parseValue(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			object := map[string]any{}
+			for !p.isPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				object[name] = value
+			}
+			p.next()
+			return object, nil
+		}
+	}
+	return nil, p.errorf(t, "expected value")
+}
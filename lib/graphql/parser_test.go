@@ -0,0 +1,42 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsDeepNesting(t *testing.T) {
+	tests := map[string]string{
+		"list values":    "{ workflows(filter: " + strings.Repeat("[", 1<<20) + strings.Repeat("]", 1<<20) + ") { id } }",
+		"object values":  "{ workflows(filter: " + strings.Repeat("{a: ", 64) + "1" + strings.Repeat("}", 64) + ") { id } }",
+		"selection sets": strings.Repeat("{ a ", 64) + strings.Repeat("}", 64),
+		"variable types": "query($v: " + strings.Repeat("[", 64) + "Int" + strings.Repeat("]", 64) + ") { id }",
+	}
+	for name, query := range tests {
+		_, err := Parse(query, nil)
+		if err == nil || !strings.Contains(err.Error(), "nested deeper") {
+			t.Errorf("%s: error = %v, want the nesting refused", name, err)
+		}
+	}
+}
+
+func TestParseAcceptsNestingUpToLimit(t *testing.T) {
+	// The selection set is level 1 and the argument value level 2
+	lists := maxDepth - 2
+	query := "{ workflows(filter: " + strings.Repeat("[", lists) + "1" + strings.Repeat("]", lists) + ") { id } }"
+	op, err := Parse(query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := op.Selections[0].Args["filter"]
+	for range lists {
+		list, ok := value.([]any)
+		if !ok || len(list) != 1 {
+			t.Fatalf("value = %#v, want a one-element list", value)
+		}
+		value = list[0]
+	}
+	if value != 1 {
+		t.Errorf("innermost value = %#v, want 1", value)
+	}
+}
@@ -0,0 +1,42 @@
+package langdetect_test
+
+import (
+	"fmt"
+
+	"workflower/lib/langdetect"
+)
+
+func ExampleDetect() {
+	for _, text := range []string{
+		"A happy song about my dog and the beach",
+		"Una canción alegre sobre mi perro y la playa",
+		"Ein fröhliches Lied über meinen Hund und den Strand",
+		"Весела пісня про мого собаку і пляж",
+		"犬と海についての明るい歌",
+		"Une chanson joyeuse sur mon chien et la plage",
+		"Una canzone allegra sul mio cane e la spiaggia",
+		"Uma música alegre sobre o meu cão e a praia",
+		"Een vrolijk liedje over mijn hond en het strand",
+		"Wesoła piosenka o moim psie i plaży",
+		"Köpeğim ve plaj hakkında neşeli bir şarkı",
+		"birthday song for Ana",
+		"ok",
+	} {
+		code, confidence := langdetect.Detect(text)
+		fmt.Printf("%q %.1f\n", code, confidence)
+	}
+	// Output:
+	// "en" 0.8
+	// "es" 0.6
+	// "de" 0.9
+	// "uk" 1.0
+	// "ja" 1.0
+	// "fr" 0.8
+	// "it" 0.6
+	// "pt" 0.7
+	// "nl" 0.9
+	// "pl" 0.7
+	// "tr" 0.9
+	// "en" 0.5
+	// "" 0.0
+}
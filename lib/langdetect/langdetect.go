@@ -0,0 +1,133 @@
+// Package langdetect guesses the language of a short text, such as a song request, offline:
+// texts in other alphabets by their script, Latin ones by their most common words and letters.
+// It knows the lyrics languages of the workflow engine; anything else is reported as unknown.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLetters is the fewest letters a text needs to be detected at all
+const minLetters = 3
+
+// Detect returns the ISO 639-1 code of the language of text and how confident the guess is
+// (0 to 1); the code is "" when the text is too short or has no recognizable words
+func Detect(text string) (code string, confidence float64) {
+	var latin, cyrillic, han, kana, hangul, devanagari, letters int
+	var ukrainian, russian int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			switch unicode.ToLower(r) {
+			case 'і', 'ї', 'є', 'ґ':
+				ukrainian++
+			case 'ы', 'э', 'ъ', 'ё':
+				russian++
+			}
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		}
+	}
+	if letters < minLetters {
+		return "", 0
+	}
+
+	share := func(n int) float64 { return float64(n) / float64(letters) }
+	switch {
+	case hangul > latin && hangul >= cyrillic:
+		return "ko", share(hangul)
+	case kana > 0 && kana+han > latin:
+		return "ja", share(kana + han) // Japanese mixes kana with kanji
+	case han > latin:
+		return "zh", share(han)
+	case devanagari > latin:
+		return "hi", share(devanagari)
+	case cyrillic > latin:
+		switch {
+		case ukrainian > russian:
+			return "uk", share(cyrillic)
+		case russian > ukrainian:
+			return "ru", share(cyrillic)
+		default:
+			return "ru", share(cyrillic) * 0.6 // no letter only one of them uses
+		}
+	}
+	return detectLatin(text)
+}
+
+// detectLatin scores the languages written in Latin script by their common words and letters
+func detectLatin(text string) (string, float64) {
+	text = strings.ToLower(text)
+	scores := make(map[string]float64, len(latinLanguages))
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	for _, word := range words {
+		for _, lang := range latinLanguages {
+			if lang.words[word] {
+				scores[lang.code]++
+			}
+		}
+	}
+	for _, r := range text {
+		for _, lang := range latinLanguages {
+			if strings.ContainsRune(lang.letters, r) {
+				scores[lang.code] += 0.5
+			}
+		}
+	}
+
+	var best string
+	var top, total float64
+	for _, lang := range latinLanguages { // in order, so that ties go to the first listed
+		score := scores[lang.code]
+		total += score
+		if score > top {
+			best, top = lang.code, score
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	// A lead over the others counts for little until a few words back it
+	return best, top / total * min(top/4, 1)
+}
+
+// latinLanguage is a language written in Latin script with its telltale words and letters
+type latinLanguage struct {
+	code    string
+	words   map[string]bool
+	letters string // letters (almost) only this language uses
+}
+
+var latinLanguages = []latinLanguage{
+	{"en", wordSet("the and of to is in it that for with about my our song her his we you i me on this be are was love"), ""},
+	{"es", wordSet("el la los las de del y que en un una por para con sobre mi su es canción amor nuestro muy al se lo como"), "ñ¿¡"},
+	{"fr", wordSet("le la les des du de et un une pour avec sur est mon ma mes notre chanson qui que dans pas au aux ce il elle"), "èêëœ"},
+	{"de", wordSet("der die das und ein eine ist mit für über von zu den dem nicht ich wir mein meine unser lied auf im sie es"), "ßäöü"},
+	{"it", wordSet("il lo la gli le di e un una per con su che è mio mia nostro canzone del della non sono nel alla ma"), "ì"},
+	{"pt", wordSet("o a os as de do da e um uma para com sobre que é meu minha nosso música canção não no na em dos"), "ãõ"},
+	{"nl", wordSet("de het een en van is met voor over op ik wij mijn ons liedje lied niet dat die te zijn naar"), "ĳ"},
+	{"pl", wordSet("i w na z że do się nie o jest dla mój moja nasz piosenka jak to ale od po przez"), "łąęśźżćń"},
+	{"tr", wordSet("ve bir bu için ile da de çok ne şarkı benim bizim hakkında gibi olan ama ben sen"), "şğı"},
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
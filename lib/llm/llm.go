@@ -0,0 +1,21 @@
+// Package llm defines what the engine needs of a language model provider; the openai and
+// openrouter clients implement it, and llmtest checks other implementations
+package llm
+
+import (
+	"context"
+
+	"workflower/lib/llm/openai"
+	"workflower/lib/llm/openrouter"
+)
+
+// Chatter answers a user prompt under a system prompt
+// API failures (rate limits, refusals, empty choices) are errors, never an empty reply.
+type Chatter interface {
+	Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+}
+
+var (
+	_ Chatter = (*openai.Client)(nil)
+	_ Chatter = (*openrouter.Client)(nil)
+)
@@ -0,0 +1,135 @@
+// Package llmtest is the conformance suite of llm.Chatter implementations. Run checks a working
+// provider, against the real API or a fake of it; RunFailures checks one whose API rejects
+// every request (an invalid key, a fake answering 401 or 429):
+//
+//	func TestProvider(t *testing.T) {
+//		llmtest.Run(t, func(t *testing.T) llm.Chatter { return mistral.New(key, "mistral-small") })
+//		llmtest.RunFailures(t, func(t *testing.T) llm.Chatter { return mistral.New("invalid", "mistral-small") })
+//	}
+//
+// Working providers are asked to answer "pong", so fakes should echo the word they are asked for.
+package llmtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"workflower/lib/llm"
+)
+
+// Factory returns a provider; it is called once per subtest
+type Factory func(t *testing.T) llm.Chatter
+
+// callTimeout bounds every call of the suite
+const callTimeout = 2 * time.Minute
+
+const (
+	pongSystemPrompt = "You are a test endpoint. Answer with the single word you are asked for, nothing else."
+	pongUserPrompt   = "Answer with the word: pong"
+)
+
+// Run checks a working provider, each behavior in its own subtest
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+	tests := []struct {
+		name string
+		run  func(t *testing.T, chatter llm.Chatter)
+	}{
+		{"Reply", testReply},
+		{"LongUnicodePrompt", testLongUnicodePrompt},
+		{"CanceledContext", testCanceledContext},
+		{"Concurrent", testConcurrent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory(t))
+		})
+	}
+}
+
+// RunFailures checks that a provider whose API rejects requests reports errors rather than
+// empty replies, which the engine would store as lyrics
+func RunFailures(t *testing.T, factory Factory) {
+	t.Helper()
+	t.Run("RejectedRequest", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+		reply, err := factory(t).Chat(ctx, pongSystemPrompt, pongUserPrompt)
+		if err == nil {
+			t.Fatalf("Chat of a rejected request returned %q and no error", reply)
+		}
+		if reply != "" {
+			t.Errorf("Chat returned reply %q along with error %v", reply, err)
+		}
+	})
+}
+
+// testReply: the reply follows the prompts
+func testReply(t *testing.T, chatter llm.Chatter) {
+	reply := chat(t, chatter, pongSystemPrompt, pongUserPrompt)
+	if !strings.Contains(strings.ToLower(reply), "pong") {
+		t.Fatalf("reply %q does not contain pong", reply)
+	}
+}
+
+// testLongUnicodePrompt: transcripts and lyrics are long, multi-line and not only ASCII
+func testLongUnicodePrompt(t *testing.T, chatter llm.Chatter) {
+	var b strings.Builder
+	for range 200 {
+		b.WriteString("[Verse] Ночной город, 夜の街, café \"quoted\" \\ backslash\n")
+	}
+	b.WriteString("\n" + pongUserPrompt)
+	if reply := chat(t, chatter, pongSystemPrompt, b.String()); strings.TrimSpace(reply) == "" {
+		t.Fatal("empty reply to a long prompt")
+	}
+}
+
+// testCanceledContext: a canceled run stops its call instead of waiting for the reply
+func testCanceledContext(t *testing.T, chatter llm.Chatter) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := chatter.Chat(ctx, pongSystemPrompt, pongUserPrompt)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Chat with a canceled context returned no error")
+		}
+	case <-time.After(callTimeout):
+		t.Fatal("Chat with a canceled context did not return")
+	}
+}
+
+// testConcurrent: workflows call the provider from their own goroutines
+func testConcurrent(t *testing.T, chatter llm.Chatter) {
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+			defer cancel()
+			if reply, err := chatter.Chat(ctx, pongSystemPrompt, pongUserPrompt); err != nil || strings.TrimSpace(reply) == "" {
+				t.Errorf("Chat = %q, %v", reply, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func chat(t *testing.T, chatter llm.Chatter, systemPrompt, userPrompt string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	reply, err := chatter.Chat(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	return reply
+}
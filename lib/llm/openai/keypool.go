@@ -0,0 +1,126 @@
+package openai
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultKeyCooldown is how long a rate-limited key is skipped when the response doesn't say
+const defaultKeyCooldown = 20 * time.Second
+
+// KeyPool hands out API keys round-robin, skipping keys that hit their rate limit
+// Keys can be replaced at any time (SetKeys) without interrupting requests in flight.
+type KeyPool struct {
+	mu   sync.Mutex
+	keys []*poolKey
+	next int
+}
+
+type poolKey struct {
+	key          string
+	limitedUntil time.Time // skipped until then
+}
+
+// NewKeyPool creates a pool of the given keys; empty keys are ignored
+func NewKeyPool(keys ...string) *KeyPool {
+	p := &KeyPool{}
+	p.SetKeys(keys)
+	return p
+}
+
+// SetKeys replaces the keys of the pool; keys kept keep their rate-limit state
+func (p *KeyPool) SetKeys(keys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := make(map[string]*poolKey, len(p.keys))
+	for _, k := range p.keys {
+		previous[k.key] = k
+	}
+	p.keys = nil
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if k, ok := previous[key]; ok {
+			p.keys = append(p.keys, k)
+			delete(previous, key)
+			continue
+		}
+		p.keys = append(p.keys, &poolKey{key: key})
+	}
+	p.next = 0
+}
+
+// Len returns the number of keys in the pool
+func (p *KeyPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// pick returns the next key that is not rate limited, or the one available soonest when
+// all are; "" for an empty pool
+func (p *KeyPool) pick(now time.Time) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) == 0 {
+		return ""
+	}
+
+	var soonest *poolKey
+	for range p.keys {
+		k := p.keys[p.next%len(p.keys)]
+		p.next = (p.next + 1) % len(p.keys)
+		if !now.Before(k.limitedUntil) {
+			return k.key
+		}
+		if soonest == nil || k.limitedUntil.Before(soonest.limitedUntil) {
+			soonest = k
+		}
+	}
+	return soonest.key
+}
+
+// observe records the rate-limit state a response reports for key
+// A 429 (or an exhausted request budget) skips the key until the limit resets.
+func (p *KeyPool) observe(key string, resp *http.Response, now time.Time) {
+	var cooldown time.Duration
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		cooldown = retryAfter(resp.Header)
+	case resp.Header.Get("x-ratelimit-remaining-requests") == "0":
+		cooldown = resetAfter(resp.Header.Get("x-ratelimit-reset-requests"))
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.key == key {
+			k.limitedUntil = now.Add(cooldown)
+		}
+	}
+}
+
+// retryAfter returns how long to wait after a 429, from Retry-After or the reset headers
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if d := resetAfter(header.Get("x-ratelimit-reset-requests")); d != defaultKeyCooldown {
+		return d
+	}
+	return resetAfter(header.Get("x-ratelimit-reset-tokens"))
+}
+
+// resetAfter parses an x-ratelimit-reset-* value such as "6m0s" or "20ms"
+func resetAfter(value string) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return defaultKeyCooldown
+}
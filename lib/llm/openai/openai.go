@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Client handles OpenAI API communication
 type Client struct {
-	apiKey     string
+	keys       *KeyPool
 	model      string
 	baseURL    string
 	httpClient *http.Client
@@ -20,8 +22,13 @@ type Client struct {
 
 // NewClient creates a new OpenAI client
 func NewClient(apiKey, model string) *Client {
+	return NewPooledClient(NewKeyPool(apiKey), model)
+}
+
+// NewPooledClient creates an OpenAI client that spreads requests over a pool of keys
+func NewPooledClient(keys *KeyPool, model string) *Client {
 	return &Client{
-		apiKey:  apiKey,
+		keys:    keys,
 		model:   model,
 		baseURL: "https://api.openai.com/v1",
 		httpClient: &http.Client{
@@ -30,6 +37,12 @@ func NewClient(apiKey, model string) *Client {
 	}
 }
 
+// WithBaseURL points the client at an OpenAI-compatible API, e.g. "http://localhost:8000/v1"
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	return c
+}
+
 // Message represents a chat message
 type Message struct {
 	Role    string `json:"role"`
@@ -70,17 +83,36 @@ type ChatResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// Usage reports the tokens consumed by a chat completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // Chat sends a chat completion request and returns the response
 func (c *Client) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	content, _, err := c.ChatWithUsage(ctx, systemPrompt, userPrompt)
+	return content, err
+}
+
+// ChatWithUsage sends a chat completion request and returns the response with its token usage
+func (c *Client) ChatWithUsage(ctx context.Context, systemPrompt, userPrompt string) (string, Usage, error) {
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: userPrompt},
 	}
-	return c.ChatWithMessages(ctx, messages)
+	return c.complete(ctx, messages)
 }
 
 // ChatWithMessages sends a chat completion request with custom messages
 func (c *Client) ChatWithMessages(ctx context.Context, messages []Message) (string, error) {
+	content, _, err := c.complete(ctx, messages)
+	return content, err
+}
+
+// complete performs the chat completion request
+func (c *Client) complete(ctx context.Context, messages []Message) (string, Usage, error) {
 	reqBody := ChatRequest{
 		Model:       c.model,
 		Messages:    messages,
@@ -88,42 +120,148 @@ func (c *Client) ChatWithMessages(ctx context.Context, messages []Message) (stri
 		MaxTokens:   4096,
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	body, err := c.post(ctx, "/chat/completions", reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", Usage{}, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if chatResp.Error != nil {
+		return "", Usage{}, fmt.Errorf("API error: %s", chatResp.Error.Message)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	if len(chatResp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices in response")
+	}
+
+	usage := Usage{
+		PromptTokens:     chatResp.Usage.PromptTokens,
+		CompletionTokens: chatResp.Usage.CompletionTokens,
+		TotalTokens:      chatResp.Usage.TotalTokens,
+	}
+	return chatResp.Choices[0].Message.Content, usage, nil
+}
+
+// ModerationRequest represents the OpenAI moderation request
+type ModerationRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+// ModerationResult is the moderation verdict for one input
+type ModerationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+// FlaggedCategories returns the names of the categories that were flagged
+func (r ModerationResult) FlaggedCategories() []string {
+	var flagged []string
+	for category, hit := range r.Categories {
+		if hit {
+			flagged = append(flagged, category)
+		}
+	}
+	sort.Strings(flagged)
+	return flagged
+}
+
+// ModerationResponse represents the OpenAI moderation response
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+	Error   *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// Moderate classifies text with the moderation endpoint and returns one result per input
+func (c *Client) Moderate(ctx context.Context, model string, inputs ...string) ([]ModerationResult, error) {
+	body, err := c.post(ctx, "/moderations", ModerationRequest{Model: model, Input: inputs})
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close() //nolint:errcheck
 
-	body, err := io.ReadAll(resp.Body)
+	var modResp ModerationResponse
+	if err := json.Unmarshal(body, &modResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if modResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s", modResp.Error.Message)
+	}
+
+	if len(modResp.Results) != len(inputs) {
+		return nil, fmt.Errorf("expected %d moderation results, got %d", len(inputs), len(modResp.Results))
+	}
+
+	return modResp.Results, nil
+}
+
+// post sends a JSON request with a key from the pool and returns the response body
+// A rate-limited key is retried with the next one, until every key was tried once.
+func (c *Client) post(ctx context.Context, endpoint string, reqBody any) ([]byte, error) {
+	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	attempts := max(c.keys.Len(), 1)
+	for attempt := 1; ; attempt++ {
+		key := c.keys.pick(time.Now())
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+endpoint, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		c.keys.observe(key, resp, time.Now())
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < attempts {
+			continue
+		}
+		return body, nil
 	}
+}
 
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s", chatResp.Error.Message)
+// Ping checks that the API is reachable, accepts the key and knows the configured model
+func (c *Client) Ping(ctx context.Context) error {
+	key := c.keys.pick(time.Now())
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models/"+c.model, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Authorization", "Bearer "+key)
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
 
-	return chatResp.Choices[0].Message.Content, nil
+	c.keys.observe(key, resp, time.Now())
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenAI API returned %s for model %s", resp.Status, c.model)
+	}
+	return nil
 }
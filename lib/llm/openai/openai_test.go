@@ -0,0 +1,52 @@
+package openai_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"workflower/lib/llm"
+	"workflower/lib/llm/llmtest"
+	"workflower/lib/llm/openai"
+)
+
+const apiKey = "sk-test"
+
+// fakeAPI answers chat completions like the OpenAI API, replying with the word the last message
+// asks for ("... the word: pong"); requests without apiKey are refused with 401
+func fakeAPI(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+			return
+		}
+		var req openai.ChatRequest
+		if r.URL.Path != "/chat/completions" || json.NewDecoder(r.Body).Decode(&req) != nil || len(req.Messages) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid request"}}`))
+			return
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		word := strings.TrimSpace(prompt[strings.LastIndex(prompt, ":")+1:])
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   req.Model,
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": word}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": len(prompt) / 4, "completion_tokens": 1, "total_tokens": len(prompt)/4 + 1},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestClient(t *testing.T) {
+	llmtest.Run(t, func(t *testing.T) llm.Chatter {
+		return openai.NewClient(apiKey, "gpt-4o-mini").WithBaseURL(fakeAPI(t))
+	})
+	llmtest.RunFailures(t, func(t *testing.T) llm.Chatter {
+		return openai.NewClient("sk-invalid", "gpt-4o-mini").WithBaseURL(fakeAPI(t))
+	})
+}
@@ -0,0 +1,168 @@
+// Package locale formats dates, numbers and durations the way a locale writes them, for the
+// pages, Telegram messages and exports (sheets, reports, digests)
+package locale
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default is the locale used when none is configured: English with ISO 8601 dates
+const Default = "en"
+
+// Locale holds the conventions of a locale
+type Locale struct {
+	Name           string // BCP 47 tag, e.g. "de-DE"
+	DateLayout     string // dates of exports and digests
+	DateTimeLayout string // timestamps of exports
+	DisplayLayout  string // timestamps of pages and messages, with the zone abbreviation
+	Decimal        string // decimal separator
+	Group          string // thousands separator
+	ListSeparator  rune   // field separator of CSV files, ';' where ',' is the decimal separator
+}
+
+var locales = []Locale{
+	{Default, "2006-01-02", "2006-01-02 15:04", "Jan 02, 2006 15:04 MST", ".", ",", ','},
+	{"en-US", "01/02/2006", "01/02/2006 3:04 PM", "Jan 2, 2006 3:04 PM MST", ".", ",", ','},
+	{"en-GB", "02/01/2006", "02/01/2006 15:04", "2 Jan 2006 15:04 MST", ".", ",", ','},
+	{"de-DE", "02.01.2006", "02.01.2006 15:04", "02.01.2006 15:04 MST", ",", ".", ';'},
+	{"es-ES", "02/01/2006", "02/01/2006 15:04", "02/01/2006 15:04 MST", ",", ".", ';'},
+	{"fr-FR", "02/01/2006", "02/01/2006 15:04", "02/01/2006 15:04 MST", ",", " ", ';'},
+	{"it-IT", "02/01/2006", "02/01/2006 15:04", "02/01/2006 15:04 MST", ",", ".", ';'},
+	{"nl-NL", "02-01-2006", "02-01-2006 15:04", "02-01-2006 15:04 MST", ",", ".", ';'},
+	{"pt-BR", "02/01/2006", "02/01/2006 15:04", "02/01/2006 15:04 MST", ",", ".", ';'},
+	{"ru-RU", "02.01.2006", "02.01.2006 15:04", "02.01.2006 15:04 MST", ",", " ", ';'},
+	{"ja-JP", "2006/01/02", "2006/01/02 15:04", "2006/01/02 15:04 MST", ".", ",", ','},
+}
+
+// Lookup returns the locale with the given tag, case-insensitive and with '_' or '-'; a bare
+// language ("de") selects its first locale
+func Lookup(name string) (Locale, error) {
+	tag := strings.ReplaceAll(strings.TrimSpace(name), "_", "-")
+	if tag == "" {
+		tag = Default
+	}
+	for _, l := range locales {
+		if strings.EqualFold(l.Name, tag) {
+			return l, nil
+		}
+	}
+	for _, l := range locales {
+		if language, _, _ := strings.Cut(l.Name, "-"); strings.EqualFold(language, tag) {
+			return l, nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unknown locale %q (use one of %s)", name, strings.Join(Names(), ", "))
+}
+
+// MustLookup returns the default locale for an unknown tag
+func MustLookup(name string) Locale {
+	l, err := Lookup(name)
+	if err != nil {
+		l, _ = Lookup(Default)
+	}
+	return l
+}
+
+// Names returns the tags of the supported locales
+func Names() []string {
+	names := make([]string, 0, len(locales))
+	for _, l := range locales {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// Date renders the date of t in loc (t's own zone when nil), "" for the zero time
+func (l Locale) Date(t time.Time, loc *time.Location) string {
+	return format(t, loc, l.DateLayout)
+}
+
+// DateTime renders t in loc for exports, without the zone
+func (l Locale) DateTime(t time.Time, loc *time.Location) string {
+	return format(t, loc, l.DateTimeLayout)
+}
+
+// Display renders t in loc for people reading a page or message, with the zone abbreviation
+func (l Locale) Display(t time.Time, loc *time.Location) string {
+	return format(t, loc, l.DisplayLayout)
+}
+
+func format(t time.Time, loc *time.Location, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(layout)
+}
+
+// Number renders v rounded to decimals places, with the decimal and thousands separators
+func (l Locale) Number(v float64, decimals int) string {
+	digits := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(digits, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(digits, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Int renders n with the thousands separator
+func (l Locale) Int(n int) string {
+	return l.Number(float64(n), 0)
+}
+
+// Percent renders a ratio (0.25) as a percentage with one decimal place ("25.0%")
+func (l Locale) Percent(ratio float64) string {
+	return l.Number(ratio*100, 1) + "%"
+}
+
+// Duration renders d briefly, e.g. "850ms", "12.4s", "3m05s" or "2h05m"
+func (l Locale) Duration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return l.Number(d.Seconds(), 1) + "s"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// CSVWriter returns a CSV writer separating fields with the locale's list separator, so that
+// spreadsheets opened in the locale split the columns
+func (l Locale) CSVWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.Comma = l.ListSeparator
+	return writer
+}
+
+// SniffCSVSeparator returns the field separator of CSV data written by any locale: the first
+// ',' or ';' of its header line
+func SniffCSVSeparator(data []byte) rune {
+	header, _, _ := strings.Cut(string(data), "\n")
+	if i := strings.IndexAny(header, ",;"); i >= 0 {
+		return rune(header[i])
+	}
+	return ','
+}
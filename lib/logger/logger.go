@@ -8,12 +8,13 @@ import (
 var Log *slog.Logger
 
 // Init initializes the global logger with structured logging
-// Outputs to stdout, which systemd captures and forwards to journalctl
+// Outputs to stdout, which systemd captures and forwards to journalctl; records logged with a
+// context carry its request ID (see WithRequestID)
 func Init() {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	})
-	Log = slog.New(handler)
+	Log = slog.New(contextHandler{handler})
 	slog.SetDefault(Log)
 }
 
@@ -22,6 +23,6 @@ func InitWithLevel(level slog.Level) {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: level,
 	})
-	Log = slog.New(handler)
+	Log = slog.New(contextHandler{handler})
 	slog.SetDefault(Log)
 }
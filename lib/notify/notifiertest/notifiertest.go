@@ -0,0 +1,161 @@
+// Package notifiertest is the conformance suite of notify.Notifier implementations. The factory
+// returns the notifier, usually pointed at a fake of its service, and an Inbox reading what that
+// fake received:
+//
+//	func TestNotifier(t *testing.T) {
+//		notifiertest.Run(t, func(t *testing.T) (notify.Notifier, notifiertest.Inbox) {
+//			fake := newFakeSlack(t)
+//			return slack.New(fake.URL, "token"), fake.Messages
+//		})
+//	}
+package notifiertest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"workflower/lib/notify"
+)
+
+// Inbox returns the text of the messages delivered to a chat so far, oldest first
+// Notifiers that convert the HTML markup deliver its text content: tags removed, entities decoded.
+type Inbox func(chatID string) []string
+
+// Factory returns a notifier and the inbox of its deliveries; it is called once per subtest
+type Factory func(t *testing.T) (notify.Notifier, Inbox)
+
+// sendTimeout bounds every send of the suite
+const sendTimeout = 30 * time.Second
+
+// Run checks the behavior the engine relies on, each in its own subtest
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+	tests := []struct {
+		name string
+		run  func(t *testing.T, n notify.Notifier, inbox Inbox)
+	}{
+		{"Deliver", testDeliver},
+		{"Markup", testMarkup},
+		{"Order", testOrder},
+		{"SeparateChats", testSeparateChats},
+		{"EmptyChat", testEmptyChat},
+		{"CanceledContext", testCanceledContext},
+		{"Concurrent", testConcurrent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, inbox := factory(t)
+			test.run(t, n, inbox)
+		})
+	}
+}
+
+// testDeliver: a message reaches its chat, multi-line and non-ASCII text intact
+func testDeliver(t *testing.T, n notify.Notifier, inbox Inbox) {
+	message := "🎵 Song ready\nЛирика: «Ночной город»"
+	send(t, n, "100", message)
+	expectInbox(t, inbox, "100", message)
+}
+
+// testMarkup: the HTML markup of engine messages is accepted, links and escapes included
+func testMarkup(t *testing.T, n notify.Notifier, inbox Inbox) {
+	send(t, n, "100", `<b>#12</b> awaits review: <a href="https://example.com/review/1?a=1&amp;b=2">open</a> &lt;draft&gt; <code>x &amp; y</code>`)
+	got := inbox("100")
+	if len(got) != 1 {
+		t.Fatalf("inbox has %d messages, want 1", len(got))
+	}
+	for _, part := range []string{"#12", "awaits review", "open", "draft", "x", "y"} {
+		if !strings.Contains(got[0], part) {
+			t.Errorf("delivered %q lacks %q", got[0], part)
+		}
+	}
+}
+
+// testOrder: sequential sends arrive in order
+func testOrder(t *testing.T, n notify.Notifier, inbox Inbox) {
+	for i := 1; i <= 3; i++ {
+		send(t, n, "100", fmt.Sprintf("message %d", i))
+	}
+	expectInbox(t, inbox, "100", "message 1", "message 2", "message 3")
+}
+
+// testSeparateChats: a message only reaches the chat it was sent to
+func testSeparateChats(t *testing.T, n notify.Notifier, inbox Inbox) {
+	send(t, n, "100", "to the team")
+	send(t, n, "-200", "to the escalation group")
+	expectInbox(t, inbox, "100", "to the team")
+	expectInbox(t, inbox, "-200", "to the escalation group")
+}
+
+// testEmptyChat: an unconfigured (empty) chat is skipped without an error
+func testEmptyChat(t *testing.T, n notify.Notifier, inbox Inbox) {
+	send(t, n, "", "nobody listens")
+	if got := inbox(""); len(got) != 0 {
+		t.Fatalf("message to an empty chat was delivered: %q", got)
+	}
+}
+
+// testCanceledContext: a canceled context fails the send instead of delivering or hanging
+func testCanceledContext(t *testing.T, n notify.Notifier, inbox Inbox) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() { done <- n.SendToChat(ctx, "100", "too late") }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("SendToChat with a canceled context returned no error")
+		}
+	case <-time.After(sendTimeout):
+		t.Fatal("SendToChat with a canceled context did not return")
+	}
+	if got := inbox("100"); len(got) != 0 {
+		t.Fatalf("canceled message was delivered: %q", got)
+	}
+}
+
+// testConcurrent: review notifications and alerts are sent from several goroutines
+func testConcurrent(t *testing.T, n notify.Notifier, inbox Inbox) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := n.SendToChat(ctx, "100", fmt.Sprintf("parallel %d", i)); err != nil {
+				t.Errorf("SendToChat: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := inbox("100"); len(got) != 8 {
+		t.Fatalf("inbox has %d messages, want 8", len(got))
+	}
+}
+
+func send(t *testing.T, n notify.Notifier, chatID, message string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := n.SendToChat(ctx, chatID, message); err != nil {
+		t.Fatalf("SendToChat(%q): %v", chatID, err)
+	}
+}
+
+func expectInbox(t *testing.T, inbox Inbox, chatID string, want ...string) {
+	t.Helper()
+	got := inbox(chatID)
+	if len(got) != len(want) {
+		t.Fatalf("chat %q received %q, want %q", chatID, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("chat %q message %d = %q, want %q", chatID, i+1, got[i], want[i])
+		}
+	}
+}
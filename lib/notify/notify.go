@@ -0,0 +1,19 @@
+// Package notify defines what the engine needs of a chat notifier; telegram.Notifier implements
+// it, and notifiertest checks other implementations
+package notify
+
+import (
+	"context"
+
+	"workflower/lib/telegram"
+)
+
+// Notifier delivers messages to chats
+// Messages are Telegram-flavored HTML (<b>, <i>, <a href>, <code> and escaped text); notifiers
+// of other services convert them. An empty chat ID means "not configured" and is skipped
+// without an error, as the engine sends to optional chats unconditionally.
+type Notifier interface {
+	SendToChat(ctx context.Context, chatID, message string) error
+}
+
+var _ Notifier = (*telegram.Notifier)(nil)
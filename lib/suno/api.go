@@ -0,0 +1,95 @@
+package suno
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API is what the workflow engine needs from Suno, whichever way it is reached (see Transport)
+type API interface {
+	CustomGenerate(ctx context.Context, req *CustomGenerateRequest) ([]AudioInfo, error)
+	ExtendAudio(ctx context.Context, req *ExtendAudioRequest) ([]AudioInfo, error)
+	GenerateStems(ctx context.Context, req *GenerateStemsRequest) (*AudioInfo, error)
+	GenerateLyrics(ctx context.Context, req *GenerateLyricsRequest) (*LyricsResponse, error)
+	Concat(ctx context.Context, req *ConcatRequest) (*AudioInfo, error)
+	Get(ctx context.Context, ids string, page int) ([]AudioInfo, error)
+	GetClip(ctx context.Context, id string) (*AudioInfo, error)
+	GetQuota(ctx context.Context) (*QuotaInfo, error)
+	CreatePersona(ctx context.Context, req *CreatePersonaRequest) (*Persona, error)
+	ListPersonas(ctx context.Context, page int) (*PersonaList, error)
+}
+
+var _ API = (*Client)(nil)
+
+// Transport selects how a Client reaches Suno; both speak the suno-api schema
+type Transport string
+
+const (
+	// TransportProxy is a self-hosted suno-api server holding the Suno session cookie; requests
+	// carry no credentials
+	TransportProxy Transport = "proxy"
+	// TransportHosted is a hosted suno-api-compatible endpoint authenticated with a bearer token
+	TransportHosted Transport = "hosted"
+)
+
+// ParseTransport returns the transport with the given name, TransportProxy for ""
+func ParseTransport(name string) (Transport, error) {
+	switch t := Transport(strings.ToLower(strings.TrimSpace(name))); t {
+	case "":
+		return TransportProxy, nil
+	case TransportProxy, TransportHosted:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown Suno transport %q (proxy or hosted)", name)
+	}
+}
+
+// Options configure a Client created with New
+type Options struct {
+	BaseURL       string
+	Transport     Transport     // TransportProxy when empty
+	Token         string        // bearer token of TransportHosted
+	CallbackURL   string        // see SetCallbackURL
+	NotFoundGrace time.Duration // see SetNotFoundGrace; 0 fails at the first miss
+	Resilience    *Resilience   // see SetResilience; nil sends every request once
+	RateLimit     *RateLimit    // see SetRateLimit; nil sends requests as they come
+}
+
+// New creates a Suno client for the given transport
+func New(opts Options) (*Client, error) {
+	c := NewClient(strings.TrimRight(opts.BaseURL, "/"))
+	switch opts.Transport {
+	case "", TransportProxy:
+	case TransportHosted:
+		if opts.Token == "" {
+			return nil, fmt.Errorf("the %s Suno transport needs a token", TransportHosted)
+		}
+		c.httpClient.Transport = &bearerTransport{token: opts.Token, next: c.httpClient.Transport}
+	default:
+		return nil, fmt.Errorf("unknown Suno transport %q", opts.Transport)
+	}
+	c.SetCallbackURL(opts.CallbackURL)
+	c.SetNotFoundGrace(opts.NotFoundGrace)
+	if opts.Resilience != nil {
+		c.SetResilience(*opts.Resilience)
+	}
+	if opts.RateLimit != nil {
+		c.SetRateLimit(*opts.RateLimit)
+	}
+	return c, nil
+}
+
+// bearerTransport authenticates the requests of TransportHosted
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
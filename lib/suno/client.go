@@ -4,17 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"workflower/lib/logger"
 )
 
+// DefaultNotFoundGrace is how long after submission a clip missing from Get is still
+// considered pending: suno-api sometimes lists a new clip only a few seconds after returning it
+const DefaultNotFoundGrace = 30 * time.Second
+
+// DefaultModel is the model suno-api generates with when a request names none
+const DefaultModel = "chirp-v3-5"
+
+// Models lists the models suno-api accepts in the Model field of generation requests; newer
+// ones can be passed as well
+var Models = []string{"chirp-v3-0", "chirp-v3-5", "chirp-v4"}
+
+// ErrClipNotFound is returned when a clip is still missing from Get after the not-found grace period
+var ErrClipNotFound = errors.New("clip not found")
+
 // Client handles Suno API communication via the third-party suno-api server
 // This wraps the unofficial suno-api (https://github.com/gcui-art/suno-api)
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL       string
+	httpClient    *http.Client
+	callbackURL   string
+	notFoundGrace time.Duration
 }
 
 // NewClient creates a new Suno API client
@@ -23,44 +43,62 @@ func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 300 * time.Second, // Suno generation can take a while
+			Timeout:   DefaultTimeout,
+			Transport: logger.Transport(nil),
 		},
+		notFoundGrace: DefaultNotFoundGrace,
 	}
 }
 
+// SetCallbackURL makes generation, extend and stems requests ask suno-api to POST the finished
+// clips to url; requests that set their own CallbackURL keep it. Empty disables callbacks.
+func (c *Client) SetCallbackURL(url string) {
+	c.callbackURL = url
+}
+
+// SetNotFoundGrace sets how long WaitForCompletion treats a clip missing from Get as pending
+// (DefaultNotFoundGrace unless set); 0 fails at the first miss
+func (c *Client) SetNotFoundGrace(grace time.Duration) {
+	c.notFoundGrace = grace
+}
+
 // GenerateRequest represents a simple song generation request using a prompt
 type GenerateRequest struct {
 	Prompt           string `json:"prompt"`
 	MakeInstrumental bool   `json:"make_instrumental"`
-	Model            string `json:"model,omitempty"` // Default: "chirp-v3-5", also supports "chirp-v3-0"
+	Model            string `json:"model,omitempty"` // see Models, DefaultModel when empty
 	WaitAudio        bool   `json:"wait_audio"`
 }
 
 // CustomGenerateRequest represents a custom song generation request with full control
 type CustomGenerateRequest struct {
-	Prompt           string `json:"prompt"`            // Lyrics or detailed prompt
-	Tags             string `json:"tags"`              // Music style/genre
+	Prompt           string `json:"prompt"`                  // Lyrics or detailed prompt
+	Tags             string `json:"tags"`                    // Music style/genre
 	NegativeTags     string `json:"negative_tags,omitempty"` // Negative music genre
 	Title            string `json:"title"`
 	MakeInstrumental bool   `json:"make_instrumental,omitempty"`
-	Model            string `json:"model,omitempty"` // Default: "chirp-v3-5"
+	Model            string `json:"model,omitempty"`      // see Models, DefaultModel when empty
+	PersonaID        string `json:"persona_id,omitempty"` // Sing with the voice and style of a persona of the account (see CreatePersona)
 	WaitAudio        bool   `json:"wait_audio,omitempty"`
+	CallbackURL      string `json:"callback_url,omitempty"` // Notified when the clips are ready (deployments with callback support)
 }
 
 // ExtendAudioRequest represents a request to extend audio length
 type ExtendAudioRequest struct {
-	AudioID    string `json:"audio_id"`
-	Prompt     string `json:"prompt,omitempty"`      // Additional lyrics
-	ContinueAt string `json:"continue_at,omitempty"` // Extend from mm:ss (e.g., "00:30")
-	Title      string `json:"title,omitempty"`
-	Tags       string `json:"tags,omitempty"`
+	AudioID      string `json:"audio_id"`
+	Prompt       string `json:"prompt,omitempty"`      // Additional lyrics
+	ContinueAt   string `json:"continue_at,omitempty"` // Extend from mm:ss (e.g., "00:30")
+	Title        string `json:"title,omitempty"`
+	Tags         string `json:"tags,omitempty"`
 	NegativeTags string `json:"negative_tags,omitempty"`
-	Model      string `json:"model,omitempty"`
+	Model        string `json:"model,omitempty"`
+	CallbackURL  string `json:"callback_url,omitempty"`
 }
 
 // GenerateStemsRequest represents a request to generate stem tracks
 type GenerateStemsRequest struct {
-	AudioID string `json:"audio_id"`
+	AudioID     string `json:"audio_id"`
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 // GenerateLyricsRequest represents a request to generate lyrics
@@ -109,17 +147,17 @@ type PersonaClip struct {
 
 // Persona represents persona information
 type Persona struct {
-	ID             string        `json:"id"`
-	Name           string        `json:"name"`
-	Description    string        `json:"description"`
-	ImageS3ID      string        `json:"image_s3_id"`
-	RootClipID     string        `json:"root_clip_id"`
-	Clip           any           `json:"clip"`
-	PersonaClips   []PersonaClip `json:"persona_clips"`
-	IsSunoPersona  bool          `json:"is_suno_persona"`
-	IsPublic       bool          `json:"is_public"`
-	UpvoteCount    int           `json:"upvote_count"`
-	ClipCount      int           `json:"clip_count"`
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	ImageS3ID     string        `json:"image_s3_id"`
+	RootClipID    string        `json:"root_clip_id"`
+	Clip          any           `json:"clip"`
+	PersonaClips  []PersonaClip `json:"persona_clips"`
+	IsSunoPersona bool          `json:"is_suno_persona"`
+	IsPublic      bool          `json:"is_public"`
+	UpvoteCount   int           `json:"upvote_count"`
+	ClipCount     int           `json:"clip_count"`
 }
 
 // PersonaResponse represents the response from get persona endpoint
@@ -130,12 +168,46 @@ type PersonaResponse struct {
 	IsFollowing  bool    `json:"is_following"`
 }
 
+// CreatePersonaRequest represents a request to create a persona from a completed clip
+type CreatePersonaRequest struct {
+	ClipID      string `json:"root_clip_id"` // The clip whose voice and style the persona keeps
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsPublic    bool   `json:"is_public,omitempty"`
+}
+
+// PersonaList represents a page of the personas of the account
+type PersonaList struct {
+	Personas     []Persona `json:"personas"`
+	TotalResults int       `json:"total_results"`
+	CurrentPage  int       `json:"current_page"`
+}
+
 // QuotaInfo represents the account quota information
 type QuotaInfo struct {
-	CreditsLeft   int    `json:"credits_left"`
-	Period        string `json:"period"`
-	MonthlyLimit  int    `json:"monthly_limit"`
-	MonthlyUsage  int    `json:"monthly_usage"`
+	CreditsLeft  int    `json:"credits_left"`
+	Period       string `json:"period"`
+	MonthlyLimit int    `json:"monthly_limit"`
+	MonthlyUsage int    `json:"monthly_usage"`
+}
+
+// APIError is an error response of the suno-api server
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// IsAuth reports whether the error means the Suno session (cookie) is no longer valid
+func (e *APIError) IsAuth() bool {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return true
+	}
+	body := strings.ToLower(e.Body)
+	return strings.Contains(body, "unauthorized") || strings.Contains(body, "cookie") || strings.Contains(body, "session expired")
 }
 
 // Generate submits a simple song generation request using a text prompt
@@ -149,16 +221,25 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) ([]AudioInf
 // 2 audio files will be generated for each request, consuming 10 credits total.
 // Returns a slice of AudioInfo (typically 2 variations)
 func (c *Client) CustomGenerate(ctx context.Context, req *CustomGenerateRequest) ([]AudioInfo, error) {
+	if req.CallbackURL == "" {
+		req.CallbackURL = c.callbackURL
+	}
 	return c.doPost(ctx, "/api/custom_generate", req)
 }
 
 // ExtendAudio extends the length of an existing audio clip
 func (c *Client) ExtendAudio(ctx context.Context, req *ExtendAudioRequest) ([]AudioInfo, error) {
+	if req.CallbackURL == "" {
+		req.CallbackURL = c.callbackURL
+	}
 	return c.doPost(ctx, "/api/extend_audio", req)
 }
 
 // GenerateStems generates stem tracks (separate audio and music tracks)
 func (c *Client) GenerateStems(ctx context.Context, req *GenerateStemsRequest) (*AudioInfo, error) {
+	if req.CallbackURL == "" {
+		req.CallbackURL = c.callbackURL
+	}
 	var result AudioInfo
 	err := c.doPostSingle(ctx, "/api/generate_stems", req, &result)
 	return &result, err
@@ -183,11 +264,11 @@ func (c *Client) Concat(ctx context.Context, req *ConcatRequest) (*AudioInfo, er
 // Optionally specify page number for pagination (default: 0 means no pagination)
 func (c *Client) Get(ctx context.Context, ids string, page int) ([]AudioInfo, error) {
 	url := c.baseURL + "/api/get"
-	
+
 	if ids != "" {
 		url += "?ids=" + ids
 	}
-	
+
 	if page > 0 {
 		if ids != "" {
 			url += fmt.Sprintf("&page=%d", page)
@@ -213,7 +294,7 @@ func (c *Client) Get(ctx context.Context, ids string, page int) ([]AudioInfo, er
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result []AudioInfo
@@ -245,7 +326,7 @@ func (c *Client) GetClip(ctx context.Context, id string) (*AudioInfo, error) {
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result AudioInfo
@@ -277,7 +358,7 @@ func (c *Client) GetAlignedLyrics(ctx context.Context, songID string) (*AudioInf
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result AudioInfo
@@ -312,7 +393,7 @@ func (c *Client) GetPersona(ctx context.Context, id string, page int) (*PersonaR
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result PersonaResponse
@@ -323,9 +404,23 @@ func (c *Client) GetPersona(ctx context.Context, id string, page int) (*PersonaR
 	return &result, nil
 }
 
-// GetQuota retrieves the current account quota information
-func (c *Client) GetQuota(ctx context.Context) (*QuotaInfo, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/get_limit", nil)
+// CreatePersona creates a persona of the account from a completed clip
+// Pass its ID as the PersonaID of CustomGenerateRequest to generate with it.
+func (c *Client) CreatePersona(ctx context.Context, req *CreatePersonaRequest) (*Persona, error) {
+	var result Persona
+	err := c.doPostSingle(ctx, "/api/create_persona", req, &result)
+	return &result, err
+}
+
+// ListPersonas retrieves the personas created by the account, newest first
+// Pages start at 1; 0 returns the first page.
+func (c *Client) ListPersonas(ctx context.Context, page int) (*PersonaList, error) {
+	url := c.baseURL + "/api/personas"
+	if page > 0 {
+		url += fmt.Sprintf("?page=%d", page)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -342,46 +437,45 @@ func (c *Client) GetQuota(ctx context.Context) (*QuotaInfo, error) {
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
-	var quotaInfo QuotaInfo
-	if err := json.Unmarshal(respBody, &quotaInfo); err != nil {
+	var result PersonaList
+	if err := json.Unmarshal(respBody, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &quotaInfo, nil
+	return &result, nil
 }
 
-// WaitForCompletion polls the API until the audio with the given ID is ready
-// It checks every pollInterval until the status is "streaming" or "complete"
-// Returns an error if the context is cancelled or if max retries are exceeded
-func (c *Client) WaitForCompletion(ctx context.Context, id string, pollInterval time.Duration, maxRetries int) (*AudioInfo, error) {
-	for i := 0; i < maxRetries; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
+// GetQuota retrieves the current account quota information
+func (c *Client) GetQuota(ctx context.Context) (*QuotaInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/get_limit", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		responses, err := c.Get(ctx, id, 0)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get audio info: %w", err)
-		}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
 
-		if len(responses) == 0 {
-			return nil, fmt.Errorf("no audio found with ID: %s", id)
-		}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 
-		audio := &responses[0]
-		if audio.Status == "streaming" || audio.Status == "complete" {
-			return audio, nil
-		}
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
 
-		time.Sleep(pollInterval)
+	var quotaInfo QuotaInfo
+	if err := json.Unmarshal(respBody, &quotaInfo); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return nil, fmt.Errorf("max retries exceeded waiting for audio completion")
+	return &quotaInfo, nil
 }
 
 // doPost is a helper method for POST requests that return an array of AudioInfo
@@ -410,7 +504,7 @@ func (c *Client) doPost(ctx context.Context, endpoint string, reqBody any) ([]Au
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result []AudioInfo
@@ -447,7 +541,7 @@ func (c *Client) doPostSingle(ctx context.Context, endpoint string, reqBody any,
 	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	if err := json.Unmarshal(respBody, result); err != nil {
@@ -456,4 +550,3 @@ func (c *Client) doPostSingle(ctx context.Context, endpoint string, reqBody any,
 
 	return nil
 }
-
@@ -0,0 +1,176 @@
+package suno
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/lib/logger"
+)
+
+// downloadAttempts is how often a download is tried, each attempt resuming where the last stopped
+const downloadAttempts = 3
+
+// ErrLinkExpired is returned by DownloadFile when the CDN no longer serves a URL (403, 404 or 410);
+// a fresh URL can be read from the clip with GetClip
+var ErrLinkExpired = errors.New("media link expired")
+
+// downloadClient fetches CDN files; downloads are bounded by their context only, as a video may
+// take longer than any API request
+var downloadClient = &http.Client{Transport: logger.Transport(nil)}
+
+// Download is a file written by DownloadFile
+type Download struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`            // hex digest of the whole file
+	Resumed bool   `json:"resumed,omitempty"` // continued from a partial file of an earlier attempt
+}
+
+// DownloadAudio downloads the audio of a clip to path (see DownloadFile)
+func (c *Client) DownloadAudio(ctx context.Context, clip *AudioInfo, path string) (*Download, error) {
+	if clip.AudioURL == "" {
+		return nil, fmt.Errorf("clip %s has no audio yet", clip.ID)
+	}
+	return DownloadFile(ctx, clip.AudioURL, path)
+}
+
+// DownloadVideo downloads the video of a clip to path (see DownloadFile)
+func (c *Client) DownloadVideo(ctx context.Context, clip *AudioInfo, path string) (*Download, error) {
+	if clip.VideoURL == "" {
+		return nil, fmt.Errorf("clip %s has no video yet", clip.ID)
+	}
+	return DownloadFile(ctx, clip.VideoURL, path)
+}
+
+// DownloadFile streams url to path and writes its SHA-256 to path.sha256 (sha256sum format)
+// The file is written to path.part first and renamed when complete, so path is never partial.
+// An interrupted download resumes from path.part with a Range request, also in a later call;
+// servers that ignore the range start it over. An existing path with a matching .sha256 is
+// not downloaded again.
+func DownloadFile(ctx context.Context, url, path string) (*Download, error) {
+	if d, ok := verified(path); ok {
+		return d, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	part := path + ".part"
+	resumed := false
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		var more bool
+		more, err = fetchPart(ctx, url, part)
+		resumed = resumed || more
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || errors.Is(err, ErrLinkExpired) || attempt == downloadAttempts {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	d, err := checksum(part)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(part, path); err != nil {
+		return nil, err
+	}
+	d.Path, d.Resumed = path, resumed
+	line := fmt.Sprintf("%s  %s\n", d.SHA256, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(line), 0o644); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// fetchPart appends the rest of url to the partial file part; resumed reports whether it
+// continued from bytes already there
+func fetchPart(ctx context.Context, url, part string) (resumed bool, err error) {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+		resumed = true
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		return true, nil // the partial file is complete
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return false, fmt.Errorf("%w: CDN returned %d", ErrLinkExpired, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("CDN returned %d", resp.StatusCode)
+	default:
+		flags |= os.O_TRUNC // no range support: start over
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return resumed, err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return resumed, fmt.Errorf("download interrupted: %w", err)
+	}
+	return resumed, f.Close()
+}
+
+// verified returns an existing download whose checksum file matches it
+func verified(path string) (*Download, bool) {
+	sum, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return nil, false
+	}
+	d, err := checksum(path)
+	if err != nil || !strings.HasPrefix(string(sum), d.SHA256+" ") {
+		return nil, false
+	}
+	d.Path = path
+	return d, true
+}
+
+// checksum hashes a file
+func checksum(path string) (*Download, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &Download{Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
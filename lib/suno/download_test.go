@@ -0,0 +1,226 @@
+package suno_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"workflower/lib/suno"
+)
+
+// cdn serves one file like the Suno CDN, with range support unless noRanges is set, and records
+// the Range header of every request
+type cdn struct {
+	content  []byte
+	noRanges bool
+	status   int // answered instead of the file when not 0
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (c *cdn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.ranges = append(c.ranges, r.Header.Get("Range"))
+	c.mu.Unlock()
+	switch {
+	case c.status != 0:
+		w.WriteHeader(c.status)
+	case c.noRanges:
+		_, _ = w.Write(c.content)
+	default:
+		http.ServeContent(w, r, "song.mp3", time.Time{}, bytes.NewReader(c.content))
+	}
+}
+
+func (c *cdn) requests() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.ranges...)
+}
+
+func newCDN(t *testing.T, c *cdn) string {
+	t.Helper()
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/song.mp3"
+}
+
+func song() []byte {
+	return bytes.Repeat([]byte("la la la "), 4096)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkDownload verifies the downloaded file, its .sha256 file and that no partial file is left
+func checkDownload(t *testing.T, d *suno.Download, path string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(want))
+	}
+	if d.Path != path || d.Size != int64(len(want)) || d.SHA256 != sha256Hex(want) {
+		t.Errorf("Download = %+v, want path %s, size %d, sha256 %s", d, path, len(want), sha256Hex(want))
+	}
+	sum, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := sha256Hex(want) + "  " + filepath.Base(path) + "\n"; string(sum) != line {
+		t.Errorf("checksum file = %q, want %q", sum, line)
+	}
+	if _, err := os.Stat(path + ".part"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := song()
+	server := &cdn{content: content}
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+
+	d, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+	if d.Resumed {
+		t.Error("a fresh download was reported as resumed")
+	}
+	if got := server.requests(); len(got) != 1 || got[0] != "" {
+		t.Errorf("requests = %q, want one without a range", got)
+	}
+}
+
+func TestDownloadFileResumesPartialFile(t *testing.T) {
+	content := song()
+	server := &cdn{content: content}
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	half := len(content) / 2
+	if err := os.WriteFile(path+".part", content[:half], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+	if !d.Resumed {
+		t.Error("Resumed = false, want true")
+	}
+	if got, want := server.requests(), []string{"bytes=" + strconv.Itoa(half) + "-"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadFileCompletePartialFile(t *testing.T) {
+	content := song()
+	server := &cdn{content: content}
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path+".part", content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The CDN answers 416 for a range starting at the end of the file
+	d, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+}
+
+func TestDownloadFileWithoutRangeSupportStartsOver(t *testing.T) {
+	content := song()
+	server := &cdn{content: content, noRanges: true}
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path+".part", []byte("stale bytes of an older attempt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+	if d.Resumed {
+		t.Error("a download the server started over was reported as resumed")
+	}
+}
+
+func TestDownloadFileSkipsVerifiedFile(t *testing.T) {
+	content := song()
+	server := &cdn{content: content}
+	url := newCDN(t, server)
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if _, err := suno.DownloadFile(context.Background(), url, path); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := suno.DownloadFile(context.Background(), url, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+	if got := server.requests(); len(got) != 1 {
+		t.Errorf("%d requests, want the verified file not to be downloaded again", len(got))
+	}
+}
+
+func TestDownloadFileChecksumMismatch(t *testing.T) {
+	content := song()
+	server := &cdn{content: content}
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	// A file that does not match its checksum (e.g. damaged on disk) is downloaded again
+	corrupt := append([]byte("XX"), content[2:]...)
+	if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	line := sha256Hex(content) + "  audio.mp3\n"
+	if err := os.WriteFile(path+".sha256", []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, d, path, content)
+	if got := server.requests(); len(got) != 1 {
+		t.Errorf("%d requests, want the mismatching file downloaded again", len(got))
+	}
+}
+
+func TestDownloadFileExpiredLink(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusNotFound, http.StatusGone} {
+		server := &cdn{status: status}
+		path := filepath.Join(t.TempDir(), "audio.mp3")
+
+		_, err := suno.DownloadFile(context.Background(), newCDN(t, server), path)
+		if !errors.Is(err, suno.ErrLinkExpired) {
+			t.Errorf("status %d: error = %v, want ErrLinkExpired", status, err)
+		}
+		if got := server.requests(); len(got) != 1 {
+			t.Errorf("status %d: %d requests, want an expired link not to be retried", status, len(got))
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("status %d: file written for an expired link", status)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package suno_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"workflower/lib/suno"
+	"workflower/lib/suno/sunotest"
 )
 
 // ExampleClient_Generate demonstrates simple music generation using a prompt
@@ -91,6 +93,27 @@ func ExampleClient_GenerateLyrics() {
 	fmt.Printf("Lyrics:\n%s\n", lyrics.Text)
 }
 
+// ExampleClient_Wait demonstrates waiting for a clip with growing poll intervals and progress reports
+func ExampleClient_Wait() {
+	client := suno.NewClient("http://localhost:3000")
+	ctx := context.Background()
+
+	audio, err := client.Wait(ctx, "your-audio-id", suno.WaitOptions{
+		PollInterval:    2 * time.Second,
+		MaxPollInterval: 30 * time.Second,
+		Timeout:         10 * time.Minute,
+		OnProgress: func(p suno.WaitProgress) {
+			fmt.Printf("Poll %d after %s: %s\n", p.Polls, p.Elapsed.Round(time.Second), p.Status)
+		},
+	})
+	var waitErr *suno.WaitError
+	if errors.As(err, &waitErr) {
+		log.Fatalf("Gave up in status %q: %v", waitErr.LastStatus, err)
+	}
+
+	fmt.Printf("Audio ready: %s\n", audio.AudioURL)
+}
+
 // ExampleClient_ExtendAudio demonstrates extending an existing audio clip
 func ExampleClient_ExtendAudio() {
 	client := suno.NewClient("http://localhost:3000")
@@ -323,3 +346,26 @@ func Example_completeWorkflow() {
 	finalQuota, _ := client.GetQuota(ctx)
 	fmt.Printf("Remaining credits: %d\n", finalQuota.CreditsLeft)
 }
+
+// ExamplePool fails over to the second account once the first one runs out of credits
+func ExamplePool() {
+	main, backup := sunotest.New(), sunotest.New()
+	main.SetCredits(10)
+	pool := suno.NewPool([]suno.PoolAccount{{Name: "main", API: main}, {Name: "backup", API: backup}}, suno.SelectLeastCredits, 10)
+	ctx := context.Background()
+	if _, err := pool.GetQuota(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := pool.CustomGenerate(ctx, &suno.CustomGenerateRequest{Title: "Night Drive"}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, account := range pool.Accounts() {
+		fmt.Println(account.Name, account.Submissions, account.CreditsLeft, account.Exhausted)
+	}
+	// Output:
+	// main 1 0 true
+	// backup 1 490 false
+}
@@ -0,0 +1,512 @@
+package suno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Selection is how a Pool picks the account of a new generation
+type Selection string
+
+const (
+	// SelectRoundRobin spreads generations over the accounts in turn
+	SelectRoundRobin Selection = "round_robin"
+	// SelectLeastCredits spends the account with the fewest credits left first, so that accounts
+	// run out one at a time and the others keep their credits in reserve
+	SelectLeastCredits Selection = "least_credits"
+)
+
+// ParseSelection returns the selection with the given name, SelectRoundRobin for ""
+func ParseSelection(name string) (Selection, error) {
+	switch s := Selection(strings.ToLower(strings.TrimSpace(name))); s {
+	case "":
+		return SelectRoundRobin, nil
+	case SelectRoundRobin, SelectLeastCredits:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown Suno account selection %q (%s or %s)", name, SelectRoundRobin, SelectLeastCredits)
+	}
+}
+
+// ErrNoAccount is returned by a Pool when every account is out of credits or unavailable
+var ErrNoAccount = errors.New("no Suno account with credits available")
+
+// maxPoolClips bounds the clip-to-account routes a Pool remembers; older clips are found by asking
+// every account
+const maxPoolClips = 10000
+
+// PoolAccount is one suno-api deployment (one Suno account) of a Pool
+type PoolAccount struct {
+	Name string
+	API  API
+}
+
+// AccountStatus is what a Pool knows about one of its accounts
+type AccountStatus struct {
+	Name         string
+	CreditsLeft  int // -1 until the quota was read
+	MonthlyLimit int
+	MonthlyUsage int
+	Exhausted    bool // out of credits; skipped for generations until its quota shows credits again
+	Submissions  int  // generations, extensions and stems requests sent through the pool
+	CheckedAt    time.Time
+	LastError    string // of the last quota check or request that failed, "" after a success
+}
+
+// Pool spreads Suno requests over several accounts: new generations go to the account the
+// Selection picks, with failover to the next one when an account is out of credits or its
+// suno-api is down, and requests about a clip go to the account that generated it.
+type Pool struct {
+	accounts  []PoolAccount
+	selection Selection
+	cost      int // credits of a generation, to tell whether an account can still pay for one
+
+	// OnChange is called with the status of an account whenever it changes, e.g. to persist it
+	OnChange func(AccountStatus)
+
+	mu     sync.Mutex
+	status []AccountStatus
+	next   int            // round-robin position
+	owners map[string]int // clip and persona ID -> account index
+	order  []string       // IDs in owners, oldest first
+}
+
+var _ API = (*Pool)(nil)
+
+// NewPool creates a pool of accounts; cost is the credits a generation needs
+func NewPool(accounts []PoolAccount, selection Selection, cost int) *Pool {
+	p := &Pool{accounts: accounts, selection: selection, cost: max(cost, 1), owners: make(map[string]int)}
+	for _, account := range accounts {
+		p.status = append(p.status, AccountStatus{Name: account.Name, CreditsLeft: -1})
+	}
+	return p
+}
+
+// Restore seeds the pool with account statuses recorded earlier, so that selection and
+// failover work before the quotas are read again; unknown names are ignored
+func (p *Pool) Restore(statuses []AccountStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, saved := range statuses {
+		if i := p.index(saved.Name); i >= 0 {
+			p.status[i] = saved
+		}
+	}
+}
+
+// Accounts returns the status of every account, in configuration order
+func (p *Pool) Accounts() []AccountStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.status)
+}
+
+func (p *Pool) index(name string) int {
+	return slices.IndexFunc(p.accounts, func(a PoolAccount) bool { return a.Name == name })
+}
+
+// candidates returns the accounts to try for a new generation, best first
+func (p *Pool) candidates() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var usable, exhausted []int
+	for k := range p.accounts {
+		i := (p.next + k) % len(p.accounts)
+		if p.status[i].Exhausted {
+			exhausted = append(exhausted, i)
+		} else {
+			usable = append(usable, i)
+		}
+	}
+	p.next = (p.next + 1) % len(p.accounts)
+	if p.selection == SelectLeastCredits {
+		// Unknown quotas (-1) sort last, so that accounts with known credits are drained first
+		slices.SortStableFunc(usable, func(a, b int) int {
+			ca, cb := p.status[a].CreditsLeft, p.status[b].CreditsLeft
+			switch {
+			case ca < 0 && cb < 0:
+				return 0
+			case ca < 0:
+				return 1
+			case cb < 0:
+				return -1
+			}
+			return ca - cb
+		})
+	}
+	// Exhausted accounts are tried last: a top-up may not have been seen yet
+	return append(usable, exhausted...)
+}
+
+// generate runs a new generation on the first account that accepts it
+func generate[T any](ctx context.Context, p *Pool, call func(API) (T, error), clips func(T) []AudioInfo) (T, error) {
+	var zero T
+	var errs []error
+	for _, i := range p.candidates() {
+		result, err := call(p.accounts[i].API)
+		if err == nil {
+			p.submitted(i, clips(result))
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return zero, err
+		}
+		p.failed(i, err)
+		if !failover(err) {
+			return zero, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.accounts[i].Name, err))
+	}
+	return zero, fmt.Errorf("%w: %w", ErrNoAccount, errors.Join(errs...))
+}
+
+// failover reports whether a failed generation should be tried on another account: it ran out of
+// credits, its session expired, it is rate limited or its suno-api is down (circuit open or not
+// accepting connections). A generation that failed with a 5xx or timed out may have run, and
+// trying it again elsewhere would charge it twice.
+func failover(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || outOfCredits(err) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.IsAuth() || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return unsent(err)
+}
+
+// outOfCredits reports whether suno-api refused a request for lack of credits
+func outOfCredits(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	body := strings.ToLower(apiErr.Body)
+	return apiErr.StatusCode == http.StatusPaymentRequired || strings.Contains(body, "insufficient credits") ||
+		strings.Contains(body, "not enough credits") || strings.Contains(body, "out of credits")
+}
+
+// submitted records a generation on an account and routes its clips there; requests without
+// clips (lyrics) are free
+func (p *Pool) submitted(i int, clips []AudioInfo) {
+	p.mu.Lock()
+	status := &p.status[i]
+	status.LastError = ""
+	if len(clips) > 0 {
+		status.Submissions++
+		if status.CreditsLeft >= 0 {
+			status.CreditsLeft = max(status.CreditsLeft-p.cost, 0)
+			status.Exhausted = status.CreditsLeft < p.cost
+		}
+	}
+	for _, clip := range clips {
+		p.route(clip.ID, i)
+	}
+	changed := *status
+	p.mu.Unlock()
+	p.notify(changed)
+}
+
+// failed records a failed request of an account; running out of credits marks it exhausted
+func (p *Pool) failed(i int, err error) {
+	p.mu.Lock()
+	status := &p.status[i]
+	status.LastError = err.Error()
+	if outOfCredits(err) {
+		status.Exhausted = true
+		status.CreditsLeft = 0
+	}
+	changed := *status
+	p.mu.Unlock()
+	p.notify(changed)
+}
+
+func (p *Pool) notify(status AccountStatus) {
+	if p.OnChange != nil {
+		p.OnChange(status)
+	}
+}
+
+// route remembers the account of a clip or persona; callers hold p.mu
+func (p *Pool) route(id string, i int) {
+	if id == "" {
+		return
+	}
+	if _, ok := p.owners[id]; !ok {
+		p.order = append(p.order, id)
+	}
+	p.owners[id] = i
+	if len(p.order) > maxPoolClips {
+		delete(p.owners, p.order[0])
+		p.order = p.order[1:]
+	}
+}
+
+// owner returns the account of a clip, or -1 when the pool has not seen it
+func (p *Pool) owner(id string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i, ok := p.owners[id]; ok {
+		return i
+	}
+	return -1
+}
+
+// locate returns the account of a clip, asking every account for clips the pool has not seen
+// (generated before a restart)
+func (p *Pool) locate(ctx context.Context, id string) (int, error) {
+	if i := p.owner(id); i >= 0 {
+		return i, nil
+	}
+	var lastErr error
+	for i, account := range p.accounts {
+		clips, err := account.API.Get(ctx, id, 0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return -1, err
+			}
+			lastErr = fmt.Errorf("%s: %w", account.Name, err)
+			continue
+		}
+		if len(clips) > 0 {
+			p.mu.Lock()
+			p.route(id, i)
+			p.mu.Unlock()
+			return i, nil
+		}
+	}
+	if lastErr != nil {
+		return -1, lastErr // the clip may be on the account that did not answer
+	}
+	return -1, fmt.Errorf("%w: %s is unknown to every Suno account", ErrClipNotFound, id)
+}
+
+// onClipAccount runs a request about an existing clip on the account that generated it
+func onClipAccount[T any](ctx context.Context, p *Pool, id string, call func(API) (T, error), clips func(T) []AudioInfo) (T, error) {
+	var zero T
+	i, err := p.locate(ctx, id)
+	if err != nil {
+		return zero, err
+	}
+	result, err := call(p.accounts[i].API)
+	if err != nil {
+		p.failed(i, err)
+		return zero, err
+	}
+	p.submitted(i, clips(result))
+	return result, nil
+}
+
+func clipList(clips []AudioInfo) []AudioInfo { return clips }
+func oneClip(clip *AudioInfo) []AudioInfo    { return []AudioInfo{*clip} }
+
+// CustomGenerate submits a song to the account the selection picks; a song with a persona goes to
+// the account the persona belongs to, without failover, as other accounts cannot use it
+func (p *Pool) CustomGenerate(ctx context.Context, req *CustomGenerateRequest) ([]AudioInfo, error) {
+	call := func(api API) ([]AudioInfo, error) { return api.CustomGenerate(ctx, req) }
+	if req.PersonaID == "" {
+		return generate(ctx, p, call, clipList)
+	}
+	i, err := p.locatePersona(ctx, req.PersonaID)
+	if err != nil {
+		return nil, err
+	}
+	clips, err := call(p.accounts[i].API)
+	if err != nil {
+		p.failed(i, err)
+		return nil, err
+	}
+	p.submitted(i, clips)
+	return clips, nil
+}
+
+// ExtendAudio extends a clip on the account that generated it
+func (p *Pool) ExtendAudio(ctx context.Context, req *ExtendAudioRequest) ([]AudioInfo, error) {
+	return onClipAccount(ctx, p, req.AudioID, func(api API) ([]AudioInfo, error) { return api.ExtendAudio(ctx, req) }, clipList)
+}
+
+// GenerateStems separates a clip on the account that generated it
+func (p *Pool) GenerateStems(ctx context.Context, req *GenerateStemsRequest) (*AudioInfo, error) {
+	return onClipAccount(ctx, p, req.AudioID, func(api API) (*AudioInfo, error) { return api.GenerateStems(ctx, req) }, oneClip)
+}
+
+// Concat joins a clip with its predecessors on the account that generated it
+func (p *Pool) Concat(ctx context.Context, req *ConcatRequest) (*AudioInfo, error) {
+	i, err := p.locate(ctx, req.ClipID)
+	if err != nil {
+		return nil, err
+	}
+	clip, err := p.accounts[i].API.Concat(ctx, req)
+	if err != nil {
+		p.failed(i, err)
+		return nil, err
+	}
+	p.mu.Lock()
+	p.route(clip.ID, i) // free, not counted as a submission
+	p.mu.Unlock()
+	return clip, nil
+}
+
+// GenerateLyrics drafts lyrics on the account the selection picks
+func (p *Pool) GenerateLyrics(ctx context.Context, req *GenerateLyricsRequest) (*LyricsResponse, error) {
+	return generate(ctx, p, func(api API) (*LyricsResponse, error) { return api.GenerateLyrics(ctx, req) },
+		func(*LyricsResponse) []AudioInfo { return nil })
+}
+
+// Get returns clips by ID from the accounts that generated them; without IDs it returns the given
+// page of the library of every account
+func (p *Pool) Get(ctx context.Context, ids string, page int) ([]AudioInfo, error) {
+	if ids == "" {
+		var result []AudioInfo
+		var errs []error
+		for _, account := range p.accounts {
+			clips, err := account.API.Get(ctx, "", page)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", account.Name, err))
+				continue
+			}
+			result = append(result, clips...)
+		}
+		if len(errs) == len(p.accounts) {
+			return nil, errors.Join(errs...)
+		}
+		return result, nil
+	}
+
+	byAccount := make(map[int][]string)
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		i, err := p.locate(ctx, id)
+		if errors.Is(err, ErrClipNotFound) {
+			continue // not listed yet, as Get does for a single account
+		}
+		if err != nil {
+			return nil, err
+		}
+		byAccount[i] = append(byAccount[i], id)
+	}
+	var result []AudioInfo
+	for i := range p.accounts {
+		if len(byAccount[i]) == 0 {
+			continue
+		}
+		clips, err := p.accounts[i].API.Get(ctx, strings.Join(byAccount[i], ","), page)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, clips...)
+	}
+	return result, nil
+}
+
+// GetClip returns a clip from the account that generated it
+func (p *Pool) GetClip(ctx context.Context, id string) (*AudioInfo, error) {
+	i, err := p.locate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.accounts[i].API.GetClip(ctx, id)
+}
+
+// CreatePersona creates a persona on the account that generated its clip; the persona is routed
+// to that account
+func (p *Pool) CreatePersona(ctx context.Context, req *CreatePersonaRequest) (*Persona, error) {
+	i, err := p.locate(ctx, req.ClipID)
+	if err != nil {
+		return nil, err
+	}
+	persona, err := p.accounts[i].API.CreatePersona(ctx, req)
+	if err != nil {
+		p.failed(i, err)
+		return nil, err
+	}
+	p.mu.Lock()
+	p.route(persona.ID, i)
+	p.mu.Unlock()
+	return persona, nil
+}
+
+// ListPersonas returns the given page of the personas of every account and routes them to their
+// account; accounts that fail are left out unless all of them fail
+func (p *Pool) ListPersonas(ctx context.Context, page int) (*PersonaList, error) {
+	result := &PersonaList{CurrentPage: max(page, 1)}
+	var errs []error
+	for i, account := range p.accounts {
+		list, err := account.API.ListPersonas(ctx, page)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.Name, err))
+			continue
+		}
+		p.mu.Lock()
+		for _, persona := range list.Personas {
+			p.route(persona.ID, i)
+		}
+		p.mu.Unlock()
+		result.Personas = append(result.Personas, list.Personas...)
+		result.TotalResults += list.TotalResults
+	}
+	if len(errs) == len(p.accounts) {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// locatePersona returns the account of a persona, listing the first page of personas of every
+// account for personas the pool has not seen
+func (p *Pool) locatePersona(ctx context.Context, id string) (int, error) {
+	if i := p.owner(id); i >= 0 {
+		return i, nil
+	}
+	if _, err := p.ListPersonas(ctx, 0); err != nil {
+		return -1, err
+	}
+	if i := p.owner(id); i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("persona %s is unknown to every Suno account", id)
+}
+
+// GetQuota reads the quota of every account and returns their sum; accounts that fail are left
+// out, and the error of the last one is returned only when all of them fail
+// An exhausted account whose quota shows credits again is used for generations again.
+func (p *Pool) GetQuota(ctx context.Context) (*QuotaInfo, error) {
+	total := &QuotaInfo{}
+	var lastErr error
+	answered := 0
+	for i, account := range p.accounts {
+		quota, err := account.API.GetQuota(ctx)
+		p.mu.Lock()
+		status := &p.status[i]
+		status.CheckedAt = time.Now()
+		if err != nil {
+			status.LastError = err.Error()
+			lastErr = fmt.Errorf("%s: %w", account.Name, err)
+		} else {
+			answered++
+			status.LastError = ""
+			status.CreditsLeft = quota.CreditsLeft
+			status.MonthlyLimit = quota.MonthlyLimit
+			status.MonthlyUsage = quota.MonthlyUsage
+			status.Exhausted = quota.CreditsLeft < p.cost
+			total.CreditsLeft += quota.CreditsLeft
+			total.MonthlyLimit += quota.MonthlyLimit
+			total.MonthlyUsage += quota.MonthlyUsage
+			total.Period = quota.Period
+		}
+		changed := *status
+		p.mu.Unlock()
+		p.notify(changed)
+	}
+	if answered == 0 {
+		return nil, lastErr
+	}
+	return total, nil
+}
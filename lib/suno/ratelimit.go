@@ -0,0 +1,68 @@
+package suno
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"workflower/lib/ratelimit"
+)
+
+// RateLimit configures the client-side rate limit of a Client (see SetRateLimit)
+type RateLimit struct {
+	PerMinute int // requests sent per minute on average, 0 or less disables the limit
+	Burst     int // requests sent at once after a quiet spell, 1 when 0
+}
+
+// SetRateLimit spaces out the requests of the client with a token bucket shared by all its
+// callers, so that batch submissions and poll loops stay under the limits of suno-api and Suno
+// A request over the limit waits for its turn, or until its context ends, instead of failing;
+// retries of SetResilience count as requests too. A zero RateLimit removes the limit.
+func (c *Client) SetRateLimit(r RateLimit) {
+	var limiter *ratelimit.Limiter
+	if r.PerMinute > 0 {
+		limiter = ratelimit.New(float64(r.PerMinute)/60, max(r.Burst, 1))
+	}
+	// The limit applies to every attempt, so it sits below the retries
+	if rt, ok := c.httpClient.Transport.(*resilientTransport); ok {
+		rt.next = withRateLimit(rt.next, limiter)
+		return
+	}
+	c.httpClient.Transport = withRateLimit(c.httpClient.Transport, limiter)
+}
+
+// withRateLimit puts next behind limiter, replacing an earlier limit; nil leaves it unlimited
+func withRateLimit(next http.RoundTripper, limiter *ratelimit.Limiter) http.RoundTripper {
+	if lt, ok := next.(*limitedTransport); ok {
+		next = lt.next
+	}
+	if limiter == nil {
+		return next
+	}
+	return &limitedTransport{next: next, limiter: limiter}
+}
+
+// limitedTransport holds requests back until the token bucket lets them through
+type limitedTransport struct {
+	next    http.RoundTripper
+	limiter *ratelimit.Limiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		ok, wait := t.limiter.Allow("")
+		if ok {
+			break
+		}
+		slog.DebugContext(req.Context(), "Waiting for the suno-api rate limit", "method", req.Method, "path", req.URL.Path, "wait", wait)
+		select {
+		case <-req.Context().Done():
+			if req.Body != nil {
+				req.Body.Close() //nolint:errcheck
+			}
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,255 @@
+package suno
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single HTTP attempt; Suno generation can take a while
+const DefaultTimeout = 300 * time.Second
+
+// maxRetryAfter is the longest Retry-After of a 429 that is waited out; a longer one is returned
+const maxRetryAfter = time.Minute
+
+// ErrCircuitOpen is returned without contacting suno-api while the circuit breaker is open
+var ErrCircuitOpen = errors.New("suno-api unavailable (circuit open)")
+
+// Resilience configures the retries and the circuit breaker of a Client (see SetResilience)
+type Resilience struct {
+	Timeout          time.Duration // per attempt, DefaultTimeout when 0
+	MaxAttempts      int           // attempts per request (see SetResilience); 1 or less disables retries
+	Backoff          time.Duration // wait before the first retry, doubled for each further one
+	BreakerThreshold int           // consecutive failed requests (network errors, 5xx) that open the circuit, 0 disables it
+	BreakerCooldown  time.Duration // how long an open circuit fails fast before one request probes suno-api again
+
+	// OnCircuitChange is called when the circuit opens (with the error that opened it) and when a
+	// probe closes it again (with nil)
+	OnCircuitChange func(open bool, err error)
+}
+
+// SetResilience retries failed requests and stops sending them while suno-api is down
+// GET requests are retried on network errors, 429 and 5xx. Other requests (submissions that
+// spend credits) are only retried when they provably never ran: on 429 and when no connection
+// could be made; after a timeout or a 5xx they may have generated clips and are not sent again.
+func (c *Client) SetResilience(r Resilience) {
+	next := c.httpClient.Transport
+	if rt, ok := next.(*resilientTransport); ok {
+		next = rt.next
+	}
+	if r.Timeout <= 0 {
+		r.Timeout = DefaultTimeout
+	}
+	// The timeout applies per attempt, not to the request with all its retries
+	c.httpClient.Timeout = 0
+	c.httpClient.Transport = &resilientTransport{
+		next:    next,
+		policy:  r,
+		breaker: &breaker{threshold: r.BreakerThreshold, cooldown: r.BreakerCooldown, onChange: r.OnCircuitChange},
+	}
+}
+
+// resilientTransport retries the attempts of a request and records their outcome in the breaker
+type resilientTransport struct {
+	next    http.RoundTripper
+	policy  Resilience
+	breaker *breaker
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(time.Now()); err != nil {
+		return nil, err
+	}
+
+	attempts := max(t.policy.MaxAttempts, 1)
+	if req.Body != nil && req.GetBody == nil {
+		attempts = 1 // the body cannot be sent again
+	}
+
+	backoff := t.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req)
+		if req.Context().Err() != nil {
+			t.breaker.abandon()
+			return resp, err
+		}
+		failed := err != nil || resp.StatusCode >= 500
+		if !retryable(req, resp, err) || attempt >= attempts {
+			t.breaker.record(time.Now(), failed, attemptError(resp, err))
+			return resp, err
+		}
+
+		wait := backoff
+		if err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				if after := retryAfter(resp.Header); after > maxRetryAfter {
+					t.breaker.record(time.Now(), false, nil)
+					return resp, nil
+				} else if after > 0 {
+					wait = after
+				}
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close() //nolint:errcheck
+		}
+		slog.WarnContext(req.Context(), "Retrying suno-api request", "method", req.Method, "path", req.URL.Path,
+			"attempt", attempt, "wait", wait, "error", attemptError(resp, err))
+
+		select {
+		case <-req.Context().Done():
+			t.breaker.abandon()
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				t.breaker.abandon()
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// attempt sends req once within the attempt timeout; the timeout covers reading the body
+func (t *resilientTransport) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable reports whether a failed attempt may be sent again: a GET only reads, any other
+// request only when it never ran (see SetResilience)
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch {
+	case err == nil && resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case err == nil && resp.StatusCode < 500:
+		return false
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		return true
+	default:
+		return unsent(err)
+	}
+}
+
+// unsent reports whether a request failed before it reached suno-api: the connection was refused
+// or could not be made
+func unsent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// attemptError describes a failed attempt for logs and the breaker
+func attemptError(resp *http.Response, err error) error {
+	if err != nil || resp == nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return &APIError{StatusCode: resp.StatusCode, Body: http.StatusText(resp.StatusCode)}
+	}
+	return nil
+}
+
+// retryAfter returns the Retry-After of a response in seconds, 0 without one
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// cancelBody releases the attempt context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// breaker opens after threshold consecutive failed requests and then fails fast for cooldown;
+// after that a single request probes suno-api, closing the circuit when it succeeds
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(open bool, err error)
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns ErrCircuitOpen when a request may not be sent
+func (b *breaker) allow(now time.Time) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if b.probing || now.Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of a request, failed meaning suno-api is unreachable or erroring
+func (b *breaker) record(now time.Time, failed bool, err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	wasOpen := b.open
+	b.probing = false
+	if failed {
+		b.failures++
+		if b.open || b.failures >= b.threshold {
+			b.open = true
+			b.openUntil = now.Add(b.cooldown)
+		}
+	} else {
+		b.failures = 0
+		b.open = false
+	}
+	isOpen := b.open
+	b.mu.Unlock()
+
+	if isOpen != wasOpen && b.onChange != nil {
+		if isOpen {
+			b.onChange(true, err)
+		} else {
+			b.onChange(false, nil)
+		}
+	}
+}
+
+// abandon forgets a request its caller cancelled, so an unfinished probe does not keep the circuit open
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
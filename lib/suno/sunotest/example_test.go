@@ -0,0 +1,53 @@
+package sunotest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"workflower/lib/suno"
+	"workflower/lib/suno/sunotest"
+)
+
+// ExampleMock follows a generated clip through the statuses Suno reports
+func ExampleMock() {
+	mock := sunotest.New()
+	ctx := context.Background()
+
+	clips, err := mock.CustomGenerate(ctx, &suno.CustomGenerateRequest{Title: "Night Drive", Tags: "synthwave"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	id := clips[0].ID
+	fmt.Println(id, clips[0].Status)
+
+	for range 3 {
+		clip, err := mock.GetClip(ctx, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %q\n", clip.Status, clip.AudioURL)
+	}
+
+	quota, _ := mock.GetQuota(ctx)
+	fmt.Println("credits left:", quota.CreditsLeft)
+	// Output:
+	// mock-clip-0001 submitted
+	// queue ""
+	// streaming "https://cdn.suno.test/stream/mock-clip-0001.mp3"
+	// complete "https://cdn.suno.test/mock-clip-0001.mp3"
+	// credits left: 490
+}
+
+// ExampleMock_FailNext makes the next request fail, e.g. to test how an expired session is handled
+func ExampleMock_FailNext() {
+	mock := sunotest.New()
+	mock.FailNext(&suno.APIError{StatusCode: 401, Body: "session expired"})
+
+	_, err := mock.CustomGenerate(context.Background(), &suno.CustomGenerateRequest{Title: "Night Drive"})
+	var apiErr *suno.APIError
+	fmt.Println(errors.As(err, &apiErr) && apiErr.IsAuth())
+	// Output:
+	// true
+}
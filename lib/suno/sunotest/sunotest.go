@@ -0,0 +1,382 @@
+// Package sunotest provides an in-memory suno.API for tests of code driving Suno, so that engine
+// and handler tests run without a suno-api server. Generated clips go through the statuses Suno
+// reports, one step every few polls:
+//
+//	mock := sunotest.New()
+//	engine.SetSunoAPI(mock)
+//	// ... approve a workflow; its clips are submitted, queued, streaming, then complete
+//	mock.FailNext(&suno.APIError{StatusCode: 401, Body: "session expired"})
+package sunotest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"workflower/lib/suno"
+)
+
+// Clip statuses in the order Mock reports them
+const (
+	StatusSubmitted = "submitted"
+	StatusQueued    = "queue"
+	StatusStreaming = "streaming"
+	StatusComplete  = "complete"
+	StatusError     = "error"
+)
+
+var progression = []string{StatusSubmitted, StatusQueued, StatusStreaming, StatusComplete}
+
+// Defaults of New
+const (
+	DefaultCredits      = 500
+	DefaultClipDuration = 180 // seconds
+	feedPageSize        = 20
+)
+
+// Mock is an in-memory suno.API; it is safe for concurrent use
+type Mock struct {
+	// PollsPerStatus is how many times Get or GetClip report a clip in a status before it moves on
+	// to the next one; 0 or 1 advances it on every poll
+	PollsPerStatus int
+	// CreditsPerGeneration is charged for every generation, extension and stems request
+	CreditsPerGeneration int
+	// MediaBaseURL prefixes the audio, video and image URLs of the clips
+	MediaBaseURL string
+
+	mu       sync.Mutex
+	clips    map[string]*clip
+	order    []string // clip IDs, oldest first
+	nextID   int
+	personas []*suno.Persona // oldest first
+	credits  int
+	fail     []error
+	calls    map[string]int
+}
+
+// clip is a generated clip and the polls it has seen in its current status
+type clip struct {
+	info  suno.AudioInfo
+	step  int // index in progression
+	polls int
+	held  bool // status set with SetStatus, no longer advanced by polls
+}
+
+var _ suno.API = (*Mock)(nil)
+
+// New returns a mock with DefaultCredits, advancing clips on every poll
+func New() *Mock {
+	return &Mock{
+		CreditsPerGeneration: 10,
+		MediaBaseURL:         "https://cdn.suno.test",
+		clips:                make(map[string]*clip),
+		credits:              DefaultCredits,
+		calls:                make(map[string]int),
+	}
+}
+
+// FailNext makes the next calls fail with the given errors, one call per error, whatever the method
+func (m *Mock) FailNext(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fail = append(m.fail, errs...)
+}
+
+// SetCredits sets the credits GetQuota reports; generations fail with 402 once they run out
+func (m *Mock) SetCredits(credits int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credits = credits
+}
+
+// SetStatus puts a clip in a status (StatusError included) and keeps it there
+func (m *Mock) SetStatus(id, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.clips[id]
+	if !ok {
+		return fmt.Errorf("sunotest: no clip %s", id)
+	}
+	c.held = true
+	if i := slices.Index(progression, status); i >= 0 {
+		c.step = i
+	}
+	c.info.Status = status
+	m.fillMedia(c)
+	return nil
+}
+
+// Complete finishes a clip at once
+func (m *Mock) Complete(id string) error {
+	return m.SetStatus(id, StatusComplete)
+}
+
+// Clips returns the clips generated so far, oldest first, in their current status
+func (m *Mock) Clips() []suno.AudioInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]suno.AudioInfo, 0, len(m.order))
+	for _, id := range m.order {
+		result = append(result, m.clips[id].info)
+	}
+	return result
+}
+
+// Calls returns how often a method was called, e.g. Calls("CustomGenerate")
+func (m *Mock) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[method]
+}
+
+// CustomGenerate submits two clips, as Suno does; a persona must exist and counts the clips
+func (m *Mock) CustomGenerate(ctx context.Context, req *suno.CustomGenerateRequest) ([]suno.AudioInfo, error) {
+	if req.PersonaID != "" {
+		m.mu.Lock()
+		known := m.persona(req.PersonaID) != nil
+		m.mu.Unlock()
+		if !known {
+			return nil, &suno.APIError{StatusCode: 404, Body: "persona not found: " + req.PersonaID}
+		}
+	}
+	clips, err := m.generate(ctx, "CustomGenerate", 2, suno.AudioInfo{Title: req.Title, Tags: req.Tags, Prompt: req.Prompt, Lyric: req.Prompt})
+	if err == nil && req.PersonaID != "" {
+		m.mu.Lock()
+		m.persona(req.PersonaID).ClipCount += len(clips)
+		m.mu.Unlock()
+	}
+	return clips, err
+}
+
+// ExtendAudio submits two extensions of an existing clip
+func (m *Mock) ExtendAudio(ctx context.Context, req *suno.ExtendAudioRequest) ([]suno.AudioInfo, error) {
+	if err := m.requireClip(req.AudioID); err != nil {
+		return nil, err
+	}
+	return m.generate(ctx, "ExtendAudio", 2, suno.AudioInfo{Title: req.Title, Tags: req.Tags, Prompt: req.Prompt, Type: "extend"})
+}
+
+// GenerateStems submits the stems of an existing clip
+func (m *Mock) GenerateStems(ctx context.Context, req *suno.GenerateStemsRequest) (*suno.AudioInfo, error) {
+	if err := m.requireClip(req.AudioID); err != nil {
+		return nil, err
+	}
+	clips, err := m.generate(ctx, "GenerateStems", 1, suno.AudioInfo{Type: "stems"})
+	if err != nil {
+		return nil, err
+	}
+	return &clips[0], nil
+}
+
+// Concat joins an extended clip with its predecessors; it is free, like on Suno
+func (m *Mock) Concat(ctx context.Context, req *suno.ConcatRequest) (*suno.AudioInfo, error) {
+	if err := m.requireClip(req.ClipID); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "Concat"); err != nil {
+		return nil, err
+	}
+	c := m.add(suno.AudioInfo{Type: "concat"})
+	return &c.info, nil
+}
+
+// GenerateLyrics answers at once with lyrics echoing the prompt
+func (m *Mock) GenerateLyrics(ctx context.Context, req *suno.GenerateLyricsRequest) (*suno.LyricsResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "GenerateLyrics"); err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf("[Verse]\n%s\n\n[Chorus]\nSing it loud, sing it clear\nThe song we wrote is finally here", strings.TrimSpace(req.Prompt))
+	return &suno.LyricsResponse{Text: text, Title: "Mock Song", Status: StatusComplete}, nil
+}
+
+// Get returns the clips with the given comma-separated IDs, each one poll further along, or
+// the newest clips page by page without IDs; unknown IDs are left out, as Suno does
+func (m *Mock) Get(ctx context.Context, ids string, page int) ([]suno.AudioInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	var result []suno.AudioInfo
+	if ids == "" {
+		for i := len(m.order) - 1 - max(page-1, 0)*feedPageSize; i >= 0 && len(result) < feedPageSize; i-- {
+			result = append(result, m.clips[m.order[i]].info)
+		}
+		return result, nil
+	}
+	for _, id := range strings.Split(ids, ",") {
+		if c, ok := m.clips[strings.TrimSpace(id)]; ok {
+			m.poll(c)
+			result = append(result, c.info)
+		}
+	}
+	return result, nil
+}
+
+// GetClip returns one clip, a poll further along
+func (m *Mock) GetClip(ctx context.Context, id string) (*suno.AudioInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "GetClip"); err != nil {
+		return nil, err
+	}
+	c, ok := m.clips[id]
+	if !ok {
+		return nil, &suno.APIError{StatusCode: 404, Body: "clip not found"}
+	}
+	m.poll(c)
+	info := c.info
+	return &info, nil
+}
+
+// GetQuota reports the credits left
+func (m *Mock) GetQuota(ctx context.Context) (*suno.QuotaInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "GetQuota"); err != nil {
+		return nil, err
+	}
+	return &suno.QuotaInfo{CreditsLeft: m.credits, Period: "month", MonthlyLimit: DefaultCredits, MonthlyUsage: max(DefaultCredits-m.credits, 0)}, nil
+}
+
+// CreatePersona creates a persona from a clip with audio (streaming or complete)
+func (m *Mock) CreatePersona(ctx context.Context, req *suno.CreatePersonaRequest) (*suno.Persona, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "CreatePersona"); err != nil {
+		return nil, err
+	}
+	c, ok := m.clips[req.ClipID]
+	if !ok {
+		return nil, &suno.APIError{StatusCode: 404, Body: "clip not found: " + req.ClipID}
+	}
+	if c.info.AudioURL == "" {
+		return nil, &suno.APIError{StatusCode: 400, Body: "clip has no audio yet: " + req.ClipID}
+	}
+	m.nextID++
+	persona := &suno.Persona{
+		ID:          fmt.Sprintf("mock-persona-%04d", m.nextID),
+		Name:        req.Name,
+		Description: req.Description,
+		RootClipID:  req.ClipID,
+		IsPublic:    req.IsPublic,
+	}
+	m.personas = append(m.personas, persona)
+	result := *persona
+	return &result, nil
+}
+
+// ListPersonas returns the personas created so far, newest first, page by page
+func (m *Mock) ListPersonas(ctx context.Context, page int) (*suno.PersonaList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, "ListPersonas"); err != nil {
+		return nil, err
+	}
+	list := &suno.PersonaList{TotalResults: len(m.personas), CurrentPage: max(page, 1)}
+	for i := len(m.personas) - 1 - max(page-1, 0)*feedPageSize; i >= 0 && len(list.Personas) < feedPageSize; i-- {
+		list.Personas = append(list.Personas, *m.personas[i])
+	}
+	return list, nil
+}
+
+// persona returns a persona by ID; callers hold m.mu
+func (m *Mock) persona(id string) *suno.Persona {
+	for _, persona := range m.personas {
+		if persona.ID == id {
+			return persona
+		}
+	}
+	return nil
+}
+
+// generate charges the credits of a request and submits n clips built from template
+func (m *Mock) generate(ctx context.Context, method string, n int, template suno.AudioInfo) ([]suno.AudioInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.begin(ctx, method); err != nil {
+		return nil, err
+	}
+	if m.credits < m.CreditsPerGeneration {
+		return nil, &suno.APIError{StatusCode: 402, Body: "insufficient credits"}
+	}
+	m.credits -= m.CreditsPerGeneration
+	result := make([]suno.AudioInfo, n)
+	for i := range result {
+		result[i] = m.add(template).info
+	}
+	return result, nil
+}
+
+// begin counts a call and returns the error it should fail with, if any
+func (m *Mock) begin(ctx context.Context, method string) error {
+	m.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(m.fail) > 0 {
+		err := m.fail[0]
+		m.fail = m.fail[1:]
+		return err
+	}
+	return nil
+}
+
+func (m *Mock) requireClip(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clips[id]; !ok {
+		return &suno.APIError{StatusCode: 404, Body: "clip not found: " + id}
+	}
+	return nil
+}
+
+// add records a new submitted clip
+func (m *Mock) add(template suno.AudioInfo) *clip {
+	m.nextID++
+	c := &clip{info: template}
+	c.info.ID = fmt.Sprintf("mock-clip-%04d", m.nextID)
+	c.info.Status = StatusSubmitted
+	c.info.ModelName = "chirp-mock"
+	c.info.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.clips[c.info.ID] = c
+	m.order = append(m.order, c.info.ID)
+	return c
+}
+
+// poll counts a poll of a clip and moves it to the next status once it has seen PollsPerStatus
+func (m *Mock) poll(c *clip) {
+	if c.held || c.step == len(progression)-1 {
+		return
+	}
+	c.polls++
+	if c.polls < max(m.PollsPerStatus, 1) {
+		return
+	}
+	c.polls = 0
+	c.step++
+	c.info.Status = progression[c.step]
+	m.fillMedia(c)
+}
+
+// fillMedia sets the media URLs a clip has in its status: a stream while streaming, the files once complete
+func (m *Mock) fillMedia(c *clip) {
+	base := strings.TrimRight(m.MediaBaseURL, "/")
+	switch c.info.Status {
+	case StatusStreaming:
+		c.info.AudioURL = base + "/stream/" + c.info.ID + ".mp3"
+		c.info.ImageURL = base + "/image_" + c.info.ID + ".jpeg"
+	case StatusComplete:
+		c.info.AudioURL = base + "/" + c.info.ID + ".mp3"
+		c.info.VideoURL = base + "/" + c.info.ID + ".mp4"
+		c.info.ImageURL = base + "/image_" + c.info.ID + ".jpeg"
+		c.info.Duration = DefaultClipDuration
+	}
+}
@@ -0,0 +1,123 @@
+package suno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultPollInterval is the first poll interval of Wait when none is given
+const DefaultPollInterval = 5 * time.Second
+
+// ErrMaxPolls is returned by Wait when the clip is not ready after WaitOptions.MaxPolls polls
+var ErrMaxPolls = errors.New("max polls exceeded")
+
+// WaitOptions configure Wait
+type WaitOptions struct {
+	PollInterval    time.Duration // first interval between polls, DefaultPollInterval when 0
+	MaxPollInterval time.Duration // intervals double up to this; 0 keeps PollInterval fixed
+	MaxPolls        int           // give up after this many polls, 0 for no limit
+	Timeout         time.Duration // overall deadline on top of ctx, 0 for none
+
+	// OnProgress is called after every poll with the latest status of the clip
+	OnProgress func(WaitProgress)
+}
+
+// WaitProgress reports one poll of Wait
+type WaitProgress struct {
+	Polls   int
+	Elapsed time.Duration
+	Status  string     // "" while Get does not list the clip yet
+	Audio   *AudioInfo // nil while Get does not list the clip yet
+}
+
+// WaitError is returned when Wait gives up; it keeps the status the clip was last seen in
+type WaitError struct {
+	ID         string
+	LastStatus string // "" when the clip was never listed
+	Polls      int
+	Err        error // ErrMaxPolls, ErrClipNotFound, the context error or the error of Get
+}
+
+func (e *WaitError) Error() string {
+	status := e.LastStatus
+	if status == "" {
+		status = "not listed"
+	}
+	return fmt.Sprintf("waiting for clip %s (last status %s after %d polls): %v", e.ID, status, e.Polls, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// Wait polls Get until the clip with the given ID is "streaming" or "complete" and returns it
+// Polling stops as soon as ctx is cancelled or the Timeout passes. A clip Get does not list yet
+// is polled like a pending one for the not-found grace period (see SetNotFoundGrace), then
+// ErrClipNotFound is returned. Errors are *WaitError values.
+func (c *Client) Wait(ctx context.Context, id string, opts WaitOptions) (*AudioInfo, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var progress WaitProgress
+	fail := func(err error) error {
+		return &WaitError{ID: id, LastStatus: progress.Status, Polls: progress.Polls, Err: err}
+	}
+	for {
+		responses, err := c.Get(ctx, id, 0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fail(ctx.Err())
+			}
+			return nil, fail(fmt.Errorf("failed to get audio info: %w", err))
+		}
+
+		progress.Polls++
+		progress.Elapsed = time.Since(start)
+		progress.Audio = nil
+		if len(responses) > 0 {
+			progress.Audio = &responses[0]
+			progress.Status = responses[0].Status
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+
+		switch {
+		case progress.Audio == nil && progress.Elapsed >= c.notFoundGrace:
+			return nil, fail(fmt.Errorf("%w: %s still missing %s after submission", ErrClipNotFound, id, c.notFoundGrace))
+		case progress.Audio != nil && (progress.Status == "streaming" || progress.Status == "complete"):
+			return progress.Audio, nil
+		case opts.MaxPolls > 0 && progress.Polls >= opts.MaxPolls:
+			return nil, fail(ErrMaxPolls)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fail(ctx.Err())
+		case <-ticker.C:
+		}
+		if next := min(interval*2, opts.MaxPollInterval); next > interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// WaitForCompletion polls the API every pollInterval until the audio with the given ID is
+// ready, at most maxRetries times (see Wait)
+func (c *Client) WaitForCompletion(ctx context.Context, id string, pollInterval time.Duration, maxRetries int) (*AudioInfo, error) {
+	return c.Wait(ctx, id, WaitOptions{PollInterval: pollInterval, MaxPolls: max(maxRetries, 1)})
+}
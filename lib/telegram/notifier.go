@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,7 +17,9 @@ import (
 type Notifier struct {
 	botToken   string
 	chatID     string
+	apiURL     string
 	httpClient *http.Client
+	dryRun     bool
 }
 
 // NewNotifier creates a new Telegram notifier
@@ -22,12 +27,25 @@ func NewNotifier(botToken, chatID string) *Notifier {
 	return &Notifier{
 		botToken: botToken,
 		chatID:   chatID,
+		apiURL:   "https://api.telegram.org",
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
+// WithAPIURL points the notifier at a Bot API server other than api.telegram.org, e.g. a local
+// one or a fake in tests
+func (n *Notifier) WithAPIURL(apiURL string) *Notifier {
+	n.apiURL = strings.TrimRight(apiURL, "/")
+	return n
+}
+
+// SetDryRun makes the notifier log messages instead of sending them (DRY_RUN)
+func (n *Notifier) SetDryRun(dryRun bool) {
+	n.dryRun = dryRun
+}
+
 // SendMessageRequest represents a Telegram sendMessage request
 type SendMessageRequest struct {
 	ChatID      string      `json:"chat_id"`
@@ -85,6 +103,9 @@ func (n *Notifier) SendWithLink(ctx context.Context, message, buttonText, button
 	})
 }
 
+// webhookUpdates are the update types the webhook is registered for
+var webhookUpdates = []string{"message", "edited_message"}
+
 type setWebhookRequest struct {
 	URL            string   `json:"url"`
 	SecretToken    string   `json:"secret_token,omitempty"`
@@ -109,7 +130,7 @@ func (n *Notifier) SetWebhook(ctx context.Context, webhookURL, secretToken strin
 	reqBody := setWebhookRequest{
 		URL:            webhookURL,
 		SecretToken:    secretToken,
-		AllowedUpdates: []string{"message", "edited_message"},
+		AllowedUpdates: webhookUpdates,
 	}
 
 	body, err := n.doRequest(ctx, "setWebhook", reqBody)
@@ -129,11 +150,93 @@ func (n *Notifier) SetWebhook(ctx context.Context, webhookURL, secretToken strin
 	return nil
 }
 
+// WebhookInfo is the current webhook registration of the bot as reported by Telegram
+type WebhookInfo struct {
+	URL                string   `json:"url"` // "" when no webhook is registered
+	PendingUpdateCount int      `json:"pending_update_count"`
+	LastErrorDate      int64    `json:"last_error_date,omitempty"` // unix time of the last delivery failure
+	LastErrorMessage   string   `json:"last_error_message,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates,omitempty"`
+}
+
+// GetWebhookInfo returns the webhook registered for the bot
+func (n *Notifier) GetWebhookInfo(ctx context.Context) (*WebhookInfo, error) {
+	body, err := n.doRequest(ctx, "getWebhookInfo", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var tgResp struct {
+		OK          bool        `json:"ok"`
+		Description string      `json:"description,omitempty"`
+		Result      WebhookInfo `json:"result"`
+	}
+	if err := json.Unmarshal(body, &tgResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if !tgResp.OK {
+		return nil, fmt.Errorf("telegram API error: %s", tgResp.Description)
+	}
+	return &tgResp.Result, nil
+}
+
+// EnsureWebhook registers webhookURL unless Telegram already delivers to it, and returns the
+// URL registered before and whether it was replaced
+// Telegram does not report the secret token, so a changed secret alone is not detected.
+func (n *Notifier) EnsureWebhook(ctx context.Context, webhookURL, secretToken string) (string, bool, error) {
+	if n.botToken == "" {
+		return "", false, nil
+	}
+	info, err := n.GetWebhookInfo(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if info.URL == webhookURL && (len(info.AllowedUpdates) == 0 || slices.Equal(info.AllowedUpdates, webhookUpdates)) {
+		return info.URL, false, nil
+	}
+	if err := n.SetWebhook(ctx, webhookURL, secretToken); err != nil {
+		return info.URL, false, err
+	}
+	return info.URL, true, nil
+}
+
+// LatestChat returns the chat of the most recent message sent to the bot, so that a chat can
+// be linked without looking up its ID; Telegram refuses this while a webhook is registered
+func (n *Notifier) LatestChat(ctx context.Context) (*Chat, error) {
+	body, err := n.doRequest(ctx, "getUpdates", map[string]any{"allowed_updates": []string{"message"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var tgResp struct {
+		OK          bool     `json:"ok"`
+		Description string   `json:"description,omitempty"`
+		Result      []Update `json:"result"`
+	}
+	if err := json.Unmarshal(body, &tgResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if !tgResp.OK {
+		return nil, fmt.Errorf("telegram API error: %s", tgResp.Description)
+	}
+
+	for i := len(tgResp.Result) - 1; i >= 0; i-- {
+		if msg := ExtractMessage(&tgResp.Result[i]); msg != nil {
+			return &msg.Chat, nil
+		}
+	}
+	return nil, fmt.Errorf("no messages yet: send a message to the bot first")
+}
+
 func (n *Notifier) sendMessage(ctx context.Context, reqBody SendMessageRequest) error {
 	if n.botToken == "" || reqBody.ChatID == "" {
 		// Silent skip if not configured
 		return nil
 	}
+	if n.dryRun {
+		slog.Info("Dry run: Telegram message not sent", "chat_id", reqBody.ChatID, "text", reqBody.Text)
+		return nil
+	}
 
 	body, err := n.doRequest(ctx, "sendMessage", reqBody)
 	if err != nil {
@@ -153,7 +256,7 @@ func (n *Notifier) sendMessage(ctx context.Context, reqBody SendMessageRequest)
 }
 
 func (n *Notifier) doRequest(ctx context.Context, endpoint string, payload interface{}) ([]byte, error) {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", n.botToken, endpoint)
+	url := fmt.Sprintf("%s/bot%s/%s", n.apiURL, n.botToken, endpoint)
 
 	jsonBody, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,66 @@
+package telegram_test
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"workflower/lib/notify"
+	"workflower/lib/notify/notifiertest"
+	"workflower/lib/telegram"
+)
+
+const botToken = "123:test"
+
+// tags matches the HTML tags Telegram removes from messages sent with parse_mode HTML
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// fakeBotAPI answers sendMessage like the Bot API and keeps the text content of the delivered
+// messages per chat
+type fakeBotAPI struct {
+	mu    sync.Mutex
+	chats map[string][]string
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bot"+botToken+"/sendMessage" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"ok":false,"description":"Not Found"}`))
+		return
+	}
+	var req telegram.SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChatID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+		return
+	}
+	text := req.Text
+	if req.ParseMode == "HTML" {
+		text = html.UnescapeString(tags.ReplaceAllString(text, ""))
+	}
+
+	f.mu.Lock()
+	f.chats[req.ChatID] = append(f.chats[req.ChatID], text)
+	id := len(f.chats[req.ChatID])
+	f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]int{"message_id": id}})
+}
+
+func (f *fakeBotAPI) inbox(chatID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.chats[chatID]...)
+}
+
+func TestNotifier(t *testing.T) {
+	notifiertest.Run(t, func(t *testing.T) (notify.Notifier, notifiertest.Inbox) {
+		fake := &fakeBotAPI{chats: make(map[string][]string)}
+		srv := httptest.NewServer(fake)
+		t.Cleanup(srv.Close)
+		return telegram.NewNotifier(botToken, "100").WithAPIURL(srv.URL), fake.inbox
+	})
+}
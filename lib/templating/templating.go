@@ -58,11 +58,16 @@ func ParseText(name, content string) (*texttemplate.Template, error) {
 // ParseHTMLTemplates parses multiple HTML template strings into a single template set
 // The first template is the main template, additional templates are parsed into it
 func ParseHTMLTemplates(name string, templates ...string) (*htmltemplate.Template, error) {
+	return ParseHTMLTemplatesWithFuncs(name, nil, templates...)
+}
+
+// ParseHTMLTemplatesWithFuncs works like ParseHTMLTemplates but registers funcs before parsing
+func ParseHTMLTemplatesWithFuncs(name string, funcs htmltemplate.FuncMap, templates ...string) (*htmltemplate.Template, error) {
 	if len(templates) == 0 {
 		return nil, fmt.Errorf("at least one template is required")
 	}
 
-	tmpl, err := htmltemplate.New(name).Parse(templates[0])
+	tmpl, err := htmltemplate.New(name).Funcs(funcs).Parse(templates[0])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse base layout template %s: %w", name, err)
 	}
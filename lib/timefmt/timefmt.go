@@ -0,0 +1,23 @@
+package timefmt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Embed the IANA time zone database so zones resolve on minimal VPS images
+	_ "time/tzdata"
+)
+
+// LoadLocation resolves an IANA zone name ("Europe/Berlin", "UTC", "Local")
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workflower/lib/logger"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the request body
+	SignatureHeader = "X-Workflower-Signature"
+	// EventHeader carries the event name of the payload
+	EventHeader = "X-Workflower-Event"
+	// DeliveryHeader carries the unique delivery ID
+	DeliveryHeader = "X-Workflower-Delivery"
+	// SchemaVersionHeader carries the schema version of the payload
+	SchemaVersionHeader = "X-Workflower-Schema-Version"
+	// TimestampHeader carries the Unix time a request was signed at, covered by its signature (see SignAt)
+	TimestampHeader = "X-Workflower-Timestamp"
+
+	signaturePrefix      = "sha256="
+	defaultMaxAttempts   = 5
+	defaultBackoff       = 2 * time.Second
+	maxResponseSnippet   = 512
+	defaultClientTimeout = 15 * time.Second
+)
+
+// Payload is the JSON envelope POSTed to every webhook URL
+type Payload struct {
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	ID            string    `json:"id"` // delivery ID, the same for every URL and retry
+	Event         string    `json:"event"`
+	Timestamp     time.Time `json:"timestamp"`
+	Data          any       `json:"data"`
+}
+
+// Delivery describes the outcome of posting one payload to one URL
+type Delivery struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Event       string    `json:"event"`
+	Attempts    int       `json:"attempts"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Dispatcher posts signed JSON payloads to a set of URLs with retries
+type Dispatcher struct {
+	urls        []string
+	secret      string
+	maxAttempts int
+	backoff     time.Duration
+	httpClient  *http.Client
+	onDelivery  func(Delivery)
+	version     int
+}
+
+// NewDispatcher creates a new webhook dispatcher
+// An empty secret disables request signing
+func NewDispatcher(urls []string, secret string) *Dispatcher {
+	return &Dispatcher{
+		urls:        urls,
+		secret:      secret,
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		httpClient: &http.Client{
+			Timeout:   defaultClientTimeout,
+			Transport: logger.Transport(nil),
+		},
+	}
+}
+
+// WithRetries sets the maximum number of attempts and the initial backoff between them
+func (d *Dispatcher) WithRetries(maxAttempts int, backoff time.Duration) *Dispatcher {
+	if maxAttempts > 0 {
+		d.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		d.backoff = backoff
+	}
+	return d
+}
+
+// WithSchemaVersion sets the schema version announced in payloads and SchemaVersionHeader
+func (d *Dispatcher) WithSchemaVersion(version int) *Dispatcher {
+	d.version = version
+	return d
+}
+
+// WithDeliveryHook sets a callback invoked after each delivery completes, e.g. for delivery logs
+func (d *Dispatcher) WithDeliveryHook(hook func(Delivery)) *Dispatcher {
+	d.onDelivery = hook
+	return d
+}
+
+// Enabled reports whether any webhook URL is configured
+func (d *Dispatcher) Enabled() bool {
+	return len(d.urls) > 0
+}
+
+// Dispatch delivers the event to every configured URL and blocks until all deliveries finish
+func (d *Dispatcher) Dispatch(ctx context.Context, deliveryID, event string, data any) error {
+	body, err := json.Marshal(Payload{
+		SchemaVersion: d.version,
+		ID:            deliveryID,
+		Event:         event,
+		Timestamp:     time.Now().UTC(),
+		Data:          data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	for _, url := range d.urls {
+		delivery := d.deliver(ctx, url, deliveryID, event, body)
+		if d.onDelivery != nil {
+			d.onDelivery(delivery)
+		}
+	}
+
+	return nil
+}
+
+// deliver posts the body to a single URL, retrying with exponential backoff
+func (d *Dispatcher) deliver(ctx context.Context, url, deliveryID, event string, body []byte) Delivery {
+	delivery := Delivery{
+		ID:        deliveryID,
+		URL:       url,
+		Event:     event,
+		CreatedAt: time.Now(),
+	}
+
+	backoff := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery.Attempts = attempt
+
+		statusCode, err := d.post(ctx, url, deliveryID, event, body)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+
+		if attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			delivery.Error = ctx.Err().Error()
+			delivery.CompletedAt = time.Now()
+			return delivery
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	delivery.CompletedAt = time.Now()
+	return delivery
+}
+
+func (d *Dispatcher) post(ctx context.Context, url, deliveryID, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	if d.version > 0 {
+		req.Header.Set(SchemaVersionHeader, strconv.Itoa(d.version))
+	}
+	if d.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(d.secret, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+		return resp.StatusCode, fmt.Errorf("webhook error (status %d): %s", resp.StatusCode, string(snippet))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for a body: "sha256=" + hex(HMAC-SHA256(secret, body))
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value against the body in constant time
+func Verify(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// SignAt returns the signature header value for a body sent with timestamp (Unix seconds, the
+// TimestampHeader value): the HMAC covers "<timestamp>.<body>", so a captured request cannot be
+// sent again under a fresh timestamp
+func SignAt(secret, timestamp string, body []byte) string {
+	return Sign(secret, append([]byte(timestamp+"."), body...))
+}
+
+// VerifyAt checks a signature made with SignAt and returns the time the request was signed at
+// It fails for a missing or malformed timestamp as well as for a wrong signature; the caller
+// decides how old a request may be.
+func VerifyAt(secret, timestamp string, body []byte, signature string) (time.Time, bool) {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !Verify(secret, append([]byte(timestamp+"."), body...), signature) {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
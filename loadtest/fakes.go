@@ -0,0 +1,160 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"workflower/lib/llm/openai"
+	"workflower/lib/suno"
+	"workflower/templates/prompts"
+)
+
+// fakeLyrics pass the lyrics checks, so every workflow can be approved as proposed
+const fakeLyrics = `[Verse 1]
+City lights are humming low
+Every window tells a story
+Footsteps echo in the snow
+Chasing down a fading glory
+
+[Chorus]
+Hold on, hold on to the night
+We are burning, burning bright
+Hold on, hold on to the light
+Till the morning makes it right
+
+[Verse 2]
+Radio is playing slow
+Songs we used to know by heart
+Streets are empty, neon glow
+Every ending is a start
+
+[Chorus]
+Hold on, hold on to the night
+We are burning, burning bright
+Hold on, hold on to the light
+Till the morning makes it right`
+
+// fakeUpstreams serves the OpenAI and suno-api endpoints the pipeline calls, answering every
+// request after latency; clips are complete on their first poll
+type fakeUpstreams struct {
+	server  *httptest.Server
+	prompts *prompts.PromptsList
+	latency time.Duration
+	clips   atomic.Int64
+	calls   atomic.Int64
+}
+
+func newFakeUpstreams(promptsList *prompts.PromptsList, latency time.Duration) *fakeUpstreams {
+	f := &fakeUpstreams{prompts: promptsList, latency: latency}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", f.chat)
+	mux.HandleFunc("POST /v1/moderations", f.moderations)
+	mux.HandleFunc("GET /v1/models/{model}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"id": r.PathValue("model")})
+	})
+	mux.HandleFunc("POST /api/custom_generate", f.generate)
+	mux.HandleFunc("POST /api/extend_audio", f.generate)
+	mux.HandleFunc("POST /api/concat", f.generateOne)
+	mux.HandleFunc("POST /api/generate_stems", f.generateOne)
+	mux.HandleFunc("GET /api/get", f.get)
+	mux.HandleFunc("GET /api/get_limit", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, suno.QuotaInfo{CreditsLeft: 1 << 20, Period: "month", MonthlyLimit: 1 << 20})
+	})
+	f.server = httptest.NewServer(f.delay(mux))
+	return f
+}
+
+// delay counts the request and holds it for the configured latency
+func (f *fakeUpstreams) delay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls.Add(1)
+		if f.latency > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(f.latency):
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chat answers by system prompt: JSON for the properties and persona steps, lyrics otherwise
+func (f *fakeUpstreams) chat(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		http.Error(w, `{"error": {"message": "invalid request"}}`, http.StatusBadRequest)
+		return
+	}
+	var content string
+	switch req.Messages[0].Content {
+	case f.prompts.SunoProperties:
+		content = `{"style": "synthwave, driving drums, warm pads", "vocal_type": "female vocals", "lyrics_mode": "custom", "weirdness": 0.4, "style_influence": "0.6"}`
+	case f.prompts.PersonaInspo:
+		content = `{"persona": "late night radio host", "inspo": "neon city drives"}`
+	case f.prompts.TranscriptSummary:
+		content = "A song about a night drive through the city"
+	default:
+		content = fakeLyrics
+	}
+
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += len(m.Content) / 4
+	}
+	completion := len(content) / 4
+	writeJSON(w, map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": content}}},
+		"usage":   map[string]int{"prompt_tokens": prompt, "completion_tokens": completion, "total_tokens": prompt + completion},
+	})
+}
+
+func (f *fakeUpstreams) moderations(w http.ResponseWriter, r *http.Request) {
+	var req openai.ModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": {"message": "invalid request"}}`, http.StatusBadRequest)
+		return
+	}
+	results := make([]openai.ModerationResult, len(req.Input))
+	writeJSON(w, openai.ModerationResponse{ID: "modr-loadtest", Results: results})
+}
+
+// generate answers a generation or extension with two submitted clips, like Suno
+func (f *fakeUpstreams) generate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, []suno.AudioInfo{f.newClip(), f.newClip()})
+}
+
+func (f *fakeUpstreams) generateOne(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, f.newClip())
+}
+
+func (f *fakeUpstreams) newClip() suno.AudioInfo {
+	return suno.AudioInfo{ID: fmt.Sprintf("loadtest-%d", f.clips.Add(1)), Status: "submitted"}
+}
+
+func (f *fakeUpstreams) get(w http.ResponseWriter, r *http.Request) {
+	var clips []suno.AudioInfo
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		clips = append(clips, suno.AudioInfo{
+			ID:       id,
+			Status:   "complete",
+			AudioURL: f.server.URL + "/audio/" + id + ".mp3",
+			Duration: 180,
+		})
+	}
+	writeJSON(w, clips)
+}
+
+func (f *fakeUpstreams) Close() {
+	f.server.Close()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,345 @@
+// Package loadtest drives complete workflows through the engine, from start over an automatic
+// approval to the finished song, and reports throughput, latency per stage and memory, so the
+// capacity of a host can be measured before a team relies on it
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"workflower/config"
+	"workflower/lib/suno"
+	"workflower/storage"
+	"workflower/templates/prompts"
+	"workflower/workflow"
+)
+
+// reviewer is recorded as the approver of the load test workflows
+const reviewer = "loadtest"
+
+// memorySampleInterval is how often the heap and goroutines are sampled
+const memorySampleInterval = 250 * time.Millisecond
+
+// Options configure a run
+type Options struct {
+	Workflows     int           // workflows to run
+	Concurrency   int           // workflows in flight at once, 0 for all of them
+	FakeUpstreams bool          // serve OpenAI and suno-api from in-process fakes instead of the configured ones
+	Latency       time.Duration // added to every fake upstream request
+	Timeout       time.Duration // the run stops and reports what finished by then, 0 for none
+}
+
+// stage is a part of the pipeline measured between two status transitions
+type stage struct {
+	name     string
+	from, to string
+}
+
+// stages are reported in pipeline order; "queued for Suno" is the wait for a submission slot
+var stages = []stage{
+	{"LLM steps (start → review)", storage.StatusProcessing, storage.StatusAwaitingReview},
+	{"queued for Suno (approved → generating)", storage.StatusApproved, storage.StatusGenerating},
+	{"Suno generation (generating → completed)", storage.StatusGenerating, storage.StatusCompleted},
+	{"end to end (start → completed)", storage.StatusProcessing, storage.StatusCompleted},
+}
+
+// run collects the transitions of the load test workflows
+type run struct {
+	mu          sync.Mutex
+	transitions map[string]map[string]time.Time // workflow ID -> status -> first time reached
+	failures    map[string]string               // workflow ID -> error of failed workflows
+	finished    chan string                     // IDs of workflows that reached a terminal status
+}
+
+// Run executes the load test with the configuration of cfg and writes the report to w
+// The run uses its own in-memory store and archives no songs (RESULTS_DIR); Telegram, outbound
+// webhooks and the Suno submission cap are disabled so nothing leaves the host but the (real or
+// fake) upstream calls.
+func Run(ctx context.Context, cfg *config.Config, opts Options, w io.Writer) error {
+	if opts.Workflows <= 0 {
+		return errors.New("--workflows must be positive")
+	}
+	if opts.Concurrency <= 0 || opts.Concurrency > opts.Workflows {
+		opts.Concurrency = opts.Workflows
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	defer cancel()
+
+	c := *cfg
+	c.StoreFile, c.ArchiveDir, c.SyncTarget, c.ResultsDir = "", "", "", ""
+	c.TelegramBotToken, c.TelegramChatID, c.EscalationChatID = "", "", ""
+	c.WebhookURLs = nil
+	c.SunoCallbackSecret = ""
+	c.MaxSunoSubmissionsPerHour = 0
+	promptsList := prompts.Init()
+	upstreams := "configured upstreams (OpenAI " + c.OpenAIBaseURL + ", suno-api " + c.SunoBaseURL + ")"
+	if opts.FakeUpstreams {
+		fakes := newFakeUpstreams(promptsList, opts.Latency)
+		defer fakes.Close()
+		c.OpenAIBaseURL = fakes.server.URL + "/v1"
+		c.OpenAIAPIKeys, c.OpenAIAPIKey = []string{"loadtest"}, "loadtest"
+		c.LyricsEngine = workflow.LyricsEngineOpenAI
+		c.SunoBaseURL = fakes.server.URL
+		c.SunoTransport = suno.TransportProxy
+		upstreams = fmt.Sprintf("in-process fakes, %s latency per request", opts.Latency)
+	}
+
+	store := storage.NewStore()
+	engine := workflow.NewEngine(&c, store, promptsList)
+	r := &run{
+		transitions: make(map[string]map[string]time.Time),
+		failures:    make(map[string]string),
+		finished:    make(chan string, opts.Workflows),
+	}
+	engine.Events().Subscribe(func(event workflow.Event) {
+		if changed, ok := event.(workflow.StatusChanged); ok {
+			r.observe(ctx, engine, store, changed)
+		}
+	})
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	sampler := newMemorySampler()
+	go sampler.run()
+
+	started := time.Now()
+	slots := make(chan struct{}, opts.Concurrency)
+	var startErrs []error
+	go func() {
+		for range r.finished {
+			<-slots
+		}
+	}()
+	for i := 1; i <= opts.Workflows; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		_, err := engine.StartWorkflow(ctx, workflow.StartParams{
+			Project:         "loadtest",
+			TaskDescription: fmt.Sprintf("Load test song %d: a night drive through a sleeping city", i),
+			Language:        c.DefaultLanguage,
+		})
+		if err != nil {
+			startErrs = append(startErrs, err)
+			<-slots
+		}
+	}
+	// Wait for the last workflows in flight
+	for i := 0; i < cap(slots) && ctx.Err() == nil; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+	}
+	elapsed := time.Since(started)
+	cancel()
+	sampler.stop()
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return r.report(w, reportInput{
+		opts: opts, upstreams: upstreams, elapsed: elapsed, startErrs: startErrs,
+		before: before, after: after, sampler: sampler,
+	})
+}
+
+// observe records a transition; workflows handed over for review are approved as proposed
+func (r *run) observe(ctx context.Context, engine *workflow.Engine, store *storage.Store, changed workflow.StatusChanged) {
+	wf := changed.Workflow
+	r.mu.Lock()
+	times, ok := r.transitions[wf.ID]
+	if !ok {
+		times = make(map[string]time.Time)
+		r.transitions[wf.ID] = times
+	}
+	_, seen := times[changed.To]
+	if !seen {
+		times[changed.To] = changed.At
+	}
+	if changed.To == storage.StatusFailed || changed.To == storage.StatusBlockedModeration {
+		r.failures[wf.ID] = wf.ErrorMsg
+	}
+	r.mu.Unlock()
+	if seen {
+		return
+	}
+
+	switch {
+	case changed.To == storage.StatusAwaitingReview:
+		go func() {
+			state, ok := store.Get(wf.ID)
+			if !ok {
+				return
+			}
+			if err := engine.ApproveWorkflow(ctx, state, reviewer); err != nil {
+				r.mu.Lock()
+				r.failures[wf.ID] = "approval: " + err.Error()
+				r.mu.Unlock()
+				r.finished <- wf.ID
+			}
+		}()
+	case storage.LookupStatus(changed.To).Terminal || changed.To == storage.StatusBlockedModeration:
+		r.finished <- wf.ID
+	}
+}
+
+// memorySampler records the peak heap and goroutine count of a run
+type memorySampler struct {
+	done          chan struct{}
+	stopped       chan struct{}
+	peakHeap      uint64
+	peakGoroutine int
+}
+
+func newMemorySampler() *memorySampler {
+	return &memorySampler{done: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+func (s *memorySampler) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(memorySampleInterval)
+	defer ticker.Stop()
+	for {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		s.peakHeap = max(s.peakHeap, m.HeapAlloc)
+		s.peakGoroutine = max(s.peakGoroutine, runtime.NumGoroutine())
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *memorySampler) stop() {
+	close(s.done)
+	<-s.stopped
+}
+
+// reportInput is what the report is written from
+type reportInput struct {
+	opts          Options
+	upstreams     string
+	elapsed       time.Duration
+	startErrs     []error
+	before, after runtime.MemStats
+	sampler       *memorySampler
+}
+
+// report writes throughput, stage latencies, failures and memory
+func (r *run) report(w io.Writer, in reportInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	completed := 0
+	for _, times := range r.transitions {
+		if _, ok := times[storage.StatusCompleted]; ok {
+			completed++
+		}
+	}
+	unfinished := len(r.transitions) - completed - len(r.failures)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Workflows\t%d started, %d completed, %d failed, %d unfinished, %d not started\n",
+		len(r.transitions), completed, len(r.failures), max(unfinished, 0), in.opts.Workflows-len(r.transitions))
+	fmt.Fprintf(tw, "Concurrency\t%d in flight\n", in.opts.Concurrency)
+	fmt.Fprintf(tw, "Upstreams\t%s\n", in.upstreams)
+	fmt.Fprintf(tw, "Duration\t%s\n", in.elapsed.Round(time.Millisecond))
+	if in.elapsed > 0 {
+		fmt.Fprintf(tw, "Throughput\t%.1f workflows/min\n", float64(completed)/in.elapsed.Minutes())
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Stage\tp50\tp95\tp99\tmax")
+	for _, s := range stages {
+		var durations []time.Duration
+		for _, times := range r.transitions {
+			from, okFrom := times[s.from]
+			to, okTo := times[s.to]
+			if okFrom && okTo {
+				durations = append(durations, to.Sub(from))
+			}
+		}
+		if len(durations) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\n", s.name)
+			continue
+		}
+		slices.Sort(durations)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.name, percentile(durations, 50), percentile(durations, 95),
+			percentile(durations, 99), durations[len(durations)-1].Round(time.Millisecond))
+	}
+	fmt.Fprintln(tw)
+
+	perWorkflow := int64(0)
+	if len(r.transitions) > 0 {
+		perWorkflow = max(int64(in.after.HeapAlloc)-int64(in.before.HeapAlloc), 0) / int64(len(r.transitions))
+	}
+	fmt.Fprintf(tw, "Heap\t%s before, %s peak, %s after (%s per workflow kept)\n", formatBytes(int64(in.before.HeapAlloc)),
+		formatBytes(int64(in.sampler.peakHeap)), formatBytes(int64(in.after.HeapAlloc)), formatBytes(perWorkflow))
+	fmt.Fprintf(tw, "Allocated\t%s in total, %d GC cycles\n", formatBytes(int64(in.after.TotalAlloc-in.before.TotalAlloc)), in.after.NumGC-in.before.NumGC)
+	fmt.Fprintf(tw, "Goroutines\t%d peak\n", in.sampler.peakGoroutine)
+	fmt.Fprintf(tw, "Process memory\t%s from the OS\n", formatBytes(int64(in.after.Sys)))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, err := range firstN(in.startErrs, 5) {
+		fmt.Fprintf(w, "start failed: %v\n", err)
+	}
+	shown := 0
+	for id, msg := range r.failures {
+		if shown == 5 {
+			fmt.Fprintf(w, "... and %d more failures\n", len(r.failures)-shown)
+			break
+		}
+		fmt.Fprintf(w, "failed %s: %s\n", id, msg)
+		shown++
+	}
+	if completed < in.opts.Workflows {
+		return fmt.Errorf("%d of %d workflows completed", completed, in.opts.Workflows)
+	}
+	return nil
+}
+
+// percentile returns the p-th percentile of sorted durations (nearest rank)
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	return sorted[max(i-1, 0)].Round(time.Millisecond)
+}
+
+func firstN[T any](list []T, n int) []T {
+	return list[:min(len(list), n)]
+}
+
+func formatBytes(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%s%.1f GiB", sign, float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%s%.1f MiB", sign, float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%s%.1f KiB", sign, float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%s%d B", sign, n)
+}
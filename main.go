@@ -1,27 +1,36 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"workflower/config"
-	"workflower/handlers"
+	"workflower/diag"
+	"workflower/evaluate"
+	"workflower/internal/handlers"
+	"workflower/internal/ui_templates"
+	"workflower/lib/blob"
 	"workflower/lib/deploy"
+	"workflower/lib/locale"
 	applogger "workflower/lib/logger"
-	"workflower/lib/telegram"
+	"workflower/loadtest"
 	"workflower/storage"
 	"workflower/templates/prompts"
-	"workflower/templates/ui_templates"
 	"workflower/workflow"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
@@ -31,8 +40,77 @@ func main() {
 	deployFlag := flag.Bool("D", false, "Deploy to remote server")
 	setupFlag := flag.Bool("setup", false, "Run remote setup (used during deployment)")
 	useTunnel := flag.Bool("L", false, "Start Cloudflare tunnel and override BASE_URL/TELEGRAM_WEBHOOK_URL")
+	versionFlag := flag.Bool("version", false, "Print the version and exit")
 	flag.Parse()
 
+	if *versionFlag {
+		fmt.Println(config.Version)
+		return
+	}
+
+	// Handle the supervise subcommand (the service entry point installed by setup)
+	if args := flag.Args(); len(args) >= 1 && args[0] == "supervise" {
+		if err := supervise(args[1:]); err != nil {
+			slog.Error("Supervisor failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the self-update subcommand
+	if args := flag.Args(); len(args) >= 1 && args[0] == "self-update" {
+		if err := selfUpdate(args[1:]); err != nil {
+			slog.Error("Self-update failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the hash-password subcommand (produces LOGIN_USERS entries)
+	if args := flag.Args(); len(args) >= 1 && args[0] == "hash-password" {
+		if err := hashPassword(); err != nil {
+			slog.Error("Hashing password failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the diag subcommand (diagnostics bundle for bug reports)
+	if args := flag.Args(); len(args) >= 1 && args[0] == "diag" {
+		if err := collectDiagnostics(args[1:]); err != nil {
+			slog.Error("Collecting diagnostics failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the loadtest subcommand (capacity measurement)
+	if args := flag.Args(); len(args) >= 1 && args[0] == "loadtest" {
+		if err := runLoadTest(args[1:]); err != nil {
+			slog.Error("Load test failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the evaluate subcommand (replay of approved workflows with new prompts)
+	if args := flag.Args(); len(args) >= 1 && args[0] == "evaluate" {
+		if err := runEvaluation(args[1:]); err != nil {
+			slog.Error("Evaluation failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the deploy export subcommand
+	if args := flag.Args(); len(args) >= 2 && args[0] == "deploy" && args[1] == "export" {
+		if err := deployExport(args[2:]); err != nil {
+			slog.Error("Deploy export failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle deployment mode
 	if *deployFlag {
 		if err := deploy.Deploy(); err != nil {
@@ -54,10 +132,24 @@ func main() {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
 		slog.Info("No .env file found, using environment variables")
+
+		// First run without any configuration: serve the setup wizard until it wrote .env
+		if config.NeedsSetup() {
+			if err := runSetupWizard(); err != nil {
+				slog.Error("Setup wizard failed", "error", err)
+				os.Exit(1)
+			}
+			if err := godotenv.Load(); err != nil {
+				slog.Error("Failed to load the configuration written by the setup wizard", "error", err)
+				os.Exit(1)
+			}
+		}
 	}
 
 	// Load configuration
 	cfg := config.Load()
+	applogger.InitWithLevel(cfg.LogLevel)
+	slog.Info("Configuration loaded", "app_env", cfg.AppEnv, "log_level", cfg.LogLevel, "dry_run", cfg.DryRun)
 
 	if *useTunnel {
 		tunnelURL, err := deploy.StartCloudflareTunnel(context.Background(), cfg.ServerPort)
@@ -66,29 +158,19 @@ func main() {
 			os.Exit(1)
 		}
 
-		baseURL := strings.TrimRight(tunnelURL, "/")
-		cfg.BaseURL = baseURL
-
-		webhookPath := strings.TrimSpace(cfg.TelegramWebhookPath)
-		if webhookPath == "" {
-			webhookPath = "/telegram/webhook"
-		} else if !strings.HasPrefix(webhookPath, "/") {
-			webhookPath = "/" + webhookPath
+		cfg.BaseURL = strings.TrimRight(tunnelURL, "/")
+		cfg.TelegramWebhookURL = cfg.WebhookURLFor(cfg.BaseURL)
+		// Requests arrive from the local cloudflared, which passes the client address on
+		if cfg.ProxyHeader == "" {
+			cfg.ProxyHeader = "CF-Connecting-IP"
 		}
-		cfg.TelegramWebhookURL = cfg.BaseURL + webhookPath
 
 		slog.Info("Cloudflare tunnel active", "url", cfg.BaseURL)
 		slog.Info("Telegram webhook URL configured", "url", cfg.TelegramWebhookURL)
 	}
 
-	// Validate required configuration
-	if cfg.OpenAIAPIKey == "" {
-		slog.Error("OPENAI_API_KEY is required")
-		os.Exit(1)
-	}
-
 	// Initialize templates
-	templates, err := ui_templates.Init()
+	templates, err := ui_templates.Init(cfg.Locale)
 	if err != nil {
 		slog.Error("Failed to initialize templates", "error", err)
 		os.Exit(1)
@@ -98,19 +180,52 @@ func main() {
 	promptsList := prompts.Init()
 
 	// Initialize storage
-	store := storage.NewStore()
+	store, err := storage.OpenStore(cfg.StoreFile)
+	if err != nil {
+		slog.Error("Failed to open store", "error", err)
+		os.Exit(1)
+	}
+
+	// Old workflow payloads are moved to compressed blobs
+	if cfg.ArchiveDir != "" {
+		blobs, err := blob.NewDir(cfg.ArchiveDir)
+		if err != nil {
+			slog.Error("Failed to open archive", "error", err)
+			os.Exit(1)
+		}
+		store.SetArchive(blobs)
+	}
 
-	// Initialize workflow engine
+	// Initialize workflow engine with its background jobs
 	engine := workflow.NewEngine(cfg, store, promptsList)
+	if err := engine.Start(context.Background()); err != nil {
+		slog.Error("Failed to start the workflow engine", "error", err)
+		os.Exit(1)
+	}
+
+	// Rotate OpenAI keys and reload the Telegram messages on SIGHUP, re-reading .env
+	go reloadOnSignal(engine)
 
 	// Initialize handlers
 	handler := handlers.NewHandler(cfg, store, engine, templates)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		BodyLimit: int(cfg.MaxAudioSizeMB) << 20,
+		// The largest audio upload plus the form fields; the upload itself is checked by the handler
+		BodyLimit: (int(cfg.MaxAudioSizeMB) + 1) << 20,
+		// Values read from requests (identities, form fields) are kept in workflows and the
+		// audit log, so they must not alias buffers that fasthttp reuses
+		Immutable: true,
+		// Behind a reverse proxy or tunnel, c.IP() (rate limits, access log) reads the client address
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.ProxyHeader != "",
+		TrustedProxies:          cfg.TrustedProxies,
+		// Styled pages for browsers, JSON for the API (see handlers.sendError)
+		ErrorHandler: handler.HandleError,
 	})
-	app.Use(logger.New())
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${respHeader:X-Request-ID} | ${error}\n",
+	}))
 	app.Use(recover.New())
 	app.Use(handlers.ErrorHandler())
 
@@ -124,21 +239,270 @@ func main() {
 	if cfg.TelegramBotToken != "" {
 		slog.Info("Telegram notifications enabled")
 		slog.Info("Telegram webhook path configured", "path", cfg.TelegramWebhookPath)
-		if cfg.TelegramWebhookURL != "" {
-			notifier := telegram.NewNotifier(cfg.TelegramBotToken, cfg.TelegramChatID)
-			if err := notifier.SetWebhook(context.Background(), cfg.TelegramWebhookURL, cfg.TelegramWebhookSecret); err != nil {
-				slog.Warn("Failed to set Telegram webhook", "error", err)
-			} else {
-				slog.Info("Telegram webhook registered", "url", cfg.TelegramWebhookURL)
-			}
-		}
+		// A restarted tunnel or a deploy to another host changes the URL; keep Telegram pointed at it
+		go engine.RunTelegramWebhookMonitor(context.Background())
 	}
 	if cfg.EnablePremiumFeatures {
 		slog.Info("Premium features enabled by default")
 	}
 
-	if err := app.Listen(addr); err != nil {
+	listenErr := make(chan error, 1)
+	go func() { listenErr <- app.Listen(addr) }()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-listenErr:
 		slog.Error("Failed to start server", "error", err)
 		os.Exit(1)
+	case sig := <-stop:
+		slog.Info("Shutting down", "signal", sig.String())
+		shutdown(app, engine, store, 0)
+		os.Exit(0)
+	case <-handler.RestartRequested():
+		slog.Info("Shutting down for the installed release", "drain_timeout", cfg.DeployDrainTimeout)
+		shutdown(app, engine, store, cfg.DeployDrainTimeout)
+		os.Exit(deploy.RestartExitCode)
+	}
+}
+
+// shutdown stops taking requests, waits up to drain for the workflows being prepared or
+// submitted to Suno (see Engine.Drain) and writes pending store changes
+func shutdown(app *fiber.App, engine *workflow.Engine, store *storage.Store, drain time.Duration) {
+	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+		slog.Warn("Server did not shut down cleanly", "error", err)
+	}
+	if drain > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), drain)
+		if err := engine.Drain(ctx); err != nil {
+			slog.Warn("Workflows still in flight, they resume or fail retryably on the next start", "error", err)
+		}
+		cancel()
+	}
+	if err := store.Flush(); err != nil {
+		slog.Error("Failed to write the store", "error", err)
+	}
+}
+
+// runSetupWizard serves the setup wizard on SERVER_PORT until the configuration is written
+func runSetupWizard() error {
+	templates, err := ui_templates.Init(locale.MustLookup(os.Getenv("LOCALE")))
+	if err != nil {
+		return err
+	}
+	wizard := handlers.NewSetupWizard(templates, ".env")
+
+	app := fiber.New(fiber.Config{Immutable: true})
+	app.Use(recover.New())
+	wizard.RegisterRoutes(app)
+
+	port := os.Getenv("SERVER_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	slog.Warn("No configuration found, open the setup wizard to configure the server",
+		"url", fmt.Sprintf("http://localhost:%s/setup?token=%s", port, wizard.Token()))
+
+	listenErr := make(chan error, 1)
+	go func() { listenErr <- app.Listen(":" + port) }()
+	select {
+	case err := <-listenErr:
+		return err
+	case <-wizard.Done():
+	}
+	slog.Info("Setup complete, starting the server")
+	return app.ShutdownWithTimeout(10 * time.Second)
+}
+
+// reloadOnSignal re-reads the OpenAI keys from .env and the environment on every SIGHUP,
+// reloads the MESSAGES_DIR overrides and checks the Telegram webhook registration
+func reloadOnSignal(engine *workflow.Engine) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := godotenv.Overload(); err != nil {
+			slog.Info("No .env file found, using environment variables")
+		}
+		engine.RotateOpenAIKeys(config.OpenAIKeys())
+		engine.ReloadMessages()
+		if err := engine.EnsureTelegramWebhook(context.Background()); err != nil {
+			slog.Warn("Failed to check Telegram webhook", "error", err)
+		}
+	}
+}
+
+// deployExport runs "deploy export --format ansible|terraform [--output FILE]"
+func deployExport(args []string) error {
+	fs := flag.NewFlagSet("deploy export", flag.ExitOnError)
+	format := fs.String("format", deploy.ExportAnsible, "Output format: ansible or terraform")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return deploy.Export(*format, os.Stdout)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := deploy.Export(*format, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// collectDiagnostics runs "diag [--output FILE] [--server URL] [--service NAME]"
+func collectDiagnostics(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	service := os.Getenv("APP_NAME")
+	if service == "" {
+		service = "workflower"
+	}
+	fs := flag.NewFlagSet("diag", flag.ExitOnError)
+	output := fs.String("output", "workflower-diag-"+time.Now().Format("20060102-150405")+".tar.gz", "Bundle file to write")
+	server := fs.String("server", "http://localhost:"+cfg.ServerPort, "Running server to probe")
+	serviceName := fs.String("service", service, "systemd unit whose journal is searched for errors")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	opts := diag.Options{ServiceName: *serviceName, ServerURL: strings.TrimRight(*server, "/")}
+	if err := diag.Collect(context.Background(), cfg, opts, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println(*output)
+	return nil
+}
+
+// runLoadTest runs "loadtest [--workflows N] [--concurrency N] [--fake-upstreams] [--latency D] [--timeout D]"
+func runLoadTest(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	workflows := fs.Int("workflows", 200, "Workflows to run")
+	concurrency := fs.Int("concurrency", 0, "Workflows in flight at once (0 = all)")
+	fakeUpstreams := fs.Bool("fake-upstreams", false, "Serve OpenAI and suno-api from in-process fakes")
+	latency := fs.Duration("latency", 200*time.Millisecond, "Latency of every fake upstream request")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Stop and report after this long (0 = no limit)")
+	verbose := fs.Bool("v", false, "Keep the engine logs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*verbose {
+		applogger.InitWithLevel(slog.LevelWarn)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return loadtest.Run(ctx, cfg, loadtest.Options{
+		Workflows:     *workflows,
+		Concurrency:   *concurrency,
+		FakeUpstreams: *fakeUpstreams,
+		Latency:       *latency,
+		Timeout:       *timeout,
+	}, os.Stdout)
+}
+
+// runEvaluation runs "evaluate --prompts DIR [--workflow ID,...] [--project P] [--limit N] [--concurrency N] [--report FILE]"
+func runEvaluation(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	promptsDir := fs.String("prompts", "", "Directory with the new prompt files (e.g. lyrics_generation.txt)")
+	ids := fs.String("workflow", "", "Comma-separated IDs of the workflows to replay (default: the newest approved ones)")
+	project := fs.String("project", "", "Only replay workflows of this project")
+	limit := fs.Int("limit", 20, "Workflows to replay at most")
+	concurrency := fs.Int("concurrency", 4, "Workflows replayed at once")
+	reportFile := fs.String("report", "", "Write the full report, with the replayed lyrics, as JSON to this file")
+	verbose := fs.Bool("v", false, "Keep the engine logs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *promptsDir == "" {
+		return fmt.Errorf("--prompts is required")
+	}
+	if !*verbose {
+		applogger.InitWithLevel(slog.LevelWarn)
+	}
+
+	store, err := storage.OpenStore(cfg.StoreFile)
+	if err != nil {
+		return err
+	}
+	var workflows []string
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			workflows = append(workflows, id)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return evaluate.Run(ctx, cfg, store, evaluate.Options{
+		PromptsDir:  *promptsDir,
+		Workflows:   workflows,
+		Project:     *project,
+		Limit:       *limit,
+		Concurrency: *concurrency,
+		ReportFile:  *reportFile,
+	}, os.Stdout)
+}
+
+// hashPassword runs "hash-password": reads a password from stdin and prints its bcrypt hash
+func hashPassword() error {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("no password on stdin")
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return fmt.Errorf("empty password")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(hash))
+	return nil
+}
+
+// selfUpdate runs "self-update [--check]"
+func selfUpdate(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	checkOnly := fs.Bool("check", false, "Only report whether an update is available")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return deploy.SelfUpdate(context.Background(), config.Version, *checkOnly)
+}
+
+// supervise runs "supervise BINARY [ARGS...]"
+func supervise(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: supervise BINARY [ARGS...]")
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return deploy.Supervise(ctx, args[0], args[1:])
 }
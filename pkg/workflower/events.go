@@ -0,0 +1,92 @@
+package workflower
+
+import (
+	"time"
+
+	"workflower/storage"
+	"workflower/workflow"
+)
+
+// Event names
+const (
+	EventStepStarted   = "step_started"
+	EventStepFinished  = "step_finished"
+	EventStatusChanged = "status_changed"
+	EventProgress      = "progress"
+	EventAssigned      = "assigned"
+	EventEscalated     = "escalated"
+	EventDeleted       = "deleted"
+	EventReviewed      = "reviewed"
+	EventCommented     = "commented"
+)
+
+// Event is a change of a workflow; the fields an event does not carry are zero
+type Event struct {
+	Name       string // one of the Event* names
+	WorkflowID string
+	At         time.Time
+
+	Step     string        // step events: the engine step, e.g. "lyrics generation"
+	Duration time.Duration // EventStepFinished
+	Error    string        // EventStepFinished: why the step failed, "" when it succeeded
+
+	From string // EventStatusChanged: the previous status; EventAssigned: the previous reviewer
+	To   string // EventStatusChanged: the new status; EventAssigned, EventEscalated: the reviewer
+	By   string // EventAssigned, EventDeleted, EventReviewed: who did it; EventCommented: the author
+
+	Decision string // EventReviewed: "approved", "approved with edits" or "rejected"
+	Level    int    // EventEscalated: 1 backup reviewer, 2 admin channel
+	Comment  string // EventCommented: the text of the note
+	Done     int    // EventProgress: clips generated of Total
+	Total    int
+
+	// Workflow is a snapshot taken with the change, nil for step and progress events
+	Workflow *Workflow
+}
+
+// Subscribe calls fn with every event of the engine and returns a function that stops it
+// fn runs synchronously within the engine and must not block; hand longer work, such as
+// approving the workflow, off to a goroutine.
+func (e *Engine) Subscribe(fn func(Event)) (unsubscribe func()) {
+	return e.engine.Events().Subscribe(func(event workflow.Event) {
+		fn(newEvent(event))
+	})
+}
+
+// newEvent converts an event of the engine
+func newEvent(event workflow.Event) Event {
+	ev := Event{Name: event.Name(), WorkflowID: event.WorkflowID()}
+	switch event := event.(type) {
+	case workflow.StepStarted:
+		ev.Step, ev.At = event.Step, event.At
+	case workflow.StepFinished:
+		ev.Step, ev.At, ev.Duration, ev.Error = event.Step, event.At, event.Duration, event.Error
+	case workflow.StatusChanged:
+		ev.From, ev.To, ev.At = event.From, event.To, event.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	case workflow.Progress:
+		ev.Done, ev.Total, ev.At = event.Done, event.Total, event.At
+	case workflow.Assigned:
+		ev.From, ev.To, ev.By, ev.At = event.From, event.To, event.By, event.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	case workflow.Escalated:
+		ev.Level, ev.To, ev.At = event.Level, event.To, event.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	case workflow.Deleted:
+		ev.By, ev.At = event.By, event.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	case workflow.Reviewed:
+		ev.By, ev.Decision, ev.At = event.By, event.Decision, event.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	case workflow.Commented:
+		ev.By, ev.Comment, ev.At = event.Comment.Author, event.Comment.Text, event.Comment.At
+		ev.Workflow = eventWorkflow(event.Workflow)
+	}
+	return ev
+}
+
+// eventWorkflow converts the snapshot an event carries
+func eventWorkflow(state storage.WorkflowState) *Workflow {
+	wf := newWorkflow(&state)
+	return &wf
+}
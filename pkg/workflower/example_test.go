@@ -0,0 +1,44 @@
+package workflower_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"workflower/lib/suno/sunotest"
+	"workflower/pkg/workflower"
+)
+
+// Example runs a workflow in-process until it awaits review, against the Suno mock and without
+// an OpenAI key, so the lyrics are drafted by (mock) Suno
+func Example() {
+	cfg := workflower.LoadConfig()
+	cfg.OpenAIAPIKeys = nil
+	cfg.StoreFile, cfg.ArchiveDir, cfg.ResultsDir = "", "", ""
+	cfg.TelegramBotToken, cfg.WebhookURLs = "", nil
+
+	engine, err := workflower.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	engine.SetSunoAPI(sunotest.New())
+
+	review := make(chan *workflower.Workflow, 1)
+	engine.Subscribe(func(event workflower.Event) {
+		if event.Name == workflower.EventStatusChanged && event.To == workflower.StatusAwaitingReview {
+			review <- event.Workflow
+		}
+	})
+
+	started, err := engine.StartWorkflow(context.Background(), workflower.StartParams{
+		TaskDescription: "A birthday song for a grandmother who loves gardening",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ready := <-review
+	wf, _ := engine.Workflows().Get(ready.ID)
+	fmt.Println(ready.ID == started.ID, wf.Status, wf.LyricsEngine, wf.Lyrics != "")
+	// Output: true awaiting_review suno true
+}
@@ -0,0 +1,77 @@
+package workflower_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"workflower/lib/suno/sunotest"
+	"workflower/pkg/workflower"
+	"workflower/templates/prompts"
+)
+
+// slowChatter answers like the OpenAI API after a delay: JSON for the properties and persona
+// steps, lyrics otherwise
+type slowChatter struct {
+	prompts *prompts.PromptsList
+	delay   time.Duration
+}
+
+func (c slowChatter) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	switch systemPrompt {
+	case c.prompts.SunoProperties:
+		return `{"style": "folk, acoustic guitar", "vocal_type": "female vocals", "lyrics_mode": "custom", "weirdness": 0.3, "style_influence": "0.5"}`, nil
+	case c.prompts.PersonaInspo:
+		return `{"persona": "village storyteller", "inspo": "spring gardens"}`, nil
+	}
+	return "[Verse]\nRoses climbing up the wall\nGrandma knows them one and all\n\n[Chorus]\nHappy birthday, happy day\nBloom forever, come what may", nil
+}
+
+// TestWorkflowsGetWhileRunning reads a workflow while its steps write it; run with -race
+func TestWorkflowsGetWhileRunning(t *testing.T) {
+	t.Setenv("ENABLE_MODERATION", "false")
+	cfg := workflower.LoadConfig()
+	cfg.OpenAIAPIKeys = []string{"sk-test"}
+	cfg.StoreFile, cfg.ArchiveDir, cfg.ResultsDir, cfg.SyncTarget = "", "", "", ""
+	cfg.TelegramBotToken, cfg.WebhookURLs = "", nil
+
+	engine, err := workflower.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.SetSunoAPI(sunotest.New())
+	engine.SetLLM(slowChatter{prompts: prompts.Init(), delay: 5 * time.Millisecond})
+
+	wf, err := engine.StartWorkflow(context.Background(), workflower.StartParams{
+		TaskDescription: "A birthday song for a grandmother who loves gardening",
+		IsPremium:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got, ok := engine.Workflows().Get(wf.ID)
+		if !ok {
+			t.Fatal("workflow not found")
+		}
+		if got.Status == workflower.StatusAwaitingReview {
+			if got.Lyrics == "" {
+				t.Error("awaiting review without lyrics")
+			}
+			return
+		}
+		if got.Status != workflower.StatusProcessing {
+			t.Fatalf("status %s (%s), want %s", got.Status, got.Error, workflower.StatusAwaitingReview)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("workflow did not reach review")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,217 @@
+// Package workflower is the Go API of the song workflow engine, for programs that embed it as a
+// library instead of calling the HTTP server
+//
+// It is the stable surface: its types are its own and are converted from the implementation
+// packages (workflow, storage, config) at the boundary, so those can change without breaking
+// embedders, and the web layer (internal/handlers, internal/ui_templates) is not importable.
+// Providers plug in through the interfaces of the lib packages: suno.API, llm.Chatter and
+// notify.Notifier. A typical embedding:
+//
+//	cfg := workflower.LoadConfig()
+//	engine, err := workflower.Open(cfg)
+//	...
+//	err = engine.Start(ctx)
+//	wf, err := engine.StartWorkflow(ctx, workflower.StartParams{TaskDescription: "..."})
+//
+// The engine then runs the workflow in the background; subscribe to its events to follow it
+// and approve it with Engine.Approve once it awaits review.
+package workflower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"workflower/config"
+	"workflower/lib/blob"
+	"workflower/lib/llm"
+	"workflower/lib/notify"
+	"workflower/lib/suno"
+	"workflower/storage"
+	"workflower/templates/prompts"
+	"workflower/workflow"
+)
+
+var (
+	// ErrNotFound is returned for a workflow ID the engine does not know
+	ErrNotFound = errors.New("workflow not found")
+	// ErrNotAwaitingReview is returned when a workflow is approved or rejected outside review,
+	// or while another approval or rejection of it is under way
+	ErrNotAwaitingReview = errors.New("workflow is not awaiting review")
+)
+
+// Config is the configuration of an embedded engine
+// LoadConfig fills it from the environment as the server does (see .env_example); the settings
+// without a field here keep their environment value.
+type Config struct {
+	StoreFile  string // STORE_FILE: JSON snapshot of the workflows, empty to keep them in memory
+	ArchiveDir string // ARCHIVE_DIR: where old workflow payloads are moved, empty disables archival
+	ResultsDir string // RESULTS_DIR: where completed songs are downloaded, empty disables it
+	SyncTarget string // SYNC_TARGET: dropbox:/folder or dir:/path, empty disables cloud sync
+
+	OpenAIAPIKeys []string // OPENAI_API_KEYS: without a key, lyrics are drafted by Suno
+	OpenAIModel   string   // OPENAI_MODEL
+	SunoBaseURL   string   // SUNO_BASE_URL: the suno-api server
+	SunoAPIToken  string   // SUNO_API_TOKEN: bearer token of the hosted transport
+
+	TelegramBotToken string   // TELEGRAM_BOT_TOKEN: empty disables Telegram notifications
+	TelegramChatID   string   // TELEGRAM_CHAT_ID
+	WebhookURLs      []string // WEBHOOK_URLS: outbound webhooks of the workflow events
+	DryRun           bool     // DRY_RUN: Telegram messages and outbound webhooks are only logged
+
+	env *config.Config // the environment the other settings are taken from
+}
+
+// LoadConfig reads the configuration from the environment, as the server does
+func LoadConfig() *Config {
+	env := config.Load()
+	return &Config{
+		StoreFile:        env.StoreFile,
+		ArchiveDir:       env.ArchiveDir,
+		ResultsDir:       env.ResultsDir,
+		SyncTarget:       env.SyncTarget,
+		OpenAIAPIKeys:    env.OpenAIAPIKeys,
+		OpenAIModel:      env.OpenAIModel,
+		SunoBaseURL:      env.SunoBaseURL,
+		SunoAPIToken:     env.SunoAPIToken,
+		TelegramBotToken: env.TelegramBotToken,
+		TelegramChatID:   env.TelegramChatID,
+		WebhookURLs:      env.WebhookURLs,
+		DryRun:           env.DryRun,
+		env:              env,
+	}
+}
+
+// config returns the engine configuration: the environment with the fields of c applied
+func (c *Config) config() *config.Config {
+	env := c.env
+	if env == nil {
+		env = config.Load()
+	}
+	cfg := *env
+	cfg.StoreFile = c.StoreFile
+	cfg.ArchiveDir = c.ArchiveDir
+	cfg.ResultsDir = c.ResultsDir
+	cfg.SyncTarget = c.SyncTarget
+	cfg.OpenAIAPIKeys = c.OpenAIAPIKeys
+	cfg.OpenAIAPIKey = "" // the first key, as config.Load derives it
+	if len(c.OpenAIAPIKeys) > 0 {
+		cfg.OpenAIAPIKey = c.OpenAIAPIKeys[0]
+	}
+	cfg.OpenAIModel = c.OpenAIModel
+	cfg.SunoBaseURL = c.SunoBaseURL
+	cfg.SunoAPIToken = c.SunoAPIToken
+	cfg.TelegramBotToken = c.TelegramBotToken
+	cfg.TelegramChatID = c.TelegramChatID
+	cfg.WebhookURLs = c.WebhookURLs
+	cfg.DryRun = c.DryRun
+	return &cfg
+}
+
+// Engine runs song workflows in-process
+type Engine struct {
+	engine *workflow.Engine
+	store  *storage.Store
+}
+
+// Open opens the store of cfg (StoreFile, in memory when empty) with its archive (ArchiveDir)
+// and creates an engine with the embedded prompts; Start runs it
+func Open(cfg *Config) (*Engine, error) {
+	c := cfg.config()
+	store, err := storage.OpenStore(c.StoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	if c.ArchiveDir != "" {
+		blobs, err := blob.NewDir(c.ArchiveDir)
+		if err != nil {
+			return nil, fmt.Errorf("open archive: %w", err)
+		}
+		store.SetArchive(blobs)
+	}
+	return &Engine{engine: workflow.NewEngine(c, store, prompts.Init()), store: store}, nil
+}
+
+// Start resumes the workflows interrupted by the last shutdown and runs the background jobs of
+// the engine until ctx is cancelled: the scheduler (reminders, escalation, expiry), the Suno
+// session and quota monitors, the style tag sync and, when configured, archival and cloud sync
+// It returns at once; only opening the SyncTarget can fail.
+func (e *Engine) Start(ctx context.Context) error {
+	return e.engine.Start(ctx)
+}
+
+// Close writes the pending changes of the store, e.g. before the program exits
+func (e *Engine) Close() error {
+	return e.store.Flush()
+}
+
+// SetSunoAPI replaces the Suno client, e.g. with the in-memory sunotest.Mock in tests
+func (e *Engine) SetSunoAPI(api suno.API) {
+	e.engine.SetSunoAPI(api)
+}
+
+// SetLLM replaces the language model that writes and edits lyrics (the OpenAI API by default)
+func (e *Engine) SetLLM(chatter llm.Chatter) {
+	e.engine.SetLLM(chatter)
+}
+
+// SetNotifier replaces the notifier chat notifications are sent with (Telegram by default)
+func (e *Engine) SetNotifier(notifier notify.Notifier) {
+	e.engine.SetNotifier(notifier)
+}
+
+// StartWorkflow creates a workflow and runs it in the background until it awaits review
+func (e *Engine) StartWorkflow(ctx context.Context, params StartParams) (Workflow, error) {
+	state, err := e.engine.StartWorkflow(ctx, params.internal())
+	if err != nil {
+		return Workflow{}, err
+	}
+	return e.snapshot(state), nil
+}
+
+// Approve submits a workflow awaiting review to Suno with its lyrics and properties as they
+// are; by is recorded as the reviewer
+func (e *Engine) Approve(ctx context.Context, id, by string) error {
+	state, ok := e.store.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return reviewError(e.engine.ApproveWorkflow(ctx, state, by))
+}
+
+// Reject ends a workflow awaiting review as rejected; by is recorded as the reviewer
+func (e *Engine) Reject(id, by string) error {
+	state, ok := e.store.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return reviewError(e.engine.RejectWorkflow(state, by))
+}
+
+// reviewError converts the refusal of a review decision outside review
+func reviewError(err error) error {
+	if errors.Is(err, workflow.ErrNotInReview) {
+		return ErrNotAwaitingReview
+	}
+	return err
+}
+
+// Cancel stops an unfinished workflow; clips already submitted keep generating on Suno
+func (e *Engine) Cancel(id string) error {
+	state, ok := e.store.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return e.engine.CancelWorkflow(state)
+}
+
+// Workflows returns the read side of the store
+func (e *Engine) Workflows() Workflows {
+	return storeWorkflows{e}
+}
+
+// snapshot converts a workflow of the store, copied under the engine lock
+func (e *Engine) snapshot(state *storage.WorkflowState) Workflow {
+	wf := e.engine.Snapshot(state)
+	return newWorkflow(&wf)
+}
@@ -0,0 +1,20 @@
+package workflower
+
+import "testing"
+
+// TestConfigOpenAIKey builds the configuration in code: the key the engine uses is the first of
+// OpenAIAPIKeys, whatever the environment holds
+func TestConfigOpenAIKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEYS", "sk-env")
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+
+	cfg := (&Config{OpenAIAPIKeys: []string{"sk-first", "sk-second"}}).config()
+	if cfg.OpenAIAPIKey != "sk-first" || !cfg.HasOpenAI() {
+		t.Errorf("with keys: OpenAIAPIKey = %q, HasOpenAI = %v, want sk-first and true", cfg.OpenAIAPIKey, cfg.HasOpenAI())
+	}
+
+	cfg = (&Config{}).config()
+	if cfg.OpenAIAPIKey != "" || cfg.HasOpenAI() {
+		t.Errorf("without keys: OpenAIAPIKey = %q, HasOpenAI = %v, want empty and false", cfg.OpenAIAPIKey, cfg.HasOpenAI())
+	}
+}
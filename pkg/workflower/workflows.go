@@ -0,0 +1,181 @@
+package workflower
+
+import (
+	"iter"
+	"time"
+
+	"workflower/storage"
+	"workflower/workflow"
+)
+
+// Workflow statuses
+const (
+	StatusProcessing        = "processing"
+	StatusAwaitingReview    = "awaiting_review"
+	StatusApproved          = "approved"
+	StatusGenerating        = "generating"
+	StatusCompleted         = "completed"
+	StatusFailed            = "failed"
+	StatusRejected          = "rejected"
+	StatusBlockedModeration = "blocked_moderation" // flagged by moderation, never sent to Suno
+	StatusCancelled         = "cancelled"
+	StatusBlockedAuth       = "blocked_auth" // approved, parked until the Suno session is valid again
+	StatusExpired           = "expired"      // never reviewed within WORKFLOW_EXPIRE_AFTER
+)
+
+// Lyrics engines of StartParams.LyricsEngine
+const (
+	LyricsEngineOpenAI = "openai"
+	LyricsEngineSuno   = "suno"
+)
+
+// StartParams describes a new workflow
+type StartParams struct {
+	TaskDescription string     // what the song is about; with a Transcript, optional directions
+	Transcript      string     // pasted chat or diary text, summarized into the task description
+	Project         string     // groups workflows, e.g. per customer
+	ExternalRef     string     // the embedder's reference (ticket, order number), searchable
+	IsPremium       bool       // generated with the premium Suno options (personas, stems)
+	LongSong        bool       // generated in several clips
+	Language        string     // lyrics language name; "" for the detected one or DEFAULT_LANGUAGE
+	LyricsEngine    string     // LyricsEngineOpenAI or LyricsEngineSuno, "" for the configured default
+	SunoModel       string     // one of SUNO_MODELS, "" for SUNO_MODEL
+	Assignee        string     // reviewer: a web user name or "tg:<chat id>", "" for anyone
+	DueAt           *time.Time // deadline of the review, nil for none
+}
+
+func (p StartParams) internal() workflow.StartParams {
+	return workflow.StartParams{
+		TaskDescription: p.TaskDescription,
+		Transcript:      p.Transcript,
+		Project:         p.Project,
+		ExternalRef:     p.ExternalRef,
+		IsPremium:       p.IsPremium,
+		LongSong:        p.LongSong,
+		Language:        p.Language,
+		LyricsEngine:    p.LyricsEngine,
+		SunoModel:       p.SunoModel,
+		Assignee:        p.Assignee,
+		DueAt:           p.DueAt,
+	}
+}
+
+// Workflow is a snapshot of a workflow; it does not change as the engine runs it
+type Workflow struct {
+	ID              string
+	Seq             int // sequence number, shown as #<seq>
+	Status          string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Project         string
+	ExternalRef     string
+	TaskDescription string
+	IsPremium       bool
+	Language        string
+	LyricsEngine    string     // the engine that drafted the lyrics
+	Title           string     // the reviewer's title when edited
+	Lyrics          string     // the reviewer's lyrics when edited, the generated ones before
+	Assignee        string     // reviewer, "" for anyone
+	DueAt           *time.Time // deadline of the review, nil for none
+	AudioURL        string     // the finished song, once completed
+	VideoURL        string
+	Error           string // why it failed or was blocked
+	Usage           Usage
+}
+
+// Usage is what a workflow has cost so far
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	LLMCostUSD       float64
+	SunoCredits      int // charged on submission
+}
+
+// newWorkflow converts a workflow of the store; state must not change meanwhile
+func newWorkflow(state *storage.WorkflowState) Workflow {
+	wf := Workflow{
+		ID:              state.ID,
+		Seq:             state.Seq,
+		Status:          state.Status,
+		CreatedAt:       state.CreatedAt,
+		UpdatedAt:       state.UpdatedAt,
+		Project:         state.Project,
+		ExternalRef:     state.ExternalRef,
+		TaskDescription: state.TaskDescription,
+		IsPremium:       state.IsPremium,
+		Language:        state.Language,
+		LyricsEngine:    state.LyricsEngine,
+		Title:           state.Title,
+		Lyrics:          state.Lyrics,
+		Assignee:        state.Assignee,
+		AudioURL:        state.AudioURL,
+		VideoURL:        state.VideoURL,
+		Error:           state.ErrorMsg,
+		Usage: Usage{
+			PromptTokens:     state.Usage.PromptTokens,
+			CompletionTokens: state.Usage.CompletionTokens,
+			LLMCostUSD:       state.Usage.LLMCostUSD,
+			SunoCredits:      state.Usage.SunoCredits,
+		},
+	}
+	if state.EditedTitle != "" {
+		wf.Title = state.EditedTitle
+	}
+	if state.EditedLyrics != "" {
+		wf.Lyrics = state.EditedLyrics
+	}
+	if state.DueAt != nil {
+		due := *state.DueAt
+		wf.DueAt = &due
+	}
+	return wf
+}
+
+// Workflows is the read side of the store, for code that lists or looks up workflows without
+// changing them
+type Workflows interface {
+	Get(id string) (Workflow, bool)
+	GetBySeq(seq int) (Workflow, bool)
+	ListByStatus(status string) []Workflow // newest first
+	All() iter.Seq[Workflow]               // newest first
+}
+
+// storeWorkflows reads the store of an engine, converting each workflow
+type storeWorkflows struct {
+	e *Engine
+}
+
+func (w storeWorkflows) Get(id string) (Workflow, bool) {
+	state, ok := w.e.store.Get(id)
+	if !ok {
+		return Workflow{}, false
+	}
+	return w.e.snapshot(state), true
+}
+
+func (w storeWorkflows) GetBySeq(seq int) (Workflow, bool) {
+	state, ok := w.e.store.GetBySeq(seq)
+	if !ok {
+		return Workflow{}, false
+	}
+	return w.e.snapshot(state), true
+}
+
+func (w storeWorkflows) ListByStatus(status string) []Workflow {
+	states := w.e.store.ListByStatus(status)
+	list := make([]Workflow, 0, len(states))
+	for _, state := range states {
+		list = append(list, w.e.snapshot(state))
+	}
+	return list
+}
+
+func (w storeWorkflows) All() iter.Seq[Workflow] {
+	return func(yield func(Workflow) bool) {
+		for state := range w.e.store.All() {
+			if !yield(w.e.snapshot(state)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Blobs is the object storage that archived workflow payloads are moved to
+type Blobs interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// payload holds the bulky parts of a workflow: the transcript, source lyrics, lyrics in every revision
+// and the step log
+// Everything else stays in the store so that archived workflows remain listed and filterable.
+type payload struct {
+	Transcript         string        `json:"transcript,omitempty"`
+	SourceLyrics       string        `json:"source_lyrics,omitempty"`
+	Lyrics             string        `json:"lyrics,omitempty"`
+	LyricsWithBrackets string        `json:"lyrics_with_brackets,omitempty"`
+	EditedLyrics       string        `json:"edited_lyrics,omitempty"`
+	LyricsIssues       []LyricsIssue `json:"lyrics_issues,omitempty"`
+	Segments           []SongSegment `json:"segments,omitempty"`
+	Variants           []SongVariant `json:"variants,omitempty"`
+	Steps              []StepRun     `json:"steps,omitempty"`
+}
+
+// SetArchive enables archival of old workflow payloads to blobs (see ArchiveBefore)
+func (s *Store) SetArchive(blobs Blobs) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = blobs
+}
+
+// ArchiveBefore moves the payload of finished workflows last updated (or restored) before
+// cutoff to the archive, gzip-compressed, and returns the number of workflows archived
+// Archived workflows are restored transparently by Get and GetBySeq.
+func (s *Store) ArchiveBefore(cutoff time.Time) (int, error) {
+	s.mu.RLock()
+	blobs := s.archive
+	var candidates []*WorkflowState
+	for _, state := range s.order {
+		if state.ArchivedAt == nil && state.IsTerminal() && state.UpdatedAt.Before(cutoff) &&
+			(state.RestoredAt == nil || state.RestoredAt.Before(cutoff)) {
+			candidates = append(candidates, state)
+		}
+	}
+	s.mu.RUnlock()
+	if blobs == nil || len(candidates) == 0 {
+		return 0, nil
+	}
+
+	archived := 0
+	for _, state := range candidates {
+		s.mu.RLock()
+		updatedAt := state.UpdatedAt
+		data, err := compressPayload(payloadOf(state))
+		s.mu.RUnlock()
+		if err != nil {
+			return archived, err
+		}
+		// Upload without holding the lock; the payload is only dropped if the workflow did not change meanwhile
+		if err := blobs.Put(archiveKey(state.ID), data); err != nil {
+			return archived, fmt.Errorf("failed to archive workflow %s: %w", state.ID, err)
+		}
+
+		s.mu.Lock()
+		if current, ok := s.workflows[state.ID]; ok && current == state && state.UpdatedAt.Equal(updatedAt) && state.ArchivedAt == nil {
+			now := time.Now()
+			s.update(state, func() {
+				state.setPayload(payload{})
+				state.ArchivedAt = &now
+			})
+			archived++
+		}
+		s.mu.Unlock()
+	}
+
+	if archived > 0 {
+		s.mu.Lock()
+		s.persist()
+		s.mu.Unlock()
+	}
+	return archived, nil
+}
+
+// restore brings back the archived payload of a workflow; it must be called without the lock held
+// Failures are logged and leave the workflow archived, without its payload.
+func (s *Store) restore(state *WorkflowState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.ArchivedAt == nil || s.archive == nil {
+		return
+	}
+
+	data, err := s.archive.Get(archiveKey(state.ID))
+	if err == nil {
+		var p payload
+		if p, err = decompressPayload(data); err == nil {
+			now := time.Now()
+			s.update(state, func() {
+				state.setPayload(p)
+				state.ArchivedAt = nil
+				state.RestoredAt = &now
+			})
+			s.persist()
+			_ = s.archive.Delete(archiveKey(state.ID))
+			return
+		}
+	}
+	slog.Error("Failed to restore archived workflow", "workflow_id", state.ID, "error", err)
+}
+
+// archiveKey is the blob key of a workflow's payload
+func archiveKey(id string) string {
+	return "workflows/" + id + ".json.gz"
+}
+
+func payloadOf(state *WorkflowState) payload {
+	return payload{
+		Transcript:         state.Transcript,
+		SourceLyrics:       state.SourceLyrics,
+		Lyrics:             state.Lyrics,
+		LyricsWithBrackets: state.LyricsWithBrackets,
+		EditedLyrics:       state.EditedLyrics,
+		LyricsIssues:       state.LyricsIssues,
+		Segments:           state.Segments,
+		Variants:           state.Variants,
+		Steps:              state.Steps,
+	}
+}
+
+func (w *WorkflowState) setPayload(p payload) {
+	w.Transcript = p.Transcript
+	w.SourceLyrics = p.SourceLyrics
+	w.Lyrics = p.Lyrics
+	w.LyricsWithBrackets = p.LyricsWithBrackets
+	w.EditedLyrics = p.EditedLyrics
+	w.LyricsIssues = p.LyricsIssues
+	w.Segments = p.Segments
+	w.Variants = p.Variants
+	w.Steps = p.Steps
+}
+
+func compressPayload(p payload) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressPayload(data []byte) (payload, error) {
+	var p payload
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return p, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return p, err
+	}
+	return p, json.Unmarshal(raw, &p)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"iter"
+	"sort"
+)
+
+// iteratePageSize is the number of workflows All reads under one lock acquisition
+const iteratePageSize = 100
+
+// ListPage returns up to limit workflows older than cursor, newest first, together with
+// the cursor of the next page (0 after the last page)
+// Cursors are sequence numbers: 0 starts at the newest workflow, and workflows created
+// while paging never shift the pages that follow.
+func (s *Store) ListPage(cursor, limit int) ([]*WorkflowState, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	end := len(s.order)
+	if cursor > 0 {
+		end = s.searchSeq(cursor)
+	}
+	start := max(end-max(limit, 0), 0)
+
+	page := make([]*WorkflowState, 0, end-start)
+	for i := end - 1; i >= start; i-- {
+		page = append(page, s.order[i])
+	}
+
+	next := 0
+	if start > 0 && len(page) > 0 {
+		next = page[len(page)-1].Seq
+	}
+	return page, next
+}
+
+// All iterates over all workflows, newest first, reading one page at a time
+// The lock is not held while the loop body runs, so it may save or delete workflows;
+// workflows created during the iteration are not visited.
+func (s *Store) All() iter.Seq[*WorkflowState] {
+	return s.Before(0)
+}
+
+// Before iterates like All over the workflows older than cursor (a ListPage cursor)
+func (s *Store) Before(cursor int) iter.Seq[*WorkflowState] {
+	return func(yield func(*WorkflowState) bool) {
+		cursor := cursor
+		for {
+			page, next := s.ListPage(cursor, iteratePageSize)
+			for _, state := range page {
+				if !yield(state) {
+					return
+				}
+			}
+			if next == 0 {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// searchSeq returns the index in order of the first workflow with a sequence number >= seq
+// It must be called with the lock held.
+func (s *Store) searchSeq(seq int) int {
+	return sort.Search(len(s.order), func(i int) bool {
+		return s.order[i].Seq >= seq
+	})
+}
+
+// index adds or replaces a workflow in the sequence index; it must be called with the write lock held
+func (s *Store) index(state *WorkflowState) {
+	i := s.searchSeq(state.Seq)
+	for ; i < len(s.order) && s.order[i].Seq == state.Seq; i++ {
+		if s.order[i].ID == state.ID {
+			s.order[i] = state
+			return
+		}
+	}
+	s.order = append(s.order, nil)
+	copy(s.order[i+1:], s.order[i:])
+	s.order[i] = state
+}
+
+// unindex removes a workflow from the sequence index; it must be called with the write lock held
+func (s *Store) unindex(state *WorkflowState) {
+	for i := s.searchSeq(state.Seq); i < len(s.order) && s.order[i].Seq == state.Seq; i++ {
+		if s.order[i].ID == state.ID {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// maxOperations bounds the operations kept; the oldest finished ones are dropped first
+const maxOperations = 2000
+
+// Operation is a slow action on a workflow (Suno generation after approval, stems) that API
+// clients poll until it is done (see workflow.StartOperation)
+type Operation struct {
+	ID         string           `json:"id"`
+	Kind       string           `json:"kind"`
+	WorkflowID string           `json:"workflow_id"`
+	Done       bool             `json:"done"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Error      string           `json:"error,omitempty"`  // why it failed, once done
+	Result     *OperationResult `json:"result,omitempty"` // what it produced, once done without error
+}
+
+// OperationResult is the outcome of a successful operation
+type OperationResult struct {
+	Status   string `json:"status"` // workflow status when the operation finished
+	ClipID   string `json:"clip_id,omitempty"`
+	AudioURL string `json:"audio_url,omitempty"`
+	VideoURL string `json:"video_url,omitempty"`
+	StemsURL string `json:"stems_url,omitempty"`
+}
+
+// SaveOperation stores or updates an operation
+func (s *Store) SaveOperation(op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.operations, func(o Operation) bool { return o.ID == op.ID }); i >= 0 {
+		s.operations[i] = op
+	} else {
+		s.operations = append(s.operations, op)
+	}
+	if len(s.operations) > maxOperations {
+		// Drop the oldest finished operation, or the oldest one when all are running
+		i := max(slices.IndexFunc(s.operations, func(o Operation) bool { return o.Done }), 0)
+		s.operations = slices.Delete(s.operations, i, i+1)
+	}
+	s.persist()
+}
+
+// GetOperation returns an operation by ID
+func (s *Store) GetOperation(id string) (Operation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, op := range s.operations {
+		if op.ID == id {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// ListOperations returns the operations of a workflow, oldest first
+func (s *Store) ListOperations(workflowID string) []Operation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []Operation
+	for _, op := range s.operations {
+		if op.WorkflowID == workflowID {
+			result = append(result, op)
+		}
+	}
+	return result
+}
+
+// PendingOperations returns the operations that are not done yet, oldest first
+func (s *Store) PendingOperations() []Operation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []Operation
+	for _, op := range s.operations {
+		if !op.Done {
+			result = append(result, op)
+		}
+	}
+	return result
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"workflower/lib/webhook"
+)
+
+// persistDelay is how long changes are collected before the snapshot file is rewritten, so that
+// bursts of saves (e.g. every poll of many generating songs) cost one write
+const persistDelay = time.Second
+
+// snapshot is the on-disk form of a Store
+type snapshot struct {
+	Workflows    []json.RawMessage          `json:"workflows"`
+	Deliveries   []webhook.Delivery         `json:"deliveries,omitempty"`
+	Audit        []AuditEntry               `json:"audit,omitempty"`
+	Seq          int                        `json:"seq"`
+	ProjectSeqs  map[string]int             `json:"project_seqs,omitempty"`
+	ChatPrefs    map[string]ChatPreferences `json:"chat_prefs,omitempty"`
+	Spend        map[string]MonthlySpend    `json:"spend,omitempty"`
+	SyncRecords  map[string]SyncRecord      `json:"sync_records,omitempty"`
+	Settings     *Settings                  `json:"settings,omitempty"`
+	Tags         *TagTaxonomy               `json:"tags,omitempty"`
+	Operations   []Operation                `json:"operations,omitempty"`
+	SunoAccounts []SunoAccount              `json:"suno_accounts,omitempty"`
+	QuotaHistory []QuotaSample              `json:"quota_history,omitempty"`
+}
+
+// OpenStore creates a store that is written to path shortly after every change (see Flush) and
+// restored from it on startup, so that workflows survive a restart
+// An empty path returns a purely in-memory store.
+func OpenStore(path string) (*Store, error) {
+	s := NewStore()
+	if path == "" {
+		return s, nil
+	}
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse store %s: %w", path, err)
+	}
+	for _, data := range snap.Workflows {
+		var state WorkflowState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse store %s: %w", path, err)
+		}
+		s.workflows[state.ID] = &state
+		s.encoded[state.ID] = data
+		s.index(&state)
+	}
+	s.deliveries = snap.Deliveries
+	s.audit = snap.Audit
+	s.seq = snap.Seq
+	for k, v := range snap.ProjectSeqs {
+		s.projectSeqs[k] = v
+	}
+	for k, v := range snap.ChatPrefs {
+		s.chatPrefs[k] = v
+	}
+	for k, v := range snap.Spend {
+		s.spend[k] = v
+	}
+	for k, v := range snap.SyncRecords {
+		s.syncRecords[k] = v
+	}
+	if snap.Settings != nil {
+		s.settings = *snap.Settings
+	}
+	if snap.Tags != nil {
+		s.tags = *snap.Tags
+	}
+	s.operations = snap.Operations
+	s.sunoAccounts = snap.SunoAccounts
+	s.quotaHistory = snap.QuotaHistory
+	return s, nil
+}
+
+// SetStateLock makes the store change (Save, archival) and encode saved workflows while holding
+// l, the lock that code changing or copying a workflow concurrently holds (the engine's, see
+// workflow.NewEngine)
+// Only the encoded copy is written to the snapshot file, never the workflow itself, so that
+// workflows can change while another one is being written.
+func (s *Store) SetStateLock(l sync.Locker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateLock = l
+}
+
+// update applies change, which sets fields of a saved workflow, and encodes the workflow, both
+// holding the state lock, so that the engine never copies it half-changed; it must be called
+// with the write lock held
+func (s *Store) update(state *WorkflowState, change func()) {
+	if s.stateLock != nil {
+		s.stateLock.Lock()
+		defer s.stateLock.Unlock()
+	}
+	change()
+	s.encode(state)
+}
+
+// encode keeps the current form of a workflow for the snapshot file; it must be called with the
+// write lock and the state lock held (see update)
+func (s *Store) encode(state *WorkflowState) {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		slog.Error("Failed to encode workflow", "workflow_id", state.ID, "error", err)
+		return
+	}
+	s.encoded[state.ID] = data
+}
+
+// persist schedules a write of the store to its file persistDelay from now, unless one is
+// already pending; it must be called with the write lock held
+func (s *Store) persist() {
+	if s.path == "" || s.dirty {
+		return
+	}
+	s.dirty = true
+	time.AfterFunc(persistDelay, func() { _ = s.Flush() })
+}
+
+// Flush writes pending changes to the snapshot file at once, e.g. before the process exits
+// Failures are logged and returned: the in-memory state stays authoritative until the next write.
+func (s *Store) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	if !s.dirty {
+		err := s.persistErr
+		s.mu.Unlock()
+		return err
+	}
+	s.dirty = false
+	data, err := s.marshalSnapshot()
+	path := s.path
+	s.mu.Unlock()
+
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	s.mu.Lock()
+	s.persistErr = err
+	s.mu.Unlock()
+	if err != nil {
+		slog.Error("Failed to persist store", "path", path, "error", err)
+	}
+	return err
+}
+
+// marshalSnapshot encodes the store, newest workflow first; it must be called with the lock held
+func (s *Store) marshalSnapshot() ([]byte, error) {
+	snap := snapshot{
+		Workflows:    make([]json.RawMessage, 0, len(s.order)),
+		Deliveries:   s.deliveries,
+		Audit:        s.audit,
+		Seq:          s.seq,
+		ProjectSeqs:  s.projectSeqs,
+		ChatPrefs:    s.chatPrefs,
+		Spend:        s.spend,
+		SyncRecords:  s.syncRecords,
+		Operations:   s.operations,
+		SunoAccounts: s.sunoAccounts,
+		QuotaHistory: s.quotaHistory,
+	}
+	if s.settings.UpdatedAt != nil {
+		snap.Settings = &s.settings
+	}
+	if s.tags.SyncedAt != nil {
+		snap.Tags = &s.tags
+	}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if data, ok := s.encoded[s.order[i].ID]; ok {
+			snap.Workflows = append(snap.Workflows, data)
+		}
+	}
+	return json.Marshal(snap)
+}
+
+// Check verifies that the store can still be written: the last snapshot write succeeded, the
+// directory of the snapshot file accepts new files and the archive round-trips a probe blob
+func (s *Store) Check() error {
+	s.mu.RLock()
+	path, persistErr, archive := s.path, s.persistErr, s.archive
+	s.mu.RUnlock()
+
+	if persistErr != nil {
+		return fmt.Errorf("last write of %s failed: %w", path, persistErr)
+	}
+	if path != "" {
+		f, err := os.CreateTemp(filepath.Dir(path), ".health-*")
+		if err != nil {
+			return fmt.Errorf("store directory is not writable: %w", err)
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	if archive != nil {
+		probe := []byte(time.Now().Format(time.RFC3339Nano))
+		if err := archive.Put(healthProbeKey, probe); err != nil {
+			return fmt.Errorf("archive is not writable: %w", err)
+		}
+		data, err := archive.Get(healthProbeKey)
+		if err != nil {
+			return fmt.Errorf("archive is not readable: %w", err)
+		}
+		if !bytes.Equal(data, probe) {
+			return errors.New("archive returned a different probe than written")
+		}
+		if err := archive.Delete(healthProbeKey); err != nil {
+			return fmt.Errorf("archive probe could not be deleted: %w", err)
+		}
+	}
+	return nil
+}
+
+// healthProbeKey is the blob written and removed again by Check
+const healthProbeKey = "health-probe"
+
+// writeFileAtomic replaces path with data so that a crash never leaves a partial file
+func writeFileAtomic(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// maxQuotaSamples bounds the quota history, about three months of hourly samples
+const maxQuotaSamples = 2200
+
+// QuotaSample is the Suno quota read at one point in time
+type QuotaSample struct {
+	At           time.Time `json:"at"`
+	CreditsLeft  int       `json:"credits_left"`
+	MonthlyLimit int       `json:"monthly_limit,omitempty"`
+	MonthlyUsage int       `json:"monthly_usage,omitempty"`
+}
+
+// AddQuotaSample appends a sample to the quota history, dropping the oldest beyond maxQuotaSamples
+func (s *Store) AddQuotaSample(sample QuotaSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotaHistory = append(s.quotaHistory, sample)
+	if len(s.quotaHistory) > maxQuotaSamples {
+		s.quotaHistory = slices.Delete(s.quotaHistory, 0, len(s.quotaHistory)-maxQuotaSamples)
+	}
+	s.persist()
+}
+
+// QuotaHistory returns the samples taken since the given time, oldest first
+func (s *Store) QuotaHistory(since time.Time) []QuotaSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, _ := slices.BinarySearchFunc(s.quotaHistory, since, func(sample QuotaSample, t time.Time) int {
+		return sample.At.Compare(t)
+	})
+	return slices.Clone(s.quotaHistory[i:])
+}
+
+// LastQuotaSample returns the newest sample of the quota history
+func (s *Store) LastQuotaSample() (QuotaSample, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.quotaHistory) == 0 {
+		return QuotaSample{}, false
+	}
+	return s.quotaHistory[len(s.quotaHistory)-1], true
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// Settings holds the runtime overrides of the configuration saved on /admin
+// nil fields keep the value configured in the environment.
+type Settings struct {
+	AutoApprove    *bool          `json:"auto_approve,omitempty"`
+	DefaultPremium *bool          `json:"default_premium,omitempty"`
+	PollInterval   *time.Duration `json:"poll_interval,omitempty"`
+	RetentionDays  *int           `json:"retention_days,omitempty"`
+	HouseStyle     *string        `json:"house_style,omitempty"`
+	LowCredits     *int           `json:"low_credits,omitempty"`
+	Presets        []Preset       `json:"presets,omitempty"`
+	UpdatedAt      *time.Time     `json:"updated_at,omitempty"`
+	UpdatedBy      string         `json:"updated_by,omitempty"`
+}
+
+// Preset is a named set of Suno properties reviewers can apply on the review page
+type Preset struct {
+	Name       string         `json:"name"`
+	Properties SunoProperties `json:"properties"`
+}
+
+// Settings returns the saved runtime settings (zero value if none are saved)
+func (s *Store) Settings() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	settings := s.settings
+	settings.Presets = slices.Clone(settings.Presets)
+	return settings
+}
+
+// SaveSettings replaces the runtime settings
+func (s *Store) SaveSettings(settings Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings.Presets = slices.Clone(settings.Presets)
+	s.settings = settings
+	s.persist()
+}
@@ -0,0 +1,73 @@
+package storage
+
+// Workflow statuses
+const (
+	StatusProcessing        = "processing"
+	StatusAwaitingReview    = "awaiting_review"
+	StatusApproved          = "approved"
+	StatusGenerating        = "generating"
+	StatusCompleted         = "completed"
+	StatusFailed            = "failed"
+	StatusRejected          = "rejected"
+	StatusBlockedModeration = "blocked_moderation" // flagged by moderation, never sent to Suno
+	StatusCancelled         = "cancelled"
+	StatusBlockedAuth       = "blocked_auth" // approved, parked until the Suno session is valid again
+	StatusExpired           = "expired"      // never reviewed within WORKFLOW_EXPIRE_AFTER
+)
+
+// StatusInfo describes a workflow status: how it is presented and whether it is final
+type StatusInfo struct {
+	Name     string
+	Label    string // short label for badges and lists
+	Heading  string // status page heading
+	Color    string // Tailwind color name (green, rose, gray, amber, orange, violet)
+	Icon     string // icon name (check, alert, cross, eye, spinner)
+	Terminal bool
+	Hidden   bool // left out of unfiltered lists, shown under its own filter only
+}
+
+// TextClass returns the Tailwind text color class of the status
+func (s StatusInfo) TextClass() string {
+	return "text-" + s.Color + "-400"
+}
+
+// BgClass returns the Tailwind background class of the status
+func (s StatusInfo) BgClass() string {
+	return "bg-" + s.Color + "-500/20"
+}
+
+// BadgeClass returns the Tailwind classes of a status badge
+func (s StatusInfo) BadgeClass() string {
+	return s.BgClass() + " " + s.TextClass()
+}
+
+// statusRegistry lists every known status in lifecycle order
+var statusRegistry = []StatusInfo{
+	{Name: StatusProcessing, Label: "processing", Heading: "Processing...", Color: "violet", Icon: "spinner"},
+	{Name: StatusAwaitingReview, Label: "awaiting review", Heading: "Awaiting Review", Color: "amber", Icon: "eye"},
+	{Name: StatusBlockedAuth, Label: "waiting for Suno", Heading: "Waiting for Suno Login", Color: "amber", Icon: "alert"},
+	{Name: StatusApproved, Label: "approved", Heading: "Approved", Color: "violet", Icon: "spinner"},
+	{Name: StatusGenerating, Label: "generating", Heading: "Generating...", Color: "violet", Icon: "spinner"},
+	{Name: StatusCompleted, Label: "completed", Heading: "Song Created!", Color: "green", Icon: "check", Terminal: true},
+	{Name: StatusFailed, Label: "failed", Heading: "Generation Failed", Color: "rose", Icon: "alert", Terminal: true},
+	{Name: StatusRejected, Label: "rejected", Heading: "Workflow Rejected", Color: "gray", Icon: "cross", Terminal: true},
+	{Name: StatusBlockedModeration, Label: "blocked", Heading: "Blocked by Moderation", Color: "orange", Icon: "alert", Terminal: true},
+	{Name: StatusCancelled, Label: "cancelled", Heading: "Workflow Cancelled", Color: "gray", Icon: "cross", Terminal: true},
+	{Name: StatusExpired, Label: "expired", Heading: "Review Expired", Color: "gray", Icon: "cross", Terminal: true, Hidden: true},
+}
+
+// LookupStatus returns the registry entry of a status
+// Unknown statuses are presented as in-progress, labelled with their name
+func LookupStatus(name string) StatusInfo {
+	for _, info := range statusRegistry {
+		if info.Name == name {
+			return info
+		}
+	}
+	return StatusInfo{Name: name, Label: name, Heading: name, Color: "violet", Icon: "spinner"}
+}
+
+// Statuses returns every registered status in lifecycle order
+func Statuses() []StatusInfo {
+	return append([]StatusInfo(nil), statusRegistry...)
+}
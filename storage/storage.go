@@ -1,37 +1,280 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"workflower/lib/webhook"
+)
+
+const (
+	// maxWebhookDeliveries bounds the in-memory webhook delivery log
+	maxWebhookDeliveries = 500
+	// maxAuditEntries bounds the in-memory audit log
+	maxAuditEntries = 5000
 )
 
 // WorkflowState represents the state of a workflow instance
 type WorkflowState struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Status    string    `json:"status"` // pending, awaiting_review, approved, rejected, completed, failed
+	ID         string    `json:"id"`
+	Seq        int       `json:"seq"`                   // global sequence number, assigned on first save
+	ProjectSeq int       `json:"project_seq,omitempty"` // sequence number within Project
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Status     string    `json:"status"`               // see status.go
+	RequestID  string    `json:"request_id,omitempty"` // HTTP request or Telegram update that started the current run
+	Session    string    `json:"session,omitempty"`    // hash of the browser session that started it (SESSION_SCOPED_LIST)
+
+	// Reviewer: a web user name or "tg:<chat id>"; empty means anyone may review
+	Assignee         string     `json:"assignee,omitempty"`
+	AssignedAt       *time.Time `json:"assigned_at,omitempty"`        // when the current reviewer was asked to review
+	ReviewNotifiedAt *time.Time `json:"review_notified_at,omitempty"` // when the review was last announced on Telegram
+	EscalationLevel  int        `json:"escalation_level,omitempty"`   // 0 assignee, 1 backup reviewer, 2 admin channel
+
+	// Deadline and reminder bookkeeping
+	DueAt             *time.Time `json:"due_at,omitempty"`
+	ReminderSentAt    *time.Time `json:"reminder_sent_at,omitempty"`
+	OverdueNotifiedAt *time.Time `json:"overdue_notified_at,omitempty"`
 
 	// Input
-	TaskDescription string `json:"task_description"`
-	IsPremium       bool   `json:"is_premium"`
-	AudioFilePath   string `json:"audio_file_path,omitempty"`
-	AudioFileName   string `json:"audio_file_name,omitempty"`
+	Project          string `json:"project,omitempty"`
+	ExternalRef      string `json:"external_ref,omitempty"` // integrator's reference (CRM ticket, order number)
+	ClonedFrom       string `json:"cloned_from,omitempty"`  // ID of the workflow this one was cloned from
+	TaskDescription  string `json:"task_description"`
+	Transcript       string `json:"transcript,omitempty"`      // pasted chat or diary the task description is summarized from
+	LyricsImported   bool   `json:"lyrics_imported,omitempty"` // Lyrics were written by the user, not generated
+	SourceLyrics     string `json:"source_lyrics,omitempty"`   // lyrics of the extended or covered track whose structure is matched
+	IsPremium        bool   `json:"is_premium"`
+	Language         string `json:"language,omitempty"`          // lyrics language name, e.g. "Spanish"
+	LanguageDetected bool   `json:"language_detected,omitempty"` // Language was detected from the task description
+	LyricsEngine     string `json:"lyrics_engine,omitempty"`     // "openai" or "suno"
+	SunoModel        string `json:"suno_model,omitempty"`        // model asked for at start, copied into the generated properties
+	SunoPersonaID    string `json:"suno_persona_id,omitempty"`   // persona asked for at start (premium), copied into the generated persona/inspo
+	AudioFilePath    string `json:"audio_file_path,omitempty"`
+	AudioFileName    string `json:"audio_file_name,omitempty"`
 
 	// Generated content
-	Lyrics              string `json:"lyrics,omitempty"`
-	LyricsWithBrackets  string `json:"lyrics_with_brackets,omitempty"`
-	SunoProperties      *SunoProperties `json:"suno_properties,omitempty"`
-	PersonaInspo        *PersonaInspo   `json:"persona_inspo,omitempty"`
+	Lyrics             string          `json:"lyrics,omitempty"`
+	LyricsWithBrackets string          `json:"lyrics_with_brackets,omitempty"`
+	SunoProperties     *SunoProperties `json:"suno_properties,omitempty"`
+	PersonaInspo       *PersonaInspo   `json:"persona_inspo,omitempty"`
 
 	// Human-in-the-loop edits
-	EditedLyrics       string          `json:"edited_lyrics,omitempty"`
-	EditedProperties   *SunoProperties `json:"edited_properties,omitempty"`
+	EditedLyrics     string           `json:"edited_lyrics,omitempty"`
+	EditedProperties *SunoProperties  `json:"edited_properties,omitempty"`
+	LyricsIssues     []LyricsIssue    `json:"lyrics_issues,omitempty"`    // validation of the lyrics under review
+	LintOverridden   bool             `json:"lint_overridden,omitempty"`  // reviewer approved despite bracket lint
+	VariantB         *SunoProperties  `json:"variant_b,omitempty"`        // second property set of an A/B submission
+	EditedTitle      string           `json:"edited_title,omitempty"`     // title sent to Suno instead of the rendered one
+	ScreeningHits    []ScreeningHit   `json:"screening_hits,omitempty"`   // screened terms in the title and style tags under review
+	SimilarityFlags  []SimilarityFlag `json:"similarity_flags,omitempty"` // passages the LLM believes copied from published songs
+	DraftSavedAt     *time.Time       `json:"draft_saved_at,omitempty"`   // when the edits above were last saved without approving
+
+	// Notes of collaborators (why it was rejected, what to tweak next time), oldest first
+	Comments []Comment `json:"comments,omitempty"`
+
+	// Naming (rendered from the configured naming template)
+	Title string `json:"title,omitempty"`
+
+	// Spend incurred so far and estimated for the Suno submission
+	Usage Usage `json:"usage"`
+
+	// Long-song orchestration: the first clip is generated, extended once per
+	// remaining segment and the final extension is concatenated into the full song
+	LongSong     bool          `json:"long_song,omitempty"`
+	Segments     []SongSegment `json:"segments,omitempty"`
+	ConcatClipID string        `json:"concat_clip_id,omitempty"`
+
+	// A/B submission: the same lyrics generated with the edited properties (A) and VariantB
+	Variants []SongVariant `json:"variants,omitempty"`
 
 	// Suno result
-	SunoJobID  string `json:"suno_job_id,omitempty"`
-	SunoResult string `json:"suno_result,omitempty"`
-	ErrorMsg   string `json:"error_msg,omitempty"`
+	SunoJobID string    `json:"suno_job_id,omitempty"`
+	Poll      *SunoPoll `json:"poll,omitempty"` // clip currently being polled, resumed after a restart
+
+	// Engine step runs, in start order
+	Steps      []StepRun `json:"steps,omitempty"`
+	SunoResult string    `json:"suno_result,omitempty"`
+	AudioURL   string    `json:"audio_url,omitempty"`
+	VideoURL   string    `json:"video_url,omitempty"`
+	ErrorMsg   string    `json:"error_msg,omitempty"`
+
+	// Moderation categories that blocked the workflow
+	ModerationCategories []string `json:"moderation_categories,omitempty"`
+
+	// Stems of the final clip, generated after completion when requested
+	GenerateStems bool   `json:"generate_stems,omitempty"`
+	StemsClipID   string `json:"stems_clip_id,omitempty"`
+	StemsURL      string `json:"stems_url,omitempty"`
+	StemsError    string `json:"stems_error,omitempty"`
+
+	// Suno persona created from the final clip, reused by later premium workflows
+	CreatedPersonaID string `json:"created_persona_id,omitempty"`
+
+	// Expiry of drafts never reviewed (see WORKFLOW_EXPIRE_AFTER)
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	PurgedAt  *time.Time `json:"purged_at,omitempty"` // transcript and lyrics dropped on expiry
+
+	// Archival: the lyrics and step log of old finished workflows live in the archive (see archive.go)
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	RestoredAt *time.Time `json:"restored_at,omitempty"` // last brought back from the archive
+}
+
+// IsTerminal reports whether the workflow has finished (successfully or not)
+func (w *WorkflowState) IsTerminal() bool {
+	return LookupStatus(w.Status).Terminal
+}
+
+// IsOverdue reports whether an unfinished workflow is past its due date
+func (w *WorkflowState) IsOverdue() bool {
+	return w.DueAt != nil && !w.IsTerminal() && time.Now().After(*w.DueAt)
+}
+
+// SegmentsDone returns the number of long-song segments whose clip has completed
+func (w *WorkflowState) SegmentsDone() int {
+	done := 0
+	for _, seg := range w.Segments {
+		if seg.Status == "complete" {
+			done++
+		}
+	}
+	return done
+}
+
+// SongSegment is one part of a long song and the Suno clip generated for it
+type SongSegment struct {
+	Index    int     `json:"index"` // 1-based
+	Lyrics   string  `json:"lyrics"`
+	ClipID   string  `json:"clip_id,omitempty"`
+	Status   string  `json:"status"` // pending, generating, complete
+	Duration float64 `json:"duration,omitempty"`
+	AudioURL string  `json:"audio_url,omitempty"`
+}
+
+// Lyrics issue severities
+const (
+	IssueError   = "error"   // blocks submission to Suno
+	IssueLint    = "lint"    // bracket problem; blocks submission unless the reviewer overrides it
+	IssueWarning = "warning" // shown to the reviewer only
+)
+
+// LyricsIssue is a problem found by the lyrics structure validation
+type LyricsIssue struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"` // 1-based, 0 for the whole text
+	Message  string `json:"message"`
+}
+
+// LyricsLine is a line of the lyrics under review with the issues found on it
+type LyricsLine struct {
+	Number int
+	Text   string
+	Issues []LyricsIssue
+}
+
+// HasLyricsErrors reports whether the lyrics under review have blocking issues
+func (w *WorkflowState) HasLyricsErrors() bool {
+	return w.hasLyricsIssue(IssueError)
+}
+
+// HasLyricsLint reports whether the lyrics under review have bracket lint (see IssueLint)
+func (w *WorkflowState) HasLyricsLint() bool {
+	return w.hasLyricsIssue(IssueLint)
+}
+
+func (w *WorkflowState) hasLyricsIssue(severity string) bool {
+	for _, issue := range w.LyricsIssues {
+		if issue.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// AnnotatedLyrics returns the lines of the lyrics under review with their issues, for highlighting
+// them inline; nil when no issue points at a line
+func (w *WorkflowState) AnnotatedLyrics() []LyricsLine {
+	byLine := make(map[int][]LyricsIssue)
+	for _, issue := range w.LyricsIssues {
+		if issue.Line > 0 {
+			byLine[issue.Line] = append(byLine[issue.Line], issue)
+		}
+	}
+	if len(byLine) == 0 {
+		return nil
+	}
+
+	var lines []LyricsLine
+	for i, text := range strings.Split(w.EditedLyrics, "\n") {
+		lines = append(lines, LyricsLine{Number: i + 1, Text: text, Issues: byLine[i+1]})
+	}
+	return lines
+}
+
+// ScreeningHit is a term of the screening list found in the title or the style tags under review
+type ScreeningHit struct {
+	Field       string `json:"field"` // review form field, e.g. "Title" or "Style (B)"
+	Term        string `json:"term"`
+	Alternative string `json:"alternative,omitempty"` // suggested wording, empty to drop the term
+}
+
+// Message describes the hit for the reviewer
+func (h ScreeningHit) Message() string {
+	if h.Alternative == "" {
+		return fmt.Sprintf("%s contains %q, which Suno may reject; remove it", h.Field, h.Term)
+	}
+	return fmt.Sprintf("%s contains %q, which Suno may reject; try %q instead", h.Field, h.Term, h.Alternative)
+}
+
+// SimilarityFlag is a passage of the lyrics the LLM similarity check believes copied from a
+// published song
+type SimilarityFlag struct {
+	Passage    string  `json:"passage"` // lines as they appeared in the lyrics
+	Source     string  `json:"source"`  // song and artist it resembles
+	Confidence float64 `json:"confidence"`
+}
+
+// SongVariant is one property set of an A/B submission and the clips Suno generated for it
+type SongVariant struct {
+	Label      string          `json:"label"` // "A" or "B"
+	Properties *SunoProperties `json:"properties"`
+	Clips      []SongClip      `json:"clips,omitempty"`
+}
+
+// SongClip is a clip generated by Suno
+type SongClip struct {
+	ID       string  `json:"id"`
+	Status   string  `json:"status"` // Suno clip status: submitted, queue, streaming, complete, error
+	AudioURL string  `json:"audio_url,omitempty"`
+	VideoURL string  `json:"video_url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// Comment is a note left on a workflow
+type Comment struct {
+	Author string    `json:"author"` // identity of the viewer who wrote it
+	At     time.Time `json:"at"`
+	Text   string    `json:"text"`
+}
+
+// StepRun records one execution of an engine step
+type StepRun struct {
+	Step       string     `json:"step"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // nil while running
+	Error      string     `json:"error,omitempty"`
+}
+
+// SunoPoll is the persisted state of a clip completion poll
+type SunoPoll struct {
+	ClipID     string    `json:"clip_id"`
+	Attempts   int       `json:"attempts"`
+	NextPollAt time.Time `json:"next_poll_at"`
+	StartedAt  time.Time `json:"started_at"` // start of the not-found grace period (see SUNO_NOT_FOUND_GRACE)
 }
 
 // SunoProperties holds the Suno configuration
@@ -41,40 +284,150 @@ type SunoProperties struct {
 	LyricsMode     string  `json:"lyrics_mode"`
 	Weirdness      float64 `json:"weirdness"`
 	StyleInfluence string  `json:"style_influence"`
+	Model          string  `json:"model,omitempty"` // Suno model, SUNO_MODEL when empty
 }
 
 // PersonaInspo holds premium Suno features
 type PersonaInspo struct {
-	Persona string `json:"persona"`
-	Inspo   string `json:"inspo"`
+	Persona   string `json:"persona"`
+	Inspo     string `json:"inspo"`
+	PersonaID string `json:"persona_id,omitempty"` // Suno persona the song is generated with, none when empty
+}
+
+// Usage tracks the LLM tokens and Suno credits of a workflow
+type Usage struct {
+	PromptTokens         int     `json:"prompt_tokens"`
+	CompletionTokens     int     `json:"completion_tokens"`
+	LLMCostUSD           float64 `json:"llm_cost_usd"`
+	EstimatedSunoCredits int     `json:"estimated_suno_credits"`
+	SunoCredits          int     `json:"suno_credits"` // charged on submission
+}
+
+// MonthlySpend aggregates spend over one calendar month ("2006-01")
+type MonthlySpend struct {
+	Month            string  `json:"month"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	LLMCostUSD       float64 `json:"llm_cost_usd"`
+	SunoCredits      int     `json:"suno_credits"`
+}
+
+// AuditEntry records who did what to a workflow
+type AuditEntry struct {
+	At         time.Time `json:"at"`
+	WorkflowID string    `json:"workflow_id"`
+	Seq        int       `json:"seq"`
+	Action     string    `json:"action"` // status_changed, assigned, escalated, deleted, reviewed
+	Actor      string    `json:"actor"`  // identity, or "engine" / "escalation" for automatic actions
+	Detail     string    `json:"detail,omitempty"`
+
+	TurnaroundMS int64 `json:"turnaround_ms,omitempty"` // reviewed: time the reviewer took
+}
+
+// ChatPreferences holds per-chat Telegram settings
+type ChatPreferences struct {
+	Timezone   string `json:"timezone,omitempty"`
+	LinkedUser string `json:"linked_user,omitempty"` // web account bound to the chat with /link
+	Language   string `json:"language,omitempty"`    // message catalog chosen with /language
+	// DetectedLanguage is the language of the chat's last workflow request, used without Language
+	DetectedLanguage string `json:"detected_language,omitempty"`
 }
 
 // Store provides thread-safe in-memory storage for workflow states
 type Store struct {
-	mu        sync.RWMutex
-	workflows map[string]*WorkflowState
+	mu           sync.RWMutex
+	workflows    map[string]*WorkflowState
+	order        []*WorkflowState // workflows by ascending sequence number (see ListPage)
+	deliveries   []webhook.Delivery
+	audit        []AuditEntry
+	seq          int
+	projectSeqs  map[string]int
+	chatPrefs    map[string]ChatPreferences
+	spend        map[string]MonthlySpend
+	syncRecords  map[string]SyncRecord // by remote path (see sync.go)
+	settings     Settings              // runtime overrides of the configuration (see settings.go)
+	tags         TagTaxonomy           // style tag taxonomy (see tags.go)
+	operations   []Operation           // polled by API clients, oldest first (see operations.go)
+	sunoAccounts []SunoAccount         // quotas of the Suno account pool (see sunoaccounts.go)
+	quotaHistory []QuotaSample         // Suno credits over time, oldest first (see quota.go)
+	path         string                // snapshot file, empty for memory only (see OpenStore)
+	persistErr   error                 // failure of the last snapshot write, nil once a write succeeds
+	archive      Blobs                 // archived workflow payloads, nil when archival is disabled
+
+	encoded   map[string]json.RawMessage // workflows as of their last Save, written to the snapshot file
+	stateLock sync.Locker                // held while a workflow is encoded (see SetStateLock)
+	dirty     bool                       // changes not yet written to the snapshot file (see persist)
+	writeMu   sync.Mutex                 // serializes snapshot writes (see Flush)
 }
 
-// NewStore creates a new in-memory store
+// NewStore creates a new in-memory store; OpenStore adds a snapshot file
 func NewStore() *Store {
 	return &Store{
-		workflows: make(map[string]*WorkflowState),
+		workflows:   make(map[string]*WorkflowState),
+		projectSeqs: make(map[string]int),
+		chatPrefs:   make(map[string]ChatPreferences),
+		spend:       make(map[string]MonthlySpend),
+		syncRecords: make(map[string]SyncRecord),
+		encoded:     make(map[string]json.RawMessage),
 	}
 }
 
 // Save stores or updates a workflow state
+// New workflows get monotonically increasing global and per-project sequence numbers
 func (s *Store) Save(state *WorkflowState) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	state.UpdatedAt = time.Now()
+	existing, exists := s.workflows[state.ID]
+	if exists && existing.Seq != state.Seq {
+		s.unindex(existing)
+	}
+	s.update(state, func() {
+		if !exists && state.Seq == 0 {
+			s.assignSequence(state)
+		}
+		state.UpdatedAt = time.Now()
+	})
 	s.workflows[state.ID] = state
+	s.index(state)
+	s.persist()
+}
+
+// assignSequence must be called with the write lock held
+func (s *Store) assignSequence(state *WorkflowState) {
+	s.seq++
+	state.Seq = s.seq
+	if state.Project != "" {
+		s.projectSeqs[state.Project]++
+		state.ProjectSeq = s.projectSeqs[state.Project]
+	}
 }
 
-// Get retrieves a workflow state by ID
+// GetBySeq retrieves a workflow state by its global sequence number, restoring its payload when it was archived
+func (s *Store) GetBySeq(seq int) (*WorkflowState, bool) {
+	s.mu.RLock()
+	var state *WorkflowState
+	if i := s.searchSeq(seq); i < len(s.order) && s.order[i].Seq == seq {
+		state = s.order[i]
+	}
+	archived := state != nil && state.ArchivedAt != nil
+	s.mu.RUnlock()
+
+	if archived {
+		s.restore(state)
+	}
+	return state, state != nil
+}
+
+// Get retrieves a workflow state by ID, restoring its payload when it was archived
 func (s *Store) Get(id string) (*WorkflowState, bool) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 	state, ok := s.workflows[id]
+	archived := ok && state.ArchivedAt != nil
+	s.mu.RUnlock()
+
+	if archived {
+		s.restore(state)
+	}
 	return state, ok
 }
 
@@ -82,32 +435,144 @@ func (s *Store) Get(id string) (*WorkflowState, bool) {
 func (s *Store) Delete(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.workflows, id)
+	if state, ok := s.workflows[id]; ok {
+		s.unindex(state)
+		delete(s.workflows, id)
+		delete(s.encoded, id)
+		if state.ArchivedAt != nil && s.archive != nil {
+			_ = s.archive.Delete(archiveKey(id))
+		}
+	}
+	s.persist()
 }
 
-// List returns all workflow states
-func (s *Store) List() []*WorkflowState {
+// ListByStatus returns workflow states with a specific status, newest first
+func (s *Store) ListByStatus(status string) []*WorkflowState {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	
-	result := make([]*WorkflowState, 0, len(s.workflows))
-	for _, state := range s.workflows {
-		result = append(result, state)
+
+	var result []*WorkflowState
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if s.order[i].Status == status {
+			result = append(result, s.order[i])
+		}
 	}
 	return result
 }
 
-// ListByStatus returns workflow states with a specific status
-func (s *Store) ListByStatus(status string) []*WorkflowState {
+// AddWebhookDelivery appends a webhook delivery to the bounded delivery log
+func (s *Store) AddWebhookDelivery(delivery webhook.Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries = append(s.deliveries, delivery)
+	if len(s.deliveries) > maxWebhookDeliveries {
+		s.deliveries = s.deliveries[len(s.deliveries)-maxWebhookDeliveries:]
+	}
+	s.persist()
+}
+
+// ListWebhookDeliveries returns the delivery log, newest first
+func (s *Store) ListWebhookDeliveries() []webhook.Delivery {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	
-	var result []*WorkflowState
-	for _, state := range s.workflows {
-		if state.Status == status {
-			result = append(result, state)
+
+	result := make([]webhook.Delivery, 0, len(s.deliveries))
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		result = append(result, s.deliveries[i])
+	}
+	return result
+}
+
+// GetChatPreferences returns the preferences of a Telegram chat (zero value if none are set)
+func (s *Store) GetChatPreferences(chatID string) ChatPreferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chatPrefs[chatID]
+}
+
+// SaveChatPreferences stores the preferences of a Telegram chat
+func (s *Store) SaveChatPreferences(chatID string, prefs ChatPreferences) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatPrefs[chatID] = prefs
+	s.persist()
+}
+
+// LinkedChat returns the Telegram chat bound to a web account, if any
+func (s *Store) LinkedChat(user string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for chatID, prefs := range s.chatPrefs {
+		if prefs.LinkedUser == user {
+			return chatID, true
 		}
 	}
+	return "", false
+}
+
+// AddSpend adds delta to the cumulative spend of delta.Month
+func (s *Store) AddSpend(delta MonthlySpend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.spend[delta.Month]
+	total.Month = delta.Month
+	total.PromptTokens += delta.PromptTokens
+	total.CompletionTokens += delta.CompletionTokens
+	total.LLMCostUSD += delta.LLMCostUSD
+	total.SunoCredits += delta.SunoCredits
+	s.spend[delta.Month] = total
+	s.persist()
+}
+
+// GetMonthlySpend returns the cumulative spend of a month (zero value if nothing was spent)
+func (s *Store) GetMonthlySpend(month string) MonthlySpend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total := s.spend[month]
+	total.Month = month
+	return total
+}
+
+// ListMonthlySpend returns the spend of every recorded month, newest first
+func (s *Store) ListMonthlySpend() []MonthlySpend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]MonthlySpend, 0, len(s.spend))
+	for _, total := range s.spend {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Month > result[j].Month
+	})
 	return result
 }
 
+// AddAuditEntry appends an entry to the bounded audit log
+func (s *Store) AddAuditEntry(entry AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.audit = append(s.audit, entry)
+	if len(s.audit) > maxAuditEntries {
+		s.audit = s.audit[len(s.audit)-maxAuditEntries:]
+	}
+	s.persist()
+}
+
+// ListAuditEntries returns the audit log of a workflow (or of all workflows when workflowID is empty), newest first
+func (s *Store) ListAuditEntries(workflowID string) []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if workflowID == "" || s.audit[i].WorkflowID == workflowID {
+			result = append(result, s.audit[i])
+		}
+	}
+	return result
+}
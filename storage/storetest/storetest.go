@@ -0,0 +1,202 @@
+// Package storetest is the conformance suite of storage.Blobs implementations (the object
+// storage archived workflow payloads are moved to). Run it from a test of the implementation:
+//
+//	func TestBlobs(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storage.Blobs { return newBucket(t) })
+//	}
+//
+// blob.Dir passes it; new expectations of the store are added here first.
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"workflower/storage"
+)
+
+// Factory returns an empty object store; it is called once per subtest
+// Cleanup belongs in t.Cleanup.
+type Factory func(t *testing.T) storage.Blobs
+
+// Run checks the behavior the store relies on, each in its own subtest
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+	tests := []struct {
+		name string
+		run  func(t *testing.T, blobs storage.Blobs)
+	}{
+		{"GetMissing", testGetMissing},
+		{"PutGet", testPutGet},
+		{"Overwrite", testOverwrite},
+		{"EmptyBlob", testEmptyBlob},
+		{"NestedKeys", testNestedKeys},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"NoAliasing", testNoAliasing},
+		{"Concurrent", testConcurrent},
+		{"ArchiveRoundTrip", testArchiveRoundTrip},
+		{"StoreCheck", testStoreCheck},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory(t))
+		})
+	}
+}
+
+// testGetMissing: a key never stored is an error, not an empty blob
+func testGetMissing(t *testing.T, blobs storage.Blobs) {
+	if data, err := blobs.Get("workflows/missing.json.gz"); err == nil {
+		t.Fatalf("Get of a missing key returned %d bytes and no error", len(data))
+	}
+}
+
+// testPutGet: binary data comes back byte for byte
+func testPutGet(t *testing.T, blobs storage.Blobs) {
+	data := make([]byte, 64<<10)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	mustPut(t, blobs, "workflows/a.json.gz", data)
+	expectBlob(t, blobs, "workflows/a.json.gz", data)
+}
+
+// testOverwrite: Put replaces the previous blob, including with a shorter one
+func testOverwrite(t *testing.T, blobs storage.Blobs) {
+	mustPut(t, blobs, "workflows/a.json.gz", []byte("first version, longer"))
+	mustPut(t, blobs, "workflows/a.json.gz", []byte("second"))
+	expectBlob(t, blobs, "workflows/a.json.gz", []byte("second"))
+}
+
+// testEmptyBlob: an empty blob is stored, not treated as missing
+func testEmptyBlob(t *testing.T, blobs storage.Blobs) {
+	mustPut(t, blobs, "workflows/empty.json.gz", nil)
+	expectBlob(t, blobs, "workflows/empty.json.gz", []byte{})
+}
+
+// testNestedKeys: slash-separated keys are independent of each other
+func testNestedKeys(t *testing.T, blobs storage.Blobs) {
+	keys := []string{"health-probe", "workflows/a.json.gz", "workflows/b.json.gz", "other/workflows/a.json.gz"}
+	for _, key := range keys {
+		mustPut(t, blobs, key, []byte(key))
+	}
+	for _, key := range keys {
+		expectBlob(t, blobs, key, []byte(key))
+	}
+}
+
+// testDelete: a deleted blob is gone, other blobs stay
+func testDelete(t *testing.T, blobs storage.Blobs) {
+	mustPut(t, blobs, "workflows/a.json.gz", []byte("a"))
+	mustPut(t, blobs, "workflows/b.json.gz", []byte("b"))
+	if err := blobs.Delete("workflows/a.json.gz"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := blobs.Get("workflows/a.json.gz"); err == nil {
+		t.Fatal("Get after Delete returned no error")
+	}
+	expectBlob(t, blobs, "workflows/b.json.gz", []byte("b"))
+}
+
+// testDeleteMissing: deleting a missing blob is not an error, as deletions are retried
+func testDeleteMissing(t *testing.T, blobs storage.Blobs) {
+	if err := blobs.Delete("workflows/missing.json.gz"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+}
+
+// testNoAliasing: the store must not keep or hand out the caller's slices
+func testNoAliasing(t *testing.T, blobs storage.Blobs) {
+	data := []byte("original")
+	mustPut(t, blobs, "workflows/a.json.gz", data)
+	copy(data, "mutated!")
+	expectBlob(t, blobs, "workflows/a.json.gz", []byte("original"))
+
+	got, err := blobs.Get("workflows/a.json.gz")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	copy(got, "mutated!")
+	expectBlob(t, blobs, "workflows/a.json.gz", []byte("original"))
+}
+
+// testConcurrent: archival and restores run from several goroutines
+func testConcurrent(t *testing.T, blobs storage.Blobs) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("workflows/%d.json.gz", i)
+			want := bytes.Repeat([]byte{byte(i)}, 1024)
+			if err := blobs.Put(key, want); err != nil {
+				errs <- fmt.Errorf("Put %s: %w", key, err)
+				return
+			}
+			got, err := blobs.Get(key)
+			if err != nil || !bytes.Equal(got, want) {
+				errs <- fmt.Errorf("Get %s: %d bytes, %v", key, len(got), err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// testArchiveRoundTrip: a store archiving to the blobs restores the payload on access
+func testArchiveRoundTrip(t *testing.T, blobs storage.Blobs) {
+	store := storage.NewStore()
+	store.SetArchive(blobs)
+	store.Save(&storage.WorkflowState{ID: "conformance", Status: storage.StatusCompleted, Lyrics: "[Verse]\nla la la"})
+
+	n, err := store.ArchiveBefore(time.Now().Add(time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("ArchiveBefore = %d, %v; want 1 archived", n, err)
+	}
+	wf, ok := store.Get("conformance")
+	if !ok || wf.ArchivedAt != nil || wf.Lyrics != "[Verse]\nla la la" {
+		t.Fatalf("restored workflow: archived %v, lyrics %q", wf.ArchivedAt != nil, wf.Lyrics)
+	}
+}
+
+// testStoreCheck: the storage health check round-trips its probe through the blobs
+func testStoreCheck(t *testing.T, blobs storage.Blobs) {
+	store := storage.NewStore()
+	store.SetArchive(blobs)
+	if err := store.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+}
+
+func mustPut(t *testing.T, blobs storage.Blobs, key string, data []byte) {
+	t.Helper()
+	if err := blobs.Put(key, data); err != nil {
+		t.Fatalf("Put %s: %v", key, err)
+	}
+}
+
+func expectBlob(t *testing.T, blobs storage.Blobs, key string, want []byte) {
+	t.Helper()
+	got, err := blobs.Get(key)
+	if err != nil {
+		t.Fatalf("Get %s: %v", key, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Get %s = %d bytes %q, want %d bytes %q", key, len(got), truncate(got), len(want), truncate(want))
+	}
+}
+
+func truncate(data []byte) []byte {
+	if len(data) > 32 {
+		return data[:32]
+	}
+	return data
+}
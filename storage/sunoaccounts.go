@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// SunoAccount is the recorded quota of one account of the Suno account pool (see suno.Pool), so
+// that exhausted accounts stay skipped across restarts
+type SunoAccount struct {
+	Name         string     `json:"name"`
+	CreditsLeft  int        `json:"credits_left"` // -1 until the quota was read
+	MonthlyLimit int        `json:"monthly_limit,omitempty"`
+	MonthlyUsage int        `json:"monthly_usage,omitempty"`
+	Exhausted    bool       `json:"exhausted,omitempty"`
+	Submissions  int        `json:"submissions,omitempty"` // generations sent to the account
+	CheckedAt    *time.Time `json:"checked_at,omitempty"`  // last quota read
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// SaveSunoAccount stores or updates the quota of an account, by name
+func (s *Store) SaveSunoAccount(account SunoAccount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.sunoAccounts, func(a SunoAccount) bool { return a.Name == account.Name }); i >= 0 {
+		s.sunoAccounts[i] = account
+	} else {
+		s.sunoAccounts = append(s.sunoAccounts, account)
+	}
+	s.persist()
+}
+
+// SunoAccounts returns the recorded quotas of the Suno accounts
+func (s *Store) SunoAccounts() []SunoAccount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.sunoAccounts)
+}
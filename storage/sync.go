@@ -0,0 +1,26 @@
+package storage
+
+import "time"
+
+// SyncRecord is what the cloud sync last wrote to a remote file (see workflow.RunCloudSync)
+type SyncRecord struct {
+	Rev      string    `json:"rev"`  // remote revision after the write
+	Hash     string    `json:"hash"` // SHA-256 of the content written
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// GetSyncRecord returns the record of a remote file, keyed by its path under the sync root
+func (s *Store) GetSyncRecord(path string) (SyncRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.syncRecords[path]
+	return record, ok
+}
+
+// SaveSyncRecord stores the record of a remote file
+func (s *Store) SaveSyncRecord(path string, record SyncRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncRecords[path] = record
+	s.persist()
+}
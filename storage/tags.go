@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// StyleTag is one Suno style tag of the taxonomy, with how it fared in the library and workflows
+type StyleTag struct {
+	Name     string  `json:"name"`               // lowercase, single-spaced
+	Category string  `json:"category,omitempty"` // genre, mood, instrument, ... for bundled tags
+	Bundled  bool    `json:"bundled"`            // part of the bundled dataset
+	Clips    int     `json:"clips"`              // clips of the Suno library tagged with it
+	Used     int     `json:"used"`               // workflows submitted with it
+	Kept     int     `json:"kept"`               // of those, completed
+	Dropped  int     `json:"dropped"`            // of those, rejected or cancelled
+	KeepRate float64 `json:"keep_rate"`          // kept out of kept and dropped
+}
+
+// TagTaxonomy is the style tag taxonomy built by the tag sync
+type TagTaxonomy struct {
+	Tags         []StyleTag `json:"tags"` // by name
+	LibraryClips int        `json:"library_clips"`
+	SyncedAt     *time.Time `json:"synced_at,omitempty"`
+}
+
+// TagTaxonomy returns the last synced taxonomy (zero value before the first sync)
+func (s *Store) TagTaxonomy() TagTaxonomy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	taxonomy := s.tags
+	taxonomy.Tags = slices.Clone(taxonomy.Tags)
+	return taxonomy
+}
+
+// SaveTagTaxonomy replaces the taxonomy
+func (s *Store) SaveTagTaxonomy(taxonomy TagTaxonomy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	taxonomy.Tags = slices.Clone(taxonomy.Tags)
+	s.tags = taxonomy
+	s.persist()
+}
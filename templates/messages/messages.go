@@ -0,0 +1,173 @@
+package messages
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultLanguage is the language of the complete catalog; the others fall back to it
+const DefaultLanguage = "en"
+
+// Embed the message catalogs at compile time, one file per language code (en.tmpl, es.tmpl)
+//
+//go:embed *.tmpl
+var catalogs embed.FS
+
+// Catalog holds the Telegram messages of every language
+// Templates are html/template so that values are escaped for Telegram's HTML parse mode.
+type Catalog struct {
+	defaultLanguage string
+	sets            map[string]*htmltemplate.Template // embedded catalogs with the MESSAGES_DIR overrides
+	builtin         map[string]*htmltemplate.Template // embedded catalogs only, used when an override fails
+}
+
+// Load builds the catalog from the embedded files and the *.tmpl files of dir ("" for none)
+// A file in dir redefines the messages it contains for its language and may add a language.
+// Files that do not parse are skipped and reported in the returned error; the catalog is
+// usable either way unless the embedded files are broken.
+func Load(dir, defaultLanguage string) (*Catalog, error) {
+	embedded, err := readCatalogs(catalogs)
+	if err != nil {
+		return nil, err
+	}
+	base, err := htmltemplate.New(DefaultLanguage).Parse(embedded[DefaultLanguage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s messages: %w", DefaultLanguage, err)
+	}
+
+	var errs []error
+	overrides := map[string]string{}
+	if dir != "" {
+		if overrides, err = readCatalogs(os.DirFS(dir)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to read messages dir %s: %w", dir, err))
+			overrides = map[string]string{}
+		}
+	}
+
+	c := &Catalog{
+		defaultLanguage: DefaultLanguage,
+		sets:            make(map[string]*htmltemplate.Template),
+		builtin:         make(map[string]*htmltemplate.Template),
+	}
+	overridden := extend(base, DefaultLanguage, overrides[DefaultLanguage], &errs)
+	for _, lang := range slices.Sorted(maps.Keys(embedded)) {
+		c.builtin[lang] = extend(base, lang, embedded[lang], &errs)
+	}
+	for _, lang := range slices.Sorted(maps.Keys(union(embedded, overrides))) {
+		set := overridden
+		if lang != DefaultLanguage {
+			set = extend(extend(overridden, lang, embedded[lang], &errs), lang, overrides[lang], &errs)
+		}
+		// A language only defined by a broken override file is left out
+		if set != overridden || lang == DefaultLanguage {
+			c.sets[lang] = set
+		}
+	}
+
+	if _, ok := c.sets[defaultLanguage]; ok {
+		c.defaultLanguage = defaultLanguage
+	} else if defaultLanguage != "" {
+		errs = append(errs, fmt.Errorf("no messages in %q, using %s", defaultLanguage, DefaultLanguage))
+	}
+	return c, errors.Join(errs...)
+}
+
+// Render executes a message in the given language, or the default one if the catalog has
+// no such language
+// A message failing in an override is rendered from the embedded catalogs instead.
+func (c *Catalog) Render(language, name string, data any) string {
+	if !c.Has(language) {
+		language = c.defaultLanguage
+	}
+	text, err := execute(c.sets[language], name, data)
+	if err == nil {
+		return text
+	}
+	slog.Warn("Failed to render message", "language", language, "message", name, "error", err)
+	builtin, ok := c.builtin[language]
+	if !ok {
+		builtin = c.builtin[DefaultLanguage]
+	}
+	text, err = execute(builtin, name, data)
+	if err != nil {
+		slog.Error("Failed to render builtin message", "language", language, "message", name, "error", err)
+	}
+	return text
+}
+
+// Has reports whether the catalog has messages in a language
+func (c *Catalog) Has(language string) bool {
+	_, ok := c.sets[language]
+	return ok
+}
+
+// Languages returns the codes of the languages with messages, sorted
+func (c *Catalog) Languages() []string {
+	return slices.Sorted(maps.Keys(c.sets))
+}
+
+// DefaultLanguage returns the language used for chats without a preference
+func (c *Catalog) DefaultLanguage() string {
+	return c.defaultLanguage
+}
+
+func execute(set *htmltemplate.Template, name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// extend returns a copy of set with the messages of content redefined; set itself is returned
+// when content is empty or does not parse
+func extend(set *htmltemplate.Template, lang, content string, errs *[]error) *htmltemplate.Template {
+	if content == "" {
+		return set
+	}
+	clone, err := set.Clone()
+	if err == nil {
+		_, err = clone.Parse(content)
+	}
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s messages: %w", lang, err))
+		return set
+	}
+	return clone
+}
+
+// readCatalogs reads the *.tmpl files of a file system root, keyed by language code
+func readCatalogs(fsys fs.FS) (map[string]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimSuffix(entry.Name(), ".tmpl")] = string(content)
+	}
+	return files, nil
+}
+
+func union(a, b map[string]string) map[string]string {
+	all := maps.Clone(a)
+	maps.Copy(all, b)
+	return all
+}
@@ -2,6 +2,12 @@ package prompts
 
 import (
 	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 )
 
 // Embed prompt templates at compile time
@@ -18,11 +24,23 @@ var bracketInstructionsPrompt string
 //go:embed persona_inspo.txt
 var personaInspoPrompt string
 
+//go:embed transcript_summary.txt
+var transcriptSummaryPrompt string
+
+//go:embed lyrics_structure.txt
+var lyricsStructurePrompt string
+
+//go:embed lyrics_similarity.txt
+var lyricsSimilarityPrompt string
+
 type PromptsList struct {
 	LyricsGeneration    string
 	SunoProperties      string
 	BracketInstructions string
 	PersonaInspo        string
+	TranscriptSummary   string
+	LyricsStructure     string
+	LyricsSimilarity    string
 }
 
 // Init initializes the prompts list with embedded content
@@ -32,5 +50,43 @@ func Init() *PromptsList {
 		SunoProperties:      sunoPropertiesPrompt,
 		BracketInstructions: bracketInstructionsPrompt,
 		PersonaInspo:        personaInspoPrompt,
+		TranscriptSummary:   transcriptSummaryPrompt,
+		LyricsStructure:     lyricsStructurePrompt,
+		LyricsSimilarity:    lyricsSimilarityPrompt,
+	}
+}
+
+// files maps the prompt files to their field in a PromptsList
+var files = map[string]func(*PromptsList) *string{
+	"lyrics_generation.txt":    func(p *PromptsList) *string { return &p.LyricsGeneration },
+	"suno_properties.txt":      func(p *PromptsList) *string { return &p.SunoProperties },
+	"bracket_instructions.txt": func(p *PromptsList) *string { return &p.BracketInstructions },
+	"persona_inspo.txt":        func(p *PromptsList) *string { return &p.PersonaInspo },
+	"transcript_summary.txt":   func(p *PromptsList) *string { return &p.TranscriptSummary },
+	"lyrics_structure.txt":     func(p *PromptsList) *string { return &p.LyricsStructure },
+	"lyrics_similarity.txt":    func(p *PromptsList) *string { return &p.LyricsSimilarity },
+}
+
+// LoadDir returns the embedded prompts with those of dir in their place, e.g. a new version of
+// lyrics_generation.txt; files are named as in this package. It also returns the names of the
+// files it used, sorted, and fails when dir has none of them.
+func LoadDir(dir string) (*PromptsList, []string, error) {
+	list := Init()
+	var used []string
+	for name, field := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		*field(list) = string(data)
+		used = append(used, name)
+	}
+	if len(used) == 0 {
+		return nil, nil, fmt.Errorf("no prompt files in %s", dir)
 	}
+	slices.Sort(used)
+	return list, used, nil
 }
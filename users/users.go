@@ -0,0 +1,63 @@
+// Package users maps identities (web names and "tg:<chat id>") to roles
+package users
+
+import "strings"
+
+// Role is what an identity may do
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // sees workflows and their status
+	RoleReviewer Role = "reviewer" // also starts, reviews, retries and clones workflows
+	RoleAdmin    Role = "admin"    // also reassigns reviews, deletes workflows and manages settings
+)
+
+var rank = map[Role]int{RoleViewer: 1, RoleReviewer: 2, RoleAdmin: 3}
+
+// ParseRole returns the role named s, and false for unknown names
+func ParseRole(s string) (Role, bool) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := rank[role]
+	return role, ok
+}
+
+// Allows reports whether the role includes everything required may do
+func (r Role) Allows(required Role) bool {
+	return rank[r] >= rank[required]
+}
+
+// Directory assigns roles to identities
+type Directory struct {
+	roles       map[string]Role
+	defaultRole Role
+}
+
+// New creates a directory from the identities of each role; identities listed under several
+// roles get the highest. Everyone else, anonymous viewers included, gets defaultRole.
+func New(admins, reviewers, viewers []string, defaultRole Role) *Directory {
+	d := &Directory{roles: make(map[string]Role), defaultRole: defaultRole}
+	for _, list := range []struct {
+		identities []string
+		role       Role
+	}{{viewers, RoleViewer}, {reviewers, RoleReviewer}, {admins, RoleAdmin}} {
+		for _, identity := range list.identities {
+			if identity != "" {
+				d.roles[identity] = list.role
+			}
+		}
+	}
+	return d
+}
+
+// Role returns the role of identity ("" for anonymous viewers)
+func (d *Directory) Role(identity string) Role {
+	if role, ok := d.roles[identity]; ok && identity != "" {
+		return role
+	}
+	return d.defaultRole
+}
+
+// Can reports whether identity has at least the required role
+func (d *Directory) Can(identity string, required Role) bool {
+	return d.Role(identity).Allows(required)
+}
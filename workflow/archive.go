@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunArchival moves the payload of finished workflows older than ARCHIVE_AFTER (or the
+// retention days setting) to the archive every ARCHIVE_CHECK_INTERVAL until ctx is cancelled
+// The store must have an archive (storage.Store.SetArchive).
+func (e *Engine) RunArchival(ctx context.Context) {
+	if e.cfg.ArchiveCheckInterval <= 0 {
+		slog.Info("Workflow archival disabled")
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.ArchiveCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		e.archiveOld(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) archiveOld(now time.Time) {
+	after := e.archiveAfter()
+	if after <= 0 {
+		return
+	}
+	archived, err := e.store.ArchiveBefore(now.Add(-after))
+	if err != nil {
+		slog.Error("Workflow archival failed", "archived", archived, "error", err)
+		return
+	}
+	if archived > 0 {
+		slog.Info("Archived old workflows", "count", archived)
+	}
+}
+
+// archiveAfter is ARCHIVE_AFTER unless the retention days setting overrides it; 0 disables archival
+func (e *Engine) archiveAfter() time.Duration {
+	if days := e.store.Settings().RetentionDays; days != nil {
+		return time.Duration(*days) * 24 * time.Hour
+	}
+	return e.cfg.ArchiveAfter
+}
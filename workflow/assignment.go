@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"workflower/storage"
+	"workflower/users"
+)
+
+// TelegramIdentityPrefix marks identities (and assignees) that are Telegram chats
+const TelegramIdentityPrefix = "tg:"
+
+// TelegramIdentity returns the identity of a Telegram chat
+func TelegramIdentity(chatID string) string {
+	return TelegramIdentityPrefix + chatID
+}
+
+// IsAdmin reports whether identity is listed in ADMIN_USERS
+func (e *Engine) IsAdmin(identity string) bool {
+	return identity != "" && e.users.Role(identity) == users.RoleAdmin
+}
+
+// Role returns the role of identity (see package users)
+func (e *Engine) Role(identity string) users.Role {
+	return e.users.Role(identity)
+}
+
+// Can reports whether identity has at least the required role
+func (e *Engine) Can(identity string, required users.Role) bool {
+	return e.users.Can(identity, required)
+}
+
+// CanReview reports whether identity may review the workflow: assigned workflows are open to
+// the assignee and admins, unassigned ones to every reviewer
+func (e *Engine) CanReview(state *storage.WorkflowState, identity string) bool {
+	if state.Assignee != "" {
+		return state.Assignee == identity || e.IsAdmin(identity)
+	}
+	return e.users.Can(identity, users.RoleReviewer)
+}
+
+// Assign changes the reviewer of a workflow; by is the identity making the change
+// Subscribers re-send the review notification to the new assignee
+// A manual reassignment restarts the escalation chain
+func (e *Engine) Assign(state *storage.WorkflowState, assignee, by string) {
+	e.update(func() { state.EscalationLevel = EscalationAssignee })
+	e.assign(state, assignee, by)
+}
+
+func (e *Engine) assign(state *storage.WorkflowState, assignee, by string) {
+	from := state.Assignee
+	now := time.Now()
+	e.update(func() {
+		state.Assignee = strings.TrimSpace(assignee)
+		state.AssignedAt = &now
+	})
+	e.store.Save(state)
+
+	e.events.Publish(Assigned{
+		From:     from,
+		To:       state.Assignee,
+		By:       by,
+		At:       now,
+		Workflow: e.Snapshot(state),
+	})
+}
+
+// Sign returns an HMAC of value keyed with SECRET_KEY, used for identity cookies and review links
+func (e *Engine) Sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(e.cfg.SecretKey))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature was produced by Sign for value
+func (e *Engine) VerifySignature(value, signature string) bool {
+	return hmac.Equal([]byte(e.Sign(value)), []byte(signature))
+}
+
+// reviewURL links to the review page; links for Telegram assignees carry a signed
+// token that identifies the chat on the web
+func (e *Engine) reviewURL(state *storage.WorkflowState) string {
+	url := e.cfg.BaseURL + "/review/" + state.ID
+	if strings.HasPrefix(state.Assignee, TelegramIdentityPrefix) {
+		url += "?as=" + e.Sign(reviewTokenValue(state.ID, state.Assignee))
+	}
+	return url
+}
+
+// VerifyReviewToken reports whether token is a review link token for the workflow's current assignee
+func (e *Engine) VerifyReviewToken(state *storage.WorkflowState, token string) bool {
+	return state.Assignee != "" && e.VerifySignature(reviewTokenValue(state.ID, state.Assignee), token)
+}
+
+func reviewTokenValue(workflowID, assignee string) string {
+	return "review|" + workflowID + "|" + assignee
+}
@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"fmt"
+
+	"workflower/storage"
+)
+
+// auditEngineActor is recorded for transitions made by the engine itself
+const auditEngineActor = "engine"
+
+// newAuditSubscriber records status changes, assignments and escalations in the store's audit log
+func newAuditSubscriber(store *storage.Store) func(Event) {
+	return func(event Event) {
+		var entry storage.AuditEntry
+		switch ev := event.(type) {
+		case StatusChanged:
+			entry = storage.AuditEntry{
+				At:     ev.At,
+				Seq:    ev.Workflow.Seq,
+				Action: EventStatusChanged,
+				Actor:  auditEngineActor,
+				Detail: fmt.Sprintf("%s -> %s", ev.From, ev.To),
+			}
+		case Assigned:
+			entry = storage.AuditEntry{
+				At:     ev.At,
+				Seq:    ev.Workflow.Seq,
+				Action: EventAssigned,
+				Actor:  ev.By,
+				Detail: fmt.Sprintf("%s -> %s", displayAssignee(ev.From), displayAssignee(ev.To)),
+			}
+		case Escalated:
+			entry = storage.AuditEntry{
+				At:     ev.At,
+				Seq:    ev.Workflow.Seq,
+				Action: EventEscalated,
+				Actor:  escalationActor,
+				Detail: fmt.Sprintf("level %d -> %s", ev.Level, ev.To),
+			}
+		case Deleted:
+			entry = storage.AuditEntry{
+				At:     ev.At,
+				Seq:    ev.Workflow.Seq,
+				Action: EventDeleted,
+				Actor:  ev.By,
+				Detail: ev.Workflow.Title,
+			}
+		case Reviewed:
+			detail := ev.Decision
+			if ev.Workflow.LintOverridden && ev.Workflow.HasLyricsLint() {
+				detail += " (bracket lint overridden)"
+			}
+			entry = storage.AuditEntry{
+				At:           ev.At,
+				Seq:          ev.Workflow.Seq,
+				Action:       EventReviewed,
+				Actor:        ev.By,
+				Detail:       detail,
+				TurnaroundMS: ev.Turnaround.Milliseconds(),
+			}
+		case Commented:
+			entry = storage.AuditEntry{
+				At:     ev.Comment.At,
+				Seq:    ev.Workflow.Seq,
+				Action: EventCommented,
+				Actor:  ev.Comment.Author,
+				Detail: truncateString(ev.Comment.Text, 80),
+			}
+		default:
+			return
+		}
+
+		entry.WorkflowID = event.WorkflowID()
+		store.AddAuditEntry(entry)
+	}
+}
+
+func displayAssignee(assignee string) string {
+	if assignee == "" {
+		return "(unassigned)"
+	}
+	return assignee
+}
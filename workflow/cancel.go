@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"workflower/lib/logger"
+	"workflower/storage"
+)
+
+// ErrFinished is returned when cancelling a workflow that has already finished
+var ErrFinished = newError(CodeConflict, "workflow has already finished")
+
+// track returns the context of a new background run of a workflow, cancelled by
+// CancelWorkflow and DeleteWorkflow; a previous run of the same workflow is cancelled
+// The run is released when the workflow reaches review or a final status.
+func (e *Engine) track(ctx context.Context, state *storage.WorkflowState) context.Context {
+	ctx = adoptRequestID(ctx, state)
+	id := state.ID
+	runCtx, cancel := context.WithCancel(ctx)
+	e.mu.Lock()
+	previous := e.runs[id]
+	e.runs[id] = cancel
+	e.mu.Unlock()
+	if previous != nil {
+		previous()
+	}
+	return runCtx
+}
+
+// adoptRequestID records the request ID of ctx on the workflow as the one of its current run
+// Without one in ctx (runs resumed after a restart) the recorded ID is carried on instead, so
+// that the logs of a run can be correlated with the request that started it.
+func adoptRequestID(ctx context.Context, state *storage.WorkflowState) context.Context {
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		state.RequestID = requestID
+		return ctx
+	}
+	return logger.WithRequestID(ctx, state.RequestID)
+}
+
+// untrack cancels and forgets the background run of a workflow, if any
+func (e *Engine) untrack(id string) {
+	e.mu.Lock()
+	cancel := e.runs[id]
+	delete(e.runs, id)
+	e.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// CancelWorkflow stops an unfinished workflow: its running LLM or Suno steps are
+// interrupted and it ends as cancelled
+// Clips already submitted to Suno keep generating there but are no longer polled.
+func (e *Engine) CancelWorkflow(state *storage.WorkflowState) error {
+	if state.IsTerminal() {
+		return ForWorkflow(ErrFinished, state.ID)
+	}
+	e.setStatus(state, storage.StatusCancelled)
+	slog.InfoContext(logContext(state), "Workflow cancelled", "workflow_id", state.ID)
+	return nil
+}
+
+// DeleteWorkflow stops any run of a workflow and removes it from the store together with its
+// uploaded reference audio and archived payload; by is recorded in the audit log
+func (e *Engine) DeleteWorkflow(state *storage.WorkflowState, by string) {
+	e.untrack(state.ID)
+	e.store.Delete(state.ID)
+	e.removeUpload(state.AudioFilePath)
+	slog.InfoContext(logContext(state), "Workflow deleted", "workflow_id", state.ID, "by", by)
+	e.events.Publish(Deleted{By: by, At: time.Now(), Workflow: e.Snapshot(state)})
+}
+
+// removeUpload deletes an uploaded file unless a clone still refers to it
+// Only files below UploadsDir are touched.
+func (e *Engine) removeUpload(path string) {
+	if path == "" || !strings.HasPrefix(filepath.Clean(path), UploadsDir+string(filepath.Separator)) {
+		return
+	}
+	for other := range e.store.All() {
+		if other.AudioFilePath == path {
+			return
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to remove uploaded audio", "path", path, "error", err)
+	}
+}
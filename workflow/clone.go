@@ -0,0 +1,48 @@
+package workflow
+
+import (
+	"context"
+
+	"workflower/storage"
+)
+
+// CloneWorkflow starts a new workflow with the task description and options of source
+// and its reviewed Suno properties; with keepLyrics the reviewed lyrics are reused too,
+// so lyrics generation and bracket instructions are skipped
+// Without keepLyrics a workflow of imported lyrics starts again from the imported lyrics.
+// The clone belongs to session, the browser cloning it (see StartParams.Session), not to the
+// one source was started from.
+func (e *Engine) CloneWorkflow(ctx context.Context, source *storage.WorkflowState, keepLyrics bool, session string) (*storage.WorkflowState, error) {
+	params := StartParams{
+		Project:         source.Project,
+		ExternalRef:     source.ExternalRef,
+		TaskDescription: source.TaskDescription,
+		IsPremium:       source.IsPremium,
+		AudioFilePath:   source.AudioFilePath,
+		AudioFileName:   source.AudioFileName,
+		LongSong:        source.LongSong,
+		Assignee:        source.Assignee,
+		GenerateStems:   source.GenerateStems,
+		Language:        source.Language,
+		LyricsEngine:    source.LyricsEngine,
+		SunoModel:       source.SunoModel,
+		SunoPersonaID:   sunoPersonaID(source),
+		SourceLyrics:    source.SourceLyrics,
+		ClonedFrom:      source.ID,
+		Session:         session,
+	}
+	if props := submittedProperties(source); props != nil {
+		seed := *props
+		params.SunoProperties = &seed
+	}
+	switch {
+	case keepLyrics:
+		params.Lyrics = submittedLyrics(source)
+	case source.LyricsImported:
+		// There is nothing to generate the lyrics from: start again from the imported ones
+		params.Lyrics = source.Lyrics
+		params.LyricsImported = true
+		params.AddBrackets = stepSucceeded(source, StepBrackets)
+	}
+	return e.StartWorkflow(ctx, params)
+}
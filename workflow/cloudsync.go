@@ -0,0 +1,368 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"workflower/lib/cloudsync"
+	"workflower/lib/locale"
+	"workflower/storage"
+)
+
+// Cloud sync modes (SYNC_MODE)
+const (
+	// SyncOneWay makes the remote folder a mirror: files changed or deleted there are written again
+	SyncOneWay = "one-way"
+	// SyncTwoWay never overwrites remote edits: files changed or deleted there are left alone, and
+	// columns and rows added to the sheets are kept when the sheets are updated
+	SyncTwoWay = "two-way"
+)
+
+const (
+	// syncSheetName is the metadata sheet of every project folder
+	syncSheetName = "songs.csv"
+	// syncNoProject names the folder of workflows without a project
+	syncNoProject = "No project"
+	// syncFetchTimeout bounds the download of one generated file
+	syncFetchTimeout = 10 * time.Minute
+)
+
+// syncSheetHeader are the columns the sync owns; "Seq" identifies the rows
+var syncSheetHeader = []string{"Seq", "Title", "Style", "Vocal Type", "Language", "Created", "Audio", "Video", "Lyrics", "Link"}
+
+// syncMediaClient downloads generated files from the Suno CDN
+var syncMediaClient = &http.Client{Timeout: syncFetchTimeout}
+
+// RunCloudSync mirrors the audio, video, lyrics and metadata sheet of completed workflows into
+// the remote folder every SYNC_INTERVAL until ctx is cancelled, one folder per project
+func (e *Engine) RunCloudSync(ctx context.Context, remote cloudsync.Remote) {
+	if e.cfg.SyncInterval <= 0 {
+		slog.Info("Cloud sync disabled")
+		return
+	}
+	slog.Info("Cloud sync enabled", "remote", remote.Name(), "mode", e.cfg.SyncMode, "interval", e.cfg.SyncInterval)
+
+	ticker := time.NewTicker(e.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		e.SyncToCloud(ctx, remote)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncToCloud runs one sync pass and returns the number of files written
+// Failures are logged per file; the next pass tries again.
+func (e *Engine) SyncToCloud(ctx context.Context, remote cloudsync.Remote) int {
+	byProject := make(map[string][]*storage.WorkflowState)
+	for _, wf := range e.store.ListByStatus(storage.StatusCompleted) {
+		byProject[wf.Project] = append(byProject[wf.Project], wf)
+	}
+
+	written := 0
+	for project, workflows := range byProject {
+		folder := syncFolderName(project)
+		slices.SortFunc(workflows, func(a, b *storage.WorkflowState) int { return a.Seq - b.Seq })
+
+		var rows [][]string
+		for _, wf := range workflows {
+			files := e.syncFiles(wf)
+			for _, file := range files {
+				if file.name == "" {
+					continue
+				}
+				ok, err := e.syncFile(ctx, remote, folder+"/"+file.name, file.content)
+				if err != nil {
+					slog.Warn("Cloud sync failed", "workflow_id", wf.ID, "file", folder+"/"+file.name, "error", err)
+					continue
+				}
+				if ok {
+					written++
+				}
+			}
+			rows = append(rows, e.syncSheetRow(wf, files))
+		}
+
+		ok, err := e.syncSheet(ctx, remote, folder+"/"+syncSheetName, rows)
+		if err != nil {
+			slog.Warn("Cloud sync failed", "file", folder+"/"+syncSheetName, "error", err)
+		} else if ok {
+			written++
+		}
+	}
+	if written > 0 {
+		slog.Info("Cloud sync wrote files", "remote", remote.Name(), "count", written)
+	}
+	return written
+}
+
+// syncedFile is a file of a workflow in its project folder
+type syncedFile struct {
+	name    string // empty when the workflow has no such file
+	content func(ctx context.Context) ([]byte, error)
+}
+
+// syncFiles returns the audio, video and lyrics files of a workflow, in sheet column order
+func (e *Engine) syncFiles(wf *storage.WorkflowState) []syncedFile {
+	base := fmt.Sprintf("%d-%s", wf.Seq, e.namer.FileName(wf))
+	files := []syncedFile{{}, {}, {}}
+	if wf.AudioURL != "" {
+		files[0] = syncedFile{base + mediaExt(wf.AudioURL, ".mp3"), func(ctx context.Context) ([]byte, error) {
+			return e.mediaContent(ctx, wf, "audio", func() string { return wf.AudioURL })
+		}}
+	}
+	if wf.VideoURL != "" {
+		files[1] = syncedFile{base + mediaExt(wf.VideoURL, ".mp4"), func(ctx context.Context) ([]byte, error) {
+			return e.mediaContent(ctx, wf, "video", func() string { return wf.VideoURL })
+		}}
+	}
+	files[2] = syncedFile{base + ".txt", func(context.Context) ([]byte, error) {
+		full, ok := e.store.Get(wf.ID) // restores the lyrics of archived workflows
+		if !ok {
+			return nil, fmt.Errorf("workflow %s not found", wf.ID)
+		}
+		return []byte(submittedLyrics(full)), nil
+	}}
+	return files
+}
+
+// syncFile brings one remote file up to date and reports whether it was written
+// Generated files never change, so content is only produced when the file is (re)written.
+func (e *Engine) syncFile(ctx context.Context, remote cloudsync.Remote, name string, content func(context.Context) ([]byte, error)) (bool, error) {
+	record, synced := e.store.GetSyncRecord(name)
+	current, err := remote.Stat(ctx, name)
+	missing := errors.Is(err, cloudsync.ErrNotFound)
+	if err != nil && !missing {
+		return false, err
+	}
+
+	switch {
+	case synced && !missing && current.Rev == record.Rev:
+		return false, nil // up to date
+	case e.cfg.SyncMode == SyncTwoWay && (synced || !missing):
+		return false, nil // changed, deleted or put there on the remote side: theirs wins
+	}
+
+	data, err := content(ctx)
+	if err != nil {
+		return false, err
+	}
+	return e.putSynced(ctx, remote, name, data, "")
+}
+
+// syncSheet writes the metadata sheet of a project folder and reports whether it was written
+// In two-way mode the sheet is merged with the remote copy: columns and rows added there are kept.
+func (e *Engine) syncSheet(ctx context.Context, remote cloudsync.Remote, name string, rows [][]string) (bool, error) {
+	data := encodeSheet(e.cfg.Locale, syncSheetHeader, rows)
+	record, synced := e.store.GetSyncRecord(name)
+
+	var current cloudsync.File
+	if e.cfg.SyncMode == SyncTwoWay {
+		remoteData, file, err := remote.Get(ctx, name)
+		switch {
+		case errors.Is(err, cloudsync.ErrNotFound) && synced:
+			return false, nil // deleted on the remote side
+		case errors.Is(err, cloudsync.ErrNotFound):
+		case err != nil:
+			return false, err
+		default:
+			if data, err = mergeSheet(e.cfg.Locale, remoteData, rows); err != nil {
+				return false, fmt.Errorf("sheet edited into an unreadable state, left alone: %w", err)
+			}
+			if bytes.Equal(data, remoteData) {
+				return false, nil
+			}
+			current = file
+		}
+	} else {
+		file, err := remote.Stat(ctx, name)
+		if err != nil && !errors.Is(err, cloudsync.ErrNotFound) {
+			return false, err
+		}
+		if synced && file.Rev == record.Rev && record.Hash == contentHash(data) {
+			return false, nil
+		}
+	}
+	return e.putSynced(ctx, remote, name, data, current.Rev)
+}
+
+// putSynced writes a remote file and records what was written
+// A conflict means the file changed on the remote side meanwhile; it is left alone this pass.
+func (e *Engine) putSynced(ctx context.Context, remote cloudsync.Remote, name string, data []byte, ifRev string) (bool, error) {
+	file, err := remote.Put(ctx, name, data, ifRev)
+	if errors.Is(err, cloudsync.ErrConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	e.store.SaveSyncRecord(name, storage.SyncRecord{Rev: file.Rev, Hash: contentHash(data), SyncedAt: time.Now()})
+	return true, nil
+}
+
+// syncSheetRow returns the sheet row of a workflow (see syncSheetHeader)
+func (e *Engine) syncSheetRow(wf *storage.WorkflowState, files []syncedFile) []string {
+	var style, vocals string
+	if props := submittedProperties(wf); props != nil {
+		style, vocals = props.Style, props.VocalType
+	}
+	return []string{
+		strconv.Itoa(wf.Seq), wf.Title, style, vocals, wf.Language,
+		e.cfg.Locale.DateTime(wf.CreatedAt, e.cfg.DisplayLocation),
+		files[0].name, files[1].name, files[2].name, e.workflowURL(wf),
+	}
+}
+
+// mergeSheet combines the rows of the sync with a sheet edited on the remote side: the sync's
+// columns are replaced, other columns keep their values and rows of unknown workflows are kept
+// The remote sheet may use either list separator; the merged one uses that of l.
+func mergeSheet(l locale.Locale, remoteData []byte, rows [][]string) ([]byte, error) {
+	r := csv.NewReader(bytes.NewReader(remoteData))
+	r.Comma = locale.SniffCSVSeparator(remoteData)
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return encodeSheet(l, syncSheetHeader, rows), nil
+	}
+	remoteHeader := records[0]
+	seqColumn := slices.Index(remoteHeader, syncSheetHeader[0])
+	if seqColumn < 0 {
+		return nil, fmt.Errorf("no %s column", syncSheetHeader[0])
+	}
+
+	header := slices.Clone(syncSheetHeader)
+	for _, column := range remoteHeader {
+		if !slices.Contains(header, column) {
+			header = append(header, column)
+		}
+	}
+	// valuesOf maps a remote row onto header
+	valuesOf := func(record []string) map[string]string {
+		values := make(map[string]string)
+		for i, column := range remoteHeader {
+			if i < len(record) {
+				values[column] = record[i]
+			}
+		}
+		return values
+	}
+
+	remoteRows := make(map[string]map[string]string)
+	var order []string // remote rows in their order
+	for _, record := range records[1:] {
+		if seqColumn < len(record) {
+			remoteRows[record[seqColumn]] = valuesOf(record)
+			order = append(order, record[seqColumn])
+		}
+	}
+
+	var merged [][]string
+	known := make(map[string]bool)
+	for _, row := range rows {
+		known[row[0]] = true
+		values := remoteRows[row[0]]
+		out := make([]string, len(header))
+		copy(out, row)
+		for i := len(row); i < len(header); i++ {
+			out[i] = values[header[i]]
+		}
+		merged = append(merged, out)
+	}
+	for _, seq := range order {
+		if known[seq] {
+			continue
+		}
+		out := make([]string, len(header))
+		for i, column := range header {
+			out[i] = remoteRows[seq][column]
+		}
+		merged = append(merged, out)
+	}
+	return encodeSheet(l, header, merged), nil
+}
+
+// encodeSheet writes a sheet with the list separator of l, so spreadsheets in the locale split it
+func encodeSheet(l locale.Locale, header []string, rows [][]string) []byte {
+	var buf bytes.Buffer
+	w := l.CSVWriter(&buf)
+	_ = w.Write(header)
+	_ = w.WriteAll(rows)
+	return buf.Bytes()
+}
+
+// mediaContent returns a generated file from MEDIA_CACHE_DIR, or downloads it from the CDN,
+// asking Suno for a fresh link once when the current one has expired
+func (e *Engine) mediaContent(ctx context.Context, wf *storage.WorkflowState, kind string, url func() string) ([]byte, error) {
+	if e.cfg.MediaCacheDir != "" {
+		if cached, _ := filepath.Glob(filepath.Join(e.cfg.MediaCacheDir, wf.ID+"-"+kind+".*")); len(cached) > 0 {
+			return os.ReadFile(cached[0])
+		}
+	}
+
+	for renewed := false; ; renewed = true {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s URL: %w", kind, err)
+		}
+		resp, err := syncMediaClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			defer resp.Body.Close() //nolint:errcheck
+			return io.ReadAll(resp.Body)
+		}
+		_ = resp.Body.Close()
+
+		expired := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone
+		if !expired || renewed {
+			return nil, fmt.Errorf("CDN returned %d", resp.StatusCode)
+		}
+		if err := e.RefreshMediaURLs(ctx, wf); err != nil {
+			return nil, fmt.Errorf("link expired and could not be renewed: %w", err)
+		}
+	}
+}
+
+// syncFolderName turns a project into a folder name
+func syncFolderName(project string) string {
+	name := strings.Trim(fileNameUnsafeChars.ReplaceAllString(project, "_"), "._-")
+	if name == "" {
+		return syncNoProject
+	}
+	return name
+}
+
+// mediaExt returns the file extension of a CDN URL, fallback when it has none
+func mediaExt(url, fallback string) string {
+	ext := path.Ext(strings.SplitN(url, "?", 2)[0])
+	if ext == "" || len(ext) > 5 {
+		return fallback
+	}
+	return ext
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
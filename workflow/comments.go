@@ -0,0 +1,34 @@
+package workflow
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"workflower/storage"
+)
+
+// MaxCommentChars caps the length of a comment
+const MaxCommentChars = 2000
+
+// ErrEmptyComment is returned when adding a comment without text
+var ErrEmptyComment = newError(CodeInvalid, "comment text is required")
+
+// AddComment records a note by author on the workflow and returns it
+// Comments can be left in any status, including after the workflow has finished.
+func (e *Engine) AddComment(state *storage.WorkflowState, author, text string) (storage.Comment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return storage.Comment{}, ForWorkflow(ErrEmptyComment, state.ID)
+	}
+	if n := utf8.RuneCountInString(text); n > MaxCommentChars {
+		return storage.Comment{}, ForWorkflow(invalidf("comment is %d characters long, at most %d are allowed", n, MaxCommentChars), state.ID)
+	}
+
+	comment := storage.Comment{Author: author, At: time.Now(), Text: text}
+	e.update(func() { state.Comments = append(state.Comments, comment) })
+	e.store.Save(state)
+
+	e.events.Publish(Commented{Comment: comment, Workflow: e.Snapshot(state)})
+	return comment, nil
+}
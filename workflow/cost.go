@@ -0,0 +1,112 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"workflower/lib/llm/openai"
+	"workflower/lib/locale"
+	"workflower/storage"
+)
+
+const (
+	tokensPerMillion = 1_000_000
+	spendMonthLayout = "2006-01"
+)
+
+// usageChatter is a provider that reports the token usage of its replies, like the OpenAI client
+type usageChatter interface {
+	ChatWithUsage(ctx context.Context, systemPrompt, userPrompt string) (string, openai.Usage, error)
+}
+
+// chat runs an LLM call and records its token usage on the workflow and in the monthly spend
+// The house style is appended to the system prompt. The call is bounded by LLM_STEP_TIMEOUT and
+// MAX_TOKENS_PER_WORKFLOW (see guardLLM).
+func (e *Engine) chat(ctx context.Context, state *storage.WorkflowState, systemPrompt, userPrompt string) (string, error) {
+	systemPrompt = withHouseStyle(systemPrompt, e.Settings().HouseStyle)
+	var content string
+	err := e.guardLLM(ctx, state, func(ctx context.Context) error {
+		var usage openai.Usage
+		var err error
+		if chatter, ok := e.llm.(usageChatter); ok {
+			content, usage, err = chatter.ChatWithUsage(ctx, systemPrompt, userPrompt)
+		} else {
+			content, err = e.llm.Chat(ctx, systemPrompt, userPrompt)
+		}
+		if usage.TotalTokens > 0 {
+			e.recordLLMUsage(state, usage)
+		}
+		return err
+	})
+	return content, err
+}
+
+// withHouseStyle appends the deployment's house style guide to a system prompt
+func withHouseStyle(systemPrompt, houseStyle string) string {
+	if houseStyle == "" {
+		return systemPrompt
+	}
+	return strings.TrimRight(systemPrompt, "\n") + "\n\nHouse style (follow it in everything you write, keeping the response format asked for above):\n" + houseStyle
+}
+
+// recordLLMUsage accumulates token usage and cost on the workflow and in the monthly spend
+func (e *Engine) recordLLMUsage(state *storage.WorkflowState, usage openai.Usage) {
+	cost := float64(usage.PromptTokens)*e.cfg.OpenAIPromptPricePerMTok/tokensPerMillion +
+		float64(usage.CompletionTokens)*e.cfg.OpenAICompletionPricePerMTok/tokensPerMillion
+
+	e.mu.Lock()
+	state.Usage.PromptTokens += usage.PromptTokens
+	state.Usage.CompletionTokens += usage.CompletionTokens
+	state.Usage.LLMCostUSD += cost
+	e.mu.Unlock()
+
+	e.store.AddSpend(storage.MonthlySpend{
+		Month:            e.spendMonth(time.Now()),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		LLMCostUSD:       cost,
+	})
+}
+
+// estimateSunoCredits returns the credits the Suno submission of a workflow will consume
+// Long songs are charged per segment (one generation plus one extension each); the concat is free.
+// A/B submissions are charged one generation per variant
+func (e *Engine) estimateSunoCredits(state *storage.WorkflowState) int {
+	lyrics := submittedLyrics(state)
+	if !e.isLongSong(state, lyrics) {
+		if state.VariantB != nil {
+			return 2 * e.cfg.SunoCreditsPerGeneration
+		}
+		return e.cfg.SunoCreditsPerGeneration
+	}
+	return len(splitLyricsSegments(lyrics, e.cfg.LongSongSegmentChars)) * e.cfg.SunoCreditsPerGeneration
+}
+
+// recordSunoCredits charges Suno credits to the workflow and the monthly spend
+func (e *Engine) recordSunoCredits(state *storage.WorkflowState, credits int) {
+	e.mu.Lock()
+	state.Usage.SunoCredits += credits
+	e.mu.Unlock()
+	e.store.AddSpend(storage.MonthlySpend{
+		Month:       e.spendMonth(time.Now()),
+		SunoCredits: credits,
+	})
+}
+
+// MonthlySpend returns the cumulative spend of the month containing t
+func (e *Engine) MonthlySpend(t time.Time) storage.MonthlySpend {
+	return e.store.GetMonthlySpend(e.spendMonth(t))
+}
+
+// spendMonth buckets spend by calendar month in the display time zone
+func (e *Engine) spendMonth(t time.Time) string {
+	return t.In(e.cfg.DisplayLocation).Format(spendMonthLayout)
+}
+
+// formatCost renders a workflow's spend for notifications
+func formatCost(l locale.Locale, usage storage.Usage) string {
+	return fmt.Sprintf("%s Suno credits + $%s OpenAI (%s tokens)",
+		l.Int(usage.EstimatedSunoCredits), l.Number(usage.LLMCostUSD, 4), l.Int(usage.PromptTokens+usage.CompletionTokens))
+}
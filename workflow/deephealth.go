@@ -0,0 +1,190 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// deepHealthTimeout bounds the check of a single dependency
+	deepHealthTimeout = 10 * time.Second
+	// deepHealthCacheTTL is how long a deep check is served again; /health is public, so
+	// a burst of requests must not turn into a burst of calls to OpenAI, Suno and Telegram
+	deepHealthCacheTTL = 15 * time.Second
+	// telegramErrorWindow is how long a failed webhook delivery degrades the Telegram check
+	telegramErrorWindow = 10 * time.Minute
+)
+
+// Dependency states of a deep health check
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // reachable, but not working as configured
+	HealthDown     = "down"
+	HealthSkipped  = "skipped" // not configured
+)
+
+// DependencyHealth is the result of checking one dependency
+type DependencyHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeepHealthReport is the result of checking every dependency
+type DeepHealthReport struct {
+	Status       string                      `json:"status"` // the worst dependency status
+	CheckedAt    time.Time                   `json:"checked_at"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// errSkipped marks a dependency that is not configured
+var errSkipped = errors.New("skipped")
+
+// degradedError marks a check failure that leaves the dependency usable
+type degradedError struct{ msg string }
+
+func (e *degradedError) Error() string { return e.msg }
+
+func degraded(format string, args ...any) error {
+	return &degradedError{fmt.Sprintf(format, args...)}
+}
+
+// DeepHealth checks OpenAI, the suno-api server, the store and the Telegram webhook
+// concurrently, each within deepHealthTimeout
+// Reports are cached for deepHealthCacheTTL; concurrent callers wait for the running check.
+func (e *Engine) DeepHealth(ctx context.Context) DeepHealthReport {
+	e.deepHealthMu.Lock()
+	defer e.deepHealthMu.Unlock()
+	if e.deepHealth != nil && time.Since(e.deepHealth.CheckedAt) < deepHealthCacheTTL {
+		return *e.deepHealth
+	}
+
+	checks := map[string]func(context.Context) (string, error){
+		"openai":   e.checkOpenAI,
+		"suno":     e.checkSuno,
+		"storage":  e.checkStorage,
+		"telegram": e.checkTelegram,
+	}
+	report := DeepHealthReport{Status: HealthOK, CheckedAt: time.Now(), Dependencies: make(map[string]DependencyHealth)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Go(func() {
+			health := runHealthCheck(ctx, check)
+			mu.Lock()
+			report.Dependencies[name] = health
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	for _, health := range report.Dependencies {
+		switch {
+		case health.Status == HealthDown:
+			report.Status = HealthDown
+		case health.Status == HealthDegraded && report.Status == HealthOK:
+			report.Status = HealthDegraded
+		}
+	}
+	e.deepHealth = &report
+	return report
+}
+
+// runHealthCheck times a check, which returns a detail and errSkipped when the dependency is
+// not configured, or a degradedError when it answers but misbehaves
+func runHealthCheck(ctx context.Context, check func(context.Context) (string, error)) DependencyHealth {
+	checkCtx, cancel := context.WithTimeout(ctx, deepHealthTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check(checkCtx)
+	health := DependencyHealth{Status: HealthOK, LatencyMS: time.Since(start).Milliseconds(), Detail: detail}
+	switch {
+	case errors.Is(err, errSkipped):
+		health = DependencyHealth{Status: HealthSkipped, Detail: detail}
+	case errors.As(err, new(*degradedError)):
+		health.Status = HealthDegraded
+		health.Error = err.Error()
+	case err != nil:
+		health.Status = HealthDown
+		health.Error = err.Error()
+	}
+	return health
+}
+
+func (e *Engine) checkOpenAI(ctx context.Context) (string, error) {
+	if !e.cfg.HasOpenAI() {
+		return "no API key configured", errSkipped
+	}
+	if err := e.openAI.Ping(ctx); err != nil {
+		return "", err
+	}
+	return "model " + e.cfg.OpenAIModel, nil
+}
+
+// checkSuno asks the suno-api server for the remaining credits (GET /api/get_limit)
+func (e *Engine) checkSuno(ctx context.Context) (string, error) {
+	quota, err := e.sunoAPI.GetQuota(ctx)
+	if err != nil {
+		if isSunoAuthError(err) {
+			return "session expired", err
+		}
+		return "", err
+	}
+	detail := fmt.Sprintf("%d credits left", quota.CreditsLeft)
+	if quota.CreditsLeft <= 0 {
+		return detail, degraded("no credits left")
+	}
+	if accounts := e.SunoAccounts(); len(accounts) > 0 {
+		var usable int
+		for _, account := range accounts {
+			if !account.Exhausted && account.LastError == "" {
+				usable++
+			}
+		}
+		detail += fmt.Sprintf(" on %d of %d accounts", usable, len(accounts))
+	}
+	return detail, nil
+}
+
+func (e *Engine) checkStorage(ctx context.Context) (string, error) {
+	detail := "in memory"
+	if e.cfg.StoreFile != "" {
+		detail = "file " + e.cfg.StoreFile
+	}
+	if e.cfg.ArchiveDir != "" {
+		detail += ", archive " + e.cfg.ArchiveDir
+	}
+	return detail, e.store.Check()
+}
+
+// checkTelegram compares the registered webhook with TELEGRAM_WEBHOOK_URL and reports
+// delivery failures Telegram saw recently
+func (e *Engine) checkTelegram(ctx context.Context) (string, error) {
+	if e.cfg.TelegramBotToken == "" {
+		return "no bot token configured", errSkipped
+	}
+	info, err := e.telegram.GetWebhookInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	detail := fmt.Sprintf("%d pending updates", info.PendingUpdateCount)
+	if info.URL != "" {
+		detail = info.URL + ", " + detail
+	}
+	switch {
+	case e.cfg.TelegramWebhookURL != "" && info.URL == "":
+		return detail, degraded("no webhook registered")
+	case e.cfg.TelegramWebhookURL != "" && info.URL != e.cfg.TelegramWebhookURL:
+		return detail, degraded("webhook points to %s instead of %s", info.URL, e.cfg.TelegramWebhookURL)
+	case info.LastErrorDate > 0 && time.Since(time.Unix(info.LastErrorDate, 0)) < telegramErrorWindow:
+		return detail, degraded("delivery failed at %s: %s",
+			time.Unix(info.LastErrorDate, 0).UTC().Format(time.RFC3339), info.LastErrorMessage)
+	}
+	return detail, nil
+}
@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies the errors the engine returns to its callers; the web handlers answer
+// with the HTTP status of the code
+type ErrorCode string
+
+const (
+	CodeInvalid     ErrorCode = "invalid"      // the request cannot be carried out as given
+	CodeForbidden   ErrorCode = "forbidden"    // the caller may not do this
+	CodeNotFound    ErrorCode = "not_found"    // no such workflow, preset or file
+	CodeConflict    ErrorCode = "conflict"     // the workflow is not in a status that allows it
+	CodeRateLimited ErrorCode = "rate_limited" // a limit was reached, retry later
+	CodeUnavailable ErrorCode = "unavailable"  // a dependency is not configured or not reachable
+	CodeInternal    ErrorCode = "internal"     // anything unexpected
+)
+
+// Error is an engine error with its code and the workflow it concerns, if any
+// Sentinel errors such as ErrFinished are *Error values; the engine wraps them with the workflow
+// ID, so errors.Is still matches them.
+type Error struct {
+	Code       ErrorCode
+	WorkflowID string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrWorkflowNotFound is returned for an unknown workflow ID or number
+var ErrWorkflowNotFound = newError(CodeNotFound, "workflow not found")
+
+// NewError returns an error with a code, concerning the workflow with the given ID ("" for none)
+func NewError(code ErrorCode, workflowID, message string) error {
+	return &Error{Code: code, WorkflowID: workflowID, Err: errors.New(message)}
+}
+
+// ErrorCodeOf returns the code of the first *Error in the chain of err, CodeInternal without one
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// ErrorWorkflowID returns the ID of the workflow err concerns, "" for none
+func ErrorWorkflowID(err error) string {
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			return ""
+		}
+		if e.WorkflowID != "" {
+			return e.WorkflowID
+		}
+		err = e.Err
+	}
+	return ""
+}
+
+// ForWorkflow marks err as concerning the workflow with the given ID, keeping its code
+func ForWorkflow(err error, workflowID string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: ErrorCodeOf(err), WorkflowID: workflowID, Err: err}
+}
+
+// newError returns a sentinel error with a code
+func newError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Err: errors.New(message)}
+}
+
+// invalidf returns an error of CodeInvalid
+func invalidf(format string, args ...any) error {
+	return &Error{Code: CodeInvalid, Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,72 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"workflower/storage"
+)
+
+// Escalation levels of an assigned review
+const (
+	EscalationAssignee = iota
+	EscalationBackup
+	EscalationAdmin
+)
+
+// escalationActor is recorded in the audit log for automatic escalations
+const escalationActor = "escalation"
+
+// checkEscalations escalates assigned reviews that were not handled within ESCALATION_AFTER:
+// first to the backup reviewer, then to the admin channel
+func (e *Engine) checkEscalations(ctx context.Context, now time.Time) {
+	if e.cfg.EscalationAfter <= 0 {
+		return
+	}
+
+	for _, state := range e.store.ListByStatus(storage.StatusAwaitingReview) {
+		if state.Assignee == "" || state.AssignedAt == nil || state.EscalationLevel >= EscalationAdmin {
+			continue
+		}
+		if now.Sub(*state.AssignedAt) < e.cfg.EscalationAfter {
+			continue
+		}
+		e.escalate(ctx, state, now)
+	}
+}
+
+// escalate moves a review one level up the chain
+// Without a backup reviewer (or when the backup already has it) the review goes straight to the admin channel
+func (e *Engine) escalate(ctx context.Context, state *storage.WorkflowState, now time.Time) {
+	backup := e.cfg.BackupReviewer
+	if state.EscalationLevel == EscalationAssignee && backup != "" && backup != state.Assignee {
+		slog.InfoContext(logContext(state), "Escalating review to backup reviewer", "workflow_id", state.ID, "from", state.Assignee, "to", backup)
+		e.update(func() { state.EscalationLevel = EscalationBackup })
+		// Reassigning re-sends the review notification to the backup reviewer
+		e.assign(state, backup, escalationActor)
+		e.events.Publish(Escalated{Level: EscalationBackup, To: backup, At: now, Workflow: e.Snapshot(state)})
+		return
+	}
+
+	slog.InfoContext(logContext(state), "Escalating review to admin channel", "workflow_id", state.ID, "assignee", state.Assignee)
+	e.update(func() { state.EscalationLevel = EscalationAdmin })
+	e.store.Save(state)
+	e.events.Publish(Escalated{Level: EscalationAdmin, To: e.cfg.EscalationChatID, At: now, Workflow: e.Snapshot(state)})
+
+	if e.cfg.TelegramBotToken == "" || e.cfg.EscalationChatID == "" {
+		return
+	}
+	message := e.Message(e.cfg.EscalationChatID, "escalation", map[string]any{
+		"Workflow":  state,
+		"Label":     digestLabel(state),
+		"Since":     e.cfg.Locale.Display(*state.AssignedAt, e.ChatLocation(e.cfg.EscalationChatID)),
+		"ReviewURL": e.cfg.BaseURL + "/review/" + state.ID,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+	defer cancel()
+	if err := e.notifier.SendToChat(ctx, e.cfg.EscalationChatID, message); err != nil {
+		slog.WarnContext(logContext(state), "Failed to send escalation notification", "error", err, "workflow_id", state.ID)
+	}
+}
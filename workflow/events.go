@@ -0,0 +1,137 @@
+package workflow
+
+import (
+	"time"
+
+	"workflower/storage"
+)
+
+// Event names
+const (
+	EventStepStarted   = "step_started"
+	EventStepFinished  = "step_finished"
+	EventStatusChanged = "status_changed"
+	EventProgress      = "progress"
+	EventAssigned      = "assigned"
+	EventEscalated     = "escalated"
+	EventDeleted       = "deleted"
+	EventReviewed      = "reviewed"
+	EventCommented     = "commented"
+)
+
+// Event is emitted by the engine on the internal event bus
+type Event interface {
+	Name() string
+	WorkflowID() string
+}
+
+// StepStarted is emitted when an engine step begins
+type StepStarted struct {
+	ID   string    `json:"workflow_id"`
+	Step string    `json:"step"`
+	At   time.Time `json:"at"`
+}
+
+// StepFinished is emitted when an engine step ends, successfully or not
+type StepFinished struct {
+	ID       string        `json:"workflow_id"`
+	Step     string        `json:"step"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// StatusChanged is emitted when a workflow moves to a new status
+// Workflow is a snapshot taken at the time of the transition
+type StatusChanged struct {
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	At       time.Time             `json:"at"`
+	Workflow storage.WorkflowState `json:"workflow"`
+}
+
+// Progress is emitted as a multi-clip (long-song) generation advances
+type Progress struct {
+	ID    string    `json:"workflow_id"`
+	Done  int       `json:"done"`
+	Total int       `json:"total"`
+	At    time.Time `json:"at"`
+}
+
+// Assigned is emitted when a workflow's reviewer changes
+// Workflow is a snapshot taken after the change
+type Assigned struct {
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	By       string                `json:"by"`
+	At       time.Time             `json:"at"`
+	Workflow storage.WorkflowState `json:"workflow"`
+}
+
+// Escalated is emitted when an unhandled review moves up the escalation chain
+// Workflow is a snapshot taken after the escalation
+type Escalated struct {
+	Level    int                   `json:"level"`
+	To       string                `json:"to"` // backup reviewer identity or admin chat
+	At       time.Time             `json:"at"`
+	Workflow storage.WorkflowState `json:"workflow"`
+}
+
+// Deleted is emitted when a workflow is removed
+// Workflow is a snapshot taken before the removal
+type Deleted struct {
+	By       string                `json:"by"`
+	At       time.Time             `json:"at"`
+	Workflow storage.WorkflowState `json:"workflow"`
+}
+
+// Review decisions
+const (
+	DecisionApproved       = "approved"
+	DecisionApprovedEdited = "approved with edits"
+	DecisionRejected       = "rejected"
+)
+
+// Reviewed is emitted when a reviewer approves or rejects a workflow
+// Turnaround is the time since the review was requested from (or reassigned to) the reviewer.
+type Reviewed struct {
+	By         string                `json:"by"`
+	Decision   string                `json:"decision"`
+	Turnaround time.Duration         `json:"turnaround"`
+	At         time.Time             `json:"at"`
+	Workflow   storage.WorkflowState `json:"workflow"`
+}
+
+// Commented is emitted when a note is left on a workflow
+// Workflow is a snapshot taken after the comment was added
+type Commented struct {
+	Comment  storage.Comment       `json:"comment"`
+	Workflow storage.WorkflowState `json:"workflow"`
+}
+
+func (e StepStarted) Name() string       { return EventStepStarted }
+func (e StepStarted) WorkflowID() string { return e.ID }
+
+func (e StepFinished) Name() string       { return EventStepFinished }
+func (e StepFinished) WorkflowID() string { return e.ID }
+
+func (e StatusChanged) Name() string       { return EventStatusChanged }
+func (e StatusChanged) WorkflowID() string { return e.Workflow.ID }
+
+func (e Progress) Name() string       { return EventProgress }
+func (e Progress) WorkflowID() string { return e.ID }
+
+func (e Assigned) Name() string       { return EventAssigned }
+func (e Assigned) WorkflowID() string { return e.Workflow.ID }
+
+func (e Escalated) Name() string       { return EventEscalated }
+func (e Escalated) WorkflowID() string { return e.Workflow.ID }
+
+func (e Deleted) Name() string       { return EventDeleted }
+func (e Deleted) WorkflowID() string { return e.Workflow.ID }
+
+func (e Reviewed) Name() string       { return EventReviewed }
+func (e Reviewed) WorkflowID() string { return e.Workflow.ID }
+
+func (e Commented) Name() string       { return EventCommented }
+func (e Commented) WorkflowID() string { return e.Workflow.ID }
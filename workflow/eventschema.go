@@ -0,0 +1,203 @@
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"workflower/storage"
+
+	"github.com/google/uuid"
+)
+
+// EventSchemaVersion is the version of the outbound event schema: webhook payloads and the
+// events streamed over SSE
+// Fields are only ever added within a version; removing or renaming a field, or changing its
+// type or meaning, raises the version (see "Event Schema" in the README).
+const EventSchemaVersion = 1
+
+// EventEnvelope is the external form of an engine event, as streamed over SSE
+// The engine's own events carry full workflow snapshots whose fields change with every
+// release; Data only holds the fields of the event's data type below.
+type EventEnvelope struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"` // unique per event, to deduplicate
+	Event         string    `json:"event"`
+	WorkflowID    string    `json:"workflow_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Data          any       `json:"data"`
+}
+
+// EventWorkflow is the workflow summary of event data and webhook payloads
+type EventWorkflow struct {
+	ID          string    `json:"id"`
+	Seq         int       `json:"seq"`
+	Status      string    `json:"status"`
+	Title       string    `json:"title,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	ExternalRef string    `json:"external_ref,omitempty"`
+	Assignee    string    `json:"assignee,omitempty"`
+	StatusURL   string    `json:"status_url"`
+	ReviewURL   string    `json:"review_url,omitempty"`
+	AudioURL    string    `json:"audio_url,omitempty"`
+	VideoURL    string    `json:"video_url,omitempty"`
+	StemsURL    string    `json:"stems_url,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StepEventData is the data of step_started and step_finished
+type StepEventData struct {
+	Step       string `json:"step"`
+	DurationMS int64  `json:"duration_ms,omitempty"` // step_finished only
+	Error      string `json:"error,omitempty"`       // step_finished of a failed run
+}
+
+// StatusEventData is the data of status_changed
+type StatusEventData struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Workflow EventWorkflow `json:"workflow"`
+}
+
+// ProgressEventData is the data of progress (long-song clips done)
+type ProgressEventData struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// AssignmentEventData is the data of assigned
+type AssignmentEventData struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	By       string        `json:"by"`
+	Workflow EventWorkflow `json:"workflow"`
+}
+
+// EscalationEventData is the data of escalated
+type EscalationEventData struct {
+	Level    int           `json:"level"` // 1 backup reviewer, 2 admin channel
+	To       string        `json:"to"`
+	Workflow EventWorkflow `json:"workflow"`
+}
+
+// DeletionEventData is the data of deleted
+type DeletionEventData struct {
+	By       string        `json:"by"`
+	Workflow EventWorkflow `json:"workflow"`
+}
+
+// ReviewEventData is the data of reviewed
+type ReviewEventData struct {
+	By           string        `json:"by"`
+	Decision     string        `json:"decision"`
+	TurnaroundMS int64         `json:"turnaround_ms"`
+	Workflow     EventWorkflow `json:"workflow"`
+}
+
+// CommentEventData is the data of commented
+type CommentEventData struct {
+	Author   string        `json:"author"`
+	Text     string        `json:"text"`
+	At       time.Time     `json:"at"`
+	Workflow EventWorkflow `json:"workflow"`
+}
+
+// eventDataTypes maps every event name to its data type
+var eventDataTypes = map[string]any{
+	EventStepStarted:   StepEventData{},
+	EventStepFinished:  StepEventData{},
+	EventStatusChanged: StatusEventData{},
+	EventProgress:      ProgressEventData{},
+	EventAssigned:      AssignmentEventData{},
+	EventEscalated:     EscalationEventData{},
+	EventDeleted:       DeletionEventData{},
+	EventReviewed:      ReviewEventData{},
+	EventCommented:     CommentEventData{},
+}
+
+// EventDataTypes returns the zero value of the data type of every event, by event name
+func EventDataTypes() map[string]any {
+	return maps.Clone(eventDataTypes)
+}
+
+// WebhookEvents returns the names of the webhook events, sorted
+// Their payload data is an EventWorkflow.
+func WebhookEvents() []string {
+	var names []string
+	for status := range webhookStatuses {
+		names = append(names, webhookEventPrefix+status)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ExternalEvent returns the external form of an engine event
+func (e *Engine) ExternalEvent(event Event) EventEnvelope {
+	envelope := EventEnvelope{
+		SchemaVersion: EventSchemaVersion,
+		ID:            uuid.New().String(),
+		Event:         event.Name(),
+		WorkflowID:    event.WorkflowID(),
+		Timestamp:     time.Now().UTC(),
+	}
+	workflow := func(state *storage.WorkflowState) EventWorkflow { return newEventWorkflow(e.cfg.BaseURL, state) }
+
+	switch ev := event.(type) {
+	case StepStarted:
+		envelope.Timestamp, envelope.Data = ev.At.UTC(), StepEventData{Step: ev.Step}
+	case StepFinished:
+		envelope.Timestamp = ev.At.UTC()
+		envelope.Data = StepEventData{Step: ev.Step, DurationMS: ev.Duration.Milliseconds(), Error: ev.Error}
+	case StatusChanged:
+		envelope.Timestamp = ev.At.UTC()
+		envelope.Data = StatusEventData{From: ev.From, To: ev.To, Workflow: workflow(&ev.Workflow)}
+	case Progress:
+		envelope.Timestamp, envelope.Data = ev.At.UTC(), ProgressEventData{Done: ev.Done, Total: ev.Total}
+	case Assigned:
+		envelope.Timestamp = ev.At.UTC()
+		envelope.Data = AssignmentEventData{From: ev.From, To: ev.To, By: ev.By, Workflow: workflow(&ev.Workflow)}
+	case Escalated:
+		envelope.Timestamp = ev.At.UTC()
+		envelope.Data = EscalationEventData{Level: ev.Level, To: ev.To, Workflow: workflow(&ev.Workflow)}
+	case Deleted:
+		envelope.Timestamp, envelope.Data = ev.At.UTC(), DeletionEventData{By: ev.By, Workflow: workflow(&ev.Workflow)}
+	case Reviewed:
+		envelope.Timestamp = ev.At.UTC()
+		envelope.Data = ReviewEventData{
+			By: ev.By, Decision: ev.Decision, TurnaroundMS: ev.Turnaround.Milliseconds(), Workflow: workflow(&ev.Workflow),
+		}
+	case Commented:
+		envelope.Timestamp = ev.Comment.At.UTC()
+		envelope.Data = CommentEventData{Author: ev.Comment.Author, Text: ev.Comment.Text, At: ev.Comment.At, Workflow: workflow(&ev.Workflow)}
+	default:
+		envelope.Data = struct{}{}
+	}
+	return envelope
+}
+
+// newEventWorkflow returns the summary of a workflow for events and webhooks
+func newEventWorkflow(baseURL string, state *storage.WorkflowState) EventWorkflow {
+	data := EventWorkflow{
+		ID:          state.ID,
+		Seq:         state.Seq,
+		Status:      state.Status,
+		Title:       state.Title,
+		Project:     state.Project,
+		ExternalRef: state.ExternalRef,
+		Assignee:    state.Assignee,
+		StatusURL:   fmt.Sprintf("%s/workflow/%s", baseURL, state.ID),
+		AudioURL:    state.AudioURL,
+		VideoURL:    state.VideoURL,
+		StemsURL:    state.StemsURL,
+		Error:       state.ErrorMsg,
+		CreatedAt:   state.CreatedAt,
+		UpdatedAt:   state.UpdatedAt,
+	}
+	if state.Status == storage.StatusAwaitingReview {
+		data.ReviewURL = fmt.Sprintf("%s/review/%s", baseURL, state.ID)
+	}
+	return data
+}
@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"log/slog"
+	"time"
+
+	"workflower/storage"
+)
+
+// expireDrafts moves workflows awaiting review for WORKFLOW_EXPIRE_AFTER to expired, dropping
+// their transcript and lyrics with EXPIRY_PURGE
+// The wait starts when the review was (re)assigned; a due date still ahead keeps the draft open.
+func (e *Engine) expireDrafts(now time.Time) {
+	if e.cfg.WorkflowExpireAfter <= 0 {
+		return
+	}
+
+	for _, state := range e.store.ListByStatus(storage.StatusAwaitingReview) {
+		since := state.UpdatedAt
+		if state.AssignedAt != nil {
+			since = *state.AssignedAt
+		}
+		if now.Sub(since) < e.cfg.WorkflowExpireAfter || (state.DueAt != nil && state.DueAt.After(now)) {
+			continue
+		}
+
+		// A review being decided right now wins over the expiry
+		release, err := e.claimReview(state, storage.StatusAwaitingReview)
+		if err != nil {
+			continue
+		}
+		slog.InfoContext(logContext(state), "Expiring unreviewed workflow", "workflow_id", state.ID, "awaiting_since", since)
+		state.ExpiredAt = &now
+		if e.cfg.ExpiryPurge {
+			purgeDraft(state, now)
+		}
+		e.setStatus(state, storage.StatusExpired)
+		release()
+	}
+}
+
+// purgeDraft drops the pasted transcript and every lyrics revision of a workflow, keeping the
+// task description, properties and step log
+func purgeDraft(state *storage.WorkflowState, now time.Time) {
+	state.Transcript = ""
+	state.SourceLyrics = ""
+	state.Lyrics = ""
+	state.LyricsWithBrackets = ""
+	state.EditedLyrics = ""
+	state.LyricsIssues = nil
+	state.SimilarityFlags = nil
+	state.PurgedAt = &now
+}
@@ -0,0 +1,240 @@
+package workflow
+
+import (
+	"time"
+
+	"workflower/storage"
+)
+
+// Graph node kinds
+const (
+	NodeLLM   = "llm"
+	NodeSuno  = "suno"
+	NodeCheck = "check"
+	NodeHuman = "human"
+)
+
+// Graph node statuses
+const (
+	NodePending = "pending"
+	NodeRunning = "running"
+	NodeDone    = "done"
+	NodeFailed  = "failed"
+	NodeBlocked = "blocked"
+	NodeSkipped = "skipped"
+)
+
+// StepReview is the human review between preparation and Suno submission
+// It is not an engine step, so it only appears in the graph
+const StepReview = "review"
+
+// Graph is the step DAG of a workflow with the recorded runs of every step
+type Graph struct {
+	WorkflowID string      `json:"workflow_id"`
+	Status     string      `json:"status"`
+	Nodes      []GraphNode `json:"nodes"`
+	Edges      []GraphEdge `json:"edges"`
+}
+
+// GraphNode is one step of the workflow
+type GraphNode struct {
+	Step       string     `json:"step"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	Runs       int        `json:"runs"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`  // first run
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // last run
+	DurationMS int64      `json:"duration_ms"`           // sum over all runs
+	Error      string     `json:"error,omitempty"`       // of the last run
+}
+
+// GraphEdge connects a step to a step that depends on it
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// graphStep describes a node of the step DAG
+type graphStep struct {
+	step      string
+	kind      string
+	dependsOn []string
+}
+
+// Graph builds the step DAG of a workflow from its options and recorded step runs
+// Optional steps (moderation, persona, similarity, long-song extension, stems) only appear when they apply.
+func (e *Engine) Graph(state *storage.WorkflowState) *Graph {
+	runs := make(map[string][]storage.StepRun)
+	for _, run := range state.Steps {
+		runs[run.Step] = append(runs[run.Step], run)
+	}
+	longSong := state.LongSong || len(state.Segments) > 0
+
+	var steps []graphStep
+	last := ""
+	// add appends a step depending on the given steps, or on the previous one when none are given
+	add := func(step, kind string, dependsOn ...string) {
+		gs := graphStep{step: step, kind: kind, dependsOn: dependsOn}
+		if len(dependsOn) == 0 && last != "" {
+			gs.dependsOn = []string{last}
+		}
+		steps = append(steps, gs)
+		last = step
+	}
+
+	if state.Transcript != "" || len(runs[StepTranscript]) > 0 {
+		add(StepTranscript, NodeLLM)
+	}
+	if e.cfg.EnableModeration || len(runs[StepModeration]) > 0 {
+		add(StepModeration, NodeLLM)
+	}
+	add(StepLyrics, NodeLLM)
+	add(StepProperties, NodeLLM)
+	// Brackets and persona/inspo run concurrently (see runParallelSteps)
+	add(StepBrackets, NodeLLM, StepProperties)
+	prepared := []string{StepBrackets}
+	if state.IsPremium {
+		add(StepPersonaInspo, NodeLLM, StepProperties)
+		prepared = append(prepared, StepPersonaInspo)
+	}
+	if e.cfg.SimilarityLLM || len(runs[StepSimilarity]) > 0 {
+		add(StepSimilarity, NodeLLM, prepared...)
+		prepared = []string{StepSimilarity}
+	}
+	add(StepValidation, NodeCheck, prepared...)
+	add(StepReview, NodeHuman)
+	add(StepSubmission, NodeSuno)
+	if longSong {
+		add(StepExtend, NodeSuno)
+	}
+	add(StepCompletion, NodeSuno)
+	if longSong {
+		add(StepConcat, NodeSuno)
+	}
+	if state.GenerateStems {
+		add(StepStems, NodeSuno)
+	}
+
+	graph := &Graph{WorkflowID: state.ID, Status: state.Status}
+	for _, gs := range steps {
+		node := GraphNode{Step: gs.step, Kind: gs.kind}
+		if gs.step == StepReview {
+			reviewNode(&node, state, runs)
+		} else {
+			summarizeRuns(&node, runs[gs.step])
+		}
+		graph.Nodes = append(graph.Nodes, node)
+		for _, dep := range gs.dependsOn {
+			graph.Edges = append(graph.Edges, GraphEdge{From: dep, To: gs.step})
+		}
+	}
+	markSkipped(graph, state)
+	return graph
+}
+
+// Levels groups the nodes by their depth in the DAG; the nodes of one level can run concurrently
+func (g *Graph) Levels() [][]GraphNode {
+	depth := make(map[string]int)
+	for _, edge := range g.Edges {
+		// Edges are listed in node order, so every From already has its final depth
+		if d := depth[edge.From] + 1; d > depth[edge.To] {
+			depth[edge.To] = d
+		}
+	}
+
+	var levels [][]GraphNode
+	for _, node := range g.Nodes {
+		d := depth[node.Step]
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], node)
+	}
+	return levels
+}
+
+// summarizeRuns fills a node from the runs of its step
+func summarizeRuns(node *GraphNode, runs []storage.StepRun) {
+	node.Runs = len(runs)
+	if len(runs) == 0 {
+		node.Status = NodePending
+		return
+	}
+
+	node.StartedAt = &runs[0].StartedAt
+	var total time.Duration
+	for _, run := range runs {
+		if run.FinishedAt != nil {
+			total += run.FinishedAt.Sub(run.StartedAt)
+		} else {
+			total += time.Since(run.StartedAt)
+		}
+	}
+	node.DurationMS = total.Milliseconds()
+
+	lastRun := runs[len(runs)-1]
+	node.FinishedAt = lastRun.FinishedAt
+	node.Error = lastRun.Error
+	switch {
+	case lastRun.FinishedAt == nil:
+		node.Status = NodeRunning
+	case lastRun.Error != "":
+		node.Status = NodeFailed
+	default:
+		node.Status = NodeDone
+	}
+}
+
+// reviewNode derives the review node from the workflow status: it starts when the
+// workflow is handed over for review and ends with the first Suno submission
+func reviewNode(node *GraphNode, state *storage.WorkflowState, runs map[string][]storage.StepRun) {
+	node.StartedAt = state.AssignedAt
+	if submissions := runs[StepSubmission]; len(submissions) > 0 {
+		node.FinishedAt = &submissions[0].StartedAt
+	}
+
+	switch state.Status {
+	case storage.StatusAwaitingReview:
+		node.Status = NodeRunning
+	case storage.StatusRejected, storage.StatusExpired:
+		node.Status = NodeFailed
+	case storage.StatusApproved, storage.StatusBlockedAuth, storage.StatusGenerating, storage.StatusCompleted:
+		node.Status = NodeDone
+	default:
+		node.Status = NodePending
+		if node.FinishedAt != nil {
+			node.Status = NodeDone
+		}
+	}
+	if node.Status != NodePending {
+		node.Runs = 1
+	}
+
+	if node.StartedAt != nil {
+		end := time.Now()
+		if node.FinishedAt != nil {
+			end = *node.FinishedAt
+		}
+		node.DurationMS = end.Sub(*node.StartedAt).Milliseconds()
+	}
+}
+
+// markSkipped marks pending steps that were passed over as skipped, and the
+// moderation step of a blocked workflow as blocked
+func markSkipped(graph *Graph, state *storage.WorkflowState) {
+	reached := -1
+	for i, node := range graph.Nodes {
+		if node.Status != NodePending {
+			reached = i
+		}
+	}
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Status == NodePending && i < reached {
+			node.Status = NodeSkipped
+		}
+		if node.Step == StepModeration && state.Status == storage.StatusBlockedModeration {
+			node.Status = NodeBlocked
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"workflower/storage"
+)
+
+// Guard breaches fail the workflow with a clear error and notify the admin channel
+var (
+	ErrStepTimeout     = errors.New("step timed out")
+	ErrTokenBudget     = errors.New("token budget of the workflow exhausted")
+	ErrSunoHourlyLimit = errors.New("hourly Suno submission limit reached")
+)
+
+// llmContext bounds an LLM call by LLM_STEP_TIMEOUT
+func (e *Engine) llmContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.cfg.LLMStepTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.cfg.LLMStepTimeout)
+}
+
+// guardLLM runs an LLM call under the step timeout and the workflow's token budget
+// Running out of the budget is detected before the call: the call that crosses it completes.
+func (e *Engine) guardLLM(ctx context.Context, state *storage.WorkflowState, call func(context.Context) error) error {
+	if max := e.cfg.MaxTokensPerWorkflow; max > 0 {
+		e.mu.Lock()
+		used := state.Usage.PromptTokens + state.Usage.CompletionTokens
+		e.mu.Unlock()
+		if used >= max {
+			return fmt.Errorf("%w (%d of %d tokens used)", ErrTokenBudget, used, max)
+		}
+	}
+
+	llmCtx, cancel := e.llmContext(ctx)
+	defer cancel()
+	err := call(llmCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(llmCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrStepTimeout, e.cfg.LLMStepTimeout)
+	}
+	return err
+}
+
+// reserveSunoSubmission counts a Suno generation against MAX_SUNO_SUBMISSIONS_PER_HOUR,
+// across all workflows, and fails when the last hour is already at the limit
+func (e *Engine) reserveSunoSubmission() error {
+	limit := e.cfg.MaxSunoSubmissionsPerHour
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	recent := e.sunoSubmissions[:0]
+	for _, at := range e.sunoSubmissions {
+		if now.Sub(at) < time.Hour {
+			recent = append(recent, at)
+		}
+	}
+	e.sunoSubmissions = recent
+	if len(recent) >= limit {
+		return fmt.Errorf("%w (%d per hour), retry later", ErrSunoHourlyLimit, limit)
+	}
+	e.sunoSubmissions = append(e.sunoSubmissions, now)
+	return nil
+}
+
+// isGuardBreach reports whether a step failed on one of the engine guards
+func isGuardBreach(err error) bool {
+	return errors.Is(err, ErrStepTimeout) || errors.Is(err, ErrTokenBudget) || errors.Is(err, ErrSunoHourlyLimit)
+}
+
+// alertGuardBreach tells the admin channel which workflow a guard stopped
+func (e *Engine) alertGuardBreach(state *storage.WorkflowState, step string, err error) {
+	e.alert(context.Background(), "guard_breach", map[string]any{
+		"Workflow": state, "Step": step, "Error": err.Error(), "Task": truncateString(state.TaskDescription, 100),
+	})
+}
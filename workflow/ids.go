@@ -0,0 +1,58 @@
+package workflow
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ID schemes of new workflows (ID_SCHEME)
+const (
+	IDSchemeUUIDv4 = "uuid4" // random
+	IDSchemeUUIDv7 = "uuid7" // time-ordered, so IDs sort by creation
+)
+
+// maxExternalRefLength bounds the external reference of a workflow
+const maxExternalRefLength = 200
+
+// IDGenerator returns a new, unique workflow ID
+type IDGenerator func() string
+
+// LookupIDScheme returns the generator of an ID scheme, UUIDv4 for ""
+func LookupIDScheme(name string) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", IDSchemeUUIDv4:
+		return uuid.NewString, nil
+	case IDSchemeUUIDv7:
+		return func() string { return uuid.Must(uuid.NewV7()).String() }, nil
+	default:
+		return nil, fmt.Errorf("unknown ID scheme %q (%s or %s)", name, IDSchemeUUIDv4, IDSchemeUUIDv7)
+	}
+}
+
+// SetIDGenerator replaces the generator of new workflow IDs, e.g. with one issuing the
+// integrator's own IDs; they must be unique and safe in URLs
+func (e *Engine) SetIDGenerator(gen IDGenerator) {
+	e.newID = gen
+}
+
+// idGenerator returns the generator of ID_SCHEME, UUIDv4 when it is unknown
+func idGenerator(scheme string) IDGenerator {
+	gen, err := LookupIDScheme(scheme)
+	if err != nil {
+		slog.Warn("Invalid ID_SCHEME, using "+IDSchemeUUIDv4, "error", err)
+		gen, _ = LookupIDScheme(IDSchemeUUIDv4)
+	}
+	return gen
+}
+
+// checkExternalRef validates the reference an integrator attaches to a new workflow
+func checkExternalRef(ref string) error {
+	if n := utf8.RuneCountInString(ref); n > maxExternalRefLength {
+		return invalidf("external reference is too long (%d characters, at most %d)", n, maxExternalRefLength)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package workflow
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"workflower/lib/locale"
+	"workflower/lib/pdf"
+	"workflower/storage"
+)
+
+const bytesPerGB = 1 << 30
+
+// InvoiceAmounts is the billable usage of a workflow, or the sum over a project or invoice
+type InvoiceAmounts struct {
+	SunoCredits    int     `json:"suno_credits"`
+	SunoCostUSD    float64 `json:"suno_cost_usd"` // credits at INVOICE_CREDIT_PRICE_USD
+	Tokens         int     `json:"tokens"`
+	LLMCostUSD     float64 `json:"llm_cost_usd"`
+	StorageBytes   int64   `json:"storage_bytes"`    // uploaded reference audio and cached media
+	StorageCostUSD float64 `json:"storage_cost_usd"` // at INVOICE_STORAGE_PRICE_PER_GB
+	TotalUSD       float64 `json:"total_usd"`
+}
+
+func (a *InvoiceAmounts) add(b InvoiceAmounts) {
+	a.SunoCredits += b.SunoCredits
+	a.SunoCostUSD += b.SunoCostUSD
+	a.Tokens += b.Tokens
+	a.LLMCostUSD += b.LLMCostUSD
+	a.StorageBytes += b.StorageBytes
+	a.StorageCostUSD += b.StorageCostUSD
+	a.TotalUSD += b.TotalUSD
+}
+
+// InvoiceLine is one workflow on an invoice
+type InvoiceLine struct {
+	WorkflowID string    `json:"workflow_id"`
+	Seq        int       `json:"seq"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	InvoiceAmounts
+}
+
+// ProjectInvoice rolls up the workflows of one project; Project is "" for workflows without one
+type ProjectInvoice struct {
+	Project string        `json:"project"`
+	Lines   []InvoiceLine `json:"lines"` // oldest first
+	InvoiceAmounts
+}
+
+// Invoice is the usage of the workflows started in a period, per project
+type Invoice struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Issuer   string           `json:"issuer,omitempty"`
+	Projects []ProjectInvoice `json:"projects"` // by project name, workflows without one last
+	Total    InvoiceAmounts   `json:"total"`
+}
+
+// Invoice rolls up the spend recorded on the workflows created between from and to per
+// project, priced with INVOICE_CREDIT_PRICE_USD and INVOICE_STORAGE_PRICE_PER_GB; project
+// limits it to one project ("" for all). Workflows that spent nothing are left out.
+func (e *Engine) Invoice(from, to time.Time, project string) Invoice {
+	invoice := Invoice{From: from, To: to, Issuer: e.cfg.InvoiceIssuer, Projects: []ProjectInvoice{}}
+	byProject := map[string]*ProjectInvoice{}
+
+	for state := range e.store.All() {
+		if state.CreatedAt.Before(from) || !state.CreatedAt.Before(to) || (project != "" && state.Project != project) {
+			continue
+		}
+		line := InvoiceLine{
+			WorkflowID:     state.ID,
+			Seq:            state.Seq,
+			Title:          e.title(state),
+			Status:         state.Status,
+			CreatedAt:      state.CreatedAt,
+			InvoiceAmounts: e.invoiceAmounts(state),
+		}
+		if line.SunoCredits == 0 && line.Tokens == 0 && line.StorageBytes == 0 {
+			continue
+		}
+		p, ok := byProject[state.Project]
+		if !ok {
+			p = &ProjectInvoice{Project: state.Project}
+			byProject[state.Project] = p
+		}
+		p.Lines = append(p.Lines, line)
+		p.add(line.InvoiceAmounts)
+	}
+
+	for _, p := range byProject {
+		slices.SortFunc(p.Lines, func(a, b InvoiceLine) int { return a.Seq - b.Seq })
+		invoice.Projects = append(invoice.Projects, *p)
+		invoice.Total.add(p.InvoiceAmounts)
+	}
+	slices.SortFunc(invoice.Projects, func(a, b ProjectInvoice) int {
+		if (a.Project == "") != (b.Project == "") {
+			if a.Project == "" {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Project, b.Project)
+	})
+	return invoice
+}
+
+// invoiceAmounts prices the usage recorded on a workflow
+func (e *Engine) invoiceAmounts(state *storage.WorkflowState) InvoiceAmounts {
+	a := InvoiceAmounts{
+		SunoCredits:  state.Usage.SunoCredits,
+		SunoCostUSD:  float64(state.Usage.SunoCredits) * e.cfg.InvoiceCreditPriceUSD,
+		Tokens:       state.Usage.PromptTokens + state.Usage.CompletionTokens,
+		LLMCostUSD:   state.Usage.LLMCostUSD,
+		StorageBytes: e.storageBytes(state),
+	}
+	a.StorageCostUSD = float64(a.StorageBytes) / bytesPerGB * e.cfg.InvoiceStoragePricePerGB
+	a.TotalUSD = a.SunoCostUSD + a.LLMCostUSD + a.StorageCostUSD
+	return a
+}
+
+// storageBytes returns the size of the files kept for a workflow: its uploaded reference audio
+// and the media cached in MEDIA_CACHE_DIR
+func (e *Engine) storageBytes(state *storage.WorkflowState) int64 {
+	var size int64
+	if state.AudioFilePath != "" {
+		if info, err := os.Stat(state.AudioFilePath); err == nil {
+			size += info.Size()
+		}
+	}
+	if e.cfg.MediaCacheDir != "" {
+		cached, _ := filepath.Glob(filepath.Join(e.cfg.MediaCacheDir, state.ID+"-*"))
+		for _, path := range cached {
+			if info, err := os.Stat(path); err == nil {
+				size += info.Size()
+			}
+		}
+	}
+	return size
+}
+
+// projectName labels a project on invoices
+func projectName(project string) string {
+	if project == "" {
+		return syncNoProject
+	}
+	return project
+}
+
+// WriteCSV writes one row per workflow followed by a total row per project, with dates and
+// amounts formatted for l in loc
+func (inv Invoice) WriteCSV(w io.Writer, l locale.Locale, loc *time.Location) error {
+	out := l.CSVWriter(w)
+	_ = out.Write([]string{"From", "To", "Project", "Workflow", "Title", "Status", "Created",
+		"Suno Credits", "Suno Cost (USD)", "Tokens", "OpenAI Cost (USD)", "Storage (MB)", "Storage Cost (USD)", "Total (USD)"})
+	from, to := l.Date(inv.From, loc), l.Date(inv.To.Add(-time.Nanosecond), loc)
+	amounts := func(a InvoiceAmounts) []string {
+		return []string{
+			l.Int(a.SunoCredits), l.Number(a.SunoCostUSD, 2), l.Int(a.Tokens), l.Number(a.LLMCostUSD, 2),
+			l.Number(float64(a.StorageBytes)/(1<<20), 1), l.Number(a.StorageCostUSD, 2), l.Number(a.TotalUSD, 2),
+		}
+	}
+	for _, p := range inv.Projects {
+		for _, line := range p.Lines {
+			_ = out.Write(append([]string{from, to, projectName(p.Project), "#" + l.Int(line.Seq), line.Title,
+				line.Status, l.DateTime(line.CreatedAt, loc)}, amounts(line.InvoiceAmounts)...))
+		}
+		_ = out.Write(append([]string{from, to, projectName(p.Project), "", "Total", "", ""}, amounts(p.InvoiceAmounts)...))
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Layout of PDF invoices, in points
+const (
+	invoiceMargin   = 50.0
+	invoiceFontSize = 9.0
+	invoiceRow      = 14.0
+)
+
+// invoiceColumns are the amount columns of PDF invoices, right-aligned at their x
+var invoiceColumns = []struct {
+	title string
+	x     float64
+	value func(l locale.Locale, a InvoiceAmounts) string
+}{
+	{"Credits", 330, func(l locale.Locale, a InvoiceAmounts) string { return l.Int(a.SunoCredits) }},
+	{"Suno", 385, func(l locale.Locale, a InvoiceAmounts) string { return "$" + l.Number(a.SunoCostUSD, 2) }},
+	{"OpenAI", 440, func(l locale.Locale, a InvoiceAmounts) string { return "$" + l.Number(a.LLMCostUSD, 2) }},
+	{"Storage", 495, func(l locale.Locale, a InvoiceAmounts) string { return "$" + l.Number(a.StorageCostUSD, 2) }},
+	{"Total", pdf.PageWidth - invoiceMargin, func(l locale.Locale, a InvoiceAmounts) string { return "$" + l.Number(a.TotalUSD, 2) }},
+}
+
+// WritePDF writes one invoice per project, each starting on a new page, with dates and amounts
+// formatted for l in loc
+func (inv Invoice) WritePDF(w io.Writer, l locale.Locale, loc *time.Location) error {
+	doc := pdf.New()
+	period := l.Date(inv.From, loc) + " - " + l.Date(inv.To.Add(-time.Nanosecond), loc)
+	right := pdf.PageWidth - invoiceMargin
+
+	var y float64
+	header := func(p ProjectInvoice) {
+		doc.AddPage()
+		y = pdf.PageHeight - invoiceMargin
+		if inv.Issuer != "" {
+			doc.Text(invoiceMargin, y, pdf.Bold, 12, inv.Issuer)
+			y -= 2 * invoiceRow
+		}
+		doc.Text(invoiceMargin, y, pdf.Bold, 16, "Invoice: "+projectName(p.Project))
+		y -= 1.5 * invoiceRow
+		doc.Text(invoiceMargin, y, pdf.Regular, 10, "Period: "+period)
+		y -= 2 * invoiceRow
+
+		doc.Text(invoiceMargin, y, pdf.Bold, invoiceFontSize, "Workflow")
+		doc.Text(invoiceMargin+45, y, pdf.Bold, invoiceFontSize, "Created")
+		for _, col := range invoiceColumns {
+			doc.TextRight(col.x, y, pdf.Bold, invoiceFontSize, col.title)
+		}
+		y -= 5
+		doc.Line(invoiceMargin, y, right, y)
+		y -= invoiceRow
+	}
+
+	for _, p := range inv.Projects {
+		header(p)
+		for _, line := range p.Lines {
+			if y < invoiceMargin+3*invoiceRow {
+				header(p)
+			}
+			doc.Text(invoiceMargin, y, pdf.Regular, invoiceFontSize, "#"+l.Int(line.Seq))
+			doc.Text(invoiceMargin+45, y, pdf.Regular, invoiceFontSize, l.Date(line.CreatedAt, loc))
+			doc.Text(invoiceMargin+105, y, pdf.Regular, invoiceFontSize,
+				pdf.Truncate(pdf.Regular, invoiceFontSize, 135, line.Title))
+			for _, col := range invoiceColumns {
+				doc.TextRight(col.x, y, pdf.Regular, invoiceFontSize, col.value(l, line.InvoiceAmounts))
+			}
+			y -= invoiceRow
+		}
+		doc.Line(invoiceMargin, y+invoiceRow-4, right, y+invoiceRow-4)
+		y -= 4
+		doc.Text(invoiceMargin, y, pdf.Bold, invoiceFontSize, "Total ("+l.Int(len(p.Lines))+" workflows)")
+		for _, col := range invoiceColumns {
+			doc.TextRight(col.x, y, pdf.Bold, invoiceFontSize, col.value(l, p.InvoiceAmounts))
+		}
+	}
+	if len(inv.Projects) == 0 {
+		doc.Text(invoiceMargin, pdf.PageHeight-invoiceMargin, pdf.Regular, 10, "No billable usage in "+period)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"strings"
+
+	"workflower/lib/langdetect"
+	"workflower/storage"
+)
+
+// Language is a lyrics language offered on the start form and via /lang
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages lists the supported lyrics languages
+var Languages = []Language{
+	{Code: "en", Name: "English"},
+	{Code: "es", Name: "Spanish"},
+	{Code: "fr", Name: "French"},
+	{Code: "de", Name: "German"},
+	{Code: "it", Name: "Italian"},
+	{Code: "pt", Name: "Portuguese"},
+	{Code: "nl", Name: "Dutch"},
+	{Code: "pl", Name: "Polish"},
+	{Code: "uk", Name: "Ukrainian"},
+	{Code: "ru", Name: "Russian"},
+	{Code: "tr", Name: "Turkish"},
+	{Code: "hi", Name: "Hindi"},
+	{Code: "ja", Name: "Japanese"},
+	{Code: "ko", Name: "Korean"},
+	{Code: "zh", Name: "Chinese"},
+}
+
+// defaultLanguage needs no prompt or tag changes
+const defaultLanguage = "English"
+
+// minDetectionConfidence is the confidence a detected language needs to be used
+const minDetectionConfidence = 0.5
+
+// LookupLanguage resolves a language by code or name (case-insensitive) and returns its name
+func LookupLanguage(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, lang := range Languages {
+		if strings.EqualFold(value, lang.Code) || strings.EqualFold(value, lang.Name) {
+			return lang.Name, true
+		}
+	}
+	return "", false
+}
+
+// DetectLanguage returns the name of the lyrics language text is written in, "" when it cannot be told
+func DetectLanguage(text string) string {
+	code, confidence := langdetect.Detect(text)
+	if code == "" || confidence < minDetectionConfidence {
+		return ""
+	}
+	name, _ := LookupLanguage(code)
+	return name
+}
+
+// languageCode returns the code of a lyrics language name, e.g. "es" for "Spanish"
+func languageCode(name string) string {
+	for _, lang := range Languages {
+		if lang.Name == name {
+			return lang.Code
+		}
+	}
+	return ""
+}
+
+// resolveLanguage returns the lyrics language of a new workflow: the requested one, else the
+// language its text is written in (LANGUAGE_DETECTION), else DEFAULT_LANGUAGE; detected reports
+// the second case
+func (e *Engine) resolveLanguage(params StartParams) (language string, detected bool) {
+	if params.Language != "" {
+		return params.Language, false
+	}
+	if e.cfg.LanguageDetection {
+		// The request says most about the language wanted; imported lyrics and transcripts are
+		// taken into account when there is no request
+		text := params.TaskDescription
+		if strings.TrimSpace(text) == "" {
+			text = params.Transcript + "\n" + params.Lyrics
+		}
+		if name := DetectLanguage(text); name != "" {
+			return name, true
+		}
+	}
+	if name, ok := LookupLanguage(e.cfg.DefaultLanguage); ok {
+		return name, false
+	}
+	return defaultLanguage, false
+}
+
+// NoteChatLanguage makes a Telegram chat without a /language preference reply in the detected
+// language of the workflow it just started, when messages are available in it
+func (e *Engine) NoteChatLanguage(chatID string, state *storage.WorkflowState) {
+	code := languageCode(state.Language)
+	if !state.LanguageDetected || !e.catalog().Has(code) {
+		return
+	}
+	prefs := e.store.GetChatPreferences(chatID)
+	if prefs.Language != "" || prefs.DetectedLanguage == code {
+		return
+	}
+	prefs.DetectedLanguage = code
+	e.store.SaveChatPreferences(chatID, prefs)
+}
+
+// languageInstruction is appended to the lyric prompts of non-English workflows
+func languageInstruction(language string) string {
+	if language == "" || language == defaultLanguage {
+		return ""
+	}
+	return "\n\nLanguage: " + language
+}
+
+// languageTag is added to the Suno tags of non-English workflows, e.g. "spanish vocals"
+func languageTag(language string) string {
+	if language == "" || language == defaultLanguage {
+		return ""
+	}
+	return strings.ToLower(language) + " vocals"
+}
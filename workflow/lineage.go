@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"workflower/storage"
+)
+
+// Lineage relations of a node to its parent
+const (
+	RelationClone     = "clone"     // workflow cloned from its parent: a remix of the concept
+	RelationClip      = "clip"      // clip generated by its workflow
+	RelationVariant   = "variant"   // clip of an A/B variant
+	RelationExtension = "extension" // long-song segment extending the previous one
+	RelationConcat    = "concat"    // full song concatenated from the last extension
+	RelationStems     = "stems"     // stems separated from the final clip
+)
+
+// Lineage is the tree of workflows cloned from one original concept and the clips each of
+// them generated
+type Lineage struct {
+	RootID string        `json:"root_id"` // workflow of the original concept
+	Nodes  []LineageNode `json:"nodes"`   // depth-first, every node after its parent
+}
+
+// LineageNode is a workflow or a clip of the lineage
+type LineageNode struct {
+	ID         string `json:"id"` // workflow ID, or "<workflow ID>/<relation>/..." for a clip
+	WorkflowID string `json:"workflow_id"`
+	Parent     string `json:"parent,omitempty"`
+	Relation   string `json:"relation,omitempty"` // empty for the root
+	Depth      int    `json:"depth"`
+	Label      string `json:"label"`
+	Status     string `json:"status"` // workflow status, or Suno clip status
+	ClipID     string `json:"clip_id,omitempty"`
+	AudioURL   string `json:"audio_url,omitempty"`
+}
+
+// IsWorkflow reports whether the node is a workflow rather than a clip
+func (n LineageNode) IsWorkflow() bool {
+	return n.ID == n.WorkflowID
+}
+
+// HasDerivatives reports whether the lineage holds more than a workflow with its own clip:
+// clones, extensions, variants or stems
+func (l *Lineage) HasDerivatives() bool {
+	for _, node := range l.Nodes {
+		if node.Relation != "" && node.Relation != RelationClip {
+			return true
+		}
+	}
+	return false
+}
+
+// Lineage returns the lineage a workflow belongs to, from the earliest ancestor still stored
+// down to every clone of its clones
+func (e *Engine) Lineage(state *storage.WorkflowState) *Lineage {
+	root := state
+	seen := map[string]bool{state.ID: true}
+	for root.ClonedFrom != "" && !seen[root.ClonedFrom] {
+		parent, ok := e.store.Get(root.ClonedFrom)
+		if !ok {
+			break
+		}
+		seen[parent.ID] = true
+		root = parent
+	}
+
+	clones := make(map[string][]*storage.WorkflowState)
+	for wf := range e.store.All() {
+		if wf.ClonedFrom != "" {
+			clones[wf.ClonedFrom] = append(clones[wf.ClonedFrom], wf)
+		}
+	}
+
+	lineage := &Lineage{RootID: root.ID}
+	visited := make(map[string]bool)
+	var walk func(wf *storage.WorkflowState, parent string, depth int)
+	walk = func(wf *storage.WorkflowState, parent string, depth int) {
+		if visited[wf.ID] {
+			return
+		}
+		visited[wf.ID] = true
+		node := LineageNode{ID: wf.ID, WorkflowID: wf.ID, Parent: parent, Depth: depth, Label: digestLabel(wf), Status: wf.Status}
+		if parent != "" {
+			node.Relation = RelationClone
+		}
+		lineage.Nodes = append(lineage.Nodes, node)
+		lineage.Nodes = append(lineage.Nodes, clipNodes(wf, depth)...)
+
+		children := clones[wf.ID]
+		slices.SortFunc(children, func(a, b *storage.WorkflowState) int { return cmp.Compare(a.Seq, b.Seq) })
+		for _, child := range children {
+			walk(child, wf.ID, depth+1)
+		}
+	}
+	walk(root, "", 0)
+	return lineage
+}
+
+// clipNodes returns the clips a workflow at depth generated: its long-song segments, each
+// extending the previous one, and the concatenated song, or its A/B variants, or its single
+// clip; stems hang off the final clip
+func clipNodes(state *storage.WorkflowState, depth int) []LineageNode {
+	var nodes []LineageNode
+	depths := map[string]int{state.ID: depth}
+	clip := func(key, parent, relation, label, status, clipID, audioURL string) LineageNode {
+		if parent == "" {
+			parent = state.ID
+		}
+		node := LineageNode{
+			ID: state.ID + "/" + key, WorkflowID: state.ID, Parent: parent, Relation: relation, Depth: depths[parent] + 1,
+			Label: label, Status: status, ClipID: clipID, AudioURL: audioURL,
+		}
+		depths[node.ID] = node.Depth
+		return node
+	}
+
+	final := ""
+	switch {
+	case len(state.Segments) > 0:
+		for _, seg := range state.Segments {
+			relation := RelationExtension
+			if final == "" {
+				relation = RelationClip
+			}
+			node := clip(fmt.Sprintf("segment/%d", seg.Index), final, relation, fmt.Sprintf("Segment %d", seg.Index), seg.Status, seg.ClipID, seg.AudioURL)
+			nodes = append(nodes, node)
+			final = node.ID
+		}
+		if state.ConcatClipID != "" {
+			node := clip("concat", final, RelationConcat, "Full song", doneStatus(state.AudioURL), state.ConcatClipID, state.AudioURL)
+			nodes = append(nodes, node)
+			final = node.ID
+		}
+	case len(state.Variants) > 0:
+		for _, variant := range state.Variants {
+			for i, c := range variant.Clips {
+				node := clip(fmt.Sprintf("variant/%s/%d", variant.Label, i+1), "", RelationVariant,
+					fmt.Sprintf("Variant %s · clip %d", variant.Label, i+1), c.Status, c.ID, c.AudioURL)
+				nodes = append(nodes, node)
+				if c.ID == state.SunoJobID {
+					final = node.ID
+				}
+			}
+		}
+	case state.SunoJobID != "" || state.AudioURL != "":
+		node := clip("clip", "", RelationClip, "Song", doneStatus(state.AudioURL), state.SunoJobID, state.AudioURL)
+		nodes = append(nodes, node)
+		final = node.ID
+	}
+
+	if state.StemsClipID != "" || state.StemsURL != "" || state.StemsError != "" {
+		status := doneStatus(state.StemsURL)
+		if state.StemsError != "" {
+			status = "error"
+		}
+		// right after the final clip, so that the nodes stay in depth-first order
+		at := slices.IndexFunc(nodes, func(n LineageNode) bool { return n.ID == final }) + 1
+		nodes = slices.Insert(nodes, at, clip("stems", final, RelationStems, "Stems", status, state.StemsClipID, state.StemsURL))
+	}
+	return nodes
+}
+
+// doneStatus returns the clip status of a result that is ready once its URL is known
+func doneStatus(url string) string {
+	if url == "" {
+		return "generating"
+	}
+	return "complete"
+}
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// isLongSong reports whether the submission must be split into extended segments:
+// either requested explicitly or because the lyrics exceed a single generation
+func (e *Engine) isLongSong(state *storage.WorkflowState, lyrics string) bool {
+	return state.LongSong || len(lyrics) > e.cfg.LongSongSegmentChars
+}
+
+// submitLongSong generates the first segment, extends the clip once per remaining
+// segment and concatenates the final extension into the whole song
+func (e *Engine) submitLongSong(ctx context.Context, state *storage.WorkflowState, lyrics, tags, title string) {
+	parts := splitLyricsSegments(lyrics, e.cfg.LongSongSegmentChars)
+	state.Segments = make([]storage.SongSegment, len(parts))
+	for i, part := range parts {
+		state.Segments[i] = storage.SongSegment{Index: i + 1, Lyrics: part, Status: "pending"}
+	}
+	state.ConcatClipID = ""
+
+	e.generateSegments(ctx, state, tags, title)
+}
+
+// generateSegments submits and polls every segment that is not complete yet, then concatenates them
+// Segments and the concat already submitted before a restart are polled instead of submitted again.
+func (e *Engine) generateSegments(ctx context.Context, state *storage.WorkflowState, tags, title string) {
+	// One unit of progress per segment, plus the concat
+	total := len(state.Segments) + 1
+	model := e.sunoModel(submittedProperties(state))
+
+	var clip *suno.AudioInfo
+	for i := range state.Segments {
+		seg := &state.Segments[i]
+		if seg.Status == "complete" {
+			clip = &suno.AudioInfo{ID: seg.ClipID, Duration: seg.Duration, AudioURL: seg.AudioURL}
+			continue
+		}
+		if seg.Status == "generating" {
+			var err error
+			if clip, err = e.waitForSegment(ctx, state, seg, i+1, total); err != nil {
+				return
+			}
+			continue
+		}
+
+		step := StepSubmission
+		if i > 0 {
+			step = StepExtend
+		}
+		var results []suno.AudioInfo
+		err := e.runStep(state, step, func() (err error) {
+			if err := e.reserveSunoSubmission(); err != nil {
+				return err
+			}
+			if i == 0 {
+				results, err = e.sunoAPI.CustomGenerate(ctx, &suno.CustomGenerateRequest{
+					Prompt:    seg.Lyrics,
+					Tags:      tags,
+					Title:     title,
+					Model:     model,
+					PersonaID: sunoPersonaID(state), // the extensions continue its voice
+				})
+			} else {
+				results, err = e.sunoAPI.ExtendAudio(ctx, &suno.ExtendAudioRequest{
+					AudioID:    clip.ID,
+					Prompt:     seg.Lyrics,
+					ContinueAt: continueAt(clip.Duration),
+					Title:      title,
+					Tags:       tags,
+					Model:      model,
+				})
+			}
+			if err == nil && len(results) == 0 {
+				err = fmt.Errorf("no results returned from Suno")
+			}
+			return err
+		})
+		if err != nil {
+			e.handleError(state, fmt.Sprintf("%s (segment %d/%d)", step, seg.Index, len(state.Segments)), err)
+			return
+		}
+
+		seg.ClipID = results[0].ID
+		seg.Status = "generating"
+		e.recordSunoCredits(state, e.cfg.SunoCreditsPerGeneration)
+		if i == 0 {
+			state.SunoJobID = seg.ClipID
+			e.setStatus(state, storage.StatusGenerating)
+		} else {
+			e.store.Save(state)
+		}
+
+		if clip, err = e.waitForSegment(ctx, state, seg, i+1, total); err != nil {
+			return
+		}
+	}
+
+	// A single segment is already the whole song
+	if len(state.Segments) > 1 {
+		err := e.runStep(state, StepConcat, func() (err error) {
+			if state.ConcatClipID == "" {
+				var full *suno.AudioInfo
+				full, err = e.sunoAPI.Concat(ctx, &suno.ConcatRequest{ClipID: clip.ID})
+				if err != nil {
+					return err
+				}
+				state.ConcatClipID = full.ID
+				e.store.Save(state)
+			}
+
+			clip, err = e.waitForClip(ctx, state, state.ConcatClipID)
+			return err
+		})
+		if err != nil {
+			e.handleError(state, StepConcat, err)
+			return
+		}
+	}
+	e.publishProgress(state, total, total)
+
+	e.completeSong(ctx, state, clip)
+}
+
+// waitForSegment polls a submitted segment and records it as complete
+// On failure the workflow is marked failed and the error returned.
+func (e *Engine) waitForSegment(ctx context.Context, state *storage.WorkflowState, seg *storage.SongSegment, done, total int) (*suno.AudioInfo, error) {
+	var clip *suno.AudioInfo
+	err := e.runStep(state, StepCompletion, func() (err error) {
+		clip, err = e.waitForClip(ctx, state, seg.ClipID)
+		return err
+	})
+	if err != nil {
+		e.handleError(state, fmt.Sprintf("%s (segment %d/%d)", StepCompletion, seg.Index, len(state.Segments)), err)
+		return nil, err
+	}
+
+	seg.Status = "complete"
+	seg.Duration = clip.Duration
+	seg.AudioURL = clip.AudioURL
+	e.store.Save(state)
+	e.publishProgress(state, done, total)
+	return clip, nil
+}
+
+func (e *Engine) publishProgress(state *storage.WorkflowState, done, total int) {
+	e.events.Publish(Progress{ID: state.ID, Done: done, Total: total, At: time.Now()})
+}
+
+// splitLyricsSegments groups blank-line separated lyric sections into segments of at most maxChars
+// A single section longer than maxChars becomes a segment of its own
+func splitLyricsSegments(lyrics string, maxChars int) []string {
+	lyrics = strings.ReplaceAll(lyrics, "\r\n", "\n")
+
+	var segments []string
+	var current strings.Builder
+	for _, section := range strings.Split(lyrics, "\n\n") {
+		section = strings.TrimSpace(section)
+		if section == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len("\n\n")+len(section) > maxChars {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(section)
+	}
+	if current.Len() > 0 {
+		segments = append(segments, current.String())
+	}
+	if len(segments) == 0 {
+		segments = []string{strings.TrimSpace(lyrics)}
+	}
+	return segments
+}
+
+// continueAt formats a clip duration as the mm:ss offset to extend from
+// An unknown duration extends from the end of the clip
+func continueAt(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// Lyrics drafting engines
+const (
+	LyricsEngineOpenAI = "openai"
+	LyricsEngineSuno   = "suno"
+)
+
+// resolveLyricsEngine returns the engine a new workflow drafts its lyrics with
+// Suno is used whenever no OpenAI key is configured
+func (e *Engine) resolveLyricsEngine(requested string) (string, error) {
+	engine := strings.ToLower(strings.TrimSpace(requested))
+	if engine == "" {
+		engine = strings.ToLower(e.cfg.LyricsEngine)
+	}
+	switch engine {
+	case LyricsEngineOpenAI:
+		if !e.cfg.HasOpenAI() {
+			return LyricsEngineSuno, nil
+		}
+		return engine, nil
+	case LyricsEngineSuno:
+		return engine, nil
+	default:
+		return "", invalidf("unknown lyrics engine %q (use %s or %s)", requested, LyricsEngineOpenAI, LyricsEngineSuno)
+	}
+}
+
+// generateLyrics drafts song lyrics from the task description with the workflow's lyrics engine
+// Lyrics for a track with source lyrics are held to their structure (see generateStructuredLyrics).
+func (e *Engine) generateLyrics(ctx context.Context, state *storage.WorkflowState) (string, error) {
+	if state.SourceLyrics != "" {
+		return e.generateStructuredLyrics(ctx, state)
+	}
+	prompt := state.TaskDescription + languageInstruction(state.Language)
+	if state.LyricsEngine != LyricsEngineSuno {
+		return e.chat(ctx, state, e.promptsList.LyricsGeneration, prompt)
+	}
+
+	resp, err := e.sunoAPI.GenerateLyrics(ctx, &suno.GenerateLyricsRequest{Prompt: prompt})
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(resp.Text) == "" {
+		return "", fmt.Errorf("suno returned no lyrics (status %q)", resp.Status)
+	}
+	return resp.Text, nil
+}
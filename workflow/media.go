@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"workflower/storage"
+)
+
+// FinalClipID returns the Suno clip of the finished song: the concatenation of a long song,
+// otherwise the (first) generated clip
+func FinalClipID(state *storage.WorkflowState) string {
+	if state.ConcatClipID != "" {
+		return state.ConcatClipID
+	}
+	return state.SunoJobID
+}
+
+// RefreshMediaURLs asks Suno for the current audio and video URLs of the final clip, whose
+// CDN links expire, and stores them on the workflow
+func (e *Engine) RefreshMediaURLs(ctx context.Context, state *storage.WorkflowState) error {
+	clipID := FinalClipID(state)
+	if clipID == "" {
+		return fmt.Errorf("workflow has no Suno clip")
+	}
+	clip, err := e.sunoAPI.GetClip(ctx, clipID)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	if clip.AudioURL != "" {
+		state.AudioURL = clip.AudioURL
+	}
+	if clip.VideoURL != "" {
+		state.VideoURL = clip.VideoURL
+	}
+	e.mu.Unlock()
+	e.store.Save(state)
+	return nil
+}
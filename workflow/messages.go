@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"log/slog"
+
+	"workflower/config"
+	"workflower/templates/messages"
+)
+
+// loadMessages loads the Telegram message catalogs with the MESSAGES_DIR overrides
+// Broken override files are logged and skipped.
+func loadMessages(cfg *config.Config) *messages.Catalog {
+	catalog, err := messages.Load(cfg.MessagesDir, cfg.MessagesLanguage)
+	if catalog == nil {
+		panic("embedded message catalogs: " + err.Error())
+	}
+	if err != nil {
+		slog.Warn("Some Telegram messages could not be loaded, using the embedded ones", "error", err)
+	}
+	return catalog
+}
+
+// ReloadMessages reads the MESSAGES_DIR overrides again, so that edited messages apply
+// without a restart
+func (e *Engine) ReloadMessages() {
+	catalog := loadMessages(e.cfg)
+	e.messagesMu.Lock()
+	e.messages = catalog
+	e.messagesMu.Unlock()
+	slog.Info("Telegram messages reloaded", "languages", catalog.Languages())
+}
+
+func (e *Engine) catalog() *messages.Catalog {
+	e.messagesMu.Lock()
+	defer e.messagesMu.Unlock()
+	return e.messages
+}
+
+// Message renders a Telegram message for a chat, in the language it chose with /language
+func (e *Engine) Message(chatID, name string, data any) string {
+	return e.catalog().Render(e.ChatLanguage(chatID), name, data)
+}
+
+// ChatLanguage returns a Telegram chat's message language: the one chosen with /language, else
+// the detected language of its last request (see NoteChatLanguage), else MESSAGES_LANGUAGE
+func (e *Engine) ChatLanguage(chatID string) string {
+	catalog := e.catalog()
+	prefs := e.store.GetChatPreferences(chatID)
+	if catalog.Has(prefs.Language) {
+		return prefs.Language
+	}
+	if catalog.Has(prefs.DetectedLanguage) {
+		return prefs.DetectedLanguage
+	}
+	return catalog.DefaultLanguage()
+}
+
+// MessageLanguages returns the codes of the languages Telegram messages are available in
+func (e *Engine) MessageLanguages() []string {
+	return e.catalog().Languages()
+}
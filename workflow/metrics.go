@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Metrics collects engine counters from the event bus
+type Metrics struct {
+	mu              sync.Mutex
+	transitions     map[string]int
+	stepRuns        map[string]int
+	stepFailures    map[string]int
+	stepDurationSum map[string]float64
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		transitions:     make(map[string]int),
+		stepRuns:        make(map[string]int),
+		stepFailures:    make(map[string]int),
+		stepDurationSum: make(map[string]float64),
+	}
+}
+
+// Handle records an engine event
+func (m *Metrics) Handle(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch ev := event.(type) {
+	case StatusChanged:
+		m.transitions[ev.To]++
+	case StepFinished:
+		m.stepRuns[ev.Step]++
+		m.stepDurationSum[ev.Step] += ev.Duration.Seconds()
+		if ev.Error != "" {
+			m.stepFailures[ev.Step]++
+		}
+	}
+}
+
+// WritePrometheus writes the counters in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sections := []struct {
+		name, help, label string
+		values            map[string]float64
+	}{
+		{"workflower_status_transitions_total", "Workflow status transitions by target status.", "status", toFloat(m.transitions)},
+		{"workflower_step_runs_total", "Engine step executions.", "step", toFloat(m.stepRuns)},
+		{"workflower_step_failures_total", "Failed engine step executions.", "step", toFloat(m.stepFailures)},
+		{"workflower_step_duration_seconds_sum", "Total time spent in engine steps.", "step", m.stepDurationSum},
+	}
+
+	for _, section := range sections {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", section.name, section.help, section.name); err != nil {
+			return err
+		}
+		for _, key := range sortedKeys(section.values) {
+			if _, err := fmt.Fprintf(w, "%s{%s=%q} %g\n", section.name, section.label, key, section.values[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func toFloat(values map[string]int) map[string]float64 {
+	result := make(map[string]float64, len(values))
+	for key, value := range values {
+		result[key] = float64(value)
+	}
+	return result
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"workflower/lib/llm/openai"
+	"workflower/storage"
+)
+
+// moderate runs text through the OpenAI moderation endpoint
+// It returns false after blocking the workflow when the text is flagged; what names the
+// checked content in the error message (e.g. "task description")
+func (e *Engine) moderate(ctx context.Context, state *storage.WorkflowState, what, text string) bool {
+	if !e.cfg.EnableModeration || !e.cfg.HasOpenAI() || strings.TrimSpace(text) == "" {
+		return true
+	}
+
+	var categories []string
+	err := e.runStep(state, StepModeration, func() error {
+		var results []openai.ModerationResult
+		err := e.guardLLM(ctx, state, func(ctx context.Context) (err error) {
+			results, err = e.openAI.Moderate(ctx, e.cfg.ModerationModel, text)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if results[0].Flagged {
+			categories = results[0].FlaggedCategories()
+			if len(categories) == 0 {
+				categories = []string{"unspecified"}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		e.handleError(state, StepModeration, err)
+		return false
+	}
+	if categories == nil {
+		return true
+	}
+
+	state.ModerationCategories = categories
+	state.ErrorMsg = fmt.Sprintf("%s flagged by moderation: %s", what, strings.Join(categories, ", "))
+	e.setStatus(state, storage.StatusBlockedModeration)
+	slog.WarnContext(logContext(state), "Workflow blocked by moderation", "workflow_id", state.ID, "content", what, "categories", categories)
+	return false
+}
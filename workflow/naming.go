@@ -0,0 +1,126 @@
+package workflow
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"workflower/config"
+	"workflower/lib/templating"
+	"workflower/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxTitleLength     = 80
+	maxFileNameLength  = 120
+	descriptionLength  = 50
+	styleShortLength   = 24
+	shortIDLength      = 8
+	fallbackFileName   = "workflow"
+	namingTemplateName = "naming"
+)
+
+var fileNameUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// NameData holds the fields available to the naming template
+type NameData struct {
+	ID          string
+	ShortID     string
+	Seq         int
+	ProjectSeq  int
+	Project     string
+	ExternalRef string
+	Description string
+	Style       string
+	StyleShort  string
+	Date        string
+}
+
+// Namer renders workflow names from the configured naming template
+type Namer struct {
+	tmpl *texttemplate.Template
+}
+
+// NewNamer parses the naming template, falling back to the default one if it is invalid
+func NewNamer(templateContent string) *Namer {
+	tmpl, err := templating.ParseText(namingTemplateName, templateContent)
+	if err != nil {
+		slog.Warn("Invalid naming template, using default", "error", err)
+		tmpl, _ = templating.ParseText(namingTemplateName, config.DefaultNamingTemplate)
+	}
+	return &Namer{tmpl: tmpl}
+}
+
+// Title renders a human-readable workflow title
+func (n *Namer) Title(state *storage.WorkflowState) string {
+	data := newNameData(state)
+
+	var buf bytes.Buffer
+	if err := templating.ExecuteToWriter(&buf, n.tmpl, data); err != nil {
+		slog.WarnContext(logContext(state), "Failed to render naming template", "error", err, "workflow_id", state.ID)
+		return data.Description
+	}
+
+	title := strings.TrimSpace(buf.String())
+	if title == "" {
+		return data.Description
+	}
+	return truncateString(title, maxTitleLength)
+}
+
+// FileName renders the workflow name as a filesystem-safe slug for archives and exports
+func (n *Namer) FileName(state *storage.WorkflowState) string {
+	slug := fileNameUnsafeChars.ReplaceAllString(n.Title(state), "_")
+	slug = strings.Trim(slug, "._-")
+	if slug == "" {
+		return fallbackFileName
+	}
+	if len(slug) > maxFileNameLength {
+		slug = slug[:maxFileNameLength]
+	}
+	return slug
+}
+
+func newNameData(state *storage.WorkflowState) NameData {
+	data := NameData{
+		ID:          state.ID,
+		ShortID:     state.ID,
+		Seq:         state.Seq,
+		ProjectSeq:  state.ProjectSeq,
+		Project:     state.Project,
+		ExternalRef: state.ExternalRef,
+		Description: truncateString(strings.TrimSpace(state.TaskDescription), descriptionLength),
+		Date:        state.CreatedAt.Format("2006-01-02"),
+	}
+	if len(data.ShortID) > shortIDLength {
+		data.ShortID = data.ShortID[:shortIDLength]
+		// UUIDv7 IDs start with a timestamp shared by workflows of the same minute
+		if id, err := uuid.Parse(state.ID); err == nil && id.Version() == 7 {
+			data.ShortID = state.ID[len(state.ID)-shortIDLength:]
+		}
+	}
+
+	props := state.EditedProperties
+	if props == nil {
+		props = state.SunoProperties
+	}
+	if props != nil {
+		data.Style = props.Style
+		data.StyleShort = shortStyle(props.Style)
+	}
+
+	return data
+}
+
+// shortStyle keeps the leading genre of a style description
+func shortStyle(style string) string {
+	short := strings.TrimSpace(strings.SplitN(style, ",", 2)[0])
+	if len(short) > styleShortLength {
+		short = strings.TrimSpace(short[:styleShortLength])
+	}
+	return short
+}
@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"workflower/storage"
+)
+
+// telegramSubscriber sends Telegram notifications for review, moderation and completion transitions
+// Review notifications go to the assignee only; timestamps are rendered in the notified chat's time zone
+func (e *Engine) telegramSubscriber() func(Event) {
+	return func(event Event) {
+		var wf storage.WorkflowState
+		var at time.Time
+		switch ev := event.(type) {
+		case StatusChanged:
+			if ev.To != storage.StatusAwaitingReview && ev.To != storage.StatusCompleted && ev.To != storage.StatusBlockedModeration {
+				return
+			}
+			wf, at = ev.Workflow, ev.At
+		case Assigned:
+			// Reassignment of a pending review: notify the new assignee
+			if ev.Workflow.Status != storage.StatusAwaitingReview || ev.To == ev.From {
+				return
+			}
+			wf, at = ev.Workflow, ev.At
+		default:
+			return
+		}
+
+		chatID := e.defaultChatID()
+		var assignedTo string
+		if wf.Status == storage.StatusAwaitingReview {
+			chatID, assignedTo = e.reviewRecipient(&wf)
+		}
+
+		when := e.cfg.Locale.Display(at, e.ChatLocation(chatID))
+		var message string
+		if wf.Status == storage.StatusAwaitingReview {
+			message = e.reviewMessage(chatID, &wf, when, assignedTo)
+		} else if wf.Status == storage.StatusBlockedModeration {
+			message = e.Message(chatID, "blocked_moderation", map[string]any{
+				"Workflow": &wf, "Label": digestLabel(&wf), "When": when, "URL": e.workflowURL(&wf),
+			})
+		} else {
+			message = e.Message(chatID, "completed", map[string]any{"Workflow": &wf, "When": when})
+		}
+
+		notifier := e.notifier
+		go func() {
+			if err := notifier.SendToChat(context.Background(), chatID, message); err != nil {
+				// Log but don't fail the workflow; reviews can be announced again with ResendReviewNotifications
+				slog.WarnContext(logContext(&wf), "Failed to send Telegram notification", "error", err, "workflow_id", wf.ID, "status", wf.Status, "chat_id", chatID)
+				return
+			}
+			if wf.Status == storage.StatusAwaitingReview && e.cfg.TelegramBotToken != "" && chatID != "" {
+				e.markReviewNotified(wf.ID, time.Now())
+			}
+		}()
+	}
+}
+
+// reviewMessage is the Telegram announcement of a workflow ready for review
+// Screening hits are listed so Telegram reviewers see them before opening the page.
+func (e *Engine) reviewMessage(chatID string, wf *storage.WorkflowState, when, assignedTo string) string {
+	screening := make([]string, 0, len(wf.ScreeningHits))
+	for _, hit := range wf.ScreeningHits {
+		screening = append(screening, hit.Message())
+	}
+	return e.Message(chatID, "review_ready", map[string]any{
+		"Workflow": wf, "When": when, "Cost": formatCost(e.cfg.Locale, wf.Usage), "AssignedTo": assignedTo,
+		"Screening": screening, "ReviewURL": e.reviewURL(wf),
+	})
+}
+
+// reviewRecipient returns the chat that receives the review notification of a workflow
+// Telegram assignees and web assignees with a linked chat are notified directly; other web
+// assignees are named in the default chat (assignedTo)
+func (e *Engine) reviewRecipient(state *storage.WorkflowState) (chatID, assignedTo string) {
+	if chat, ok := strings.CutPrefix(state.Assignee, TelegramIdentityPrefix); ok {
+		return chat, ""
+	}
+	if chat := e.LinkedTelegramChat(state.Assignee); state.Assignee != "" && chat != "" {
+		return chat, ""
+	}
+	if state.Assignee != "" {
+		return e.defaultChatID(), state.Assignee
+	}
+	return e.defaultChatID(), ""
+}
@@ -0,0 +1,15 @@
+package workflow
+
+import "log/slog"
+
+// RotateOpenAIKeys replaces the OpenAI key pool without a restart; requests in flight finish
+// with the key they started with. An empty list is ignored so that a broken reload does not
+// disable the LLM steps.
+func (e *Engine) RotateOpenAIKeys(keys []string) {
+	if len(keys) == 0 {
+		slog.Warn("No OpenAI keys configured, keeping the current keys")
+		return
+	}
+	e.openAIKeys.SetKeys(keys)
+	slog.Info("OpenAI keys rotated", "keys", len(keys))
+}
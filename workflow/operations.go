@@ -0,0 +1,157 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"workflower/storage"
+
+	"github.com/google/uuid"
+)
+
+// Operation kinds
+const (
+	OperationGenerate = "generate" // Suno generation after approval, including long-song extensions and stems
+	OperationStems    = "stems"    // stems of an already completed song
+)
+
+// ErrStemsUnavailable is returned when stems are requested for a song that is not completed
+var ErrStemsUnavailable = newError(CodeConflict, "stems can only be generated for completed workflows")
+
+// ErrStemsExist is returned when stems are requested for a song that has them or is getting them
+var ErrStemsExist = newError(CodeConflict, "stems have already been generated or are being generated")
+
+// StartOperation records a running operation of the given kind on a workflow whose action was
+// just started; it is finished when the workflow reaches the outcome of the action
+func (e *Engine) StartOperation(kind string, state *storage.WorkflowState) storage.Operation {
+	op := storage.Operation{ID: uuid.NewString(), Kind: kind, WorkflowID: state.ID, CreatedAt: time.Now()}
+	e.store.SaveOperation(op)
+	// The action may have finished before the operation was recorded
+	if current, ok := e.store.Get(state.ID); ok {
+		e.settleOperations(current)
+	}
+	op, _ = e.store.GetOperation(op.ID)
+	return op
+}
+
+// GetOperation returns an operation by ID
+func (e *Engine) GetOperation(id string) (storage.Operation, bool) {
+	return e.store.GetOperation(id)
+}
+
+// GenerateStems separates a completed song into stems in the background and returns the
+// operation tracking it
+func (e *Engine) GenerateStems(ctx context.Context, state *storage.WorkflowState) (storage.Operation, error) {
+	if state.Status != storage.StatusCompleted {
+		return storage.Operation{}, ForWorkflow(ErrStemsUnavailable, state.ID)
+	}
+	if state.StemsURL != "" || e.pendingOperation(state.ID, OperationStems) {
+		return storage.Operation{}, ForWorkflow(ErrStemsExist, state.ID)
+	}
+	state.GenerateStems = true
+	state.StemsClipID = ""
+	state.StemsError = ""
+	e.store.Save(state)
+
+	op := e.StartOperation(OperationStems, state)
+	go e.runStems(e.track(ctx, state), state)
+	return op, nil
+}
+
+// runStems generates the stems of a completed song and finishes its stems operation
+// A run stopped by DeleteWorkflow leaves the store alone.
+func (e *Engine) runStems(ctx context.Context, state *storage.WorkflowState) {
+	e.generateStems(ctx, state, FinalClipID(state))
+	if ctx.Err() != nil {
+		return
+	}
+	e.untrack(state.ID)
+	e.store.Save(state)
+	e.settleOperations(state)
+}
+
+// resumeStems continues stems operations interrupted by a restart
+func (e *Engine) resumeStems(ctx context.Context) {
+	for _, op := range e.store.PendingOperations() {
+		if op.Kind != OperationStems {
+			continue
+		}
+		state, ok := e.store.Get(op.WorkflowID)
+		if !ok || state.Status != storage.StatusCompleted {
+			e.finishOperation(op, nil, "workflow is no longer completed")
+			continue
+		}
+		slog.InfoContext(logContext(state), "Resuming stem generation", "workflow_id", state.ID, "clip_id", state.StemsClipID)
+		go e.runStems(e.track(ctx, state), state)
+	}
+}
+
+// pendingOperation reports whether an operation of the given kind is running on a workflow
+func (e *Engine) pendingOperation(workflowID, kind string) bool {
+	for _, op := range e.store.ListOperations(workflowID) {
+		if op.Kind == kind && !op.Done {
+			return true
+		}
+	}
+	return false
+}
+
+// settleOperations finishes the running operations of a workflow whose outcome is known
+func (e *Engine) settleOperations(state *storage.WorkflowState) {
+	for _, op := range e.store.ListOperations(state.ID) {
+		if op.Done {
+			continue
+		}
+		switch {
+		case op.Kind == OperationStems && state.StemsURL != "":
+			e.finishOperation(op, operationResult(state), "")
+		case op.Kind == OperationStems && state.StemsError != "":
+			e.finishOperation(op, nil, state.StemsError)
+		case state.Status == storage.StatusCompleted && op.Kind == OperationGenerate:
+			e.finishOperation(op, operationResult(state), "")
+		case state.IsTerminal() && state.Status != storage.StatusCompleted:
+			reason := state.ErrorMsg
+			if reason == "" {
+				reason = "workflow " + state.Status
+			}
+			e.finishOperation(op, nil, reason)
+		}
+	}
+}
+
+// finishOperation marks an operation done with its result, or with the reason it failed
+func (e *Engine) finishOperation(op storage.Operation, result *storage.OperationResult, reason string) {
+	now := time.Now()
+	op.Done = true
+	op.FinishedAt = &now
+	op.Result = result
+	op.Error = reason
+	e.store.SaveOperation(op)
+}
+
+func operationResult(state *storage.WorkflowState) *storage.OperationResult {
+	return &storage.OperationResult{
+		Status:   state.Status,
+		ClipID:   FinalClipID(state),
+		AudioURL: state.AudioURL,
+		VideoURL: state.VideoURL,
+		StemsURL: state.StemsURL,
+	}
+}
+
+// newOperationsSubscriber finishes operations as their workflows reach a final status or are deleted
+func newOperationsSubscriber(e *Engine) func(Event) {
+	return func(event Event) {
+		switch ev := event.(type) {
+		case StatusChanged:
+			e.settleOperations(&ev.Workflow)
+		case Deleted:
+			for _, op := range e.store.ListOperations(ev.Workflow.ID) {
+				if !op.Done {
+					e.finishOperation(op, nil, "workflow deleted")
+				}
+			}
+		}
+	}
+}
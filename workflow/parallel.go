@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"context"
+
+	"workflower/storage"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// parallelStep is an engine step that can run concurrently with its siblings
+type parallelStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// stepFailure is the error of a parallel step, naming the step for runParallelSteps
+type stepFailure struct {
+	step string
+	err  error
+}
+
+func (f *stepFailure) Error() string { return f.step + ": " + f.err.Error() }
+func (f *stepFailure) Unwrap() error { return f.err }
+
+// runParallelSteps runs independent steps concurrently, each wrapped in runStep
+// The context of the remaining steps is cancelled as soon as one fails; the name
+// and error of the first failure are returned. Steps must write disjoint state fields.
+func (e *Engine) runParallelSteps(ctx context.Context, state *storage.WorkflowState, steps ...parallelStep) (string, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, step := range steps {
+		g.Go(func() error {
+			err := e.runStep(state, step.name, func() error {
+				return step.run(ctx)
+			})
+			if err != nil {
+				return &stepFailure{step: step.name, err: err}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		failure := err.(*stepFailure)
+		return failure.step, failure.err
+	}
+	return "", nil
+}
@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+const (
+	// personaCacheTTL is how long the personas of the Suno account are served before they are
+	// listed again; creating a persona refreshes them at once
+	personaCacheTTL = 10 * time.Minute
+	// sunoPersonaTimeout bounds listing or creating personas
+	sunoPersonaTimeout = 30 * time.Second
+)
+
+// ErrPersonaUnavailable is returned when a persona is created from a workflow that is not a
+// completed premium song
+var ErrPersonaUnavailable = newError(CodeConflict, "personas can only be created from completed premium workflows")
+
+// SunoPersonas returns the personas of the Suno account (all accounts with SUNO_ACCOUNTS), newest
+// first, that premium workflows may be generated with
+func (e *Engine) SunoPersonas(ctx context.Context) ([]suno.Persona, error) {
+	e.personaMu.Lock()
+	defer e.personaMu.Unlock()
+	if e.personas != nil && time.Since(e.personasAt) < personaCacheTTL {
+		return e.personas, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, sunoPersonaTimeout)
+	defer cancel()
+	list, err := e.sunoAPI.ListPersonas(ctx, 1)
+	if err != nil {
+		return nil, personaError(err)
+	}
+	e.personas = append([]suno.Persona{}, list.Personas...)
+	e.personasAll = len(list.Personas) >= list.TotalResults
+	e.personasAt = time.Now()
+	return e.personas, nil
+}
+
+// CreateSunoPersona creates a Suno persona from the final clip of a completed premium workflow, so
+// that later premium workflows can be generated with its voice and style; name defaults to the
+// song title
+func (e *Engine) CreateSunoPersona(ctx context.Context, state *storage.WorkflowState, name, description string) (*suno.Persona, error) {
+	if !state.IsPremium || state.Status != storage.StatusCompleted || FinalClipID(state) == "" {
+		return nil, ForWorkflow(ErrPersonaUnavailable, state.ID)
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		name = state.Title
+	}
+	if name == "" {
+		return nil, ForWorkflow(invalidf("a persona needs a name"), state.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sunoPersonaTimeout)
+	defer cancel()
+	persona, err := e.sunoAPI.CreatePersona(ctx, &suno.CreatePersonaRequest{
+		ClipID:      FinalClipID(state),
+		Name:        name,
+		Description: strings.TrimSpace(description),
+	})
+	if err != nil {
+		return nil, ForWorkflow(personaError(err), state.ID)
+	}
+
+	e.mu.Lock()
+	state.CreatedPersonaID = persona.ID
+	e.mu.Unlock()
+	e.store.Save(state)
+
+	e.personaMu.Lock()
+	e.personas = nil
+	e.personaMu.Unlock()
+	slog.InfoContext(logContext(state), "Suno persona created", "workflow_id", state.ID, "persona_id", persona.ID, "name", persona.Name)
+	return persona, nil
+}
+
+// checkSunoPersona checks that the persona a premium workflow is about to be generated with
+// exists; when the personas cannot all be listed, Suno is left to reject an unknown one
+func (e *Engine) checkSunoPersona(ctx context.Context, state *storage.WorkflowState) error {
+	id := sunoPersonaID(state)
+	if id == "" {
+		return nil
+	}
+	personas, err := e.SunoPersonas(ctx)
+	if err != nil {
+		slog.WarnContext(logContext(state), "Could not list Suno personas", "workflow_id", state.ID, "error", err)
+		return nil
+	}
+	for _, persona := range personas {
+		if persona.ID == id {
+			return nil
+		}
+	}
+	e.personaMu.Lock()
+	all := e.personasAll
+	e.personaMu.Unlock()
+	if !all {
+		return nil
+	}
+	return invalidf("unknown Suno persona %q", id)
+}
+
+// personaError codes a failed persona request: Suno refusing it (e.g. a clip it cannot make a
+// persona of) is invalid, anything else means Suno is not reachable
+func personaError(err error) error {
+	var apiErr *suno.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && !apiErr.IsAuth() {
+		return &Error{Code: CodeInvalid, Err: fmt.Errorf("suno refused the persona request: %w", err)}
+	}
+	return &Error{Code: CodeUnavailable, Err: fmt.Errorf("suno personas: %w", err)}
+}
+
+// sunoPersonaID returns the Suno persona a workflow is generated with, "" for none: the reviewed
+// persona/inspo's, or the one asked for at start before it is generated; only premium workflows
+// use personas
+func sunoPersonaID(state *storage.WorkflowState) string {
+	switch {
+	case !state.IsPremium:
+		return ""
+	case state.PersonaInspo != nil:
+		return strings.TrimSpace(state.PersonaInspo.PersonaID)
+	default:
+		return state.SunoPersonaID
+	}
+}
@@ -0,0 +1,155 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// waitForClip polls Suno until the clip is ready, recording every attempt on the workflow
+// so that ResumePolling can continue where it stopped (same attempt budget and schedule)
+// A clip suno-api does not list yet counts as pending for SUNO_NOT_FOUND_GRACE after submission.
+// A clip delivered by a Suno callback (see DeliverClip) ends the wait at once; polling then
+// only covers callbacks that never arrive.
+func (e *Engine) waitForClip(ctx context.Context, state *storage.WorkflowState, clipID string) (*suno.AudioInfo, error) {
+	poll := state.Poll
+	if poll == nil || poll.ClipID != clipID {
+		poll = &storage.SunoPoll{ClipID: clipID, NextPollAt: time.Now(), StartedAt: time.Now()}
+		state.Poll = poll
+		e.store.Save(state)
+	}
+	interval, retries := e.pollSchedule()
+	notify := e.clipNotify(clipID)
+	defer e.forgetClip(clipID)
+
+	for poll.Attempts < retries {
+		if wait := time.Until(poll.NextPollAt); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case audio := <-notify:
+				if audio.Status == "streaming" || audio.Status == "complete" {
+					slog.InfoContext(logContext(state), "Suno callback received", "workflow_id", state.ID, "clip_id", clipID, "status", audio.Status)
+					state.Poll = nil
+					e.store.Save(state)
+					return audio, nil
+				}
+				continue // not ready yet, keep the poll schedule
+			case <-time.After(wait):
+			}
+		}
+
+		responses, err := e.sunoAPI.Get(ctx, clipID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get audio info: %w", err)
+		}
+		next := interval
+		switch grace := e.cfg.SunoNotFoundGrace - time.Since(poll.StartedAt); {
+		case len(responses) == 0 && grace <= 0:
+			state.Poll = nil
+			return nil, fmt.Errorf("%w: %s still missing %s after submission", suno.ErrClipNotFound, clipID, e.cfg.SunoNotFoundGrace)
+		case len(responses) == 0:
+			// suno-api may list a clip only a few seconds after returning it from the submission;
+			// the last check falls at the end of the grace period at the latest
+			slog.InfoContext(logContext(state), "Suno clip not listed yet, polling again", "workflow_id", state.ID, "clip_id", clipID)
+			next = min(interval, grace)
+		case responses[0].Status == "streaming" || responses[0].Status == "complete":
+			state.Poll = nil
+			e.store.Save(state)
+			return &responses[0], nil
+		}
+
+		poll.Attempts++
+		poll.NextPollAt = time.Now().Add(next)
+		e.store.Save(state)
+	}
+
+	state.Poll = nil
+	return nil, fmt.Errorf("max retries exceeded waiting for audio completion")
+}
+
+// drainInterval is how often Drain checks for workflows in flight
+const drainInterval = time.Second
+
+// errInterrupted fails the runs cut off by a shutdown (see ResumePolling)
+var errInterrupted = errors.New("interrupted by a restart")
+
+// ResumePolling continues the workflows that were running when the process stopped, and the
+// stems requested for completed songs; call it once on startup, after the store is restored
+// Generating workflows keep polling their clips and processing ones run their preparation again
+// from the first step that did not succeed. Approved workflows fail instead, to be retried with
+// RetryWorkflow: their Suno submission may have gone through without its clip being recorded,
+// and submitting it again on its own could charge the song twice.
+func (e *Engine) ResumePolling(ctx context.Context) {
+	for _, state := range e.store.ListByStatus(storage.StatusProcessing) {
+		slog.InfoContext(logContext(state), "Resuming workflow preparation", "workflow_id", state.ID)
+		closeInterruptedSteps(state)
+		e.store.Save(state)
+		go e.runWorkflowSteps(e.track(ctx, state), state)
+	}
+	for _, state := range e.store.ListByStatus(storage.StatusApproved) {
+		step := closeInterruptedSteps(state)
+		if step == "" {
+			step = StepSubmission
+		}
+		e.handleError(state, step, errInterrupted)
+	}
+	for _, state := range e.store.ListByStatus(storage.StatusGenerating) {
+		slog.InfoContext(logContext(state), "Resuming Suno polling", "workflow_id", state.ID, "clip_id", state.SunoJobID)
+		runCtx := e.track(ctx, state)
+		switch {
+		case len(state.Segments) > 0:
+			go e.generateSegments(runCtx, state, sunoTags(state, submittedProperties(state)), state.Title)
+		case len(state.Variants) > 0:
+			go e.pollVariants(runCtx, state)
+		case state.SunoJobID != "":
+			go e.pollSunoCompletion(runCtx, state, state.SunoJobID)
+		default:
+			e.handleError(state, StepCompletion, fmt.Errorf("%w before a Suno clip was recorded", errInterrupted))
+		}
+	}
+	e.resumeStems(ctx)
+	e.resumeResultArchival(ctx)
+}
+
+// Drain waits until no workflow is being prepared or submitted to Suno (processing or approved),
+// so that stopping the process afterwards only interrupts Suno polling, which ResumePolling
+// continues; when ctx ends first its error is returned and the next start takes over the rest
+func (e *Engine) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for logged := false; ; logged = true {
+		busy := len(e.store.ListByStatus(storage.StatusProcessing)) + len(e.store.ListByStatus(storage.StatusApproved))
+		if busy == 0 {
+			return nil
+		}
+		if !logged {
+			slog.InfoContext(ctx, "Waiting for workflows in flight", "workflows", busy)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeInterruptedSteps ends the step runs a shutdown left unfinished with errInterrupted, so
+// that they are neither shown as running nor taken for succeeded, and returns the last one's step
+func closeInterruptedSteps(state *storage.WorkflowState) string {
+	var step string
+	now := time.Now()
+	for i := range state.Steps {
+		if run := &state.Steps[i]; run.FinishedAt == nil {
+			run.FinishedAt = &now
+			run.Error = errInterrupted.Error()
+			step = run.Step
+		}
+	}
+	return step
+}
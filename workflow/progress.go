@@ -0,0 +1,179 @@
+package workflow
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"workflower/storage"
+)
+
+// defaultStepDurations are the expected durations of the engine steps until successful runs
+// of them have been observed
+var defaultStepDurations = map[string]time.Duration{
+	StepTranscript:   15 * time.Second,
+	StepModeration:   3 * time.Second,
+	StepLyrics:       30 * time.Second,
+	StepProperties:   10 * time.Second,
+	StepBrackets:     20 * time.Second,
+	StepPersonaInspo: 15 * time.Second,
+	StepSimilarity:   10 * time.Second,
+	StepValidation:   100 * time.Millisecond,
+	StepSubmission:   10 * time.Second,
+	StepExtend:       10 * time.Second,
+	StepCompletion:   2 * time.Minute,
+	StepConcat:       time.Minute,
+	StepStems:        time.Minute,
+}
+
+// maxRunningShare caps the share of a running step counted as done, so that a step taking
+// longer than usual does not look finished
+const maxRunningShare = 0.95
+
+// ProgressEstimate is the estimated completion of a workflow, from its finished steps and the
+// average durations of the steps left
+// The human review is not estimated: while a workflow awaits review, its progress stands still.
+type ProgressEstimate struct {
+	Percent     int    `json:"percent"`
+	Step        string `json:"step,omitempty"` // running step, "review" while awaiting review
+	StepPercent int    `json:"step_percent"`   // percent reached when the running step finishes
+	RemainingMS int64  `json:"remaining_ms"`   // estimated time left in the engine steps
+}
+
+// Bar renders the estimate as a text progress bar of the given width, e.g. "▓▓▓▓░░░░░░ 42%"
+func (p ProgressEstimate) Bar(width int) string {
+	filled := p.Percent * width / 100
+	return strings.Repeat("▓", filled) + strings.Repeat("░", width-filled) + " " + strconv.Itoa(p.Percent) + "%"
+}
+
+// Remaining returns the estimated time left, rounded to the second
+func (p ProgressEstimate) Remaining() time.Duration {
+	return (time.Duration(p.RemainingMS) * time.Millisecond).Round(time.Second)
+}
+
+// Progress estimates how far a workflow has come; ok is false for workflows that ended
+// without completing. Completed workflows are at 100%.
+func (e *Engine) Progress(state *storage.WorkflowState) (estimate ProgressEstimate, ok bool) {
+	if state.Status == storage.StatusCompleted {
+		return ProgressEstimate{Percent: 100, StepPercent: 100}, true
+	}
+	if state.IsTerminal() {
+		return ProgressEstimate{}, false
+	}
+
+	runs := make(map[string][]storage.StepRun)
+	for _, run := range state.Steps {
+		runs[run.Step] = append(runs[run.Step], run)
+	}
+
+	var total, done, remaining, stepEnd time.Duration
+	for _, node := range e.Graph(state).Nodes {
+		if node.Step == StepReview {
+			if node.Status == NodeRunning {
+				estimate.Step = StepReview
+				stepEnd = done
+			}
+			continue
+		}
+
+		expected := e.stepDurations.average(node.Step)
+		count := expectedRuns(state, node.Step)
+		finished, running := 0, (*storage.StepRun)(nil)
+		for _, run := range runs[node.Step] {
+			switch {
+			case run.FinishedAt == nil:
+				running = &run
+			case run.Error == "":
+				finished++
+			}
+		}
+		if node.Status == NodeSkipped {
+			finished = count
+		}
+		finished = min(finished, count)
+
+		nodeDone := expected * time.Duration(finished)
+		left := count - finished
+		if running != nil && left > 0 {
+			elapsed := min(time.Since(running.StartedAt), time.Duration(float64(expected)*maxRunningShare))
+			estimate.Step = node.Step
+			stepEnd = total + nodeDone + expected
+			nodeDone += elapsed
+			remaining += expected - elapsed
+			left--
+		}
+		remaining += expected * time.Duration(left)
+		done += nodeDone
+		total += expected * time.Duration(count)
+	}
+	if total <= 0 {
+		return estimate, true
+	}
+
+	// Only completion reaches 100%
+	estimate.Percent = min(int(done*100/total), 99)
+	estimate.StepPercent = max(min(int(stepEnd*100/total), 99), estimate.Percent)
+	estimate.RemainingMS = remaining.Milliseconds()
+	return estimate, true
+}
+
+// expectedRuns returns how often a step runs for a workflow: once, except for the Suno
+// steps of long songs, which run once per segment
+func expectedRuns(state *storage.WorkflowState, step string) int {
+	switch {
+	case step == StepCompletion && len(state.Segments) > 1:
+		return len(state.Segments)
+	case step == StepExtend && len(state.Segments) > 2:
+		return len(state.Segments) - 1
+	default:
+		return 1
+	}
+}
+
+// stepDurations keeps the average duration of the successful runs of every engine step,
+// seeded from the stored workflows and updated from the event bus
+type stepDurations struct {
+	mu    sync.Mutex
+	total map[string]time.Duration
+	runs  map[string]int
+}
+
+func newStepDurations(store *storage.Store) *stepDurations {
+	d := &stepDurations{total: make(map[string]time.Duration), runs: make(map[string]int)}
+	for state := range store.All() {
+		for _, run := range state.Steps {
+			if run.FinishedAt != nil && run.Error == "" {
+				d.observe(run.Step, run.FinishedAt.Sub(run.StartedAt))
+			}
+		}
+	}
+	return d
+}
+
+// Handle records the duration of a successful step run
+func (d *stepDurations) Handle(event Event) {
+	if ev, ok := event.(StepFinished); ok && ev.Error == "" {
+		d.observe(ev.Step, ev.Duration)
+	}
+}
+
+func (d *stepDurations) observe(step string, duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total[step] += duration
+	d.runs[step]++
+}
+
+// average returns the average duration of a step, or its default before any run was observed
+func (d *stepDurations) average(step string) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if n := d.runs[step]; n > 0 {
+		return d.total[step] / time.Duration(n)
+	}
+	if duration, ok := defaultStepDurations[step]; ok {
+		return duration
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"workflower/storage"
+)
+
+const (
+	// sunoQuotaTimeout bounds a single quota read
+	sunoQuotaTimeout = 30 * time.Second
+	// quotaReportDays is how many days of credit usage the admin page shows
+	quotaReportDays = 14
+	// quotaForecastDays is how many days of usage the days-left forecast averages
+	quotaForecastDays = 7
+)
+
+// QuotaReport is the Suno credit usage shown on the admin page
+type QuotaReport struct {
+	Latest    *storage.QuotaSample // nil before the first read
+	Threshold int                  // low credit warning threshold, 0 for none
+	Low       bool                 // the latest credits are below Threshold
+	DaysLeft  int                  // at the average usage of the last quotaForecastDays, -1 without usage
+	Days      []QuotaDay           // newest first
+}
+
+// QuotaDay is the credit usage of one day (in DISPLAY_TIMEZONE)
+type QuotaDay struct {
+	Day    time.Time
+	Used   int  // credits spent, summed over the drops between samples
+	Lowest int  // fewest credits left seen
+	TopUp  bool // the credits went up (renewal or purchase)
+}
+
+// RunSunoQuotaMonitor reads the Suno quota every SUNO_QUOTA_INTERVAL until ctx is cancelled,
+// records it in the quota history and warns the admin channel when the credits left drop below
+// the low credit threshold
+func (e *Engine) RunSunoQuotaMonitor(ctx context.Context) {
+	if e.cfg.SunoQuotaInterval <= 0 {
+		slog.Info("Suno quota monitor disabled")
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.SunoQuotaInterval)
+	defer ticker.Stop()
+
+	for {
+		e.checkSunoQuota(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSunoQuota records the current quota; failures are left to the session monitor
+// The warning is sent once, when the credits cross the threshold: the previous sample tells
+// whether it was crossed already, also across restarts.
+func (e *Engine) checkSunoQuota(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, sunoQuotaTimeout)
+	quota, err := e.sunoAPI.GetQuota(checkCtx)
+	cancel()
+	if err != nil {
+		slog.Warn("Suno quota check failed", "error", err)
+		return
+	}
+
+	previous, hadPrevious := e.store.LastQuotaSample()
+	sample := storage.QuotaSample{
+		At:           time.Now().UTC(),
+		CreditsLeft:  quota.CreditsLeft,
+		MonthlyLimit: quota.MonthlyLimit,
+		MonthlyUsage: quota.MonthlyUsage,
+	}
+	e.store.AddQuotaSample(sample)
+
+	threshold := e.Settings().LowCredits
+	if threshold <= 0 || sample.CreditsLeft >= threshold || (hadPrevious && previous.CreditsLeft < threshold) {
+		return
+	}
+	report := e.QuotaReport()
+	slog.Warn("Suno credits low", "credits_left", sample.CreditsLeft, "threshold", threshold, "days_left", report.DaysLeft)
+	e.alert(ctx, "suno_low_credits", map[string]any{
+		"CreditsLeft": sample.CreditsLeft,
+		"Threshold":   threshold,
+		"DaysLeft":    report.DaysLeft,
+	})
+}
+
+// QuotaReport returns the latest quota and the credit usage per day of the quota history
+func (e *Engine) QuotaReport() QuotaReport {
+	loc := e.cfg.DisplayLocation
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	first := today.AddDate(0, 0, -(quotaReportDays - 1))
+	// One sample before the first day gives the usage up to its first sample
+	samples := e.store.QuotaHistory(first.Add(-e.cfg.SunoQuotaInterval))
+
+	report := QuotaReport{Threshold: e.Settings().LowCredits, DaysLeft: -1}
+	if len(samples) == 0 {
+		return report
+	}
+	latest := samples[len(samples)-1]
+	report.Latest = &latest
+	report.Low = report.Threshold > 0 && latest.CreditsLeft < report.Threshold
+
+	days := make([]QuotaDay, quotaReportDays)
+	for i := range days {
+		days[i] = QuotaDay{Day: today.AddDate(0, 0, -i), Lowest: -1}
+	}
+	for i, sample := range samples {
+		at := sample.At.In(loc)
+		day := int(today.Sub(time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)).Hours()+12) / 24
+		if day < 0 || day >= quotaReportDays {
+			continue
+		}
+		d := &days[day]
+		if d.Lowest < 0 || sample.CreditsLeft < d.Lowest {
+			d.Lowest = sample.CreditsLeft
+		}
+		if i == 0 {
+			continue
+		}
+		switch delta := samples[i-1].CreditsLeft - sample.CreditsLeft; {
+		case delta > 0:
+			d.Used += delta
+		case delta < 0:
+			d.TopUp = true
+		}
+	}
+	report.Days = days
+
+	used := 0
+	for _, d := range days[:quotaForecastDays] {
+		used += d.Used
+	}
+	if used > 0 {
+		report.DaysLeft = latest.CreditsLeft * quotaForecastDays / used
+	}
+	return report
+}
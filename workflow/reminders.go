@@ -0,0 +1,203 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"workflower/lib/locale"
+	"workflower/storage"
+)
+
+const (
+	digestDayLayout     = "2006-01-02"
+	maxDigestItems      = 10
+	reminderSendTimeout = 30 * time.Second
+)
+
+// SetDueDate sets (or clears, when due is nil) the due date of a workflow
+// Reminder bookkeeping is reset so the new deadline is reminded about again
+func (e *Engine) SetDueDate(state *storage.WorkflowState, due *time.Time) {
+	state.DueAt = due
+	state.ReminderSentAt = nil
+	state.OverdueNotifiedAt = nil
+	e.store.Save(state)
+}
+
+// SetProjectDueDate sets the due date of every unfinished workflow in a project
+// and returns the number of workflows updated
+func (e *Engine) SetProjectDueDate(project string, due *time.Time) int {
+	updated := 0
+	for state := range e.store.All() {
+		if state.Project != project || state.IsTerminal() {
+			continue
+		}
+		e.SetDueDate(state, due)
+		updated++
+	}
+	return updated
+}
+
+// RunScheduler sends due-date reminders, overdue alerts and the daily digest,
+// escalates unhandled reviews and expires abandoned ones until ctx is cancelled
+func (e *Engine) RunScheduler(ctx context.Context) {
+	if e.cfg.ReminderCheckInterval <= 0 {
+		slog.Info("Reminder scheduler disabled")
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.ReminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.checkDueDates(ctx, now)
+			e.checkEscalations(ctx, now)
+			e.expireDrafts(now)
+			e.maybeSendDigest(ctx, now)
+		}
+	}
+}
+
+// checkDueDates notifies once when a workflow is due within the lead time and once when it becomes overdue
+func (e *Engine) checkDueDates(ctx context.Context, now time.Time) {
+	loc := e.ChatLocation(e.defaultChatID())
+	for state := range e.store.All() {
+		if state.DueAt == nil || state.IsTerminal() {
+			continue
+		}
+
+		var name string
+		switch {
+		case now.After(*state.DueAt):
+			if state.OverdueNotifiedAt != nil {
+				continue
+			}
+			state.OverdueNotifiedAt = &now
+			name = "overdue"
+		case state.DueAt.Sub(now) <= e.cfg.ReminderLeadTime:
+			if state.ReminderSentAt != nil {
+				continue
+			}
+			state.ReminderSentAt = &now
+			name = "due_soon"
+		default:
+			continue
+		}
+
+		e.store.Save(state)
+		e.sendReminder(ctx, state.ID, e.Message(e.defaultChatID(), name, map[string]any{
+			"Workflow": state, "Label": digestLabel(state), "Due": e.cfg.Locale.Display(*state.DueAt, loc), "URL": e.workflowURL(state),
+		}))
+	}
+}
+
+// maybeSendDigest sends the daily digest once per day, at DigestHour in the display time zone
+func (e *Engine) maybeSendDigest(ctx context.Context, now time.Time) {
+	if e.cfg.DigestHour < 0 {
+		return
+	}
+
+	local := now.In(e.ChatLocation(e.defaultChatID()))
+	day := local.Format(digestDayLayout)
+	if local.Hour() < e.cfg.DigestHour || day == e.lastDigestDay {
+		return
+	}
+	e.lastDigestDay = day
+
+	message, ok := e.buildDigest(now)
+	if !ok {
+		slog.Info("No open workflows, skipping daily digest", "day", day)
+		return
+	}
+	e.sendReminder(ctx, "", message)
+}
+
+// buildDigest summarises open workflows; ok is false when there is nothing to report
+func (e *Engine) buildDigest(now time.Time) (message string, ok bool) {
+	loc := e.ChatLocation(e.defaultChatID())
+
+	var awaitingReview, inProgress int
+	var overdue, dueSoon []*storage.WorkflowState
+	for state := range e.store.All() {
+		if state.IsTerminal() {
+			continue
+		}
+		if state.Status == storage.StatusAwaitingReview {
+			awaitingReview++
+		} else {
+			inProgress++
+		}
+
+		switch {
+		case state.DueAt == nil:
+		case now.After(*state.DueAt):
+			overdue = append(overdue, state)
+		case state.DueAt.Sub(now) <= e.cfg.ReminderLeadTime:
+			dueSoon = append(dueSoon, state)
+		}
+	}
+	if awaitingReview+inProgress == 0 {
+		return "", false
+	}
+
+	return e.Message(e.defaultChatID(), "digest", map[string]any{
+		"Day":            e.cfg.Locale.Date(now, loc),
+		"AwaitingReview": awaitingReview,
+		"InProgress":     inProgress,
+		"Overdue":        newDigestSection(overdue, e.cfg.Locale, loc),
+		"DueSoon":        newDigestSection(dueSoon, e.cfg.Locale, loc),
+		"URL":            e.cfg.BaseURL + "/workflows",
+	}), true
+}
+
+// digestSection lists the workflows of a digest heading, at most maxDigestItems of them
+type digestSection struct {
+	Count int
+	Items []digestItem
+	More  int // workflows left out of Items
+}
+
+type digestItem struct {
+	Label string
+	Due   string
+}
+
+// newDigestSection returns nil for no workflows, which leaves the heading out of the digest
+func newDigestSection(states []*storage.WorkflowState, l locale.Locale, loc *time.Location) *digestSection {
+	if len(states) == 0 {
+		return nil
+	}
+	section := &digestSection{Count: len(states), More: max(len(states)-maxDigestItems, 0)}
+	for _, state := range states[:len(states)-section.More] {
+		section.Items = append(section.Items, digestItem{Label: digestLabel(state), Due: l.Display(*state.DueAt, loc)})
+	}
+	return section
+}
+
+func digestLabel(state *storage.WorkflowState) string {
+	label := state.Title
+	if label == "" {
+		label = truncateString(state.TaskDescription, descriptionLength)
+	}
+	return fmt.Sprintf("#%d %s", state.Seq, label)
+}
+
+func (e *Engine) workflowURL(state *storage.WorkflowState) string {
+	return fmt.Sprintf("%s/w/%d", e.cfg.BaseURL, state.Seq)
+}
+
+func (e *Engine) sendReminder(ctx context.Context, workflowID, message string) {
+	if e.cfg.TelegramBotToken == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+	defer cancel()
+	if err := e.notifier.SendToChat(ctx, e.defaultChatID(), message); err != nil {
+		slog.Warn("Failed to send Telegram reminder", "error", err, "workflow_id", workflowID)
+	}
+}
@@ -0,0 +1,75 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"workflower/storage"
+)
+
+// ErrTelegramDisabled is returned when notifications are requested without TELEGRAM_BOT_TOKEN
+var ErrTelegramDisabled = newError(CodeUnavailable, "telegram notifications are disabled (set TELEGRAM_BOT_TOKEN)")
+
+// ResendReport summarises a ResendReviewNotifications run
+type ResendReport struct {
+	Sent            []string          `json:"sent"`             // workflow IDs announced now
+	AlreadyNotified []string          `json:"already_notified"` // announced after their last (re)assignment
+	Failed          map[string]string `json:"failed"`           // workflow ID -> error
+}
+
+// ResendReviewNotifications announces again every workflow that entered review (or was
+// reassigned) since the given time and whose announcement never got through, e.g. while the
+// bot token was revoked
+// It is idempotent: a successful announcement is recorded on the workflow, so running it
+// again only retries the failures.
+func (e *Engine) ResendReviewNotifications(ctx context.Context, since time.Time) (*ResendReport, error) {
+	if e.cfg.TelegramBotToken == "" {
+		return nil, ErrTelegramDisabled
+	}
+
+	report := &ResendReport{Sent: []string{}, AlreadyNotified: []string{}, Failed: map[string]string{}}
+	for _, state := range e.store.ListByStatus(storage.StatusAwaitingReview) {
+		if state.AssignedAt == nil || state.AssignedAt.Before(since) {
+			continue
+		}
+		if state.ReviewNotifiedAt != nil && !state.ReviewNotifiedAt.Before(*state.AssignedAt) {
+			report.AlreadyNotified = append(report.AlreadyNotified, state.ID)
+			continue
+		}
+
+		chatID, assignedTo := e.reviewRecipient(state)
+		if chatID == "" {
+			report.Failed[state.ID] = "no chat to notify (set TELEGRAM_CHAT_ID)"
+			continue
+		}
+		message := e.reviewMessage(chatID, state, e.cfg.Locale.Display(*state.AssignedAt, e.ChatLocation(chatID)), assignedTo)
+
+		sendCtx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+		err := e.notifier.SendToChat(sendCtx, chatID, message)
+		cancel()
+		if err != nil {
+			slog.WarnContext(logContext(state), "Failed to re-send review notification", "error", err, "workflow_id", state.ID, "chat_id", chatID)
+			// Transport errors carry the request URL, which contains the bot token
+			report.Failed[state.ID] = strings.ReplaceAll(err.Error(), e.cfg.TelegramBotToken, "[REDACTED]")
+			continue
+		}
+		e.markReviewNotified(state.ID, time.Now())
+		report.Sent = append(report.Sent, state.ID)
+	}
+
+	slog.Info("Re-sent review notifications", "since", since, "sent", len(report.Sent),
+		"already_notified", len(report.AlreadyNotified), "failed", len(report.Failed))
+	return report, nil
+}
+
+// markReviewNotified records that the current review of a workflow was announced
+func (e *Engine) markReviewNotified(id string, at time.Time) {
+	state, ok := e.store.Get(id)
+	if !ok {
+		return
+	}
+	state.ReviewNotifiedAt = &at
+	e.store.Save(state)
+}
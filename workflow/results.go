@@ -0,0 +1,165 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+const (
+	// resultArchiveTimeout bounds archiving the files of one song
+	resultArchiveTimeout = 30 * time.Minute
+	// resultCatchUp is how far back songs completed while the server was down are archived on startup
+	resultCatchUp = 7 * 24 * time.Hour
+	// resultManifest describes an archived song; it is written last, so it marks a finished archive
+	resultManifest = "song.json"
+)
+
+// Result file kinds, as in the archive file names (audio.mp3, video.mp4)
+const (
+	ResultAudio = "audio"
+	ResultVideo = "video"
+)
+
+// ResultArchive is the manifest of an archived song (RESULTS_DIR/<workflow id>/song.json)
+type ResultArchive struct {
+	WorkflowID string                    `json:"workflow_id"`
+	Title      string                    `json:"title"`
+	ClipID     string                    `json:"clip_id"`
+	Lyrics     string                    `json:"lyrics"`
+	Properties *storage.SunoProperties   `json:"properties,omitempty"`
+	Files      map[string]*suno.Download `json:"files"` // by kind, paths relative to the archive
+	ArchivedAt time.Time                 `json:"archived_at"`
+}
+
+// newResultsSubscriber archives songs as they complete and removes the archive of deleted workflows
+func newResultsSubscriber(e *Engine) func(Event) {
+	return func(event Event) {
+		if e.cfg.ResultsDir == "" {
+			return
+		}
+		switch ev := event.(type) {
+		case StatusChanged:
+			if ev.To == storage.StatusCompleted {
+				go e.archiveResult(context.Background(), ev.Workflow.ID)
+			}
+		case Deleted:
+			if err := os.RemoveAll(e.resultDir(ev.Workflow.ID)); err != nil {
+				slog.Warn("Failed to remove archived song", "workflow_id", ev.Workflow.ID, "error", err)
+			}
+		}
+	}
+}
+
+// resumeResultArchival archives the songs completed recently that have no finished archive,
+// e.g. because the server stopped during a download; interrupted downloads are resumed
+func (e *Engine) resumeResultArchival(ctx context.Context) {
+	if e.cfg.ResultsDir == "" {
+		return
+	}
+	for _, state := range e.store.ListByStatus(storage.StatusCompleted) {
+		if time.Since(state.UpdatedAt) > resultCatchUp {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(e.resultDir(state.ID), resultManifest)); err != nil {
+			go e.archiveResult(ctx, state.ID)
+		}
+	}
+}
+
+func (e *Engine) resultDir(workflowID string) string {
+	return filepath.Join(e.cfg.ResultsDir, workflowID)
+}
+
+// ResultFile returns the archived file of a kind (ResultAudio, ResultVideo) of a workflow, if
+// its archive is finished
+func (e *Engine) ResultFile(workflowID, kind string) (string, bool) {
+	if e.cfg.ResultsDir == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(e.resultDir(workflowID), resultManifest))
+	if err != nil {
+		return "", false
+	}
+	var archive ResultArchive
+	if err := json.Unmarshal(data, &archive); err != nil || archive.Files[kind] == nil {
+		return "", false
+	}
+	return filepath.Join(e.resultDir(workflowID), archive.Files[kind].Path), true
+}
+
+// archiveResult downloads the audio and video of a completed song with their checksums, then
+// writes its lyrics and manifest; an expired link is renewed from Suno once
+func (e *Engine) archiveResult(ctx context.Context, workflowID string) {
+	if _, running := e.resultRuns.LoadOrStore(workflowID, true); running {
+		return
+	}
+	defer e.resultRuns.Delete(workflowID)
+	ctx, cancel := context.WithTimeout(ctx, resultArchiveTimeout)
+	defer cancel()
+
+	state, ok := e.store.Get(workflowID)
+	if !ok {
+		return
+	}
+	dir := e.resultDir(workflowID)
+	archive := ResultArchive{
+		WorkflowID: state.ID,
+		Title:      state.Title,
+		ClipID:     FinalClipID(state),
+		Lyrics:     submittedLyrics(state),
+		Properties: submittedProperties(state),
+		Files:      make(map[string]*suno.Download),
+	}
+	kinds := []struct {
+		name, defaultExt string
+		url              func(*storage.WorkflowState) string
+	}{
+		{ResultAudio, ".mp3", func(wf *storage.WorkflowState) string { return wf.AudioURL }},
+		{ResultVideo, ".mp4", func(wf *storage.WorkflowState) string { return wf.VideoURL }},
+	}
+	for _, kind := range kinds {
+		if kind.url(state) == "" {
+			continue
+		}
+		file := filepath.Join(dir, kind.name+mediaExt(kind.url(state), kind.defaultExt))
+		d, err := suno.DownloadFile(ctx, kind.url(state), file)
+		if errors.Is(err, suno.ErrLinkExpired) {
+			if err = e.RefreshMediaURLs(ctx, state); err == nil {
+				d, err = suno.DownloadFile(ctx, kind.url(state), file)
+			}
+		}
+		if err != nil {
+			slog.ErrorContext(logContext(state), "Failed to archive song", "workflow_id", state.ID, "kind", kind.name, "error", err)
+			return
+		}
+		d.Path = filepath.Base(d.Path)
+		archive.Files[kind.name] = d
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		slog.ErrorContext(logContext(state), "Failed to archive song", "workflow_id", state.ID, "error", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lyrics.txt"), []byte(archive.Lyrics), 0o644); err != nil {
+		slog.ErrorContext(logContext(state), "Failed to archive song", "workflow_id", state.ID, "error", err)
+		return
+	}
+	archive.ArchivedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, resultManifest), data, 0o644)
+	}
+	if err != nil {
+		slog.ErrorContext(logContext(state), "Failed to archive song", "workflow_id", state.ID, "error", err)
+		return
+	}
+	slog.InfoContext(logContext(state), "Song archived", "workflow_id", state.ID, "dir", dir, "files", len(archive.Files))
+}
@@ -0,0 +1,131 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"workflower/config"
+	"workflower/lib/suno"
+	"workflower/lib/suno/sunotest"
+	"workflower/storage"
+	"workflower/templates/prompts"
+)
+
+// newResultsEngine returns an engine archiving songs to a temporary RESULTS_DIR, generating on
+// a mock whose clips are served by a CDN that answers 404 for expired links
+func newResultsEngine(t *testing.T) (*Engine, *sunotest.Mock) {
+	t.Helper()
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/expired") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("media of " + r.URL.Path))
+	}))
+	t.Cleanup(cdn.Close)
+
+	cfg := config.Load()
+	cfg.ResultsDir = t.TempDir()
+	e := NewEngine(cfg, storage.NewStore(), prompts.Init())
+	mock := sunotest.New()
+	mock.MediaBaseURL = cdn.URL
+	e.SetSunoAPI(mock)
+	return e, mock
+}
+
+// completedSong saves a completed workflow of a finished mock clip
+func completedSong(t *testing.T, e *Engine, mock *sunotest.Mock) (*storage.WorkflowState, *suno.AudioInfo) {
+	t.Helper()
+	clips, err := mock.CustomGenerate(context.Background(), &suno.CustomGenerateRequest{Prompt: "la la la", Title: "Night Drive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.Complete(clips[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	clip, err := mock.GetClip(context.Background(), clips[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &storage.WorkflowState{
+		ID:           "wf-1",
+		Status:       storage.StatusCompleted,
+		Title:        "Night Drive",
+		EditedLyrics: "la la la",
+		SunoJobID:    clip.ID,
+		AudioURL:     clip.AudioURL,
+		VideoURL:     clip.VideoURL,
+	}
+	e.store.Save(state)
+	return state, clip
+}
+
+// readArchive returns the manifest of an archived song, failing the test when it is missing
+func readArchive(t *testing.T, e *Engine, workflowID string) ResultArchive {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(e.resultDir(workflowID), resultManifest))
+	if err != nil {
+		t.Fatalf("no finished archive: %v", err)
+	}
+	var archive ResultArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestArchiveResult(t *testing.T) {
+	e, mock := newResultsEngine(t)
+	state, clip := completedSong(t, e, mock)
+
+	e.archiveResult(context.Background(), state.ID)
+
+	archive := readArchive(t, e, state.ID)
+	if archive.WorkflowID != state.ID || archive.ClipID != clip.ID || archive.Title != "Night Drive" || archive.Lyrics != "la la la" {
+		t.Errorf("manifest = %+v", archive)
+	}
+	for kind, want := range map[string]string{ResultAudio: "/" + clip.ID + ".mp3", ResultVideo: "/" + clip.ID + ".mp4"} {
+		path, ok := e.ResultFile(state.ID, kind)
+		if !ok {
+			t.Errorf("%s not archived", kind)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "media of "+want {
+			t.Errorf("%s = %q, want the media of %s", kind, data, want)
+		}
+		if d := archive.Files[kind]; d == nil || d.Path != filepath.Base(path) || d.Size != int64(len(data)) || d.SHA256 == "" {
+			t.Errorf("%s manifest entry = %+v", kind, d)
+		}
+	}
+	lyrics, err := os.ReadFile(filepath.Join(e.resultDir(state.ID), "lyrics.txt"))
+	if err != nil || string(lyrics) != "la la la" {
+		t.Errorf("lyrics.txt = %q, %v", lyrics, err)
+	}
+}
+
+func TestArchiveResultRenewsExpiredLink(t *testing.T) {
+	e, mock := newResultsEngine(t)
+	state, clip := completedSong(t, e, mock)
+	state.AudioURL = mock.MediaBaseURL + "/expired/" + clip.ID + ".mp3"
+	e.store.Save(state)
+
+	e.archiveResult(context.Background(), state.ID)
+
+	readArchive(t, e, state.ID)
+	if state.AudioURL != clip.AudioURL {
+		t.Errorf("audio URL = %s, want the renewed %s", state.AudioURL, clip.AudioURL)
+	}
+	if n := mock.Calls("GetClip"); n != 2 {
+		t.Errorf("GetClip called %d times, want once to renew the link (plus once by the test)", n)
+	}
+}
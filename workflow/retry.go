@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"workflower/storage"
+)
+
+// ErrNotFailed is returned when retrying a workflow that has not failed
+var ErrNotFailed = newError(CodeConflict, "only failed workflows can be retried")
+
+// preparationSteps are the steps before the human review
+var preparationSteps = map[string]bool{
+	StepTranscript:   true,
+	StepModeration:   true,
+	StepLyrics:       true,
+	StepProperties:   true,
+	StepBrackets:     true,
+	StepPersonaInspo: true,
+	StepSimilarity:   true,
+	StepValidation:   true,
+}
+
+// RetryWorkflow re-runs a failed workflow from the step that failed and returns that step
+// Everything generated before it is kept: a preparation failure skips the lyrics, properties
+// and persona/inspo already generated, and a Suno failure resubmits only what has no clip
+// yet and polls clips already submitted, so nothing is generated (or paid for) twice.
+func (e *Engine) RetryWorkflow(ctx context.Context, state *storage.WorkflowState) (string, error) {
+	if state.Status != storage.StatusFailed {
+		return "", ForWorkflow(ErrNotFailed, state.ID)
+	}
+	step := failedStep(state)
+	ctx = adoptRequestID(ctx, state)
+	slog.InfoContext(ctx, "Retrying workflow", "workflow_id", state.ID, "step", step, "error", state.ErrorMsg)
+	state.ErrorMsg = ""
+
+	if preparationSteps[step] {
+		e.setStatus(state, storage.StatusProcessing)
+		go e.runWorkflowSteps(e.track(ctx, state), state)
+		return step, nil
+	}
+
+	if state.SunoJobID != "" {
+		e.setStatus(state, storage.StatusGenerating)
+	} else {
+		e.setStatus(state, storage.StatusApproved)
+	}
+	go e.resumeSuno(e.track(ctx, state), state)
+	return step, nil
+}
+
+// resumeSuno continues the Suno phase of a workflow from what was already submitted
+func (e *Engine) resumeSuno(ctx context.Context, state *storage.WorkflowState) {
+	switch {
+	case len(state.Segments) > 0:
+		e.generateSegments(ctx, state, sunoTags(state, submittedProperties(state)), state.Title)
+	case len(state.Variants) > 0:
+		e.generateVariants(ctx, state, submittedLyrics(state), state.Title)
+	case state.SunoJobID != "":
+		e.pollSunoCompletion(ctx, state, state.SunoJobID)
+	default:
+		e.submitToSuno(ctx, state)
+	}
+}
+
+// failedStep returns the step a workflow failed at: the last step run with an error,
+// or the step named in ErrorMsg for failures outside a step run
+func failedStep(state *storage.WorkflowState) string {
+	for i := len(state.Steps) - 1; i >= 0; i-- {
+		if state.Steps[i].Error != "" {
+			return state.Steps[i].Step
+		}
+	}
+	step, _, _ := strings.Cut(state.ErrorMsg, " failed: ")
+	step, _, _ = strings.Cut(step, " (segment ")
+	return step
+}
+
+// stepSucceeded reports whether a step has completed without error at least once
+func stepSucceeded(state *storage.WorkflowState, step string) bool {
+	for _, run := range state.Steps {
+		if run.Step == step && run.FinishedAt != nil && run.Error == "" {
+			return true
+		}
+	}
+	return false
+}
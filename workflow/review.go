@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"workflower/storage"
+)
+
+// ErrNotInReview is returned when a review decision or edit is made on a workflow that is not
+// awaiting review, e.g. because another reviewer decided it first
+var ErrNotInReview = newError(CodeConflict, "workflow is not awaiting review")
+
+// claimReview claims the review decision of a workflow in status from, so that concurrent
+// approvals and rejections of it cannot both go through; release ends the claim once the
+// decision has set the new status (or failed, leaving the workflow in review)
+func (e *Engine) claimReview(state *storage.WorkflowState, from string) (release func(), err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if state.Status != from || e.deciding[state.ID] {
+		return nil, ForWorkflow(ErrNotInReview, state.ID)
+	}
+	e.deciding[state.ID] = true
+	return func() {
+		e.mu.Lock()
+		delete(e.deciding, state.ID)
+		e.mu.Unlock()
+	}, nil
+}
+
+// EditReview applies a reviewer's edits (lyrics, title, properties, lint override) to a workflow
+// awaiting review and saves them; edit runs under the engine lock and must only set fields
+// It returns ErrNotInReview once the review has been decided or while a decision is being made.
+func (e *Engine) EditReview(state *storage.WorkflowState, edit func(*storage.WorkflowState)) error {
+	e.mu.Lock()
+	if state.Status != storage.StatusAwaitingReview || e.deciding[state.ID] {
+		e.mu.Unlock()
+		return ForWorkflow(ErrNotInReview, state.ID)
+	}
+	edit(state)
+	e.mu.Unlock()
+	e.store.Save(state)
+	return nil
+}
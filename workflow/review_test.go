@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"workflower/config"
+	"workflower/lib/suno/sunotest"
+	"workflower/storage"
+	"workflower/templates/prompts"
+)
+
+// newReviewEngine returns an engine on the Suno mock with a workflow awaiting review
+func newReviewEngine(t *testing.T) (*Engine, *storage.WorkflowState) {
+	t.Helper()
+	cfg := config.Load()
+	cfg.StoreFile, cfg.ArchiveDir, cfg.ResultsDir, cfg.SyncTarget = "", "", "", ""
+	cfg.OpenAIAPIKeys, cfg.OpenAIAPIKey = nil, ""
+	cfg.TelegramBotToken, cfg.WebhookURLs = "", nil
+	e := NewEngine(cfg, storage.NewStore(), prompts.Init())
+	e.SetSunoAPI(sunotest.New())
+
+	state := &storage.WorkflowState{
+		ID:                 "wf-review",
+		Status:             storage.StatusAwaitingReview,
+		TaskDescription:    "A birthday song",
+		Title:              "Happy Birthday",
+		Lyrics:             "[Verse]\nCandles on the cake tonight",
+		LyricsWithBrackets: "[Verse]\nCandles on the cake tonight",
+		SunoProperties:     &storage.SunoProperties{Style: "pop, upbeat", VocalType: "female"},
+	}
+	e.store.Save(state)
+	return e, state
+}
+
+// decide runs the review decisions concurrently and returns how many succeeded and how many
+// were refused with ErrNotInReview; any other error fails the test
+func decide(t *testing.T, decisions []func() error) (ok, refused int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make([]error, len(decisions))
+	for i, decision := range decisions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = decision()
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrNotInReview):
+			refused++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return ok, refused
+}
+
+func TestApproveConcurrentlyApprovesOnce(t *testing.T) {
+	e, state := newReviewEngine(t)
+	var reviewed atomic.Int32
+	e.Events().Subscribe(func(event Event) {
+		if _, ok := event.(Reviewed); ok {
+			reviewed.Add(1)
+		}
+	})
+
+	const reviewers = 16
+	decisions := make([]func() error, reviewers)
+	for i := range decisions {
+		decisions[i] = func() error {
+			return e.ApproveWorkflow(context.Background(), state, fmt.Sprintf("reviewer-%d", i))
+		}
+	}
+	ok, refused := decide(t, decisions)
+	if ok != 1 || refused != reviewers-1 {
+		t.Fatalf("%d approvals went through and %d were refused, want 1 and %d", ok, refused, reviewers-1)
+	}
+	if n := reviewed.Load(); n != 1 {
+		t.Fatalf("%d review decisions published, want 1", n)
+	}
+	if err := e.ApproveWorkflow(context.Background(), state, "late"); !errors.Is(err, ErrNotInReview) {
+		t.Fatalf("approving an approved workflow: %v, want ErrNotInReview", err)
+	}
+}
+
+func TestApproveAndRejectConcurrentlyDecideOnce(t *testing.T) {
+	e, state := newReviewEngine(t)
+	ok, refused := decide(t, []func() error{
+		func() error { return e.ApproveWorkflow(context.Background(), state, "alice") },
+		func() error { return e.RejectWorkflow(state, "bob") },
+	})
+	if ok != 1 || refused != 1 {
+		t.Fatalf("%d decisions went through and %d were refused, want 1 and 1", ok, refused)
+	}
+	if err := e.EditReview(state, func(state *storage.WorkflowState) { state.EditedTitle = "Late" }); !errors.Is(err, ErrNotInReview) {
+		t.Fatalf("editing a decided workflow: %v, want ErrNotInReview", err)
+	}
+}
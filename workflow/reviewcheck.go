@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"context"
+
+	"workflower/storage"
+)
+
+// CheckReview recomputes the lyrics issues and screening hits of a workflow from the reviewer's
+// edits, as approving it would, without saving it
+func (e *Engine) CheckReview(state *storage.WorkflowState) {
+	lyrics := submittedLyrics(state)
+	state.LyricsIssues = e.lyricsIssues(state, lyrics)
+	state.ScreeningHits = e.ScreenTitleAndStyle(state)
+}
+
+// lyricsIssues checks lyrics against the Suno constraints (ValidateLyrics), the structure of the
+// source lyrics and known songs
+func (e *Engine) lyricsIssues(state *storage.WorkflowState, lyrics string) []storage.LyricsIssue {
+	issues := append(e.ValidateLyrics(lyrics), e.structureIssues(state, lyrics)...)
+	return append(issues, e.similarityIssues(state, lyrics)...)
+}
+
+// TemplateTitle returns the title the naming template renders for a workflow and its edits
+func (e *Engine) TemplateTitle(state *storage.WorkflowState) string {
+	return e.namer.Title(state)
+}
+
+// RegenerateProperties asks the LLM for a new set of Suno properties for a workflow under review
+// The reviewer decides whether to keep them, so only the cost of the call is saved on the workflow.
+func (e *Engine) RegenerateProperties(ctx context.Context, state *storage.WorkflowState) (*storage.SunoProperties, error) {
+	if !e.cfg.HasOpenAI() {
+		return nil, ForWorkflow(newError(CodeUnavailable, "properties are generated with OpenAI, which is not configured"), state.ID)
+	}
+	props, err := e.determineSunoProperties(ctx, state)
+	e.store.Save(state)
+	return props, err
+}
@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"workflower/lib/locale"
+	"workflower/storage"
+)
+
+// ReviewerStats summarises the decisions of one reviewer
+type ReviewerStats struct {
+	Reviewer          string  `json:"reviewer"`
+	Reviews           int     `json:"reviews"`
+	Approved          int     `json:"approved"`            // as generated
+	ApprovedWithEdits int     `json:"approved_with_edits"` // lyrics or properties changed first
+	Rejected          int     `json:"rejected"`
+	ApprovalRate      float64 `json:"approval_rate"` // approved, with or without edits
+	ChangeRate        float64 `json:"change_rate"`   // approved with edits or rejected
+	MedianTurnaroundS int64   `json:"median_turnaround_s"`
+	MeanTurnaroundS   int64   `json:"mean_turnaround_s"`
+
+	turnarounds []int64
+}
+
+// ReviewReport is the review throughput of a period, taken from the audit log
+type ReviewReport struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Reviews   int             `json:"reviews"`
+	Reviewers []ReviewerStats `json:"reviewers"` // most reviews first
+	// Decisions per hour of the day (0-23) in the display time zone
+	ByHour       [24]int `json:"by_hour"`
+	BusiestHours []int   `json:"busiest_hours"` // hours with the most decisions, busiest first (up to 3)
+}
+
+// ReviewReport computes turnaround times, approval and change rates per reviewer and the
+// busiest hours from the review decisions recorded between from and to
+// Only decisions still in the bounded audit log are counted.
+func (e *Engine) ReviewReport(from, to time.Time) ReviewReport {
+	report := ReviewReport{From: from, To: to, Reviewers: []ReviewerStats{}, BusiestHours: []int{}}
+	byReviewer := map[string]*ReviewerStats{}
+
+	for _, entry := range e.store.ListAuditEntries("") {
+		if entry.Action != EventReviewed || entry.At.Before(from) || !entry.At.Before(to) {
+			continue
+		}
+		reviewer := entry.Actor
+		if reviewer == "" {
+			reviewer = "(anonymous)"
+		}
+		stats, ok := byReviewer[reviewer]
+		if !ok {
+			stats = &ReviewerStats{Reviewer: reviewer}
+			byReviewer[reviewer] = stats
+		}
+
+		stats.Reviews++
+		switch entry.Detail {
+		case DecisionApproved:
+			stats.Approved++
+		case DecisionApprovedEdited:
+			stats.ApprovedWithEdits++
+		case DecisionRejected:
+			stats.Rejected++
+		}
+		stats.turnarounds = append(stats.turnarounds, entry.TurnaroundMS/1000)
+		report.ByHour[entry.At.In(e.cfg.DisplayLocation).Hour()]++
+		report.Reviews++
+	}
+
+	for _, stats := range byReviewer {
+		stats.ApprovalRate = ratio(stats.Approved+stats.ApprovedWithEdits, stats.Reviews)
+		stats.ChangeRate = ratio(stats.ApprovedWithEdits+stats.Rejected, stats.Reviews)
+		slices.Sort(stats.turnarounds)
+		stats.MedianTurnaroundS = stats.turnarounds[len(stats.turnarounds)/2]
+		var total int64
+		for _, t := range stats.turnarounds {
+			total += t
+		}
+		stats.MeanTurnaroundS = total / int64(len(stats.turnarounds))
+		report.Reviewers = append(report.Reviewers, *stats)
+	}
+	slices.SortFunc(report.Reviewers, func(a, b ReviewerStats) int {
+		if a.Reviews != b.Reviews {
+			return b.Reviews - a.Reviews
+		}
+		return strings.Compare(a.Reviewer, b.Reviewer)
+	})
+
+	hours := make([]int, 0, 24)
+	for hour, count := range report.ByHour {
+		if count > 0 {
+			hours = append(hours, hour)
+		}
+	}
+	slices.SortStableFunc(hours, func(a, b int) int { return report.ByHour[b] - report.ByHour[a] })
+	report.BusiestHours = hours[:min(len(hours), 3)]
+	return report
+}
+
+// WriteCSV writes the per-reviewer rows of the report as a spreadsheet, with dates, rates and
+// turnarounds formatted for l in loc
+func (r ReviewReport) WriteCSV(w io.Writer, l locale.Locale, loc *time.Location) error {
+	out := l.CSVWriter(w)
+	_ = out.Write([]string{"From", "To", "Reviewer", "Reviews", "Approved", "Approved With Edits", "Rejected",
+		"Approval Rate", "Change Rate", "Median Turnaround", "Mean Turnaround"})
+	from, to := l.DateTime(r.From, loc), l.DateTime(r.To, loc)
+	for _, stats := range r.Reviewers {
+		_ = out.Write([]string{
+			from, to, stats.Reviewer,
+			l.Int(stats.Reviews), l.Int(stats.Approved), l.Int(stats.ApprovedWithEdits), l.Int(stats.Rejected),
+			l.Percent(stats.ApprovalRate), l.Percent(stats.ChangeRate),
+			l.Duration(time.Duration(stats.MedianTurnaroundS) * time.Second),
+			l.Duration(time.Duration(stats.MeanTurnaroundS) * time.Second),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// publishReviewed announces a review decision, timed from the (re)assignment of the review
+func (e *Engine) publishReviewed(state *storage.WorkflowState, by, decision string) {
+	now := time.Now()
+	var turnaround time.Duration
+	if state.AssignedAt != nil {
+		turnaround = now.Sub(*state.AssignedAt)
+	}
+	e.events.Publish(Reviewed{By: by, Decision: decision, Turnaround: turnaround, At: now, Workflow: e.Snapshot(state)})
+}
+
+// reviewerEdited reports whether the reviewer changed the generated lyrics or properties
+func reviewerEdited(state *storage.WorkflowState) bool {
+	if submittedLyrics(state) != state.LyricsWithBrackets {
+		return true
+	}
+	edited, generated := state.EditedProperties, state.SunoProperties
+	return edited != nil && generated != nil && *edited != *generated
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
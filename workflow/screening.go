@@ -0,0 +1,169 @@
+package workflow
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"workflower/config"
+	"workflower/storage"
+)
+
+// defaultScreeningTerms are artist names Suno rejects in style prompts, trademarks and profanity,
+// with the wording suggested instead ("" when the term should just be removed)
+var defaultScreeningTerms = map[string]string{
+	// Artists: describe the sound instead of naming it
+	"Taylor Swift":          "female pop vocals, confessional country-pop",
+	"Beyonce":               "powerful female R&B vocals",
+	"Beyoncé":               "powerful female R&B vocals",
+	"Drake":                 "moody melodic hip hop, male vocals",
+	"Billie Eilish":         "whispery female vocals, dark minimal pop",
+	"Ed Sheeran":            "acoustic pop, male singer-songwriter",
+	"The Beatles":           "60s british pop rock, vocal harmonies",
+	"Beatles":               "60s british pop rock, vocal harmonies",
+	"Eminem":                "fast aggressive rap, male vocals",
+	"Adele":                 "soulful female ballad vocals",
+	"Michael Jackson":       "80s funk pop, energetic male vocals",
+	"Metallica":             "thrash metal, distorted guitars",
+	"Daft Punk":             "french house, vocoder vocals",
+	"Bob Dylan":             "folk rock, harmonica, raspy male vocals",
+	"Elvis Presley":         "50s rock and roll, crooning male vocals",
+	"Lady Gaga":             "theatrical dance pop, female vocals",
+	"Kanye West":            "soulful sampled hip hop",
+	"Bruno Mars":            "retro funk pop, smooth male vocals",
+	"Ariana Grande":         "airy high female pop vocals",
+	"Nirvana":               "90s grunge, distorted guitars",
+	"Rolling Stones":        "bluesy 60s rock, swaggering vocals",
+	"Frank Sinatra":         "big band swing, crooner",
+	"Johnny Cash":           "outlaw country, deep baritone",
+	"Hans Zimmer":           "epic orchestral film score",
+	"Pink Floyd":            "psychedelic progressive rock",
+	"Ludovico Einaudi":      "minimal neoclassical piano",
+	"Imagine Dragons":       "anthemic arena pop rock",
+	"Coldplay":              "anthemic piano pop rock",
+	"Linkin Park":           "nu metal, rap rock",
+	"Rihanna":               "caribbean-tinged female pop",
+	"Justin Bieber":         "light male pop vocals",
+	"The Weeknd":            "dark 80s synth R&B, falsetto",
+	"Dua Lipa":              "disco pop, female vocals",
+	"Post Malone":           "melodic hip hop, sung male vocals",
+	"Kendrick Lamar":        "conscious hip hop, jazz samples",
+	"Bad Bunny":             "reggaeton, latin trap",
+	"Olivia Rodrigo":        "pop punk, female vocals",
+	"Harry Styles":          "70s soft rock pop, male vocals",
+	"Freddie Mercury":       "operatic rock vocals",
+	"Whitney Houston":       "powerhouse female soul vocals",
+	"Mariah Carey":          "female R&B vocals, whistle register",
+	"Stevie Wonder":         "70s soul, clavinet",
+	"Radiohead":             "art rock, atmospheric guitars",
+	"Arctic Monkeys":        "indie rock, british male vocals",
+	"Red Hot Chili Peppers": "funk rock, slap bass",
+
+	// Trademarks
+	"Coca-Cola":    "soda",
+	"Pepsi":        "soda",
+	"iPhone":       "phone",
+	"Instagram":    "social media",
+	"TikTok":       "short videos",
+	"Disney":       "fairytale",
+	"Pokemon":      "pocket monsters",
+	"Pokémon":      "pocket monsters",
+	"Star Wars":    "space opera",
+	"Harry Potter": "wizard school",
+	"McDonald's":   "fast food",
+	"Nike":         "sneakers",
+	"Lego":         "building blocks",
+
+	// Profanity
+	"fuck":         "",
+	"fucking":      "",
+	"shit":         "",
+	"bitch":        "",
+	"motherfucker": "",
+}
+
+// screeningTerm is an entry of the screening list
+type screeningTerm struct {
+	term        string
+	alternative string
+	pattern     *regexp.Regexp // the term as a whole word or phrase, case-insensitively
+}
+
+// newScreeningList builds the screening list from the built-in terms (unless disabled) and
+// SCREENING_TERMS, which overrides the suggestion of a built-in term
+func newScreeningList(cfg *config.Config) []screeningTerm {
+	terms := make(map[string]string)
+	if cfg.ScreeningDefaults {
+		for term, alternative := range defaultScreeningTerms {
+			terms[term] = alternative
+		}
+	}
+	for term, alternative := range cfg.ScreeningTerms {
+		terms[term] = alternative
+	}
+
+	list := make([]screeningTerm, 0, len(terms))
+	for term, alternative := range terms {
+		list = append(list, screeningTerm{
+			term:        term,
+			alternative: alternative,
+			pattern:     regexp.MustCompile(`(?i)(^|[^\pL\pN])` + regexp.QuoteMeta(term) + `($|[^\pL\pN])`),
+		})
+	}
+	// Longer terms first, so "The Beatles" is reported rather than "Beatles" as well
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].term) != len(list[j].term) {
+			return len(list[i].term) > len(list[j].term)
+		}
+		return list[i].term < list[j].term
+	})
+	return list
+}
+
+// ScreenTitleAndStyle checks the title and the style tags of the property sets under review
+// against the screening list (artist names Suno rejects, trademarks, profanity)
+// Hits are warnings with a suggested alternative; they do not block approval.
+func (e *Engine) ScreenTitleAndStyle(state *storage.WorkflowState) []storage.ScreeningHit {
+	var hits []storage.ScreeningHit
+	screen := func(field, text string) {
+		var matched []string
+		for _, entry := range e.screening {
+			if !entry.pattern.MatchString(text) || containsTermOf(matched, entry.term) {
+				continue
+			}
+			matched = append(matched, entry.term)
+			hits = append(hits, storage.ScreeningHit{Field: field, Term: entry.term, Alternative: entry.alternative})
+		}
+	}
+
+	screen("Title", e.title(state))
+	if props := submittedProperties(state); props != nil {
+		screen("Style", props.Style)
+		screen("Vocal Type", props.VocalType)
+		screen("Style Influence", props.StyleInfluence)
+	}
+	if b := state.VariantB; b != nil {
+		screen("Style (B)", b.Style)
+		screen("Vocal Type (B)", b.VocalType)
+		screen("Style Influence (B)", b.StyleInfluence)
+	}
+	return hits
+}
+
+// containsTermOf reports whether term is part of an already matched longer term
+func containsTermOf(matched []string, term string) bool {
+	for _, m := range matched {
+		if strings.Contains(strings.ToLower(m), strings.ToLower(term)) {
+			return true
+		}
+	}
+	return false
+}
+
+// title returns the title sent to Suno: the reviewer's edit, or the one rendered from the naming template
+func (e *Engine) title(state *storage.WorkflowState) string {
+	if state.EditedTitle != "" {
+		return state.EditedTitle
+	}
+	return e.namer.Title(state)
+}
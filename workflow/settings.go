@@ -0,0 +1,196 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"workflower/storage"
+)
+
+// AutoApprover is recorded as the reviewer of automatically approved workflows
+const AutoApprover = "auto-approve"
+
+// Bounds of the Suno poll interval setting
+const (
+	minPollInterval = time.Second
+	maxPollInterval = 5 * time.Minute
+)
+
+// maxHouseStyleLength bounds the house style guide, which is sent with every LLM call
+const maxHouseStyleLength = 4000
+
+// Settings are the settings tunable at runtime on /admin
+// Saved values override the environment (AUTO_APPROVE, ENABLE_PREMIUM_FEATURES,
+// SUNO_POLL_INTERVAL, ARCHIVE_AFTER, HOUSE_STYLE, SUNO_LOW_CREDITS) without a restart.
+type Settings struct {
+	AutoApprove    bool          // approve workflows without lyrics issues or screening hits as proposed
+	DefaultPremium bool          // premium mode of workflows started without choosing one
+	PollInterval   time.Duration // how often a submitted clip is polled
+	RetentionDays  int           // finished workflows untouched for this long are archived, 0 never
+	HouseStyle     string        // style guide appended to every LLM system prompt, "" for none
+	LowCredits     int           // Suno credits left below which admins are warned, 0 never
+	Presets        []storage.Preset
+}
+
+// DefaultSettings returns the settings configured in the environment
+func (e *Engine) DefaultSettings() Settings {
+	return Settings{
+		AutoApprove:    e.cfg.AutoApprove,
+		DefaultPremium: e.cfg.EnablePremiumFeatures,
+		PollInterval:   e.cfg.SunoPollInterval,
+		RetentionDays:  int(e.cfg.ArchiveAfter / (24 * time.Hour)),
+		HouseStyle:     strings.TrimSpace(e.cfg.HouseStyle),
+		LowCredits:     max(e.cfg.SunoLowCredits, 0),
+	}
+}
+
+// Settings returns the settings in effect: the saved overrides over the environment defaults
+func (e *Engine) Settings() Settings {
+	settings := e.DefaultSettings()
+	saved := e.store.Settings()
+	if saved.AutoApprove != nil {
+		settings.AutoApprove = *saved.AutoApprove
+	}
+	if saved.DefaultPremium != nil {
+		settings.DefaultPremium = *saved.DefaultPremium
+	}
+	if saved.PollInterval != nil {
+		settings.PollInterval = *saved.PollInterval
+	}
+	if saved.RetentionDays != nil {
+		settings.RetentionDays = *saved.RetentionDays
+	}
+	if saved.HouseStyle != nil {
+		settings.HouseStyle = *saved.HouseStyle
+	}
+	if saved.LowCredits != nil {
+		settings.LowCredits = *saved.LowCredits
+	}
+	settings.Presets = saved.Presets
+	return settings
+}
+
+// SettingsUpdated returns when and by whom the settings were last saved (nil if never)
+func (e *Engine) SettingsUpdated() (*time.Time, string) {
+	saved := e.store.Settings()
+	return saved.UpdatedAt, saved.UpdatedBy
+}
+
+// UpdateSettings saves the settings edited by an admin; presets are kept
+// Values equal to the environment default are not stored, so that they follow later changes
+// of the environment.
+func (e *Engine) UpdateSettings(settings Settings, by string) error {
+	if settings.PollInterval < minPollInterval || settings.PollInterval > maxPollInterval {
+		return invalidf("poll interval must be between %s and %s", minPollInterval, maxPollInterval)
+	}
+	if settings.RetentionDays < 0 {
+		return invalidf("retention days cannot be negative")
+	}
+	if settings.LowCredits < 0 {
+		return invalidf("low credit threshold cannot be negative")
+	}
+	settings.HouseStyle = strings.TrimSpace(strings.ReplaceAll(settings.HouseStyle, "\r\n", "\n"))
+	if n := utf8.RuneCountInString(settings.HouseStyle); n > maxHouseStyleLength {
+		return invalidf("house style is too long (%d characters, at most %d)", n, maxHouseStyleLength)
+	}
+
+	defaults := e.DefaultSettings()
+	saved := e.store.Settings()
+	saved.AutoApprove = override(settings.AutoApprove, defaults.AutoApprove)
+	saved.DefaultPremium = override(settings.DefaultPremium, defaults.DefaultPremium)
+	saved.PollInterval = override(settings.PollInterval, defaults.PollInterval)
+	saved.RetentionDays = override(settings.RetentionDays, defaults.RetentionDays)
+	saved.HouseStyle = override(settings.HouseStyle, defaults.HouseStyle)
+	saved.LowCredits = override(settings.LowCredits, defaults.LowCredits)
+	e.saveSettings(saved, by)
+	slog.Info("Settings updated", "by", by, "auto_approve", settings.AutoApprove, "default_premium", settings.DefaultPremium,
+		"poll_interval", settings.PollInterval, "retention_days", settings.RetentionDays, "house_style_chars", len(settings.HouseStyle),
+		"low_credits", settings.LowCredits)
+	return nil
+}
+
+// ResetSettings drops the saved overrides, returning to the environment defaults; presets are kept
+func (e *Engine) ResetSettings(by string) {
+	saved := e.store.Settings()
+	e.saveSettings(storage.Settings{Presets: saved.Presets}, by)
+	slog.Info("Settings reset to the environment", "by", by)
+}
+
+// Preset returns the preset with the given name
+func (e *Engine) Preset(name string) (storage.Preset, bool) {
+	for _, preset := range e.store.Settings().Presets {
+		if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+			return preset, true
+		}
+	}
+	return storage.Preset{}, false
+}
+
+// SavePreset adds a preset, replacing the one with the same name
+func (e *Engine) SavePreset(preset storage.Preset, by string) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	preset.Properties.Style = strings.TrimSpace(preset.Properties.Style)
+	if preset.Name == "" {
+		return invalidf("preset name is required")
+	}
+	if preset.Properties.Style == "" {
+		return invalidf("preset style is required")
+	}
+	if preset.Properties.Weirdness < 0 || preset.Properties.Weirdness > 1 {
+		return invalidf("preset weirdness must be between 0 and 1")
+	}
+
+	saved := e.store.Settings()
+	saved.Presets = slices.DeleteFunc(saved.Presets, func(p storage.Preset) bool { return strings.EqualFold(p.Name, preset.Name) })
+	saved.Presets = append(saved.Presets, preset)
+	slices.SortFunc(saved.Presets, func(a, b storage.Preset) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	e.saveSettings(saved, by)
+	return nil
+}
+
+// DeletePreset removes a preset and reports whether it existed
+func (e *Engine) DeletePreset(name, by string) bool {
+	saved := e.store.Settings()
+	n := len(saved.Presets)
+	saved.Presets = slices.DeleteFunc(saved.Presets, func(p storage.Preset) bool { return strings.EqualFold(p.Name, strings.TrimSpace(name)) })
+	if len(saved.Presets) == n {
+		return false
+	}
+	e.saveSettings(saved, by)
+	return true
+}
+
+func (e *Engine) saveSettings(saved storage.Settings, by string) {
+	now := time.Now()
+	saved.UpdatedAt, saved.UpdatedBy = &now, by
+	e.store.SaveSettings(saved)
+}
+
+// override returns the value to store for a setting: nil when it matches the default
+func override[T comparable](value, defaultValue T) *T {
+	if value == defaultValue {
+		return nil
+	}
+	return &value
+}
+
+// autoApprove approves a workflow as proposed when AutoApprove is set and nothing needs a
+// reviewer's eye: no lyrics issues of any severity and no screening hits. It reports whether
+// the workflow was approved; otherwise it goes to review as usual.
+func (e *Engine) autoApprove(ctx context.Context, state *storage.WorkflowState) bool {
+	if !e.Settings().AutoApprove || len(state.LyricsIssues) > 0 || len(state.ScreeningHits) > 0 {
+		return false
+	}
+	// The preparation run ends here; the Suno submission starts a run of its own
+	if err := e.approve(context.WithoutCancel(ctx), state, AutoApprover, storage.StatusProcessing); err != nil {
+		return false
+	}
+	slog.InfoContext(ctx, "Workflow approved automatically", "workflow_id", state.ID)
+	return true
+}
@@ -0,0 +1,231 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"workflower/storage"
+)
+
+const (
+	similarityShingle  = 3 // words per compared word sequence
+	minSimilarityWords = 4 // shorter lines ("oh baby, oh") are not compared
+	minSimilarityLines = 2 // passages of fewer matching lines are not flagged
+)
+
+// similarityCorpus indexes the word sequences of known lyrics (SIMILARITY_CORPUS_DIR)
+type similarityCorpus struct {
+	sources  []string         // names of the texts: their file names without .txt
+	shingles map[string][]int // word sequence -> indexes of the texts containing it
+}
+
+// similarityPassage is a run of lines resembling one text of the corpus
+type similarityPassage struct {
+	from, to         int // 1-based line numbers
+	source           int
+	matched, shingle int // word sequences found in the source, of all compared
+	lines            int // compared lines
+}
+
+// loadSimilarityCorpus indexes the .txt files of dir, one song per file; nil when dir is empty
+func loadSimilarityCorpus(dir string) (*similarityCorpus, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read similarity corpus: %w", err)
+	}
+
+	corpus := &similarityCorpus{shingles: make(map[string][]int)}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !strings.EqualFold(ext, ".txt") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read similarity corpus: %w", err)
+		}
+
+		// Sequences run across line breaks, so a line split differently still matches
+		index := len(corpus.sources)
+		corpus.sources = append(corpus.sources, strings.TrimSuffix(entry.Name(), ext))
+		var words []string
+		for _, line := range strings.Split(string(data), "\n") {
+			words = append(words, lyricsWords(stripCues(line))...)
+		}
+		seen := make(map[string]bool)
+		for _, shingle := range shingles(words) {
+			if !seen[shingle] {
+				seen[shingle] = true
+				corpus.shingles[shingle] = append(corpus.shingles[shingle], index)
+			}
+		}
+	}
+	return corpus, nil
+}
+
+// passages returns the runs of lyrics lines whose word sequences are found in one text of the
+// corpus for at least the threshold share of each line
+// Blank lines, tags and short lines neither match nor break a passage.
+func (c *similarityCorpus) passages(lyrics string, threshold float64) []similarityPassage {
+	var passages []similarityPassage
+	var current *similarityPassage
+	flush := func() {
+		if current != nil && current.lines >= minSimilarityLines {
+			passages = append(passages, *current)
+		}
+		current = nil
+	}
+
+	for i, line := range strings.Split(lyrics, "\n") {
+		words := lyricsWords(stripCues(line))
+		if len(words) < minSimilarityWords {
+			continue
+		}
+		lineShingles := shingles(words)
+		counts := make(map[int]int)
+		for _, shingle := range lineShingles {
+			for _, source := range c.shingles[shingle] {
+				counts[source]++
+			}
+		}
+		matches := func(source int) bool {
+			return float64(counts[source]) >= threshold*float64(len(lineShingles))
+		}
+
+		// Stay with the source of the passage while the line still matches it
+		best := -1
+		if current != nil && matches(current.source) {
+			best = current.source
+		} else {
+			for source, n := range counts {
+				if best < 0 || n > counts[best] || (n == counts[best] && source < best) {
+					best = source
+				}
+			}
+		}
+		if best < 0 || !matches(best) {
+			flush()
+			continue
+		}
+		if current == nil || current.source != best {
+			flush()
+			current = &similarityPassage{from: i + 1, source: best}
+		}
+		current.to = i + 1
+		current.matched += counts[best]
+		current.shingle += len(lineShingles)
+		current.lines++
+	}
+	flush()
+	return passages
+}
+
+// similarityIssues returns warnings for the passages of lyrics resembling known songs: those
+// found in the corpus, and those the LLM flagged that are still in the lyrics
+func (e *Engine) similarityIssues(state *storage.WorkflowState, lyrics string) []storage.LyricsIssue {
+	var issues []storage.LyricsIssue
+	add := func(from, to int, format string, args ...any) {
+		subject := "This line resembles"
+		if to > from {
+			subject = fmt.Sprintf("The passage through line %d resembles", to)
+		}
+		issues = append(issues, storage.LyricsIssue{
+			Severity: storage.IssueWarning, Line: from, Message: subject + " " + fmt.Sprintf(format, args...),
+		})
+	}
+
+	if e.corpus != nil {
+		for _, p := range e.corpus.passages(lyrics, e.cfg.SimilarityThreshold) {
+			add(p.from, p.to, "%q of the similarity corpus (%d%% of their phrases match)",
+				e.corpus.sources[p.source], p.matched*100/p.shingle)
+		}
+	}
+	for _, flag := range state.SimilarityFlags {
+		if from, to, ok := locatePassage(lyrics, flag.Passage); ok {
+			add(from, to, "%q (LLM confidence %d%%)", flag.Source, int(flag.Confidence*100))
+		}
+	}
+	return issues
+}
+
+// checkSimilarity asks the LLM for passages of the lyrics under review copied from published
+// songs, keeping those flagged with at least SimilarityThreshold confidence
+func (e *Engine) checkSimilarity(ctx context.Context, state *storage.WorkflowState) ([]storage.SimilarityFlag, error) {
+	response, err := e.chat(ctx, state, e.promptsList.LyricsSimilarity, "Lyrics to check:\n"+state.LyricsWithBrackets)
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON in the similarity check response")
+	}
+	var result struct {
+		Passages []storage.SimilarityFlag `json:"passages"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse the similarity check: %w", err)
+	}
+
+	var flags []storage.SimilarityFlag
+	for _, flag := range result.Passages {
+		if strings.TrimSpace(flag.Passage) != "" && flag.Confidence >= e.cfg.SimilarityThreshold {
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+// locatePassage returns the lines of lyrics holding passage: from its first line as far as its
+// lines follow in order; ok is false once the reviewer rewrote the first line
+func locatePassage(lyrics, passage string) (from, to int, ok bool) {
+	var want []string
+	for _, line := range strings.Split(passage, "\n") {
+		if words := lyricsWords(stripCues(line)); len(words) > 0 {
+			want = append(want, strings.Join(words, " "))
+		}
+	}
+	if len(want) == 0 {
+		return 0, 0, false
+	}
+
+	matched := 0
+	for i, line := range strings.Split(lyrics, "\n") {
+		words := lyricsWords(stripCues(line))
+		if len(words) == 0 {
+			continue
+		}
+		switch text := strings.Join(words, " "); {
+		case matched > 0 && matched < len(want) && text == want[matched]:
+			matched++
+			to = i + 1
+		case matched > 0:
+			return from, to, true
+		case text == want[0]:
+			matched, from, to = 1, i+1, i+1
+		}
+	}
+	return from, to, matched > 0
+}
+
+// lyricsWords returns the words of a line in lowercase, without punctuation or apostrophes
+func lyricsWords(line string) []string {
+	line = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(line))
+	return strings.FieldsFunc(line, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+}
+
+// shingles returns the overlapping sequences of similarityShingle words
+func shingles(words []string) []string {
+	var out []string
+	for i := 0; i+similarityShingle <= len(words); i++ {
+		out = append(out, strings.Join(words[i:i+similarityShingle], " "))
+	}
+	return out
+}
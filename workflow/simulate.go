@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"workflower/storage"
+)
+
+// ErrSimulationNeedsOpenAI is returned by Simulate without an OpenAI key: the prompts replayed are OpenAI prompts
+var ErrSimulationNeedsOpenAI = newError(CodeInvalid, "replaying workflows requires OPENAI_API_KEY")
+
+// Simulation is what the LLM steps produce for a stored workflow with the engine's prompts
+type Simulation struct {
+	Lyrics             string                  `json:"lyrics"`
+	LyricsWithBrackets string                  `json:"lyrics_with_brackets"`
+	Properties         *storage.SunoProperties `json:"properties"`
+	PersonaInspo       *storage.PersonaInspo   `json:"persona_inspo,omitempty"`
+	Usage              storage.Usage           `json:"usage"`
+}
+
+// Simulate replays the LLM steps of a stored workflow (lyrics, properties, brackets and, for
+// premium workflows, Persona/Inspo) with the engine's prompts, always drafting lyrics with OpenAI
+// It is a dry run: the workflow is not changed, nothing is saved or published and Suno is not
+// called. The task description of a workflow started from a transcript is its stored summary.
+func (e *Engine) Simulate(ctx context.Context, original *storage.WorkflowState) (*Simulation, error) {
+	if !e.cfg.HasOpenAI() {
+		return nil, ErrSimulationNeedsOpenAI
+	}
+	state := &storage.WorkflowState{
+		ID:              original.ID,
+		TaskDescription: original.TaskDescription,
+		SourceLyrics:    original.SourceLyrics,
+		IsPremium:       original.IsPremium,
+		Language:        original.Language,
+		LyricsEngine:    LyricsEngineOpenAI,
+	}
+
+	var err error
+	if state.Lyrics, err = e.generateLyrics(ctx, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", StepLyrics, err)
+	}
+	if state.SunoProperties, err = e.determineSunoProperties(ctx, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", StepProperties, err)
+	}
+	if state.LyricsWithBrackets, err = e.addBracketInstructions(ctx, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", StepBrackets, err)
+	}
+	if state.IsPremium {
+		if state.PersonaInspo, err = e.generatePersonaInspo(ctx, state); err != nil {
+			return nil, fmt.Errorf("%s: %w", StepPersonaInspo, err)
+		}
+	}
+	return &Simulation{
+		Lyrics:             state.Lyrics,
+		LyricsWithBrackets: state.LyricsWithBrackets,
+		Properties:         state.SunoProperties,
+		PersonaInspo:       state.PersonaInspo,
+		Usage:              state.Usage,
+	}, nil
+}
+
+// ApprovedContent returns the lyrics and property set a workflow was approved with, the ones
+// sent to Suno; ok is false for workflows never approved or whose lyrics were not generated
+func ApprovedContent(state *storage.WorkflowState) (lyrics string, props *storage.SunoProperties, ok bool) {
+	switch state.Status {
+	case storage.StatusApproved, storage.StatusGenerating, storage.StatusCompleted, storage.StatusBlockedAuth:
+	default:
+		return "", nil, false
+	}
+	lyrics, props = submittedLyrics(state), submittedProperties(state)
+	if state.LyricsImported || lyrics == "" || props == nil {
+		return "", nil, false
+	}
+	return lyrics, props, true
+}
@@ -0,0 +1,229 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"workflower/storage"
+)
+
+// maxStructureFeedback caps the mismatches quoted back to the LLM and in step errors
+const maxStructureFeedback = 12
+
+// ErrStructureNeedsOpenAI is returned when source lyrics are given without an OpenAI key to match them
+var ErrStructureNeedsOpenAI = newError(CodeInvalid, "matching the structure of source lyrics requires OPENAI_API_KEY")
+
+// structureLine is a sung line of lyrics: its line number in the text and its syllable count
+type structureLine struct {
+	Line      int
+	Syllables int
+}
+
+// lyricsStructure is the shape a melody imposes on lyrics: the sung lines of each section
+// Sections are separated by blank lines or section tags; [tags] and (backing vocals) are not counted.
+type lyricsStructure [][]structureLine
+
+// CheckSourceLyrics validates the lyrics of the track a workflow extends or covers before it is
+// started; importing reports whether the workflow imports its lyrics instead of generating them
+func (e *Engine) CheckSourceLyrics(sourceLyrics string, importing bool) error {
+	if strings.TrimSpace(sourceLyrics) == "" {
+		return nil
+	}
+	if importing {
+		return invalidf("source lyrics are matched by generated lyrics and cannot be combined with imported lyrics")
+	}
+	if !e.cfg.HasOpenAI() {
+		return ErrStructureNeedsOpenAI
+	}
+	if len(parseStructure(sourceLyrics, "")) == 0 {
+		return invalidf("source lyrics have no sung lines")
+	}
+	return nil
+}
+
+// generateStructuredLyrics drafts lyrics for the task description that match the line and
+// syllable structure of the workflow's source lyrics
+// Drafts are checked here rather than trusted: a draft off by more than the configured tolerance
+// is sent back with its mismatches, and the step fails once StructureAttempts drafts were off.
+func (e *Engine) generateStructuredLyrics(ctx context.Context, state *storage.WorkflowState) (string, error) {
+	source := parseStructure(state.SourceLyrics, state.Language)
+	request := fmt.Sprintf("Song description: %s%s\n\nStructure to match:\n%s\nOriginal lyrics:\n%s",
+		state.TaskDescription, languageInstruction(state.Language), source.describe(), strings.TrimSpace(state.SourceLyrics))
+
+	attempts := max(e.cfg.StructureAttempts, 1)
+	prompt := request
+	for attempt := 1; ; attempt++ {
+		lyrics, err := e.chat(ctx, state, e.promptsList.LyricsStructure, prompt)
+		if err != nil {
+			return "", err
+		}
+		mismatches := source.compare(parseStructure(lyrics, state.Language), e.cfg.StructureTolerance)
+		if len(mismatches) == 0 {
+			return lyrics, nil
+		}
+		if attempt == attempts {
+			return "", fmt.Errorf("lyrics do not match the source structure after %d drafts: %s",
+				attempts, strings.Join(issueMessages(mismatches), "; "))
+		}
+		prompt = fmt.Sprintf("%s\n\nYour previous draft:\n%s\n\nProblems to fix:\n- %s",
+			request, strings.TrimSpace(lyrics), strings.Join(issueMessages(mismatches), "\n- "))
+	}
+}
+
+// structureIssues returns advisory issues for lyrics that drift from the structure of the
+// workflow's source lyrics (e.g. after review edits); none without source lyrics
+func (e *Engine) structureIssues(state *storage.WorkflowState, lyrics string) []storage.LyricsIssue {
+	if state.SourceLyrics == "" {
+		return nil
+	}
+	source := parseStructure(state.SourceLyrics, state.Language)
+	return source.compare(parseStructure(lyrics, state.Language), e.cfg.StructureTolerance)
+}
+
+// parseStructure splits lyrics into sections of sung lines
+func parseStructure(lyrics, language string) lyricsStructure {
+	var structure lyricsStructure
+	var section []structureLine
+	closeSection := func() {
+		if len(section) > 0 {
+			structure = append(structure, section)
+			section = nil
+		}
+	}
+
+	for i, line := range strings.Split(lyrics, "\n") {
+		if strings.TrimSpace(line) == "" {
+			closeSection()
+			continue
+		}
+		sung := stripCues(line)
+		if strings.TrimSpace(sung) == "" {
+			// A line of tags only: a new section when it names one, a cue otherwise
+			if tags, _ := bracketTags(line); len(tags) > 0 && isSectionMarker(tags[0]) {
+				closeSection()
+			}
+			continue
+		}
+		section = append(section, structureLine{Line: i + 1, Syllables: countSyllables(sung, language)})
+	}
+	closeSection()
+	return structure
+}
+
+// describe lists the sections and the syllable counts of their lines for the LLM
+func (s lyricsStructure) describe() string {
+	var b strings.Builder
+	for i, section := range s {
+		counts := make([]string, len(section))
+		for j, line := range section {
+			counts[j] = fmt.Sprint(line.Syllables)
+		}
+		fmt.Fprintf(&b, "Section %d: %d lines, syllables per line: %s\n", i+1, len(section), strings.Join(counts, ", "))
+	}
+	return b.String()
+}
+
+// compare returns the places where lyrics do not match the structure s, at most maxStructureFeedback
+// Lines may differ by tolerance syllables; sections and line counts must match exactly.
+func (s lyricsStructure) compare(lyrics lyricsStructure, tolerance int) []storage.LyricsIssue {
+	var issues []storage.LyricsIssue
+	add := func(line int, format string, args ...any) {
+		if len(issues) < maxStructureFeedback {
+			issues = append(issues, storage.LyricsIssue{Severity: storage.IssueWarning, Line: line, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	if len(lyrics) != len(s) {
+		add(0, "%d sections instead of the %d of the source lyrics", len(lyrics), len(s))
+	}
+	for i := range min(len(s), len(lyrics)) {
+		want, got := s[i], lyrics[i]
+		if len(got) != len(want) {
+			add(got[0].Line, "Section %d has %d lines instead of %d", i+1, len(got), len(want))
+		}
+		for j := range min(len(want), len(got)) {
+			if diff := got[j].Syllables - want[j].Syllables; diff > tolerance || -diff > tolerance {
+				add(got[j].Line, "Line %d of section %d has %d syllables instead of %d", j+1, i+1, got[j].Syllables, want[j].Syllables)
+			}
+		}
+	}
+	return issues
+}
+
+// issueMessages returns the messages of issues
+func issueMessages(issues []storage.LyricsIssue) []string {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.Message
+	}
+	return messages
+}
+
+// stripCues removes [tags] and (backing vocals) from a line, leaving the sung words
+func stripCues(line string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range line {
+		switch r {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth = max(depth-1, 0)
+		default:
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// countSyllables estimates the sung syllables of a line
+// Alphabetic scripts count vowel groups (with English silent e's dropped); CJK, kana and Devanagari
+// characters count one syllable each. The estimate is close enough to compare two lines of one language.
+func countSyllables(line, language string) int {
+	english := language == "" || language == defaultLanguage
+	count := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		count += wordSyllables(word, english)
+	}
+	return count
+}
+
+// wordSyllables estimates the syllables of one lowercase word
+func wordSyllables(word string, english bool) int {
+	runes := []rune(word)
+	count, inVowel := 0, false
+	for _, r := range runes {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Devanagari):
+			if unicode.IsLetter(r) {
+				count++
+			}
+			inVowel = false
+		case isVowel(r):
+			if !inVowel {
+				count++
+			}
+			inVowel = true
+		default:
+			inVowel = false
+		}
+	}
+
+	// English final e is silent ("love", "time") except after a consonant + l ("little")
+	if english && count > 1 && len(runes) > 2 && runes[len(runes)-1] == 'e' &&
+		!isVowel(runes[len(runes)-2]) && (runes[len(runes)-2] != 'l' || isVowel(runes[len(runes)-3])) {
+		count--
+	}
+	return max(count, 1)
+}
+
+// isVowel reports whether r is a vowel letter of a Latin, Greek or Cyrillic alphabet
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyàáâãäåæèéêëìíîïòóôõöøùúûüýÿœąęıİαεηιουωаеёиоуыэюяіїє", r)
+}
@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"workflower/config"
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// newSunoPool returns a pool of the Suno accounts of SUNO_ACCOUNTS, each with its own client,
+// retries and circuit breaker; account quotas are recorded in the store and restored from it
+func newSunoPool(cfg *config.Config, store *storage.Store, callbackURL string, onCircuitChange func(open bool, err error)) *suno.Pool {
+	accounts := make([]suno.PoolAccount, 0, len(cfg.SunoAccounts))
+	for _, account := range cfg.SunoAccounts {
+		token := account.Token
+		if token == "" {
+			token = cfg.SunoAPIToken
+		}
+		name := account.Name
+		circuitChanged := func(open bool, err error) {
+			if err != nil {
+				err = fmt.Errorf("Suno account %s: %w", name, err)
+			}
+			onCircuitChange(open, err)
+		}
+		accounts = append(accounts, suno.PoolAccount{Name: name, API: newSunoAPI(cfg, account.BaseURL, token, callbackURL, circuitChanged)})
+	}
+
+	pool := suno.NewPool(accounts, cfg.SunoAccountSelection, cfg.SunoCreditsPerGeneration)
+	var saved []suno.AccountStatus
+	for _, account := range store.SunoAccounts() {
+		saved = append(saved, accountStatus(account))
+	}
+	pool.Restore(saved)
+	pool.OnChange = func(status suno.AccountStatus) {
+		store.SaveSunoAccount(storedAccount(status))
+	}
+	return pool
+}
+
+// SunoAccounts returns the quota of every account of the Suno account pool, in configuration
+// order; nil without SUNO_ACCOUNTS
+func (e *Engine) SunoAccounts() []storage.SunoAccount {
+	if e.sunoPool == nil {
+		return nil
+	}
+	var accounts []storage.SunoAccount
+	for _, status := range e.sunoPool.Accounts() {
+		accounts = append(accounts, storedAccount(status))
+	}
+	return accounts
+}
+
+func accountStatus(a storage.SunoAccount) suno.AccountStatus {
+	status := suno.AccountStatus{
+		Name:         a.Name,
+		CreditsLeft:  a.CreditsLeft,
+		MonthlyLimit: a.MonthlyLimit,
+		MonthlyUsage: a.MonthlyUsage,
+		Exhausted:    a.Exhausted,
+		Submissions:  a.Submissions,
+		LastError:    a.LastError,
+	}
+	if a.CheckedAt != nil {
+		status.CheckedAt = *a.CheckedAt
+	}
+	return status
+}
+
+func storedAccount(status suno.AccountStatus) storage.SunoAccount {
+	a := storage.SunoAccount{
+		Name:         status.Name,
+		CreditsLeft:  status.CreditsLeft,
+		MonthlyLimit: status.MonthlyLimit,
+		MonthlyUsage: status.MonthlyUsage,
+		Exhausted:    status.Exhausted,
+		Submissions:  status.Submissions,
+		LastError:    status.LastError,
+	}
+	if !status.CheckedAt.IsZero() {
+		checkedAt := status.CheckedAt.UTC().Truncate(time.Second)
+		a.CheckedAt = &checkedAt
+	}
+	return a
+}
@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"net/url"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// SunoCallbackPath receives finished clips from suno-api deployments that support callbacks
+const SunoCallbackPath = "/suno/callback"
+
+// SunoCallbackURL returns the callback URL sent with Suno requests, carrying the shared secret
+func (e *Engine) SunoCallbackURL() string {
+	return e.cfg.BaseURL + SunoCallbackPath + "?token=" + url.QueryEscape(e.cfg.SunoCallbackSecret)
+}
+
+// pollSchedule returns the interval and attempt budget of waitForClip: the poll interval
+// setting within an overall time budget of sunoPollRetries polls every sunoPollInterval. With
+// callbacks enabled polling is only a fallback, so it runs at the slower configured interval.
+func (e *Engine) pollSchedule() (time.Duration, int) {
+	interval := e.Settings().PollInterval
+	if interval <= 0 {
+		interval = sunoPollInterval
+	}
+	if e.cfg.SunoCallbackSecret != "" {
+		interval = max(interval, e.cfg.SunoCallbackPollInterval)
+	}
+	budget := sunoPollInterval * sunoPollRetries
+	return interval, int((budget + interval - 1) / interval)
+}
+
+// clipNotify returns the channel a clip's callback is delivered on, creating it when needed
+// A callback arriving before waitForClip starts waiting is kept in the channel's buffer.
+func (e *Engine) clipNotify(clipID string) chan *suno.AudioInfo {
+	e.clipMu.Lock()
+	defer e.clipMu.Unlock()
+	ch, ok := e.clipWaiters[clipID]
+	if !ok {
+		ch = make(chan *suno.AudioInfo, 1)
+		e.clipWaiters[clipID] = ch
+	}
+	return ch
+}
+
+// forgetClip drops the callback channel of a clip that is no longer waited on
+func (e *Engine) forgetClip(clipID string) {
+	e.clipMu.Lock()
+	delete(e.clipWaiters, clipID)
+	e.clipMu.Unlock()
+}
+
+// DeliverClip hands a clip reported by a Suno callback to the workflow waiting for it, which
+// then advances without waiting for its next poll. It returns the workflow, or false when no
+// generating workflow knows the clip.
+func (e *Engine) DeliverClip(clip *suno.AudioInfo) (*storage.WorkflowState, bool) {
+	state, ok := e.workflowForClip(clip.ID)
+	if !ok {
+		return nil, false
+	}
+	select {
+	case e.clipNotify(clip.ID) <- clip:
+	default: // an earlier callback of the clip is still pending
+	}
+	return state, true
+}
+
+// workflowForClip finds the generating workflow a clip belongs to: its song, a long song
+// segment or concatenation, a variant clip or its stems
+func (e *Engine) workflowForClip(clipID string) (*storage.WorkflowState, bool) {
+	if clipID == "" {
+		return nil, false
+	}
+	for _, state := range e.store.ListByStatus(storage.StatusGenerating) {
+		if state.SunoJobID == clipID || state.StemsClipID == clipID || state.ConcatClipID == clipID ||
+			(state.Poll != nil && state.Poll.ClipID == clipID) {
+			return state, true
+		}
+		for _, seg := range state.Segments {
+			if seg.ClipID == clipID {
+				return state, true
+			}
+		}
+		for _, variant := range state.Variants {
+			for _, c := range variant.Clips {
+				if c.ID == clipID {
+					return state, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
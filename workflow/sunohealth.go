@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// sunoHealthTimeout bounds a single session check
+const sunoHealthTimeout = 30 * time.Second
+
+// SunoHealth is the result of the last Suno session check
+type SunoHealth struct {
+	Healthy     bool      `json:"healthy"`
+	AuthExpired bool      `json:"auth_expired"` // the suno-api cookie/session was rejected
+	CheckedAt   time.Time `json:"checked_at"`
+	CreditsLeft int       `json:"credits_left"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// SunoHealth returns the result of the last session check; before the first check the
+// session is assumed valid
+func (e *Engine) SunoHealth() SunoHealth {
+	e.healthMu.Lock()
+	defer e.healthMu.Unlock()
+	return e.sunoHealth
+}
+
+// RunSunoHealthMonitor validates the Suno session every SUNO_HEALTH_INTERVAL until ctx is
+// cancelled: an expired session is reported to Telegram at once and parks new submissions
+// in blocked_auth, which are submitted again as soon as the session is valid
+func (e *Engine) RunSunoHealthMonitor(ctx context.Context) {
+	if e.cfg.SunoHealthInterval <= 0 {
+		slog.Info("Suno health monitor disabled")
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.SunoHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		e.checkSunoHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSunoHealth validates the session through the quota endpoint
+// Other failures (suno-api unreachable, server errors) are logged but do not park submissions.
+func (e *Engine) checkSunoHealth(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, sunoHealthTimeout)
+	quota, err := e.sunoAPI.GetQuota(checkCtx)
+	cancel()
+
+	switch {
+	case err == nil:
+		e.setSunoHealth(ctx, SunoHealth{Healthy: true, CheckedAt: time.Now(), CreditsLeft: quota.CreditsLeft})
+	case isSunoAuthError(err):
+		e.setSunoHealth(ctx, SunoHealth{AuthExpired: true, CheckedAt: time.Now(), Error: err.Error()})
+	default:
+		slog.Warn("Suno health check failed", "error", err)
+		health := e.SunoHealth()
+		health.CheckedAt = time.Now()
+		health.Error = err.Error()
+		e.setSunoHealth(ctx, health)
+	}
+}
+
+// setSunoHealth records a check result and acts on changes of the session state
+func (e *Engine) setSunoHealth(ctx context.Context, health SunoHealth) {
+	e.healthMu.Lock()
+	wasExpired := e.sunoHealth.AuthExpired
+	e.sunoHealth = health
+	e.healthMu.Unlock()
+
+	switch {
+	case health.AuthExpired && !wasExpired:
+		slog.Error("Suno session expired, parking new submissions", "error", health.Error)
+		e.alert(ctx, "suno_session_expired", map[string]any{"Error": truncateString(health.Error, 300)})
+	case !health.AuthExpired && wasExpired:
+		slog.Info("Suno session valid again")
+		e.alert(ctx, "suno_session_valid", nil)
+	}
+	if health.Healthy {
+		e.releaseBlockedAuth(ctx)
+	}
+}
+
+// parkForSunoAuth moves an approved workflow to blocked_auth while the Suno session is expired
+// and reports whether it did; the workflow is submitted again when the session recovers
+func (e *Engine) parkForSunoAuth(state *storage.WorkflowState) bool {
+	if !e.SunoHealth().AuthExpired {
+		return false
+	}
+	slog.WarnContext(logContext(state), "Suno session expired, parking submission", "workflow_id", state.ID)
+	e.setStatus(state, storage.StatusBlockedAuth)
+	e.untrack(state.ID)
+	return true
+}
+
+// releaseBlockedAuth submits the workflows parked while the session was expired
+func (e *Engine) releaseBlockedAuth(ctx context.Context) {
+	for _, state := range e.store.ListByStatus(storage.StatusBlockedAuth) {
+		slog.InfoContext(logContext(state), "Submitting parked workflow", "workflow_id", state.ID)
+		e.setStatus(state, storage.StatusApproved)
+		go e.resumeSuno(e.track(ctx, state), state)
+	}
+}
+
+// sunoCircuitChanged reports suno-api going down (the circuit breaker opened) and coming back
+func (e *Engine) sunoCircuitChanged(open bool, err error) {
+	ctx := context.Background()
+	if open {
+		slog.Error("suno-api unavailable, failing Suno requests fast", "error", err)
+		e.alert(ctx, "suno_unavailable", map[string]any{"Error": truncateString(err.Error(), 300)})
+		return
+	}
+	slog.Info("suno-api reachable again")
+	e.alert(ctx, "suno_available", nil)
+}
+
+// alert sends an operational message to the admin channel (ESCALATION_CHAT_ID), or the
+// default chat without one (see defaultChatID); name and data select the message (see Message)
+func (e *Engine) alert(ctx context.Context, name string, data any) {
+	if e.cfg.TelegramBotToken == "" {
+		return
+	}
+	chatID := e.cfg.EscalationChatID
+	if chatID == "" {
+		chatID = e.defaultChatID()
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+	defer cancel()
+	if err := e.notifier.SendToChat(sendCtx, chatID, e.Message(chatID, name, data)); err != nil {
+		slog.Error("Failed to send alert", "error", err)
+	}
+}
+
+// isSunoAuthError reports whether a Suno API error means the session is no longer valid
+func isSunoAuthError(err error) bool {
+	var apiErr *suno.APIError
+	return errors.As(err, &apiErr) && apiErr.IsAuth()
+}
@@ -0,0 +1,42 @@
+package workflow
+
+import (
+	"slices"
+	"strings"
+
+	"workflower/storage"
+)
+
+// SunoModels returns the Suno models a workflow may be generated with (SUNO_MODELS)
+func (e *Engine) SunoModels() []string {
+	return e.cfg.SunoModels
+}
+
+// checkSunoModel accepts "" (SUNO_MODEL) and the models of SUNO_MODELS
+func (e *Engine) checkSunoModel(model string) error {
+	if model == "" || slices.Contains(e.cfg.SunoModels, model) {
+		return nil
+	}
+	return invalidf("unknown Suno model %q (use one of %s)", model, strings.Join(e.cfg.SunoModels, ", "))
+}
+
+// checkSunoModels checks the models of the property sets a workflow is about to be submitted with
+func (e *Engine) checkSunoModels(state *storage.WorkflowState) error {
+	for _, props := range []*storage.SunoProperties{submittedProperties(state), state.VariantB} {
+		if props == nil {
+			continue
+		}
+		if err := e.checkSunoModel(props.Model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sunoModel returns the model a property set is generated with
+func (e *Engine) sunoModel(props *storage.SunoProperties) string {
+	if props != nil && props.Model != "" {
+		return props.Model
+	}
+	return e.cfg.SunoModel
+}
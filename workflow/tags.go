@@ -0,0 +1,276 @@
+package workflow
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"workflower/storage"
+)
+
+// maxTagLength drops free-text prompts that ended up in the tags field of a clip
+const maxTagLength = 40
+
+// bundledStyleTags is the built-in style tag dataset by category, merged into every sync
+var bundledStyleTags = map[string][]string{
+	"genre": {
+		"pop", "indie pop", "synthpop", "dream pop", "k-pop", "j-pop", "dance pop", "electropop",
+		"rock", "indie rock", "alternative rock", "hard rock", "punk", "pop punk", "post-punk",
+		"grunge", "shoegaze", "emo", "metal", "heavy metal", "metalcore", "progressive rock",
+		"psychedelic rock", "surf rock", "garage rock", "hip hop", "rap", "trap", "boom bap",
+		"drill", "lo-fi hip hop", "r&b", "neo soul", "soul", "funk", "disco", "motown", "gospel",
+		"blues", "jazz", "smooth jazz", "bebop", "swing", "big band", "bossa nova", "latin",
+		"reggaeton", "salsa", "cumbia", "bachata", "flamenco", "reggae", "dancehall", "afrobeats",
+		"country", "bluegrass", "americana", "folk", "indie folk", "singer-songwriter", "celtic",
+		"edm", "house", "deep house", "tech house", "techno", "trance", "drum and bass", "dubstep",
+		"garage", "uk garage", "electro", "synthwave", "vaporwave", "chillwave", "ambient",
+		"downtempo", "trip hop", "chillout", "lo-fi", "classical", "neoclassical", "orchestral",
+		"cinematic", "film score", "opera", "musical theatre", "children's music", "chiptune",
+	},
+	"mood": {
+		"happy", "uplifting", "euphoric", "energetic", "upbeat", "playful", "romantic", "sensual",
+		"melancholic", "sad", "nostalgic", "bittersweet", "dreamy", "ethereal", "atmospheric",
+		"dark", "moody", "haunting", "eerie", "aggressive", "angry", "epic", "triumphant",
+		"anthemic", "hopeful", "peaceful", "calm", "relaxing", "introspective", "emotional",
+		"groovy", "quirky", "whimsical", "mysterious", "tense",
+	},
+	"instrument": {
+		"acoustic guitar", "electric guitar", "distorted guitar", "slide guitar", "bass",
+		"slap bass", "808", "piano", "rhodes", "organ", "synth", "analog synth", "synth pads",
+		"arpeggiator", "strings", "violin", "cello", "brass", "horns", "saxophone", "trumpet",
+		"flute", "harmonica", "banjo", "mandolin", "ukulele", "accordion", "harp", "choir",
+		"drums", "live drums", "drum machine", "breakbeat", "percussion", "handclaps", "bells",
+		"vocoder", "sitar", "steel drums",
+	},
+	"vocals": {
+		"male vocals", "female vocals", "duet", "male and female vocals", "choir vocals",
+		"harmonies", "falsetto", "whispered vocals", "breathy vocals", "raspy vocals",
+		"powerful vocals", "soulful vocals", "operatic vocals", "spoken word", "rapped vocals",
+		"auto-tune", "harmonized vocals", "instrumental", "a cappella",
+	},
+	"era": {
+		"50s", "60s", "70s", "80s", "90s", "2000s", "2010s", "retro", "vintage", "modern",
+		"futuristic",
+	},
+	"production": {
+		"slow", "mid-tempo", "fast", "ballad", "driving", "half-time", "four on the floor",
+		"lo-fi production", "polished", "raw", "live recording", "reverb", "heavy bass",
+		"minimal", "layered", "catchy hook", "build-up", "drop", "fade out",
+	},
+}
+
+// RunTagSync refreshes the style tag taxonomy at startup and then every TAG_SYNC_INTERVAL
+// until ctx is cancelled
+func (e *Engine) RunTagSync(ctx context.Context) {
+	if e.cfg.TagSyncInterval <= 0 {
+		slog.Info("Tag sync disabled")
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.TagSyncInterval)
+	defer ticker.Stop()
+	for {
+		e.SyncTags(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncTags rebuilds the style tag taxonomy from the bundled dataset, the tags of the clips in the
+// Suno library (the first TAG_SYNC_PAGES pages of the feed) and the properties of the workflows,
+// and saves it
+// When the library cannot be read, the clip counts of the previous sync are kept.
+func (e *Engine) SyncTags(ctx context.Context) storage.TagTaxonomy {
+	e.tagSyncMu.Lock()
+	defer e.tagSyncMu.Unlock()
+
+	previous := e.store.TagTaxonomy()
+	tags := make(map[string]*storage.StyleTag)
+	tag := func(name string) *storage.StyleTag {
+		t, ok := tags[name]
+		if !ok {
+			t = &storage.StyleTag{Name: name}
+			tags[name] = t
+		}
+		return t
+	}
+
+	for category, names := range bundledStyleTags {
+		for _, name := range names {
+			t := tag(name)
+			t.Bundled, t.Category = true, category
+		}
+	}
+
+	clips, err := e.countLibraryTags(ctx, tag)
+	if err != nil {
+		slog.Warn("Failed to read the Suno library, keeping the previous clip counts", "error", err)
+		clips = previous.LibraryClips
+		for _, t := range previous.Tags {
+			if t.Clips > 0 {
+				tag(t.Name).Clips = t.Clips
+			}
+		}
+	}
+
+	for state := range e.store.All() {
+		kept := state.Status == storage.StatusCompleted
+		dropped := state.Status == storage.StatusRejected || state.Status == storage.StatusCancelled
+		for _, name := range workflowTags(state) {
+			t := tag(name)
+			t.Used++
+			if kept {
+				t.Kept++
+			} else if dropped {
+				t.Dropped++
+			}
+		}
+	}
+
+	now := time.Now()
+	taxonomy := storage.TagTaxonomy{Tags: make([]storage.StyleTag, 0, len(tags)), LibraryClips: clips, SyncedAt: &now}
+	for _, t := range tags {
+		t.KeepRate = ratio(t.Kept, t.Kept+t.Dropped)
+		taxonomy.Tags = append(taxonomy.Tags, *t)
+	}
+	slices.SortFunc(taxonomy.Tags, func(a, b storage.StyleTag) int { return strings.Compare(a.Name, b.Name) })
+	e.store.SaveTagTaxonomy(taxonomy)
+	slog.Info("Style tags synced", "tags", len(taxonomy.Tags), "library_clips", clips)
+	return taxonomy
+}
+
+// countLibraryTags counts the clips of the Suno library per tag and returns the number of clips read
+func (e *Engine) countLibraryTags(ctx context.Context, tag func(string) *storage.StyleTag) (int, error) {
+	counts := make(map[string]int)
+	clips := 0
+	for page := 1; page <= e.cfg.TagSyncPages; page++ {
+		feed, err := e.sunoAPI.Get(ctx, "", page)
+		if err != nil {
+			return 0, err
+		}
+		if len(feed) == 0 {
+			break
+		}
+		for _, clip := range feed {
+			clips++
+			for _, name := range splitTags(clip.Tags) {
+				counts[name]++
+			}
+		}
+	}
+	for name, n := range counts {
+		tag(name).Clips = n
+	}
+	return clips, nil
+}
+
+// workflowTags returns the distinct style and vocal type tags a workflow was (or would be)
+// submitted with, variant B included
+func workflowTags(state *storage.WorkflowState) []string {
+	var names []string
+	for _, props := range []*storage.SunoProperties{submittedProperties(state), state.VariantB} {
+		if props != nil {
+			names = append(names, splitTags(props.Style+","+props.VocalType)...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// splitTags splits a comma-separated tag list into normalized tags: lowercase, single-spaced,
+// without empty or overlong entries
+func splitTags(list string) []string {
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		name := normalizeStyleTag(part)
+		if name != "" && len(name) <= maxTagLength {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func normalizeStyleTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// StyleTags returns the synced taxonomy, or the bundled dataset before the first sync
+func (e *Engine) StyleTags() storage.TagTaxonomy {
+	taxonomy := e.store.TagTaxonomy()
+	if taxonomy.SyncedAt != nil {
+		return taxonomy
+	}
+	for category, names := range bundledStyleTags {
+		for _, name := range names {
+			taxonomy.Tags = append(taxonomy.Tags, storage.StyleTag{Name: name, Category: category, Bundled: true})
+		}
+	}
+	slices.SortFunc(taxonomy.Tags, func(a, b storage.StyleTag) int { return strings.Compare(a.Name, b.Name) })
+	return taxonomy
+}
+
+// SuggestTags returns up to limit tags starting with prefix (or containing it, after those),
+// the ones most often kept and used first
+func (e *Engine) SuggestTags(prefix string, limit int) []storage.StyleTag {
+	prefix = normalizeStyleTag(prefix)
+	var starts, contains []storage.StyleTag
+	for _, t := range e.StyleTags().Tags {
+		switch {
+		case strings.HasPrefix(t.Name, prefix):
+			starts = append(starts, t)
+		case strings.Contains(t.Name, prefix):
+			contains = append(contains, t)
+		}
+	}
+	byRank := func(a, b storage.StyleTag) int {
+		return cmp.Or(
+			cmp.Compare(b.Kept, a.Kept),
+			cmp.Compare(b.Used+b.Clips, a.Used+a.Clips),
+			strings.Compare(a.Name, b.Name),
+		)
+	}
+	slices.SortFunc(starts, byRank)
+	slices.SortFunc(contains, byRank)
+	suggestions := append(starts, contains...)
+	return suggestions[:min(len(suggestions), limit)]
+}
+
+// UnknownTags returns the tags of a comma-separated list that are neither bundled nor seen in
+// the Suno library or on a kept workflow
+func (e *Engine) UnknownTags(list string) []string {
+	known := make(map[string]bool)
+	for _, t := range e.StyleTags().Tags {
+		known[t.Name] = t.Bundled || t.Clips > 0 || t.Kept > 0
+	}
+	var unknown []string
+	for _, name := range splitTags(list) {
+		if !known[name] && !slices.Contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// TagReport returns the tags used by at least minUses workflows, best keep rate first
+func (e *Engine) TagReport(minUses int) []storage.StyleTag {
+	report := []storage.StyleTag{}
+	for _, t := range e.StyleTags().Tags {
+		if t.Used >= max(minUses, 1) {
+			report = append(report, t)
+		}
+	}
+	slices.SortFunc(report, func(a, b storage.StyleTag) int {
+		return cmp.Or(
+			cmp.Compare(b.KeepRate, a.KeepRate),
+			cmp.Compare(b.Kept+b.Dropped, a.Kept+a.Dropped),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+	return report
+}
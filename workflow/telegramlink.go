@@ -0,0 +1,110 @@
+package workflow
+
+import (
+	"crypto/rand"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// TelegramLinkCodeTTL is how long a /link code stays valid
+const TelegramLinkCodeTTL = 10 * time.Minute
+
+// linkCodeAlphabet leaves out characters that are easily confused (0/O, 1/I/L)
+const linkCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// ErrInvalidLinkCode reports an unknown, used or expired /link code
+var ErrInvalidLinkCode = newError(CodeInvalid, "invalid or expired link code")
+
+// linkCode is a pending /link code of a web account
+type linkCode struct {
+	user    string
+	expires time.Time
+}
+
+// NewTelegramLinkCode issues a one-time code that binds the Telegram chat sending
+// "/link CODE" to the web account user; a new code replaces the user's previous one
+func (e *Engine) NewTelegramLinkCode(user string) (string, time.Time) {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = linkCodeAlphabet[int(b[i])%len(linkCodeAlphabet)]
+	}
+	code := string(b[:4]) + "-" + string(b[4:])
+	expires := time.Now().Add(TelegramLinkCodeTTL)
+
+	e.linkMu.Lock()
+	defer e.linkMu.Unlock()
+	for pending, link := range e.linkCodes {
+		if link.user == user || time.Now().After(link.expires) {
+			delete(e.linkCodes, pending)
+		}
+	}
+	e.linkCodes[code] = linkCode{user: user, expires: expires}
+	return code, expires
+}
+
+// LinkTelegramChat binds a chat to the web account that issued code and returns the account
+// The account's previous chat is unlinked: an account has at most one chat.
+func (e *Engine) LinkTelegramChat(chatID, code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) == 8 {
+		code = code[:4] + "-" + code[4:]
+	}
+
+	e.linkMu.Lock()
+	link, ok := e.linkCodes[code]
+	delete(e.linkCodes, code)
+	e.linkMu.Unlock()
+	if !ok || time.Now().After(link.expires) {
+		return "", ErrInvalidLinkCode
+	}
+
+	e.UnlinkTelegramChat(link.user)
+	prefs := e.store.GetChatPreferences(chatID)
+	prefs.LinkedUser = link.user
+	e.store.SaveChatPreferences(chatID, prefs)
+	slog.Info("Telegram chat linked", "chat_id", chatID, "user", link.user)
+	return link.user, nil
+}
+
+// UnlinkTelegramChat removes the chat binding of a web account
+func (e *Engine) UnlinkTelegramChat(user string) {
+	chatID, ok := e.store.LinkedChat(user)
+	if !ok {
+		return
+	}
+	prefs := e.store.GetChatPreferences(chatID)
+	prefs.LinkedUser = ""
+	e.store.SaveChatPreferences(chatID, prefs)
+	slog.Info("Telegram chat unlinked", "chat_id", chatID, "user", user)
+}
+
+// LinkedTelegramChat returns the chat bound to a web account, "" when none is
+func (e *Engine) LinkedTelegramChat(user string) string {
+	chatID, _ := e.store.LinkedChat(user)
+	return chatID
+}
+
+// ChatIdentity returns the identity a Telegram chat acts as: the linked web account,
+// otherwise the chat itself (tg:<chat id>)
+func (e *Engine) ChatIdentity(chatID string) string {
+	if user := e.store.GetChatPreferences(chatID).LinkedUser; user != "" {
+		return user
+	}
+	return TelegramIdentity(chatID)
+}
+
+// defaultChatID is the chat of general notifications: TELEGRAM_CHAT_ID, or without it the
+// chat linked by the first admin in ADMIN_USERS that has one
+func (e *Engine) defaultChatID() string {
+	if e.cfg.TelegramChatID != "" {
+		return e.cfg.TelegramChatID
+	}
+	for _, admin := range e.cfg.AdminUsers {
+		if chatID := e.LinkedTelegramChat(admin); chatID != "" {
+			return chatID
+		}
+	}
+	return ""
+}
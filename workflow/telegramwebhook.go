@@ -0,0 +1,62 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// telegramWebhookTimeout bounds a single webhook check
+const telegramWebhookTimeout = 30 * time.Second
+
+// RunTelegramWebhookMonitor makes sure Telegram delivers to TELEGRAM_WEBHOOK_URL at startup and
+// every TELEGRAM_WEBHOOK_CHECK_INTERVAL until ctx is cancelled, so the bot does not keep posting
+// to a dead tunnel, or to the URL another instance of the bot registered meanwhile
+func (e *Engine) RunTelegramWebhookMonitor(ctx context.Context) {
+	if e.cfg.TelegramBotToken == "" || e.cfg.TelegramWebhookURL == "" {
+		return
+	}
+	if err := e.EnsureTelegramWebhook(ctx); err != nil {
+		slog.Warn("Failed to check Telegram webhook", "error", err)
+	}
+	if e.cfg.TelegramWebhookCheck <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.TelegramWebhookCheck)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := e.EnsureTelegramWebhook(ctx); err != nil {
+			slog.Warn("Failed to check Telegram webhook", "error", err)
+		}
+	}
+}
+
+// EnsureTelegramWebhook compares the webhook registered with Telegram against
+// TELEGRAM_WEBHOOK_URL and registers it again only when they differ
+func (e *Engine) EnsureTelegramWebhook(ctx context.Context) error {
+	if e.cfg.TelegramBotToken == "" || e.cfg.TelegramWebhookURL == "" {
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, telegramWebhookTimeout)
+	defer cancel()
+	previous, changed, err := e.telegram.EnsureWebhook(checkCtx, e.cfg.TelegramWebhookURL, e.cfg.TelegramWebhookSecret)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !changed:
+		slog.Debug("Telegram webhook up to date", "url", previous)
+	case previous == "":
+		slog.Info("Telegram webhook registered", "url", e.cfg.TelegramWebhookURL)
+	default:
+		slog.Warn("Telegram webhook pointed elsewhere, registered again", "previous", previous, "url", e.cfg.TelegramWebhookURL)
+	}
+	return nil
+}
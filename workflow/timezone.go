@@ -0,0 +1,18 @@
+package workflow
+
+import (
+	"time"
+
+	"workflower/lib/timefmt"
+)
+
+// ChatLocation returns a Telegram chat's preferred time zone,
+// falling back to the configured display time zone
+func (e *Engine) ChatLocation(chatID string) *time.Location {
+	if name := e.store.GetChatPreferences(chatID).Timezone; name != "" {
+		if loc, err := timefmt.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return e.cfg.DisplayLocation
+}
@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"workflower/storage"
+)
+
+// ErrTranscriptNeedsOpenAI is returned when a transcript is given without an OpenAI key to summarize it
+var ErrTranscriptNeedsOpenAI = newError(CodeInvalid, "summarizing a transcript requires OPENAI_API_KEY")
+
+// CheckTranscript validates a pasted transcript before a workflow is started from it
+func (e *Engine) CheckTranscript(transcript string) error {
+	if strings.TrimSpace(transcript) == "" {
+		return nil
+	}
+	if !e.cfg.HasOpenAI() {
+		return ErrTranscriptNeedsOpenAI
+	}
+	if n := utf8.RuneCountInString(transcript); e.cfg.TranscriptMaxChars > 0 && n > e.cfg.TranscriptMaxChars {
+		return invalidf("transcript is too long (%d characters, at most %d)", n, e.cfg.TranscriptMaxChars)
+	}
+	return nil
+}
+
+// summarizeTranscript extracts the themes of the workflow's transcript into a song brief
+// The task description given with the transcript is passed along as directions.
+func (e *Engine) summarizeTranscript(ctx context.Context, state *storage.WorkflowState) (string, error) {
+	userPrompt := "Text:\n" + state.Transcript
+	if directions := strings.TrimSpace(state.TaskDescription); directions != "" {
+		userPrompt = fmt.Sprintf("Directions for the song: %s\n\n%s", directions, userPrompt)
+	}
+
+	summary, err := e.chat(ctx, state, e.promptsList.TranscriptSummary, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", errors.New("the transcript summary is empty")
+	}
+	return summary, nil
+}
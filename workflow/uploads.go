@@ -0,0 +1,55 @@
+package workflow
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UploadsPath is the route uploaded files are served under, with signed links only
+const UploadsPath = "/uploads/"
+
+// UploadURL returns a link to an uploaded file that is valid for UPLOAD_URL_TTL, signed with
+// SECRET_KEY, or "" for files outside UploadsDir
+func (e *Engine) UploadURL(path string, now time.Time) string {
+	rel, ok := uploadRelPath(path)
+	if !ok {
+		return ""
+	}
+	expires := strconv.FormatInt(now.Add(e.cfg.UploadURLTTL).Unix(), 10)
+	segments := strings.Split(rel, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	query := url.Values{"expires": {expires}, "sig": {e.Sign(uploadTokenValue(rel, expires))}}
+	return UploadsPath + strings.Join(segments, "/") + "?" + query.Encode()
+}
+
+// VerifyUploadURL checks the signature and expiry of a link made by UploadURL and returns the
+// file it points to; rel is the unescaped path below UploadsPath
+func (e *Engine) VerifyUploadURL(rel, expires, signature string, now time.Time) (string, bool) {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix || !e.VerifySignature(uploadTokenValue(rel, expires), signature) {
+		return "", false
+	}
+	path := filepath.Join(UploadsDir, filepath.FromSlash(rel))
+	if _, ok := uploadRelPath(path); !ok {
+		return "", false
+	}
+	return path, true
+}
+
+// uploadRelPath returns the slash-separated path of an uploaded file below UploadsDir
+func uploadRelPath(path string) (string, bool) {
+	rel, err := filepath.Rel(UploadsDir, filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func uploadTokenValue(rel, expires string) string {
+	return "upload|" + rel + "|" + expires
+}
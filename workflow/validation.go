@@ -0,0 +1,134 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"workflower/storage"
+)
+
+const (
+	maxTagChars  = 60  // longer bracket tags are likely lyrics caught between brackets
+	maxLineChars = 200 // longer lines are usually a missing line break
+)
+
+// ErrInvalidLyrics is returned when lyrics with blocking issues are approved
+// (errors, or bracket lint the reviewer did not override)
+var ErrInvalidLyrics = newError(CodeInvalid, "lyrics have blocking issues")
+
+// sectionMarkers are the song sections Suno recognizes (matched case-insensitively, numbers ignored)
+var sectionMarkers = []string{
+	"intro", "verse", "pre-chorus", "prechorus", "chorus", "post-chorus", "hook", "refrain",
+	"bridge", "break", "breakdown", "interlude", "instrumental", "instrumental break", "solo",
+	"build", "build-up", "drop", "outro", "end", "fade out",
+}
+
+// cueWords start the non-section tags the bracket instructions step uses (voices, delivery, mood, tempo)
+var cueWords = []string{
+	"male", "female", "duet", "choir", "spoken", "whispered", "belted", "falsetto", "rap",
+	"guitar", "piano", "drum", "bass", "synth", "strings",
+	"slow", "fast", "building", "dropping", "emotional", "energetic", "calm", "intense", "soft", "loud",
+}
+
+// ValidateLyrics checks lyrics against the Suno constraints
+// Errors (empty lyrics, too long) block submission. Bracket lint (malformed, empty or unknown tags,
+// unbalanced parentheses, no section tags) blocks it too unless the reviewer overrides it: Suno sings
+// what it does not recognize, and a take with sung tags is a wasted generation. Warnings are advisory.
+func (e *Engine) ValidateLyrics(lyrics string) []storage.LyricsIssue {
+	var issues []storage.LyricsIssue
+	add := func(severity string, line int, format string, args ...any) {
+		issues = append(issues, storage.LyricsIssue{Severity: severity, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(lyrics) == "" {
+		add(storage.IssueError, 0, "Lyrics are empty")
+		return issues
+	}
+
+	length := len([]rune(lyrics))
+	if e.cfg.LyricsMaxChars > 0 && length > e.cfg.LyricsMaxChars {
+		add(storage.IssueError, 0, "Lyrics are %d characters long, the maximum is %d", length, e.cfg.LyricsMaxChars)
+	} else if length > e.cfg.LongSongSegmentChars {
+		add(storage.IssueWarning, 0, "Lyrics are %d characters long and will be generated as a long song in %d segments",
+			length, len(splitLyricsSegments(lyrics, e.cfg.LongSongSegmentChars)))
+	}
+
+	sections := 0
+	for i, line := range strings.Split(lyrics, "\n") {
+		n := i + 1
+		tags, problem := bracketTags(line)
+		if problem != "" {
+			add(storage.IssueLint, n, "%s", problem)
+			continue
+		}
+		for _, tag := range tags {
+			switch {
+			case strings.TrimSpace(tag) == "":
+				add(storage.IssueLint, n, "Empty bracket tag []")
+			case len([]rune(tag)) > maxTagChars:
+				add(storage.IssueLint, n, "Bracket tag is %d characters long; Suno may sing it", len([]rune(tag)))
+			case isSectionMarker(tag):
+				sections++
+			case !isCue(tag):
+				add(storage.IssueLint, n, "Unrecognized tag [%s]; Suno may sing it", tag)
+			}
+		}
+		if strings.Count(line, "(") != strings.Count(line, ")") {
+			add(storage.IssueLint, n, "Unbalanced parentheses (backing vocals)")
+		}
+		if len([]rune(line)) > maxLineChars {
+			add(storage.IssueWarning, n, "Line is %d characters long; a line break may be missing", len([]rune(line)))
+		}
+	}
+
+	if sections == 0 {
+		add(storage.IssueLint, 0, "No section markers such as [Verse] or [Chorus]")
+	}
+	return issues
+}
+
+// bracketTags returns the contents of the [tags] of a line, or a problem when the brackets are malformed
+func bracketTags(line string) ([]string, string) {
+	var tags []string
+	start := -1
+	for i, r := range line {
+		switch r {
+		case '[':
+			if start >= 0 {
+				return nil, "Nested '[' inside a bracket tag"
+			}
+			start = i
+		case ']':
+			if start < 0 {
+				return nil, "Closing ']' without an opening '['"
+			}
+			tags = append(tags, line[start+1:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		return nil, "Unclosed '[' bracket tag"
+	}
+	return tags, ""
+}
+
+// normalizeTag lowercases a tag and drops qualifiers ("Verse 2: Male Voice" -> "verse")
+func normalizeTag(tag string) string {
+	tag, _, _ = strings.Cut(tag, ":")
+	tag, _, _ = strings.Cut(tag, "(")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return strings.TrimRightFunc(tag, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsSpace(r)
+	})
+}
+
+func isSectionMarker(tag string) bool {
+	return slices.Contains(sectionMarkers, normalizeTag(tag))
+}
+
+func isCue(tag string) bool {
+	first, _, _ := strings.Cut(normalizeTag(tag), " ")
+	return slices.Contains(cueWords, first)
+}
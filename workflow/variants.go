@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"workflower/lib/suno"
+	"workflower/storage"
+)
+
+// submitVariants submits the same lyrics once per A/B property set and tracks every resulting clip
+func (e *Engine) submitVariants(ctx context.Context, state *storage.WorkflowState, lyrics, title string, props *storage.SunoProperties) {
+	state.Variants = []storage.SongVariant{
+		{Label: "A", Properties: props},
+		{Label: "B", Properties: state.VariantB},
+	}
+	e.generateVariants(ctx, state, lyrics, title)
+}
+
+// generateVariants submits the variants that have no clips yet and polls all of them
+// Variants submitted before a failed retry keep their clips and are not paid for twice.
+func (e *Engine) generateVariants(ctx context.Context, state *storage.WorkflowState, lyrics, title string) {
+	err := e.runStep(state, StepSubmission, func() error {
+		for i := range state.Variants {
+			variant := &state.Variants[i]
+			if len(variant.Clips) > 0 {
+				continue
+			}
+			if err := e.reserveSunoSubmission(); err != nil {
+				return fmt.Errorf("variant %s: %w", variant.Label, err)
+			}
+			results, err := e.sunoAPI.CustomGenerate(ctx, &suno.CustomGenerateRequest{
+				Prompt:    lyrics,
+				Tags:      sunoTags(state, variant.Properties),
+				Title:     title,
+				Model:     e.sunoModel(variant.Properties),
+				PersonaID: sunoPersonaID(state),
+			})
+			if err == nil && len(results) == 0 {
+				err = fmt.Errorf("no results returned from Suno")
+			}
+			if err != nil {
+				return fmt.Errorf("variant %s: %w", variant.Label, err)
+			}
+
+			for _, result := range results {
+				variant.Clips = append(variant.Clips, storage.SongClip{ID: result.ID, Status: result.Status})
+			}
+			e.recordSunoCredits(state, e.cfg.SunoCreditsPerGeneration)
+		}
+		return nil
+	})
+	if err != nil {
+		e.handleError(state, StepSubmission, err)
+		return
+	}
+
+	state.SunoJobID = state.Variants[0].Clips[0].ID
+	e.setStatus(state, storage.StatusGenerating)
+
+	go e.pollVariants(ctx, state)
+}
+
+// pollVariants waits for every A/B clip; the first clip of variant A becomes the workflow's song
+func (e *Engine) pollVariants(ctx context.Context, state *storage.WorkflowState) {
+	total := 0
+	for _, variant := range state.Variants {
+		total += len(variant.Clips)
+	}
+
+	var primary *suno.AudioInfo
+	done := 0
+	err := e.runStep(state, StepCompletion, func() error {
+		for i := range state.Variants {
+			variant := &state.Variants[i]
+			for j := range variant.Clips {
+				clip := &variant.Clips[j]
+				// Clips finished before a restart are not polled again
+				if clip.AudioURL == "" {
+					audio, err := e.waitForClip(ctx, state, clip.ID)
+					if err != nil {
+						return fmt.Errorf("variant %s clip %d: %w", variant.Label, j+1, err)
+					}
+
+					clip.Status = audio.Status
+					clip.AudioURL = audio.AudioURL
+					clip.VideoURL = audio.VideoURL
+					clip.Duration = audio.Duration
+					e.store.Save(state)
+				}
+				if primary == nil {
+					primary = &suno.AudioInfo{ID: clip.ID, Status: clip.Status, AudioURL: clip.AudioURL, VideoURL: clip.VideoURL, Duration: clip.Duration}
+				}
+
+				done++
+				e.publishProgress(state, done, total)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		e.handleError(state, StepCompletion, err)
+		return
+	}
+
+	e.completeSong(ctx, state, primary)
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"workflower/config"
+	"workflower/lib/webhook"
+	"workflower/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookEventPrefix  = "workflow."
+	webhookRetryBackoff = 2 * time.Second
+	webhookTimeout      = 5 * time.Minute
+)
+
+// webhookStatuses lists the transitions that trigger outbound webhooks
+var webhookStatuses = map[string]bool{
+	storage.StatusAwaitingReview:    true,
+	storage.StatusCompleted:         true,
+	storage.StatusFailed:            true,
+	storage.StatusBlockedModeration: true,
+}
+
+// newWebhookSubscriber posts webhooks for the transitions listed in webhookStatuses
+// Every delivery is recorded in the store's delivery log
+func newWebhookSubscriber(cfg *config.Config, store *storage.Store) func(Event) {
+	dispatcher := webhook.NewDispatcher(cfg.WebhookURLs, cfg.WebhookSecret).
+		WithSchemaVersion(EventSchemaVersion).
+		WithRetries(cfg.WebhookMaxAttempts, webhookRetryBackoff).
+		WithDeliveryHook(func(delivery webhook.Delivery) {
+			store.AddWebhookDelivery(delivery)
+			if !delivery.Success {
+				slog.Warn("Webhook delivery failed", "url", delivery.URL, "event", delivery.Event, "attempts", delivery.Attempts, "error", delivery.Error)
+			}
+		})
+
+	return func(event Event) {
+		changed, ok := event.(StatusChanged)
+		if !ok || !dispatcher.Enabled() || !webhookStatuses[changed.To] {
+			return
+		}
+
+		data := newEventWorkflow(cfg.BaseURL, &changed.Workflow)
+		name := webhookEventPrefix + changed.To
+		if cfg.DryRun {
+			slog.Info("Dry run: webhook not sent", "event", name, "workflow_id", data.ID)
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(logContext(&changed.Workflow), webhookTimeout)
+			defer cancel()
+
+			if err := dispatcher.Dispatch(ctx, uuid.New().String(), name, data); err != nil {
+				slog.WarnContext(ctx, "Failed to dispatch webhook", "error", err, "workflow_id", data.ID, "event", name)
+			}
+		}()
+	}
+}
@@ -5,126 +5,575 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"workflower/config"
+	"workflower/lib/cloudsync"
+	"workflower/lib/eventbus"
+	"workflower/lib/llm"
 	"workflower/lib/llm/openai"
+	"workflower/lib/logger"
+	"workflower/lib/notify"
 	"workflower/lib/suno"
 	"workflower/lib/telegram"
 	"workflower/storage"
+	"workflower/templates/messages"
 	"workflower/templates/prompts"
+	"workflower/users"
+)
+
+// Engine step names, used in step events and error messages
+const (
+	StepTranscript   = "transcript summary"
+	StepModeration   = "moderation"
+	StepLyrics       = "lyrics generation"
+	StepProperties   = "suno properties"
+	StepBrackets     = "bracket instructions"
+	StepSimilarity   = "similarity check"
+	StepValidation   = "lyrics validation"
+	StepPersonaInspo = "persona/inspo"
+	StepSubmission   = "suno submission"
+	StepCompletion   = "suno completion"
+	StepExtend       = "suno extend"
+	StepConcat       = "suno concat"
+	StepStems        = "suno stems"
+)
 
-	"github.com/google/uuid"
+// Suno completion polling: every 5 seconds, max 60 retries (5 minutes) per clip; other poll
+// intervals keep the same 5 minute budget (see pollSchedule)
+const (
+	sunoPollInterval = 5 * time.Second
+	sunoPollRetries  = 60
 )
 
+// UploadsDir is where uploaded reference audio is stored, in one directory per day
+const UploadsDir = "uploads"
+
 // Engine orchestrates the song creation workflow
 type Engine struct {
 	cfg         *config.Config
-	llmClient   *openai.Client
-	sunoAPI     *suno.Client
-	notifier    *telegram.Notifier
+	llm         llm.Chatter    // writes and edits lyrics: the OpenAI client unless replaced (SetLLM)
+	openAI      *openai.Client // moderation and the health check of the OpenAI API
+	openAIKeys  *openai.KeyPool
+	sunoAPI     suno.API
+	sunoPool    *suno.Pool         // the accounts behind sunoAPI with SUNO_ACCOUNTS, nil otherwise
+	notifier    notify.Notifier    // sends chat notifications: the Telegram bot unless replaced (SetNotifier)
+	telegram    *telegram.Notifier // the Telegram bot: its webhook and DRY_RUN
 	store       *storage.Store
 	promptsList *prompts.PromptsList
+	namer       *Namer
+	screening   []screeningTerm   // see ScreenTitleAndStyle
+	corpus      *similarityCorpus // known lyrics of the similarity check, nil when not configured
+	events      *eventbus.Bus[Event]
+	metrics     *Metrics
+	users       *users.Directory
+
+	stepDurations *stepDurations // average step durations for progress estimates (see Progress)
+
+	lastDigestDay string // owned by the scheduler goroutine
+
+	mu   sync.Mutex                    // guards step records and usage written by concurrently running steps, and runs (the store encodes workflows under it)
+	runs map[string]context.CancelFunc // background runs by workflow ID (see track)
+	// deciding holds the workflows whose review decision is being made, guarded by mu (see claimReview)
+	deciding map[string]bool
+
+	sunoSubmissions []time.Time // Suno generations of the last hour, guarded by mu (see reserveSunoSubmission)
+
+	healthMu   sync.Mutex
+	sunoHealth SunoHealth // last Suno session check (see RunSunoHealthMonitor)
+
+	deepHealthMu sync.Mutex        // serializes deep checks, held while one runs
+	deepHealth   *DeepHealthReport // last deep check, served until deepHealthCacheTTL passes
+
+	linkMu    sync.Mutex
+	linkCodes map[string]linkCode // pending Telegram /link codes (see NewTelegramLinkCode)
+
+	clipMu      sync.Mutex
+	clipWaiters map[string]chan *suno.AudioInfo // Suno callbacks by clip ID (see DeliverClip)
+
+	messagesMu sync.Mutex
+	messages   *messages.Catalog // Telegram message templates (see Message and ReloadMessages)
+
+	tagSyncMu sync.Mutex // serializes style tag syncs (see SyncTags)
+
+	resultRuns sync.Map // workflow IDs whose song is being archived (see archiveResult)
+
+	personaMu   sync.Mutex
+	personas    []suno.Persona // personas of the Suno account, nil until listed (see SunoPersonas)
+	personasAll bool           // personas holds every persona, not just the first page
+	personasAt  time.Time
+
+	newID IDGenerator // IDs of new workflows (ID_SCHEME, see SetIDGenerator)
+}
+
+// StartParams holds the user input for a new workflow
+type StartParams struct {
+	Project         string
+	ExternalRef     string // the integrator's reference (CRM ticket, order number), searchable
+	TaskDescription string // with a Transcript, optional directions for the song
+	Transcript      string // pasted chat or diary text, summarized into the task description
+	IsPremium       bool
+	AudioFilePath   string
+	AudioFileName   string
+	DueAt           *time.Time
+	LongSong        bool
+	Assignee        string
+	GenerateStems   bool
+	Language        string // lyrics language name; "" for the detected one or DEFAULT_LANGUAGE
+	LyricsEngine    string // LyricsEngineOpenAI or LyricsEngineSuno, empty for the configured default
+	SunoModel       string // one of SUNO_MODELS, empty for SUNO_MODEL
+	SunoPersonaID   string // Suno persona premium workflows are generated with (see SunoPersonas), empty for none
+	SourceLyrics    string // lyrics of an extended or covered track; generated lyrics match their structure (OpenAI only)
+	Session         string // hash of the browser session starting it, empty outside the web UI
+
+	// Seeds of a cloned workflow: the matching generation steps are skipped
+	ClonedFrom     string
+	Lyrics         string                  // reviewed lyrics, brackets included
+	SunoProperties *storage.SunoProperties // reviewed property set
+
+	// Import mode: Lyrics were written by the user and are not generated; bracket
+	// instructions are only added with AddBrackets
+	LyricsImported bool
+	AddBrackets    bool
 }
 
 // NewEngine creates a new workflow engine
+// Telegram notifications, outbound webhooks and metrics are wired as event bus subscribers
 func NewEngine(cfg *config.Config, store *storage.Store, promptsList *prompts.PromptsList) *Engine {
-	return &Engine{
+	openAIKeys := openai.NewKeyPool(cfg.OpenAIAPIKeys...)
+	openAI := openai.NewPooledClient(openAIKeys, cfg.OpenAIModel).WithBaseURL(cfg.OpenAIBaseURL)
+	bot := telegram.NewNotifier(cfg.TelegramBotToken, cfg.TelegramChatID)
+	e := &Engine{
 		cfg:         cfg,
-		llmClient:   openai.NewClient(cfg.OpenAIAPIKey, cfg.OpenAIModel),
-		sunoAPI:     suno.NewClient(cfg.SunoBaseURL),
-		notifier:    telegram.NewNotifier(cfg.TelegramBotToken, cfg.TelegramChatID),
+		llm:         openAI,
+		openAI:      openAI,
+		openAIKeys:  openAIKeys,
+		notifier:    bot,
+		telegram:    bot,
 		store:       store,
 		promptsList: promptsList,
+		namer:       NewNamer(cfg.NamingTemplate),
+		newID:       idGenerator(cfg.IDScheme),
+		screening:   newScreeningList(cfg),
+		events:      eventbus.New[Event](),
+		metrics:     NewMetrics(),
+		users:       users.New(cfg.AdminUsers, cfg.ReviewerUsers, cfg.ViewerUsers, cfg.DefaultRole),
+		runs:        make(map[string]context.CancelFunc),
+		deciding:    make(map[string]bool),
+		sunoHealth:  SunoHealth{Healthy: true},
+		linkCodes:   make(map[string]linkCode),
+		clipWaiters: make(map[string]chan *suno.AudioInfo),
+	}
+	store.SetStateLock(&e.mu)
+	e.stepDurations = newStepDurations(store)
+	e.messages = loadMessages(cfg)
+	corpus, err := loadSimilarityCorpus(cfg.SimilarityCorpusDir)
+	if err != nil {
+		slog.Warn("Similarity corpus not loaded, lyrics are not compared with it", "error", err)
+	} else if corpus != nil {
+		slog.Info("Similarity corpus loaded", "texts", len(corpus.sources))
 	}
+	e.corpus = corpus
+	e.telegram.SetDryRun(cfg.DryRun)
+	var callbackURL string
+	if cfg.SunoCallbackSecret != "" {
+		callbackURL = e.SunoCallbackURL()
+	}
+	if len(cfg.SunoAccounts) > 0 {
+		e.sunoPool = newSunoPool(cfg, store, callbackURL, e.sunoCircuitChanged)
+		e.sunoAPI = e.sunoPool
+	} else {
+		e.sunoAPI = newSunoAPI(cfg, cfg.SunoBaseURL, cfg.SunoAPIToken, callbackURL, e.sunoCircuitChanged)
+	}
+
+	e.events.Subscribe(e.telegramSubscriber())
+	e.events.Subscribe(newAuditSubscriber(store))
+	e.events.Subscribe(newOperationsSubscriber(e))
+	e.events.Subscribe(newResultsSubscriber(e))
+	e.events.Subscribe(newWebhookSubscriber(cfg, store))
+	e.events.Subscribe(e.metrics.Handle)
+	e.events.Subscribe(e.stepDurations.Handle)
+
+	return e
+}
+
+// newSunoAPI returns the Suno client of the configured transport (SUNO_TRANSPORT) for the
+// suno-api at baseURL; the engine does not depend on which one is used. onCircuitChange is
+// told when suno-api goes down and up.
+func newSunoAPI(cfg *config.Config, baseURL, token, callbackURL string, onCircuitChange func(open bool, err error)) suno.API {
+	opts := suno.Options{
+		BaseURL:       baseURL,
+		Transport:     cfg.SunoTransport,
+		Token:         token,
+		CallbackURL:   callbackURL,
+		NotFoundGrace: cfg.SunoNotFoundGrace,
+		Resilience: &suno.Resilience{
+			Timeout:          cfg.SunoRequestTimeout,
+			MaxAttempts:      cfg.SunoMaxAttempts,
+			Backoff:          cfg.SunoRetryBackoff,
+			BreakerThreshold: cfg.SunoBreakerThreshold,
+			BreakerCooldown:  cfg.SunoBreakerCooldown,
+			OnCircuitChange:  onCircuitChange,
+		},
+		RateLimit: &suno.RateLimit{PerMinute: cfg.SunoRateLimit, Burst: cfg.SunoRateBurst},
+	}
+	client, err := suno.New(opts)
+	if err != nil {
+		slog.Error("Invalid Suno transport, using the suno-api proxy", "error", err)
+		opts.Transport = suno.TransportProxy
+		client, _ = suno.New(opts)
+	}
+	return client
+}
+
+// SetSunoAPI replaces the Suno client, e.g. with the in-memory sunotest.Mock in tests
+// Retries and the circuit breaker of the configured client do not apply to it.
+func (e *Engine) SetSunoAPI(api suno.API) {
+	e.sunoAPI = api
+	e.sunoPool, _ = api.(*suno.Pool)
+}
+
+// SetLLM replaces the language model that writes and edits lyrics, e.g. with another provider
+// or a fake in tests; token usage is only recorded for providers that report it like the OpenAI
+// client. Moderation keeps using the OpenAI API.
+func (e *Engine) SetLLM(chatter llm.Chatter) {
+	e.llm = chatter
+}
+
+// SetNotifier replaces the notifier chat notifications are sent with, e.g. with a fake in tests
+// The Telegram webhook is still managed with TELEGRAM_BOT_TOKEN.
+func (e *Engine) SetNotifier(notifier notify.Notifier) {
+	e.notifier = notifier
+}
+
+// Start resumes the workflows interrupted by the last shutdown and runs the background jobs
+// until ctx is cancelled: the scheduler (reminders, escalation, expiry), the Suno session and
+// quota monitors, the style tag sync and, when configured, archival and cloud sync
+// It returns at once; only opening the SYNC_TARGET can fail.
+func (e *Engine) Start(ctx context.Context) error {
+	e.ResumePolling(ctx)
+	go e.RunScheduler(ctx)
+	go e.RunSunoHealthMonitor(ctx)
+	go e.RunSunoQuotaMonitor(ctx)
+	go e.RunTagSync(ctx)
+	if e.cfg.ArchiveDir != "" {
+		go e.RunArchival(ctx)
+	}
+	if e.cfg.SyncTarget != "" {
+		remote, err := cloudsync.Open(e.cfg.SyncTarget, e.cfg.DropboxToken)
+		if err != nil {
+			return fmt.Errorf("open sync target: %w", err)
+		}
+		go e.RunCloudSync(ctx, remote)
+	}
+	return nil
+}
+
+// Snapshot returns a copy of a workflow taken under the engine lock, for readers that must not
+// see it half-updated by a running step; the steps and the store write under the same lock
+// (see update and storage.Store.SetStateLock)
+func (e *Engine) Snapshot(state *storage.WorkflowState) storage.WorkflowState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return *state
+}
+
+// Namer returns the workflow namer used for titles and file names
+func (e *Engine) Namer() *Namer {
+	return e.namer
+}
+
+// Events returns the engine event bus for additional subscribers
+func (e *Engine) Events() *eventbus.Bus[Event] {
+	return e.events
+}
+
+// Metrics returns the engine metrics collector
+func (e *Engine) Metrics() *Metrics {
+	return e.metrics
 }
 
 // StartWorkflow begins a new song creation workflow
-func (e *Engine) StartWorkflow(ctx context.Context, taskDescription string, isPremium bool, audioFilePath, audioFileName string) (*storage.WorkflowState, error) {
+func (e *Engine) StartWorkflow(ctx context.Context, params StartParams) (*storage.WorkflowState, error) {
+	lyricsEngine, err := e.resolveLyricsEngine(params.LyricsEngine)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.CheckTranscript(params.Transcript); err != nil {
+		return nil, err
+	}
+	if err := e.CheckSourceLyrics(params.SourceLyrics, params.LyricsImported && params.Lyrics != ""); err != nil {
+		return nil, err
+	}
+	externalRef := strings.TrimSpace(params.ExternalRef)
+	if err := checkExternalRef(externalRef); err != nil {
+		return nil, err
+	}
+	sunoModel := strings.TrimSpace(params.SunoModel)
+	if err := e.checkSunoModel(sunoModel); err != nil {
+		return nil, err
+	}
+	sunoPersonaID := ""
+	if params.IsPremium {
+		sunoPersonaID = strings.TrimSpace(params.SunoPersonaID)
+	}
+	if strings.TrimSpace(params.SourceLyrics) != "" {
+		lyricsEngine = LyricsEngineOpenAI // Suno cannot be held to a structure
+	}
+	language, languageDetected := e.resolveLanguage(params)
+
 	// Create new workflow state
 	state := &storage.WorkflowState{
-		ID:              uuid.New().String(),
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-		Status:          "processing",
-		TaskDescription: taskDescription,
-		IsPremium:       isPremium,
-		AudioFilePath:   audioFilePath,
-		AudioFileName:   audioFileName,
+		ID:               e.newID(),
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		Project:          strings.TrimSpace(params.Project),
+		ExternalRef:      externalRef,
+		TaskDescription:  params.TaskDescription,
+		Transcript:       strings.TrimSpace(params.Transcript),
+		SourceLyrics:     strings.TrimSpace(params.SourceLyrics),
+		IsPremium:        params.IsPremium,
+		AudioFilePath:    params.AudioFilePath,
+		AudioFileName:    params.AudioFileName,
+		DueAt:            params.DueAt,
+		LongSong:         params.LongSong,
+		Assignee:         params.Assignee,
+		GenerateStems:    params.GenerateStems,
+		Language:         language,
+		LanguageDetected: languageDetected,
+		LyricsEngine:     lyricsEngine,
+		SunoModel:        sunoModel,
+		SunoPersonaID:    sunoPersonaID,
+		ClonedFrom:       params.ClonedFrom,
+		RequestID:        logger.RequestID(ctx),
+		Session:          params.Session,
+		Lyrics:           params.Lyrics,
+		LyricsImported:   params.LyricsImported && params.Lyrics != "",
+		SunoProperties:   params.SunoProperties,
 	}
-	e.store.Save(state)
+	if state.LyricsImported && !params.AddBrackets {
+		state.LyricsWithBrackets = state.Lyrics
+	}
+	e.setStatus(state, storage.StatusProcessing)
 
 	// Run the workflow steps asynchronously
-	go e.runWorkflowSteps(ctx, state)
+	go e.runWorkflowSteps(e.track(ctx, state), state)
 
 	return state, nil
 }
 
 // runWorkflowSteps executes all workflow steps
 func (e *Engine) runWorkflowSteps(ctx context.Context, state *storage.WorkflowState) {
-	var err error
+	// Step 0: A pasted transcript is turned into the task description the other steps build on
+	if state.Transcript != "" && !stepSucceeded(state, StepTranscript) {
+		err := e.runStep(state, StepTranscript, func() error {
+			task, err := e.summarizeTranscript(ctx, state)
+			e.update(func() { state.TaskDescription = task })
+			return err
+		})
+		if err != nil {
+			e.handleError(state, StepTranscript, err)
+			return
+		}
+		e.store.Save(state)
+	}
 
-	// Step 1: Generate lyrics
-	state.Lyrics, err = e.generateLyrics(ctx, state.TaskDescription)
-	if err != nil {
-		e.handleError(state, "lyrics generation", err)
+	if !e.moderate(ctx, state, "Task description", state.TaskDescription) {
 		return
 	}
-	e.store.Save(state)
 
-	// Step 2: Determine Suno properties
-	state.SunoProperties, err = e.determineSunoProperties(ctx, state.TaskDescription, state.Lyrics)
-	if err != nil {
-		e.handleError(state, "suno properties", err)
+	// Step 1: Generate lyrics, unless imported, seeded from a cloned workflow or kept from a failed run
+	// Seeded lyrics are the reviewed lyrics of the original and already carry their brackets;
+	// imported lyrics get them in step 3 if asked for (see StartParams.AddBrackets)
+	var err error
+	if state.Lyrics == "" {
+		err = e.runStep(state, StepLyrics, func() error {
+			lyrics, err := e.generateLyrics(ctx, state)
+			e.update(func() { state.Lyrics = lyrics })
+			return err
+		})
+		if err != nil {
+			e.handleError(state, StepLyrics, err)
+			return
+		}
+		e.store.Save(state)
+	} else if !state.LyricsImported && !stepSucceeded(state, StepLyrics) {
+		e.update(func() { state.LyricsWithBrackets = state.Lyrics })
+	}
+	lyricsSource := "Generated lyrics"
+	if state.LyricsImported {
+		lyricsSource = "Imported lyrics"
+	}
+	if !e.moderate(ctx, state, lyricsSource, state.Lyrics) {
 		return
 	}
-	e.store.Save(state)
 
-	// Step 3: Add bracket instructions to lyrics
-	state.LyricsWithBrackets, err = e.addBracketInstructions(ctx, state.Lyrics, state.SunoProperties)
-	if err != nil {
-		e.handleError(state, "bracket instructions", err)
-		return
+	// Without OpenAI the reviewer fills in the properties and Suno's lyrics
+	// (which already carry section markers) are reviewed as drafted
+	if !e.cfg.HasOpenAI() {
+		e.update(func() {
+			if state.SunoProperties == nil {
+				state.SunoProperties = &storage.SunoProperties{Model: state.SunoModel}
+			}
+			state.LyricsWithBrackets = state.Lyrics
+		})
 	}
-	e.store.Save(state)
 
-	// Step 4: Add Persona and Inspo (premium only)
-	if state.IsPremium {
-		state.PersonaInspo, err = e.generatePersonaInspo(ctx, state.TaskDescription, state.SunoProperties)
+	// Step 2: Determine Suno properties, unless seeded from a cloned workflow
+	if e.cfg.HasOpenAI() && state.SunoProperties == nil {
+		err = e.runStep(state, StepProperties, func() error {
+			props, err := e.determineSunoProperties(ctx, state)
+			e.update(func() { state.SunoProperties = props })
+			return err
+		})
 		if err != nil {
-			e.handleError(state, "persona/inspo", err)
+			e.handleError(state, StepProperties, err)
 			return
 		}
 		e.store.Save(state)
 	}
 
-	// Step 5: Update status and notify for human review
-	state.Status = "awaiting_review"
-	state.EditedLyrics = state.LyricsWithBrackets
-	state.EditedProperties = state.SunoProperties
-	e.store.Save(state)
+	// Steps 3 and 4: bracket instructions and Persona/Inspo (premium only) both build on the
+	// properties but not on each other, so they run concurrently
+	if e.cfg.HasOpenAI() {
+		var steps []parallelStep
+		if state.LyricsWithBrackets == "" {
+			steps = append(steps, parallelStep{
+				name: StepBrackets,
+				run: func(ctx context.Context) error {
+					lyrics, err := e.addBracketInstructions(ctx, state)
+					e.update(func() { state.LyricsWithBrackets = lyrics })
+					return err
+				},
+			})
+		}
+		if state.IsPremium && state.PersonaInspo == nil {
+			steps = append(steps, parallelStep{
+				name: StepPersonaInspo,
+				run: func(ctx context.Context) error {
+					personaInspo, err := e.generatePersonaInspo(ctx, state)
+					e.update(func() { state.PersonaInspo = personaInspo })
+					return err
+				},
+			})
+		}
+		if step, err := e.runParallelSteps(ctx, state, steps...); err != nil {
+			e.handleError(state, step, err)
+			return
+		}
+		e.store.Save(state)
+	}
 
-	// Notify via Telegram
-	reviewURL := fmt.Sprintf("%s/review/%s", e.cfg.BaseURL, state.ID)
-	message := fmt.Sprintf("🎵 Song workflow ready for review!\n\nTask: %s\n\n🔗 Review: %s",
-		truncateString(state.TaskDescription, 100), reviewURL)
+	// Step 4b: Ask the LLM for passages copied from published songs (SIMILARITY_LLM)
+	if e.cfg.SimilarityLLM && e.cfg.HasOpenAI() && !stepSucceeded(state, StepSimilarity) {
+		err = e.runStep(state, StepSimilarity, func() error {
+			flags, err := e.checkSimilarity(ctx, state)
+			e.update(func() { state.SimilarityFlags = flags })
+			return err
+		})
+		if err != nil {
+			e.handleError(state, StepSimilarity, err)
+			return
+		}
+		e.store.Save(state)
+	}
 
-	if err := e.notifier.Send(ctx, message); err != nil {
-		// Log but don't fail the workflow
-		slog.Warn("Failed to send Telegram notification", "error", err, "workflow_id", state.ID)
+	// Step 5: Check the lyrics against the Suno constraints, the source structure and known
+	// songs; issues are shown to the reviewer
+	e.update(func() { state.EditedLyrics = state.LyricsWithBrackets })
+	_ = e.runStep(state, StepValidation, func() error {
+		issues := e.lyricsIssues(state, state.EditedLyrics)
+		e.update(func() { state.LyricsIssues = issues })
+		return nil
+	})
+
+	// Step 6: Hand over for human review; subscribers send the notifications
+	e.update(func() { state.EditedProperties = state.SunoProperties })
+	title := e.namer.Title(state)
+	e.update(func() { state.Title = title })
+	hits := e.ScreenTitleAndStyle(state)
+	credits := e.estimateSunoCredits(state)
+	reviewRequested := time.Now()
+	e.update(func() {
+		state.ScreeningHits = hits
+		state.Usage.EstimatedSunoCredits = credits
+		state.AssignedAt = &reviewRequested
+	})
+	if e.autoApprove(ctx, state) {
+		return
 	}
+	e.setStatus(state, storage.StatusAwaitingReview)
 }
 
-// generateLyrics creates song lyrics from the task description
-func (e *Engine) generateLyrics(ctx context.Context, taskDescription string) (string, error) {
-	return e.llmClient.Chat(ctx, e.promptsList.LyricsGeneration, taskDescription)
+// update applies fn, which sets fields of a running workflow, under the engine lock, so that
+// Snapshot and the store never copy the workflow half-written
+func (e *Engine) update(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn()
+}
+
+// runStep executes a single engine step, recording the run on the workflow
+// and publishing step events around it
+func (e *Engine) runStep(state *storage.WorkflowState, step string, fn func() error) error {
+	started := time.Now()
+	e.mu.Lock()
+	state.Steps = append(state.Steps, storage.StepRun{Step: step, StartedAt: started})
+	run := len(state.Steps) - 1
+	e.mu.Unlock()
+	e.events.Publish(StepStarted{ID: state.ID, Step: step, At: started})
+
+	err := fn()
+
+	finished := StepFinished{ID: state.ID, Step: step, At: time.Now(), Duration: time.Since(started)}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	e.mu.Lock()
+	state.Steps[run].FinishedAt = &finished.At
+	state.Steps[run].Error = finished.Error
+	e.mu.Unlock()
+	e.events.Publish(finished)
+	slog.InfoContext(logContext(state), "Workflow step finished", "workflow_id", state.ID, "step", step,
+		"duration_ms", finished.Duration.Milliseconds(), "error", finished.Error)
+
+	return err
+}
+
+// setStatus persists a status transition and publishes it on the event bus
+// A cancelled workflow keeps its status: runs still finishing after the cancellation are ignored.
+// It reports whether the status was set.
+func (e *Engine) setStatus(state *storage.WorkflowState, status string) bool {
+	e.mu.Lock()
+	from := state.Status
+	if from == storage.StatusCancelled && status != storage.StatusCancelled {
+		e.mu.Unlock()
+		return false
+	}
+	state.Status = status
+	e.mu.Unlock()
+	e.store.Save(state)
+	slog.InfoContext(logContext(state), "Workflow status changed", "workflow_id", state.ID, "from", from, "to", status)
+	if status == storage.StatusAwaitingReview || state.IsTerminal() {
+		e.untrack(state.ID)
+	}
+
+	e.events.Publish(StatusChanged{
+		From:     from,
+		To:       status,
+		At:       time.Now(),
+		Workflow: e.Snapshot(state),
+	})
+	return true
 }
 
 // determineSunoProperties generates optimal Suno configuration
-func (e *Engine) determineSunoProperties(ctx context.Context, taskDescription, lyrics string) (*storage.SunoProperties, error) {
-	userPrompt := fmt.Sprintf("Subject Description:\n%s\n\nLyrics:\n%s", taskDescription, lyrics)
+func (e *Engine) determineSunoProperties(ctx context.Context, state *storage.WorkflowState) (*storage.SunoProperties, error) {
+	userPrompt := fmt.Sprintf("Subject Description:\n%s\n\nLyrics:\n%s", state.TaskDescription, state.Lyrics)
 
-	response, err := e.llmClient.Chat(ctx, e.promptsList.SunoProperties, userPrompt)
+	response, err := e.chat(ctx, state, e.promptsList.SunoProperties, userPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -137,24 +586,27 @@ func (e *Engine) determineSunoProperties(ctx context.Context, taskDescription, l
 			return nil, fmt.Errorf("failed to parse suno properties: %w", err)
 		}
 	}
+	props.Model = state.SunoModel // chosen by the requester, not the LLM
 
 	return &props, nil
 }
 
 // addBracketInstructions enhances lyrics with Suno bracket instructions
-func (e *Engine) addBracketInstructions(ctx context.Context, lyrics string, props *storage.SunoProperties) (string, error) {
-	userPrompt := fmt.Sprintf("Original Lyrics:\n%s\n\nSong Style: %s\nVocal Type: %s",
-		lyrics, props.Style, props.VocalType)
+func (e *Engine) addBracketInstructions(ctx context.Context, state *storage.WorkflowState) (string, error) {
+	props := state.SunoProperties
+	userPrompt := fmt.Sprintf("Original Lyrics:\n%s\n\nSong Style: %s\nVocal Type: %s%s",
+		state.Lyrics, props.Style, props.VocalType, languageInstruction(state.Language))
 
-	return e.llmClient.Chat(ctx, e.promptsList.BracketInstructions, userPrompt)
+	return e.chat(ctx, state, e.promptsList.BracketInstructions, userPrompt)
 }
 
 // generatePersonaInspo creates premium Suno features
-func (e *Engine) generatePersonaInspo(ctx context.Context, taskDescription string, props *storage.SunoProperties) (*storage.PersonaInspo, error) {
+func (e *Engine) generatePersonaInspo(ctx context.Context, state *storage.WorkflowState) (*storage.PersonaInspo, error) {
+	props := state.SunoProperties
 	userPrompt := fmt.Sprintf("Subject: %s\nStyle: %s\nVocal Type: %s",
-		taskDescription, props.Style, props.VocalType)
+		state.TaskDescription, props.Style, props.VocalType)
 
-	response, err := e.llmClient.Chat(ctx, e.promptsList.PersonaInspo, userPrompt)
+	response, err := e.chat(ctx, state, e.promptsList.PersonaInspo, userPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -167,40 +619,92 @@ func (e *Engine) generatePersonaInspo(ctx context.Context, taskDescription strin
 			return nil, fmt.Errorf("failed to parse persona/inspo: %w", err)
 		}
 	}
+	pi.PersonaID = state.SunoPersonaID // chosen by the requester, not the LLM
 
 	return &pi, nil
 }
 
 // ApproveWorkflow processes the approved workflow
-func (e *Engine) ApproveWorkflow(ctx context.Context, state *storage.WorkflowState) error {
-	state.Status = "approved"
-	e.store.Save(state)
+// It returns ErrInvalidLyrics, leaving the workflow in review, when the lyrics to submit have blocking
+// issues; bracket lint only blocks it while state.LintOverridden is unset. A workflow that is
+// not awaiting review, or whose review is being decided by another call, is refused with
+// ErrNotInReview, so it is submitted to Suno once.
+func (e *Engine) ApproveWorkflow(ctx context.Context, state *storage.WorkflowState, by string) error {
+	return e.approve(ctx, state, by, storage.StatusAwaitingReview)
+}
+
+// approve approves a workflow in status from: awaiting review, or processing when it is
+// approved automatically at the end of its preparation
+func (e *Engine) approve(ctx context.Context, state *storage.WorkflowState, by, from string) error {
+	release, err := e.claimReview(state, from)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	lyrics := submittedLyrics(state)
+	if err := e.checkSunoModels(state); err != nil {
+		return ForWorkflow(err, state.ID)
+	}
+	if err := e.checkSunoPersona(ctx, state); err != nil {
+		return ForWorkflow(err, state.ID)
+	}
+	state.LyricsIssues = e.lyricsIssues(state, lyrics)
+	state.ScreeningHits = e.ScreenTitleAndStyle(state)
+	if state.HasLyricsErrors() || (state.HasLyricsLint() && !state.LintOverridden) {
+		e.store.Save(state)
+		return ForWorkflow(ErrInvalidLyrics, state.ID)
+	}
+	ctx = adoptRequestID(ctx, state)
+	decision := DecisionApproved
+	if reviewerEdited(state) {
+		decision = DecisionApprovedEdited
+	}
+	e.publishReviewed(state, by, decision)
+	state.Usage.EstimatedSunoCredits = e.estimateSunoCredits(state)
+
+	if !e.setStatus(state, storage.StatusApproved) {
+		return ForWorkflow(ErrFinished, state.ID) // cancelled meanwhile
+	}
 
 	// Submit to Suno
-	go e.submitToSuno(ctx, state)
+	go e.submitToSuno(e.track(ctx, state), state)
 
 	return nil
 }
 
 // submitToSuno sends the song request to Suno API via suno-api server
 func (e *Engine) submitToSuno(ctx context.Context, state *storage.WorkflowState) {
-	props := state.EditedProperties
-	if props == nil {
-		props = state.SunoProperties
+	if e.parkForSunoAuth(state) {
+		return
 	}
+	props := submittedProperties(state)
+	lyrics := submittedLyrics(state)
 
-	lyrics := state.EditedLyrics
-	if lyrics == "" {
-		lyrics = state.LyricsWithBrackets
+	// The reviewer may have changed the lyrics: check what is actually submitted
+	if lyrics != state.Lyrics && !e.moderate(ctx, state, "Submitted lyrics", lyrics) {
+		return
 	}
 
-	// Construct a descriptive title from the task description
-	title := truncateString(state.TaskDescription, 50)
-	
-	// Build the style/tags string
-	tags := props.Style
-	if props.VocalType != "" {
-		tags += ", " + props.VocalType
+	// Render the title from the naming template, reflecting any edited properties,
+	// unless the reviewer wrote one
+	state.Title = e.title(state)
+	title := state.Title
+
+	tags := sunoTags(state, props)
+
+	if e.isLongSong(state, lyrics) {
+		if state.VariantB != nil {
+			slog.WarnContext(logContext(state), "A/B submission is not supported for long songs, submitting variant A only", "workflow_id", state.ID)
+			state.VariantB = nil
+		}
+		e.submitLongSong(ctx, state, lyrics, tags, title)
+		return
+	}
+
+	if state.VariantB != nil {
+		e.submitVariants(ctx, state, lyrics, title, props)
+		return
 	}
 
 	// Use CustomGenerate for full control over the song
@@ -208,62 +712,159 @@ func (e *Engine) submitToSuno(ctx context.Context, state *storage.WorkflowState)
 		Prompt:           lyrics,
 		Tags:             tags,
 		Title:            title,
+		Model:            e.sunoModel(props),
+		PersonaID:        sunoPersonaID(state),
 		MakeInstrumental: false,
 		WaitAudio:        false, // Don't wait, we'll poll for completion
 	}
 
-	results, err := e.sunoAPI.CustomGenerate(ctx, req)
+	var results []suno.AudioInfo
+	err := e.runStep(state, StepSubmission, func() (err error) {
+		if err := e.reserveSunoSubmission(); err != nil {
+			return err
+		}
+		results, err = e.sunoAPI.CustomGenerate(ctx, req)
+		return err
+	})
 	if err != nil {
-		e.handleError(state, "suno submission", err)
+		e.handleError(state, StepSubmission, err)
 		return
 	}
 
 	// Store the IDs of generated songs (typically 2 variations)
 	if len(results) > 0 {
 		state.SunoJobID = results[0].ID
-		state.Status = "generating"
-		e.store.Save(state)
+		e.recordSunoCredits(state, e.cfg.SunoCreditsPerGeneration)
+		e.setStatus(state, storage.StatusGenerating)
 
 		// Start polling for completion
 		go e.pollSunoCompletion(ctx, state, results[0].ID)
 	} else {
-		e.handleError(state, "suno submission", fmt.Errorf("no results returned from Suno"))
+		e.handleError(state, StepSubmission, fmt.Errorf("no results returned from Suno"))
+	}
+}
+
+// sunoTags builds the Suno style/tags string from a property set
+func sunoTags(state *storage.WorkflowState, props *storage.SunoProperties) string {
+	tags := props.Style
+	if props.VocalType != "" {
+		tags += ", " + props.VocalType
+	}
+	if tag := languageTag(state.Language); tag != "" {
+		tags += ", " + tag
+	}
+	return tags
+}
+
+// submittedLyrics returns the lyrics sent to Suno: the reviewer's edit, or the generated lyrics
+func submittedLyrics(state *storage.WorkflowState) string {
+	if state.EditedLyrics != "" {
+		return state.EditedLyrics
 	}
+	return state.LyricsWithBrackets
+}
+
+// submittedProperties returns the property set sent to Suno: the reviewer's edit, or the generated one
+func submittedProperties(state *storage.WorkflowState) *storage.SunoProperties {
+	if state.EditedProperties != nil {
+		return state.EditedProperties
+	}
+	return state.SunoProperties
 }
 
 // pollSunoCompletion polls the suno-api server until the audio is ready
 func (e *Engine) pollSunoCompletion(ctx context.Context, state *storage.WorkflowState, audioID string) {
-	// Poll every 5 seconds, max 60 retries (5 minutes)
-	audio, err := e.sunoAPI.WaitForCompletion(ctx, audioID, 5*time.Second, 60)
+	var audio *suno.AudioInfo
+	err := e.runStep(state, StepCompletion, func() (err error) {
+		audio, err = e.waitForClip(ctx, state, audioID)
+		return err
+	})
 	if err != nil {
-		e.handleError(state, "suno completion", err)
+		e.handleError(state, StepCompletion, err)
 		return
 	}
 
-	state.SunoResult = audio.Status
-	state.Status = "completed"
-	e.store.Save(state)
+	e.completeSong(ctx, state, audio)
+}
+
+// completeSong stores the final clip, generates its stems when requested and marks the workflow completed
+// A stems failure is recorded on the workflow but does not fail it; stems already requested
+// before a restart are polled again rather than generated twice
+func (e *Engine) completeSong(ctx context.Context, state *storage.WorkflowState, clip *suno.AudioInfo) {
+	state.SunoResult = clip.Status
+	state.AudioURL = clip.AudioURL
+	state.VideoURL = clip.VideoURL
 
-	// Notify completion with audio URL
-	message := fmt.Sprintf("✅ Song generation completed!\n\n🎵 Title: %s\n🔗 Audio: %s\n📹 Video: %s",
-		audio.Title, audio.AudioURL, audio.VideoURL)
-	if err := e.notifier.Send(ctx, message); err != nil {
-		slog.Warn("Failed to send completion notification", "error", err, "workflow_id", state.ID, "audio_id", audioID)
+	if state.GenerateStems {
+		e.generateStems(ctx, state, clip.ID)
+	}
+
+	e.setStatus(state, storage.StatusCompleted)
+}
+
+// generateStems separates the clip into stems, recording the download URL or why it failed
+func (e *Engine) generateStems(ctx context.Context, state *storage.WorkflowState, clipID string) {
+	var stems *suno.AudioInfo
+	err := e.runStep(state, StepStems, func() (err error) {
+		if state.StemsClipID == "" {
+			stems, err = e.sunoAPI.GenerateStems(ctx, &suno.GenerateStemsRequest{AudioID: clipID})
+			if err != nil {
+				return err
+			}
+			state.StemsClipID = stems.ID
+			e.store.Save(state)
+		}
+
+		stems, err = e.waitForClip(ctx, state, state.StemsClipID)
+		return err
+	})
+	if err != nil {
+		state.StemsError = err.Error()
+		slog.WarnContext(logContext(state), "Stem generation failed", "workflow_id", state.ID, "clip_id", clipID, "error", err)
+	} else {
+		state.StemsURL = stems.AudioURL
 	}
 }
 
 // RejectWorkflow marks the workflow as rejected
-func (e *Engine) RejectWorkflow(state *storage.WorkflowState) {
-	state.Status = "rejected"
-	e.store.Save(state)
+// Like ApproveWorkflow it returns ErrNotInReview unless the workflow awaits review and no other
+// decision is being made on it.
+func (e *Engine) RejectWorkflow(state *storage.WorkflowState, by string) error {
+	release, err := e.claimReview(state, storage.StatusAwaitingReview)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	e.publishReviewed(state, by, DecisionRejected)
+	e.setStatus(state, storage.StatusRejected)
+	return nil
+}
+
+// logContext returns a context carrying the request ID of the workflow's current run, for log
+// records about the workflow made outside of the run's own context
+func logContext(state *storage.WorkflowState) context.Context {
+	return logger.WithRequestID(context.Background(), state.RequestID)
 }
 
 // handleError updates state with error information
 func (e *Engine) handleError(state *storage.WorkflowState, step string, err error) {
-	state.Status = "failed"
+	if state.Status == storage.StatusCancelled {
+		return // the run was stopped by CancelWorkflow
+	}
+	if (strings.HasPrefix(step, StepSubmission) || strings.HasPrefix(step, StepExtend)) && isSunoAuthError(err) && e.cfg.SunoHealthInterval > 0 {
+		// Park instead of failing; the health monitor submits again once the session is renewed
+		e.setSunoHealth(context.Background(), SunoHealth{AuthExpired: true, CheckedAt: time.Now(), Error: err.Error()})
+		if e.parkForSunoAuth(state) {
+			return
+		}
+	}
 	state.ErrorMsg = fmt.Sprintf("%s failed: %v", step, err)
-	e.store.Save(state)
-	slog.Error("Workflow error", "workflow_id", state.ID, "step", step, "error", err)
+	e.setStatus(state, storage.StatusFailed)
+	slog.ErrorContext(logContext(state), "Workflow error", "workflow_id", state.ID, "step", step, "error", err)
+	if isGuardBreach(err) {
+		e.alertGuardBreach(state, step, err)
+	}
 }
 
 // Helper functions